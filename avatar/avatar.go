@@ -0,0 +1,144 @@
+package avatar
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"image"
+	"image/color"
+	_ "image/gif"
+	"image/jpeg"
+	_ "image/png"
+	"net/http"
+
+	"github.com/VitoNaychev/bt-customer-svc/blobstore"
+)
+
+// Sizes are the edges, in pixels, of the square thumbnails rendered for
+// every avatar. The largest one is used as the avatar URL.
+var Sizes = []int{64, 128, 256}
+
+const MaxDimension = 4096
+
+var (
+	ErrUnsupportedFormat = errors.New("image format is not supported")
+	ErrImageTooLarge     = errors.New("image dimensions are too large")
+	ErrInvalidImage      = errors.New("image couldn't be decoded")
+)
+
+// Process sniffs and decodes an uploaded image and renders the standard
+// thumbnails as JPEG. Re-encoding the pixels drops all metadata of the
+// original file, EXIF included.
+func Process(data []byte) (map[int][]byte, error) {
+	switch http.DetectContentType(data) {
+	case "image/jpeg", "image/png", "image/gif":
+	default:
+		return nil, ErrUnsupportedFormat
+	}
+
+	config, _, err := image.DecodeConfig(bytes.NewReader(data))
+	if err != nil {
+		return nil, ErrInvalidImage
+	}
+
+	if config.Width > MaxDimension || config.Height > MaxDimension {
+		return nil, ErrImageTooLarge
+	}
+
+	img, _, err := image.Decode(bytes.NewReader(data))
+	if err != nil {
+		return nil, ErrInvalidImage
+	}
+
+	thumbnails := map[int][]byte{}
+	for _, size := range Sizes {
+		buf := bytes.NewBuffer([]byte{})
+		err = jpeg.Encode(buf, Thumbnail(img, size), &jpeg.Options{Quality: 85})
+		if err != nil {
+			return nil, err
+		}
+		thumbnails[size] = buf.Bytes()
+	}
+
+	return thumbnails, nil
+}
+
+// Thumbnail crops the centred square of img and scales it to size x size
+// by averaging the source pixels that fall into every target pixel.
+// Transparent areas are flattened onto white.
+func Thumbnail(img image.Image, size int) *image.RGBA {
+	bounds := img.Bounds()
+	edge := min(bounds.Dx(), bounds.Dy())
+	originX := bounds.Min.X + (bounds.Dx()-edge)/2
+	originY := bounds.Min.Y + (bounds.Dy()-edge)/2
+
+	thumbnail := image.NewRGBA(image.Rect(0, 0, size, size))
+	for y := 0; y < size; y++ {
+		y0, y1 := scaleSpan(y, size, edge)
+		for x := 0; x < size; x++ {
+			x0, x1 := scaleSpan(x, size, edge)
+
+			var r, g, b, a, n uint64
+			for sy := y0; sy < y1; sy++ {
+				for sx := x0; sx < x1; sx++ {
+					pr, pg, pb, pa := img.At(originX+sx, originY+sy).RGBA()
+					r, g, b, a = r+uint64(pr), g+uint64(pg), b+uint64(pb), a+uint64(pa)
+					n++
+				}
+			}
+
+			// colours are alpha-premultiplied, so adding the missing alpha
+			// composites the pixel onto a white background
+			white := 0xffff*n - a
+			thumbnail.SetRGBA(x, y, color.RGBA{
+				R: uint8((r + white) / n >> 8),
+				G: uint8((g + white) / n >> 8),
+				B: uint8((b + white) / n >> 8),
+				A: 0xff,
+			})
+		}
+	}
+
+	return thumbnail
+}
+
+// scaleSpan maps target pixel i of a size wide row to the half-open range
+// of source pixels of an edge wide row that it covers.
+func scaleSpan(i, size, edge int) (int, int) {
+	start := i * edge / size
+	end := (i + 1) * edge / size
+	if end <= start {
+		end = start + 1
+	}
+	return start, end
+}
+
+func Key(customerId int, size int) string {
+	return fmt.Sprintf("avatars/%d/%d.jpg", customerId, size)
+}
+
+// Save uploads the thumbnails of a customer's avatar and returns the URL
+// of the largest one.
+func Save(store blobstore.BlobStore, customerId int, thumbnails map[int][]byte) (string, error) {
+	for _, size := range Sizes {
+		err := store.Put(Key(customerId, size), "image/jpeg", bytes.NewReader(thumbnails[size]))
+		if err != nil {
+			return "", err
+		}
+	}
+
+	return store.URL(Key(customerId, Sizes[len(Sizes)-1])), nil
+}
+
+// Delete removes all thumbnails of a customer's avatar. Missing thumbnails
+// are not an error.
+func Delete(store blobstore.BlobStore, customerId int) error {
+	for _, size := range Sizes {
+		err := store.Delete(Key(customerId, size))
+		if err != nil && !errors.Is(err, blobstore.ErrNotFound) {
+			return err
+		}
+	}
+
+	return nil
+}
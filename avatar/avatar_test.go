@@ -0,0 +1,88 @@
+package avatar_test
+
+import (
+	"bytes"
+	"image"
+	"image/color"
+	"image/jpeg"
+	"testing"
+
+	"github.com/VitoNaychev/bt-customer-svc/avatar"
+)
+
+func newTestJPEG(t testing.TB, width, height int) []byte {
+	t.Helper()
+
+	img := image.NewRGBA(image.Rect(0, 0, width, height))
+	for y := 0; y < height; y++ {
+		for x := 0; x < width; x++ {
+			img.Set(x, y, color.RGBA{R: uint8(x), G: uint8(y), B: 0x40, A: 0xff})
+		}
+	}
+
+	buf := bytes.NewBuffer([]byte{})
+	if err := jpeg.Encode(buf, img, nil); err != nil {
+		t.Fatalf("couldn't encode test image: %v", err)
+	}
+	return buf.Bytes()
+}
+
+// withEXIF inserts an APP1 Exif segment right after the SOI marker.
+func withEXIF(data []byte) []byte {
+	payload := append([]byte("Exif\x00\x00"), []byte("GPS 42.6977 23.3219")...)
+	length := len(payload) + 2
+	segment := append([]byte{0xff, 0xe1, byte(length >> 8), byte(length)}, payload...)
+
+	result := append([]byte{}, data[:2]...)
+	result = append(result, segment...)
+	return append(result, data[2:]...)
+}
+
+func TestProcess(t *testing.T) {
+	t.Run("renders square thumbnails of every size", func(t *testing.T) {
+		thumbnails, err := avatar.Process(newTestJPEG(t, 400, 300))
+		if err != nil {
+			t.Fatalf("got error %v", err)
+		}
+
+		for _, size := range avatar.Sizes {
+			config, format, err := image.DecodeConfig(bytes.NewReader(thumbnails[size]))
+			if err != nil {
+				t.Fatalf("couldn't decode thumbnail %d: %v", size, err)
+			}
+
+			if format != "jpeg" || config.Width != size || config.Height != size {
+				t.Errorf("got %s %dx%d want jpeg %dx%d", format, config.Width, config.Height, size, size)
+			}
+		}
+	})
+
+	t.Run("strips EXIF metadata", func(t *testing.T) {
+		thumbnails, err := avatar.Process(withEXIF(newTestJPEG(t, 100, 100)))
+		if err != nil {
+			t.Fatalf("got error %v", err)
+		}
+
+		for size, thumbnail := range thumbnails {
+			if bytes.Contains(thumbnail, []byte("Exif")) {
+				t.Errorf("thumbnail %d still contains EXIF data", size)
+			}
+		}
+	})
+
+	t.Run("rejects unsupported formats", func(t *testing.T) {
+		_, err := avatar.Process([]byte("<svg xmlns=\"http://www.w3.org/2000/svg\"></svg>"))
+		if err != avatar.ErrUnsupportedFormat {
+			t.Errorf("got error %v want %v", err, avatar.ErrUnsupportedFormat)
+		}
+	})
+
+	t.Run("rejects truncated images", func(t *testing.T) {
+		data := newTestJPEG(t, 100, 100)
+
+		_, err := avatar.Process(data[:len(data)/2])
+		if err != avatar.ErrInvalidImage {
+			t.Errorf("got error %v want %v", err, avatar.ErrInvalidImage)
+		}
+	})
+}
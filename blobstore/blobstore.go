@@ -0,0 +1,19 @@
+package blobstore
+
+import (
+	"errors"
+	"io"
+)
+
+var (
+	ErrInvalidKey = errors.New("blob key is invalid")
+	ErrNotFound   = errors.New("blob doesn't exist")
+)
+
+// BlobStore stores binary objects, such as avatars, under slash separated
+// keys and knows the public URL they are served from.
+type BlobStore interface {
+	Put(key string, contentType string, data io.Reader) error
+	Delete(key string) error
+	URL(key string) string
+}
@@ -0,0 +1,109 @@
+package blobstore
+
+import (
+	"errors"
+	"io"
+	"io/fs"
+	"net/http"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+)
+
+// LocalBlobStore keeps blobs on the local filesystem under root. The files
+// are expected to be served by its Handler mounted at baseURL.
+type LocalBlobStore struct {
+	root    string
+	baseURL string
+}
+
+func NewLocalBlobStore(root string, baseURL string) (*LocalBlobStore, error) {
+	if err := os.MkdirAll(root, 0o755); err != nil {
+		return nil, err
+	}
+
+	return &LocalBlobStore{root: root, baseURL: strings.TrimSuffix(baseURL, "/")}, nil
+}
+
+func (l *LocalBlobStore) Put(key string, contentType string, data io.Reader) error {
+	filePath, err := l.filePath(key)
+	if err != nil {
+		return err
+	}
+
+	if err = os.MkdirAll(filepath.Dir(filePath), 0o755); err != nil {
+		return err
+	}
+
+	// write to a temporary file first so readers never see a partial blob
+	tmp, err := os.CreateTemp(filepath.Dir(filePath), ".upload-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err = io.Copy(tmp, data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err = tmp.Close(); err != nil {
+		return err
+	}
+
+	return os.Rename(tmp.Name(), filePath)
+}
+
+func (l *LocalBlobStore) Delete(key string) error {
+	filePath, err := l.filePath(key)
+	if err != nil {
+		return err
+	}
+
+	err = os.Remove(filePath)
+	if errors.Is(err, fs.ErrNotExist) {
+		return ErrNotFound
+	}
+	return err
+}
+
+func (l *LocalBlobStore) URL(key string) string {
+	return l.baseURL + "/" + key
+}
+
+// Handler serves the blobs, without the prefix of baseURL. Directories are
+// not listed, so the keys of other blobs, e.g. avatars named after customer
+// IDs, can't be enumerated.
+func (l *LocalBlobStore) Handler() http.Handler {
+	return http.FileServer(filesOnly{http.Dir(l.root)})
+}
+
+// filesOnly reports directories as missing, which http.FileServer answers
+// with 404 instead of a listing.
+type filesOnly struct {
+	http.FileSystem
+}
+
+func (f filesOnly) Open(name string) (http.File, error) {
+	file, err := f.FileSystem.Open(name)
+	if err != nil {
+		return nil, err
+	}
+
+	info, err := file.Stat()
+	if err != nil || info.IsDir() {
+		file.Close()
+		return nil, fs.ErrNotExist
+	}
+
+	return file, nil
+}
+
+func (l *LocalBlobStore) filePath(key string) (string, error) {
+	cleaned := path.Clean("/" + key)
+	if key == "" || cleaned != "/"+key {
+		return "", ErrInvalidKey
+	}
+
+	return filepath.Join(l.root, filepath.FromSlash(key)), nil
+}
@@ -0,0 +1,43 @@
+package blobstore_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/VitoNaychev/bt-customer-svc/blobstore"
+	"github.com/VitoNaychev/bt-customer-svc/testutil"
+)
+
+func TestLocalBlobStoreHandler(t *testing.T) {
+	store, err := blobstore.NewLocalBlobStore(t.TempDir(), "/blobs")
+	if err != nil {
+		t.Fatalf("couldn't create blob store: %v", err)
+	}
+
+	if err = store.Put("avatars/1/256.jpg", "image/jpeg", strings.NewReader("avatar")); err != nil {
+		t.Fatalf("couldn't put blob: %v", err)
+	}
+
+	cases := map[string]struct {
+		path   string
+		status int
+	}{
+		"serves a blob":               {path: "/avatars/1/256.jpg", status: http.StatusOK},
+		"doesn't list the root":       {path: "/", status: http.StatusNotFound},
+		"doesn't list a directory":    {path: "/avatars/", status: http.StatusNotFound},
+		"doesn't redirect to listing": {path: "/avatars", status: http.StatusNotFound},
+	}
+
+	for name, test := range cases {
+		t.Run(name, func(t *testing.T) {
+			request, _ := http.NewRequest(http.MethodGet, test.path, nil)
+			response := httptest.NewRecorder()
+
+			store.Handler().ServeHTTP(response, request)
+
+			testutil.AssertStatus(t, response.Code, test.status)
+		})
+	}
+}
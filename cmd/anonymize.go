@@ -0,0 +1,46 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"log"
+	"os"
+
+	"github.com/VitoNaychev/bt-customer-svc/avatar"
+	"github.com/VitoNaychev/bt-customer-svc/handlers"
+	"github.com/VitoNaychev/bt-customer-svc/models"
+)
+
+// runAnonymize irreversibly scrubs the personal data of a customer and
+// prints a report of the fields that were erased.
+func runAnonymize(args []string) {
+	flags := flag.NewFlagSet("anonymize", flag.ExitOnError)
+	id := flags.Int("id", 0, "ID of the customer to anonymize")
+	flags.Parse(args)
+
+	if *id <= 0 {
+		flags.Usage()
+		os.Exit(2)
+	}
+
+	dbConfig := loadDBConfig()
+	customerStore, err := models.NewPgCustomerStore(context.Background(), dbConfig.getConnectionString())
+	if err != nil {
+		log.Fatalf("Customer Store error: %v", err)
+	}
+
+	report, err := customerStore.AnonymizeCustomer(*id)
+	if err != nil {
+		log.Fatalf("couldn't anonymize customer %d: %v", *id, err)
+	}
+
+	err = avatar.Delete(newBlobStore(), *id)
+	if err != nil {
+		log.Fatalf("couldn't delete avatar of customer %d: %v", *id, err)
+	}
+
+	encoder := json.NewEncoder(os.Stdout)
+	encoder.SetIndent("", "  ")
+	encoder.Encode(handlers.ErasureReportToAnonymizeCustomerResponse(report))
+}
@@ -0,0 +1,53 @@
+package main
+
+import (
+	"log"
+	"os"
+	"time"
+
+	"github.com/VitoNaychev/bt-customer-svc/zones"
+)
+
+func loadZonesReloadInterval() time.Duration {
+	interval, err := time.ParseDuration(getEnvOrDefault("DELIVERY_ZONES_RELOAD_INTERVAL", "1m"))
+	if err != nil {
+		log.Fatalf("invalid DELIVERY_ZONES_RELOAD_INTERVAL: %v", err)
+	}
+
+	return interval
+}
+
+// loadDeliveryZones returns nil when no zones directory is configured, in
+// which case every address is deliverable.
+func loadDeliveryZones() *zones.Registry {
+	dir := os.Getenv("DELIVERY_ZONES_DIR")
+	if dir == "" {
+		return nil
+	}
+
+	registry, err := zones.NewRegistry(dir)
+	if err != nil {
+		log.Fatalf("Delivery Zones error: %v", err)
+	}
+	log.Printf("loaded %d delivery zones from %s", registry.Len(), dir)
+
+	return registry
+}
+
+// scheduleZonesReload picks up edited zone files every interval. Invalid
+// files are logged and the zones loaded before stay in use.
+func scheduleZonesReload(registry *zones.Registry, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for range ticker.C {
+		reloaded, err := registry.ReloadIfChanged()
+		if err != nil {
+			log.Printf("couldn't reload delivery zones: %v", err)
+			continue
+		}
+		if reloaded {
+			log.Printf("reloaded %d delivery zones", registry.Len())
+		}
+	}
+}
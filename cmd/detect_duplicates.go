@@ -0,0 +1,59 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"log"
+	"os"
+	"time"
+
+	"github.com/VitoNaychev/bt-customer-svc/models"
+)
+
+func loadDetectionInterval() time.Duration {
+	interval, err := time.ParseDuration(getEnvOrDefault("DUPLICATE_DETECTION_INTERVAL", "24h"))
+	if err != nil {
+		log.Fatalf("invalid DUPLICATE_DETECTION_INTERVAL: %v", err)
+	}
+
+	return interval
+}
+
+// scheduleDuplicateDetection queues duplicate candidates for review every
+// interval.
+func scheduleDuplicateDetection(store models.DuplicateStore, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for range ticker.C {
+		candidates, err := store.DetectDuplicates()
+		if err != nil {
+			log.Printf("couldn't detect duplicate customers: %v", err)
+			continue
+		}
+		log.Printf("%d duplicate candidates pending review", len(candidates))
+	}
+}
+
+// runDetectDuplicates queues duplicate candidates once and prints the ones
+// pending review.
+func runDetectDuplicates(args []string) {
+	flags := flag.NewFlagSet("detect-duplicates", flag.ExitOnError)
+	flags.Parse(args)
+
+	dbConfig := loadDBConfig()
+	duplicateStore, err := models.NewPgDuplicateStore(context.Background(), dbConfig.getConnectionString())
+	if err != nil {
+		log.Fatalf("Duplicate Store error: %v", err)
+	}
+
+	candidates, err := duplicateStore.DetectDuplicates()
+	if err != nil {
+		log.Fatalf("couldn't detect duplicate customers: %v", err)
+	}
+
+	encoder := json.NewEncoder(os.Stdout)
+	encoder.SetIndent("", "  ")
+	encoder.Encode(candidates)
+}
@@ -0,0 +1,58 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"log"
+	"os"
+	"time"
+
+	"github.com/VitoNaychev/bt-customer-svc/models"
+)
+
+func loadExpiryInterval() time.Duration {
+	interval, err := time.ParseDuration(getEnvOrDefault("POINTS_EXPIRY_INTERVAL", "24h"))
+	if err != nil {
+		log.Fatalf("invalid POINTS_EXPIRY_INTERVAL: %v", err)
+	}
+
+	return interval
+}
+
+// schedulePointsExpiry writes off expired loyalty points every interval.
+func schedulePointsExpiry(store models.LoyaltyStore, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for now := range ticker.C {
+		expired, err := store.ExpirePoints(now)
+		if err != nil {
+			log.Printf("couldn't expire loyalty points: %v", err)
+			continue
+		}
+		log.Printf("expired loyalty points of %d customers", len(expired))
+	}
+}
+
+// runExpirePoints writes off expired loyalty points once, e.g. from cron,
+// and prints the expire transactions it recorded.
+func runExpirePoints(args []string) {
+	flags := flag.NewFlagSet("expire-points", flag.ExitOnError)
+	flags.Parse(args)
+
+	dbConfig := loadDBConfig()
+	loyaltyStore, err := models.NewPgLoyaltyStore(context.Background(), dbConfig.getConnectionString())
+	if err != nil {
+		log.Fatalf("Loyalty Store error: %v", err)
+	}
+
+	expired, err := loyaltyStore.ExpirePoints(time.Now())
+	if err != nil {
+		log.Fatalf("couldn't expire loyalty points: %v", err)
+	}
+
+	encoder := json.NewEncoder(os.Stdout)
+	encoder.SetIndent("", "  ")
+	encoder.Encode(expired)
+}
@@ -0,0 +1,78 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"log"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/VitoNaychev/bt-customer-svc/exporter"
+	"github.com/VitoNaychev/bt-customer-svc/models"
+)
+
+// runExport writes the customers, addresses and preferences tables to a
+// file each in the output directory and prints a report of the exported rows.
+func runExport(args []string) {
+	flags := flag.NewFlagSet("export", flag.ExitOnError)
+	format := flags.String("format", exporter.JSONL_FORMAT, "jsonl, csv or parquet")
+	piiMode := flags.String("pii", exporter.HASH_PII, "keep, hash or drop the personal columns")
+	since := flags.String("since", "", "only export rows updated at or after this RFC 3339 time")
+	outDir := flags.String("out", ".", "directory the export files are written to")
+	flags.Parse(args)
+
+	var updatedSince time.Time
+	if *since != "" {
+		var err error
+		if updatedSince, err = time.Parse(time.RFC3339, *since); err != nil {
+			log.Fatalf("invalid -since: %v", err)
+		}
+	}
+
+	dbConfig := loadDBConfig()
+	customerStore, err := models.NewPgCustomerStore(context.Background(), dbConfig.getConnectionString())
+	if err != nil {
+		log.Fatalf("Customer Store error: %v", err)
+	}
+
+	tableExporter, err := exporter.NewExporter(&customerStore, *piiMode, []byte(os.Getenv("EXPORT_HASH_KEY")))
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	reports := []exporter.Report{}
+	for _, table := range []string{exporter.CUSTOMERS_TABLE, exporter.ADDRESSES_TABLE, exporter.PREFERENCES_TABLE} {
+		report, err := exportTable(tableExporter, filepath.Join(*outDir, table+"."+*format), table, *format, updatedSince)
+		if err != nil {
+			log.Fatalf("couldn't export %s: %v", table, err)
+		}
+		reports = append(reports, report)
+	}
+
+	encoder := json.NewEncoder(os.Stdout)
+	encoder.SetIndent("", "  ")
+	encoder.Encode(reports)
+}
+
+// exportTable writes to a temporary file that is renamed once the export
+// is complete, so a failed export never leaves a truncated file behind.
+func exportTable(tableExporter *exporter.Exporter, path string, table string, format string, since time.Time) (exporter.Report, error) {
+	file, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".*.tmp")
+	if err != nil {
+		return exporter.Report{}, err
+	}
+	defer os.Remove(file.Name())
+	defer file.Close()
+
+	report, err := tableExporter.Export(file, table, format, since)
+	if err != nil {
+		return report, err
+	}
+
+	if err = file.Close(); err != nil {
+		return report, err
+	}
+	return report, os.Rename(file.Name(), path)
+}
@@ -0,0 +1,85 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/VitoNaychev/bt-customer-svc/importer"
+	"github.com/VitoNaychev/bt-customer-svc/models"
+)
+
+// runImportCustomers loads customers from a CSV or JSONL file and prints a
+// report. Rerunning it after an interruption resumes after the last
+// committed batch; rejected records are appended to the rejects file.
+func runImportCustomers(args []string) {
+	flags := flag.NewFlagSet("import-customers", flag.ExitOnError)
+	format := flags.String("format", "", "csv or jsonl, by default taken from the file extension")
+	source := flags.String("source", "", "name the checkpoint is kept under, by default the file name")
+	rejectsPath := flags.String("rejects", "", "file rejected records are appended to, by default <file>.rejects.jsonl")
+	batchSize := flags.Int("batch-size", importer.DEFAULT_BATCH_SIZE, "number of customers copied per transaction")
+	dryRun := flags.Bool("dry-run", false, "validate and insert every batch, but roll it back")
+	flags.Usage = func() {
+		fmt.Fprintln(flags.Output(), "usage: main import-customers [flags] <file>")
+		flags.PrintDefaults()
+	}
+	flags.Parse(args)
+
+	if flags.NArg() != 1 {
+		flags.Usage()
+		os.Exit(2)
+	}
+	path := flags.Arg(0)
+
+	if *format == "" {
+		*format = strings.TrimPrefix(strings.ToLower(filepath.Ext(path)), ".")
+	}
+	if *source == "" {
+		*source = filepath.Base(path)
+	}
+	if *rejectsPath == "" {
+		*rejectsPath = path + ".rejects.jsonl"
+	}
+
+	file, err := os.Open(path)
+	if err != nil {
+		log.Fatalf("couldn't open import file: %v", err)
+	}
+	defer file.Close()
+
+	reader, err := importer.NewReader(file, *format)
+	if err != nil {
+		log.Fatalf("couldn't read import file: %v", err)
+	}
+
+	rejects, err := os.OpenFile(*rejectsPath, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0644)
+	if err != nil {
+		log.Fatalf("couldn't open rejects file: %v", err)
+	}
+	defer rejects.Close()
+
+	dbConfig := loadDBConfig()
+	customerStore, err := models.NewPgCustomerStore(context.Background(), dbConfig.getConnectionString())
+	if err != nil {
+		log.Fatalf("Customer Store error: %v", err)
+	}
+
+	customerImporter := importer.NewImporter(&customerStore, loadIdentityRules(), rejects)
+	customerImporter.SetBatchSize(*batchSize)
+	customerImporter.SetDryRun(*dryRun)
+
+	report, err := customerImporter.Run(*source, reader)
+
+	encoder := json.NewEncoder(os.Stdout)
+	encoder.SetIndent("", "  ")
+	encoder.Encode(report)
+
+	if err != nil {
+		log.Fatalf("import stopped: %v", err)
+	}
+}
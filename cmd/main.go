@@ -7,7 +7,10 @@ import (
 	"net/http"
 	"os"
 	"time"
+	_ "time/tzdata"
 
+	"github.com/VitoNaychev/bt-customer-svc/blobstore"
+	"github.com/VitoNaychev/bt-customer-svc/geocoding"
 	"github.com/VitoNaychev/bt-customer-svc/handlers"
 	"github.com/VitoNaychev/bt-customer-svc/models"
 )
@@ -25,33 +28,208 @@ func (d *DBConfig) getConnectionString() string {
 		d.postgresUser, d.postgresPassword, d.postgresHost, d.postgresPort, d.postgresDB)
 }
 
-func main() {
-	secretKey := []byte(os.Getenv("SECRET"))
-	expiresAt := 24 * time.Hour
-
+func loadDBConfig() DBConfig {
 	dbConfig := DBConfig{
-		postgresHost:     "customer-db",
-		postgresPort:     "5432",
+		postgresHost:     getEnvOrDefault("POSTGRES_HOST", "customer-db"),
+		postgresPort:     getEnvOrDefault("POSTGRES_PORT", "5432"),
 		postgresUser:     os.Getenv("POSTGRES_USER"),
 		postgresPassword: os.Getenv("POSTGRES_PASSWORD"),
 		postgresDB:       os.Getenv("POSTGRES_DB"),
 	}
+
+	return dbConfig
+}
+
+func newBlobStore() *blobstore.LocalBlobStore {
+	blobStore, err := blobstore.NewLocalBlobStore(
+		getEnvOrDefault("BLOB_DIR", "/var/lib/customer-svc/blobs"),
+		getEnvOrDefault("BLOB_BASE_URL", "/blobs"),
+	)
+	if err != nil {
+		log.Fatalf("Blob Store error: %v", err)
+	}
+
+	return blobStore
+}
+
+// loadGazetteer returns nil when no gazetteer is configured, in which case
+// addresses have to be sent with coordinates.
+func loadGazetteer() *geocoding.Gazetteer {
+	path := os.Getenv("GAZETTEER_FILE")
+	if path == "" {
+		return nil
+	}
+
+	gazetteer, err := geocoding.LoadGazetteer(path)
+	if err != nil {
+		log.Fatalf("Gazetteer error: %v", err)
+	}
+
+	return gazetteer
+}
+
+func loadIdentityRules() models.IdentityRules {
+	return models.IdentityRules{
+		PhoneRegion:        getEnvOrDefault("PHONE_REGION", models.DEFAULT_PHONE_REGION),
+		EmailProviderRules: os.Getenv("EMAIL_PROVIDER_RULES") == "true",
+	}
+}
+
+func getEnvOrDefault(key, fallback string) string {
+	if value := os.Getenv(key); value != "" {
+		return value
+	}
+	return fallback
+}
+
+func main() {
+	if len(os.Args) > 1 {
+		runCommand(os.Args[1], os.Args[2:])
+		return
+	}
+
+	serve()
+}
+
+func runCommand(command string, args []string) {
+	switch command {
+	case "serve":
+		serve()
+	case "anonymize":
+		runAnonymize(args)
+	case "normalize-identities":
+		runNormalizeIdentities(args)
+	case "normalize-countries":
+		runNormalizeCountries(args)
+	case "expire-points":
+		runExpirePoints(args)
+	case "detect-duplicates":
+		runDetectDuplicates(args)
+	case "import-customers":
+		runImportCustomers(args)
+	case "export":
+		runExport(args)
+	default:
+		fmt.Fprintf(os.Stderr, "unknown command %q\n", command)
+		fmt.Fprintln(os.Stderr, "usage: main [serve | anonymize | normalize-identities | normalize-countries | expire-points | detect-duplicates | import-customers | export]")
+		os.Exit(2)
+	}
+}
+
+func serve() {
+	secretKey := []byte(os.Getenv("SECRET"))
+	adminSecretKey := []byte(os.Getenv("ADMIN_SECRET"))
+	if len(adminSecretKey) == 0 {
+		log.Fatal("ADMIN_SECRET must be set, admin tokens are signed with it")
+	}
+	internalSecretKey := []byte(os.Getenv("INTERNAL_SECRET"))
+	if len(internalSecretKey) == 0 {
+		log.Fatal("INTERNAL_SECRET must be set, internal tokens are signed with it")
+	}
+	exportHashKey := []byte(os.Getenv("EXPORT_HASH_KEY"))
+	if len(exportHashKey) == 0 {
+		log.Fatal("EXPORT_HASH_KEY must be set, exports hash PII with it")
+	}
+	referralHashKey := []byte(os.Getenv("REFERRAL_HASH_KEY"))
+	if len(referralHashKey) == 0 {
+		log.Fatal("REFERRAL_HASH_KEY must be set, referred phone numbers and emails are hashed with it")
+	}
+	expiresAt := 24 * time.Hour
+
+	dbConfig := loadDBConfig()
 	connStr := dbConfig.getConnectionString()
 
 	customerStore, err := models.NewPgCustomerStore(context.Background(), connStr)
 	if err != nil {
-		fmt.Printf("Customer Store error: %v", err)
+		log.Fatalf("Customer Store error: %v", err)
 	}
+	customerStore.SetIdentityRules(loadIdentityRules())
 
 	addressStore, err := models.NewPgAddressStore(context.Background(), connStr)
 	if err != nil {
-		fmt.Printf("Address Store error: %v", err)
+		log.Fatalf("Address Store error: %v", err)
+	}
+
+	preferencesStore, err := models.NewPgPreferencesStore(context.Background(), connStr)
+	if err != nil {
+		log.Fatalf("Preferences Store error: %v", err)
+	}
+
+	consentStore, err := models.NewPgConsentStore(context.Background(), connStr)
+	if err != nil {
+		log.Fatalf("Consent Store error: %v", err)
+	}
+
+	loyaltyStore, err := models.NewPgLoyaltyStore(context.Background(), connStr)
+	if err != nil {
+		log.Fatalf("Loyalty Store error: %v", err)
+	}
+
+	referralStore, err := models.NewPgReferralStore(context.Background(), connStr)
+	if err != nil {
+		log.Fatalf("Referral Store error: %v", err)
+	}
+
+	duplicateStore, err := models.NewPgDuplicateStore(context.Background(), connStr)
+	if err != nil {
+		log.Fatalf("Duplicate Store error: %v", err)
+	}
+
+	segmentStore, err := models.NewPgSegmentStore(context.Background(), connStr)
+	if err != nil {
+		log.Fatalf("Segment Store error: %v", err)
+	}
+
+	householdStore, err := models.NewPgHouseholdStore(context.Background(), connStr)
+	if err != nil {
+		log.Fatalf("Household Store error: %v", err)
+	}
+
+	blobStore := newBlobStore()
+
+	customerServer := handlers.NewCustomerServer(secretKey, expiresAt, &customerStore, &consentStore, &referralStore)
+	customerServer.SetReferralHashKey(referralHashKey)
+	addressServer := handlers.NewCustomerAddressServer(&addressStore, &customerStore, secretKey, &householdStore)
+	if gazetteer := loadGazetteer(); gazetteer != nil {
+		addressServer.SetGeocoder(geocoding.NewCachingGeocoder(gazetteer, geocoding.DEFAULT_CACHE_SIZE))
+		addressServer.SetReverseGeocoder(gazetteer)
+	}
+	if deliveryZones := loadDeliveryZones(); deliveryZones != nil {
+		addressServer.SetDeliveryArea(deliveryZones)
+		go scheduleZonesReload(deliveryZones, loadZonesReloadInterval())
+	}
+	preferencesServer := handlers.NewPreferencesServer(&preferencesStore, &customerStore, secretKey)
+	consentServer := handlers.NewConsentServer(&consentStore, &customerStore, secretKey)
+	avatarServer := handlers.NewAvatarServer(&customerStore, blobStore, secretKey)
+	loyaltyServer := handlers.NewLoyaltyServer(&loyaltyStore, &customerStore, secretKey)
+	referralServer := handlers.NewReferralServer(&referralStore, &customerStore, secretKey)
+	adminServer := handlers.NewAdminServer(adminSecretKey, &customerStore, &consentStore, blobStore, &loyaltyStore,
+		&duplicateStore, &segmentStore, &customerStore, &addressStore)
+	adminServer.SetExportHashKey(exportHashKey)
+	householdServer := handlers.NewHouseholdServer(&householdStore, &customerStore, secretKey)
+	internalServer := handlers.NewInternalServer(internalSecretKey, &customerStore, &addressStore, &householdStore, &segmentStore)
+
+	if os.Getenv("STRICT_PRECONDITIONS") == "true" {
+		customerServer.SetPreconditionMode(handlers.STRICT_PRECONDITIONS)
+		addressServer.SetPreconditionMode(handlers.STRICT_PRECONDITIONS)
 	}
 
-	customerServer := handlers.NewCustomerServer(secretKey, expiresAt, &customerStore)
-	addressServer := handlers.NewCustomerAddressServer(&addressStore, &customerStore, secretKey)
+	router := handlers.NewRouterServer(
+		consentServer.RequireConsents(customerServer),
+		consentServer.RequireConsents(addressServer),
+	)
+	router.Handle("/customer/preferences/", consentServer.RequireConsents(preferencesServer))
+	router.Handle("/customer/consents/", consentServer)
+	router.Handle("/customer/avatar/", consentServer.RequireConsents(avatarServer))
+	router.Handle("/customer/loyalty/", consentServer.RequireConsents(loyaltyServer))
+	router.Handle("/customer/referrals/", consentServer.RequireConsents(referralServer))
+	router.Handle("/customer/households/", consentServer.RequireConsents(householdServer))
+	router.Handle("/blobs/", http.StripPrefix("/blobs/", blobStore.Handler()))
+	router.Handle("/admin/", adminServer)
+	router.Handle("/internal/", internalServer)
 
-	router := handlers.NewRouterServer(customerServer, addressServer)
+	go schedulePointsExpiry(&loyaltyStore, loadExpiryInterval())
+	go scheduleDuplicateDetection(&duplicateStore, loadDetectionInterval())
 
 	fmt.Println("Customer service listening on :8080")
 	log.Fatal(http.ListenAndServe(":8080", router))
@@ -0,0 +1,39 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"log"
+	"os"
+
+	"github.com/VitoNaychev/bt-customer-svc/models"
+)
+
+// runNormalizeCountries rewrites the countries of existing addresses to
+// ISO 3166-1 alpha-2 codes, prints a report of the rows that couldn't be
+// migrated and exits with status 1 if there are any.
+func runNormalizeCountries(args []string) {
+	flags := flag.NewFlagSet("normalize-countries", flag.ExitOnError)
+	dryRun := flags.Bool("dry-run", false, "report the changes without applying them")
+	flags.Parse(args)
+
+	dbConfig := loadDBConfig()
+	addressStore, err := models.NewPgAddressStore(context.Background(), dbConfig.getConnectionString())
+	if err != nil {
+		log.Fatalf("Address Store error: %v", err)
+	}
+
+	report, err := addressStore.NormalizeCountries(*dryRun)
+	if err != nil {
+		log.Fatalf("couldn't normalize address countries: %v", err)
+	}
+
+	encoder := json.NewEncoder(os.Stdout)
+	encoder.SetIndent("", "  ")
+	encoder.Encode(report)
+
+	if !report.Constrained {
+		os.Exit(1)
+	}
+}
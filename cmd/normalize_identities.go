@@ -0,0 +1,40 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"log"
+	"os"
+
+	"github.com/VitoNaychev/bt-customer-svc/models"
+)
+
+// runNormalizeIdentities rewrites existing phone numbers to E.164 and
+// emails to their canonical form, prints a report of the rows that
+// couldn't be migrated and exits with status 1 if there are any.
+func runNormalizeIdentities(args []string) {
+	flags := flag.NewFlagSet("normalize-identities", flag.ExitOnError)
+	dryRun := flags.Bool("dry-run", false, "report the changes without applying them")
+	flags.Parse(args)
+
+	dbConfig := loadDBConfig()
+	customerStore, err := models.NewPgCustomerStore(context.Background(), dbConfig.getConnectionString())
+	if err != nil {
+		log.Fatalf("Customer Store error: %v", err)
+	}
+	customerStore.SetIdentityRules(loadIdentityRules())
+
+	report, err := customerStore.NormalizeIdentities(*dryRun)
+	if err != nil {
+		log.Fatalf("couldn't normalize customer identities: %v", err)
+	}
+
+	encoder := json.NewEncoder(os.Stdout)
+	encoder.SetIndent("", "  ")
+	encoder.Encode(report)
+
+	if !report.Constrained {
+		os.Exit(1)
+	}
+}
@@ -8,8 +8,10 @@ import (
 )
 
 type Enviornment struct {
-	SecretKey []byte
-	ExpiresAt time.Duration
+	SecretKey         []byte
+	AdminSecretKey    []byte
+	InternalSecretKey []byte
+	ExpiresAt         time.Duration
 
 	Dbuser string
 	Dbpass string
@@ -21,7 +23,9 @@ func LoadEnviornment(file string) Enviornment {
 
 	testEnv := Enviornment{}
 	testEnv.SecretKey = []byte(os.Getenv("SECRET"))
-	testEnv.ExpiresAt = time.Second
+	testEnv.AdminSecretKey = []byte(os.Getenv("ADMIN_SECRET"))
+	testEnv.InternalSecretKey = []byte(os.Getenv("INTERNAL_SECRET"))
+	testEnv.ExpiresAt = time.Minute
 
 	testEnv.Dbuser = os.Getenv("DBUSER")
 	testEnv.Dbpass = os.Getenv("DBPASS")
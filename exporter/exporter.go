@@ -0,0 +1,246 @@
+package exporter
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"io"
+	"math"
+	"time"
+
+	"github.com/VitoNaychev/bt-customer-svc/models"
+)
+
+const (
+	CUSTOMERS_TABLE   = "customers"
+	ADDRESSES_TABLE   = "addresses"
+	PREFERENCES_TABLE = "preferences"
+)
+
+// PII modes. With HASH_PII personal columns are replaced by a keyed hash,
+// so analysts can still join and count distinct values, and coordinates
+// are rounded to about a kilometer. With DROP_PII they are left out.
+const (
+	KEEP_PII = "keep"
+	HASH_PII = "hash"
+	DROP_PII = "drop"
+)
+
+// HASHED_COORDINATE_PRECISION is the number of decimals coordinates are
+// rounded to with HASH_PII.
+const HASHED_COORDINATE_PRECISION = 2
+
+var (
+	ErrUnknownTable   = errors.New("export table must be customers, addresses or preferences")
+	ErrUnknownPIIMode = errors.New("PII mode must be keep, hash or drop")
+	ErrMissingHashKey = errors.New("hash PII mode needs a hash key")
+)
+
+type ColumnKind int
+
+const (
+	STRING_COLUMN ColumnKind = iota
+	INT_COLUMN
+	FLOAT_COLUMN
+	TIME_COLUMN
+)
+
+// Column describes a column of an export. Values are string, int64,
+// float64 or time.Time depending on Kind, or nil.
+type Column struct {
+	Name string
+	Kind ColumnKind
+	PII  bool
+}
+
+// Passwords and canonical emails are never exported.
+var customerColumns = []Column{
+	{Name: "id", Kind: INT_COLUMN},
+	{Name: "first_name", Kind: STRING_COLUMN, PII: true},
+	{Name: "last_name", Kind: STRING_COLUMN, PII: true},
+	{Name: "phone_number", Kind: STRING_COLUMN, PII: true},
+	{Name: "email", Kind: STRING_COLUMN, PII: true},
+	{Name: "avatar_url", Kind: STRING_COLUMN, PII: true},
+	{Name: "status", Kind: STRING_COLUMN},
+	{Name: "merged_into", Kind: INT_COLUMN},
+	{Name: "created_at", Kind: TIME_COLUMN},
+	{Name: "updated_at", Kind: TIME_COLUMN},
+}
+
+var addressColumns = []Column{
+	{Name: "id", Kind: INT_COLUMN},
+	{Name: "customer_id", Kind: INT_COLUMN},
+	{Name: "lat", Kind: FLOAT_COLUMN, PII: true},
+	{Name: "lon", Kind: FLOAT_COLUMN, PII: true},
+	{Name: "address_line1", Kind: STRING_COLUMN, PII: true},
+	{Name: "address_line2", Kind: STRING_COLUMN, PII: true},
+	{Name: "city", Kind: STRING_COLUMN},
+	{Name: "region", Kind: STRING_COLUMN},
+	{Name: "postal_code", Kind: STRING_COLUMN, PII: true},
+	{Name: "country", Kind: STRING_COLUMN},
+	{Name: "updated_at", Kind: TIME_COLUMN},
+}
+
+// Notifications are exported as a JSON object of message types to
+// channels.
+var preferencesColumns = []Column{
+	{Name: "customer_id", Kind: INT_COLUMN},
+	{Name: "language", Kind: STRING_COLUMN},
+	{Name: "currency", Kind: STRING_COLUMN},
+	{Name: "time_zone", Kind: STRING_COLUMN},
+	{Name: "notifications", Kind: STRING_COLUMN},
+	{Name: "updated_at", Kind: TIME_COLUMN},
+}
+
+func customerValues(customer models.Customer) []any {
+	var mergedInto any
+	if customer.MergedInto != nil {
+		mergedInto = int64(*customer.MergedInto)
+	}
+
+	return []any{int64(customer.Id), customer.FirstName, customer.LastName, customer.PhoneNumber,
+		customer.Email, customer.AvatarURL, customer.Status, mergedInto, customer.CreatedAt, customer.UpdatedAt}
+}
+
+func addressValues(address models.Address) []any {
+	return []any{int64(address.Id), int64(address.CustomerId), address.Lat, address.Lon,
+		address.AddressLine1, address.AddressLine2, address.City, address.Region, address.PostalCode, address.Country,
+		address.UpdatedAt}
+}
+
+func preferencesValues(preferences models.Preferences) []any {
+	notifications, _ := json.Marshal(preferences.Notifications)
+
+	return []any{int64(preferences.CustomerId), preferences.Language, preferences.Currency, preferences.TimeZone,
+		string(notifications), preferences.UpdatedAt}
+}
+
+type Report struct {
+	Table string
+	Rows  int
+}
+
+// Exporter writes the customers, addresses or preferences table in one
+// of the supported formats, applying its PII mode to every row.
+type Exporter struct {
+	store   models.CustomerExportStore
+	piiMode string
+	hashKey []byte
+}
+
+// NewExporter returns an exporter. hashKey keys the HMAC used by HASH_PII;
+// it should stay the same across exports so hashes can be joined. HASH_PII
+// is rejected without a key, as unkeyed hashes of phone numbers and emails
+// can be reversed by trying every likely value.
+func NewExporter(store models.CustomerExportStore, piiMode string, hashKey []byte) (*Exporter, error) {
+	if piiMode != KEEP_PII && piiMode != HASH_PII && piiMode != DROP_PII {
+		return nil, ErrUnknownPIIMode
+	}
+
+	if piiMode == HASH_PII && len(hashKey) == 0 {
+		return nil, ErrMissingHashKey
+	}
+
+	return &Exporter{store: store, piiMode: piiMode, hashKey: hashKey}, nil
+}
+
+// Export streams the rows of table changed at or after since to w. Rows
+// are written as they are read, so on error w holds a partial export.
+func (e *Exporter) Export(w io.Writer, table string, format string, since time.Time) (Report, error) {
+	report := Report{Table: table}
+
+	var columns []Column
+	switch table {
+	case CUSTOMERS_TABLE:
+		columns = customerColumns
+	case ADDRESSES_TABLE:
+		columns = addressColumns
+	case PREFERENCES_TABLE:
+		columns = preferencesColumns
+	default:
+		return report, ErrUnknownTable
+	}
+
+	writer, err := NewWriter(w, format, e.exportedColumns(columns))
+	if err != nil {
+		return report, err
+	}
+
+	write := func(values []any) error {
+		report.Rows++
+		return writer.WriteRow(e.applyPIIMode(columns, values))
+	}
+
+	switch table {
+	case CUSTOMERS_TABLE:
+		err = e.store.ExportCustomers(since, func(customer models.Customer) error {
+			return write(customerValues(customer))
+		})
+	case ADDRESSES_TABLE:
+		err = e.store.ExportAddresses(since, func(address models.Address) error {
+			return write(addressValues(address))
+		})
+	case PREFERENCES_TABLE:
+		err = e.store.ExportPreferences(since, func(preferences models.Preferences) error {
+			return write(preferencesValues(preferences))
+		})
+	}
+	if err != nil {
+		return report, err
+	}
+
+	return report, writer.Close()
+}
+
+func (e *Exporter) exportedColumns(columns []Column) []Column {
+	exported := []Column{}
+	for _, column := range columns {
+		if column.PII && e.piiMode == DROP_PII {
+			continue
+		}
+		if column.PII && e.piiMode == HASH_PII && column.Kind == STRING_COLUMN {
+			column.Name += "_hash"
+		}
+		exported = append(exported, column)
+	}
+	return exported
+}
+
+func (e *Exporter) applyPIIMode(columns []Column, values []any) []any {
+	if e.piiMode == KEEP_PII {
+		return values
+	}
+
+	applied := make([]any, 0, len(values))
+	for i, column := range columns {
+		switch {
+		case !column.PII:
+			applied = append(applied, values[i])
+		case e.piiMode == DROP_PII:
+		case column.Kind == FLOAT_COLUMN:
+			applied = append(applied, roundCoordinate(values[i].(float64)))
+		default:
+			applied = append(applied, e.hash(values[i].(string)))
+		}
+	}
+	return applied
+}
+
+// hash keeps empty values empty, so a missing address line doesn't look
+// like a value shared by every customer.
+func (e *Exporter) hash(value string) string {
+	if value == "" {
+		return ""
+	}
+
+	mac := hmac.New(sha256.New, e.hashKey)
+	mac.Write([]byte(value))
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+func roundCoordinate(coordinate float64) float64 {
+	scale := math.Pow10(HASHED_COORDINATE_PRECISION)
+	return math.Round(coordinate*scale) / scale
+}
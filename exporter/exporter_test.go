@@ -0,0 +1,159 @@
+package exporter_test
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/csv"
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/VitoNaychev/bt-customer-svc/exporter"
+	"github.com/VitoNaychev/bt-customer-svc/models"
+	"github.com/VitoNaychev/bt-customer-svc/testdata"
+	"github.com/VitoNaychev/bt-customer-svc/testutil"
+)
+
+var updatedAt = time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)
+
+func newStore() *testutil.StubCustomerExportStore {
+	peter, alice := testdata.PeterCustomer, testdata.AliceCustomer
+	peter.UpdatedAt = updatedAt
+	alice.UpdatedAt = updatedAt.Add(-48 * time.Hour)
+
+	address := testdata.PeterAddress1
+	address.UpdatedAt = updatedAt
+
+	preferences := testdata.AlicePreferences
+	preferences.UpdatedAt = updatedAt
+
+	return testutil.NewStubCustomerExportStore([]models.Customer{alice, peter}, []models.Address{address},
+		[]models.Preferences{preferences})
+}
+
+func export(t testing.TB, piiMode string, table string, format string, since time.Time) (*bytes.Buffer, exporter.Report) {
+	t.Helper()
+
+	tableExporter, err := exporter.NewExporter(newStore(), piiMode, []byte("key"))
+	if err != nil {
+		t.Fatalf("couldn't create exporter: %v", err)
+	}
+
+	buf := bytes.NewBuffer([]byte{})
+	report, err := tableExporter.Export(buf, table, format, since)
+	if err != nil {
+		t.Fatalf("couldn't export: %v", err)
+	}
+	return buf, report
+}
+
+func decodeJSONL(t testing.TB, buf *bytes.Buffer) []map[string]any {
+	t.Helper()
+
+	rows := []map[string]any{}
+	decoder := json.NewDecoder(buf)
+	for decoder.More() {
+		var row map[string]any
+		if err := decoder.Decode(&row); err != nil {
+			t.Fatalf("couldn't decode row: %v", err)
+		}
+		rows = append(rows, row)
+	}
+	return rows
+}
+
+func TestExport(t *testing.T) {
+	t.Run("exports customers in ID order without passwords", func(t *testing.T) {
+		buf, report := export(t, exporter.KEEP_PII, exporter.CUSTOMERS_TABLE, exporter.JSONL_FORMAT, time.Time{})
+
+		rows := decodeJSONL(t, buf)
+		testutil.AssertEqual(t, report.Rows, 2)
+		testutil.AssertEqual(t, rows[0]["email"], any(testdata.PeterCustomer.Email))
+		testutil.AssertEqual(t, rows[0]["merged_into"], nil)
+		testutil.AssertEqual(t, rows[0]["updated_at"], any("2024-03-01T12:00:00Z"))
+
+		if _, ok := rows[0]["password"]; ok {
+			t.Errorf("export contains passwords")
+		}
+	})
+
+	t.Run("exports rows updated since", func(t *testing.T) {
+		buf, _ := export(t, exporter.KEEP_PII, exporter.CUSTOMERS_TABLE, exporter.JSONL_FORMAT, updatedAt)
+
+		rows := decodeJSONL(t, buf)
+		testutil.AssertEqual(t, len(rows), 1)
+		testutil.AssertEqual(t, rows[0]["id"], any(float64(testdata.PeterCustomer.Id)))
+	})
+
+	t.Run("exports saved preferences", func(t *testing.T) {
+		buf, report := export(t, exporter.HASH_PII, exporter.PREFERENCES_TABLE, exporter.CSV_FORMAT, time.Time{})
+
+		records, err := csv.NewReader(buf).ReadAll()
+		if err != nil {
+			t.Fatalf("couldn't read CSV: %v", err)
+		}
+
+		testutil.AssertEqual(t, report.Rows, 1)
+		testutil.AssertEqual(t, records, [][]string{
+			{"customer_id", "language", "currency", "time_zone", "notifications", "updated_at"},
+			{"2", "en-GB", "EUR", "Europe/London", `{"account":["email"],"order_updates":["sms"],"promotions":["email"]}`,
+				"2024-03-01T12:00:00Z"},
+		})
+	})
+
+	t.Run("hashes PII and coarsens coordinates", func(t *testing.T) {
+		buf, _ := export(t, exporter.HASH_PII, exporter.ADDRESSES_TABLE, exporter.JSONL_FORMAT, time.Time{})
+		again, _ := export(t, exporter.HASH_PII, exporter.ADDRESSES_TABLE, exporter.JSONL_FORMAT, time.Time{})
+
+		row := decodeJSONL(t, buf)[0]
+		hash := row["address_line1_hash"].(string)
+
+		testutil.AssertEqual(t, len(hash), 64)
+		testutil.AssertEqual(t, decodeJSONL(t, again)[0]["address_line1_hash"], any(hash))
+		testutil.AssertEqual(t, row["address_line2_hash"], any(""))
+		testutil.AssertEqual(t, row["lat"], any(42.7))
+		testutil.AssertEqual(t, row["city"], any(testdata.PeterAddress1.City))
+	})
+
+	t.Run("drops PII columns", func(t *testing.T) {
+		buf, _ := export(t, exporter.DROP_PII, exporter.CUSTOMERS_TABLE, exporter.CSV_FORMAT, time.Time{})
+
+		records, err := csv.NewReader(buf).ReadAll()
+		if err != nil {
+			t.Fatalf("couldn't read CSV: %v", err)
+		}
+
+		testutil.AssertEqual(t, records[0], []string{"id", "status", "merged_into", "created_at", "updated_at"})
+		testutil.AssertEqual(t, len(records), 3)
+	})
+
+	t.Run("writes Parquet file with footer", func(t *testing.T) {
+		buf, _ := export(t, exporter.KEEP_PII, exporter.CUSTOMERS_TABLE, exporter.PARQUET_FORMAT, time.Time{})
+		data := buf.Bytes()
+
+		testutil.AssertEqual(t, string(data[:4]), "PAR1")
+		testutil.AssertEqual(t, string(data[len(data)-4:]), "PAR1")
+
+		metadataLength := int(binary.LittleEndian.Uint32(data[len(data)-8:]))
+		metadata := string(data[len(data)-8-metadataLength : len(data)-8])
+
+		if !strings.Contains(metadata, "phone_number") || !strings.Contains(metadata, "bt-customer-svc") {
+			t.Errorf("Parquet metadata doesn't describe the columns")
+		}
+	})
+
+	t.Run("rejects unknown PII mode and format", func(t *testing.T) {
+		_, err := exporter.NewExporter(newStore(), "mask", nil)
+		testutil.AssertEqual(t, err, exporter.ErrUnknownPIIMode)
+
+		tableExporter, _ := exporter.NewExporter(newStore(), exporter.KEEP_PII, nil)
+		_, err = tableExporter.Export(bytes.NewBuffer([]byte{}), exporter.CUSTOMERS_TABLE, "xml", time.Time{})
+		testutil.AssertEqual(t, err, exporter.ErrUnknownFormat)
+	})
+
+	t.Run("rejects hashing PII without a key", func(t *testing.T) {
+		_, err := exporter.NewExporter(newStore(), exporter.HASH_PII, nil)
+		testutil.AssertEqual(t, err, exporter.ErrMissingHashKey)
+	})
+}
@@ -0,0 +1,359 @@
+package exporter
+
+import (
+	"bytes"
+	"encoding/binary"
+	"io"
+	"math"
+	"time"
+)
+
+// PARQUET_ROW_GROUP_SIZE is how many rows the Parquet writer buffers
+// before it writes them out as a row group.
+const PARQUET_ROW_GROUP_SIZE = 10000
+
+// The subset of the Parquet format the writer produces: one uncompressed,
+// PLAIN encoded data page per column chunk, and only optional flat columns,
+// whose definition levels are RLE encoded.
+const (
+	parquetMagic = "PAR1"
+
+	parquetInt64     = 2
+	parquetDouble    = 5
+	parquetByteArray = 6
+
+	parquetOptional = 1
+
+	parquetUTF8            = 0
+	parquetTimestampMicros = 10
+
+	parquetPlain = 0
+	parquetRLE   = 3
+
+	parquetUncompressed = 0
+	parquetDataPage     = 0
+)
+
+type parquetColumn struct {
+	name          string
+	physicalType  int32
+	convertedType *int32
+	// definitions holds a byte per value: 1 if it is set and 0 if null
+	definitions []byte
+	values      bytes.Buffer
+}
+
+type parquetColumnChunk struct {
+	column     *parquetColumn
+	offset     int64
+	size       int64
+	valueCount int64
+}
+
+type parquetRowGroup struct {
+	chunks []parquetColumnChunk
+	size   int64
+	rows   int64
+}
+
+// parquetWriter buffers a row group at a time, so its memory is bounded by
+// PARQUET_ROW_GROUP_SIZE, and writes the file metadata on Close.
+type parquetWriter struct {
+	w         io.Writer
+	offset    int64
+	columns   []*parquetColumn
+	rows      int64
+	totalRows int64
+	rowGroups []parquetRowGroup
+}
+
+func newParquetWriter(w io.Writer, columns []Column) (*parquetWriter, error) {
+	utf8, timestampMicros := int32(parquetUTF8), int32(parquetTimestampMicros)
+
+	parquetColumns := make([]*parquetColumn, len(columns))
+	for i, column := range columns {
+		parquetColumns[i] = &parquetColumn{name: column.Name}
+		switch column.Kind {
+		case STRING_COLUMN:
+			parquetColumns[i].physicalType = parquetByteArray
+			parquetColumns[i].convertedType = &utf8
+		case INT_COLUMN:
+			parquetColumns[i].physicalType = parquetInt64
+		case FLOAT_COLUMN:
+			parquetColumns[i].physicalType = parquetDouble
+		case TIME_COLUMN:
+			parquetColumns[i].physicalType = parquetInt64
+			parquetColumns[i].convertedType = &timestampMicros
+		}
+	}
+
+	writer := &parquetWriter{w: w, columns: parquetColumns}
+	if err := writer.write([]byte(parquetMagic)); err != nil {
+		return nil, err
+	}
+	return writer, nil
+}
+
+func (p *parquetWriter) write(data []byte) error {
+	n, err := p.w.Write(data)
+	p.offset += int64(n)
+	return err
+}
+
+func (p *parquetWriter) WriteRow(values []any) error {
+	for i, value := range values {
+		column := p.columns[i]
+		if value == nil {
+			column.definitions = append(column.definitions, 0)
+			continue
+		}
+		column.definitions = append(column.definitions, 1)
+
+		switch value := value.(type) {
+		case string:
+			binary.Write(&column.values, binary.LittleEndian, uint32(len(value)))
+			column.values.WriteString(value)
+		case int64:
+			binary.Write(&column.values, binary.LittleEndian, value)
+		case float64:
+			binary.Write(&column.values, binary.LittleEndian, math.Float64bits(value))
+		case time.Time:
+			binary.Write(&column.values, binary.LittleEndian, value.UnixMicro())
+		}
+	}
+
+	p.rows++
+	if p.rows >= PARQUET_ROW_GROUP_SIZE {
+		return p.flushRowGroup()
+	}
+	return nil
+}
+
+func (p *parquetWriter) flushRowGroup() error {
+	rowGroup := parquetRowGroup{rows: p.rows}
+
+	for _, column := range p.columns {
+		page := bytes.Buffer{}
+		levels := encodeRLE(column.definitions)
+		binary.Write(&page, binary.LittleEndian, uint32(len(levels)))
+		page.Write(levels)
+		page.Write(column.values.Bytes())
+
+		header := thriftWriter{}
+		header.i32Field(1, parquetDataPage)
+		header.i32Field(2, int32(page.Len()))
+		header.i32Field(3, int32(page.Len()))
+		header.structBegin(5)
+		header.i32Field(1, int32(len(column.definitions)))
+		header.i32Field(2, parquetPlain)
+		header.i32Field(3, parquetRLE)
+		header.i32Field(4, parquetRLE)
+		header.structEnd()
+		header.stop()
+
+		chunk := parquetColumnChunk{
+			column:     column,
+			offset:     p.offset,
+			size:       int64(header.buf.Len() + page.Len()),
+			valueCount: int64(len(column.definitions)),
+		}
+		if err := p.write(header.buf.Bytes()); err != nil {
+			return err
+		}
+		if err := p.write(page.Bytes()); err != nil {
+			return err
+		}
+
+		rowGroup.chunks = append(rowGroup.chunks, chunk)
+		rowGroup.size += chunk.size
+
+		column.definitions = column.definitions[:0]
+		column.values.Reset()
+	}
+
+	p.rowGroups = append(p.rowGroups, rowGroup)
+	p.totalRows += p.rows
+	p.rows = 0
+	return nil
+}
+
+func (p *parquetWriter) Close() error {
+	if p.rows > 0 {
+		if err := p.flushRowGroup(); err != nil {
+			return err
+		}
+	}
+
+	metadata := p.fileMetadata()
+	if err := p.write(metadata); err != nil {
+		return err
+	}
+
+	footer := binary.LittleEndian.AppendUint32(nil, uint32(len(metadata)))
+	return p.write(append(footer, parquetMagic...))
+}
+
+// fileMetadata encodes the FileMetaData struct of the footer.
+func (p *parquetWriter) fileMetadata() []byte {
+	metadata := thriftWriter{}
+	metadata.i32Field(1, 1)
+
+	metadata.listBegin(2, thriftStruct, len(p.columns)+1)
+	metadata.elementBegin()
+	metadata.stringField(4, "schema")
+	metadata.i32Field(5, int32(len(p.columns)))
+	metadata.structEnd()
+	for _, column := range p.columns {
+		metadata.elementBegin()
+		metadata.i32Field(1, column.physicalType)
+		metadata.i32Field(3, parquetOptional)
+		metadata.stringField(4, column.name)
+		if column.convertedType != nil {
+			metadata.i32Field(6, *column.convertedType)
+		}
+		metadata.structEnd()
+	}
+
+	metadata.i64Field(3, p.totalRows)
+
+	metadata.listBegin(4, thriftStruct, len(p.rowGroups))
+	for _, rowGroup := range p.rowGroups {
+		metadata.elementBegin()
+		metadata.listBegin(1, thriftStruct, len(rowGroup.chunks))
+		for _, chunk := range rowGroup.chunks {
+			metadata.elementBegin()
+			metadata.i64Field(2, chunk.offset)
+			metadata.structBegin(3)
+			metadata.i32Field(1, chunk.column.physicalType)
+			metadata.listBegin(2, thriftI32, 2)
+			metadata.i32(parquetPlain)
+			metadata.i32(parquetRLE)
+			metadata.listBegin(3, thriftBinary, 1)
+			metadata.string(chunk.column.name)
+			metadata.i32Field(4, parquetUncompressed)
+			metadata.i64Field(5, chunk.valueCount)
+			metadata.i64Field(6, chunk.size)
+			metadata.i64Field(7, chunk.size)
+			metadata.i64Field(9, chunk.offset)
+			metadata.structEnd()
+			metadata.structEnd()
+		}
+		metadata.i64Field(2, rowGroup.size)
+		metadata.i64Field(3, rowGroup.rows)
+		metadata.structEnd()
+	}
+
+	metadata.stringField(6, "bt-customer-svc")
+	metadata.stop()
+
+	return metadata.buf.Bytes()
+}
+
+// encodeRLE encodes definition levels of bit width 1 as runs of the RLE/
+// bit-packing hybrid encoding.
+func encodeRLE(levels []byte) []byte {
+	encoded := []byte{}
+	for start := 0; start < len(levels); {
+		end := start
+		for end < len(levels) && levels[end] == levels[start] {
+			end++
+		}
+
+		encoded = binary.AppendUvarint(encoded, uint64(end-start)<<1)
+		encoded = append(encoded, levels[start])
+		start = end
+	}
+	return encoded
+}
+
+// Field types of the Thrift compact protocol Parquet metadata is encoded in.
+const (
+	thriftI32    = 5
+	thriftI64    = 6
+	thriftBinary = 8
+	thriftList   = 9
+	thriftStruct = 12
+)
+
+// thriftWriter encodes structs in the Thrift compact protocol. Field IDs
+// are written as deltas to the previous field of the same struct, so the
+// last field ID of every enclosing struct is kept on a stack.
+type thriftWriter struct {
+	buf       bytes.Buffer
+	lastField int16
+	stack     []int16
+}
+
+func (t *thriftWriter) fieldHeader(id int16, fieldType byte) {
+	if delta := id - t.lastField; delta > 0 && delta <= 15 {
+		t.buf.WriteByte(byte(delta)<<4 | fieldType)
+	} else {
+		t.buf.WriteByte(fieldType)
+		t.varint(uint64(zigzag(int64(id))))
+	}
+	t.lastField = id
+}
+
+func (t *thriftWriter) varint(value uint64) {
+	t.buf.Write(binary.AppendUvarint(nil, value))
+}
+
+func zigzag(value int64) uint64 {
+	return uint64((value << 1) ^ (value >> 63))
+}
+
+func (t *thriftWriter) i32(value int32) {
+	t.varint(zigzag(int64(value)))
+}
+
+func (t *thriftWriter) string(value string) {
+	t.varint(uint64(len(value)))
+	t.buf.WriteString(value)
+}
+
+func (t *thriftWriter) i32Field(id int16, value int32) {
+	t.fieldHeader(id, thriftI32)
+	t.i32(value)
+}
+
+func (t *thriftWriter) i64Field(id int16, value int64) {
+	t.fieldHeader(id, thriftI64)
+	t.varint(zigzag(value))
+}
+
+func (t *thriftWriter) stringField(id int16, value string) {
+	t.fieldHeader(id, thriftBinary)
+	t.string(value)
+}
+
+func (t *thriftWriter) listBegin(id int16, elementType byte, size int) {
+	t.fieldHeader(id, thriftList)
+	if size < 15 {
+		t.buf.WriteByte(byte(size)<<4 | elementType)
+	} else {
+		t.buf.WriteByte(0xf0 | elementType)
+		t.varint(uint64(size))
+	}
+}
+
+func (t *thriftWriter) structBegin(id int16) {
+	t.fieldHeader(id, thriftStruct)
+	t.elementBegin()
+}
+
+// elementBegin starts a struct that is an element of a list, which has no
+// field header.
+func (t *thriftWriter) elementBegin() {
+	t.stack = append(t.stack, t.lastField)
+	t.lastField = 0
+}
+
+func (t *thriftWriter) structEnd() {
+	t.stop()
+	t.lastField = t.stack[len(t.stack)-1]
+	t.stack = t.stack[:len(t.stack)-1]
+}
+
+func (t *thriftWriter) stop() {
+	t.buf.WriteByte(0)
+}
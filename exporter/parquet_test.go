@@ -0,0 +1,382 @@
+package exporter_test
+
+import (
+	"bytes"
+	"encoding/binary"
+	"math"
+	"testing"
+	"time"
+
+	"github.com/VitoNaychev/bt-customer-svc/exporter"
+	"github.com/VitoNaychev/bt-customer-svc/models"
+	"github.com/VitoNaychev/bt-customer-svc/testdata"
+	"github.com/VitoNaychev/bt-customer-svc/testutil"
+)
+
+func TestParquetExport(t *testing.T) {
+	t.Run("reads back the schema and rows", func(t *testing.T) {
+		peter, alice := testdata.PeterCustomer, testdata.AliceCustomer
+		peter.UpdatedAt = updatedAt
+		alice.CreatedAt = updatedAt.Add(-48 * time.Hour)
+		alice.UpdatedAt = updatedAt
+		alice.Status = models.MERGED_STATUS
+		alice.MergedInto = &peter.Id
+		store := testutil.NewStubCustomerExportStore([]models.Customer{alice, peter}, nil, nil)
+
+		file := readParquet(t, exportParquet(t, store, exporter.CUSTOMERS_TABLE))
+
+		testutil.AssertEqual(t, file.columns, []parquetSchemaColumn{
+			{"id", parquetInt64, -1},
+			{"first_name", parquetByteArray, parquetUTF8},
+			{"last_name", parquetByteArray, parquetUTF8},
+			{"phone_number", parquetByteArray, parquetUTF8},
+			{"email", parquetByteArray, parquetUTF8},
+			{"avatar_url", parquetByteArray, parquetUTF8},
+			{"status", parquetByteArray, parquetUTF8},
+			{"merged_into", parquetInt64, -1},
+			{"created_at", parquetInt64, parquetTimestampMicros},
+			{"updated_at", parquetInt64, parquetTimestampMicros},
+		})
+		testutil.AssertEqual(t, file.numRows, 2)
+		testutil.AssertEqual(t, file.rows, [][]any{
+			{int64(peter.Id), peter.FirstName, peter.LastName, peter.PhoneNumber, peter.Email, peter.AvatarURL,
+				peter.Status, nil, peter.CreatedAt, peter.UpdatedAt},
+			{int64(alice.Id), alice.FirstName, alice.LastName, alice.PhoneNumber, alice.Email, alice.AvatarURL,
+				alice.Status, int64(peter.Id), alice.CreatedAt, alice.UpdatedAt},
+		})
+	})
+
+	t.Run("reads back doubles", func(t *testing.T) {
+		address := testdata.PeterAddress1
+		address.UpdatedAt = updatedAt
+		store := testutil.NewStubCustomerExportStore(nil, []models.Address{address}, nil)
+
+		file := readParquet(t, exportParquet(t, store, exporter.ADDRESSES_TABLE))
+
+		testutil.AssertEqual(t, file.rows, [][]any{
+			{int64(address.Id), int64(address.CustomerId), address.Lat, address.Lon, address.AddressLine1,
+				address.AddressLine2, address.City, address.Region, address.PostalCode, address.Country,
+				address.UpdatedAt},
+		})
+	})
+
+	t.Run("splits rows into row groups", func(t *testing.T) {
+		customers := make([]models.Customer, exporter.PARQUET_ROW_GROUP_SIZE+1)
+		for i := range customers {
+			customers[i] = testdata.PeterCustomer
+			customers[i].Id = i + 1
+		}
+		store := testutil.NewStubCustomerExportStore(customers, nil, nil)
+
+		file := readParquet(t, exportParquet(t, store, exporter.CUSTOMERS_TABLE))
+
+		testutil.AssertEqual(t, file.rowGroupRows, []int64{exporter.PARQUET_ROW_GROUP_SIZE, 1})
+		testutil.AssertEqual(t, file.numRows, int64(len(customers)))
+		for i, row := range file.rows {
+			if row[0] != int64(i+1) {
+				t.Fatalf("got ID %v in row %d, want %d", row[0], i, i+1)
+			}
+		}
+	})
+}
+
+func exportParquet(t testing.TB, store models.CustomerExportStore, table string) []byte {
+	t.Helper()
+
+	tableExporter, err := exporter.NewExporter(store, exporter.KEEP_PII, nil)
+	if err != nil {
+		t.Fatalf("couldn't create exporter: %v", err)
+	}
+
+	buf := bytes.NewBuffer([]byte{})
+	if _, err = tableExporter.Export(buf, table, exporter.PARQUET_FORMAT, time.Time{}); err != nil {
+		t.Fatalf("couldn't export: %v", err)
+	}
+	return buf.Bytes()
+}
+
+// The Parquet types and encodings the exporter uses, as numbered by the
+// parquet-format Thrift definitions.
+const (
+	parquetInt64     = 2
+	parquetDouble    = 5
+	parquetByteArray = 6
+
+	parquetUTF8            = 0
+	parquetTimestampMicros = 10
+
+	parquetPlain = 0
+	parquetRLE   = 3
+)
+
+type parquetSchemaColumn struct {
+	name          string
+	physicalType  int64
+	convertedType int64
+}
+
+type parquetFile struct {
+	columns      []parquetSchemaColumn
+	numRows      int64
+	rowGroupRows []int64
+	rows         [][]any
+}
+
+// readParquet decodes a Parquet file independently of the writer, from the
+// footer to the pages of every column chunk, and fails on anything that
+// doesn't add up, like a chunk whose offsets don't point at its page.
+func readParquet(t testing.TB, data []byte) parquetFile {
+	t.Helper()
+
+	if len(data) < 12 || string(data[:4]) != "PAR1" || string(data[len(data)-4:]) != "PAR1" {
+		t.Fatal("file doesn't start and end with PAR1")
+	}
+	metadataLength := int(binary.LittleEndian.Uint32(data[len(data)-8:]))
+	metadataStart := len(data) - 8 - metadataLength
+
+	reader := &thriftReader{t: t, data: data[:len(data)-8], pos: metadataStart}
+	metadata := reader.readStruct()
+	if reader.pos != len(data)-8 {
+		t.Fatalf("file metadata ends at %d, want %d", reader.pos, len(data)-8)
+	}
+
+	file := parquetFile{numRows: metadata[3].(int64)}
+
+	schema := metadata[2].([]any)
+	root := schema[0].(map[int16]any)
+	if root[5] != int64(len(schema)-1) {
+		t.Fatalf("schema root has %v children, want %d", root[5], len(schema)-1)
+	}
+	for _, element := range schema[1:] {
+		column := element.(map[int16]any)
+		convertedType, ok := column[6].(int64)
+		if !ok {
+			convertedType = -1
+		}
+		file.columns = append(file.columns, parquetSchemaColumn{
+			name:          string(column[4].([]byte)),
+			physicalType:  column[1].(int64),
+			convertedType: convertedType,
+		})
+	}
+
+	offset := int64(4)
+	for _, element := range metadata[4].([]any) {
+		rowGroup := element.(map[int16]any)
+		rows := rowGroup[3].(int64)
+		file.rowGroupRows = append(file.rowGroupRows, rows)
+
+		values := make([][]any, rows)
+		for i := range values {
+			values[i] = make([]any, len(file.columns))
+		}
+
+		var rowGroupSize int64
+		for i, element := range rowGroup[1].([]any) {
+			chunk := element.(map[int16]any)
+			chunkMetadata := chunk[3].(map[int16]any)
+			column := file.columns[i]
+
+			testutil.AssertEqual(t, chunkMetadata[1], any(column.physicalType))
+			testutil.AssertEqual(t, chunkMetadata[3], any([]any{[]byte(column.name)}))
+			testutil.AssertEqual(t, chunkMetadata[4], any(int64(0)))
+			testutil.AssertEqual(t, chunkMetadata[5], any(rows))
+			testutil.AssertEqual(t, chunkMetadata[6], chunkMetadata[7])
+			testutil.AssertEqual(t, chunk[2], chunkMetadata[9])
+
+			pageOffset := chunkMetadata[9].(int64)
+			if pageOffset != offset {
+				t.Fatalf("column chunk %q starts at %d, want %d", column.name, pageOffset, offset)
+			}
+
+			size := chunkMetadata[7].(int64)
+			readColumnChunk(t, data[pageOffset:pageOffset+size], column, values, i)
+
+			offset += size
+			rowGroupSize += size
+		}
+		testutil.AssertEqual(t, rowGroup[2], any(rowGroupSize))
+
+		file.rows = append(file.rows, values...)
+	}
+
+	if offset != int64(metadataStart) {
+		t.Fatalf("column chunks end at %d, but the file metadata starts at %d", offset, metadataStart)
+	}
+	testutil.AssertEqual(t, int64(len(file.rows)), file.numRows)
+
+	return file
+}
+
+// readColumnChunk decodes the single data page of a column chunk into
+// column i of rows.
+func readColumnChunk(t testing.TB, chunk []byte, column parquetSchemaColumn, rows [][]any, i int) {
+	t.Helper()
+
+	reader := &thriftReader{t: t, data: chunk}
+	header := reader.readStruct()
+	testutil.AssertEqual(t, header[1], any(int64(0)))
+	testutil.AssertEqual(t, header[2], header[3])
+
+	dataPageHeader := header[5].(map[int16]any)
+	testutil.AssertEqual(t, dataPageHeader[1], any(int64(len(rows))))
+	testutil.AssertEqual(t, dataPageHeader[2], any(int64(parquetPlain)))
+	testutil.AssertEqual(t, dataPageHeader[3], any(int64(parquetRLE)))
+
+	page := chunk[reader.pos:]
+	if int64(len(page)) != header[3].(int64) {
+		t.Fatalf("page of %q is %d bytes, want %d", column.name, len(page), header[3])
+	}
+
+	levelsLength := binary.LittleEndian.Uint32(page)
+	definitions := decodeDefinitionLevels(t, page[4:4+levelsLength], len(rows))
+	values := page[4+levelsLength:]
+
+	for row, defined := range definitions {
+		if !defined {
+			continue
+		}
+
+		switch column.physicalType {
+		case parquetInt64:
+			value := int64(binary.LittleEndian.Uint64(values))
+			if column.convertedType == parquetTimestampMicros {
+				rows[row][i] = time.UnixMicro(value).UTC()
+			} else {
+				rows[row][i] = value
+			}
+			values = values[8:]
+		case parquetDouble:
+			rows[row][i] = math.Float64frombits(binary.LittleEndian.Uint64(values))
+			values = values[8:]
+		case parquetByteArray:
+			length := binary.LittleEndian.Uint32(values)
+			rows[row][i] = string(values[4 : 4+length])
+			values = values[4+length:]
+		}
+	}
+
+	if len(values) != 0 {
+		t.Fatalf("page of %q has %d bytes left after its values", column.name, len(values))
+	}
+}
+
+// decodeDefinitionLevels decodes count levels of bit width 1 from the
+// RLE/bit-packing hybrid encoding.
+func decodeDefinitionLevels(t testing.TB, data []byte, count int) []bool {
+	t.Helper()
+
+	levels := []bool{}
+	for len(data) > 0 {
+		header, n := binary.Uvarint(data)
+		data = data[n:]
+
+		if header&1 == 0 {
+			for j := uint64(0); j < header>>1; j++ {
+				levels = append(levels, data[0] == 1)
+			}
+			data = data[1:]
+		} else {
+			groups := int(header >> 1)
+			for _, packed := range data[:groups] {
+				for bit := 0; bit < 8; bit++ {
+					levels = append(levels, packed>>bit&1 == 1)
+				}
+			}
+			data = data[groups:]
+		}
+	}
+
+	if len(levels) < count {
+		t.Fatalf("got %d definition levels, want %d", len(levels), count)
+	}
+	return levels[:count]
+}
+
+// thriftReader decodes the Thrift compact protocol into maps of field IDs
+// to values. Integers are read as int64, binaries as []byte and lists as
+// []any.
+type thriftReader struct {
+	t    testing.TB
+	data []byte
+	pos  int
+}
+
+func (r *thriftReader) byte() byte {
+	if r.pos >= len(r.data) {
+		r.t.Fatalf("Thrift data ends at %d", r.pos)
+	}
+	r.pos++
+	return r.data[r.pos-1]
+}
+
+func (r *thriftReader) varint() uint64 {
+	value, n := binary.Uvarint(r.data[r.pos:])
+	if n <= 0 {
+		r.t.Fatalf("invalid varint at %d", r.pos)
+	}
+	r.pos += n
+	return value
+}
+
+func (r *thriftReader) zigzag() int64 {
+	value := r.varint()
+	return int64(value>>1) ^ -int64(value&1)
+}
+
+func (r *thriftReader) readStruct() map[int16]any {
+	fields := map[int16]any{}
+
+	var lastField int16
+	for {
+		header := r.byte()
+		if header == 0 {
+			return fields
+		}
+
+		id := lastField + int16(header>>4)
+		if header>>4 == 0 {
+			id = int16(r.zigzag())
+		}
+		lastField = id
+
+		fields[id] = r.readValue(header & 0x0f)
+	}
+}
+
+func (r *thriftReader) readValue(valueType byte) any {
+	switch valueType {
+	case 1, 2:
+		return valueType == 1
+	case 3:
+		return int64(int8(r.byte()))
+	case 4, 5, 6:
+		return r.zigzag()
+	case 7:
+		value := math.Float64frombits(binary.LittleEndian.Uint64(r.data[r.pos:]))
+		r.pos += 8
+		return value
+	case 8:
+		length := int(r.varint())
+		value := r.data[r.pos : r.pos+length]
+		r.pos += length
+		return value
+	case 9:
+		header := r.byte()
+		size := int(header >> 4)
+		if size == 15 {
+			size = int(r.varint())
+		}
+
+		elements := make([]any, size)
+		for i := range elements {
+			elements[i] = r.readValue(header & 0x0f)
+		}
+		return elements
+	case 12:
+		return r.readStruct()
+	}
+
+	r.t.Fatalf("unknown Thrift type %d at %d", valueType, r.pos)
+	return nil
+}
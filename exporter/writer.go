@@ -0,0 +1,136 @@
+package exporter
+
+import (
+	"bufio"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"io"
+	"strconv"
+	"time"
+)
+
+const (
+	JSONL_FORMAT   = "jsonl"
+	CSV_FORMAT     = "csv"
+	PARQUET_FORMAT = "parquet"
+)
+
+var ErrUnknownFormat = errors.New("export format must be jsonl, csv or parquet")
+
+var contentTypes = map[string]string{
+	JSONL_FORMAT:   "application/x-ndjson",
+	CSV_FORMAT:     "text/csv",
+	PARQUET_FORMAT: "application/vnd.apache.parquet",
+}
+
+// ContentType returns the media type of an export format.
+func ContentType(format string) (string, error) {
+	contentType, ok := contentTypes[format]
+	if !ok {
+		return "", ErrUnknownFormat
+	}
+	return contentType, nil
+}
+
+// Writer encodes export rows. Close flushes buffered rows but doesn't
+// close the underlying writer.
+type Writer interface {
+	WriteRow(values []any) error
+	Close() error
+}
+
+func NewWriter(w io.Writer, format string, columns []Column) (Writer, error) {
+	switch format {
+	case JSONL_FORMAT:
+		return newJSONLWriter(w, columns), nil
+	case CSV_FORMAT:
+		return newCSVWriter(w, columns)
+	case PARQUET_FORMAT:
+		return newParquetWriter(w, columns)
+	default:
+		return nil, ErrUnknownFormat
+	}
+}
+
+// jsonlWriter writes every row as an object with the columns as keys, in
+// column order.
+type jsonlWriter struct {
+	w       *bufio.Writer
+	columns []Column
+}
+
+func newJSONLWriter(w io.Writer, columns []Column) *jsonlWriter {
+	return &jsonlWriter{w: bufio.NewWriter(w), columns: columns}
+}
+
+func (j *jsonlWriter) WriteRow(values []any) error {
+	j.w.WriteByte('{')
+	for i, column := range j.columns {
+		if i > 0 {
+			j.w.WriteByte(',')
+		}
+
+		key, _ := json.Marshal(column.Name)
+		value, err := json.Marshal(values[i])
+		if err != nil {
+			return err
+		}
+
+		j.w.Write(key)
+		j.w.WriteByte(':')
+		j.w.Write(value)
+	}
+	j.w.WriteString("}\n")
+
+	return nil
+}
+
+func (j *jsonlWriter) Close() error {
+	return j.w.Flush()
+}
+
+// csvWriter writes a header row followed by the rows. Nil values are
+// written as empty fields and times in RFC 3339.
+type csvWriter struct {
+	w      *csv.Writer
+	fields []string
+}
+
+func newCSVWriter(w io.Writer, columns []Column) (*csvWriter, error) {
+	writer := csv.NewWriter(w)
+
+	header := make([]string, len(columns))
+	for i, column := range columns {
+		header[i] = column.Name
+	}
+	if err := writer.Write(header); err != nil {
+		return nil, err
+	}
+
+	return &csvWriter{w: writer, fields: make([]string, len(columns))}, nil
+}
+
+func (c *csvWriter) WriteRow(values []any) error {
+	for i, value := range values {
+		switch value := value.(type) {
+		case nil:
+			c.fields[i] = ""
+		case string:
+			c.fields[i] = value
+		case int64:
+			c.fields[i] = strconv.FormatInt(value, 10)
+		case float64:
+			c.fields[i] = strconv.FormatFloat(value, 'f', -1, 64)
+		case time.Time:
+			c.fields[i] = value.UTC().Format(time.RFC3339Nano)
+		}
+	}
+
+	return c.w.Write(c.fields)
+}
+
+func (c *csvWriter) Close() error {
+	c.w.Flush()
+	return c.w.Error()
+}
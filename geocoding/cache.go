@@ -0,0 +1,80 @@
+package geocoding
+
+import (
+	"container/list"
+	"errors"
+	"sync"
+)
+
+const DEFAULT_CACHE_SIZE = 10000
+
+type cacheEntry struct {
+	key      string
+	location Location
+	err      error
+}
+
+// CachingGeocoder remembers the results of the last size distinct queries
+// of another geocoder, evicting the least recently used one. Addresses the
+// geocoder doesn't know are cached too; other errors are not, so failed
+// lookups are retried.
+type CachingGeocoder struct {
+	geocoder Geocoder
+	size     int
+
+	mu      sync.Mutex
+	entries map[string]*list.Element
+	recency *list.List
+}
+
+func NewCachingGeocoder(geocoder Geocoder, size int) *CachingGeocoder {
+	if size < 1 {
+		size = DEFAULT_CACHE_SIZE
+	}
+
+	return &CachingGeocoder{
+		geocoder: geocoder,
+		size:     size,
+		entries:  map[string]*list.Element{},
+		recency:  list.New(),
+	}
+}
+
+func (c *CachingGeocoder) Geocode(query Query) (Location, error) {
+	key := gazetteerKey(query.Country, query.City, normalize(query.AddressLine1))
+
+	c.mu.Lock()
+	if element, ok := c.entries[key]; ok {
+		c.recency.MoveToFront(element)
+		entry := element.Value.(*cacheEntry)
+		c.mu.Unlock()
+		return entry.location, entry.err
+	}
+	c.mu.Unlock()
+
+	location, err := c.geocoder.Geocode(query)
+	if err != nil && !errors.Is(err, ErrNotFound) {
+		return location, err
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if _, ok := c.entries[key]; !ok {
+		c.entries[key] = c.recency.PushFront(&cacheEntry{key: key, location: location, err: err})
+		if c.recency.Len() > c.size {
+			oldest := c.recency.Back()
+			c.recency.Remove(oldest)
+			delete(c.entries, oldest.Value.(*cacheEntry).key)
+		}
+	}
+
+	return location, err
+}
+
+func (c *CachingGeocoder) Len() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return c.recency.Len()
+}
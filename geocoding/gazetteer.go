@@ -0,0 +1,170 @@
+package geocoding
+
+import (
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"github.com/VitoNaychev/bt-customer-svc/models"
+)
+
+var ErrInvalidGazetteer = errors.New("gazetteer file is invalid")
+
+var gazetteerColumns = []string{"country", "city", "street", "lat", "lon"}
+
+// Gazetteer geocodes offline from a CSV file with the columns country,
+// city, street, lat and lon. A row with an empty street is the center of
+// its city, a street with a house number locates a single address and a
+// street without one locates the whole street. Lookups ignore case,
+// punctuation and spacing and fall back from the address to its street
+// and then to its city. Reverse lookups search a grid of the places for
+// the nearest one.
+type Gazetteer struct {
+	locations map[string]Location
+	places    []Place
+	streets   *grid
+	cities    *grid
+}
+
+func LoadGazetteer(path string) (*Gazetteer, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	return NewGazetteer(file)
+}
+
+func NewGazetteer(r io.Reader) (*Gazetteer, error) {
+	reader := csv.NewReader(r)
+	reader.Comment = '#'
+	reader.FieldsPerRecord = len(gazetteerColumns)
+	reader.TrimLeadingSpace = true
+
+	header, err := reader.Read()
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidGazetteer, err)
+	}
+	for i, column := range gazetteerColumns {
+		if strings.ToLower(strings.TrimSpace(header[i])) != column {
+			return nil, fmt.Errorf("%w: expected columns %s", ErrInvalidGazetteer, strings.Join(gazetteerColumns, ","))
+		}
+	}
+
+	g := &Gazetteer{locations: map[string]Location{}, streets: newGrid(), cities: newGrid()}
+	for {
+		fields, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("%w: %v", ErrInvalidGazetteer, err)
+		}
+
+		line, _ := reader.FieldPos(0)
+		lat, latErr := strconv.ParseFloat(strings.TrimSpace(fields[3]), 64)
+		lon, lonErr := strconv.ParseFloat(strings.TrimSpace(fields[4]), 64)
+		if latErr != nil || lonErr != nil || lat < -90 || lat > 90 || lon < -180 || lon > 180 {
+			return nil, fmt.Errorf("%w: line %d has invalid coordinates", ErrInvalidGazetteer, line)
+		}
+
+		street := normalize(fields[2])
+		precision := STREET_PRECISION
+		if street == "" {
+			precision = CITY_PRECISION
+		} else if street != streetName(street) {
+			precision = ADDRESS_PRECISION
+		}
+
+		country := countryCode(fields[0])
+		location := Location{Lat: lat, Lon: lon, Precision: precision}
+		g.locations[gazetteerKey(country, fields[1], street)] = location
+
+		g.places = append(g.places, Place{
+			AddressLine1: strings.TrimSpace(fields[2]),
+			City:         strings.TrimSpace(fields[1]),
+			Country:      country,
+			Location:     location,
+		})
+		if precision == CITY_PRECISION {
+			g.cities.add(lat, lon, len(g.places)-1)
+		} else {
+			g.streets.add(lat, lon, len(g.places)-1)
+		}
+	}
+
+	return g, nil
+}
+
+func (g *Gazetteer) Geocode(query Query) (Location, error) {
+	line := normalize(query.AddressLine1)
+
+	for _, street := range []string{line, streetName(line), ""} {
+		if location, ok := g.locations[gazetteerKey(countryCode(query.Country), query.City, street)]; ok {
+			return location, nil
+		}
+	}
+
+	return Location{}, ErrNotFound
+}
+
+// Reverse suggests the address or street nearest to the given point, or
+// the nearest city when no street is close enough.
+func (g *Gazetteer) Reverse(lat, lon float64) (Place, error) {
+	if i, distance, ok := g.streets.nearest(lat, lon, MAX_STREET_DISTANCE); ok {
+		place := g.places[i]
+		place.Distance = distance
+		return place, nil
+	}
+
+	if i, distance, ok := g.cities.nearest(lat, lon, MAX_CITY_DISTANCE); ok {
+		place := g.places[i]
+		place.Distance = distance
+		return place, nil
+	}
+
+	return Place{}, ErrNotFound
+}
+
+func (g *Gazetteer) Len() int {
+	return len(g.locations)
+}
+
+func gazetteerKey(country, city, street string) string {
+	return normalize(country) + "|" + normalize(city) + "|" + street
+}
+
+// normalize lowercases s and reduces everything but letters and digits to
+// single spaces, so "Shipka St. 6" and "shipka st 6" are the same.
+func normalize(s string) string {
+	return strings.Join(strings.FieldsFunc(strings.ToLower(s), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	}), " ")
+}
+
+// streetName drops the words of a normalized address line that contain
+// digits, such as house and block numbers.
+func streetName(line string) string {
+	words := []string{}
+	for _, word := range strings.Fields(line) {
+		if !strings.ContainsFunc(word, unicode.IsDigit) {
+			words = append(words, word)
+		}
+	}
+	return strings.Join(words, " ")
+}
+
+// countryCode lets gazetteers and queries name countries either by code or
+// by name.
+func countryCode(country string) string {
+	if code, ok := models.CountryCode(country); ok {
+		return code
+	}
+	return strings.TrimSpace(country)
+}
@@ -0,0 +1,54 @@
+package geocoding
+
+import (
+	"errors"
+
+	"github.com/VitoNaychev/bt-customer-svc/models"
+)
+
+// Precisions of a geocoded location, from the most to the least exact.
+const (
+	ADDRESS_PRECISION = "address"
+	STREET_PRECISION  = "street"
+	CITY_PRECISION    = "city"
+)
+
+// mismatchDistances are how far, in meters, coordinates sent with an
+// address may be from its geocoded location before they are flagged. The
+// less exact the location, the more room there is.
+var mismatchDistances = map[string]float64{
+	ADDRESS_PRECISION: 250,
+	STREET_PRECISION:  2000,
+	CITY_PRECISION:    15000,
+}
+
+var ErrNotFound = errors.New("address couldn't be geocoded")
+
+// Query is the part of an address a geocoder looks up.
+type Query struct {
+	AddressLine1 string
+	City         string
+	Country      string
+}
+
+func AddressQuery(address models.Address) Query {
+	return Query{AddressLine1: address.AddressLine1, City: address.City, Country: address.Country}
+}
+
+type Location struct {
+	Lat       float64
+	Lon       float64
+	Precision string
+}
+
+// Geocoder turns address lines into coordinates. It returns ErrNotFound
+// when it doesn't know the address, not even at city precision.
+type Geocoder interface {
+	Geocode(query Query) (Location, error)
+}
+
+// Mismatch reports whether lat and lon are too far from a geocoded
+// location to belong to the same address.
+func Mismatch(location Location, lat, lon float64) bool {
+	return models.Distance(location.Lat, location.Lon, lat, lon) > mismatchDistances[location.Precision]
+}
@@ -0,0 +1,221 @@
+package geocoding_test
+
+import (
+	"errors"
+	"fmt"
+	"math/rand"
+	"strings"
+	"testing"
+
+	"github.com/VitoNaychev/bt-customer-svc/geocoding"
+	"github.com/VitoNaychev/bt-customer-svc/models"
+	"github.com/VitoNaychev/bt-customer-svc/testutil"
+)
+
+const testGazetteer = `# test data
+country,city,street,lat,lon
+Bulgaria,Sofia,,42.6977,23.3219
+Bulgaria,Sofia,Shipka Street,42.6950,23.3305
+Bulgaria,Sofia,Shipka Street 6,42.6951,23.3292
+`
+
+func TestGazetteer(t *testing.T) {
+	gazetteer, err := geocoding.NewGazetteer(strings.NewReader(testGazetteer))
+	if err != nil {
+		t.Fatalf("couldn't load gazetteer: %v", err)
+	}
+
+	cases := map[string]struct {
+		query geocoding.Query
+		want  geocoding.Location
+	}{
+		"finds address ignoring case and punctuation": {
+			geocoding.Query{AddressLine1: "shipka street, 6", City: "SOFIA", Country: "bulgaria"},
+			geocoding.Location{Lat: 42.6951, Lon: 23.3292, Precision: geocoding.ADDRESS_PRECISION},
+		},
+		"falls back to street of unknown house number": {
+			geocoding.Query{AddressLine1: "Shipka Street 12", City: "Sofia", Country: "Bulgaria"},
+			geocoding.Location{Lat: 42.6950, Lon: 23.3305, Precision: geocoding.STREET_PRECISION},
+		},
+		"falls back to city of unknown street": {
+			geocoding.Query{AddressLine1: "Oborishte 3", City: "Sofia", Country: "Bulgaria"},
+			geocoding.Location{Lat: 42.6977, Lon: 23.3219, Precision: geocoding.CITY_PRECISION},
+		},
+	}
+
+	for name, test := range cases {
+		t.Run(name, func(t *testing.T) {
+			got, err := gazetteer.Geocode(test.query)
+			if err != nil {
+				t.Fatalf("couldn't geocode: %v", err)
+			}
+			testutil.AssertEqual(t, got, test.want)
+		})
+	}
+
+	t.Run("returns ErrNotFound on unknown city", func(t *testing.T) {
+		_, err := gazetteer.Geocode(geocoding.Query{AddressLine1: "Shipka Street 6", City: "Varna", Country: "Bulgaria"})
+		testutil.AssertEqual(t, err, geocoding.ErrNotFound)
+	})
+
+	t.Run("rejects invalid coordinates", func(t *testing.T) {
+		_, err := geocoding.NewGazetteer(strings.NewReader("country,city,street,lat,lon\nBulgaria,Sofia,,95,23\n"))
+		if !errors.Is(err, geocoding.ErrInvalidGazetteer) {
+			t.Errorf("got error %v want %v", err, geocoding.ErrInvalidGazetteer)
+		}
+	})
+
+	t.Run("rejects unknown columns", func(t *testing.T) {
+		_, err := geocoding.NewGazetteer(strings.NewReader("city,country,street,lat,lon\n"))
+		if !errors.Is(err, geocoding.ErrInvalidGazetteer) {
+			t.Errorf("got error %v want %v", err, geocoding.ErrInvalidGazetteer)
+		}
+	})
+}
+
+type countingGeocoder struct {
+	calls int
+	err   error
+}
+
+func (c *countingGeocoder) Geocode(query geocoding.Query) (geocoding.Location, error) {
+	c.calls++
+	if c.err != nil {
+		return geocoding.Location{}, c.err
+	}
+	if query.City == "Nowhere" {
+		return geocoding.Location{}, geocoding.ErrNotFound
+	}
+	return geocoding.Location{Lat: 42, Lon: 23, Precision: geocoding.CITY_PRECISION}, nil
+}
+
+func TestCachingGeocoder(t *testing.T) {
+	sofia := geocoding.Query{AddressLine1: "Shipka Street 6", City: "Sofia", Country: "Bulgaria"}
+	nowhere := geocoding.Query{AddressLine1: "Shipka Street 6", City: "Nowhere", Country: "Bulgaria"}
+
+	t.Run("caches locations and unknown addresses", func(t *testing.T) {
+		geocoder := &countingGeocoder{}
+		cache := geocoding.NewCachingGeocoder(geocoder, 10)
+
+		cache.Geocode(sofia)
+		cache.Geocode(geocoding.Query{AddressLine1: "shipka street 6", City: "sofia", Country: "Bulgaria"})
+		_, err := cache.Geocode(nowhere)
+		testutil.AssertEqual(t, err, geocoding.ErrNotFound)
+		_, err = cache.Geocode(nowhere)
+		testutil.AssertEqual(t, err, geocoding.ErrNotFound)
+
+		testutil.AssertEqual(t, geocoder.calls, 2)
+	})
+
+	t.Run("doesn't cache failures", func(t *testing.T) {
+		geocoder := &countingGeocoder{err: errors.New("geocoder is down")}
+		cache := geocoding.NewCachingGeocoder(geocoder, 10)
+
+		cache.Geocode(sofia)
+		cache.Geocode(sofia)
+
+		testutil.AssertEqual(t, geocoder.calls, 2)
+		testutil.AssertEqual(t, cache.Len(), 0)
+	})
+
+	t.Run("evicts the least recently used query", func(t *testing.T) {
+		geocoder := &countingGeocoder{}
+		cache := geocoding.NewCachingGeocoder(geocoder, 2)
+
+		cache.Geocode(sofia)
+		cache.Geocode(nowhere)
+		cache.Geocode(sofia)
+		cache.Geocode(geocoding.Query{AddressLine1: "Vitosha Blvd 1", City: "Sofia", Country: "Bulgaria"})
+		cache.Geocode(sofia)
+		cache.Geocode(nowhere)
+
+		testutil.AssertEqual(t, cache.Len(), 2)
+		testutil.AssertEqual(t, geocoder.calls, 4)
+	})
+}
+
+func TestMismatch(t *testing.T) {
+	location := geocoding.Location{Lat: 42.6951, Lon: 23.3292, Precision: geocoding.ADDRESS_PRECISION}
+
+	testutil.AssertEqual(t, geocoding.Mismatch(location, 42.6952, 23.3293), false)
+	testutil.AssertEqual(t, geocoding.Mismatch(location, 42.7049, 23.3112), true)
+
+	location.Precision = geocoding.CITY_PRECISION
+	testutil.AssertEqual(t, geocoding.Mismatch(location, 42.7049, 23.3112), false)
+}
+
+func TestReverse(t *testing.T) {
+	gazetteer, err := geocoding.NewGazetteer(strings.NewReader(testGazetteer +
+		"Bulgaria,Sofia,Vitosha Blvd,42.6894,23.3194\nBulgaria,Pernik,,42.6052,23.0378\n"))
+	if err != nil {
+		t.Fatalf("couldn't load gazetteer: %v", err)
+	}
+
+	t.Run("suggests the nearest address", func(t *testing.T) {
+		got, err := gazetteer.Reverse(42.6952, 23.3293)
+		if err != nil {
+			t.Fatalf("couldn't reverse geocode: %v", err)
+		}
+
+		testutil.AssertEqual(t, got.AddressLine1, "Shipka Street 6")
+		testutil.AssertEqual(t, got.City, "Sofia")
+		testutil.AssertEqual(t, got.Location.Precision, geocoding.ADDRESS_PRECISION)
+	})
+
+	t.Run("suggests the nearest street", func(t *testing.T) {
+		got, _ := gazetteer.Reverse(42.6890, 23.3190)
+
+		testutil.AssertEqual(t, got.AddressLine1, "Vitosha Blvd")
+		testutil.AssertEqual(t, got.Location.Precision, geocoding.STREET_PRECISION)
+	})
+
+	t.Run("falls back to the nearest city", func(t *testing.T) {
+		got, _ := gazetteer.Reverse(42.6100, 23.0500)
+
+		testutil.AssertEqual(t, got.AddressLine1, "")
+		testutil.AssertEqual(t, got.City, "Pernik")
+		testutil.AssertEqual(t, got.Location.Precision, geocoding.CITY_PRECISION)
+	})
+
+	t.Run("returns ErrNotFound far from every place", func(t *testing.T) {
+		_, err := gazetteer.Reverse(43.2141, 27.9147)
+		testutil.AssertEqual(t, err, geocoding.ErrNotFound)
+	})
+
+	t.Run("finds the same place as a full scan", func(t *testing.T) {
+		random := rand.New(rand.NewSource(1))
+
+		data := strings.Builder{}
+		data.WriteString("country,city,street,lat,lon\n")
+		points := [][2]float64{}
+		for i := 0; i < 2000; i++ {
+			lat, lon := 42.6+random.Float64()*0.2, 23.2+random.Float64()*0.2
+			points = append(points, [2]float64{lat, lon})
+			fmt.Fprintf(&data, "Bulgaria,Sofia,Street %d,%v,%v\n", i, lat, lon)
+		}
+
+		gazetteer, err := geocoding.NewGazetteer(strings.NewReader(data.String()))
+		if err != nil {
+			t.Fatalf("couldn't load gazetteer: %v", err)
+		}
+
+		for i := 0; i < 200; i++ {
+			lat, lon := 42.6+random.Float64()*0.2, 23.2+random.Float64()*0.2
+
+			want := geocoding.MAX_STREET_DISTANCE + 1.0
+			for _, point := range points {
+				want = min(want, models.Distance(lat, lon, point[0], point[1]))
+			}
+
+			got, err := gazetteer.Reverse(lat, lon)
+			if want > geocoding.MAX_STREET_DISTANCE {
+				testutil.AssertEqual(t, err, geocoding.ErrNotFound)
+				continue
+			}
+			if err != nil {
+				t.Fatalf("couldn't reverse geocode %f,%f: %v", lat, lon, err)
+			}
+			testutil.AssertEqual(t, got.Distance, want)
+		}
+	})
+}
@@ -0,0 +1,118 @@
+package geocoding
+
+import (
+	"math"
+
+	"github.com/VitoNaychev/bt-customer-svc/models"
+)
+
+// How far, in meters, a point may be from a street or city center to be
+// suggested as its address.
+const (
+	MAX_STREET_DISTANCE = 500
+	MAX_CITY_DISTANCE   = 30000
+)
+
+// Place is a suggested address for a point. Depending on the precision of
+// the location AddressLine1 has a house number, only names the street or
+// is empty. Distance is in meters from the point to the location.
+type Place struct {
+	AddressLine1 string
+	City         string
+	Country      string
+	Location     Location
+	Distance     float64
+}
+
+// ReverseGeocoder suggests the address at a point. It returns ErrNotFound
+// when it knows nothing close enough.
+type ReverseGeocoder interface {
+	Reverse(lat, lon float64) (Place, error)
+}
+
+// GRID_CELL_SIZE is the edge, in degrees, of the cells of a grid.
+const GRID_CELL_SIZE = 0.01
+
+// metersPerDegree is the length of a degree of latitude.
+const metersPerDegree = math.Pi * 6371000.0 / 180
+
+type cell struct {
+	lat int
+	lon int
+}
+
+type gridPoint struct {
+	lat   float64
+	lon   float64
+	index int
+}
+
+// grid buckets points into cells of GRID_CELL_SIZE degrees, so the
+// nearest point is found by searching rings of cells around a point
+// instead of every point.
+type grid struct {
+	cells map[cell][]gridPoint
+}
+
+func newGrid() *grid {
+	return &grid{cells: map[cell][]gridPoint{}}
+}
+
+func cellOf(lat, lon float64) cell {
+	return cell{int(math.Floor(lat / GRID_CELL_SIZE)), int(math.Floor(lon / GRID_CELL_SIZE))}
+}
+
+func (g *grid) add(lat, lon float64, index int) {
+	c := cellOf(lat, lon)
+	g.cells[c] = append(g.cells[c], gridPoint{lat, lon, index})
+}
+
+// nearest returns the index of the point closest to lat and lon, and its
+// distance, if one is within maxDistance meters.
+func (g *grid) nearest(lat, lon float64, maxDistance float64) (int, float64, bool) {
+	// a ring of cells is narrowest along the longitude, where cells shrink
+	// towards the poles
+	cellMeters := GRID_CELL_SIZE * metersPerDegree * math.Max(math.Cos(lat*math.Pi/180), 0.01)
+	maxRing := int(math.Ceil(maxDistance/cellMeters)) + 1
+
+	center := cellOf(lat, lon)
+	best, bestDistance := -1, maxDistance
+	for ring := 0; ring <= maxRing; ring++ {
+		// every point outside of the rings searched so far is at least
+		// this far away
+		if best >= 0 && bestDistance <= float64(ring-1)*cellMeters {
+			break
+		}
+
+		for _, c := range ringCells(center, ring) {
+			for _, point := range g.cells[c] {
+				distance := models.Distance(lat, lon, point.lat, point.lon)
+				if distance < bestDistance || (best < 0 && distance == bestDistance) {
+					best, bestDistance = point.index, distance
+				}
+			}
+		}
+	}
+
+	return best, bestDistance, best >= 0
+}
+
+// ringCells returns the cells ring steps away from center.
+func ringCells(center cell, ring int) []cell {
+	if ring == 0 {
+		return []cell{center}
+	}
+
+	cells := []cell{}
+	for d := -ring; d <= ring; d++ {
+		cells = append(cells,
+			cell{center.lat - ring, center.lon + d},
+			cell{center.lat + ring, center.lon + d})
+	}
+	for d := -ring + 1; d <= ring-1; d++ {
+		cells = append(cells,
+			cell{center.lat + d, center.lon - ring},
+			cell{center.lat + d, center.lon + ring})
+	}
+	return cells
+}
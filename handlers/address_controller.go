@@ -5,21 +5,51 @@ import (
 	"errors"
 	"net/http"
 	"strconv"
+	"strings"
 
+	"github.com/VitoNaychev/bt-customer-svc/geocoding"
 	"github.com/VitoNaychev/bt-customer-svc/models"
 	"github.com/VitoNaychev/validation"
 )
 
+// updateAddress replaces the address in the path. The body may leave out
+// the ID, but if it has one it must be the same.
 func (c *CustomerAddressServer) updateAddress(w http.ResponseWriter, r *http.Request) {
+	addressId, err := getAddressIDFromPath(r)
+	if err != nil {
+		writeJSONError(w, http.StatusBadRequest, ErrInvalidAddressID)
+		return
+	}
+
+	updateAddressRequest, err := validation.ValidateBody[UpdateAddressRequest](r.Body)
+	if err != nil {
+		writeJSONError(w, http.StatusBadRequest, ErrInvalidRequestField)
+		return
+	}
+
+	if updateAddressRequest.Id != 0 && updateAddressRequest.Id != addressId {
+		writeJSONError(w, http.StatusBadRequest, ErrAddressIDMismatch)
+		return
+	}
+	updateAddressRequest.Id = addressId
+
+	c.replaceAddress(w, r, updateAddressRequest)
+}
+
+func (c *CustomerAddressServer) updateAddressWithBodyID(w http.ResponseWriter, r *http.Request) {
 	updateAddressRequest, err := validation.ValidateBody[UpdateAddressRequest](r.Body)
 	if err != nil {
 		writeJSONError(w, http.StatusBadRequest, ErrInvalidRequestField)
 		return
 	}
 
+	c.replaceAddress(w, r, updateAddressRequest)
+}
+
+func (c *CustomerAddressServer) replaceAddress(w http.ResponseWriter, r *http.Request, updateAddressRequest UpdateAddressRequest) {
 	customerId, _ := strconv.Atoi(r.Header["Subject"][0])
 
-	_, err = c.customerStore.GetCustomerByID(customerId)
+	_, err := getActiveCustomer(c.customerStore, customerId)
 	if err != nil {
 		handleAddressStoreError(w, err, ErrCustomerNotFound)
 		return
@@ -31,50 +61,173 @@ func (c *CustomerAddressServer) updateAddress(w http.ResponseWriter, r *http.Req
 		return
 	}
 
-	if address.CustomerId != customerId {
-		writeJSONError(w, http.StatusUnauthorized, ErrUnathorizedAction)
+	if !checkAddressAccess(w, c.householdStore, customerId, address, manageAddress) {
+		return
+	}
+
+	if !checkIfMatch(w, r, versionETag(address.Version), c.preconditionMode) {
+		return
+	}
+
+	stored := address
+	address = UpdateAddressRequestToAddress(updateAddressRequest, stored.CustomerId)
+	address.HouseholdId = stored.HouseholdId
+	address.Version = stored.Version
+
+	if address.HouseholdId != nil && address.IsDefault {
+		writeJSONError(w, http.StatusBadRequest, ErrHouseholdDefault)
+		return
+	}
+
+	if !checkCountryRules(w, &address) || !c.locateAddress(w, &address) {
 		return
 	}
 
-	address = UpdateAddressRequestToAddress(updateAddressRequest, customerId)
+	// addresses that were saved before their zone shrank can still be edited
+	moved := address.Lat != stored.Lat || address.Lon != stored.Lon
+	if moved && !c.checkDeliveryArea(w, address) {
+		return
+	}
 
 	err = c.addressStore.UpdateAddress(&address)
 	if err != nil {
-		writeJSONError(w, http.StatusInternalServerError, ErrDatabaseError)
+		handleVersionedStoreError(w, err)
+		return
+	}
+
+	w.Header().Set("ETag", versionETag(address.Version))
+	json.NewEncoder(w).Encode(address)
+}
+
+func (c *CustomerAddressServer) patchAddress(w http.ResponseWriter, r *http.Request) {
+	addressId, err := getAddressIDFromPath(r)
+	if err != nil {
+		writeJSONError(w, http.StatusBadRequest, ErrInvalidAddressID)
+		return
+	}
+
+	patchAddressRequest, err := parseMergePatch[PatchAddressRequest](r.Body, "AddressLine2", "Region", "PostalCode",
+		"Label", "DeliveryInstructions", "Floor", "Entrance", "Apartment", "ContactPhone")
+	if err != nil {
+		writeJSONError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	customerId, _ := strconv.Atoi(r.Header["Subject"][0])
+
+	_, err = getActiveCustomer(c.customerStore, customerId)
+	if err != nil {
+		handleAddressStoreError(w, err, ErrCustomerNotFound)
+		return
+	}
+
+	address, err := c.addressStore.GetAddressByID(addressId)
+	if err != nil {
+		handleAddressStoreError(w, err, ErrMissingAddress)
+		return
+	}
+
+	if !checkAddressAccess(w, c.householdStore, customerId, address, manageAddress) {
+		return
+	}
+
+	if !checkIfMatch(w, r, versionETag(address.Version), c.preconditionMode) {
+		return
+	}
+
+	addressPatch := PatchAddressRequestToAddressPatch(patchAddressRequest)
+
+	if address.HouseholdId != nil && addressPatch.IsDefault != nil && *addressPatch.IsDefault {
+		writeJSONError(w, http.StatusBadRequest, ErrHouseholdDefault)
+		return
+	}
+
+	if changesPostalAddress(addressPatch) {
+		patched := addressPatch.Apply(address)
+		if !checkCountryRules(w, &patched) {
+			return
+		}
+		addressPatch.Region, addressPatch.PostalCode = &patched.Region, &patched.PostalCode
+		addressPatch.Country = &patched.Country
+	}
+
+	if movesAddress(addressPatch) {
+		patched := addressPatch.Apply(address)
+
+		if c.geocoder != nil {
+			// coordinates of the old address lines don't locate the new ones
+			if addressPatch.Lat == nil && addressPatch.Lon == nil {
+				patched.Lat, patched.Lon = 0, 0
+			}
+
+			if !c.locateAddress(w, &patched) {
+				return
+			}
+			addressPatch.Lat, addressPatch.Lon = &patched.Lat, &patched.Lon
+			addressPatch.CoordinatesMismatch = &patched.CoordinatesMismatch
+		}
+
+		if !c.checkDeliveryArea(w, patched) {
+			return
+		}
+	}
+
+	address, err = c.addressStore.PatchAddress(addressId, address.Version, addressPatch)
+	if err != nil {
+		handleVersionedStoreError(w, err)
+		return
 	}
 
+	w.Header().Set("ETag", versionETag(address.Version))
 	json.NewEncoder(w).Encode(address)
 }
 
 func (c *CustomerAddressServer) deleteAddress(w http.ResponseWriter, r *http.Request) {
+	addressId, err := getAddressIDFromPath(r)
+	if err != nil {
+		writeJSONError(w, http.StatusBadRequest, ErrInvalidAddressID)
+		return
+	}
+
+	c.removeAddress(w, r, addressId)
+}
+
+func (c *CustomerAddressServer) deleteAddressWithBodyID(w http.ResponseWriter, r *http.Request) {
 	deleteAddressRequest, err := validation.ValidateBody[DeleteAddressRequest](r.Body)
 	if err != nil {
 		writeJSONError(w, http.StatusBadRequest, err)
 		return
 	}
 
+	c.removeAddress(w, r, deleteAddressRequest.Id)
+}
+
+func (c *CustomerAddressServer) removeAddress(w http.ResponseWriter, r *http.Request, addressId int) {
 	customerId, _ := strconv.Atoi(r.Header["Subject"][0])
 
-	_, err = c.customerStore.GetCustomerByID(customerId)
+	_, err := getActiveCustomer(c.customerStore, customerId)
 	if err != nil {
 		handleAddressStoreError(w, err, ErrCustomerNotFound)
 		return
 	}
 
-	address, err := c.addressStore.GetAddressByID(deleteAddressRequest.Id)
+	address, err := c.addressStore.GetAddressByID(addressId)
 	if err != nil {
 		handleAddressStoreError(w, err, ErrMissingAddress)
 		return
 	}
 
-	if address.CustomerId != customerId {
-		writeJSONError(w, http.StatusUnauthorized, ErrUnathorizedAction)
+	if !checkAddressAccess(w, c.householdStore, customerId, address, manageAddress) {
+		return
+	}
+
+	if !checkIfMatch(w, r, versionETag(address.Version), c.preconditionMode) {
 		return
 	}
 
-	err = c.addressStore.DeleteAddress(deleteAddressRequest.Id)
+	err = c.addressStore.DeleteAddress(addressId, address.Version)
 	if err != nil {
-		writeJSONError(w, http.StatusInternalServerError, ErrDatabaseError)
+		handleVersionedStoreError(w, err)
 	}
 }
 
@@ -87,7 +240,7 @@ func (c *CustomerAddressServer) createAddress(w http.ResponseWriter, r *http.Req
 
 	customerId, _ := strconv.Atoi(r.Header["Subject"][0])
 
-	_, err = c.customerStore.GetCustomerByID(customerId)
+	_, err = getActiveCustomer(c.customerStore, customerId)
 	if err != nil {
 		handleAddressStoreError(w, err, ErrCustomerNotFound)
 		return
@@ -95,9 +248,26 @@ func (c *CustomerAddressServer) createAddress(w http.ResponseWriter, r *http.Req
 
 	address := CreateAddressRequestToAddress(createAddressRequest, customerId)
 
+	if address.HouseholdId != nil {
+		if address.IsDefault {
+			writeJSONError(w, http.StatusBadRequest, ErrHouseholdDefault)
+			return
+		}
+		if !checkAddressAccess(w, c.householdStore, customerId, address, useAddress) {
+			return
+		}
+	}
+
+	if !checkCountryRules(w, &address) || !c.locateAddress(w, &address) || !c.checkDeliveryArea(w, address) {
+		return
+	}
+
 	err = c.addressStore.CreateAddress(&address)
 	if err != nil {
-		writeJSONError(w, http.StatusInternalServerError, ErrDatabaseError)
+		if !handleConstraintError(w, err) {
+			writeJSONError(w, http.StatusInternalServerError, ErrDatabaseError)
+		}
+		return
 	}
 
 	json.NewEncoder(w).Encode(address)
@@ -106,15 +276,20 @@ func (c *CustomerAddressServer) createAddress(w http.ResponseWriter, r *http.Req
 func (c *CustomerAddressServer) getAddress(w http.ResponseWriter, r *http.Request) {
 	customerId, _ := strconv.Atoi(r.Header["Subject"][0])
 
-	_, err := c.customerStore.GetCustomerByID(customerId)
+	_, err := getActiveCustomer(c.customerStore, customerId)
 	if err != nil {
 		handleAddressStoreError(w, err, ErrCustomerNotFound)
 		return
 	}
 
-	addresses, err := c.addressStore.GetAddressesByCustomerID(customerId)
+	addresses, err := c.getAvailableAddresses(customerId)
 	if err != nil {
 		writeJSONError(w, http.StatusInternalServerError, ErrDatabaseError)
+		return
+	}
+
+	if checkIfNoneMatch(w, r, addressesETag(addresses)) {
+		return
 	}
 
 	getAddressResponse := []GetAddressResponse{}
@@ -125,6 +300,306 @@ func (c *CustomerAddressServer) getAddress(w http.ResponseWriter, r *http.Reques
 	json.NewEncoder(w).Encode(getAddressResponse)
 }
 
+func (c *CustomerAddressServer) getAddressByID(w http.ResponseWriter, r *http.Request) {
+	addressId, err := getAddressIDFromPath(r)
+	if err != nil {
+		writeJSONError(w, http.StatusBadRequest, ErrInvalidAddressID)
+		return
+	}
+
+	customerId, _ := strconv.Atoi(r.Header["Subject"][0])
+
+	_, err = getActiveCustomer(c.customerStore, customerId)
+	if err != nil {
+		handleAddressStoreError(w, err, ErrCustomerNotFound)
+		return
+	}
+
+	address, err := c.addressStore.GetAddressByID(addressId)
+	if err != nil {
+		handleAddressStoreError(w, err, ErrMissingAddress)
+		return
+	}
+
+	if !checkAddressAccess(w, c.householdStore, customerId, address, useAddress) {
+		return
+	}
+
+	if checkIfNoneMatch(w, r, versionETag(address.Version)) {
+		return
+	}
+
+	json.NewEncoder(w).Encode(AddressToGetAddressResponse(address))
+}
+
+// getAddressRevision also serves revisions of deleted addresses that an
+// order has frozen, so orders can show where they were delivered to.
+func (c *CustomerAddressServer) getAddressRevision(w http.ResponseWriter, r *http.Request) {
+	addressId, revision, err := getAddressRevisionFromPath(r)
+	if err != nil {
+		writeJSONError(w, http.StatusBadRequest, ErrInvalidRevision)
+		return
+	}
+
+	customerId, _ := strconv.Atoi(r.Header["Subject"][0])
+
+	_, err = getActiveCustomer(c.customerStore, customerId)
+	if err != nil {
+		handleAddressStoreError(w, err, ErrCustomerNotFound)
+		return
+	}
+
+	addressRevision, err := c.addressStore.GetAddressRevision(addressId, revision)
+	if err != nil {
+		handleAddressStoreError(w, err, ErrMissingRevision)
+		return
+	}
+
+	if !checkAddressAccess(w, c.householdStore, customerId, addressRevision.Address(), useAddress) {
+		return
+	}
+
+	json.NewEncoder(w).Encode(AddressRevisionToAddressSnapshotResponse(addressRevision))
+}
+
+func (c *CustomerAddressServer) getDeliverableAddresses(w http.ResponseWriter, r *http.Request) {
+	customerId, _ := strconv.Atoi(r.Header["Subject"][0])
+
+	_, err := getActiveCustomer(c.customerStore, customerId)
+	if err != nil {
+		handleAddressStoreError(w, err, ErrCustomerNotFound)
+		return
+	}
+
+	addresses, err := c.getAvailableAddresses(customerId)
+	if err != nil {
+		writeJSONError(w, http.StatusInternalServerError, ErrDatabaseError)
+		return
+	}
+
+	deliverableAddressResponse := []DeliverableAddressResponse{}
+	for _, address := range addresses {
+		response := DeliverableAddressResponse{Id: address.Id, Deliverable: true}
+		if c.deliveryArea != nil {
+			zone, ok := c.deliveryArea.Locate(address.Lat, address.Lon)
+			response.Deliverable, response.Zone = ok, zone.Name
+		}
+		deliverableAddressResponse = append(deliverableAddressResponse, response)
+	}
+
+	json.NewEncoder(w).Encode(deliverableAddressResponse)
+}
+
+func (c *CustomerAddressServer) reverseGeocode(w http.ResponseWriter, r *http.Request) {
+	reverseGeocodeRequest, err := validation.ValidateBody[ReverseGeocodeRequest](r.Body)
+	if err != nil {
+		writeJSONError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	customerId, _ := strconv.Atoi(r.Header["Subject"][0])
+
+	_, err = getActiveCustomer(c.customerStore, customerId)
+	if err != nil {
+		handleAddressStoreError(w, err, ErrCustomerNotFound)
+		return
+	}
+
+	if c.reverseGeocoder == nil {
+		writeJSONError(w, http.StatusNotImplemented, ErrNoReverseGeocoder)
+		return
+	}
+
+	place, err := c.reverseGeocoder.Reverse(reverseGeocodeRequest.Lat, reverseGeocodeRequest.Lon)
+	if err != nil {
+		if errors.Is(err, geocoding.ErrNotFound) {
+			writeJSONError(w, http.StatusNotFound, ErrUnknownLocation)
+		} else {
+			writeJSONError(w, http.StatusInternalServerError, ErrGeocodingError)
+		}
+		return
+	}
+
+	json.NewEncoder(w).Encode(PlaceToReverseGeocodeResponse(place, reverseGeocodeRequest))
+}
+
+// formatAddress renders an address without saving it. The postal code is
+// normalized but the country rules aren't enforced, so addresses can be
+// previewed while they are still incomplete.
+func (c *CustomerAddressServer) formatAddress(w http.ResponseWriter, r *http.Request) {
+	formatAddressRequest, err := validation.ValidateBody[FormatAddressRequest](r.Body)
+	if err != nil {
+		writeJSONError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	address := FormatAddressRequestToAddress(formatAddressRequest)
+	models.NormalizePostalAddress(&address)
+
+	json.NewEncoder(w).Encode(AddressToFormattedAddress(address))
+}
+
+// locateAddress fills in the coordinates of an address sent without them
+// and flags coordinates that are far from where its address lines geocode
+// to. Coordinates that can't be checked are accepted as they are.
+func (c *CustomerAddressServer) locateAddress(w http.ResponseWriter, address *models.Address) bool {
+	hasCoordinates := address.Lat != 0 || address.Lon != 0
+	address.CoordinatesMismatch = false
+
+	if c.geocoder == nil {
+		if !hasCoordinates {
+			writeJSONError(w, http.StatusBadRequest, ErrMissingCoordinates)
+			return false
+		}
+		return true
+	}
+
+	location, err := c.geocoder.Geocode(geocoding.AddressQuery(*address))
+	switch {
+	case err != nil && hasCoordinates:
+		return true
+	case errors.Is(err, geocoding.ErrNotFound):
+		writeJSONError(w, http.StatusUnprocessableEntity, ErrUnknownAddress)
+		return false
+	case err != nil:
+		writeJSONError(w, http.StatusInternalServerError, ErrGeocodingError)
+		return false
+	case !hasCoordinates:
+		address.Lat, address.Lon = location.Lat, location.Lon
+	default:
+		address.CoordinatesMismatch = geocoding.Mismatch(location, address.Lat, address.Lon)
+	}
+
+	return true
+}
+
+func (c *CustomerAddressServer) checkDeliveryArea(w http.ResponseWriter, address models.Address) bool {
+	if c.deliveryArea == nil {
+		return true
+	}
+
+	if _, ok := c.deliveryArea.Locate(address.Lat, address.Lon); !ok {
+		writeJSONError(w, http.StatusUnprocessableEntity, ErrOutsideDeliveryArea)
+		return false
+	}
+	return true
+}
+
+// checkCountryRules normalizes the postal code and region of an address
+// and validates them against the rules of its country.
+func checkCountryRules(w http.ResponseWriter, address *models.Address) bool {
+	models.NormalizePostalAddress(address)
+
+	if err := models.CheckCountryRules(*address); err != nil {
+		writeJSONError(w, http.StatusBadRequest, err)
+		return false
+	}
+	return true
+}
+
+// changesPostalAddress reports whether a patch changes a field the
+// country rules apply to.
+func changesPostalAddress(patch models.AddressPatch) bool {
+	return patch.Country != nil || patch.Region != nil || patch.PostalCode != nil
+}
+
+// movesAddress reports whether a patch changes where the address is, so
+// its coordinates have to be located again.
+func movesAddress(patch models.AddressPatch) bool {
+	return patch.Lat != nil || patch.Lon != nil || patch.AddressLine1 != nil ||
+		patch.City != nil || patch.Country != nil
+}
+
+func getAddressIDFromPath(r *http.Request) (int, error) {
+	idString := strings.Trim(strings.TrimPrefix(r.URL.Path, "/customer/address/"), "/")
+	return strconv.Atoi(idString)
+}
+
+// getAvailableAddresses returns the personal addresses of a customer
+// followed by the addresses of their households.
+func (c *CustomerAddressServer) getAvailableAddresses(customerId int) ([]models.Address, error) {
+	addresses, err := c.addressStore.GetAddressesByCustomerID(customerId)
+	if err != nil {
+		return nil, err
+	}
+
+	households, err := c.householdStore.GetHouseholdsByCustomerID(customerId)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, household := range households {
+		householdAddresses, err := c.addressStore.GetAddressesByHouseholdID(household.Id)
+		if err != nil {
+			return nil, err
+		}
+		addresses = append(addresses, householdAddresses...)
+	}
+
+	return addresses, nil
+}
+
+type addressAccess int
+
+const (
+	useAddress addressAccess = iota
+	manageAddress
+)
+
+// checkAddressAccess lets customers use their personal addresses and the
+// addresses of the households they are members of. Household addresses
+// can only be managed, i.e. edited and deleted, by the owners of the
+// household and by the member who added them.
+func checkAddressAccess(w http.ResponseWriter, householdStore models.HouseholdStore, customerId int,
+	address models.Address, access addressAccess) bool {
+	if address.HouseholdId == nil {
+		if address.CustomerId != customerId {
+			writeJSONError(w, http.StatusUnauthorized, ErrUnathorizedAction)
+			return false
+		}
+		return true
+	}
+
+	member, err := householdStore.GetHouseholdMember(*address.HouseholdId, customerId)
+	if errors.Is(err, models.ErrNotFound) {
+		writeJSONError(w, http.StatusUnauthorized, ErrUnathorizedAction)
+		return false
+	} else if err != nil {
+		writeJSONError(w, http.StatusInternalServerError, ErrDatabaseError)
+		return false
+	}
+
+	if access == manageAddress && !member.CanManage(address) {
+		writeJSONError(w, http.StatusUnauthorized, ErrUnathorizedAction)
+		return false
+	}
+
+	return true
+}
+
+// getAddressRevisionFromPath reads paths of the form
+// /customer/address/{id}/revisions/{revision}.
+func getAddressRevisionFromPath(r *http.Request) (int, int, error) {
+	path := strings.Trim(strings.TrimPrefix(r.URL.Path, "/customer/address/"), "/")
+
+	idString, revisionString, found := strings.Cut(path, "/revisions/")
+	if !found {
+		return 0, 0, ErrInvalidRevision
+	}
+
+	addressId, err := strconv.Atoi(idString)
+	if err != nil {
+		return 0, 0, err
+	}
+
+	revision, err := strconv.Atoi(revisionString)
+	if err != nil {
+		return 0, 0, err
+	}
+
+	return addressId, revision, nil
+}
+
 func handleAddressStoreError(w http.ResponseWriter, err error, missingEntityError error) {
 	if errors.Is(err, models.ErrNotFound) {
 		// wrap models.ErrNotFound in customer handlers error type?
@@ -4,6 +4,7 @@ import (
 	"bytes"
 	"encoding/json"
 	"net/http"
+	"strconv"
 
 	"github.com/VitoNaychev/bt-customer-svc/models"
 )
@@ -14,13 +15,47 @@ func NewUpdateAddressRequest(customerJWT string, address models.Address) *http.R
 	body := bytes.NewBuffer([]byte{})
 	json.NewEncoder(body).Encode(updateAddressRequest)
 
+	request, _ := http.NewRequest(http.MethodPut, "/customer/address/"+strconv.Itoa(address.Id), body)
+	request.Header.Add("Token", customerJWT)
+
+	return request
+}
+
+// NewUpdateAddressWithBodyIDRequest uses the deprecated form of updates
+// with the address ID only in the body.
+func NewUpdateAddressWithBodyIDRequest(customerJWT string, address models.Address) *http.Request {
+	updateAddressRequest := AddressToUpdateAddressRequest(address)
+
+	body := bytes.NewBuffer([]byte{})
+	json.NewEncoder(body).Encode(updateAddressRequest)
+
 	request, _ := http.NewRequest(http.MethodPut, "/customer/address/", body)
 	request.Header.Add("Token", customerJWT)
 
 	return request
 }
 
+func NewPatchAddressRequest(customerJWT string, id int, patch map[string]any) *http.Request {
+	body := bytes.NewBuffer([]byte{})
+	json.NewEncoder(body).Encode(patch)
+
+	request, _ := http.NewRequest(http.MethodPatch, "/customer/address/"+strconv.Itoa(id), body)
+	request.Header.Add("Token", customerJWT)
+	request.Header.Add("Content-Type", "application/merge-patch+json")
+
+	return request
+}
+
 func NewDeleteAddressRequest(customerJWT string, deleteAddressRequest DeleteAddressRequest) *http.Request {
+	request, _ := http.NewRequest(http.MethodDelete, "/customer/address/"+strconv.Itoa(deleteAddressRequest.Id), nil)
+	request.Header.Add("Token", customerJWT)
+
+	return request
+}
+
+// NewDeleteAddressWithBodyIDRequest uses the deprecated form of deletes
+// with the address ID in the body.
+func NewDeleteAddressWithBodyIDRequest(customerJWT string, deleteAddressRequest DeleteAddressRequest) *http.Request {
 	body := bytes.NewBuffer([]byte{})
 	json.NewEncoder(body).Encode(deleteAddressRequest)
 
@@ -47,3 +82,45 @@ func NewGetAddressRequest(customerJWT string) *http.Request {
 
 	return request
 }
+
+func NewGetAddressByIDRequest(customerJWT string, id int) *http.Request {
+	request, _ := http.NewRequest(http.MethodGet, "/customer/address/"+strconv.Itoa(id), nil)
+	request.Header.Add("Token", customerJWT)
+
+	return request
+}
+
+func NewGetAddressRevisionRequest(customerJWT string, id int, revision int) *http.Request {
+	request, _ := http.NewRequest(http.MethodGet,
+		"/customer/address/"+strconv.Itoa(id)+"/revisions/"+strconv.Itoa(revision), nil)
+	request.Header.Add("Token", customerJWT)
+
+	return request
+}
+
+func NewReverseGeocodeRequest(customerJWT string, lat, lon float64) *http.Request {
+	body := bytes.NewBuffer([]byte{})
+	json.NewEncoder(body).Encode(ReverseGeocodeRequest{Lat: lat, Lon: lon})
+
+	request, _ := http.NewRequest(http.MethodPost, "/customer/address/reverse/", body)
+	request.Header.Add("Token", customerJWT)
+
+	return request
+}
+
+func NewGetDeliverableAddressesRequest(customerJWT string) *http.Request {
+	request, _ := http.NewRequest(http.MethodGet, "/customer/address/deliverable/", nil)
+	request.Header.Add("Token", customerJWT)
+
+	return request
+}
+
+func NewFormatAddressRequest(customerJWT string, formatAddressRequest FormatAddressRequest) *http.Request {
+	body := bytes.NewBuffer([]byte{})
+	json.NewEncoder(body).Encode(formatAddressRequest)
+
+	request, _ := http.NewRequest(http.MethodPost, "/customer/address/format/", body)
+	request.Header.Add("Token", customerJWT)
+
+	return request
+}
@@ -2,36 +2,162 @@ package handlers
 
 import (
 	"net/http"
+	"strconv"
+	"strings"
+	"time"
 
 	"github.com/VitoNaychev/auth"
+	"github.com/VitoNaychev/bt-customer-svc/geocoding"
 	"github.com/VitoNaychev/bt-customer-svc/models"
+	"github.com/VitoNaychev/bt-customer-svc/zones"
 )
 
 type CustomerAddressServer struct {
-	addressStore  models.CustomerAddressStore
-	customerStore models.CustomerStore
-	secretKey     []byte
+	addressStore     models.CustomerAddressStore
+	customerStore    models.CustomerStore
+	secretKey        []byte
+	householdStore   models.HouseholdStore
+	preconditionMode PreconditionMode
+	geocoder         geocoding.Geocoder
+	reverseGeocoder  geocoding.ReverseGeocoder
+	deliveryArea     zones.DeliveryArea
 }
 
-func NewCustomerAddressServer(addressStore models.CustomerAddressStore, customerStore models.CustomerStore, secretKey []byte) *CustomerAddressServer {
+func NewCustomerAddressServer(addressStore models.CustomerAddressStore, customerStore models.CustomerStore, secretKey []byte,
+	householdStore models.HouseholdStore) *CustomerAddressServer {
 	customerAddressServer := CustomerAddressServer{
-		addressStore:  addressStore,
-		customerStore: customerStore,
-		secretKey:     secretKey,
+		addressStore:   addressStore,
+		customerStore:  customerStore,
+		secretKey:      secretKey,
+		householdStore: householdStore,
 	}
 
 	return &customerAddressServer
 }
 
+func (c *CustomerAddressServer) SetPreconditionMode(mode PreconditionMode) {
+	c.preconditionMode = mode
+}
+
+// SetGeocoder lets addresses be created without coordinates and flags
+// coordinates that don't match the address lines. Without a geocoder
+// coordinates are required and never checked.
+func (c *CustomerAddressServer) SetGeocoder(geocoder geocoding.Geocoder) {
+	c.geocoder = geocoder
+}
+
+// SetReverseGeocoder enables suggesting addresses for map pins.
+func (c *CustomerAddressServer) SetReverseGeocoder(reverseGeocoder geocoding.ReverseGeocoder) {
+	c.reverseGeocoder = reverseGeocoder
+}
+
+// SetDeliveryArea rejects new and moved addresses outside of the delivery
+// zones. Without a delivery area every address is deliverable.
+func (c *CustomerAddressServer) SetDeliveryArea(deliveryArea zones.DeliveryArea) {
+	c.deliveryArea = deliveryArea
+}
+
 func (c *CustomerAddressServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	switch r.URL.Path {
+	case "/customer/address/reverse/":
+		c.ReverseHandler(w, r)
+	case "/customer/address/deliverable/":
+		c.DeliverableHandler(w, r)
+	case "/customer/address/format/":
+		c.FormatHandler(w, r)
+	case "/customer/address/", "/customer/address":
+		c.AddressesHandler(w, r)
+	default:
+		if strings.Contains(r.URL.Path, "/revisions/") {
+			c.AddressRevisionHandler(w, r)
+		} else {
+			c.AddressHandler(w, r)
+		}
+	}
+}
+
+// AddressesHandler serves the collection of the customer's addresses. PUT
+// and DELETE with the address ID in the body predate the address
+// resources and are only kept for old clients.
+func (c *CustomerAddressServer) AddressesHandler(w http.ResponseWriter, r *http.Request) {
 	switch r.Method {
 	case http.MethodPost:
 		auth.AuthenticationMiddleware(c.createAddress, c.secretKey)(w, r)
 	case http.MethodGet:
 		auth.AuthenticationMiddleware(c.getAddress, c.secretKey)(w, r)
+	case http.MethodPut:
+		deprecated(auth.AuthenticationMiddleware(c.updateAddressWithBodyID, c.secretKey))(w, r)
 	case http.MethodDelete:
-		auth.AuthenticationMiddleware(c.deleteAddress, c.secretKey)(w, r)
+		deprecated(auth.AuthenticationMiddleware(c.deleteAddressWithBodyID, c.secretKey))(w, r)
+	default:
+		writeMethodNotAllowed(w, http.MethodGet, http.MethodPost, http.MethodPut, http.MethodDelete)
+	}
+}
+
+// AddressHandler serves a single address, identified by the ID in the
+// path.
+func (c *CustomerAddressServer) AddressHandler(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+		auth.AuthenticationMiddleware(c.getAddressByID, c.secretKey)(w, r)
 	case http.MethodPut:
 		auth.AuthenticationMiddleware(c.updateAddress, c.secretKey)(w, r)
+	case http.MethodPatch:
+		auth.AuthenticationMiddleware(c.patchAddress, c.secretKey)(w, r)
+	case http.MethodDelete:
+		auth.AuthenticationMiddleware(c.deleteAddress, c.secretKey)(w, r)
+	default:
+		writeMethodNotAllowed(w, http.MethodGet, http.MethodPut, http.MethodPatch, http.MethodDelete)
+	}
+}
+
+// AddressRevisionHandler serves the snapshots of an address at its
+// revisions, which don't change when the address does.
+func (c *CustomerAddressServer) AddressRevisionHandler(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+		auth.AuthenticationMiddleware(c.getAddressRevision, c.secretKey)(w, r)
+	default:
+		writeMethodNotAllowed(w, http.MethodGet)
+	}
+}
+
+func (c *CustomerAddressServer) ReverseHandler(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodPost:
+		auth.AuthenticationMiddleware(c.reverseGeocode, c.secretKey)(w, r)
+	default:
+		writeMethodNotAllowed(w, http.MethodPost)
+	}
+}
+
+func (c *CustomerAddressServer) DeliverableHandler(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+		auth.AuthenticationMiddleware(c.getDeliverableAddresses, c.secretKey)(w, r)
+	default:
+		writeMethodNotAllowed(w, http.MethodGet)
+	}
+}
+
+func (c *CustomerAddressServer) FormatHandler(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodPost:
+		auth.AuthenticationMiddleware(c.formatAddress, c.secretKey)(w, r)
+	default:
+		writeMethodNotAllowed(w, http.MethodPost)
+	}
+}
+
+// bodyIDDeprecation is when the request forms with the address ID in the
+// body were deprecated in favour of the address resources.
+var bodyIDDeprecation = time.Date(2026, time.October, 18, 0, 0, 0, 0, time.UTC)
+
+// deprecated marks the responses of a deprecated request form with the
+// Deprecation header of RFC 9745.
+func deprecated(handler http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Deprecation", "@"+strconv.FormatInt(bodyIDDeprecation.Unix(), 10))
+		handler(w, r)
 	}
 }
@@ -5,27 +5,32 @@ import (
 	"encoding/json"
 	"net/http"
 	"net/http/httptest"
+	"strconv"
+	"strings"
 	"testing"
 
 	"github.com/VitoNaychev/auth"
+	"github.com/VitoNaychev/bt-customer-svc/geocoding"
 	"github.com/VitoNaychev/bt-customer-svc/handlers"
 	"github.com/VitoNaychev/bt-customer-svc/models"
 	td "github.com/VitoNaychev/bt-customer-svc/testdata"
 	"github.com/VitoNaychev/bt-customer-svc/testutil"
+	"github.com/VitoNaychev/bt-customer-svc/zones"
 )
 
 func TestAddressEndpointAuthentication(t *testing.T) {
 	customerData := []models.Customer{td.PeterCustomer, td.AliceCustomer}
 	stubAddressStore := testutil.NewStubAddressStore(nil)
 	stubCustomerStore := testutil.NewStubCustomerStore(customerData)
-	server := handlers.NewCustomerAddressServer(stubAddressStore, stubCustomerStore, testEnv.SecretKey)
+	server := handlers.NewCustomerAddressServer(stubAddressStore, stubCustomerStore, testEnv.SecretKey, testutil.NewStubHouseholdStore(nil, nil, nil))
 
 	invalidJWT := "thisIsAnInvalidJWT"
 	cases := map[string]*http.Request{
-		"get address authentication":    handlers.NewGetAddressRequest(invalidJWT),
-		"create address authentication": handlers.NewCreateAddressRequest(invalidJWT, models.Address{}),
-		"update address authentication": handlers.NewUpdateAddressRequest(invalidJWT, models.Address{}),
-		"delete address authentication": handlers.NewDeleteAddressRequest(invalidJWT, handlers.DeleteAddressRequest{}),
+		"get address authentication":        handlers.NewGetAddressRequest(invalidJWT),
+		"get single address authentication": handlers.NewGetAddressByIDRequest(invalidJWT, 1),
+		"create address authentication":     handlers.NewCreateAddressRequest(invalidJWT, models.Address{}),
+		"update address authentication":     handlers.NewUpdateAddressRequest(invalidJWT, models.Address{}),
+		"delete address authentication":     handlers.NewDeleteAddressRequest(invalidJWT, handlers.DeleteAddressRequest{}),
 	}
 
 	for name, request := range cases {
@@ -44,7 +49,7 @@ func TestUpdateCustomerAddress(t *testing.T) {
 	customerData := []models.Customer{td.PeterCustomer, td.AliceCustomer}
 	stubAddressStore := testutil.NewStubAddressStore(addressData)
 	stubCustomerStore := testutil.NewStubCustomerStore(customerData)
-	server := handlers.NewCustomerAddressServer(stubAddressStore, stubCustomerStore, testEnv.SecretKey)
+	server := handlers.NewCustomerAddressServer(stubAddressStore, stubCustomerStore, testEnv.SecretKey, testutil.NewStubHouseholdStore(nil, nil, nil))
 
 	t.Run("updates address on valid body and credentials", func(t *testing.T) {
 		updatedAddress := td.PeterAddress2
@@ -105,6 +110,40 @@ func TestUpdateCustomerAddress(t *testing.T) {
 		testutil.AssertErrorResponse(t, response.Body, handlers.ErrMissingAddress)
 	})
 
+	t.Run("returns Precondition Failed on stale If-Match", func(t *testing.T) {
+		updatedAddress := td.PeterAddress1
+		updatedAddress.City = "Varna"
+
+		peterJWT, _ := auth.GenerateJWT(testEnv.SecretKey, testEnv.ExpiresAt, td.PeterCustomer.Id)
+
+		request := handlers.NewUpdateAddressRequest(peterJWT, updatedAddress)
+		request.Header.Set("If-Match", `"0"`)
+		response := httptest.NewRecorder()
+
+		server.ServeHTTP(response, request)
+
+		testutil.AssertStatus(t, response.Code, http.StatusPreconditionFailed)
+		testutil.AssertErrorResponse(t, response.Body, handlers.ErrPreconditionFailed)
+	})
+
+	t.Run("returns Precondition Required on missing If-Match in strict mode", func(t *testing.T) {
+		server.SetPreconditionMode(handlers.STRICT_PRECONDITIONS)
+		defer server.SetPreconditionMode(handlers.LENIENT_PRECONDITIONS)
+
+		updatedAddress := td.PeterAddress1
+		updatedAddress.City = "Varna"
+
+		peterJWT, _ := auth.GenerateJWT(testEnv.SecretKey, testEnv.ExpiresAt, td.PeterCustomer.Id)
+
+		request := handlers.NewUpdateAddressRequest(peterJWT, updatedAddress)
+		response := httptest.NewRecorder()
+
+		server.ServeHTTP(response, request)
+
+		testutil.AssertStatus(t, response.Code, http.StatusPreconditionRequired)
+		testutil.AssertErrorResponse(t, response.Body, handlers.ErrPreconditionRequired)
+	})
+
 	t.Run("returns Unauthorized on update on another customer's address", func(t *testing.T) {
 		updatedAddress := td.PeterAddress2
 		updatedAddress.City = "Varna"
@@ -121,12 +160,84 @@ func TestUpdateCustomerAddress(t *testing.T) {
 	})
 }
 
+func TestPatchCustomerAddress(t *testing.T) {
+	addressData := []models.Address{td.PeterAddress1, td.PeterAddress2, td.AliceAddress}
+	customerData := []models.Customer{td.PeterCustomer, td.AliceCustomer}
+	stubAddressStore := testutil.NewStubAddressStore(addressData)
+	stubCustomerStore := testutil.NewStubCustomerStore(customerData)
+	server := handlers.NewCustomerAddressServer(stubAddressStore, stubCustomerStore, testEnv.SecretKey, testutil.NewStubHouseholdStore(nil, nil, nil))
+
+	peterJWT, _ := auth.GenerateJWT(testEnv.SecretKey, testEnv.ExpiresAt, td.PeterCustomer.Id)
+
+	t.Run("returns Bad Request on invalid address ID", func(t *testing.T) {
+		request, _ := http.NewRequest(http.MethodPatch, "/customer/address/abc", nil)
+		request.Header.Add("Token", peterJWT)
+		response := httptest.NewRecorder()
+
+		server.ServeHTTP(response, request)
+
+		testutil.AssertStatus(t, response.Code, http.StatusBadRequest)
+		testutil.AssertErrorResponse(t, response.Body, handlers.ErrInvalidAddressID)
+	})
+
+	t.Run("returns Bad Request on null required field", func(t *testing.T) {
+		request := handlers.NewPatchAddressRequest(peterJWT, td.PeterAddress1.Id, map[string]any{"City": nil})
+		response := httptest.NewRecorder()
+
+		server.ServeHTTP(response, request)
+
+		testutil.AssertStatus(t, response.Code, http.StatusBadRequest)
+		testutil.AssertErrorResponse(t, response.Body, handlers.ErrNullField)
+	})
+
+	t.Run("returns Not Found on missing address", func(t *testing.T) {
+		request := handlers.NewPatchAddressRequest(peterJWT, 10, map[string]any{"City": "Varna"})
+		response := httptest.NewRecorder()
+
+		server.ServeHTTP(response, request)
+
+		testutil.AssertStatus(t, response.Code, http.StatusNotFound)
+		testutil.AssertErrorResponse(t, response.Body, handlers.ErrMissingAddress)
+	})
+
+	t.Run("returns Unauthorized on patch of another customer's address", func(t *testing.T) {
+		request := handlers.NewPatchAddressRequest(peterJWT, td.AliceAddress.Id, map[string]any{"City": "Varna"})
+		response := httptest.NewRecorder()
+
+		server.ServeHTTP(response, request)
+
+		testutil.AssertStatus(t, response.Code, http.StatusUnauthorized)
+		testutil.AssertErrorResponse(t, response.Body, handlers.ErrUnathorizedAction)
+	})
+
+	t.Run("updates present fields and clears nulled optional fields", func(t *testing.T) {
+		city := "Varna"
+		addressLine2 := ""
+
+		patch := map[string]any{"City": city, "AddressLine2": nil}
+		request := handlers.NewPatchAddressRequest(peterJWT, td.PeterAddress2.Id, patch)
+		response := httptest.NewRecorder()
+
+		server.ServeHTTP(response, request)
+
+		testutil.AssertStatus(t, response.Code, http.StatusOK)
+		testutil.AssertPatchedAddress(t, stubAddressStore, models.AddressPatch{City: &city, AddressLine2: &addressLine2})
+
+		want := td.PeterAddress2
+		want.City = city
+		want.Version = td.PeterAddress2.Version + 1
+
+		got := testutil.ParseAddressResponse(t, response.Body)
+		testutil.AssertEqual(t, got, want)
+	})
+}
+
 func TestDeleteCustomerAddress(t *testing.T) {
 	addressData := []models.Address{td.PeterAddress1, td.PeterAddress2, td.AliceAddress}
 	customerData := []models.Customer{td.PeterCustomer, td.AliceCustomer}
 	stubAddressStore := testutil.NewStubAddressStore(addressData)
 	stubCustomerStore := testutil.NewStubCustomerStore(customerData)
-	server := handlers.NewCustomerAddressServer(stubAddressStore, stubCustomerStore, testEnv.SecretKey)
+	server := handlers.NewCustomerAddressServer(stubAddressStore, stubCustomerStore, testEnv.SecretKey, testutil.NewStubHouseholdStore(nil, nil, nil))
 
 	t.Run("returns Bad Request on inavlid request", func(t *testing.T) {
 		body := bytes.NewBuffer([]byte{})
@@ -181,7 +292,7 @@ func TestDeleteCustomerAddress(t *testing.T) {
 		testutil.AssertErrorResponse(t, response.Body, handlers.ErrUnathorizedAction)
 	})
 
-	t.Run("deletes address on valid body and credentials", func(t *testing.T) {
+	t.Run("deletes address on valid ID and credentials", func(t *testing.T) {
 		peterJWT, _ := auth.GenerateJWT(testEnv.SecretKey, testEnv.ExpiresAt, td.PeterCustomer.Id)
 		deleteAddressRequest := handlers.DeleteAddressRequest{Id: td.PeterAddress1.Id}
 
@@ -200,7 +311,7 @@ func TestSaveCustomerAddress(t *testing.T) {
 	customerData := []models.Customer{td.PeterCustomer, td.AliceCustomer}
 	stubAddressStore := testutil.NewStubAddressStore(addressData)
 	stubCustomerStore := testutil.NewStubCustomerStore(customerData)
-	server := handlers.NewCustomerAddressServer(stubAddressStore, stubCustomerStore, testEnv.SecretKey)
+	server := handlers.NewCustomerAddressServer(stubAddressStore, stubCustomerStore, testEnv.SecretKey, testutil.NewStubHouseholdStore(nil, nil, nil))
 
 	t.Run("returns Bad Request on inavlid request", func(t *testing.T) {
 		peterJWT, _ := auth.GenerateJWT(testEnv.SecretKey, testEnv.ExpiresAt, td.PeterCustomer.Id)
@@ -224,6 +335,22 @@ func TestSaveCustomerAddress(t *testing.T) {
 		testutil.AssertStatus(t, response.Code, http.StatusNotFound)
 	})
 
+	t.Run("returns Not Found on anonymized user", func(t *testing.T) {
+		anonymized, _ := models.EraseCustomerPII(td.AliceCustomer)
+		customerStore := testutil.NewStubCustomerStore([]models.Customer{td.PeterCustomer, anonymized})
+		server := handlers.NewCustomerAddressServer(stubAddressStore, customerStore, testEnv.SecretKey, testutil.NewStubHouseholdStore(nil, nil, nil))
+
+		aliceJWT, _ := auth.GenerateJWT(testEnv.SecretKey, testEnv.ExpiresAt, td.AliceCustomer.Id)
+
+		request := handlers.NewCreateAddressRequest(aliceJWT, td.AliceAddress)
+		response := httptest.NewRecorder()
+
+		server.ServeHTTP(response, request)
+
+		testutil.AssertStatus(t, response.Code, http.StatusNotFound)
+		testutil.AssertErrorResponse(t, response.Body, handlers.ErrCustomerNotFound)
+	})
+
 	t.Run("saves Peter's new address", func(t *testing.T) {
 		peterJWT, _ := auth.GenerateJWT(testEnv.SecretKey, testEnv.ExpiresAt, td.PeterCustomer.Id)
 
@@ -249,60 +376,843 @@ func TestSaveCustomerAddress(t *testing.T) {
 		testutil.AssertStatus(t, response.Code, http.StatusOK)
 		testutil.AssertStoredAddress(t, stubAddressStore, td.AliceAddress)
 	})
+
+	t.Run("returns Bad Request on invalid contact phone", func(t *testing.T) {
+		address := td.PeterAddress2
+		address.ContactPhone = "0881234567"
+
+		peterJWT, _ := auth.GenerateJWT(testEnv.SecretKey, testEnv.ExpiresAt, td.PeterCustomer.Id)
+
+		request := handlers.NewCreateAddressRequest(peterJWT, address)
+		response := httptest.NewRecorder()
+
+		server.ServeHTTP(response, request)
+
+		testutil.AssertStatus(t, response.Code, http.StatusBadRequest)
+	})
+
+	t.Run("makes the first address the default and moves it on request", func(t *testing.T) {
+		stubAddressStore.Empty()
+
+		peterJWT, _ := auth.GenerateJWT(testEnv.SecretKey, testEnv.ExpiresAt, td.PeterCustomer.Id)
+
+		first := td.PeterAddress1
+		first.IsDefault = false
+		response := httptest.NewRecorder()
+		server.ServeHTTP(response, handlers.NewCreateAddressRequest(peterJWT, first))
+
+		testutil.AssertStatus(t, response.Code, http.StatusOK)
+		got := testutil.ParseAddressResponse(t, response.Body)
+		testutil.AssertEqual(t, got.IsDefault, true)
+
+		second := td.PeterAddress2
+		second.IsDefault = true
+		second.Label = "Work"
+		response = httptest.NewRecorder()
+		server.ServeHTTP(response, handlers.NewCreateAddressRequest(peterJWT, second))
+
+		testutil.AssertStatus(t, response.Code, http.StatusOK)
+
+		previous, _ := stubAddressStore.GetAddressByID(got.Id)
+		testutil.AssertEqual(t, previous.IsDefault, false)
+	})
 }
 
-func TestGetCustomerAddress(t *testing.T) {
-	addressData := []models.Address{td.PeterAddress1, td.PeterAddress2, td.AliceAddress}
+func TestGeocodeCustomerAddress(t *testing.T) {
 	customerData := []models.Customer{td.PeterCustomer, td.AliceCustomer}
-	stubAddressStore := testutil.NewStubAddressStore(addressData)
+	stubAddressStore := testutil.NewStubAddressStore([]models.Address{td.PeterAddress1, td.PeterAddress2})
 	stubCustomerStore := testutil.NewStubCustomerStore(customerData)
-	server := handlers.NewCustomerAddressServer(stubAddressStore, stubCustomerStore, testEnv.SecretKey)
+	stubGeocoder := testutil.NewStubGeocoder(map[string]geocoding.Location{
+		td.PeterAddress1.AddressLine1: {Lat: td.PeterAddress1.Lat, Lon: td.PeterAddress1.Lon, Precision: geocoding.ADDRESS_PRECISION},
+		"Slivnitsa Blvd 2":            {Lat: 42.7049, Lon: 23.3112, Precision: geocoding.ADDRESS_PRECISION},
+	})
+	server := handlers.NewCustomerAddressServer(stubAddressStore, stubCustomerStore, testEnv.SecretKey, testutil.NewStubHouseholdStore(nil, nil, nil))
 
-	t.Run("returns Peter's addresses", func(t *testing.T) {
-		peterJWT, _ := auth.GenerateJWT(testEnv.SecretKey, testEnv.ExpiresAt, td.PeterCustomer.Id)
-		request := handlers.NewGetAddressRequest(peterJWT)
+	peterJWT, _ := auth.GenerateJWT(testEnv.SecretKey, testEnv.ExpiresAt, td.PeterCustomer.Id)
+
+	t.Run("returns Bad Request on missing coordinates without geocoder", func(t *testing.T) {
+		address := td.PeterAddress1
+		address.Lat, address.Lon = 0, 0
+
+		request := handlers.NewCreateAddressRequest(peterJWT, address)
+		response := httptest.NewRecorder()
+
+		server.ServeHTTP(response, request)
+
+		testutil.AssertStatus(t, response.Code, http.StatusBadRequest)
+		testutil.AssertErrorResponse(t, response.Body, handlers.ErrMissingCoordinates)
+	})
+
+	server.SetGeocoder(stubGeocoder)
+
+	t.Run("fills in missing coordinates", func(t *testing.T) {
+		address := td.PeterAddress1
+		address.Lat, address.Lon = 0, 0
+
+		request := handlers.NewCreateAddressRequest(peterJWT, address)
 		response := httptest.NewRecorder()
 
 		server.ServeHTTP(response, request)
 
 		testutil.AssertStatus(t, response.Code, http.StatusOK)
 
-		want := []handlers.GetAddressResponse{
-			handlers.AddressToGetAddressResponse(td.PeterAddress1),
-			handlers.AddressToGetAddressResponse(td.PeterAddress2),
-		}
-		var got []handlers.GetAddressResponse
-		json.NewDecoder(response.Body).Decode(&got)
+		got := testutil.ParseAddressResponse(t, response.Body)
+		testutil.AssertEqual(t, [2]float64{got.Lat, got.Lon}, [2]float64{td.PeterAddress1.Lat, td.PeterAddress1.Lon})
+		testutil.AssertEqual(t, got.CoordinatesMismatch, false)
+	})
 
-		testutil.AssertEqual(t, got, want)
+	t.Run("flags coordinates far from the address", func(t *testing.T) {
+		address := td.PeterAddress1
+		address.Lat, address.Lon = 42.7049, 23.3112
+
+		request := handlers.NewCreateAddressRequest(peterJWT, address)
+		response := httptest.NewRecorder()
+
+		server.ServeHTTP(response, request)
+
+		testutil.AssertStatus(t, response.Code, http.StatusOK)
+
+		got := testutil.ParseAddressResponse(t, response.Body)
+		testutil.AssertEqual(t, got.CoordinatesMismatch, true)
 	})
 
-	t.Run("returns Alice's addresses", func(t *testing.T) {
-		aliceJWT, _ := auth.GenerateJWT(testEnv.SecretKey, testEnv.ExpiresAt, td.AliceCustomer.Id)
-		request := handlers.NewGetAddressRequest(aliceJWT)
+	t.Run("accepts coordinates of an unknown address", func(t *testing.T) {
+		request := handlers.NewCreateAddressRequest(peterJWT, td.PeterAddress2)
 		response := httptest.NewRecorder()
 
 		server.ServeHTTP(response, request)
 
 		testutil.AssertStatus(t, response.Code, http.StatusOK)
 
-		want := []handlers.GetAddressResponse{
-			handlers.AddressToGetAddressResponse(td.AliceAddress),
-		}
-		var got []handlers.GetAddressResponse
+		got := testutil.ParseAddressResponse(t, response.Body)
+		testutil.AssertEqual(t, got.CoordinatesMismatch, false)
+	})
+
+	t.Run("returns Unprocessable Entity on unknown address without coordinates", func(t *testing.T) {
+		address := td.PeterAddress2
+		address.Lat, address.Lon = 0, 0
+
+		request := handlers.NewCreateAddressRequest(peterJWT, address)
+		response := httptest.NewRecorder()
+
+		server.ServeHTTP(response, request)
+
+		testutil.AssertStatus(t, response.Code, http.StatusUnprocessableEntity)
+		testutil.AssertErrorResponse(t, response.Body, handlers.ErrUnknownAddress)
+	})
+
+	t.Run("locates patched address lines", func(t *testing.T) {
+		patch := map[string]any{"AddressLine1": "Slivnitsa Blvd 2"}
+		request := handlers.NewPatchAddressRequest(peterJWT, td.PeterAddress2.Id, patch)
+		response := httptest.NewRecorder()
+
+		server.ServeHTTP(response, request)
+
+		testutil.AssertStatus(t, response.Code, http.StatusOK)
+
+		got := testutil.ParseAddressResponse(t, response.Body)
+		testutil.AssertEqual(t, [2]float64{got.Lat, got.Lon}, [2]float64{42.7049, 23.3112})
+	})
+}
+
+func TestReverseGeocodeAddress(t *testing.T) {
+	customerData := []models.Customer{td.PeterCustomer, td.AliceCustomer}
+	stubAddressStore := testutil.NewStubAddressStore(nil)
+	stubCustomerStore := testutil.NewStubCustomerStore(customerData)
+	stubGeocoder := testutil.NewStubGeocoder(nil)
+	stubGeocoder.Places = []geocoding.Place{{
+		AddressLine1: td.PeterAddress1.AddressLine1,
+		City:         td.PeterAddress1.City,
+		Country:      td.PeterAddress1.Country,
+		Location:     geocoding.Location{Lat: td.PeterAddress1.Lat, Lon: td.PeterAddress1.Lon, Precision: geocoding.ADDRESS_PRECISION},
+	}}
+	server := handlers.NewCustomerAddressServer(stubAddressStore, stubCustomerStore, testEnv.SecretKey, testutil.NewStubHouseholdStore(nil, nil, nil))
+
+	peterJWT, _ := auth.GenerateJWT(testEnv.SecretKey, testEnv.ExpiresAt, td.PeterCustomer.Id)
+
+	t.Run("returns Not Implemented without reverse geocoder", func(t *testing.T) {
+		request := handlers.NewReverseGeocodeRequest(peterJWT, td.PeterAddress1.Lat, td.PeterAddress1.Lon)
+		response := httptest.NewRecorder()
+
+		server.ServeHTTP(response, request)
+
+		testutil.AssertStatus(t, response.Code, http.StatusNotImplemented)
+	})
+
+	server.SetReverseGeocoder(stubGeocoder)
+
+	t.Run("returns Unauthorized on invalid JWT", func(t *testing.T) {
+		request := handlers.NewReverseGeocodeRequest("thisIsAnInvalidJWT", td.PeterAddress1.Lat, td.PeterAddress1.Lon)
+		response := httptest.NewRecorder()
+
+		server.ServeHTTP(response, request)
+
+		testutil.AssertStatus(t, response.Code, http.StatusUnauthorized)
+	})
+
+	t.Run("returns Bad Request on invalid coordinates", func(t *testing.T) {
+		request := handlers.NewReverseGeocodeRequest(peterJWT, 91, td.PeterAddress1.Lon)
+		response := httptest.NewRecorder()
+
+		server.ServeHTTP(response, request)
+
+		testutil.AssertStatus(t, response.Code, http.StatusBadRequest)
+	})
+
+	t.Run("suggests the address near the pin", func(t *testing.T) {
+		lat, lon := td.PeterAddress1.Lat+0.0001, td.PeterAddress1.Lon
+		request := handlers.NewReverseGeocodeRequest(peterJWT, lat, lon)
+		response := httptest.NewRecorder()
+
+		server.ServeHTTP(response, request)
+
+		testutil.AssertStatus(t, response.Code, http.StatusOK)
+
+		var got handlers.ReverseGeocodeResponse
 		json.NewDecoder(response.Body).Decode(&got)
 
-		testutil.AssertEqual(t, got, want)
+		testutil.AssertEqual(t, got.AddressLine1, td.PeterAddress1.AddressLine1)
+		testutil.AssertEqual(t, got.City, td.PeterAddress1.City)
+		testutil.AssertEqual(t, [2]float64{got.Lat, got.Lon}, [2]float64{lat, lon})
+		testutil.AssertEqual(t, got.Precision, geocoding.ADDRESS_PRECISION)
 	})
 
-	t.Run("returns Not Found on missing user", func(t *testing.T) {
-		aliceJWT, _ := auth.GenerateJWT(testEnv.SecretKey, testEnv.ExpiresAt, 10)
-		request := handlers.NewGetAddressRequest(aliceJWT)
+	t.Run("returns Not Found far from known addresses", func(t *testing.T) {
+		request := handlers.NewReverseGeocodeRequest(peterJWT, 43.2141, 27.9147)
 		response := httptest.NewRecorder()
 
 		server.ServeHTTP(response, request)
 
 		testutil.AssertStatus(t, response.Code, http.StatusNotFound)
-		testutil.AssertErrorResponse(t, response.Body, handlers.ErrCustomerNotFound)
+		testutil.AssertErrorResponse(t, response.Body, handlers.ErrUnknownLocation)
+	})
+}
+
+func TestDeliveryArea(t *testing.T) {
+	customerData := []models.Customer{td.PeterCustomer, td.AliceCustomer}
+	stubAddressStore := testutil.NewStubAddressStore([]models.Address{td.PeterAddress1, td.PeterAddress2})
+	stubCustomerStore := testutil.NewStubCustomerStore(customerData)
+	server := handlers.NewCustomerAddressServer(stubAddressStore, stubCustomerStore, testEnv.SecretKey, testutil.NewStubHouseholdStore(nil, nil, nil))
+
+	peterJWT, _ := auth.GenerateJWT(testEnv.SecretKey, testEnv.ExpiresAt, td.PeterCustomer.Id)
+
+	t.Run("returns every address as deliverable without delivery area", func(t *testing.T) {
+		request := handlers.NewGetDeliverableAddressesRequest(peterJWT)
+		response := httptest.NewRecorder()
+
+		server.ServeHTTP(response, request)
+
+		testutil.AssertStatus(t, response.Code, http.StatusOK)
+
+		var got []handlers.DeliverableAddressResponse
+		json.NewDecoder(response.Body).Decode(&got)
+		testutil.AssertEqual(t, got, []handlers.DeliverableAddressResponse{
+			{Id: td.PeterAddress1.Id, Deliverable: true},
+			{Id: td.PeterAddress2.Id, Deliverable: true},
+		})
+	})
+
+	// a square around Peter's first address that leaves out his second one
+	square := `{"type": "Feature", "properties": {"name": "shipka"}, "geometry": {"type": "Polygon",
+		"coordinates": [[[23.325, 42.690], [23.333, 42.690], [23.333, 42.700], [23.325, 42.700]]]}}`
+	parsed, err := zones.ParseGeoJSON(strings.NewReader(square), "test.geojson")
+	if err != nil {
+		t.Fatalf("couldn't parse zones: %v", err)
+	}
+	server.SetDeliveryArea(zones.NewZones(parsed))
+
+	t.Run("tells which addresses are deliverable", func(t *testing.T) {
+		request := handlers.NewGetDeliverableAddressesRequest(peterJWT)
+		response := httptest.NewRecorder()
+
+		server.ServeHTTP(response, request)
+
+		testutil.AssertStatus(t, response.Code, http.StatusOK)
+
+		var got []handlers.DeliverableAddressResponse
+		json.NewDecoder(response.Body).Decode(&got)
+		testutil.AssertEqual(t, got, []handlers.DeliverableAddressResponse{
+			{Id: td.PeterAddress1.Id, Deliverable: true, Zone: "shipka"},
+			{Id: td.PeterAddress2.Id, Deliverable: false},
+		})
+	})
+
+	t.Run("creates address inside the delivery area", func(t *testing.T) {
+		request := handlers.NewCreateAddressRequest(peterJWT, td.PeterAddress1)
+		response := httptest.NewRecorder()
+
+		server.ServeHTTP(response, request)
+
+		testutil.AssertStatus(t, response.Code, http.StatusOK)
+	})
+
+	t.Run("returns Unprocessable Entity on new address outside the delivery area", func(t *testing.T) {
+		request := handlers.NewCreateAddressRequest(peterJWT, td.PeterAddress2)
+		response := httptest.NewRecorder()
+
+		server.ServeHTTP(response, request)
+
+		testutil.AssertStatus(t, response.Code, http.StatusUnprocessableEntity)
+		testutil.AssertErrorResponse(t, response.Body, handlers.ErrOutsideDeliveryArea)
+	})
+
+	t.Run("updates address outside the delivery area that doesn't move", func(t *testing.T) {
+		updatedAddress := td.PeterAddress2
+		updatedAddress.Label = "Office"
+
+		request := handlers.NewUpdateAddressRequest(peterJWT, updatedAddress)
+		response := httptest.NewRecorder()
+
+		server.ServeHTTP(response, request)
+
+		testutil.AssertStatus(t, response.Code, http.StatusOK)
+	})
+
+	t.Run("returns Unprocessable Entity on address moved outside the delivery area", func(t *testing.T) {
+		patch := map[string]any{"Lat": td.PeterAddress2.Lat, "Lon": td.PeterAddress2.Lon}
+		request := handlers.NewPatchAddressRequest(peterJWT, td.PeterAddress1.Id, patch)
+		response := httptest.NewRecorder()
+
+		server.ServeHTTP(response, request)
+
+		testutil.AssertStatus(t, response.Code, http.StatusUnprocessableEntity)
+		testutil.AssertErrorResponse(t, response.Body, handlers.ErrOutsideDeliveryArea)
+	})
+}
+
+func TestCountryRules(t *testing.T) {
+	customerData := []models.Customer{td.PeterCustomer, td.AliceCustomer}
+	stubAddressStore := testutil.NewStubAddressStore([]models.Address{td.PeterAddress1, td.PeterAddress2})
+	stubCustomerStore := testutil.NewStubCustomerStore(customerData)
+	server := handlers.NewCustomerAddressServer(stubAddressStore, stubCustomerStore, testEnv.SecretKey, testutil.NewStubHouseholdStore(nil, nil, nil))
+
+	peterJWT, _ := auth.GenerateJWT(testEnv.SecretKey, testEnv.ExpiresAt, td.PeterCustomer.Id)
+
+	t.Run("saves normalized postal code", func(t *testing.T) {
+		newAddress := td.PeterAddress1
+		newAddress.PostalCode = " 1000"
+
+		request := handlers.NewCreateAddressRequest(peterJWT, newAddress)
+		response := httptest.NewRecorder()
+
+		server.ServeHTTP(response, request)
+
+		testutil.AssertStatus(t, response.Code, http.StatusOK)
+
+		got := testutil.ParseAddressResponse(t, response.Body)
+		testutil.AssertEqual(t, got.PostalCode, "1000")
+	})
+
+	t.Run("returns Bad Request on country name", func(t *testing.T) {
+		newAddress := td.PeterAddress1
+		newAddress.Country = "Bulgaria"
+
+		request := handlers.NewCreateAddressRequest(peterJWT, newAddress)
+		response := httptest.NewRecorder()
+
+		server.ServeHTTP(response, request)
+
+		testutil.AssertStatus(t, response.Code, http.StatusBadRequest)
+	})
+
+	t.Run("returns Bad Request on postal code of another format", func(t *testing.T) {
+		updatedAddress := td.PeterAddress2
+		updatedAddress.PostalCode = "10000"
+
+		request := handlers.NewUpdateAddressRequest(peterJWT, updatedAddress)
+		response := httptest.NewRecorder()
+
+		server.ServeHTTP(response, request)
+
+		testutil.AssertStatus(t, response.Code, http.StatusBadRequest)
+		testutil.AssertErrorResponse(t, response.Body, models.ErrInvalidPostalCode)
+	})
+
+	t.Run("returns Bad Request on patch to a country that requires a postal code", func(t *testing.T) {
+		request := handlers.NewPatchAddressRequest(peterJWT, td.PeterAddress2.Id, map[string]any{"Country": "DE"})
+		response := httptest.NewRecorder()
+
+		server.ServeHTTP(response, request)
+
+		testutil.AssertStatus(t, response.Code, http.StatusBadRequest)
+		testutil.AssertErrorResponse(t, response.Body, models.ErrMissingPostalCode)
+	})
+
+	t.Run("patches country together with postal code", func(t *testing.T) {
+		request := handlers.NewPatchAddressRequest(peterJWT, td.PeterAddress2.Id,
+			map[string]any{"Country": "NL", "PostalCode": "1012ab"})
+		response := httptest.NewRecorder()
+
+		server.ServeHTTP(response, request)
+
+		testutil.AssertStatus(t, response.Code, http.StatusOK)
+
+		got := testutil.ParseAddressResponse(t, response.Body)
+		testutil.AssertEqual(t, got.Country, "NL")
+		testutil.AssertEqual(t, got.PostalCode, "1012 AB")
+	})
+}
+
+func TestFormatAddress(t *testing.T) {
+	customerData := []models.Customer{td.PeterCustomer, td.AliceCustomer}
+	stubAddressStore := testutil.NewStubAddressStore([]models.Address{td.PeterAddress1})
+	stubCustomerStore := testutil.NewStubCustomerStore(customerData)
+	server := handlers.NewCustomerAddressServer(stubAddressStore, stubCustomerStore, testEnv.SecretKey, testutil.NewStubHouseholdStore(nil, nil, nil))
+
+	peterJWT, _ := auth.GenerateJWT(testEnv.SecretKey, testEnv.ExpiresAt, td.PeterCustomer.Id)
+
+	t.Run("formats unsaved address", func(t *testing.T) {
+		formatAddressRequest := handlers.FormatAddressRequest{
+			AddressLine1: "10 Downing Street",
+			City:         "London",
+			PostalCode:   "sw1a2aa",
+			Country:      "GB",
+		}
+
+		request := handlers.NewFormatAddressRequest(peterJWT, formatAddressRequest)
+		response := httptest.NewRecorder()
+
+		server.ServeHTTP(response, request)
+
+		testutil.AssertStatus(t, response.Code, http.StatusOK)
+
+		var got handlers.FormattedAddress
+		json.NewDecoder(response.Body).Decode(&got)
+		testutil.AssertEqual(t, got, handlers.FormattedAddress{
+			SingleLine: "10 Downing Street, London, SW1A 2AA, United Kingdom",
+			MultiLine:  "10 Downing Street\nLondon\nSW1A 2AA\nUnited Kingdom",
+			Label:      "10 Downing Street\nLONDON\nSW1A 2AA\nUNITED KINGDOM",
+		})
+	})
+
+	t.Run("returns Bad Request on address without city", func(t *testing.T) {
+		request := handlers.NewFormatAddressRequest(peterJWT, handlers.FormatAddressRequest{AddressLine1: "Shipka Street 6", Country: "BG"})
+		response := httptest.NewRecorder()
+
+		server.ServeHTTP(response, request)
+
+		testutil.AssertStatus(t, response.Code, http.StatusBadRequest)
+	})
+
+	t.Run("formats saved addresses", func(t *testing.T) {
+		request := handlers.NewGetAddressRequest(peterJWT)
+		response := httptest.NewRecorder()
+
+		server.ServeHTTP(response, request)
+
+		testutil.AssertStatus(t, response.Code, http.StatusOK)
+
+		var got []handlers.GetAddressResponse
+		json.NewDecoder(response.Body).Decode(&got)
+		testutil.AssertEqual(t, got[0].Formatted.SingleLine, "Shipka Street 6, Sofia, Bulgaria")
+	})
+}
+
+func TestAddressResources(t *testing.T) {
+	customerData := []models.Customer{td.PeterCustomer, td.AliceCustomer}
+	addressData := []models.Address{td.PeterAddress1, td.PeterAddress2, td.AliceAddress}
+	stubAddressStore := testutil.NewStubAddressStore(addressData)
+	stubCustomerStore := testutil.NewStubCustomerStore(customerData)
+	server := handlers.NewCustomerAddressServer(stubAddressStore, stubCustomerStore, testEnv.SecretKey, testutil.NewStubHouseholdStore(nil, nil, nil))
+
+	peterJWT, _ := auth.GenerateJWT(testEnv.SecretKey, testEnv.ExpiresAt, td.PeterCustomer.Id)
+
+	t.Run("gets single address", func(t *testing.T) {
+		request := handlers.NewGetAddressByIDRequest(peterJWT, td.PeterAddress2.Id)
+		response := httptest.NewRecorder()
+
+		server.ServeHTTP(response, request)
+
+		testutil.AssertStatus(t, response.Code, http.StatusOK)
+
+		var got handlers.GetAddressResponse
+		json.NewDecoder(response.Body).Decode(&got)
+		testutil.AssertEqual(t, got, handlers.AddressToGetAddressResponse(td.PeterAddress2))
+
+		request = handlers.NewGetAddressByIDRequest(peterJWT, td.PeterAddress2.Id)
+		request.Header.Set("If-None-Match", response.Header().Get("ETag"))
+		response = httptest.NewRecorder()
+
+		server.ServeHTTP(response, request)
+
+		testutil.AssertStatus(t, response.Code, http.StatusNotModified)
+	})
+
+	t.Run("returns Unathorized on another customer's address", func(t *testing.T) {
+		request := handlers.NewGetAddressByIDRequest(peterJWT, td.AliceAddress.Id)
+		response := httptest.NewRecorder()
+
+		server.ServeHTTP(response, request)
+
+		testutil.AssertStatus(t, response.Code, http.StatusUnauthorized)
+		testutil.AssertErrorResponse(t, response.Body, handlers.ErrUnathorizedAction)
+	})
+
+	t.Run("returns Bad Request on body ID that doesn't match the path", func(t *testing.T) {
+		updatedAddress := td.PeterAddress2
+		updatedAddress.Label = "Office"
+
+		request := handlers.NewUpdateAddressRequest(peterJWT, updatedAddress)
+		request.URL.Path = "/customer/address/" + strconv.Itoa(td.PeterAddress1.Id)
+		response := httptest.NewRecorder()
+
+		server.ServeHTTP(response, request)
+
+		testutil.AssertStatus(t, response.Code, http.StatusBadRequest)
+		testutil.AssertErrorResponse(t, response.Body, handlers.ErrAddressIDMismatch)
+	})
+
+	t.Run("updates address with ID in body as deprecated", func(t *testing.T) {
+		updatedAddress := td.PeterAddress2
+		updatedAddress.Label = "Office"
+
+		request := handlers.NewUpdateAddressWithBodyIDRequest(peterJWT, updatedAddress)
+		response := httptest.NewRecorder()
+
+		server.ServeHTTP(response, request)
+
+		testutil.AssertStatus(t, response.Code, http.StatusOK)
+		if response.Header().Get("Deprecation") == "" {
+			t.Errorf("response doesn't have a Deprecation header")
+		}
+	})
+
+	t.Run("deletes address with ID in body as deprecated", func(t *testing.T) {
+		request := handlers.NewDeleteAddressWithBodyIDRequest(peterJWT, handlers.DeleteAddressRequest{Id: td.PeterAddress2.Id})
+		response := httptest.NewRecorder()
+
+		server.ServeHTTP(response, request)
+
+		testutil.AssertStatus(t, response.Code, http.StatusOK)
+		testutil.AssertDeletedAddress(t, stubAddressStore, td.PeterAddress2)
+		if response.Header().Get("Deprecation") == "" {
+			t.Errorf("response doesn't have a Deprecation header")
+		}
+	})
+
+	cases := []struct {
+		method string
+		path   string
+		allow  string
+	}{
+		{http.MethodPost, "/customer/address/1", "GET, PUT, PATCH, DELETE"},
+		{http.MethodPatch, "/customer/address/", "GET, POST, PUT, DELETE"},
+		{http.MethodGet, "/customer/address/reverse/", "POST"},
+		{http.MethodPost, "/customer/address/deliverable/", "GET"},
+	}
+
+	for _, test := range cases {
+		t.Run("returns Method Not Allowed on "+test.method+" "+test.path, func(t *testing.T) {
+			request, _ := http.NewRequest(test.method, test.path, nil)
+			request.Header.Add("Token", peterJWT)
+			response := httptest.NewRecorder()
+
+			server.ServeHTTP(response, request)
+
+			testutil.AssertStatus(t, response.Code, http.StatusMethodNotAllowed)
+			testutil.AssertEqual(t, response.Header().Get("Allow"), test.allow)
+		})
+	}
+}
+
+func TestGetCustomerAddress(t *testing.T) {
+	addressData := []models.Address{td.PeterAddress1, td.PeterAddress2, td.AliceAddress}
+	customerData := []models.Customer{td.PeterCustomer, td.AliceCustomer}
+	stubAddressStore := testutil.NewStubAddressStore(addressData)
+	stubCustomerStore := testutil.NewStubCustomerStore(customerData)
+	server := handlers.NewCustomerAddressServer(stubAddressStore, stubCustomerStore, testEnv.SecretKey, testutil.NewStubHouseholdStore(nil, nil, nil))
+
+	t.Run("returns Peter's addresses", func(t *testing.T) {
+		peterJWT, _ := auth.GenerateJWT(testEnv.SecretKey, testEnv.ExpiresAt, td.PeterCustomer.Id)
+		request := handlers.NewGetAddressRequest(peterJWT)
+		response := httptest.NewRecorder()
+
+		server.ServeHTTP(response, request)
+
+		testutil.AssertStatus(t, response.Code, http.StatusOK)
+
+		want := []handlers.GetAddressResponse{
+			handlers.AddressToGetAddressResponse(td.PeterAddress1),
+			handlers.AddressToGetAddressResponse(td.PeterAddress2),
+		}
+		var got []handlers.GetAddressResponse
+		json.NewDecoder(response.Body).Decode(&got)
+
+		testutil.AssertEqual(t, got, want)
+	})
+
+	t.Run("returns Alice's addresses", func(t *testing.T) {
+		aliceJWT, _ := auth.GenerateJWT(testEnv.SecretKey, testEnv.ExpiresAt, td.AliceCustomer.Id)
+		request := handlers.NewGetAddressRequest(aliceJWT)
+		response := httptest.NewRecorder()
+
+		server.ServeHTTP(response, request)
+
+		testutil.AssertStatus(t, response.Code, http.StatusOK)
+
+		want := []handlers.GetAddressResponse{
+			handlers.AddressToGetAddressResponse(td.AliceAddress),
+		}
+		var got []handlers.GetAddressResponse
+		json.NewDecoder(response.Body).Decode(&got)
+
+		testutil.AssertEqual(t, got, want)
+	})
+
+	t.Run("returns Not Modified on matching If-None-Match", func(t *testing.T) {
+		peterJWT, _ := auth.GenerateJWT(testEnv.SecretKey, testEnv.ExpiresAt, td.PeterCustomer.Id)
+		request := handlers.NewGetAddressRequest(peterJWT)
+		response := httptest.NewRecorder()
+
+		server.ServeHTTP(response, request)
+
+		etag := response.Header().Get("ETag")
+		if etag == "" {
+			t.Fatal("expected ETag header on address list")
+		}
+
+		request = handlers.NewGetAddressRequest(peterJWT)
+		request.Header.Set("If-None-Match", etag)
+		response = httptest.NewRecorder()
+
+		server.ServeHTTP(response, request)
+
+		testutil.AssertStatus(t, response.Code, http.StatusNotModified)
+	})
+
+	t.Run("returns Not Found on missing user", func(t *testing.T) {
+		aliceJWT, _ := auth.GenerateJWT(testEnv.SecretKey, testEnv.ExpiresAt, 10)
+		request := handlers.NewGetAddressRequest(aliceJWT)
+		response := httptest.NewRecorder()
+
+		server.ServeHTTP(response, request)
+
+		testutil.AssertStatus(t, response.Code, http.StatusNotFound)
+		testutil.AssertErrorResponse(t, response.Body, handlers.ErrCustomerNotFound)
+	})
+}
+
+func TestAddressRevisions(t *testing.T) {
+	customerData := []models.Customer{td.PeterCustomer, td.AliceCustomer}
+	addressData := []models.Address{td.PeterAddress1, td.PeterAddress2, td.AliceAddress}
+	stubAddressStore := testutil.NewStubAddressStore(addressData)
+	stubCustomerStore := testutil.NewStubCustomerStore(customerData)
+	server := handlers.NewCustomerAddressServer(stubAddressStore, stubCustomerStore, testEnv.SecretKey, testutil.NewStubHouseholdStore(nil, nil, nil))
+
+	peterJWT, _ := auth.GenerateJWT(testEnv.SecretKey, testEnv.ExpiresAt, td.PeterCustomer.Id)
+
+	t.Run("keeps the snapshot of a revision after the address is edited", func(t *testing.T) {
+		request := handlers.NewPatchAddressRequest(peterJWT, td.PeterAddress1.Id, map[string]any{"DeliveryInstructions": "Ring twice"})
+		response := httptest.NewRecorder()
+
+		server.ServeHTTP(response, request)
+
+		testutil.AssertStatus(t, response.Code, http.StatusOK)
+
+		var patched models.Address
+		json.NewDecoder(response.Body).Decode(&patched)
+		testutil.AssertEqual(t, patched.Version, td.PeterAddress1.Version+1)
+
+		request = handlers.NewGetAddressRevisionRequest(peterJWT, td.PeterAddress1.Id, td.PeterAddress1.Version)
+		response = httptest.NewRecorder()
+
+		server.ServeHTTP(response, request)
+
+		testutil.AssertStatus(t, response.Code, http.StatusOK)
+
+		var got handlers.AddressSnapshotResponse
+		json.NewDecoder(response.Body).Decode(&got)
+		want := handlers.AddressRevisionToAddressSnapshotResponse(models.NewAddressRevision(td.PeterAddress1))
+		testutil.AssertEqual(t, got, want)
+
+		request = handlers.NewGetAddressRevisionRequest(peterJWT, td.PeterAddress1.Id, patched.Version)
+		response = httptest.NewRecorder()
+
+		server.ServeHTTP(response, request)
+
+		testutil.AssertStatus(t, response.Code, http.StatusOK)
+
+		json.NewDecoder(response.Body).Decode(&got)
+		testutil.AssertEqual(t, got.DeliveryInstructions, "Ring twice")
+	})
+
+	t.Run("returns Unathorized on another customer's revision", func(t *testing.T) {
+		request := handlers.NewGetAddressRevisionRequest(peterJWT, td.AliceAddress.Id, td.AliceAddress.Version)
+		response := httptest.NewRecorder()
+
+		server.ServeHTTP(response, request)
+
+		testutil.AssertStatus(t, response.Code, http.StatusUnauthorized)
+		testutil.AssertErrorResponse(t, response.Body, handlers.ErrUnathorizedAction)
+	})
+
+	t.Run("returns Not Found on missing revision", func(t *testing.T) {
+		request := handlers.NewGetAddressRevisionRequest(peterJWT, td.PeterAddress2.Id, 10)
+		response := httptest.NewRecorder()
+
+		server.ServeHTTP(response, request)
+
+		testutil.AssertStatus(t, response.Code, http.StatusNotFound)
+		testutil.AssertErrorResponse(t, response.Body, handlers.ErrMissingRevision)
+	})
+
+	t.Run("returns Bad Request on invalid revision", func(t *testing.T) {
+		request := handlers.NewGetAddressRevisionRequest(peterJWT, td.PeterAddress2.Id, 1)
+		request.URL.Path = "/customer/address/2/revisions/latest"
+		response := httptest.NewRecorder()
+
+		server.ServeHTTP(response, request)
+
+		testutil.AssertStatus(t, response.Code, http.StatusBadRequest)
+		testutil.AssertErrorResponse(t, response.Body, handlers.ErrInvalidRevision)
+	})
+
+	t.Run("returns Method Not Allowed on changing a revision", func(t *testing.T) {
+		request := handlers.NewGetAddressRevisionRequest(peterJWT, td.PeterAddress2.Id, 1)
+		request.Method = http.MethodPut
+		response := httptest.NewRecorder()
+
+		server.ServeHTTP(response, request)
+
+		testutil.AssertStatus(t, response.Code, http.StatusMethodNotAllowed)
+		testutil.AssertEqual(t, response.Header().Get("Allow"), http.MethodGet)
+	})
+}
+
+func TestHouseholdAddresses(t *testing.T) {
+	newServer := func(members []models.HouseholdMember) (*handlers.CustomerAddressServer, *testutil.StubAddressStore) {
+		addressData := []models.Address{td.PeterAddress1, td.PeterAddress2, td.AliceAddress, td.SmithHouseholdAddress}
+		addressStore := testutil.NewStubAddressStore(addressData)
+		customerStore := testutil.NewStubCustomerStore([]models.Customer{td.PeterCustomer, td.AliceCustomer})
+		householdStore := testutil.NewStubHouseholdStore([]models.Household{td.SmithHousehold}, members, nil)
+		return handlers.NewCustomerAddressServer(addressStore, customerStore, testEnv.SecretKey, householdStore), addressStore
+	}
+
+	peterJWT, _ := auth.GenerateJWT(testEnv.SecretKey, testEnv.ExpiresAt, td.PeterCustomer.Id)
+	aliceJWT, _ := auth.GenerateJWT(testEnv.SecretKey, testEnv.ExpiresAt, td.AliceCustomer.Id)
+
+	t.Run("lets members get household address", func(t *testing.T) {
+		server, _ := newServer([]models.HouseholdMember{td.PeterHouseholdOwner, td.AliceHouseholdMember})
+
+		request := handlers.NewGetAddressByIDRequest(peterJWT, td.SmithHouseholdAddress.Id)
+		response := httptest.NewRecorder()
+
+		server.ServeHTTP(response, request)
+
+		testutil.AssertStatus(t, response.Code, http.StatusOK)
+
+		var got handlers.GetAddressResponse
+		json.NewDecoder(response.Body).Decode(&got)
+		testutil.AssertEqual(t, got, handlers.AddressToGetAddressResponse(td.SmithHouseholdAddress))
+	})
+
+	t.Run("lists household addresses after personal ones", func(t *testing.T) {
+		server, _ := newServer([]models.HouseholdMember{td.PeterHouseholdOwner, td.AliceHouseholdMember})
+
+		request := handlers.NewGetAddressRequest(peterJWT)
+		response := httptest.NewRecorder()
+
+		server.ServeHTTP(response, request)
+
+		testutil.AssertStatus(t, response.Code, http.StatusOK)
+
+		var got []handlers.GetAddressResponse
+		json.NewDecoder(response.Body).Decode(&got)
+		want := []handlers.GetAddressResponse{
+			handlers.AddressToGetAddressResponse(td.PeterAddress1),
+			handlers.AddressToGetAddressResponse(td.PeterAddress2),
+			handlers.AddressToGetAddressResponse(td.SmithHouseholdAddress),
+		}
+		testutil.AssertEqual(t, got, want)
+	})
+
+	t.Run("returns Unathorized on household address of non-member", func(t *testing.T) {
+		server, _ := newServer([]models.HouseholdMember{td.PeterHouseholdOwner})
+
+		request := handlers.NewGetAddressByIDRequest(aliceJWT, td.SmithHouseholdAddress.Id)
+		response := httptest.NewRecorder()
+
+		server.ServeHTTP(response, request)
+
+		testutil.AssertStatus(t, response.Code, http.StatusUnauthorized)
+		testutil.AssertErrorResponse(t, response.Body, handlers.ErrUnathorizedAction)
+	})
+
+	t.Run("lets owner delete address another member added", func(t *testing.T) {
+		server, addressStore := newServer([]models.HouseholdMember{td.PeterHouseholdOwner, td.AliceHouseholdMember})
+
+		request := handlers.NewDeleteAddressRequest(peterJWT, handlers.DeleteAddressRequest{Id: td.SmithHouseholdAddress.Id})
+		response := httptest.NewRecorder()
+
+		server.ServeHTTP(response, request)
+
+		testutil.AssertStatus(t, response.Code, http.StatusOK)
+		testutil.AssertDeletedAddress(t, addressStore, td.SmithHouseholdAddress)
+	})
+
+	t.Run("returns Unathorized on member deleting address another member added", func(t *testing.T) {
+		peterMember := td.PeterHouseholdOwner
+		peterMember.Role = models.MEMBER_ROLE
+		aliceOwner := td.AliceHouseholdMember
+		aliceOwner.Role = models.OWNER_ROLE
+		server, _ := newServer([]models.HouseholdMember{peterMember, aliceOwner})
+
+		request := handlers.NewDeleteAddressRequest(peterJWT, handlers.DeleteAddressRequest{Id: td.SmithHouseholdAddress.Id})
+		response := httptest.NewRecorder()
+
+		server.ServeHTTP(response, request)
+
+		testutil.AssertStatus(t, response.Code, http.StatusUnauthorized)
+		testutil.AssertErrorResponse(t, response.Body, handlers.ErrUnathorizedAction)
+	})
+
+	t.Run("adds address to household", func(t *testing.T) {
+		server, addressStore := newServer([]models.HouseholdMember{td.PeterHouseholdOwner, td.AliceHouseholdMember})
+
+		newAddress := td.PeterAddress2
+		newAddress.HouseholdId = &td.SmithHousehold.Id
+
+		request := handlers.NewCreateAddressRequest(peterJWT, newAddress)
+		response := httptest.NewRecorder()
+
+		server.ServeHTTP(response, request)
+
+		testutil.AssertStatus(t, response.Code, http.StatusOK)
+
+		var got models.Address
+		json.NewDecoder(response.Body).Decode(&got)
+		testutil.AssertEqual(t, *got.HouseholdId, td.SmithHousehold.Id)
+		testutil.AssertEqual(t, got.IsDefault, false)
+
+		stored, _ := addressStore.GetAddressByID(got.Id)
+		testutil.AssertEqual(t, *stored.HouseholdId, td.SmithHousehold.Id)
+	})
+
+	t.Run("returns Unathorized on adding address to household of non-member", func(t *testing.T) {
+		server, _ := newServer([]models.HouseholdMember{td.PeterHouseholdOwner})
+
+		newAddress := td.AliceAddress
+		newAddress.IsDefault = false
+		newAddress.HouseholdId = &td.SmithHousehold.Id
+
+		request := handlers.NewCreateAddressRequest(aliceJWT, newAddress)
+		response := httptest.NewRecorder()
+
+		server.ServeHTTP(response, request)
+
+		testutil.AssertStatus(t, response.Code, http.StatusUnauthorized)
+		testutil.AssertErrorResponse(t, response.Body, handlers.ErrUnathorizedAction)
+	})
+
+	t.Run("returns Bad Request on making household address the default", func(t *testing.T) {
+		server, _ := newServer([]models.HouseholdMember{td.PeterHouseholdOwner, td.AliceHouseholdMember})
+
+		request := handlers.NewPatchAddressRequest(aliceJWT, td.SmithHouseholdAddress.Id, map[string]any{"IsDefault": true})
+		response := httptest.NewRecorder()
+
+		server.ServeHTTP(response, request)
+
+		testutil.AssertStatus(t, response.Code, http.StatusBadRequest)
+		testutil.AssertErrorResponse(t, response.Body, handlers.ErrHouseholdDefault)
 	})
 }
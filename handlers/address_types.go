@@ -1,26 +1,49 @@
 package handlers
 
-import "github.com/VitoNaychev/bt-customer-svc/models"
+import (
+	"time"
+
+	"github.com/VitoNaychev/bt-customer-svc/geocoding"
+	"github.com/VitoNaychev/bt-customer-svc/models"
+)
 
 type UpdateAddressRequest struct {
-	Id           int     `validate:"min=0"`
-	Lat          float64 `validate:"latitude,required"`
-	Lon          float64 `validate:"longitude,required"`
-	AddressLine1 string  `validate:"required,max=40"`
-	AddressLine2 string  `validate:"max=40"`
-	City         string  `validate:"required,max=40"`
-	Country      string  `validate:"required,max=20"`
+	Id                   int     `validate:"min=0"`
+	Lat                  float64 `validate:"latitude"`
+	Lon                  float64 `validate:"longitude"`
+	AddressLine1         string  `validate:"required,max=40"`
+	AddressLine2         string  `validate:"max=40"`
+	City                 string  `validate:"required,max=40"`
+	Region               string  `validate:"max=40"`
+	PostalCode           string  `validate:"max=10"`
+	Country              string  `validate:"required,iso3166_1_alpha2"`
+	Label                string  `validate:"max=20"`
+	IsDefault            bool
+	DeliveryInstructions string `validate:"max=255"`
+	Floor                string `validate:"max=10"`
+	Entrance             string `validate:"max=10"`
+	Apartment            string `validate:"max=10"`
+	ContactPhone         string `validate:"omitempty,e164"`
 }
 
 func AddressToUpdateAddressRequest(address models.Address) UpdateAddressRequest {
 	updateAddressRequest := UpdateAddressRequest{
-		Id:           address.Id,
-		Lat:          address.Lat,
-		Lon:          address.Lon,
-		AddressLine1: address.AddressLine1,
-		AddressLine2: address.AddressLine2,
-		City:         address.City,
-		Country:      address.Country,
+		Id:                   address.Id,
+		Lat:                  address.Lat,
+		Lon:                  address.Lon,
+		AddressLine1:         address.AddressLine1,
+		AddressLine2:         address.AddressLine2,
+		City:                 address.City,
+		Region:               address.Region,
+		PostalCode:           address.PostalCode,
+		Country:              address.Country,
+		Label:                address.Label,
+		IsDefault:            address.IsDefault,
+		DeliveryInstructions: address.DeliveryInstructions,
+		Floor:                address.Floor,
+		Entrance:             address.Entrance,
+		Apartment:            address.Apartment,
+		ContactPhone:         address.ContactPhone,
 	}
 
 	return updateAddressRequest
@@ -28,40 +51,113 @@ func AddressToUpdateAddressRequest(address models.Address) UpdateAddressRequest
 
 func UpdateAddressRequestToAddress(UpdateAddressRequest UpdateAddressRequest, customerId int) models.Address {
 	address := models.Address{
-		Id:           UpdateAddressRequest.Id,
-		CustomerId:   customerId,
-		Lat:          UpdateAddressRequest.Lat,
-		Lon:          UpdateAddressRequest.Lon,
-		AddressLine1: UpdateAddressRequest.AddressLine1,
-		AddressLine2: UpdateAddressRequest.AddressLine2,
-		City:         UpdateAddressRequest.City,
-		Country:      UpdateAddressRequest.Country,
+		Id:                   UpdateAddressRequest.Id,
+		CustomerId:           customerId,
+		Lat:                  UpdateAddressRequest.Lat,
+		Lon:                  UpdateAddressRequest.Lon,
+		AddressLine1:         UpdateAddressRequest.AddressLine1,
+		AddressLine2:         UpdateAddressRequest.AddressLine2,
+		City:                 UpdateAddressRequest.City,
+		Region:               UpdateAddressRequest.Region,
+		PostalCode:           UpdateAddressRequest.PostalCode,
+		Country:              UpdateAddressRequest.Country,
+		Label:                UpdateAddressRequest.Label,
+		IsDefault:            UpdateAddressRequest.IsDefault,
+		DeliveryInstructions: UpdateAddressRequest.DeliveryInstructions,
+		Floor:                UpdateAddressRequest.Floor,
+		Entrance:             UpdateAddressRequest.Entrance,
+		Apartment:            UpdateAddressRequest.Apartment,
+		ContactPhone:         UpdateAddressRequest.ContactPhone,
 	}
 
 	return address
 }
 
+type PatchAddressRequest struct {
+	Lat                  *float64 `validate:"omitnil,latitude,required"`
+	Lon                  *float64 `validate:"omitnil,longitude,required"`
+	AddressLine1         *string  `validate:"omitnil,required,max=40"`
+	AddressLine2         *string  `validate:"omitnil,max=40"`
+	City                 *string  `validate:"omitnil,required,max=40"`
+	Region               *string  `validate:"omitnil,max=40"`
+	PostalCode           *string  `validate:"omitnil,max=10"`
+	Country              *string  `validate:"omitnil,required,iso3166_1_alpha2"`
+	Label                *string  `validate:"omitnil,max=20"`
+	IsDefault            *bool
+	DeliveryInstructions *string `validate:"omitnil,max=255"`
+	Floor                *string `validate:"omitnil,max=10"`
+	Entrance             *string `validate:"omitnil,max=10"`
+	Apartment            *string `validate:"omitnil,max=10"`
+	ContactPhone         *string `validate:"omitnil,e164|len=0"`
+}
+
+func PatchAddressRequestToAddressPatch(patchAddressRequest PatchAddressRequest) models.AddressPatch {
+	addressPatch := models.AddressPatch{
+		Lat:                  patchAddressRequest.Lat,
+		Lon:                  patchAddressRequest.Lon,
+		AddressLine1:         patchAddressRequest.AddressLine1,
+		AddressLine2:         patchAddressRequest.AddressLine2,
+		City:                 patchAddressRequest.City,
+		Region:               patchAddressRequest.Region,
+		PostalCode:           patchAddressRequest.PostalCode,
+		Country:              patchAddressRequest.Country,
+		Label:                patchAddressRequest.Label,
+		IsDefault:            patchAddressRequest.IsDefault,
+		DeliveryInstructions: patchAddressRequest.DeliveryInstructions,
+		Floor:                patchAddressRequest.Floor,
+		Entrance:             patchAddressRequest.Entrance,
+		Apartment:            patchAddressRequest.Apartment,
+		ContactPhone:         patchAddressRequest.ContactPhone,
+	}
+
+	return addressPatch
+}
+
 type DeleteAddressRequest struct {
 	Id int `validate:"min=0"`
 }
 
+// CreateAddressRequest may leave out Lat and Lon, like UpdateAddressRequest,
+// when the server has a geocoder to derive them from the address lines.
 type CreateAddressRequest struct {
-	Lat          float64 `validate:"latitude,required"`
-	Lon          float64 `validate:"longitude,required"`
-	AddressLine1 string  `validate:"required,max=40"`
-	AddressLine2 string  `validate:"max=40"`
-	City         string  `validate:"required,max=40"`
-	Country      string  `validate:"required,max=20"`
+	Lat                  float64 `validate:"latitude"`
+	Lon                  float64 `validate:"longitude"`
+	AddressLine1         string  `validate:"required,max=40"`
+	AddressLine2         string  `validate:"max=40"`
+	City                 string  `validate:"required,max=40"`
+	Region               string  `validate:"max=40"`
+	PostalCode           string  `validate:"max=10"`
+	Country              string  `validate:"required,iso3166_1_alpha2"`
+	Label                string  `validate:"max=20"`
+	IsDefault            bool
+	DeliveryInstructions string `validate:"max=255"`
+	Floor                string `validate:"max=10"`
+	Entrance             string `validate:"max=10"`
+	Apartment            string `validate:"max=10"`
+	ContactPhone         string `validate:"omitempty,e164"`
+	HouseholdId          int    `validate:"min=0"`
 }
 
 func AddressToCreateAddressRequest(address models.Address) CreateAddressRequest {
 	createAddressRequest := CreateAddressRequest{
-		Lat:          address.Lat,
-		Lon:          address.Lon,
-		AddressLine1: address.AddressLine1,
-		AddressLine2: address.AddressLine2,
-		City:         address.City,
-		Country:      address.Country,
+		Lat:                  address.Lat,
+		Lon:                  address.Lon,
+		AddressLine1:         address.AddressLine1,
+		AddressLine2:         address.AddressLine2,
+		City:                 address.City,
+		Region:               address.Region,
+		PostalCode:           address.PostalCode,
+		Country:              address.Country,
+		Label:                address.Label,
+		IsDefault:            address.IsDefault,
+		DeliveryInstructions: address.DeliveryInstructions,
+		Floor:                address.Floor,
+		Entrance:             address.Entrance,
+		Apartment:            address.Apartment,
+		ContactPhone:         address.ContactPhone,
+	}
+	if address.HouseholdId != nil {
+		createAddressRequest.HouseholdId = *address.HouseholdId
 	}
 
 	return createAddressRequest
@@ -69,38 +165,215 @@ func AddressToCreateAddressRequest(address models.Address) CreateAddressRequest
 
 func CreateAddressRequestToAddress(createAddressRequest CreateAddressRequest, customerId int) models.Address {
 	address := models.Address{
-		CustomerId:   customerId,
-		Lat:          createAddressRequest.Lat,
-		Lon:          createAddressRequest.Lon,
-		AddressLine1: createAddressRequest.AddressLine1,
-		AddressLine2: createAddressRequest.AddressLine2,
-		City:         createAddressRequest.City,
-		Country:      createAddressRequest.Country,
+		CustomerId:           customerId,
+		Lat:                  createAddressRequest.Lat,
+		Lon:                  createAddressRequest.Lon,
+		AddressLine1:         createAddressRequest.AddressLine1,
+		AddressLine2:         createAddressRequest.AddressLine2,
+		City:                 createAddressRequest.City,
+		Region:               createAddressRequest.Region,
+		PostalCode:           createAddressRequest.PostalCode,
+		Country:              createAddressRequest.Country,
+		Label:                createAddressRequest.Label,
+		IsDefault:            createAddressRequest.IsDefault,
+		DeliveryInstructions: createAddressRequest.DeliveryInstructions,
+		Floor:                createAddressRequest.Floor,
+		Entrance:             createAddressRequest.Entrance,
+		Apartment:            createAddressRequest.Apartment,
+		ContactPhone:         createAddressRequest.ContactPhone,
+	}
+	if createAddressRequest.HouseholdId != 0 {
+		address.HouseholdId = &createAddressRequest.HouseholdId
 	}
 
 	return address
 }
 
 type GetAddressResponse struct {
-	Id           int     `validate:"min=0"`
-	Lat          float64 `validate:"latitude,required"`
-	Lon          float64 `validate:"longitude,required"`
-	AddressLine1 string  `validate:"required,max=40"`
-	AddressLine2 string  `validate:"max=40"`
-	City         string  `validate:"required,max=40"`
-	Country      string  `validate:"required,max=20"`
+	Id                   int     `validate:"min=0"`
+	Lat                  float64 `validate:"latitude,required"`
+	Lon                  float64 `validate:"longitude,required"`
+	AddressLine1         string  `validate:"required,max=40"`
+	AddressLine2         string  `validate:"max=40"`
+	City                 string  `validate:"required,max=40"`
+	Region               string  `validate:"max=40"`
+	PostalCode           string  `validate:"max=10"`
+	Country              string  `validate:"required,iso3166_1_alpha2"`
+	Label                string  `validate:"max=20"`
+	IsDefault            bool
+	DeliveryInstructions string `validate:"max=255"`
+	Floor                string `validate:"max=10"`
+	Entrance             string `validate:"max=10"`
+	Apartment            string `validate:"max=10"`
+	ContactPhone         string `validate:"omitempty,e164"`
+	CoordinatesMismatch  bool
+	HouseholdId          *int
+	Revision             int
+	Formatted            FormattedAddress
 }
 
 func AddressToGetAddressResponse(address models.Address) GetAddressResponse {
 	getAddressResponse := GetAddressResponse{
-		Id:           address.Id,
-		Lat:          address.Lat,
-		Lon:          address.Lon,
-		AddressLine1: address.AddressLine1,
-		AddressLine2: address.AddressLine2,
-		City:         address.City,
-		Country:      address.Country,
+		Id:                   address.Id,
+		Lat:                  address.Lat,
+		Lon:                  address.Lon,
+		AddressLine1:         address.AddressLine1,
+		AddressLine2:         address.AddressLine2,
+		City:                 address.City,
+		Region:               address.Region,
+		PostalCode:           address.PostalCode,
+		Country:              address.Country,
+		Label:                address.Label,
+		IsDefault:            address.IsDefault,
+		DeliveryInstructions: address.DeliveryInstructions,
+		Floor:                address.Floor,
+		Entrance:             address.Entrance,
+		Apartment:            address.Apartment,
+		ContactPhone:         address.ContactPhone,
+		CoordinatesMismatch:  address.CoordinatesMismatch,
+		HouseholdId:          address.HouseholdId,
+		Revision:             address.Version,
+		Formatted:            AddressToFormattedAddress(address),
 	}
 
 	return getAddressResponse
 }
+
+// AddressSnapshotResponse is an address as it was at one of its revisions.
+// FrozenAt is set once an order has frozen the revision at checkout.
+type AddressSnapshotResponse struct {
+	AddressId            int
+	Revision             int
+	HouseholdId          *int
+	Lat                  float64
+	Lon                  float64
+	AddressLine1         string
+	AddressLine2         string
+	City                 string
+	Region               string
+	PostalCode           string
+	Country              string
+	DeliveryInstructions string
+	Floor                string
+	Entrance             string
+	Apartment            string
+	ContactPhone         string
+	Formatted            FormattedAddress
+	CreatedAt            time.Time
+	FrozenAt             *time.Time
+}
+
+func AddressRevisionToAddressSnapshotResponse(revision models.AddressRevision) AddressSnapshotResponse {
+	addressSnapshotResponse := AddressSnapshotResponse{
+		AddressId:            revision.AddressId,
+		Revision:             revision.Revision,
+		HouseholdId:          revision.HouseholdId,
+		Lat:                  revision.Lat,
+		Lon:                  revision.Lon,
+		AddressLine1:         revision.AddressLine1,
+		AddressLine2:         revision.AddressLine2,
+		City:                 revision.City,
+		Region:               revision.Region,
+		PostalCode:           revision.PostalCode,
+		Country:              revision.Country,
+		DeliveryInstructions: revision.DeliveryInstructions,
+		Floor:                revision.Floor,
+		Entrance:             revision.Entrance,
+		Apartment:            revision.Apartment,
+		ContactPhone:         revision.ContactPhone,
+		Formatted:            AddressToFormattedAddress(revision.Address()),
+		CreatedAt:            revision.CreatedAt,
+		FrozenAt:             revision.FrozenAt,
+	}
+
+	return addressSnapshotResponse
+}
+
+// FormattedAddress is an address rendered the way its country writes
+// them, so clients don't have to assemble the lines themselves.
+type FormattedAddress struct {
+	SingleLine string
+	MultiLine  string
+	Label      string
+}
+
+func AddressToFormattedAddress(address models.Address) FormattedAddress {
+	formattedAddress := FormattedAddress{
+		SingleLine: models.FormatAddress(address, models.SINGLE_LINE_FORMAT),
+		MultiLine:  models.FormatAddress(address, models.MULTI_LINE_FORMAT),
+		Label:      models.FormatAddress(address, models.LABEL_FORMAT),
+	}
+
+	return formattedAddress
+}
+
+// FormatAddressRequest is an address that doesn't have to be saved, e.g.
+// one that is still being entered.
+type FormatAddressRequest struct {
+	AddressLine1 string `validate:"required,max=40"`
+	AddressLine2 string `validate:"max=40"`
+	City         string `validate:"required,max=40"`
+	Region       string `validate:"max=40"`
+	PostalCode   string `validate:"max=10"`
+	Country      string `validate:"required,iso3166_1_alpha2"`
+	Floor        string `validate:"max=10"`
+	Entrance     string `validate:"max=10"`
+	Apartment    string `validate:"max=10"`
+}
+
+func FormatAddressRequestToAddress(formatAddressRequest FormatAddressRequest) models.Address {
+	address := models.Address{
+		AddressLine1: formatAddressRequest.AddressLine1,
+		AddressLine2: formatAddressRequest.AddressLine2,
+		City:         formatAddressRequest.City,
+		Region:       formatAddressRequest.Region,
+		PostalCode:   formatAddressRequest.PostalCode,
+		Country:      formatAddressRequest.Country,
+		Floor:        formatAddressRequest.Floor,
+		Entrance:     formatAddressRequest.Entrance,
+		Apartment:    formatAddressRequest.Apartment,
+	}
+
+	return address
+}
+
+type ReverseGeocodeRequest struct {
+	Lat float64 `validate:"latitude,required"`
+	Lon float64 `validate:"longitude,required"`
+}
+
+// ReverseGeocodeResponse prefills an address form. Lat and Lon are the
+// requested point, so the pin stays where the customer dropped it, and
+// Distance is how far the suggested place is from it, in meters.
+type ReverseGeocodeResponse struct {
+	Lat          float64
+	Lon          float64
+	AddressLine1 string
+	City         string
+	Country      string
+	Precision    string
+	Distance     float64
+}
+
+func PlaceToReverseGeocodeResponse(place geocoding.Place, request ReverseGeocodeRequest) ReverseGeocodeResponse {
+	reverseGeocodeResponse := ReverseGeocodeResponse{
+		Lat:          request.Lat,
+		Lon:          request.Lon,
+		AddressLine1: place.AddressLine1,
+		City:         place.City,
+		Country:      place.Country,
+		Precision:    place.Location.Precision,
+		Distance:     place.Distance,
+	}
+
+	return reverseGeocodeResponse
+}
+
+// DeliverableAddressResponse tells whether an address is inside one of
+// the delivery zones as they are now. Zone is empty when no delivery
+// zones are configured.
+type DeliverableAddressResponse struct {
+	Id          int
+	Deliverable bool
+	Zone        string
+}
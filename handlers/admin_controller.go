@@ -0,0 +1,587 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+
+	"github.com/VitoNaychev/bt-customer-svc/avatar"
+	"github.com/VitoNaychev/bt-customer-svc/exporter"
+	"github.com/VitoNaychev/bt-customer-svc/models"
+	"github.com/VitoNaychev/validation"
+)
+
+func (a *AdminServer) anonymizeCustomer(w http.ResponseWriter, r *http.Request) {
+	anonymizeCustomerRequest, err := validation.ValidateBody[AnonymizeCustomerRequest](r.Body)
+	if err != nil {
+		writeJSONError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	report, err := a.customerStore.AnonymizeCustomer(anonymizeCustomerRequest.Id)
+	if err != nil {
+		handleStoreError(w, err)
+		return
+	}
+
+	err = avatar.Delete(a.blobStore, anonymizeCustomerRequest.Id)
+	if err != nil {
+		writeJSONError(w, http.StatusInternalServerError, ErrBlobStoreError)
+		return
+	}
+
+	json.NewEncoder(w).Encode(ErasureReportToAnonymizeCustomerResponse(report))
+}
+
+func (a *AdminServer) publishDocument(w http.ResponseWriter, r *http.Request) {
+	publishDocumentRequest, err := validation.ValidateBody[PublishDocumentRequest](r.Body)
+	if err != nil {
+		writeJSONError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	document := PublishDocumentRequestToConsentDocument(publishDocumentRequest)
+
+	err = a.consentStore.PublishDocument(&document)
+	if err != nil {
+		writeJSONError(w, http.StatusInternalServerError, ErrDatabaseError)
+		return
+	}
+
+	json.NewEncoder(w).Encode(ConsentDocumentToConsentDocumentResponse(document))
+}
+
+func (a *AdminServer) searchCustomers(w http.ResponseWriter, r *http.Request) {
+	searchCustomersRequest, err := parseSearchCustomersRequest(r.URL.Query())
+	if err != nil {
+		writeJSONError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	search, err := SearchCustomersRequestToCustomerSearch(searchCustomersRequest)
+	if err != nil {
+		writeJSONError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	page, err := a.customerStore.SearchCustomers(search)
+	if err != nil {
+		writeJSONError(w, http.StatusInternalServerError, ErrDatabaseError)
+		return
+	}
+
+	json.NewEncoder(w).Encode(CustomerPageToSearchCustomersResponse(page))
+}
+
+func parseSearchCustomersRequest(query url.Values) (SearchCustomersRequest, error) {
+	searchCustomersRequest := SearchCustomersRequest{
+		Name:   query.Get("name"),
+		Email:  query.Get("email"),
+		Phone:  query.Get("phone"),
+		Status: query.Get("status"),
+		Sort:   query.Get("sort"),
+		Order:  query.Get("order"),
+		Cursor: query.Get("cursor"),
+		Limit:  DEFAULT_SEARCH_LIMIT,
+	}
+
+	if searchCustomersRequest.Sort == "" {
+		searchCustomersRequest.Sort = models.SORT_BY_ID
+	}
+	if searchCustomersRequest.Order == "" {
+		searchCustomersRequest.Order = "asc"
+	}
+
+	var err error
+	if value := query.Get("limit"); value != "" {
+		if searchCustomersRequest.Limit, err = strconv.Atoi(value); err != nil {
+			return SearchCustomersRequest{}, ErrIncorrectRequestType
+		}
+	}
+	if value := query.Get("created_after"); value != "" {
+		if searchCustomersRequest.CreatedAfter, err = time.Parse(time.RFC3339, value); err != nil {
+			return SearchCustomersRequest{}, ErrIncorrectRequestType
+		}
+	}
+	if value := query.Get("created_before"); value != "" {
+		if searchCustomersRequest.CreatedBefore, err = time.Parse(time.RFC3339, value); err != nil {
+			return SearchCustomersRequest{}, ErrIncorrectRequestType
+		}
+	}
+
+	err = validation.ValidateStruct(searchCustomersRequest)
+	if err != nil {
+		return SearchCustomersRequest{}, err
+	}
+
+	return searchCustomersRequest, nil
+}
+
+func (a *AdminServer) recordTransaction(w http.ResponseWriter, r *http.Request) {
+	recordTransactionRequest, err := validation.ValidateBody[RecordTransactionRequest](r.Body)
+	if err != nil {
+		writeJSONError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	transaction, err := RecordTransactionRequestToLoyaltyTransaction(recordTransactionRequest, time.Now())
+	if err != nil {
+		writeJSONError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	_, err = a.customerStore.GetCustomerByID(transaction.CustomerId)
+	if err != nil {
+		handleStoreError(w, err)
+		return
+	}
+
+	err = a.loyaltyStore.RecordTransaction(&transaction)
+	if err != nil {
+		handleLoyaltyStoreError(w, err)
+		return
+	}
+
+	json.NewEncoder(w).Encode(LoyaltyTransactionToLoyaltyTransactionResponse(transaction))
+}
+
+func (a *AdminServer) getDuplicateCandidates(w http.ResponseWriter, r *http.Request) {
+	listCandidatesRequest := ListCandidatesRequest{Status: r.URL.Query().Get("status")}
+	if listCandidatesRequest.Status == "" {
+		listCandidatesRequest.Status = models.PENDING_CANDIDATE
+	}
+
+	err := validation.ValidateStruct(listCandidatesRequest)
+	if err != nil {
+		writeJSONError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	candidates, err := a.duplicateStore.GetCandidates(listCandidatesRequest.Status)
+	if err != nil {
+		writeJSONError(w, http.StatusInternalServerError, ErrDatabaseError)
+		return
+	}
+
+	candidatesResponse := []DuplicateCandidateResponse{}
+	for _, candidate := range candidates {
+		candidatesResponse = append(candidatesResponse, DuplicateCandidateToDuplicateCandidateResponse(candidate))
+	}
+
+	json.NewEncoder(w).Encode(candidatesResponse)
+}
+
+func (a *AdminServer) mergeCustomers(w http.ResponseWriter, r *http.Request) {
+	mergeCustomersRequest, err := validation.ValidateBody[MergeCustomersRequest](r.Body)
+	if err != nil {
+		writeJSONError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	report, err := a.duplicateStore.MergeCustomers(mergeCustomersRequest.CandidateId, mergeCustomersRequest.SurvivorId)
+	if err != nil {
+		handleDuplicateStoreError(w, err)
+		return
+	}
+
+	err = avatar.Delete(a.blobStore, report.MergedId)
+	if err != nil {
+		writeJSONError(w, http.StatusInternalServerError, ErrBlobStoreError)
+		return
+	}
+
+	json.NewEncoder(w).Encode(MergeReportToMergeCustomersResponse(report))
+}
+
+func (a *AdminServer) dismissCandidate(w http.ResponseWriter, r *http.Request) {
+	dismissCandidateRequest, err := validation.ValidateBody[DismissCandidateRequest](r.Body)
+	if err != nil {
+		writeJSONError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	err = a.duplicateStore.DismissCandidate(dismissCandidateRequest.CandidateId)
+	if err != nil {
+		handleDuplicateStoreError(w, err)
+		return
+	}
+}
+
+func handleDuplicateStoreError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, models.ErrNotFound):
+		writeJSONError(w, http.StatusNotFound, ErrCandidateNotFound)
+	case errors.Is(err, models.ErrCandidateReviewed):
+		writeJSONError(w, http.StatusConflict, ErrCandidateReviewed)
+	case errors.Is(err, models.ErrInvalidMerge):
+		writeJSONError(w, http.StatusConflict, ErrInvalidMerge)
+	default:
+		writeJSONError(w, http.StatusInternalServerError, ErrDatabaseError)
+	}
+}
+
+func (a *AdminServer) getTags(w http.ResponseWriter, r *http.Request) {
+	customerId, err := strconv.Atoi(r.URL.Query().Get("customer_id"))
+	if err != nil {
+		writeJSONError(w, http.StatusBadRequest, ErrIncorrectRequestType)
+		return
+	}
+
+	_, err = a.customerStore.GetCustomerByID(customerId)
+	if err != nil {
+		handleStoreError(w, err)
+		return
+	}
+
+	tags, err := a.segmentStore.GetTags(customerId)
+	if err != nil {
+		writeJSONError(w, http.StatusInternalServerError, ErrDatabaseError)
+		return
+	}
+
+	json.NewEncoder(w).Encode(TagsResponse{CustomerId: customerId, Tags: tags})
+}
+
+func (a *AdminServer) addTags(w http.ResponseWriter, r *http.Request) {
+	addTagsRequest, err := validation.ValidateBody[AddTagsRequest](r.Body)
+	if err != nil {
+		writeJSONError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	tags, err := models.NormalizeTags(addTagsRequest.Tags)
+	if err != nil {
+		writeFieldError(w, http.StatusBadRequest, err, "Tags")
+		return
+	}
+
+	_, err = a.customerStore.GetCustomerByID(addTagsRequest.CustomerId)
+	if err != nil {
+		handleStoreError(w, err)
+		return
+	}
+
+	err = a.segmentStore.AddTags(addTagsRequest.CustomerId, tags)
+	if err != nil {
+		writeJSONError(w, http.StatusInternalServerError, ErrDatabaseError)
+		return
+	}
+
+	tags, err = a.segmentStore.GetTags(addTagsRequest.CustomerId)
+	if err != nil {
+		writeJSONError(w, http.StatusInternalServerError, ErrDatabaseError)
+		return
+	}
+
+	json.NewEncoder(w).Encode(TagsResponse{CustomerId: addTagsRequest.CustomerId, Tags: tags})
+}
+
+func (a *AdminServer) removeTag(w http.ResponseWriter, r *http.Request) {
+	customerId, err := strconv.Atoi(r.URL.Query().Get("customer_id"))
+	if err != nil {
+		writeJSONError(w, http.StatusBadRequest, ErrIncorrectRequestType)
+		return
+	}
+
+	tag, err := models.NormalizeTag(r.URL.Query().Get("tag"))
+	if err != nil {
+		writeFieldError(w, http.StatusBadRequest, err, "Tag")
+		return
+	}
+
+	err = a.segmentStore.RemoveTag(customerId, tag)
+	if err != nil {
+		if errors.Is(err, models.ErrNotFound) {
+			writeJSONError(w, http.StatusNotFound, ErrTagNotFound)
+		} else {
+			writeJSONError(w, http.StatusInternalServerError, ErrDatabaseError)
+		}
+		return
+	}
+}
+
+func (a *AdminServer) getSegments(w http.ResponseWriter, r *http.Request) {
+	segments, err := a.segmentStore.GetSegments()
+	if err != nil {
+		writeJSONError(w, http.StatusInternalServerError, ErrDatabaseError)
+		return
+	}
+
+	segmentsResponse := []SegmentResponse{}
+	for _, segment := range segments {
+		segmentsResponse = append(segmentsResponse, SegmentToSegmentResponse(segment))
+	}
+
+	json.NewEncoder(w).Encode(segmentsResponse)
+}
+
+func (a *AdminServer) createSegment(w http.ResponseWriter, r *http.Request) {
+	createSegmentRequest, err := validation.ValidateBody[CreateSegmentRequest](r.Body)
+	if err != nil {
+		writeJSONError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	segment, err := CreateSegmentRequestToSegment(createSegmentRequest)
+	if err != nil {
+		writeJSONError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	err = a.segmentStore.CreateSegment(&segment)
+	if err != nil {
+		if !handleConstraintError(w, err) {
+			writeJSONError(w, http.StatusInternalServerError, ErrDatabaseError)
+		}
+		return
+	}
+
+	json.NewEncoder(w).Encode(SegmentToSegmentResponse(segment))
+}
+
+func (a *AdminServer) deleteSegment(w http.ResponseWriter, r *http.Request) {
+	err := a.segmentStore.DeleteSegment(r.URL.Query().Get("name"))
+	if err != nil {
+		handleSegmentStoreError(w, err)
+		return
+	}
+}
+
+func (a *AdminServer) getSegmentMembers(w http.ResponseWriter, r *http.Request) {
+	writeSegmentMembers(w, r, a.segmentStore)
+}
+
+// writeSegmentMembers writes the page of segment members requested in the
+// query string. Both the admin and the internal API serve it.
+func writeSegmentMembers(w http.ResponseWriter, r *http.Request, segmentStore models.SegmentStore) {
+	segmentMembersRequest, err := parseSegmentMembersRequest(r.URL.Query())
+	if err != nil {
+		writeJSONError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	members, err := segmentStore.GetSegmentMembers(segmentMembersRequest.Segment, segmentMembersRequest.After, segmentMembersRequest.Limit)
+	if err != nil {
+		handleSegmentStoreError(w, err)
+		return
+	}
+
+	segmentMembersResponse := SegmentMembersResponse{Segment: segmentMembersRequest.Segment, CustomerIds: members}
+	if len(members) == segmentMembersRequest.Limit {
+		segmentMembersResponse.NextAfter = members[len(members)-1]
+	}
+
+	json.NewEncoder(w).Encode(segmentMembersResponse)
+}
+
+func parseSegmentMembersRequest(query url.Values) (SegmentMembersRequest, error) {
+	segmentMembersRequest := SegmentMembersRequest{
+		Segment: query.Get("segment"),
+		Limit:   DEFAULT_SEGMENT_MEMBERS_LIMIT,
+	}
+
+	var err error
+	if value := query.Get("after"); value != "" {
+		if segmentMembersRequest.After, err = strconv.Atoi(value); err != nil {
+			return SegmentMembersRequest{}, ErrIncorrectRequestType
+		}
+	}
+	if value := query.Get("limit"); value != "" {
+		if segmentMembersRequest.Limit, err = strconv.Atoi(value); err != nil {
+			return SegmentMembersRequest{}, ErrIncorrectRequestType
+		}
+	}
+
+	err = validation.ValidateStruct(segmentMembersRequest)
+	if err != nil {
+		return SegmentMembersRequest{}, err
+	}
+
+	return segmentMembersRequest, nil
+}
+
+func (a *AdminServer) getCustomerSegments(w http.ResponseWriter, r *http.Request) {
+	customerId, err := strconv.Atoi(r.URL.Query().Get("customer_id"))
+	if err != nil {
+		writeJSONError(w, http.StatusBadRequest, ErrIncorrectRequestType)
+		return
+	}
+
+	_, err = a.customerStore.GetCustomerByID(customerId)
+	if err != nil {
+		handleStoreError(w, err)
+		return
+	}
+
+	segments, err := a.segmentStore.GetCustomerSegments(customerId)
+	if err != nil {
+		writeJSONError(w, http.StatusInternalServerError, ErrDatabaseError)
+		return
+	}
+
+	json.NewEncoder(w).Encode(CustomerSegmentsResponse{CustomerId: customerId, Segments: segments})
+}
+
+func handleSegmentStoreError(w http.ResponseWriter, err error) {
+	if errors.Is(err, models.ErrNotFound) {
+		writeJSONError(w, http.StatusNotFound, ErrSegmentNotFound)
+	} else {
+		writeJSONError(w, http.StatusInternalServerError, ErrDatabaseError)
+	}
+}
+
+// exportTable streams the export as it is read. Once the first row is
+// written the status can't change anymore, so a failing export aborts the
+// response instead of ending it like a complete file.
+func (a *AdminServer) exportTable(w http.ResponseWriter, r *http.Request) {
+	exportRequest, err := parseExportRequest(r.URL.Query())
+	if err != nil {
+		writeJSONError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	tableExporter, err := exporter.NewExporter(a.exportStore, exportRequest.PII, a.exportHashKey)
+	if errors.Is(err, exporter.ErrMissingHashKey) {
+		writeJSONError(w, http.StatusInternalServerError, err)
+		return
+	} else if err != nil {
+		writeJSONError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	contentType, _ := exporter.ContentType(exportRequest.Format)
+	w.Header().Set("Content-Type", contentType)
+	w.Header().Set("Content-Disposition",
+		fmt.Sprintf(`attachment; filename="%s.%s"`, exportRequest.Table, exportRequest.Format))
+
+	_, err = tableExporter.Export(w, exportRequest.Table, exportRequest.Format, exportRequest.Since)
+	if err != nil {
+		log.Printf("export of %s failed: %v", exportRequest.Table, err)
+		panic(http.ErrAbortHandler)
+	}
+}
+
+func parseExportRequest(query url.Values) (ExportRequest, error) {
+	exportRequest := ExportRequest{
+		Table:  query.Get("table"),
+		Format: query.Get("format"),
+		PII:    query.Get("pii"),
+	}
+
+	if exportRequest.Format == "" {
+		exportRequest.Format = exporter.JSONL_FORMAT
+	}
+	if exportRequest.PII == "" {
+		exportRequest.PII = exporter.HASH_PII
+	}
+
+	var err error
+	if value := query.Get("since"); value != "" {
+		if exportRequest.Since, err = time.Parse(time.RFC3339, value); err != nil {
+			return ExportRequest{}, ErrIncorrectRequestType
+		}
+	}
+
+	err = validation.ValidateStruct(exportRequest)
+	if err != nil {
+		return ExportRequest{}, err
+	}
+
+	return exportRequest, nil
+}
+
+func (a *AdminServer) searchNearbyAddresses(w http.ResponseWriter, r *http.Request) {
+	nearbyAddressesRequest, err := parseNearbyAddressesRequest(r.URL.Query())
+	if err != nil {
+		writeJSONError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	search, err := NearbyAddressesRequestToAddressProximitySearch(nearbyAddressesRequest)
+	if err != nil {
+		writeJSONError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	page, err := a.addressStore.SearchAddressesNear(search)
+	if err != nil {
+		writeJSONError(w, http.StatusInternalServerError, ErrDatabaseError)
+		return
+	}
+
+	json.NewEncoder(w).Encode(AddressMatchPageToNearbyAddressesResponse(page))
+}
+
+// parseNearbyAddressesRequest accepts lat, lon and radius or min_lat,
+// min_lon, max_lat and max_lon, but not a mix of the two.
+func parseNearbyAddressesRequest(query url.Values) (NearbyAddressesRequest, error) {
+	nearbyAddressesRequest := NearbyAddressesRequest{
+		Cursor: query.Get("cursor"),
+		Limit:  DEFAULT_NEARBY_LIMIT,
+	}
+
+	circleParams := map[string]*float64{
+		"lat":    &nearbyAddressesRequest.Lat,
+		"lon":    &nearbyAddressesRequest.Lon,
+		"radius": &nearbyAddressesRequest.Radius,
+	}
+	boxParams := map[string]*float64{
+		"min_lat": &nearbyAddressesRequest.MinLat,
+		"min_lon": &nearbyAddressesRequest.MinLon,
+		"max_lat": &nearbyAddressesRequest.MaxLat,
+		"max_lon": &nearbyAddressesRequest.MaxLon,
+	}
+
+	circle, err := parseFloatParams(query, circleParams)
+	if err != nil {
+		return NearbyAddressesRequest{}, err
+	}
+	box, err := parseFloatParams(query, boxParams)
+	if err != nil {
+		return NearbyAddressesRequest{}, err
+	}
+
+	isCircle := circle == len(circleParams) && box == 0 && nearbyAddressesRequest.Radius > 0
+	isBox := box == len(boxParams) && circle == 0
+	if !isCircle && !isBox {
+		return NearbyAddressesRequest{}, ErrInvalidArea
+	}
+
+	if value := query.Get("limit"); value != "" {
+		if nearbyAddressesRequest.Limit, err = strconv.Atoi(value); err != nil {
+			return NearbyAddressesRequest{}, ErrIncorrectRequestType
+		}
+	}
+
+	err = validation.ValidateStruct(nearbyAddressesRequest)
+	if err != nil {
+		return NearbyAddressesRequest{}, err
+	}
+
+	return nearbyAddressesRequest, nil
+}
+
+// parseFloatParams parses the query params that are present into their
+// fields and returns how many were present.
+func parseFloatParams(query url.Values, params map[string]*float64) (int, error) {
+	present := 0
+	for name, field := range params {
+		value := query.Get(name)
+		if value == "" {
+			continue
+		}
+
+		var err error
+		if *field, err = strconv.ParseFloat(value, 64); err != nil {
+			return 0, ErrIncorrectRequestType
+		}
+		present++
+	}
+
+	return present, nil
+}
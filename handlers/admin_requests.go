@@ -0,0 +1,146 @@
+package handlers
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/url"
+	"strconv"
+)
+
+func NewAnonymizeCustomerRequest(adminJWT string, id int) *http.Request {
+	body := bytes.NewBuffer([]byte{})
+	json.NewEncoder(body).Encode(AnonymizeCustomerRequest{Id: id})
+
+	request, _ := http.NewRequest(http.MethodPost, "/admin/customer/anonymize/", body)
+	request.Header.Add("Token", adminJWT)
+
+	return request
+}
+
+func NewPublishDocumentRequest(adminJWT string, publishDocumentRequest PublishDocumentRequest) *http.Request {
+	body := bytes.NewBuffer([]byte{})
+	json.NewEncoder(body).Encode(publishDocumentRequest)
+
+	request, _ := http.NewRequest(http.MethodPost, "/admin/consents/documents/", body)
+	request.Header.Add("Token", adminJWT)
+
+	return request
+}
+
+func NewSearchCustomersRequest(adminJWT string, query url.Values) *http.Request {
+	request, _ := http.NewRequest(http.MethodGet, "/admin/customer/search/?"+query.Encode(), nil)
+	request.Header.Add("Token", adminJWT)
+
+	return request
+}
+
+func NewExportRequest(adminJWT string, query url.Values) *http.Request {
+	request, _ := http.NewRequest(http.MethodGet, "/admin/export/?"+query.Encode(), nil)
+	request.Header.Add("Token", adminJWT)
+
+	return request
+}
+
+func NewRecordTransactionRequest(adminJWT string, recordTransactionRequest RecordTransactionRequest) *http.Request {
+	body := bytes.NewBuffer([]byte{})
+	json.NewEncoder(body).Encode(recordTransactionRequest)
+
+	request, _ := http.NewRequest(http.MethodPost, "/admin/loyalty/transactions/", body)
+	request.Header.Add("Token", adminJWT)
+
+	return request
+}
+
+func NewGetDuplicateCandidatesRequest(adminJWT string, status string) *http.Request {
+	query := url.Values{}
+	if status != "" {
+		query.Set("status", status)
+	}
+
+	request, _ := http.NewRequest(http.MethodGet, "/admin/duplicates/?"+query.Encode(), nil)
+	request.Header.Add("Token", adminJWT)
+
+	return request
+}
+
+func NewMergeCustomersRequest(adminJWT string, candidateID int, survivorID int) *http.Request {
+	body := bytes.NewBuffer([]byte{})
+	json.NewEncoder(body).Encode(MergeCustomersRequest{CandidateId: candidateID, SurvivorId: survivorID})
+
+	request, _ := http.NewRequest(http.MethodPost, "/admin/duplicates/merge/", body)
+	request.Header.Add("Token", adminJWT)
+
+	return request
+}
+
+func NewDismissCandidateRequest(adminJWT string, candidateID int) *http.Request {
+	body := bytes.NewBuffer([]byte{})
+	json.NewEncoder(body).Encode(DismissCandidateRequest{CandidateId: candidateID})
+
+	request, _ := http.NewRequest(http.MethodPost, "/admin/duplicates/dismiss/", body)
+	request.Header.Add("Token", adminJWT)
+
+	return request
+}
+
+func NewGetTagsRequest(adminJWT string, customerID int) *http.Request {
+	query := url.Values{"customer_id": {strconv.Itoa(customerID)}}
+
+	request, _ := http.NewRequest(http.MethodGet, "/admin/customer/tags/?"+query.Encode(), nil)
+	request.Header.Add("Token", adminJWT)
+
+	return request
+}
+
+func NewAddTagsRequest(adminJWT string, customerID int, tags []string) *http.Request {
+	body := bytes.NewBuffer([]byte{})
+	json.NewEncoder(body).Encode(AddTagsRequest{CustomerId: customerID, Tags: tags})
+
+	request, _ := http.NewRequest(http.MethodPost, "/admin/customer/tags/", body)
+	request.Header.Add("Token", adminJWT)
+
+	return request
+}
+
+func NewRemoveTagRequest(adminJWT string, customerID int, tag string) *http.Request {
+	query := url.Values{"customer_id": {strconv.Itoa(customerID)}, "tag": {tag}}
+
+	request, _ := http.NewRequest(http.MethodDelete, "/admin/customer/tags/?"+query.Encode(), nil)
+	request.Header.Add("Token", adminJWT)
+
+	return request
+}
+
+func NewCreateSegmentRequest(adminJWT string, createSegmentRequest CreateSegmentRequest) *http.Request {
+	body := bytes.NewBuffer([]byte{})
+	json.NewEncoder(body).Encode(createSegmentRequest)
+
+	request, _ := http.NewRequest(http.MethodPost, "/admin/segments/", body)
+	request.Header.Add("Token", adminJWT)
+
+	return request
+}
+
+func NewGetSegmentMembersRequest(adminJWT string, query url.Values) *http.Request {
+	request, _ := http.NewRequest(http.MethodGet, "/admin/segments/members/?"+query.Encode(), nil)
+	request.Header.Add("Token", adminJWT)
+
+	return request
+}
+
+func NewGetCustomerSegmentsRequest(adminJWT string, customerID int) *http.Request {
+	query := url.Values{"customer_id": {strconv.Itoa(customerID)}}
+
+	request, _ := http.NewRequest(http.MethodGet, "/admin/customer/segments/?"+query.Encode(), nil)
+	request.Header.Add("Token", adminJWT)
+
+	return request
+}
+
+func NewNearbyAddressesRequest(adminJWT string, query url.Values) *http.Request {
+	request, _ := http.NewRequest(http.MethodGet, "/admin/addresses/nearby/?"+query.Encode(), nil)
+	request.Header.Add("Token", adminJWT)
+
+	return request
+}
@@ -0,0 +1,179 @@
+package handlers
+
+import (
+	"net/http"
+
+	"github.com/VitoNaychev/auth"
+	"github.com/VitoNaychev/bt-customer-svc/blobstore"
+	"github.com/VitoNaychev/bt-customer-svc/models"
+)
+
+type AdminServer struct {
+	secretKey      []byte
+	customerStore  models.CustomerStore
+	consentStore   models.ConsentStore
+	blobStore      blobstore.BlobStore
+	loyaltyStore   models.LoyaltyStore
+	duplicateStore models.DuplicateStore
+	segmentStore   models.SegmentStore
+	exportStore    models.CustomerExportStore
+	addressStore   models.CustomerAddressStore
+	exportHashKey  []byte
+	http.Handler
+}
+
+func NewAdminServer(secretKey []byte, customerStore models.CustomerStore, consentStore models.ConsentStore,
+	blobStore blobstore.BlobStore, loyaltyStore models.LoyaltyStore, duplicateStore models.DuplicateStore,
+	segmentStore models.SegmentStore, exportStore models.CustomerExportStore,
+	addressStore models.CustomerAddressStore) *AdminServer {
+	// Tokens signed with an empty key can be minted by anyone.
+	if len(secretKey) == 0 {
+		panic(ErrMissingSecretKey)
+	}
+
+	a := new(AdminServer)
+
+	a.secretKey = secretKey
+	a.customerStore = customerStore
+	a.consentStore = consentStore
+	a.blobStore = blobStore
+	a.loyaltyStore = loyaltyStore
+	a.duplicateStore = duplicateStore
+	a.segmentStore = segmentStore
+	a.exportStore = exportStore
+	a.addressStore = addressStore
+
+	router := http.NewServeMux()
+	router.HandleFunc("/admin/customer/anonymize/", a.AnonymizeHandler)
+	router.HandleFunc("/admin/consents/documents/", a.ConsentDocumentsHandler)
+	router.HandleFunc("/admin/customer/search/", a.SearchHandler)
+	router.HandleFunc("/admin/loyalty/transactions/", a.LoyaltyTransactionsHandler)
+	router.HandleFunc("/admin/duplicates/", a.DuplicatesHandler)
+	router.HandleFunc("/admin/duplicates/merge/", a.MergeHandler)
+	router.HandleFunc("/admin/duplicates/dismiss/", a.DismissHandler)
+	router.HandleFunc("/admin/customer/tags/", a.TagsHandler)
+	router.HandleFunc("/admin/customer/segments/", a.CustomerSegmentsHandler)
+	router.HandleFunc("/admin/segments/", a.SegmentsHandler)
+	router.HandleFunc("/admin/segments/members/", a.SegmentMembersHandler)
+	router.HandleFunc("/admin/export/", a.ExportHandler)
+	router.HandleFunc("/admin/addresses/nearby/", a.NearbyAddressesHandler)
+
+	a.Handler = router
+
+	return a
+}
+
+// SetExportHashKey sets the key PII is hashed with in exports. Without one,
+// exports that hash PII fail.
+func (a *AdminServer) SetExportHashKey(key []byte) {
+	a.exportHashKey = key
+}
+
+func (a *AdminServer) AnonymizeHandler(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodPost:
+		auth.AuthenticationMiddleware(a.anonymizeCustomer, a.secretKey)(w, r)
+	}
+}
+
+func (a *AdminServer) ConsentDocumentsHandler(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodPost:
+		auth.AuthenticationMiddleware(a.publishDocument, a.secretKey)(w, r)
+	}
+}
+
+func (a *AdminServer) SearchHandler(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+		auth.AuthenticationMiddleware(a.searchCustomers, a.secretKey)(w, r)
+	}
+}
+
+func (a *AdminServer) LoyaltyTransactionsHandler(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodPost:
+		auth.AuthenticationMiddleware(a.recordTransaction, a.secretKey)(w, r)
+	}
+}
+
+func (a *AdminServer) DuplicatesHandler(w http.ResponseWriter, r *http.Request) {
+	if r.URL.Path != "/admin/duplicates/" {
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+
+	switch r.Method {
+	case http.MethodGet:
+		auth.AuthenticationMiddleware(a.getDuplicateCandidates, a.secretKey)(w, r)
+	}
+}
+
+func (a *AdminServer) MergeHandler(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodPost:
+		auth.AuthenticationMiddleware(a.mergeCustomers, a.secretKey)(w, r)
+	}
+}
+
+func (a *AdminServer) DismissHandler(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodPost:
+		auth.AuthenticationMiddleware(a.dismissCandidate, a.secretKey)(w, r)
+	}
+}
+
+func (a *AdminServer) TagsHandler(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+		auth.AuthenticationMiddleware(a.getTags, a.secretKey)(w, r)
+	case http.MethodPost:
+		auth.AuthenticationMiddleware(a.addTags, a.secretKey)(w, r)
+	case http.MethodDelete:
+		auth.AuthenticationMiddleware(a.removeTag, a.secretKey)(w, r)
+	}
+}
+
+func (a *AdminServer) CustomerSegmentsHandler(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+		auth.AuthenticationMiddleware(a.getCustomerSegments, a.secretKey)(w, r)
+	}
+}
+
+func (a *AdminServer) SegmentsHandler(w http.ResponseWriter, r *http.Request) {
+	if r.URL.Path != "/admin/segments/" {
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+
+	switch r.Method {
+	case http.MethodGet:
+		auth.AuthenticationMiddleware(a.getSegments, a.secretKey)(w, r)
+	case http.MethodPost:
+		auth.AuthenticationMiddleware(a.createSegment, a.secretKey)(w, r)
+	case http.MethodDelete:
+		auth.AuthenticationMiddleware(a.deleteSegment, a.secretKey)(w, r)
+	}
+}
+
+func (a *AdminServer) SegmentMembersHandler(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+		auth.AuthenticationMiddleware(a.getSegmentMembers, a.secretKey)(w, r)
+	}
+}
+
+func (a *AdminServer) ExportHandler(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+		auth.AuthenticationMiddleware(a.exportTable, a.secretKey)(w, r)
+	}
+}
+
+func (a *AdminServer) NearbyAddressesHandler(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+		auth.AuthenticationMiddleware(a.searchNearbyAddresses, a.secretKey)(w, r)
+	}
+}
@@ -0,0 +1,776 @@
+package handlers_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/VitoNaychev/auth"
+	"github.com/VitoNaychev/bt-customer-svc/exporter"
+	"github.com/VitoNaychev/bt-customer-svc/handlers"
+	"github.com/VitoNaychev/bt-customer-svc/models"
+	td "github.com/VitoNaychev/bt-customer-svc/testdata"
+	"github.com/VitoNaychev/bt-customer-svc/testutil"
+)
+
+func TestAdminEndpointAuthentication(t *testing.T) {
+	customerData := []models.Customer{td.PeterCustomer, td.AliceCustomer}
+	store := testutil.NewStubCustomerStore(customerData)
+	server := handlers.NewAdminServer(testEnv.AdminSecretKey, store, testutil.NewStubConsentStore(nil, nil), testutil.NewStubBlobStore(), testutil.NewStubLoyaltyStore(nil), testutil.NewStubDuplicateStore(nil), testutil.NewStubSegmentStore(nil, nil, nil), testutil.NewStubCustomerExportStore(nil, nil, nil), testutil.NewStubAddressStore(nil))
+
+	customerJWT, _ := auth.GenerateJWT(testEnv.SecretKey, testEnv.ExpiresAt, td.PeterCustomer.Id)
+	cases := map[string]*http.Request{
+		"anonymize with invalid JWT":  handlers.NewAnonymizeCustomerRequest("thisIsAnInvalidJWT", td.PeterCustomer.Id),
+		"anonymize with customer JWT": handlers.NewAnonymizeCustomerRequest(customerJWT, td.PeterCustomer.Id),
+		"search with customer JWT":    handlers.NewSearchCustomersRequest(customerJWT, url.Values{}),
+	}
+
+	for name, request := range cases {
+		t.Run(name, func(t *testing.T) {
+			response := httptest.NewRecorder()
+
+			server.ServeHTTP(response, request)
+
+			testutil.AssertStatus(t, response.Code, http.StatusUnauthorized)
+		})
+	}
+}
+
+func TestAdminServerRefusesEmptySecret(t *testing.T) {
+	defer func() {
+		testutil.AssertEqual(t, recover(), any(handlers.ErrMissingSecretKey))
+	}()
+
+	handlers.NewAdminServer(nil, testutil.NewStubCustomerStore(nil), testutil.NewStubConsentStore(nil, nil), testutil.NewStubBlobStore(), testutil.NewStubLoyaltyStore(nil), testutil.NewStubDuplicateStore(nil), testutil.NewStubSegmentStore(nil, nil, nil), testutil.NewStubCustomerExportStore(nil, nil, nil), testutil.NewStubAddressStore(nil))
+}
+
+func TestAnonymizeCustomer(t *testing.T) {
+	customerData := []models.Customer{td.PeterCustomer, td.AliceCustomer}
+	store := testutil.NewStubCustomerStore(customerData)
+	server := handlers.NewAdminServer(testEnv.AdminSecretKey, store, testutil.NewStubConsentStore(nil, nil), testutil.NewStubBlobStore(), testutil.NewStubLoyaltyStore(nil), testutil.NewStubDuplicateStore(nil), testutil.NewStubSegmentStore(nil, nil, nil), testutil.NewStubCustomerExportStore(nil, nil, nil), testutil.NewStubAddressStore(nil))
+
+	adminJWT, _ := auth.GenerateJWT(testEnv.AdminSecretKey, testEnv.ExpiresAt, 1)
+
+	t.Run("anonymizes customer and reports scrubbed fields", func(t *testing.T) {
+		request := handlers.NewAnonymizeCustomerRequest(adminJWT, td.PeterCustomer.Id)
+		response := httptest.NewRecorder()
+
+		server.ServeHTTP(response, request)
+
+		testutil.AssertStatus(t, response.Code, http.StatusOK)
+		testutil.AssertAnonymizedCustomer(t, store, td.PeterCustomer)
+
+		want := handlers.AnonymizeCustomerResponse{
+			CustomerId:     td.PeterCustomer.Id,
+			CustomerFields: []string{"FirstName", "LastName", "PhoneNumber", "Email", "Password"},
+			Addresses:      []handlers.AnonymizedAddressResponse{},
+		}
+		var got handlers.AnonymizeCustomerResponse
+		json.NewDecoder(response.Body).Decode(&got)
+
+		testutil.AssertEqual(t, got, want)
+
+		erased, _ := store.GetCustomerByID(td.PeterCustomer.Id)
+		testutil.AssertEqual(t, erased.Id, td.PeterCustomer.Id)
+		if erased.Email == td.PeterCustomer.Email || erased.FirstName == td.PeterCustomer.FirstName {
+			t.Errorf("customer PII was not scrubbed, got %v", erased)
+		}
+	})
+
+	t.Run("returns Not Found on missing customer", func(t *testing.T) {
+		request := handlers.NewAnonymizeCustomerRequest(adminJWT, 10)
+		response := httptest.NewRecorder()
+
+		server.ServeHTTP(response, request)
+
+		testutil.AssertStatus(t, response.Code, http.StatusNotFound)
+		testutil.AssertErrorResponse(t, response.Body, handlers.ErrCustomerNotFound)
+	})
+}
+
+func TestPublishConsentDocument(t *testing.T) {
+	customerStore := testutil.NewStubCustomerStore(nil)
+	consentStore := testutil.NewStubConsentStore([]models.ConsentDocument{td.TermsV1}, nil)
+	server := handlers.NewAdminServer(testEnv.AdminSecretKey, customerStore, consentStore, testutil.NewStubBlobStore(), testutil.NewStubLoyaltyStore(nil), testutil.NewStubDuplicateStore(nil), testutil.NewStubSegmentStore(nil, nil, nil), testutil.NewStubCustomerExportStore(nil, nil, nil), testutil.NewStubAddressStore(nil))
+
+	adminJWT, _ := auth.GenerateJWT(testEnv.AdminSecretKey, testEnv.ExpiresAt, 1)
+
+	t.Run("returns Bad Request on unknown document kind", func(t *testing.T) {
+		publishDocumentRequest := handlers.PublishDocumentRequest{Kind: "cookies", Version: "2023-11", Mandatory: true}
+
+		request := handlers.NewPublishDocumentRequest(adminJWT, publishDocumentRequest)
+		response := httptest.NewRecorder()
+
+		server.ServeHTTP(response, request)
+
+		testutil.AssertStatus(t, response.Code, http.StatusBadRequest)
+	})
+
+	t.Run("publishes a new document version", func(t *testing.T) {
+		publishDocumentRequest := handlers.PublishDocumentRequest{
+			Kind:      td.TermsV2.Kind,
+			Version:   td.TermsV2.Version,
+			Mandatory: td.TermsV2.Mandatory,
+		}
+
+		request := handlers.NewPublishDocumentRequest(adminJWT, publishDocumentRequest)
+		response := httptest.NewRecorder()
+
+		server.ServeHTTP(response, request)
+
+		testutil.AssertStatus(t, response.Code, http.StatusOK)
+
+		latest, _ := consentStore.GetLatestDocuments()
+		testutil.AssertEqual(t, len(latest), 1)
+		testutil.AssertEqual(t, latest[0].Version, td.TermsV2.Version)
+	})
+}
+
+func TestSearchCustomers(t *testing.T) {
+	created := time.Date(2023, time.October, 1, 12, 0, 0, 0, time.UTC)
+	newCustomer := func(id int, firstName, lastName, phoneNumber, email, status string, age time.Duration) models.Customer {
+		return models.Customer{
+			Id:          id,
+			FirstName:   firstName,
+			LastName:    lastName,
+			PhoneNumber: phoneNumber,
+			Email:       email,
+			Status:      status,
+			CreatedAt:   created.Add(-age),
+			Version:     1,
+		}
+	}
+
+	ivan := newCustomer(1, "Ivan", "Petrov", "+359 88 123 4411", "ivan.petrov@abv.bg", models.ACTIVE_STATUS, 72*time.Hour)
+	ivana := newCustomer(2, "Ivana", "Georgieva", "+359 88 765 4411", "ivana@gmail.com", models.ACTIVE_STATUS, 48*time.Hour)
+	ivo := newCustomer(3, "Ivo", "Ivanov", "+359 87 555 1234", "ivo@abv.bg", models.ACTIVE_STATUS, 24*time.Hour)
+	erased := newCustomer(4, "erased", "erased", "+9990000000004", "erased-4@erased.invalid", models.ANONYMIZED_STATUS, 0)
+
+	store := testutil.NewStubCustomerStore([]models.Customer{ivan, ivana, ivo, erased})
+	server := handlers.NewAdminServer(testEnv.AdminSecretKey, store, testutil.NewStubConsentStore(nil, nil), testutil.NewStubBlobStore(), testutil.NewStubLoyaltyStore(nil), testutil.NewStubDuplicateStore(nil), testutil.NewStubSegmentStore(nil, nil, nil), testutil.NewStubCustomerExportStore(nil, nil, nil), testutil.NewStubAddressStore(nil))
+
+	adminJWT, _ := auth.GenerateJWT(testEnv.AdminSecretKey, testEnv.ExpiresAt, 1)
+
+	search := func(t testing.TB, query url.Values) handlers.SearchCustomersResponse {
+		t.Helper()
+
+		request := handlers.NewSearchCustomersRequest(adminJWT, query)
+		response := httptest.NewRecorder()
+
+		server.ServeHTTP(response, request)
+
+		testutil.AssertStatus(t, response.Code, http.StatusOK)
+
+		var got handlers.SearchCustomersResponse
+		json.NewDecoder(response.Body).Decode(&got)
+		return got
+	}
+
+	customerIds := func(searchCustomersResponse handlers.SearchCustomersResponse) []int {
+		ids := []int{}
+		for _, customer := range searchCustomersResponse.Customers {
+			ids = append(ids, customer.Id)
+		}
+		return ids
+	}
+
+	t.Run("finds Ivan by name prefix and phone ending", func(t *testing.T) {
+		got := search(t, url.Values{"name": {"ivan"}, "phone": {"4411"}})
+
+		testutil.AssertEqual(t, customerIds(got), []int{ivan.Id, ivana.Id})
+		testutil.AssertEqual(t, got.Customers[0], handlers.CustomerToAdminCustomerResponse(ivan))
+		testutil.AssertEqual(t, got.NextCursor, "")
+	})
+
+	t.Run("matches prefix of the full name", func(t *testing.T) {
+		got := search(t, url.Values{"name": {"Ivan Pet"}})
+
+		testutil.AssertEqual(t, customerIds(got), []int{ivan.Id})
+	})
+
+	t.Run("filters by email fragment and status", func(t *testing.T) {
+		got := search(t, url.Values{"email": {"ABV.BG"}, "status": {models.ACTIVE_STATUS}})
+
+		testutil.AssertEqual(t, customerIds(got), []int{ivan.Id, ivo.Id})
+	})
+
+	t.Run("filters by created range", func(t *testing.T) {
+		got := search(t, url.Values{
+			"created_after":  {created.Add(-48 * time.Hour).Format(time.RFC3339)},
+			"created_before": {created.Format(time.RFC3339)},
+		})
+
+		testutil.AssertEqual(t, customerIds(got), []int{ivana.Id, ivo.Id})
+	})
+
+	t.Run("pages through results sorted by newest first", func(t *testing.T) {
+		query := url.Values{"sort": {"created_at"}, "order": {"desc"}, "limit": {"3"}}
+
+		first := search(t, query)
+		testutil.AssertEqual(t, customerIds(first), []int{erased.Id, ivo.Id, ivana.Id})
+
+		query.Set("cursor", first.NextCursor)
+		second := search(t, query)
+		testutil.AssertEqual(t, customerIds(second), []int{ivan.Id})
+		testutil.AssertEqual(t, second.NextCursor, "")
+	})
+
+	t.Run("returns Bad Request on cursor of a different sort", func(t *testing.T) {
+		first := search(t, url.Values{"limit": {"1"}})
+
+		request := handlers.NewSearchCustomersRequest(adminJWT, url.Values{"sort": {"email"}, "cursor": {first.NextCursor}})
+		response := httptest.NewRecorder()
+
+		server.ServeHTTP(response, request)
+
+		testutil.AssertStatus(t, response.Code, http.StatusBadRequest)
+		testutil.AssertErrorResponse(t, response.Body, models.ErrInvalidCursor)
+	})
+
+	cases := map[string]url.Values{
+		"unknown sort":   {"sort": {"password"}},
+		"too large page": {"limit": {"1000"}},
+		"invalid date":   {"created_after": {"yesterday"}},
+		"unknown status": {"status": {"deleted"}},
+	}
+
+	for name, query := range cases {
+		t.Run("returns Bad Request on "+name, func(t *testing.T) {
+			request := handlers.NewSearchCustomersRequest(adminJWT, query)
+			response := httptest.NewRecorder()
+
+			server.ServeHTTP(response, request)
+
+			testutil.AssertStatus(t, response.Code, http.StatusBadRequest)
+		})
+	}
+}
+
+func TestRecordLoyaltyTransaction(t *testing.T) {
+	customerStore := testutil.NewStubCustomerStore([]models.Customer{td.PeterCustomer, td.AliceCustomer})
+	loyaltyStore := testutil.NewStubLoyaltyStore([]models.LoyaltyTransaction{td.PeterEarnedPoints})
+	server := handlers.NewAdminServer(testEnv.AdminSecretKey, customerStore, testutil.NewStubConsentStore(nil, nil),
+		testutil.NewStubBlobStore(), loyaltyStore, testutil.NewStubDuplicateStore(nil), testutil.NewStubSegmentStore(nil, nil, nil), testutil.NewStubCustomerExportStore(nil, nil, nil), testutil.NewStubAddressStore(nil))
+
+	adminJWT, _ := auth.GenerateJWT(testEnv.AdminSecretKey, testEnv.ExpiresAt, 1)
+
+	t.Run("records earned points with an expiry", func(t *testing.T) {
+		recordTransactionRequest := handlers.RecordTransactionRequest{
+			Id:         "order-2001-earn",
+			CustomerId: td.AliceCustomer.Id,
+			Kind:       models.EARN_TRANSACTION,
+			Points:     40,
+		}
+
+		request := handlers.NewRecordTransactionRequest(adminJWT, recordTransactionRequest)
+		response := httptest.NewRecorder()
+
+		server.ServeHTTP(response, request)
+
+		testutil.AssertStatus(t, response.Code, http.StatusOK)
+
+		want := models.LoyaltyTransaction{Id: "order-2001-earn", CustomerId: td.AliceCustomer.Id, Kind: models.EARN_TRANSACTION, Points: 40}
+		testutil.AssertRecordedTransaction(t, loyaltyStore, want)
+
+		var got handlers.LoyaltyTransactionResponse
+		json.NewDecoder(response.Body).Decode(&got)
+
+		if got.ExpiresAt == nil || got.ExpiresAt.Sub(got.CreatedAt) < models.POINTS_LIFETIME-time.Minute {
+			t.Errorf("earned points should expire after %v, got %v", models.POINTS_LIFETIME, got.ExpiresAt)
+		}
+	})
+
+	t.Run("returns the recorded transaction on retry", func(t *testing.T) {
+		recordTransactionRequest := handlers.RecordTransactionRequest{
+			Id:          td.PeterEarnedPoints.Id,
+			CustomerId:  td.PeterEarnedPoints.CustomerId,
+			Kind:        td.PeterEarnedPoints.Kind,
+			Points:      td.PeterEarnedPoints.Points,
+			Description: td.PeterEarnedPoints.Description,
+		}
+
+		request := handlers.NewRecordTransactionRequest(adminJWT, recordTransactionRequest)
+		response := httptest.NewRecorder()
+
+		server.ServeHTTP(response, request)
+
+		testutil.AssertStatus(t, response.Code, http.StatusOK)
+
+		var got handlers.LoyaltyTransactionResponse
+		json.NewDecoder(response.Body).Decode(&got)
+
+		testutil.AssertEqual(t, got, handlers.LoyaltyTransactionToLoyaltyTransactionResponse(td.PeterEarnedPoints))
+	})
+
+	cases := map[string]struct {
+		request handlers.RecordTransactionRequest
+		status  int
+		err     error
+	}{
+		"reused transaction ID": {
+			request: handlers.RecordTransactionRequest{Id: td.PeterEarnedPoints.Id, CustomerId: td.PeterCustomer.Id, Kind: models.EARN_TRANSACTION, Points: 500},
+			status:  http.StatusConflict,
+			err:     handlers.ErrTransactionConflict,
+		},
+		"redemption over the balance": {
+			request: handlers.RecordTransactionRequest{Id: "order-1003-redeem", CustomerId: td.PeterCustomer.Id, Kind: models.REDEEM_TRANSACTION, Points: 500},
+			status:  http.StatusConflict,
+			err:     handlers.ErrInsufficientPoints,
+		},
+		"adjustment over the balance": {
+			request: handlers.RecordTransactionRequest{Id: "support-17", CustomerId: td.PeterCustomer.Id, Kind: models.ADJUST_TRANSACTION, Points: -500},
+			status:  http.StatusConflict,
+			err:     handlers.ErrInsufficientPoints,
+		},
+		"negative earned points": {
+			request: handlers.RecordTransactionRequest{Id: "order-1004-earn", CustomerId: td.PeterCustomer.Id, Kind: models.EARN_TRANSACTION, Points: -5},
+			status:  http.StatusBadRequest,
+			err:     handlers.ErrNegativePoints,
+		},
+		"missing customer": {
+			request: handlers.RecordTransactionRequest{Id: "order-1005-earn", CustomerId: 10, Kind: models.EARN_TRANSACTION, Points: 5},
+			status:  http.StatusNotFound,
+			err:     handlers.ErrCustomerNotFound,
+		},
+	}
+
+	for name, test := range cases {
+		t.Run("rejects "+name, func(t *testing.T) {
+			request := handlers.NewRecordTransactionRequest(adminJWT, test.request)
+			response := httptest.NewRecorder()
+
+			server.ServeHTTP(response, request)
+
+			testutil.AssertStatus(t, response.Code, test.status)
+			testutil.AssertErrorResponse(t, response.Body, test.err)
+		})
+	}
+}
+
+func TestDuplicateReview(t *testing.T) {
+	newCandidates := func() []models.DuplicateCandidate {
+		return []models.DuplicateCandidate{
+			{Id: 1, CustomerId: td.PeterCustomer.Id, DuplicateId: td.AliceCustomer.Id, Score: 0.85,
+				Reasons: []string{models.SAME_PHONE_REASON, models.SAME_NAME_REASON}, Status: models.PENDING_CANDIDATE},
+			{Id: 2, CustomerId: td.PeterCustomer.Id, DuplicateId: 3, Score: 0.6,
+				Reasons: []string{models.SIMILAR_NAME_REASON, models.NEAR_ADDRESS_REASON}, Status: models.DISMISSED_CANDIDATE},
+		}
+	}
+	newServer := func(duplicateStore models.DuplicateStore) *handlers.AdminServer {
+		customerStore := testutil.NewStubCustomerStore([]models.Customer{td.PeterCustomer, td.AliceCustomer})
+		return handlers.NewAdminServer(testEnv.AdminSecretKey, customerStore, testutil.NewStubConsentStore(nil, nil),
+			testutil.NewStubBlobStore(), testutil.NewStubLoyaltyStore(nil), duplicateStore,
+			testutil.NewStubSegmentStore(nil, nil, nil), testutil.NewStubCustomerExportStore(nil, nil, nil), testutil.NewStubAddressStore(nil))
+	}
+
+	adminJWT, _ := auth.GenerateJWT(testEnv.AdminSecretKey, testEnv.ExpiresAt, 1)
+
+	t.Run("lists pending candidates by default", func(t *testing.T) {
+		server := newServer(testutil.NewStubDuplicateStore(newCandidates()))
+
+		request := handlers.NewGetDuplicateCandidatesRequest(adminJWT, "")
+		response := httptest.NewRecorder()
+
+		server.ServeHTTP(response, request)
+
+		testutil.AssertStatus(t, response.Code, http.StatusOK)
+
+		var got []handlers.DuplicateCandidateResponse
+		json.NewDecoder(response.Body).Decode(&got)
+
+		want := []handlers.DuplicateCandidateResponse{handlers.DuplicateCandidateToDuplicateCandidateResponse(newCandidates()[0])}
+		testutil.AssertEqual(t, got, want)
+	})
+
+	t.Run("returns Bad Request on unknown status", func(t *testing.T) {
+		server := newServer(testutil.NewStubDuplicateStore(newCandidates()))
+
+		request := handlers.NewGetDuplicateCandidatesRequest(adminJWT, "open")
+		response := httptest.NewRecorder()
+
+		server.ServeHTTP(response, request)
+
+		testutil.AssertStatus(t, response.Code, http.StatusBadRequest)
+	})
+
+	t.Run("merges into the chosen survivor", func(t *testing.T) {
+		duplicateStore := testutil.NewStubDuplicateStore(newCandidates())
+		server := newServer(duplicateStore)
+
+		request := handlers.NewMergeCustomersRequest(adminJWT, 1, td.AliceCustomer.Id)
+		response := httptest.NewRecorder()
+
+		server.ServeHTTP(response, request)
+
+		testutil.AssertStatus(t, response.Code, http.StatusOK)
+		testutil.AssertMergedCustomers(t, duplicateStore, td.AliceCustomer.Id, td.PeterCustomer.Id)
+
+		var got handlers.MergeCustomersResponse
+		json.NewDecoder(response.Body).Decode(&got)
+
+		testutil.AssertEqual(t, got.MergedId, td.PeterCustomer.Id)
+	})
+
+	t.Run("dismisses a candidate", func(t *testing.T) {
+		duplicateStore := testutil.NewStubDuplicateStore(newCandidates())
+		server := newServer(duplicateStore)
+
+		request := handlers.NewDismissCandidateRequest(adminJWT, 1)
+		response := httptest.NewRecorder()
+
+		server.ServeHTTP(response, request)
+
+		testutil.AssertStatus(t, response.Code, http.StatusOK)
+
+		pending, _ := duplicateStore.GetCandidates(models.PENDING_CANDIDATE)
+		testutil.AssertEqual(t, len(pending), 0)
+	})
+
+	cases := map[string]struct {
+		request *http.Request
+		status  int
+		err     error
+	}{
+		"survivor outside the pair": {
+			request: handlers.NewMergeCustomersRequest(adminJWT, 1, 3),
+			status:  http.StatusConflict,
+			err:     handlers.ErrInvalidMerge,
+		},
+		"merge of a reviewed candidate": {
+			request: handlers.NewMergeCustomersRequest(adminJWT, 2, td.PeterCustomer.Id),
+			status:  http.StatusConflict,
+			err:     handlers.ErrCandidateReviewed,
+		},
+		"dismissal of a reviewed candidate": {
+			request: handlers.NewDismissCandidateRequest(adminJWT, 2),
+			status:  http.StatusConflict,
+			err:     handlers.ErrCandidateReviewed,
+		},
+		"missing candidate": {
+			request: handlers.NewMergeCustomersRequest(adminJWT, 10, td.PeterCustomer.Id),
+			status:  http.StatusNotFound,
+			err:     handlers.ErrCandidateNotFound,
+		},
+	}
+
+	for name, test := range cases {
+		t.Run("rejects "+name, func(t *testing.T) {
+			server := newServer(testutil.NewStubDuplicateStore(newCandidates()))
+			response := httptest.NewRecorder()
+
+			server.ServeHTTP(response, test.request)
+
+			testutil.AssertStatus(t, response.Code, test.status)
+			testutil.AssertErrorResponse(t, response.Body, test.err)
+		})
+	}
+}
+
+func TestCustomerTags(t *testing.T) {
+	customerStore := testutil.NewStubCustomerStore([]models.Customer{td.PeterCustomer, td.AliceCustomer})
+	segmentStore := testutil.NewStubSegmentStore(map[int][]string{td.PeterCustomer.Id: {"vip"}}, nil, nil)
+	server := handlers.NewAdminServer(testEnv.AdminSecretKey, customerStore, testutil.NewStubConsentStore(nil, nil),
+		testutil.NewStubBlobStore(), testutil.NewStubLoyaltyStore(nil), testutil.NewStubDuplicateStore(nil), segmentStore,
+		testutil.NewStubCustomerExportStore(nil, nil, nil), testutil.NewStubAddressStore(nil))
+
+	adminJWT, _ := auth.GenerateJWT(testEnv.AdminSecretKey, testEnv.ExpiresAt, 1)
+
+	t.Run("adds normalized tags", func(t *testing.T) {
+		request := handlers.NewAddTagsRequest(adminJWT, td.PeterCustomer.Id, []string{" Corporate", "VIP"})
+		response := httptest.NewRecorder()
+
+		server.ServeHTTP(response, request)
+
+		testutil.AssertStatus(t, response.Code, http.StatusOK)
+
+		var got handlers.TagsResponse
+		json.NewDecoder(response.Body).Decode(&got)
+
+		testutil.AssertEqual(t, got, handlers.TagsResponse{CustomerId: td.PeterCustomer.Id, Tags: []string{"corporate", "vip"}})
+	})
+
+	t.Run("removes a tag", func(t *testing.T) {
+		request := handlers.NewRemoveTagRequest(adminJWT, td.PeterCustomer.Id, "corporate")
+		response := httptest.NewRecorder()
+
+		server.ServeHTTP(response, request)
+
+		testutil.AssertStatus(t, response.Code, http.StatusOK)
+
+		tags, _ := segmentStore.GetTags(td.PeterCustomer.Id)
+		testutil.AssertEqual(t, tags, []string{"vip"})
+	})
+
+	t.Run("returns Bad Request on malformed tag", func(t *testing.T) {
+		request := handlers.NewAddTagsRequest(adminJWT, td.PeterCustomer.Id, []string{"fraud watch!"})
+		response := httptest.NewRecorder()
+
+		server.ServeHTTP(response, request)
+
+		testutil.AssertStatus(t, response.Code, http.StatusBadRequest)
+		testutil.AssertFieldErrorResponse(t, response.Body, models.ErrInvalidTag, "Tags")
+	})
+
+	t.Run("returns Not Found on missing customer", func(t *testing.T) {
+		request := handlers.NewAddTagsRequest(adminJWT, 10, []string{"vip"})
+		response := httptest.NewRecorder()
+
+		server.ServeHTTP(response, request)
+
+		testutil.AssertStatus(t, response.Code, http.StatusNotFound)
+		testutil.AssertErrorResponse(t, response.Body, handlers.ErrCustomerNotFound)
+	})
+
+	t.Run("returns Not Found on missing tag", func(t *testing.T) {
+		request := handlers.NewRemoveTagRequest(adminJWT, td.AliceCustomer.Id, "vip")
+		response := httptest.NewRecorder()
+
+		server.ServeHTTP(response, request)
+
+		testutil.AssertStatus(t, response.Code, http.StatusNotFound)
+		testutil.AssertErrorResponse(t, response.Body, handlers.ErrTagNotFound)
+	})
+}
+
+func TestSegments(t *testing.T) {
+	customerData := []models.Customer{td.PeterCustomer, td.AliceCustomer}
+	segmentStore := testutil.NewStubSegmentStore(nil, nil, map[string][]int{"sofia": {td.PeterCustomer.Id}})
+	server := handlers.NewAdminServer(testEnv.AdminSecretKey, testutil.NewStubCustomerStore(customerData), testutil.NewStubConsentStore(nil, nil),
+		testutil.NewStubBlobStore(), testutil.NewStubLoyaltyStore(nil), testutil.NewStubDuplicateStore(nil), segmentStore,
+		testutil.NewStubCustomerExportStore(nil, nil, nil), testutil.NewStubAddressStore(nil))
+
+	adminJWT, _ := auth.GenerateJWT(testEnv.AdminSecretKey, testEnv.ExpiresAt, 1)
+
+	createSegmentRequest := handlers.CreateSegmentRequest{
+		Name:        "Sofia",
+		Description: "customers with addresses in Sofia",
+		Rules: handlers.SegmentRulesRequest{
+			Cities:       []string{"sofia"},
+			ExcludedTags: []string{"Test-Account"},
+		},
+	}
+
+	t.Run("creates a segment", func(t *testing.T) {
+		request := handlers.NewCreateSegmentRequest(adminJWT, createSegmentRequest)
+		response := httptest.NewRecorder()
+
+		server.ServeHTTP(response, request)
+
+		testutil.AssertStatus(t, response.Code, http.StatusOK)
+
+		var got handlers.SegmentResponse
+		json.NewDecoder(response.Body).Decode(&got)
+
+		testutil.AssertEqual(t, got.Name, "sofia")
+		testutil.AssertEqual(t, got.Rules.ExcludedTags, []string{"test-account"})
+	})
+
+	t.Run("returns Conflict on existing segment name", func(t *testing.T) {
+		request := handlers.NewCreateSegmentRequest(adminJWT, createSegmentRequest)
+		response := httptest.NewRecorder()
+
+		server.ServeHTTP(response, request)
+
+		testutil.AssertStatus(t, response.Code, http.StatusConflict)
+		testutil.AssertFieldErrorResponse(t, response.Body, handlers.ErrConflictingField, "Name")
+	})
+
+	t.Run("lists segment members", func(t *testing.T) {
+		request := handlers.NewGetSegmentMembersRequest(adminJWT, url.Values{"segment": {"sofia"}})
+		response := httptest.NewRecorder()
+
+		server.ServeHTTP(response, request)
+
+		testutil.AssertStatus(t, response.Code, http.StatusOK)
+
+		var got handlers.SegmentMembersResponse
+		json.NewDecoder(response.Body).Decode(&got)
+
+		testutil.AssertEqual(t, got, handlers.SegmentMembersResponse{Segment: "sofia", CustomerIds: []int{td.PeterCustomer.Id}})
+	})
+
+	t.Run("lists the segments of a customer", func(t *testing.T) {
+		request := handlers.NewGetCustomerSegmentsRequest(adminJWT, td.AliceCustomer.Id)
+		response := httptest.NewRecorder()
+
+		server.ServeHTTP(response, request)
+
+		testutil.AssertStatus(t, response.Code, http.StatusOK)
+
+		var got handlers.CustomerSegmentsResponse
+		json.NewDecoder(response.Body).Decode(&got)
+
+		testutil.AssertEqual(t, got, handlers.CustomerSegmentsResponse{CustomerId: td.AliceCustomer.Id, Segments: []string{}})
+	})
+
+	t.Run("returns Not Found on missing segment", func(t *testing.T) {
+		request := handlers.NewGetSegmentMembersRequest(adminJWT, url.Values{"segment": {"varna"}})
+		response := httptest.NewRecorder()
+
+		server.ServeHTTP(response, request)
+
+		testutil.AssertStatus(t, response.Code, http.StatusNotFound)
+		testutil.AssertErrorResponse(t, response.Body, handlers.ErrSegmentNotFound)
+	})
+}
+
+func TestExportTable(t *testing.T) {
+	customerData := []models.Customer{td.PeterCustomer, td.AliceCustomer}
+	exportStore := testutil.NewStubCustomerExportStore(customerData, []models.Address{td.PeterAddress1}, nil)
+	server := handlers.NewAdminServer(testEnv.AdminSecretKey, testutil.NewStubCustomerStore(customerData), testutil.NewStubConsentStore(nil, nil),
+		testutil.NewStubBlobStore(), testutil.NewStubLoyaltyStore(nil), testutil.NewStubDuplicateStore(nil),
+		testutil.NewStubSegmentStore(nil, nil, nil), exportStore, testutil.NewStubAddressStore(nil))
+	server.SetExportHashKey([]byte("testExportHashKey"))
+
+	adminJWT, _ := auth.GenerateJWT(testEnv.AdminSecretKey, testEnv.ExpiresAt, 1)
+
+	t.Run("streams table with hashed PII by default", func(t *testing.T) {
+		request := handlers.NewExportRequest(adminJWT, url.Values{"table": {"customers"}})
+		response := httptest.NewRecorder()
+
+		server.ServeHTTP(response, request)
+
+		testutil.AssertStatus(t, response.Code, http.StatusOK)
+		testutil.AssertEqual(t, response.Header().Get("Content-Type"), "application/x-ndjson")
+
+		var got map[string]any
+		json.NewDecoder(response.Body).Decode(&got)
+
+		if _, ok := got["email_hash"]; !ok {
+			t.Errorf("got %v want hashed email", got)
+		}
+	})
+
+	t.Run("returns Bad Request on unknown table", func(t *testing.T) {
+		request := handlers.NewExportRequest(adminJWT, url.Values{"table": {"consents"}})
+		response := httptest.NewRecorder()
+
+		server.ServeHTTP(response, request)
+
+		testutil.AssertStatus(t, response.Code, http.StatusBadRequest)
+	})
+
+	t.Run("returns Bad Request on invalid since", func(t *testing.T) {
+		request := handlers.NewExportRequest(adminJWT, url.Values{"table": {"addresses"}, "since": {"yesterday"}})
+		response := httptest.NewRecorder()
+
+		server.ServeHTTP(response, request)
+
+		testutil.AssertStatus(t, response.Code, http.StatusBadRequest)
+		testutil.AssertErrorResponse(t, response.Body, handlers.ErrIncorrectRequestType)
+	})
+
+	t.Run("returns Internal Server Error on hashed PII without a hash key", func(t *testing.T) {
+		server := handlers.NewAdminServer(testEnv.AdminSecretKey, testutil.NewStubCustomerStore(customerData), testutil.NewStubConsentStore(nil, nil),
+			testutil.NewStubBlobStore(), testutil.NewStubLoyaltyStore(nil), testutil.NewStubDuplicateStore(nil),
+			testutil.NewStubSegmentStore(nil, nil, nil), exportStore, testutil.NewStubAddressStore(nil))
+
+		request := handlers.NewExportRequest(adminJWT, url.Values{"table": {"customers"}})
+		response := httptest.NewRecorder()
+
+		server.ServeHTTP(response, request)
+
+		testutil.AssertStatus(t, response.Code, http.StatusInternalServerError)
+		testutil.AssertErrorResponse(t, response.Body, exporter.ErrMissingHashKey)
+	})
+}
+
+func TestSearchNearbyAddresses(t *testing.T) {
+	addressData := []models.Address{td.PeterAddress1, td.PeterAddress2, td.AliceAddress}
+	server := handlers.NewAdminServer(testEnv.AdminSecretKey, testutil.NewStubCustomerStore(nil), testutil.NewStubConsentStore(nil, nil),
+		testutil.NewStubBlobStore(), testutil.NewStubLoyaltyStore(nil), testutil.NewStubDuplicateStore(nil),
+		testutil.NewStubSegmentStore(nil, nil, nil), testutil.NewStubCustomerExportStore(nil, nil, nil), testutil.NewStubAddressStore(addressData))
+
+	adminJWT, _ := auth.GenerateJWT(testEnv.AdminSecretKey, testEnv.ExpiresAt, 1)
+
+	search := func(t testing.TB, query url.Values) handlers.NearbyAddressesResponse {
+		t.Helper()
+
+		request := handlers.NewNearbyAddressesRequest(adminJWT, query)
+		response := httptest.NewRecorder()
+
+		server.ServeHTTP(response, request)
+
+		testutil.AssertStatus(t, response.Code, http.StatusOK)
+
+		var got handlers.NearbyAddressesResponse
+		json.NewDecoder(response.Body).Decode(&got)
+		return got
+	}
+
+	addressIds := func(nearbyAddressesResponse handlers.NearbyAddressesResponse) []int {
+		ids := []int{}
+		for _, address := range nearbyAddressesResponse.Addresses {
+			ids = append(ids, address.Id)
+		}
+		return ids
+	}
+
+	aliceQuery := func(radius, limit string) url.Values {
+		return url.Values{"lat": {"42.6931204"}, "lon": {"23.3225465"}, "radius": {radius}, "limit": {limit}}
+	}
+
+	t.Run("finds addresses within radius nearest first", func(t *testing.T) {
+		got := search(t, aliceQuery("1000", "20"))
+
+		testutil.AssertEqual(t, addressIds(got), []int{td.AliceAddress.Id, td.PeterAddress1.Id})
+		testutil.AssertEqual(t, got.Addresses[0].Distance, 0.0)
+
+		want := models.Distance(td.AliceAddress.Lat, td.AliceAddress.Lon, td.PeterAddress1.Lat, td.PeterAddress1.Lon)
+		testutil.AssertEqual(t, got.Addresses[1].Distance, want)
+		testutil.AssertEqual(t, got.Addresses[1].CustomerId, td.PeterAddress1.CustomerId)
+	})
+
+	t.Run("pages through matches", func(t *testing.T) {
+		first := search(t, aliceQuery("2000", "2"))
+		testutil.AssertEqual(t, addressIds(first), []int{td.AliceAddress.Id, td.PeterAddress1.Id})
+
+		query := aliceQuery("2000", "2")
+		query.Set("cursor", first.NextCursor)
+		second := search(t, query)
+		testutil.AssertEqual(t, addressIds(second), []int{td.PeterAddress2.Id})
+		testutil.AssertEqual(t, second.NextCursor, "")
+	})
+
+	t.Run("finds addresses in bounding box", func(t *testing.T) {
+		got := search(t, url.Values{"min_lat": {"42.693"}, "min_lon": {"23.325"}, "max_lat": {"42.696"}, "max_lon": {"23.34"}})
+
+		testutil.AssertEqual(t, addressIds(got), []int{td.PeterAddress1.Id, td.PeterAddress2.Id})
+	})
+
+	cases := map[string]struct {
+		query url.Values
+		err   error
+	}{
+		"missing area":        {url.Values{}, handlers.ErrInvalidArea},
+		"radius without lon":  {url.Values{"lat": {"42.69"}, "radius": {"1000"}}, handlers.ErrInvalidArea},
+		"both radius and box": {url.Values{"lat": {"42.69"}, "lon": {"23.32"}, "radius": {"1000"}, "min_lat": {"42.6"}}, handlers.ErrInvalidArea},
+		"too large box":       {url.Values{"min_lat": {"42"}, "min_lon": {"23"}, "max_lat": {"44"}, "max_lon": {"24"}}, handlers.ErrInvalidArea},
+		"invalid radius":      {url.Values{"lat": {"42.69"}, "lon": {"23.32"}, "radius": {"far"}}, handlers.ErrIncorrectRequestType},
+		"invalid cursor":      {url.Values{"lat": {"42.69"}, "lon": {"23.32"}, "radius": {"1000"}, "cursor": {"nope"}}, models.ErrInvalidCursor},
+	}
+
+	for name, test := range cases {
+		t.Run("returns Bad Request on "+name, func(t *testing.T) {
+			request := handlers.NewNearbyAddressesRequest(adminJWT, test.query)
+			response := httptest.NewRecorder()
+
+			server.ServeHTTP(response, request)
+
+			testutil.AssertStatus(t, response.Code, http.StatusBadRequest)
+			testutil.AssertErrorResponse(t, response.Body, test.err)
+		})
+	}
+
+	t.Run("returns Bad Request on too large radius", func(t *testing.T) {
+		request := handlers.NewNearbyAddressesRequest(adminJWT, aliceQuery("100000", "20"))
+		response := httptest.NewRecorder()
+
+		server.ServeHTTP(response, request)
+
+		testutil.AssertStatus(t, response.Code, http.StatusBadRequest)
+	})
+}
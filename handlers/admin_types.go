@@ -0,0 +1,383 @@
+package handlers
+
+import (
+	"time"
+
+	"github.com/VitoNaychev/bt-customer-svc/models"
+)
+
+type AnonymizeCustomerRequest struct {
+	Id int `validate:"min=1"`
+}
+
+type AnonymizeCustomerResponse struct {
+	CustomerId     int
+	CustomerFields []string
+	Addresses      []AnonymizedAddressResponse
+}
+
+type AnonymizedAddressResponse struct {
+	AddressId int
+	Fields    []string
+}
+
+func ErasureReportToAnonymizeCustomerResponse(report models.ErasureReport) AnonymizeCustomerResponse {
+	anonymizeCustomerResponse := AnonymizeCustomerResponse{
+		CustomerId:     report.CustomerId,
+		CustomerFields: report.CustomerFields,
+		Addresses:      []AnonymizedAddressResponse{},
+	}
+
+	for _, address := range report.Addresses {
+		anonymizedAddress := AnonymizedAddressResponse{
+			AddressId: address.AddressId,
+			Fields:    address.Fields,
+		}
+		anonymizeCustomerResponse.Addresses = append(anonymizeCustomerResponse.Addresses, anonymizedAddress)
+	}
+
+	return anonymizeCustomerResponse
+}
+
+const DEFAULT_SEARCH_LIMIT = 20
+
+// SearchCustomersRequest is parsed from the query string of an admin
+// search. CreatedAfter is inclusive and CreatedBefore is exclusive.
+type SearchCustomersRequest struct {
+	Name          string `validate:"max=40"`
+	Email         string `validate:"max=40"`
+	Phone         string `validate:"max=20"`
+	Status        string `validate:"omitempty,oneof=active anonymized merged"`
+	CreatedAfter  time.Time
+	CreatedBefore time.Time
+	Sort          string `validate:"oneof=id created_at first_name last_name email"`
+	Order         string `validate:"oneof=asc desc"`
+	Cursor        string `validate:"max=512"`
+	Limit         int    `validate:"min=1,max=100"`
+}
+
+type AdminCustomerResponse struct {
+	Id          int
+	FirstName   string
+	LastName    string
+	PhoneNumber string
+	Email       string
+	Status      string
+	CreatedAt   time.Time
+}
+
+type SearchCustomersResponse struct {
+	Customers  []AdminCustomerResponse
+	NextCursor string
+}
+
+func SearchCustomersRequestToCustomerSearch(searchCustomersRequest SearchCustomersRequest) (models.CustomerSearch, error) {
+	search := models.CustomerSearch{
+		NamePrefix:    searchCustomersRequest.Name,
+		EmailFragment: searchCustomersRequest.Email,
+		PhoneFragment: searchCustomersRequest.Phone,
+		Status:        searchCustomersRequest.Status,
+		CreatedAfter:  searchCustomersRequest.CreatedAfter,
+		CreatedBefore: searchCustomersRequest.CreatedBefore,
+		SortBy:        searchCustomersRequest.Sort,
+		Descending:    searchCustomersRequest.Order == "desc",
+		Limit:         searchCustomersRequest.Limit,
+	}
+
+	if searchCustomersRequest.Cursor != "" {
+		cursor, err := models.DecodeSearchCursor(searchCustomersRequest.Cursor)
+		if err != nil || cursor.SortBy != search.SortBy {
+			return models.CustomerSearch{}, models.ErrInvalidCursor
+		}
+		search.After = &cursor
+	}
+
+	return search, nil
+}
+
+func CustomerToAdminCustomerResponse(customer models.Customer) AdminCustomerResponse {
+	return AdminCustomerResponse{
+		Id:          customer.Id,
+		FirstName:   customer.FirstName,
+		LastName:    customer.LastName,
+		PhoneNumber: customer.PhoneNumber,
+		Email:       customer.Email,
+		Status:      customer.Status,
+		CreatedAt:   customer.CreatedAt,
+	}
+}
+
+func CustomerPageToSearchCustomersResponse(page models.CustomerPage) SearchCustomersResponse {
+	searchCustomersResponse := SearchCustomersResponse{Customers: []AdminCustomerResponse{}}
+
+	for _, customer := range page.Customers {
+		searchCustomersResponse.Customers = append(searchCustomersResponse.Customers, CustomerToAdminCustomerResponse(customer))
+	}
+
+	if page.NextCursor != nil {
+		searchCustomersResponse.NextCursor = page.NextCursor.Encode()
+	}
+
+	return searchCustomersResponse
+}
+
+type ListCandidatesRequest struct {
+	Status string `validate:"oneof=pending merged dismissed"`
+}
+
+type DuplicateCandidateResponse struct {
+	Id          int
+	CustomerId  int
+	DuplicateId int
+	Score       float64
+	Reasons     []string
+	Status      string
+	CreatedAt   time.Time
+	ReviewedAt  *time.Time
+}
+
+func DuplicateCandidateToDuplicateCandidateResponse(candidate models.DuplicateCandidate) DuplicateCandidateResponse {
+	return DuplicateCandidateResponse{
+		Id:          candidate.Id,
+		CustomerId:  candidate.CustomerId,
+		DuplicateId: candidate.DuplicateId,
+		Score:       candidate.Score,
+		Reasons:     candidate.Reasons,
+		Status:      candidate.Status,
+		CreatedAt:   candidate.CreatedAt,
+		ReviewedAt:  candidate.ReviewedAt,
+	}
+}
+
+type MergeCustomersRequest struct {
+	CandidateId int `validate:"min=1"`
+	SurvivorId  int `validate:"min=1"`
+}
+
+type MergeCustomersResponse struct {
+	SurvivorId        int
+	MergedId          int
+	Addresses         int
+	Preferences       bool
+	TransferredPoints int
+}
+
+func MergeReportToMergeCustomersResponse(report models.MergeReport) MergeCustomersResponse {
+	return MergeCustomersResponse{
+		SurvivorId:        report.SurvivorId,
+		MergedId:          report.MergedId,
+		Addresses:         report.Addresses,
+		Preferences:       report.Preferences,
+		TransferredPoints: report.TransferredPoints,
+	}
+}
+
+type DismissCandidateRequest struct {
+	CandidateId int `validate:"min=1"`
+}
+
+type AddTagsRequest struct {
+	CustomerId int      `validate:"min=1"`
+	Tags       []string `validate:"required,min=1,max=20"`
+}
+
+type TagsResponse struct {
+	CustomerId int
+	Tags       []string
+}
+
+type SegmentRulesRequest struct {
+	Tags          []string `validate:"max=20"`
+	ExcludedTags  []string `validate:"max=20"`
+	Cities        []string `validate:"max=50,dive,required,max=40"`
+	Countries     []string `validate:"max=50,dive,required,max=40"`
+	CreatedAfter  *time.Time
+	CreatedBefore *time.Time
+}
+
+type CreateSegmentRequest struct {
+	Name        string `validate:"required,max=32"`
+	Description string `validate:"max=255"`
+	Rules       SegmentRulesRequest
+}
+
+func CreateSegmentRequestToSegment(createSegmentRequest CreateSegmentRequest) (models.Segment, error) {
+	name, err := models.NormalizeSegmentName(createSegmentRequest.Name)
+	if err != nil {
+		return models.Segment{}, err
+	}
+
+	segment := models.Segment{
+		Name:        name,
+		Description: createSegmentRequest.Description,
+		Rules: models.SegmentRules{
+			Tags:          createSegmentRequest.Rules.Tags,
+			ExcludedTags:  createSegmentRequest.Rules.ExcludedTags,
+			Cities:        createSegmentRequest.Rules.Cities,
+			Countries:     createSegmentRequest.Rules.Countries,
+			CreatedAfter:  createSegmentRequest.Rules.CreatedAfter,
+			CreatedBefore: createSegmentRequest.Rules.CreatedBefore,
+		},
+	}
+
+	err = segment.Rules.Normalize()
+	return segment, err
+}
+
+type SegmentRulesResponse struct {
+	Tags          []string
+	ExcludedTags  []string
+	Cities        []string
+	Countries     []string
+	CreatedAfter  *time.Time
+	CreatedBefore *time.Time
+}
+
+type SegmentResponse struct {
+	Id          int
+	Name        string
+	Description string
+	Rules       SegmentRulesResponse
+	CreatedAt   time.Time
+}
+
+func SegmentToSegmentResponse(segment models.Segment) SegmentResponse {
+	return SegmentResponse{
+		Id:          segment.Id,
+		Name:        segment.Name,
+		Description: segment.Description,
+		Rules: SegmentRulesResponse{
+			Tags:          segment.Rules.Tags,
+			ExcludedTags:  segment.Rules.ExcludedTags,
+			Cities:        segment.Rules.Cities,
+			Countries:     segment.Rules.Countries,
+			CreatedAfter:  segment.Rules.CreatedAfter,
+			CreatedBefore: segment.Rules.CreatedBefore,
+		},
+		CreatedAt: segment.CreatedAt,
+	}
+}
+
+const DEFAULT_SEGMENT_MEMBERS_LIMIT = 1000
+
+type SegmentMembersRequest struct {
+	Segment string `validate:"required,max=32"`
+	After   int    `validate:"min=0"`
+	Limit   int    `validate:"min=1,max=10000"`
+}
+
+// SegmentMembersResponse holds a page of member IDs. NextAfter is passed
+// as after to fetch the next page and is 0 on the last one.
+type SegmentMembersResponse struct {
+	Segment     string
+	CustomerIds []int
+	NextAfter   int
+}
+
+type CustomerSegmentsResponse struct {
+	CustomerId int
+	Segments   []string
+}
+
+// ExportRequest is parsed from the query string of an export. Since is
+// inclusive and compared to the time rows were last updated.
+type ExportRequest struct {
+	Table  string `validate:"oneof=customers addresses preferences"`
+	Format string `validate:"oneof=jsonl csv parquet"`
+	PII    string `validate:"oneof=keep hash drop"`
+	Since  time.Time
+}
+
+const (
+	DEFAULT_NEARBY_LIMIT = 20
+	// MAX_NEARBY_BOX_SPAN is the largest side, in degrees, of a bounding
+	// box that can be searched for nearby addresses.
+	MAX_NEARBY_BOX_SPAN = 1.0
+)
+
+// NearbyAddressesRequest is parsed from the query string of an admin
+// proximity search. It either has a Radius, in meters, around Lat and Lon
+// or a bounding box.
+type NearbyAddressesRequest struct {
+	Lat    float64 `validate:"latitude"`
+	Lon    float64 `validate:"longitude"`
+	Radius float64 `validate:"min=0,max=50000"`
+	MinLat float64 `validate:"latitude"`
+	MinLon float64 `validate:"longitude"`
+	MaxLat float64 `validate:"latitude,gtefield=MinLat"`
+	MaxLon float64 `validate:"longitude,gtefield=MinLon"`
+	Cursor string  `validate:"max=512"`
+	Limit  int     `validate:"min=1,max=100"`
+}
+
+// NearbyAddressResponse is an address in the searched area. Distance is
+// in meters from the center of the area.
+type NearbyAddressResponse struct {
+	Id           int
+	CustomerId   int
+	Lat          float64
+	Lon          float64
+	AddressLine1 string
+	AddressLine2 string
+	City         string
+	Country      string
+	Distance     float64
+}
+
+type NearbyAddressesResponse struct {
+	Addresses  []NearbyAddressResponse
+	NextCursor string
+}
+
+func NearbyAddressesRequestToAddressProximitySearch(nearbyAddressesRequest NearbyAddressesRequest) (models.AddressProximitySearch, error) {
+	search := models.AddressProximitySearch{Limit: nearbyAddressesRequest.Limit}
+
+	if nearbyAddressesRequest.Radius > 0 {
+		search.Area = models.CircleArea(nearbyAddressesRequest.Lat, nearbyAddressesRequest.Lon, nearbyAddressesRequest.Radius)
+	} else {
+		box := models.BoundingBox{
+			MinLat: nearbyAddressesRequest.MinLat,
+			MinLon: nearbyAddressesRequest.MinLon,
+			MaxLat: nearbyAddressesRequest.MaxLat,
+			MaxLon: nearbyAddressesRequest.MaxLon,
+		}
+		if box.MaxLat-box.MinLat > MAX_NEARBY_BOX_SPAN || box.MaxLon-box.MinLon > MAX_NEARBY_BOX_SPAN {
+			return models.AddressProximitySearch{}, ErrInvalidArea
+		}
+		search.Area = models.BoxArea(box)
+	}
+
+	if nearbyAddressesRequest.Cursor != "" {
+		cursor, err := models.DecodeProximityCursor(nearbyAddressesRequest.Cursor)
+		if err != nil {
+			return models.AddressProximitySearch{}, err
+		}
+		search.After = &cursor
+	}
+
+	return search, nil
+}
+
+func AddressMatchPageToNearbyAddressesResponse(page models.AddressMatchPage) NearbyAddressesResponse {
+	nearbyAddressesResponse := NearbyAddressesResponse{Addresses: []NearbyAddressResponse{}}
+
+	for _, match := range page.Matches {
+		nearbyAddressesResponse.Addresses = append(nearbyAddressesResponse.Addresses, NearbyAddressResponse{
+			Id:           match.Address.Id,
+			CustomerId:   match.Address.CustomerId,
+			Lat:          match.Address.Lat,
+			Lon:          match.Address.Lon,
+			AddressLine1: match.Address.AddressLine1,
+			AddressLine2: match.Address.AddressLine2,
+			City:         match.Address.City,
+			Country:      match.Address.Country,
+			Distance:     match.Distance,
+		})
+	}
+
+	if page.NextCursor != nil {
+		nearbyAddressesResponse.NextCursor = page.NextCursor.Encode()
+	}
+
+	return nearbyAddressesResponse
+}
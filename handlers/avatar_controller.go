@@ -0,0 +1,116 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+
+	"github.com/VitoNaychev/bt-customer-svc/avatar"
+	"github.com/VitoNaychev/bt-customer-svc/models"
+)
+
+func (a *AvatarServer) uploadAvatar(w http.ResponseWriter, r *http.Request) {
+	customerId, _ := strconv.Atoi(r.Header["Subject"][0])
+
+	customer, err := getActiveCustomer(a.customerStore, customerId)
+	if err != nil {
+		handleStoreError(w, err)
+		return
+	}
+
+	data, err := readAvatarFile(w, r)
+	if err != nil {
+		handleAvatarError(w, err)
+		return
+	}
+
+	thumbnails, err := avatar.Process(data)
+	if err != nil {
+		handleAvatarError(w, err)
+		return
+	}
+
+	avatarURL, err := avatar.Save(a.blobStore, customerId, thumbnails)
+	if err != nil {
+		writeJSONError(w, http.StatusInternalServerError, ErrBlobStoreError)
+		return
+	}
+
+	// thumbnails are overwritten in place, so the version in the query
+	// string keeps clients and caches from serving the previous picture
+	avatarURL = fmt.Sprintf("%s?v=%d", avatarURL, customer.Version+1)
+
+	customer, err = a.customerStore.PatchCustomer(customerId, customer.Version, models.CustomerPatch{AvatarURL: &avatarURL})
+	if err != nil {
+		handleVersionedStoreError(w, err)
+		return
+	}
+
+	json.NewEncoder(w).Encode(CustomerToAvatarResponse(customer))
+}
+
+func (a *AvatarServer) deleteAvatar(w http.ResponseWriter, r *http.Request) {
+	customerId, _ := strconv.Atoi(r.Header["Subject"][0])
+
+	customer, err := getActiveCustomer(a.customerStore, customerId)
+	if err != nil {
+		handleStoreError(w, err)
+		return
+	}
+
+	err = avatar.Delete(a.blobStore, customerId)
+	if err != nil {
+		writeJSONError(w, http.StatusInternalServerError, ErrBlobStoreError)
+		return
+	}
+
+	if customer.AvatarURL == "" {
+		return
+	}
+
+	noAvatar := ""
+	_, err = a.customerStore.PatchCustomer(customerId, customer.Version, models.CustomerPatch{AvatarURL: &noAvatar})
+	if err != nil {
+		handleVersionedStoreError(w, err)
+		return
+	}
+}
+
+func readAvatarFile(w http.ResponseWriter, r *http.Request) ([]byte, error) {
+	r.Body = http.MaxBytesReader(w, r.Body, MaxAvatarSize+1<<10)
+
+	file, _, err := r.FormFile(AVATAR_FORM_FIELD)
+	if err != nil {
+		var maxBytesError *http.MaxBytesError
+		if errors.As(err, &maxBytesError) {
+			return nil, ErrAvatarTooLarge
+		}
+		return nil, ErrMissingAvatar
+	}
+	defer file.Close()
+
+	data, err := io.ReadAll(io.LimitReader(file, MaxAvatarSize+1))
+	if err != nil {
+		return nil, ErrMissingAvatar
+	}
+
+	if len(data) > MaxAvatarSize {
+		return nil, ErrAvatarTooLarge
+	}
+
+	return data, nil
+}
+
+func handleAvatarError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, ErrAvatarTooLarge), errors.Is(err, avatar.ErrImageTooLarge):
+		writeJSONError(w, http.StatusRequestEntityTooLarge, err)
+	case errors.Is(err, avatar.ErrUnsupportedFormat):
+		writeJSONError(w, http.StatusUnsupportedMediaType, err)
+	default:
+		writeJSONError(w, http.StatusBadRequest, err)
+	}
+}
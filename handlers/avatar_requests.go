@@ -0,0 +1,28 @@
+package handlers
+
+import (
+	"bytes"
+	"mime/multipart"
+	"net/http"
+)
+
+func NewUploadAvatarRequest(customerJWT string, image []byte) *http.Request {
+	body := bytes.NewBuffer([]byte{})
+	writer := multipart.NewWriter(body)
+	part, _ := writer.CreateFormFile(AVATAR_FORM_FIELD, "avatar")
+	part.Write(image)
+	writer.Close()
+
+	request, _ := http.NewRequest(http.MethodPost, "/customer/avatar/", body)
+	request.Header.Add("Token", customerJWT)
+	request.Header.Add("Content-Type", writer.FormDataContentType())
+
+	return request
+}
+
+func NewDeleteAvatarRequest(customerJWT string) *http.Request {
+	request, _ := http.NewRequest(http.MethodDelete, "/customer/avatar/", nil)
+	request.Header.Add("Token", customerJWT)
+
+	return request
+}
@@ -0,0 +1,36 @@
+package handlers
+
+import (
+	"net/http"
+
+	"github.com/VitoNaychev/auth"
+	"github.com/VitoNaychev/bt-customer-svc/blobstore"
+	"github.com/VitoNaychev/bt-customer-svc/models"
+)
+
+const MaxAvatarSize = 5 << 20
+
+type AvatarServer struct {
+	customerStore models.CustomerStore
+	blobStore     blobstore.BlobStore
+	secretKey     []byte
+}
+
+func NewAvatarServer(customerStore models.CustomerStore, blobStore blobstore.BlobStore, secretKey []byte) *AvatarServer {
+	avatarServer := AvatarServer{
+		customerStore: customerStore,
+		blobStore:     blobStore,
+		secretKey:     secretKey,
+	}
+
+	return &avatarServer
+}
+
+func (a *AvatarServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodPost:
+		auth.AuthenticationMiddleware(a.uploadAvatar, a.secretKey)(w, r)
+	case http.MethodDelete:
+		auth.AuthenticationMiddleware(a.deleteAvatar, a.secretKey)(w, r)
+	}
+}
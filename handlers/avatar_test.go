@@ -0,0 +1,157 @@
+package handlers_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"image"
+	"image/color"
+	"image/png"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/VitoNaychev/auth"
+	"github.com/VitoNaychev/bt-customer-svc/avatar"
+	"github.com/VitoNaychev/bt-customer-svc/handlers"
+	"github.com/VitoNaychev/bt-customer-svc/models"
+	td "github.com/VitoNaychev/bt-customer-svc/testdata"
+	"github.com/VitoNaychev/bt-customer-svc/testutil"
+)
+
+func newTestPNG(width, height int) []byte {
+	img := image.NewRGBA(image.Rect(0, 0, width, height))
+	for y := 0; y < height; y++ {
+		for x := 0; x < width; x++ {
+			img.Set(x, y, color.RGBA{R: uint8(x), G: uint8(y), B: 0x80, A: 0xff})
+		}
+	}
+
+	buf := bytes.NewBuffer([]byte{})
+	png.Encode(buf, img)
+	return buf.Bytes()
+}
+
+func TestAvatarEndpointAuthentication(t *testing.T) {
+	customerData := []models.Customer{td.PeterCustomer, td.AliceCustomer}
+	server := handlers.NewAvatarServer(testutil.NewStubCustomerStore(customerData), testutil.NewStubBlobStore(), testEnv.SecretKey)
+
+	invalidJWT := "thisIsAnInvalidJWT"
+	cases := map[string]*http.Request{
+		"upload avatar authentication": handlers.NewUploadAvatarRequest(invalidJWT, newTestPNG(8, 8)),
+		"delete avatar authentication": handlers.NewDeleteAvatarRequest(invalidJWT),
+	}
+
+	for name, request := range cases {
+		t.Run(name, func(t *testing.T) {
+			response := httptest.NewRecorder()
+
+			server.ServeHTTP(response, request)
+
+			testutil.AssertStatus(t, response.Code, http.StatusUnauthorized)
+		})
+	}
+}
+
+func TestUploadAvatar(t *testing.T) {
+	customerData := []models.Customer{td.PeterCustomer, td.AliceCustomer}
+	customerStore := testutil.NewStubCustomerStore(customerData)
+	blobStore := testutil.NewStubBlobStore()
+	server := handlers.NewAvatarServer(customerStore, blobStore, testEnv.SecretKey)
+
+	t.Run("stores thumbnails and sets avatar URL", func(t *testing.T) {
+		peterJWT, _ := auth.GenerateJWT(testEnv.SecretKey, testEnv.ExpiresAt, td.PeterCustomer.Id)
+		request := handlers.NewUploadAvatarRequest(peterJWT, newTestPNG(300, 200))
+		response := httptest.NewRecorder()
+
+		server.ServeHTTP(response, request)
+
+		testutil.AssertStatus(t, response.Code, http.StatusOK)
+
+		for _, size := range avatar.Sizes {
+			key := avatar.Key(td.PeterCustomer.Id, size)
+			if _, ok := blobStore.Blobs[key]; !ok {
+				t.Errorf("missing thumbnail %q", key)
+			}
+			testutil.AssertEqual(t, blobStore.ContentTypes[key], "image/jpeg")
+		}
+
+		wantURL := fmt.Sprintf("/blobs/avatars/%d/256.jpg?v=%d", td.PeterCustomer.Id, td.PeterCustomer.Version+1)
+		testutil.AssertPatchedCustomer(t, customerStore, models.CustomerPatch{AvatarURL: &wantURL})
+
+		var got handlers.AvatarResponse
+		json.NewDecoder(response.Body).Decode(&got)
+
+		testutil.AssertEqual(t, got, handlers.AvatarResponse{AvatarURL: wantURL})
+	})
+
+	t.Run("returns Unsupported Media Type on non-image file", func(t *testing.T) {
+		peterJWT, _ := auth.GenerateJWT(testEnv.SecretKey, testEnv.ExpiresAt, td.PeterCustomer.Id)
+		request := handlers.NewUploadAvatarRequest(peterJWT, []byte("definitely not an image"))
+		response := httptest.NewRecorder()
+
+		server.ServeHTTP(response, request)
+
+		testutil.AssertStatus(t, response.Code, http.StatusUnsupportedMediaType)
+		testutil.AssertErrorResponse(t, response.Body, avatar.ErrUnsupportedFormat)
+	})
+
+	t.Run("returns Request Entity Too Large on oversized file", func(t *testing.T) {
+		peterJWT, _ := auth.GenerateJWT(testEnv.SecretKey, testEnv.ExpiresAt, td.PeterCustomer.Id)
+		data := append(newTestPNG(8, 8), make([]byte, handlers.MaxAvatarSize)...)
+		request := handlers.NewUploadAvatarRequest(peterJWT, data)
+		response := httptest.NewRecorder()
+
+		server.ServeHTTP(response, request)
+
+		testutil.AssertStatus(t, response.Code, http.StatusRequestEntityTooLarge)
+	})
+
+	t.Run("returns Bad Request on missing file", func(t *testing.T) {
+		peterJWT, _ := auth.GenerateJWT(testEnv.SecretKey, testEnv.ExpiresAt, td.PeterCustomer.Id)
+		request, _ := http.NewRequest(http.MethodPost, "/customer/avatar/", nil)
+		request.Header.Add("Token", peterJWT)
+		response := httptest.NewRecorder()
+
+		server.ServeHTTP(response, request)
+
+		testutil.AssertStatus(t, response.Code, http.StatusBadRequest)
+		testutil.AssertErrorResponse(t, response.Body, handlers.ErrMissingAvatar)
+	})
+
+	t.Run("returns Not Found on missing customer", func(t *testing.T) {
+		missingJWT, _ := auth.GenerateJWT(testEnv.SecretKey, testEnv.ExpiresAt, 10)
+		request := handlers.NewUploadAvatarRequest(missingJWT, newTestPNG(8, 8))
+		response := httptest.NewRecorder()
+
+		server.ServeHTTP(response, request)
+
+		testutil.AssertStatus(t, response.Code, http.StatusNotFound)
+		testutil.AssertErrorResponse(t, response.Body, handlers.ErrCustomerNotFound)
+	})
+}
+
+func TestDeleteAvatar(t *testing.T) {
+	alice := td.AliceCustomer
+	alice.AvatarURL = fmt.Sprintf("/blobs/avatars/%d/256.jpg?v=1", alice.Id)
+
+	customerData := []models.Customer{td.PeterCustomer, alice}
+	customerStore := testutil.NewStubCustomerStore(customerData)
+	blobStore := testutil.NewStubBlobStore()
+	for _, size := range avatar.Sizes {
+		blobStore.Blobs[avatar.Key(alice.Id, size)] = []byte{}
+	}
+	server := handlers.NewAvatarServer(customerStore, blobStore, testEnv.SecretKey)
+
+	aliceJWT, _ := auth.GenerateJWT(testEnv.SecretKey, testEnv.ExpiresAt, alice.Id)
+	request := handlers.NewDeleteAvatarRequest(aliceJWT)
+	response := httptest.NewRecorder()
+
+	server.ServeHTTP(response, request)
+
+	testutil.AssertStatus(t, response.Code, http.StatusOK)
+	testutil.AssertEqual(t, len(blobStore.Blobs), 0)
+
+	noAvatar := ""
+	testutil.AssertPatchedCustomer(t, customerStore, models.CustomerPatch{AvatarURL: &noAvatar})
+}
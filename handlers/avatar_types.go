@@ -0,0 +1,13 @@
+package handlers
+
+import "github.com/VitoNaychev/bt-customer-svc/models"
+
+const AVATAR_FORM_FIELD = "avatar"
+
+type AvatarResponse struct {
+	AvatarURL string
+}
+
+func CustomerToAvatarResponse(customer models.Customer) AvatarResponse {
+	return AvatarResponse{AvatarURL: customer.AvatarURL}
+}
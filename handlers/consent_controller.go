@@ -0,0 +1,125 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+
+	"github.com/VitoNaychev/bt-customer-svc/models"
+	"github.com/VitoNaychev/validation"
+)
+
+func (c *ConsentServer) getConsents(w http.ResponseWriter, r *http.Request) {
+	customerId, _ := strconv.Atoi(r.Header["Subject"][0])
+
+	_, err := getActiveCustomer(c.customerStore, customerId)
+	if err != nil {
+		handleStoreError(w, err)
+		return
+	}
+
+	latest, err := c.consentStore.GetLatestDocuments()
+	if err != nil {
+		writeJSONError(w, http.StatusInternalServerError, ErrDatabaseError)
+		return
+	}
+
+	history, err := c.consentStore.GetConsentHistory(customerId)
+	if err != nil {
+		writeJSONError(w, http.StatusInternalServerError, ErrDatabaseError)
+		return
+	}
+
+	consentsResponse := ConsentsResponse{
+		Documents: ConsentDocumentsToConsentDocumentResponses(latest),
+		Pending:   ConsentDocumentsToConsentDocumentResponses(models.PendingDocuments(latest, history)),
+		History:   []ConsentResponse{},
+	}
+	for _, consent := range history {
+		consentsResponse.History = append(consentsResponse.History, ConsentToConsentResponse(consent))
+	}
+
+	json.NewEncoder(w).Encode(consentsResponse)
+}
+
+func (c *ConsentServer) grantConsent(w http.ResponseWriter, r *http.Request) {
+	c.recordConsent(w, r, true)
+}
+
+func (c *ConsentServer) withdrawConsent(w http.ResponseWriter, r *http.Request) {
+	c.recordConsent(w, r, false)
+}
+
+func (c *ConsentServer) recordConsent(w http.ResponseWriter, r *http.Request, granted bool) {
+	consentRequest, err := validation.ValidateBody[ConsentRequest](r.Body)
+	if err != nil {
+		writeJSONError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	customerId, _ := strconv.Atoi(r.Header["Subject"][0])
+
+	_, err = getActiveCustomer(c.customerStore, customerId)
+	if err != nil {
+		handleStoreError(w, err)
+		return
+	}
+
+	latest, err := c.consentStore.GetLatestDocuments()
+	if err != nil {
+		writeJSONError(w, http.StatusInternalServerError, ErrDatabaseError)
+		return
+	}
+
+	if !containsDocument(latest, consentRequest.DocumentId) {
+		writeJSONError(w, http.StatusBadRequest, ErrUnknownDocument)
+		return
+	}
+
+	consent := models.Consent{
+		CustomerId: customerId,
+		DocumentId: consentRequest.DocumentId,
+		Granted:    granted,
+	}
+
+	err = c.consentStore.RecordConsent(&consent)
+	if err != nil {
+		writeJSONError(w, http.StatusInternalServerError, ErrDatabaseError)
+		return
+	}
+
+	json.NewEncoder(w).Encode(ConsentToConsentResponse(consent))
+}
+
+func (c *ConsentServer) getPendingDocuments(customerId int) ([]models.ConsentDocument, error) {
+	latest, err := c.consentStore.GetLatestDocuments()
+	if err != nil {
+		return nil, err
+	}
+
+	history, err := c.consentStore.GetConsentHistory(customerId)
+	if err != nil {
+		return nil, err
+	}
+
+	return models.PendingDocuments(latest, history), nil
+}
+
+func containsDocument(documents []models.ConsentDocument, id int) bool {
+	for _, document := range documents {
+		if document.Id == id {
+			return true
+		}
+	}
+	return false
+}
+
+func writeConsentRequiredError(w http.ResponseWriter, statusCode int, pending []models.ConsentDocument) {
+	w.WriteHeader(statusCode)
+
+	consentRequiredResponse := ConsentRequiredResponse{
+		Message: ErrConsentRequired.Error(),
+		Pending: ConsentDocumentsToConsentDocumentResponses(pending),
+	}
+	json.NewEncoder(w).Encode(consentRequiredResponse)
+}
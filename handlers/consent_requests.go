@@ -0,0 +1,32 @@
+package handlers
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+)
+
+func NewGetConsentsRequest(customerJWT string) *http.Request {
+	request, _ := http.NewRequest(http.MethodGet, "/customer/consents/", nil)
+	request.Header.Add("Token", customerJWT)
+
+	return request
+}
+
+func NewGrantConsentRequest(customerJWT string, documentId int) *http.Request {
+	return newConsentRequest(http.MethodPost, customerJWT, documentId)
+}
+
+func NewWithdrawConsentRequest(customerJWT string, documentId int) *http.Request {
+	return newConsentRequest(http.MethodDelete, customerJWT, documentId)
+}
+
+func newConsentRequest(method string, customerJWT string, documentId int) *http.Request {
+	body := bytes.NewBuffer([]byte{})
+	json.NewEncoder(body).Encode(ConsentRequest{DocumentId: documentId})
+
+	request, _ := http.NewRequest(method, "/customer/consents/", body)
+	request.Header.Add("Token", customerJWT)
+
+	return request
+}
@@ -0,0 +1,84 @@
+package handlers
+
+import (
+	"net/http"
+
+	"github.com/VitoNaychev/auth"
+	"github.com/VitoNaychev/bt-customer-svc/models"
+)
+
+type ConsentServer struct {
+	consentStore  models.ConsentStore
+	customerStore models.CustomerStore
+	secretKey     []byte
+}
+
+func NewConsentServer(consentStore models.ConsentStore, customerStore models.CustomerStore, secretKey []byte) *ConsentServer {
+	consentServer := ConsentServer{
+		consentStore:  consentStore,
+		customerStore: customerStore,
+		secretKey:     secretKey,
+	}
+
+	return &consentServer
+}
+
+func (c *ConsentServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+		auth.AuthenticationMiddleware(c.getConsents, c.secretKey)(w, r)
+	case http.MethodPost:
+		auth.AuthenticationMiddleware(c.grantConsent, c.secretKey)(w, r)
+	case http.MethodDelete:
+		auth.AuthenticationMiddleware(c.withdrawConsent, c.secretKey)(w, r)
+	}
+}
+
+// RequireConsents wraps a customer facing server and rejects authenticated
+// requests of customers that haven't accepted the latest mandatory
+// documents. Requests without a valid token are passed through so the
+// wrapped server can answer them as usual.
+func (c *ConsentServer) RequireConsents(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if isConsentExempt(r) || r.Header.Get("Token") == "" {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		token, err := auth.VerifyJWT(r.Header.Get("Token"), c.secretKey)
+		if err != nil {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		customerId, err := getCustomerIDFromToken(token)
+		if err != nil {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		pending, err := c.getPendingDocuments(customerId)
+		if err != nil {
+			writeJSONError(w, http.StatusInternalServerError, ErrDatabaseError)
+			return
+		}
+
+		if len(pending) > 0 {
+			writeConsentRequiredError(w, http.StatusForbidden, pending)
+			return
+		}
+
+		next.ServeHTTP(w, r)
+	})
+}
+
+// isConsentExempt reports whether a request stays available to customers
+// with pending documents: token verification for other services and
+// account deletion.
+func isConsentExempt(r *http.Request) bool {
+	if r.URL.Path == "/customer/auth/" {
+		return true
+	}
+
+	return r.URL.Path == "/customer/" && r.Method == http.MethodDelete
+}
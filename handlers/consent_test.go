@@ -0,0 +1,183 @@
+package handlers_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/VitoNaychev/auth"
+	"github.com/VitoNaychev/bt-customer-svc/handlers"
+	"github.com/VitoNaychev/bt-customer-svc/models"
+	td "github.com/VitoNaychev/bt-customer-svc/testdata"
+	"github.com/VitoNaychev/bt-customer-svc/testutil"
+)
+
+func TestConsentsOnSignup(t *testing.T) {
+	documentData := []models.ConsentDocument{td.TermsV1, td.MarketingV1}
+	customerStore := testutil.NewStubCustomerStore([]models.Customer{})
+
+	t.Run("returns Bad Request when mandatory documents aren't accepted", func(t *testing.T) {
+		consentStore := testutil.NewStubConsentStore(documentData, nil)
+		server := handlers.NewCustomerServer(testEnv.SecretKey, testEnv.ExpiresAt, customerStore, consentStore, testutil.NewStubReferralStore(nil, nil))
+
+		request := handlers.NewCreateCustomerRequestWithConsents(td.PeterCustomer, []int{td.MarketingV1.Id})
+		response := httptest.NewRecorder()
+
+		server.ServeHTTP(response, request)
+
+		testutil.AssertStatus(t, response.Code, http.StatusBadRequest)
+
+		var got handlers.ConsentRequiredResponse
+		json.NewDecoder(response.Body).Decode(&got)
+
+		testutil.AssertEqual(t, got.Message, handlers.ErrConsentRequired.Error())
+		testutil.AssertEqual(t, len(got.Pending), 1)
+		testutil.AssertEqual(t, got.Pending[0].Id, td.TermsV1.Id)
+	})
+
+	t.Run("returns Bad Request on unknown document", func(t *testing.T) {
+		consentStore := testutil.NewStubConsentStore(documentData, nil)
+		server := handlers.NewCustomerServer(testEnv.SecretKey, testEnv.ExpiresAt, customerStore, consentStore, testutil.NewStubReferralStore(nil, nil))
+
+		request := handlers.NewCreateCustomerRequestWithConsents(td.PeterCustomer, []int{td.TermsV1.Id, 10})
+		response := httptest.NewRecorder()
+
+		server.ServeHTTP(response, request)
+
+		testutil.AssertStatus(t, response.Code, http.StatusBadRequest)
+		testutil.AssertErrorResponse(t, response.Body, handlers.ErrUnknownDocument)
+	})
+
+	t.Run("records accepted documents", func(t *testing.T) {
+		consentStore := testutil.NewStubConsentStore(documentData, nil)
+		server := handlers.NewCustomerServer(testEnv.SecretKey, testEnv.ExpiresAt, customerStore, consentStore, testutil.NewStubReferralStore(nil, nil))
+
+		request := handlers.NewCreateCustomerRequestWithConsents(td.PeterCustomer, []int{td.TermsV1.Id, td.MarketingV1.Id})
+		response := httptest.NewRecorder()
+
+		server.ServeHTTP(response, request)
+
+		testutil.AssertStatus(t, response.Code, http.StatusAccepted)
+
+		customer := testutil.ParseCreateCustomerResponse(t, response.Body).Customer
+		testutil.AssertSignupConsents(t, customerStore, []models.Consent{
+			{CustomerId: customer.Id, DocumentId: td.TermsV1.Id, Granted: true},
+			{CustomerId: customer.Id, DocumentId: td.MarketingV1.Id, Granted: true},
+		})
+	})
+}
+
+func TestConsentServer(t *testing.T) {
+	documentData := []models.ConsentDocument{td.TermsV1, td.MarketingV1}
+	consentData := []models.Consent{
+		{Id: 1, CustomerId: td.PeterCustomer.Id, DocumentId: td.TermsV1.Id, Granted: true},
+	}
+	customerData := []models.Customer{td.PeterCustomer, td.AliceCustomer}
+	consentStore := testutil.NewStubConsentStore(documentData, consentData)
+	customerStore := testutil.NewStubCustomerStore(customerData)
+	server := handlers.NewConsentServer(consentStore, customerStore, testEnv.SecretKey)
+
+	peterJWT, _ := auth.GenerateJWT(testEnv.SecretKey, testEnv.ExpiresAt, td.PeterCustomer.Id)
+
+	t.Run("returns Peter's consents", func(t *testing.T) {
+		request := handlers.NewGetConsentsRequest(peterJWT)
+		response := httptest.NewRecorder()
+
+		server.ServeHTTP(response, request)
+
+		testutil.AssertStatus(t, response.Code, http.StatusOK)
+
+		var got handlers.ConsentsResponse
+		json.NewDecoder(response.Body).Decode(&got)
+
+		testutil.AssertEqual(t, len(got.Documents), 2)
+		testutil.AssertEqual(t, got.Pending, []handlers.ConsentDocumentResponse{})
+		testutil.AssertEqual(t, len(got.History), 1)
+	})
+
+	t.Run("returns Bad Request on outdated document", func(t *testing.T) {
+		request := handlers.NewGrantConsentRequest(peterJWT, 10)
+		response := httptest.NewRecorder()
+
+		server.ServeHTTP(response, request)
+
+		testutil.AssertStatus(t, response.Code, http.StatusBadRequest)
+		testutil.AssertErrorResponse(t, response.Body, handlers.ErrUnknownDocument)
+	})
+
+	t.Run("appends withdrawal and grant to the history", func(t *testing.T) {
+		request := handlers.NewWithdrawConsentRequest(peterJWT, td.TermsV1.Id)
+		response := httptest.NewRecorder()
+
+		server.ServeHTTP(response, request)
+
+		testutil.AssertStatus(t, response.Code, http.StatusOK)
+
+		request = handlers.NewGrantConsentRequest(peterJWT, td.MarketingV1.Id)
+		response = httptest.NewRecorder()
+
+		server.ServeHTTP(response, request)
+
+		testutil.AssertStatus(t, response.Code, http.StatusOK)
+		testutil.AssertRecordedConsents(t, consentStore, []models.Consent{
+			{CustomerId: td.PeterCustomer.Id, DocumentId: td.TermsV1.Id, Granted: false},
+			{CustomerId: td.PeterCustomer.Id, DocumentId: td.MarketingV1.Id, Granted: true},
+		})
+
+		history, _ := consentStore.GetConsentHistory(td.PeterCustomer.Id)
+		testutil.AssertEqual(t, len(history), 3)
+	})
+}
+
+func TestRequireConsents(t *testing.T) {
+	documentData := []models.ConsentDocument{td.TermsV1, td.MarketingV1}
+	consentData := []models.Consent{
+		{Id: 1, CustomerId: td.PeterCustomer.Id, DocumentId: td.TermsV1.Id, Granted: true},
+	}
+	customerData := []models.Customer{td.PeterCustomer, td.AliceCustomer}
+	consentStore := testutil.NewStubConsentStore(documentData, consentData)
+	customerStore := testutil.NewStubCustomerStore(customerData)
+	consentServer := handlers.NewConsentServer(consentStore, customerStore, testEnv.SecretKey)
+	customerServer := handlers.NewCustomerServer(testEnv.SecretKey, testEnv.ExpiresAt, customerStore, consentStore, testutil.NewStubReferralStore(nil, nil))
+
+	server := consentServer.RequireConsents(customerServer)
+
+	peterJWT, _ := auth.GenerateJWT(testEnv.SecretKey, testEnv.ExpiresAt, td.PeterCustomer.Id)
+
+	t.Run("passes requests of customers with current consents", func(t *testing.T) {
+		request := handlers.NewGetCustomerRequest(peterJWT)
+		response := httptest.NewRecorder()
+
+		server.ServeHTTP(response, request)
+
+		testutil.AssertStatus(t, response.Code, http.StatusOK)
+	})
+
+	t.Run("returns Forbidden after a new mandatory version is published", func(t *testing.T) {
+		newTerms := td.TermsV2
+		consentStore.PublishDocument(&newTerms)
+
+		request := handlers.NewGetCustomerRequest(peterJWT)
+		response := httptest.NewRecorder()
+
+		server.ServeHTTP(response, request)
+
+		testutil.AssertStatus(t, response.Code, http.StatusForbidden)
+
+		var got handlers.ConsentRequiredResponse
+		json.NewDecoder(response.Body).Decode(&got)
+
+		testutil.AssertEqual(t, len(got.Pending), 1)
+		testutil.AssertEqual(t, got.Pending[0].Version, td.TermsV2.Version)
+	})
+
+	t.Run("keeps token verification available", func(t *testing.T) {
+		request := handlers.NewAuthRequest(peterJWT)
+		response := httptest.NewRecorder()
+
+		server.ServeHTTP(response, request)
+
+		testutil.AssertStatus(t, response.Code, http.StatusOK)
+	})
+}
@@ -0,0 +1,83 @@
+package handlers
+
+import (
+	"time"
+
+	"github.com/VitoNaychev/bt-customer-svc/models"
+)
+
+type ConsentDocumentResponse struct {
+	Id          int
+	Kind        string
+	Version     string
+	Mandatory   bool
+	PublishedAt time.Time
+}
+
+func ConsentDocumentToConsentDocumentResponse(document models.ConsentDocument) ConsentDocumentResponse {
+	consentDocumentResponse := ConsentDocumentResponse{
+		Id:          document.Id,
+		Kind:        document.Kind,
+		Version:     document.Version,
+		Mandatory:   document.Mandatory,
+		PublishedAt: document.PublishedAt,
+	}
+
+	return consentDocumentResponse
+}
+
+func ConsentDocumentsToConsentDocumentResponses(documents []models.ConsentDocument) []ConsentDocumentResponse {
+	consentDocumentResponses := []ConsentDocumentResponse{}
+	for _, document := range documents {
+		consentDocumentResponses = append(consentDocumentResponses, ConsentDocumentToConsentDocumentResponse(document))
+	}
+
+	return consentDocumentResponses
+}
+
+type ConsentResponse struct {
+	DocumentId int
+	Granted    bool
+	RecordedAt time.Time
+}
+
+func ConsentToConsentResponse(consent models.Consent) ConsentResponse {
+	consentResponse := ConsentResponse{
+		DocumentId: consent.DocumentId,
+		Granted:    consent.Granted,
+		RecordedAt: consent.RecordedAt,
+	}
+
+	return consentResponse
+}
+
+type ConsentsResponse struct {
+	Documents []ConsentDocumentResponse
+	Pending   []ConsentDocumentResponse
+	History   []ConsentResponse
+}
+
+type ConsentRequiredResponse struct {
+	Message string
+	Pending []ConsentDocumentResponse
+}
+
+type ConsentRequest struct {
+	DocumentId int `validate:"min=1"`
+}
+
+type PublishDocumentRequest struct {
+	Kind      string `validate:"required,oneof=terms privacy marketing"`
+	Version   string `validate:"required,max=20"`
+	Mandatory bool
+}
+
+func PublishDocumentRequestToConsentDocument(publishDocumentRequest PublishDocumentRequest) models.ConsentDocument {
+	document := models.ConsentDocument{
+		Kind:      publishDocumentRequest.Kind,
+		Version:   publishDocumentRequest.Version,
+		Mandatory: publishDocumentRequest.Mandatory,
+	}
+
+	return document
+}
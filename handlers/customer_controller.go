@@ -32,7 +32,7 @@ func (c *CustomerServer) AuthHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	_, err = c.store.GetCustomerByID(customerID)
+	_, err = getActiveCustomer(c.store, customerID)
 	if err != nil {
 		if errors.Is(err, models.ErrNotFound) {
 			handleAuthError(w, authResponse, NOT_FOUND)
@@ -94,9 +94,14 @@ func (c *CustomerServer) LoginHandler(w http.ResponseWriter, r *http.Request) {
 
 func (c *CustomerServer) updateCustomer(w http.ResponseWriter, r *http.Request) {
 	id, _ := strconv.Atoi(r.Header["Subject"][0])
-	customer, err := c.store.GetCustomerByID(id)
+	customer, err := getActiveCustomer(c.store, id)
 	if err != nil {
 		handleStoreError(w, err)
+		return
+	}
+
+	if !checkIfMatch(w, r, versionETag(customer.Version), c.preconditionMode) {
+		return
 	}
 
 	updateCustomerRequest, err := validation.ValidateBody[UpdateCustomerRequest](r.Body)
@@ -105,22 +110,67 @@ func (c *CustomerServer) updateCustomer(w http.ResponseWriter, r *http.Request)
 		return
 	}
 
+	version, status := customer.Version, customer.Status
 	customer = UpdateCustomerRequestToCustomer(updateCustomerRequest, id)
+	customer.Version = version
+	customer.Status = status
 
 	err = c.store.UpdateCustomer(&customer)
+	if err != nil {
+		handleVersionedStoreError(w, err)
+		return
+	}
+
+	w.Header().Set("ETag", versionETag(customer.Version))
+	json.NewEncoder(w).Encode(CustomerToCustomerResponse(customer))
+}
+
+func (c *CustomerServer) patchCustomer(w http.ResponseWriter, r *http.Request) {
+	id, _ := strconv.Atoi(r.Header["Subject"][0])
+
+	patchCustomerRequest, err := parseMergePatch[PatchCustomerRequest](r.Body)
 	if err != nil {
 		writeJSONError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	customer, err := getActiveCustomer(c.store, id)
+	if err != nil {
+		handleStoreError(w, err)
+		return
+	}
+
+	if !checkIfMatch(w, r, versionETag(customer.Version), c.preconditionMode) {
+		return
+	}
+
+	customerPatch := PatchCustomerRequestToCustomerPatch(patchCustomerRequest)
+
+	customer, err = c.store.PatchCustomer(id, customer.Version, customerPatch)
+	if err != nil {
+		handleVersionedStoreError(w, err)
+		return
 	}
 
+	w.Header().Set("ETag", versionETag(customer.Version))
 	json.NewEncoder(w).Encode(CustomerToCustomerResponse(customer))
 }
 
 func (c *CustomerServer) deleteCustomer(w http.ResponseWriter, r *http.Request) {
 	id, _ := strconv.Atoi(r.Header["Subject"][0])
-	err := c.store.DeleteCustomer(id)
-
+	customer, err := getActiveCustomer(c.store, id)
 	if err != nil {
 		handleStoreError(w, err)
+		return
+	}
+
+	if !checkIfMatch(w, r, versionETag(customer.Version), c.preconditionMode) {
+		return
+	}
+
+	err = c.store.DeleteCustomer(id, customer.Version)
+	if err != nil {
+		handleVersionedStoreError(w, err)
 	}
 }
 
@@ -132,20 +182,55 @@ func (c *CustomerServer) createCustomer(w http.ResponseWriter, r *http.Request)
 		return
 	}
 
-	_, err = c.store.GetCustomerByEmail(createCustomerRequest.Email)
-	if err == nil {
-		writeJSONError(w, http.StatusBadRequest, ErrExistingCustomer)
-		return
-	} else if err != models.ErrNotFound {
+	latest, err := c.consentStore.GetLatestDocuments()
+	if err != nil {
 		writeJSONError(w, http.StatusInternalServerError, ErrDatabaseError)
 		return
 	}
 
+	acceptedConsents := []models.Consent{}
+	for _, documentId := range createCustomerRequest.AcceptedDocuments {
+		if !containsDocument(latest, documentId) {
+			writeJSONError(w, http.StatusBadRequest, ErrUnknownDocument)
+			return
+		}
+		acceptedConsents = append(acceptedConsents, models.Consent{DocumentId: documentId, Granted: true})
+	}
+
+	if pending := models.PendingDocuments(latest, acceptedConsents); len(pending) > 0 {
+		writeConsentRequiredError(w, http.StatusBadRequest, pending)
+		return
+	}
+
+	var referrer *models.Customer
+	if createCustomerRequest.ReferralCode != "" {
+		referrer, err = c.getReferrer(createCustomerRequest.ReferralCode)
+		if err != nil {
+			if errors.Is(err, models.ErrNotFound) {
+				writeFieldError(w, http.StatusBadRequest, ErrInvalidReferralCode, "ReferralCode")
+			} else {
+				writeJSONError(w, http.StatusInternalServerError, ErrDatabaseError)
+			}
+			return
+		}
+	}
+
+	// Referrals rejected as self-referral or identity reuse are recorded
+	// but don't fail the signup, the referrer just isn't credited.
+	var referralSignup *models.ReferralSignup
+	if referrer != nil {
+		referralSignup = &models.ReferralSignup{
+			Referrer: *referrer,
+			Code:     models.NormalizeReferralCode(createCustomerRequest.ReferralCode),
+			HashKey:  c.referralHashKey,
+		}
+	}
+
 	customer := CreateCustomerRequestToCustomer(createCustomerRequest)
 
-	err = c.store.CreateCustomer(&customer)
+	err = c.store.SignUpCustomer(&customer, acceptedConsents, referralSignup)
 	if err != nil {
-		writeJSONError(w, http.StatusInternalServerError, ErrDatabaseError)
+		handleStoreError(w, err)
 		return
 	}
 
@@ -160,20 +245,60 @@ func (c *CustomerServer) createCustomer(w http.ResponseWriter, r *http.Request)
 	json.NewEncoder(w).Encode(createCustomerResponse)
 }
 
+// getReferrer returns the customer a referral code belongs to, or
+// models.ErrNotFound if it is unknown or its owner is no longer active.
+func (c *CustomerServer) getReferrer(code string) (*models.Customer, error) {
+	referrerId, err := c.referralStore.GetReferrerByCode(models.NormalizeReferralCode(code))
+	if err != nil {
+		return nil, err
+	}
+
+	referrer, err := getActiveCustomer(c.store, referrerId)
+	if err != nil {
+		return nil, err
+	}
+
+	return &referrer, nil
+}
+
 func (c *CustomerServer) getCustomer(w http.ResponseWriter, r *http.Request) {
 	id, _ := strconv.Atoi(r.Header["Subject"][0])
-	customer, err := c.store.GetCustomerByID(id)
+	customer, err := getActiveCustomer(c.store, id)
 
 	if err != nil {
 		handleStoreError(w, err)
 		return
 	}
 
+	if checkIfNoneMatch(w, r, versionETag(customer.Version)) {
+		return
+	}
+
 	getCustomerResponse := CustomerToGetCustomerResponse(customer)
 	json.NewEncoder(w).Encode(getCustomerResponse)
 }
 
+// getActiveCustomer looks up the customer a JWT was issued to. Anonymized
+// and merged customers are reported as models.ErrNotFound, so the tokens
+// they were issued stop working once they are erased or merged.
+func getActiveCustomer(store models.CustomerStore, id int) (models.Customer, error) {
+	customer, err := store.GetCustomerByID(id)
+	if err != nil {
+		return models.Customer{}, err
+	}
+
+	if customer.Status != models.ACTIVE_STATUS {
+		return models.Customer{}, models.ErrNotFound
+	}
+
+	return customer, nil
+}
+
 func handleStoreError(w http.ResponseWriter, err error) {
+	if handleConstraintError(w, err) {
+		return
+	}
+
 	if errors.Is(err, models.ErrNotFound) {
 		// wrap models.ErrNotFound in customer handlers error type?
 		writeJSONError(w, http.StatusNotFound, ErrCustomerNotFound)
@@ -183,3 +308,68 @@ func handleStoreError(w http.ResponseWriter, err error) {
 		return
 	}
 }
+
+// conflictErrors holds the messages of unique violations on columns that
+// customers can pick themselves.
+var conflictErrors = map[string]error{
+	"canonical_email": ErrExistingCustomer,
+	"phone_number":    ErrExistingPhoneNumber,
+}
+
+// columnFields maps the columns of constraint errors to the request field
+// their value comes from. Columns derived by the store, like
+// canonical_email, map to the field they are derived from.
+var columnFields = map[string]string{
+	"first_name":      "FirstName",
+	"last_name":       "LastName",
+	"email":           "Email",
+	"canonical_email": "Email",
+	"phone_number":    "PhoneNumber",
+	"password":        "Password",
+	"country":         "Country",
+	"contact_phone":   "ContactPhone",
+	"is_default":      "IsDefault",
+	"tag":             "Tags",
+	"name":            "Name",
+}
+
+// handleConstraintError writes the response for a store error caused by a
+// violated schema constraint, naming the offending request field. Unique
+// violations are conflicts with existing data and result in 409; the rest,
+// like phone numbers the store can't canonicalize, mean the request carried
+// a value the schema doesn't accept. Errors on columns that don't map to a
+// request field get a generic message without a field. It reports whether
+// err was handled.
+func handleConstraintError(w http.ResponseWriter, err error) bool {
+	if errors.Is(err, models.ErrInvalidPhoneNumber) {
+		writeFieldError(w, http.StatusBadRequest, ErrInvalidPhoneNumber, "PhoneNumber")
+		return true
+	}
+
+	var constraintError *models.ConstraintError
+	if !errors.As(err, &constraintError) {
+		return false
+	}
+
+	field, ok := columnFields[constraintError.Column]
+	if !ok {
+		if errors.Is(err, models.ErrUniqueViolation) {
+			writeJSONError(w, http.StatusConflict, ErrConflictingField)
+		} else {
+			writeJSONError(w, http.StatusBadRequest, ErrInvalidRequestField)
+		}
+		return true
+	}
+
+	if errors.Is(err, models.ErrUniqueViolation) {
+		conflictError, ok := conflictErrors[constraintError.Column]
+		if !ok {
+			conflictError = ErrConflictingField
+		}
+		writeFieldError(w, http.StatusConflict, conflictError, field)
+	} else {
+		writeFieldError(w, http.StatusBadRequest, ErrInvalidRequestField, field)
+	}
+
+	return true
+}
@@ -32,6 +32,17 @@ func NewUpdateCustomerRequest(customer models.Customer, customerJWT string) *htt
 	return request
 }
 
+func NewPatchCustomerRequest(customerJWT string, patch map[string]any) *http.Request {
+	body := bytes.NewBuffer([]byte{})
+	json.NewEncoder(body).Encode(patch)
+
+	request, _ := http.NewRequest(http.MethodPatch, "/customer/", body)
+	request.Header.Add("Token", customerJWT)
+	request.Header.Add("Content-Type", "application/merge-patch+json")
+
+	return request
+}
+
 func NewLoginRequest(customer models.Customer) *http.Request {
 	loginCustomerRequest := CustomerToLoginCustomerRequest(customer)
 	body := bytes.NewBuffer([]byte{})
@@ -50,6 +61,26 @@ func NewCreateCustomerRequest(customer models.Customer) *http.Request {
 	return request
 }
 
+func NewCreateCustomerRequestWithConsents(customer models.Customer, acceptedDocuments []int) *http.Request {
+	createCustomerRequest := CustomerToCreateCustomerRequest(customer)
+	createCustomerRequest.AcceptedDocuments = acceptedDocuments
+	body := bytes.NewBuffer([]byte{})
+	json.NewEncoder(body).Encode(createCustomerRequest)
+
+	request, _ := http.NewRequest(http.MethodPost, "/customer/", body)
+	return request
+}
+
+func NewCreateCustomerRequestWithReferral(customer models.Customer, referralCode string) *http.Request {
+	createCustomerRequest := CustomerToCreateCustomerRequest(customer)
+	createCustomerRequest.ReferralCode = referralCode
+	body := bytes.NewBuffer([]byte{})
+	json.NewEncoder(body).Encode(createCustomerRequest)
+
+	request, _ := http.NewRequest(http.MethodPost, "/customer/", body)
+	return request
+}
+
 func NewGetCustomerRequest(jwt string) *http.Request {
 	request, _ := http.NewRequest(http.MethodGet, "/customer/", nil)
 	request.Header.Add("Token", jwt)
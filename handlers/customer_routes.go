@@ -9,18 +9,25 @@ import (
 )
 
 type CustomerServer struct {
-	secretKey []byte
-	expiresAt time.Duration
-	store     models.CustomerStore
+	secretKey        []byte
+	expiresAt        time.Duration
+	store            models.CustomerStore
+	consentStore     models.ConsentStore
+	referralStore    models.ReferralStore
+	referralHashKey  []byte
+	preconditionMode PreconditionMode
 	http.Handler
 }
 
-func NewCustomerServer(secretKey []byte, expiresAt time.Duration, store models.CustomerStore) *CustomerServer {
+func NewCustomerServer(secretKey []byte, expiresAt time.Duration, store models.CustomerStore, consentStore models.ConsentStore,
+	referralStore models.ReferralStore) *CustomerServer {
 	c := new(CustomerServer)
 
 	c.secretKey = secretKey
 	c.expiresAt = expiresAt
 	c.store = store
+	c.consentStore = consentStore
+	c.referralStore = referralStore
 
 	router := http.NewServeMux()
 	router.HandleFunc("/customer/", c.CustomerHandler)
@@ -32,6 +39,16 @@ func NewCustomerServer(secretKey []byte, expiresAt time.Duration, store models.C
 	return c
 }
 
+func (c *CustomerServer) SetPreconditionMode(mode PreconditionMode) {
+	c.preconditionMode = mode
+}
+
+// SetReferralHashKey sets the key the phone numbers and emails of referred
+// customers are hashed with.
+func (c *CustomerServer) SetReferralHashKey(key []byte) {
+	c.referralHashKey = key
+}
+
 func (c *CustomerServer) CustomerHandler(w http.ResponseWriter, r *http.Request) {
 	if r.URL.Path != "/customer/" {
 		w.WriteHeader(http.StatusNotFound)
@@ -47,5 +64,7 @@ func (c *CustomerServer) CustomerHandler(w http.ResponseWriter, r *http.Request)
 		auth.AuthenticationMiddleware(c.deleteCustomer, c.secretKey)(w, r)
 	case http.MethodPut:
 		auth.AuthenticationMiddleware(c.updateCustomer, c.secretKey)(w, r)
+	case http.MethodPatch:
+		auth.AuthenticationMiddleware(c.patchCustomer, c.secretKey)(w, r)
 	}
 }
@@ -19,7 +19,7 @@ import (
 func TestCustomerEndpointAuthentication(t *testing.T) {
 	customerData := []models.Customer{td.PeterCustomer, td.AliceCustomer}
 	store := testutil.NewStubCustomerStore(customerData)
-	server := handlers.NewCustomerServer(testEnv.SecretKey, testEnv.ExpiresAt, store)
+	server := handlers.NewCustomerServer(testEnv.SecretKey, testEnv.ExpiresAt, store, testutil.NewStubConsentStore(nil, nil), testutil.NewStubReferralStore(nil, nil))
 
 	invalidJWT := "thisIsAnInvalidJWT"
 	cases := map[string]*http.Request{
@@ -44,7 +44,7 @@ func TestCustomerEndpointAuthentication(t *testing.T) {
 func TestAuthHandler(t *testing.T) {
 	customerData := []models.Customer{td.PeterCustomer, td.AliceCustomer}
 	store := testutil.NewStubCustomerStore(customerData)
-	server := handlers.NewCustomerServer(testEnv.SecretKey, testEnv.ExpiresAt, store)
+	server := handlers.NewCustomerServer(testEnv.SecretKey, testEnv.ExpiresAt, store, testutil.NewStubConsentStore(nil, nil), testutil.NewStubReferralStore(nil, nil))
 
 	t.Run("returns OK status and customer ID on valid JWT", func(t *testing.T) {
 		peterJWT, _ := auth.GenerateJWT(testEnv.SecretKey, testEnv.ExpiresAt, td.PeterCustomer.Id)
@@ -154,12 +154,39 @@ func TestAuthHandler(t *testing.T) {
 		testutil.AssertEqual(t, got, want)
 	})
 
+	t.Run("returns NOT_FOUND on anonymized and merged customers", func(t *testing.T) {
+		anonymized, _ := models.EraseCustomerPII(td.PeterCustomer)
+		merged := td.AliceCustomer
+		merged.Status = models.MERGED_STATUS
+		merged.MergedInto = &anonymized.Id
+
+		store := testutil.NewStubCustomerStore([]models.Customer{anonymized, merged})
+		server := handlers.NewCustomerServer(testEnv.SecretKey, testEnv.ExpiresAt, store, testutil.NewStubConsentStore(nil, nil), testutil.NewStubReferralStore(nil, nil))
+
+		for _, customer := range []models.Customer{anonymized, merged} {
+			customerJWT, _ := auth.GenerateJWT(testEnv.SecretKey, testEnv.ExpiresAt, customer.Id)
+
+			request := handlers.NewAuthRequest(customerJWT)
+			response := httptest.NewRecorder()
+
+			server.ServeHTTP(response, request)
+
+			want := handlers.AuthResponse{
+				Status: handlers.NOT_FOUND,
+				ID:     0,
+			}
+			var got handlers.AuthResponse
+			json.NewDecoder(response.Body).Decode(&got)
+
+			testutil.AssertEqual(t, got, want)
+		}
+	})
 }
 
 func TestUpdateUser(t *testing.T) {
 	customerData := []models.Customer{td.PeterCustomer, td.AliceCustomer}
 	store := testutil.NewStubCustomerStore(customerData)
-	server := handlers.NewCustomerServer(testEnv.SecretKey, testEnv.ExpiresAt, store)
+	server := handlers.NewCustomerServer(testEnv.SecretKey, testEnv.ExpiresAt, store, testutil.NewStubConsentStore(nil, nil), testutil.NewStubReferralStore(nil, nil))
 
 	t.Run("updates customer information on valid JWT", func(t *testing.T) {
 		updateCustomer := td.PeterCustomer
@@ -176,12 +203,136 @@ func TestUpdateUser(t *testing.T) {
 		testutil.AssertStatus(t, response.Code, http.StatusOK)
 		testutil.AssertUpdatedCustomer(t, store, updateCustomer)
 	})
+
+	t.Run("returns Conflict on email of another customer", func(t *testing.T) {
+		updateCustomer := td.AliceCustomer
+		updateCustomer.Email = td.PeterCustomer.Email
+
+		aliceJWT, _ := auth.GenerateJWT(testEnv.SecretKey, testEnv.ExpiresAt, td.AliceCustomer.Id)
+
+		request := handlers.NewUpdateCustomerRequest(updateCustomer, aliceJWT)
+		response := httptest.NewRecorder()
+
+		server.ServeHTTP(response, request)
+
+		testutil.AssertStatus(t, response.Code, http.StatusConflict)
+		testutil.AssertFieldErrorResponse(t, response.Body, handlers.ErrExistingCustomer, "Email")
+	})
+
+	t.Run("returns Precondition Failed on stale If-Match", func(t *testing.T) {
+		aliceJWT, _ := auth.GenerateJWT(testEnv.SecretKey, testEnv.ExpiresAt, td.AliceCustomer.Id)
+
+		request := handlers.NewUpdateCustomerRequest(td.AliceCustomer, aliceJWT)
+		request.Header.Set("If-Match", `"0"`)
+		response := httptest.NewRecorder()
+
+		server.ServeHTTP(response, request)
+
+		testutil.AssertStatus(t, response.Code, http.StatusPreconditionFailed)
+		testutil.AssertErrorResponse(t, response.Body, handlers.ErrPreconditionFailed)
+	})
+
+	t.Run("returns Precondition Required on missing If-Match in strict mode", func(t *testing.T) {
+		server.SetPreconditionMode(handlers.STRICT_PRECONDITIONS)
+		defer server.SetPreconditionMode(handlers.LENIENT_PRECONDITIONS)
+
+		aliceJWT, _ := auth.GenerateJWT(testEnv.SecretKey, testEnv.ExpiresAt, td.AliceCustomer.Id)
+
+		request := handlers.NewUpdateCustomerRequest(td.AliceCustomer, aliceJWT)
+		response := httptest.NewRecorder()
+
+		server.ServeHTTP(response, request)
+
+		testutil.AssertStatus(t, response.Code, http.StatusPreconditionRequired)
+		testutil.AssertErrorResponse(t, response.Body, handlers.ErrPreconditionRequired)
+	})
+}
+
+func TestPatchUser(t *testing.T) {
+	customerData := []models.Customer{td.PeterCustomer, td.AliceCustomer}
+	store := testutil.NewStubCustomerStore(customerData)
+	server := handlers.NewCustomerServer(testEnv.SecretKey, testEnv.ExpiresAt, store, testutil.NewStubConsentStore(nil, nil), testutil.NewStubReferralStore(nil, nil))
+
+	peterJWT, _ := auth.GenerateJWT(testEnv.SecretKey, testEnv.ExpiresAt, td.PeterCustomer.Id)
+
+	t.Run("returns Bad Request on null required field", func(t *testing.T) {
+		request := handlers.NewPatchCustomerRequest(peterJWT, map[string]any{"LastName": nil})
+		response := httptest.NewRecorder()
+
+		server.ServeHTTP(response, request)
+
+		testutil.AssertStatus(t, response.Code, http.StatusBadRequest)
+		testutil.AssertErrorResponse(t, response.Body, handlers.ErrNullField)
+	})
+
+	t.Run("returns Bad Request on invalid present field", func(t *testing.T) {
+		request := handlers.NewPatchCustomerRequest(peterJWT, map[string]any{"Email": "notanemail"})
+		response := httptest.NewRecorder()
+
+		server.ServeHTTP(response, request)
+
+		testutil.AssertStatus(t, response.Code, http.StatusBadRequest)
+	})
+
+	t.Run("returns Conflict on phone number of another customer", func(t *testing.T) {
+		request := handlers.NewPatchCustomerRequest(peterJWT, map[string]any{"PhoneNumber": td.AliceCustomer.PhoneNumber})
+		response := httptest.NewRecorder()
+
+		server.ServeHTTP(response, request)
+
+		testutil.AssertStatus(t, response.Code, http.StatusConflict)
+		testutil.AssertFieldErrorResponse(t, response.Body, handlers.ErrExistingPhoneNumber, "PhoneNumber")
+	})
+
+	t.Run("returns Bad Request on unknown field", func(t *testing.T) {
+		request := handlers.NewPatchCustomerRequest(peterJWT, map[string]any{"Nickname": "Pete"})
+		response := httptest.NewRecorder()
+
+		server.ServeHTTP(response, request)
+
+		testutil.AssertStatus(t, response.Code, http.StatusBadRequest)
+		testutil.AssertErrorResponse(t, response.Body, handlers.ErrIncorrectRequestType)
+	})
+
+	t.Run("updates only the fields present in the patch", func(t *testing.T) {
+		firstName := "Pete"
+
+		request := handlers.NewPatchCustomerRequest(peterJWT, map[string]any{"FirstName": firstName})
+		response := httptest.NewRecorder()
+
+		server.ServeHTTP(response, request)
+
+		testutil.AssertStatus(t, response.Code, http.StatusOK)
+		testutil.AssertPatchedCustomer(t, store, models.CustomerPatch{FirstName: &firstName})
+
+		patchedCustomer := td.PeterCustomer
+		patchedCustomer.FirstName = firstName
+
+		want := handlers.CustomerToCustomerResponse(patchedCustomer)
+		got := testutil.ParseCustomerResponse(t, response.Body)
+
+		testutil.AssertEqual(t, got, want)
+	})
+
+	t.Run("keeps the ETag on an empty patch", func(t *testing.T) {
+		response := httptest.NewRecorder()
+		server.ServeHTTP(response, handlers.NewGetCustomerRequest(peterJWT))
+		etag := response.Header().Get("ETag")
+
+		request := handlers.NewPatchCustomerRequest(peterJWT, map[string]any{})
+		response = httptest.NewRecorder()
+
+		server.ServeHTTP(response, request)
+
+		testutil.AssertStatus(t, response.Code, http.StatusOK)
+		testutil.AssertEqual(t, response.Header().Get("ETag"), etag)
+	})
 }
 
 func TestDeleteUser(t *testing.T) {
 	customerData := []models.Customer{td.PeterCustomer, td.AliceCustomer}
 	store := testutil.NewStubCustomerStore(customerData)
-	server := handlers.NewCustomerServer(testEnv.SecretKey, testEnv.ExpiresAt, store)
+	server := handlers.NewCustomerServer(testEnv.SecretKey, testEnv.ExpiresAt, store, testutil.NewStubConsentStore(nil, nil), testutil.NewStubReferralStore(nil, nil))
 
 	t.Run("deletes customer on valid JWT", func(t *testing.T) {
 		peterJWT, _ := auth.GenerateJWT(testEnv.SecretKey, testEnv.ExpiresAt, td.PeterCustomer.Id)
@@ -209,7 +360,7 @@ func TestDeleteUser(t *testing.T) {
 func TestLoginUser(t *testing.T) {
 	customerData := []models.Customer{td.PeterCustomer, td.AliceCustomer}
 	store := testutil.NewStubCustomerStore(customerData)
-	server := handlers.NewCustomerServer(testEnv.SecretKey, testEnv.ExpiresAt, store)
+	server := handlers.NewCustomerServer(testEnv.SecretKey, testEnv.ExpiresAt, store, testutil.NewStubConsentStore(nil, nil), testutil.NewStubReferralStore(nil, nil))
 
 	t.Run("returns JWT on Peter's credentials", func(t *testing.T) {
 		request := handlers.NewLoginRequest(td.PeterCustomer)
@@ -267,7 +418,7 @@ func TestLoginUser(t *testing.T) {
 func TestCreateUser(t *testing.T) {
 	customerData := []models.Customer{}
 	store := testutil.NewStubCustomerStore(customerData)
-	server := handlers.NewCustomerServer(testEnv.SecretKey, testEnv.ExpiresAt, store)
+	server := handlers.NewCustomerServer(testEnv.SecretKey, testEnv.ExpiresAt, store, testutil.NewStubConsentStore(nil, nil), testutil.NewStubReferralStore(nil, nil))
 
 	t.Run("stores customer on POST", func(t *testing.T) {
 		store.Empty()
@@ -304,7 +455,7 @@ func TestCreateUser(t *testing.T) {
 		testutil.AssertEqual(t, gotResponse.Customer, wantResponseCustomer)
 	})
 
-	t.Run("return Bad Request on user with same email", func(t *testing.T) {
+	t.Run("return Conflict on user with same email", func(t *testing.T) {
 		store.Empty()
 
 		request := handlers.NewCreateCustomerRequest(td.PeterCustomer)
@@ -318,15 +469,34 @@ func TestCreateUser(t *testing.T) {
 		response = httptest.NewRecorder()
 		server.ServeHTTP(response, request)
 
-		testutil.AssertStatus(t, response.Code, http.StatusBadRequest)
-		testutil.AssertErrorResponse(t, response.Body, handlers.ErrExistingCustomer)
+		testutil.AssertStatus(t, response.Code, http.StatusConflict)
+		testutil.AssertFieldErrorResponse(t, response.Body, handlers.ErrExistingCustomer, "Email")
+	})
+
+	t.Run("return Conflict on user with same phone number", func(t *testing.T) {
+		store.Empty()
+
+		request := handlers.NewCreateCustomerRequest(td.PeterCustomer)
+		response := httptest.NewRecorder()
+
+		server.ServeHTTP(response, request)
+
+		samePhone := td.AliceCustomer
+		samePhone.PhoneNumber = td.PeterCustomer.PhoneNumber
+
+		request = handlers.NewCreateCustomerRequest(samePhone)
+		response = httptest.NewRecorder()
+		server.ServeHTTP(response, request)
+
+		testutil.AssertStatus(t, response.Code, http.StatusConflict)
+		testutil.AssertFieldErrorResponse(t, response.Body, handlers.ErrExistingPhoneNumber, "PhoneNumber")
 	})
 }
 
 func TestGetUser(t *testing.T) {
 	customerData := []models.Customer{td.PeterCustomer, td.AliceCustomer}
 	store := testutil.NewStubCustomerStore(customerData)
-	server := handlers.NewCustomerServer(testEnv.SecretKey, testEnv.ExpiresAt, store)
+	server := handlers.NewCustomerServer(testEnv.SecretKey, testEnv.ExpiresAt, store, testutil.NewStubConsentStore(nil, nil), testutil.NewStubReferralStore(nil, nil))
 
 	t.Run("returns Peter's customer information", func(t *testing.T) {
 		peterJWT, _ := auth.GenerateJWT(testEnv.SecretKey, testEnv.ExpiresAt, td.PeterCustomer.Id)
@@ -360,6 +530,18 @@ func TestGetUser(t *testing.T) {
 		testutil.AssertEqual(t, got, want)
 	})
 
+	t.Run("returns ETag and Not Modified on matching If-None-Match", func(t *testing.T) {
+		peterJWT, _ := auth.GenerateJWT(testEnv.SecretKey, testEnv.ExpiresAt, td.PeterCustomer.Id)
+		request := handlers.NewGetCustomerRequest(peterJWT)
+		request.Header.Set("If-None-Match", `"1"`)
+		response := httptest.NewRecorder()
+
+		server.ServeHTTP(response, request)
+
+		testutil.AssertStatus(t, response.Code, http.StatusNotModified)
+		testutil.AssertEqual(t, response.Header().Get("ETag"), `"1"`)
+	})
+
 	t.Run("returns Not Found on missing customer", func(t *testing.T) {
 		noCustomerJWT, _ := auth.GenerateJWT(testEnv.SecretKey, testEnv.ExpiresAt, 3)
 		request := handlers.NewGetCustomerRequest(noCustomerJWT)
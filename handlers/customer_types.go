@@ -26,6 +26,7 @@ type GetCustomerResponse struct {
 	LastName    string
 	PhoneNumber string
 	Email       string
+	AvatarURL   string
 }
 
 func CustomerToGetCustomerResponse(customer models.Customer) GetCustomerResponse {
@@ -35,17 +36,20 @@ func CustomerToGetCustomerResponse(customer models.Customer) GetCustomerResponse
 		LastName:    customer.LastName,
 		PhoneNumber: customer.PhoneNumber,
 		Email:       customer.Email,
+		AvatarURL:   customer.AvatarURL,
 	}
 
 	return getCustomerResponse
 }
 
 type CreateCustomerRequest struct {
-	FirstName   string `validate:"required,max=20"`
-	LastName    string `validate:"required,max=20"`
-	PhoneNumber string `validate:"required,phonenumber"`
-	Email       string `validate:"required,email"`
-	Password    string `validate:"required,max=72"`
+	FirstName         string `validate:"required,max=20"`
+	LastName          string `validate:"required,max=20"`
+	PhoneNumber       string `validate:"required,max=20"`
+	Email             string `validate:"required,email"`
+	Password          string `validate:"required,max=72"`
+	AcceptedDocuments []int  `validate:"dive,min=1"`
+	ReferralCode      string `validate:"max=20"`
 }
 
 func CustomerToCreateCustomerRequest(customer models.Customer) CreateCustomerRequest {
@@ -114,7 +118,7 @@ func CustomerToLoginCustomerRequest(customer models.Customer) LoginCustomerReque
 type UpdateCustomerRequest struct {
 	FirstName   string `validate:"required,max=20"`
 	LastName    string `validate:"required,max=20"`
-	PhoneNumber string `validate:"phonenumber,required"`
+	PhoneNumber string `validate:"required,max=20"`
 	Email       string `validate:"required,email"`
 	Password    string `validate:"required,max=72"`
 }
@@ -143,3 +147,23 @@ func UpdateCustomerRequestToCustomer(updateCustomerRequest UpdateCustomerRequest
 
 	return customer
 }
+
+type PatchCustomerRequest struct {
+	FirstName   *string `validate:"omitnil,required,max=20"`
+	LastName    *string `validate:"omitnil,required,max=20"`
+	PhoneNumber *string `validate:"omitnil,required,max=20"`
+	Email       *string `validate:"omitnil,required,email"`
+	Password    *string `validate:"omitnil,required,max=72"`
+}
+
+func PatchCustomerRequestToCustomerPatch(patchCustomerRequest PatchCustomerRequest) models.CustomerPatch {
+	customerPatch := models.CustomerPatch{
+		FirstName:   patchCustomerRequest.FirstName,
+		LastName:    patchCustomerRequest.LastName,
+		PhoneNumber: patchCustomerRequest.PhoneNumber,
+		Email:       patchCustomerRequest.Email,
+		Password:    patchCustomerRequest.Password,
+	}
+
+	return customerPatch
+}
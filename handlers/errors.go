@@ -4,6 +4,7 @@ import (
 	"encoding/json"
 	"errors"
 	"net/http"
+	"strings"
 )
 
 var (
@@ -18,16 +19,78 @@ var (
 	ErrEmptyJSON            = errors.New("request JSON is empty")
 	ErrIncorrectRequestType = errors.New("request type is incorrect")
 	ErrInvalidRequestField  = errors.New("request contains invalid field(s)")
+	ErrNullField            = errors.New("request sets a required field to null")
+	ErrInvalidAddressID     = errors.New("address ID in path is invalid")
 	ErrMissingAddress       = errors.New("address doesn't exists")
 	ErrUnathorizedAction    = errors.New("customer does not have permission to perform this action")
 	ErrDatabaseError        = errors.New("operation encountered a database error")
+	ErrPreconditionFailed   = errors.New("resource was modified since it was last read")
+	ErrPreconditionRequired = errors.New("request must contain an If-Match header")
+	ErrConsentRequired      = errors.New("customer must accept the latest mandatory documents")
+	ErrUnknownDocument      = errors.New("document is not a currently published version")
+	ErrMissingAvatar        = errors.New("request doesn't contain an avatar file")
+	ErrAvatarTooLarge       = errors.New("avatar file is too large")
+	ErrBlobStoreError       = errors.New("operation encountered a blob store error")
+	ErrExistingPhoneNumber  = errors.New("customer with this phone number already exists")
+	ErrConflictingField     = errors.New("request field conflicts with an existing value")
+	ErrInvalidPhoneNumber   = errors.New("phone number is not valid")
+	ErrInsufficientPoints   = errors.New("customer doesn't have enough loyalty points")
+	ErrTransactionConflict  = errors.New("transaction ID was already used for a different transaction")
+	ErrNegativePoints       = errors.New("earned and redeemed points must be positive")
+	ErrInvalidReferralCode  = errors.New("referral code doesn't belong to an active customer")
+	ErrCandidateNotFound    = errors.New("duplicate candidate doesn't exist")
+	ErrCandidateReviewed    = errors.New("duplicate candidate was already merged or dismissed")
+	ErrInvalidMerge         = errors.New("survivor must be an active customer of the candidate pair")
+	ErrTagNotFound          = errors.New("customer doesn't have this tag")
+	ErrSegmentNotFound      = errors.New("segment doesn't exist")
+	ErrMissingCoordinates   = errors.New("address must have coordinates")
+	ErrUnknownAddress       = errors.New("address couldn't be geocoded")
+	ErrGeocodingError       = errors.New("operation encountered a geocoding error")
+	ErrNoReverseGeocoder    = errors.New("reverse geocoding is not available")
+	ErrUnknownLocation      = errors.New("no address is known near this location")
+	ErrOutsideDeliveryArea  = errors.New("address is outside of the delivery area")
+	ErrInvalidArea          = errors.New("request must have either a radius around a point or a bounding box")
+	ErrAddressIDMismatch    = errors.New("address ID in body doesn't match the one in path")
+	ErrMethodNotAllowed     = errors.New("method is not allowed on this resource")
+	ErrInvalidRevision      = errors.New("address revision in path is invalid")
+	ErrMissingRevision      = errors.New("address revision doesn't exists")
+	ErrHouseholdDefault     = errors.New("household addresses can't be the default address")
+	ErrInvalidHouseholdID   = errors.New("household ID in path is invalid")
+	ErrInvalidMemberID      = errors.New("member ID in path is invalid")
+	ErrHouseholdNotFound    = errors.New("household doesn't exist")
+	ErrMemberNotFound       = errors.New("customer isn't a member of the household")
+	ErrInvitationNotFound   = errors.New("invitation doesn't exist")
+	ErrInvitationExpired    = errors.New("invitation has expired")
+	ErrInvitationUsed       = errors.New("invitation was already accepted")
+	ErrAlreadyMember        = errors.New("customer is already a member of the household")
+	ErrLastOwner            = errors.New("household must keep at least one owner")
+	ErrMissingSecretKey     = errors.New("server needs a secret key to sign and verify tokens")
 )
 
 type ErrorResponse struct {
 	Message string
 }
 
+// FieldErrorResponse is returned when a request is rejected because of a
+// single field, such as an email that is already taken.
+type FieldErrorResponse struct {
+	Message string
+	Field   string
+}
+
 func writeJSONError(w http.ResponseWriter, statusCode int, err error) {
 	w.WriteHeader(statusCode)
 	json.NewEncoder(w).Encode(ErrorResponse{Message: err.Error()})
 }
+
+// writeMethodNotAllowed rejects a method the resource doesn't support and
+// lists the ones it does in the Allow header.
+func writeMethodNotAllowed(w http.ResponseWriter, allowed ...string) {
+	w.Header().Set("Allow", strings.Join(allowed, ", "))
+	writeJSONError(w, http.StatusMethodNotAllowed, ErrMethodNotAllowed)
+}
+
+func writeFieldError(w http.ResponseWriter, statusCode int, err error, field string) {
+	w.WriteHeader(statusCode)
+	json.NewEncoder(w).Encode(FieldErrorResponse{Message: err.Error(), Field: field})
+}
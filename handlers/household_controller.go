@@ -0,0 +1,275 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/VitoNaychev/bt-customer-svc/models"
+	"github.com/VitoNaychev/validation"
+)
+
+func (h *HouseholdServer) createHousehold(w http.ResponseWriter, r *http.Request) {
+	createHouseholdRequest, err := validation.ValidateBody[CreateHouseholdRequest](r.Body)
+	if err != nil {
+		writeJSONError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	customerId, _ := strconv.Atoi(r.Header["Subject"][0])
+
+	_, err = getActiveCustomer(h.customerStore, customerId)
+	if err != nil {
+		handleStoreError(w, err)
+		return
+	}
+
+	household := models.Household{Name: createHouseholdRequest.Name}
+	err = h.householdStore.CreateHousehold(&household, customerId)
+	if err != nil {
+		writeJSONError(w, http.StatusInternalServerError, ErrDatabaseError)
+		return
+	}
+
+	h.writeHousehold(w, household)
+}
+
+func (h *HouseholdServer) getHouseholds(w http.ResponseWriter, r *http.Request) {
+	customerId, _ := strconv.Atoi(r.Header["Subject"][0])
+
+	_, err := getActiveCustomer(h.customerStore, customerId)
+	if err != nil {
+		handleStoreError(w, err)
+		return
+	}
+
+	households, err := h.householdStore.GetHouseholdsByCustomerID(customerId)
+	if err != nil {
+		writeJSONError(w, http.StatusInternalServerError, ErrDatabaseError)
+		return
+	}
+
+	householdResponses := []HouseholdResponse{}
+	for _, household := range households {
+		members, err := h.householdStore.GetHouseholdMembers(household.Id)
+		if err != nil {
+			writeJSONError(w, http.StatusInternalServerError, ErrDatabaseError)
+			return
+		}
+		householdResponses = append(householdResponses, HouseholdToHouseholdResponse(household, members))
+	}
+
+	json.NewEncoder(w).Encode(householdResponses)
+}
+
+func (h *HouseholdServer) getHousehold(w http.ResponseWriter, r *http.Request) {
+	householdId, err := getHouseholdIDFromPath(r)
+	if err != nil {
+		writeJSONError(w, http.StatusBadRequest, ErrInvalidHouseholdID)
+		return
+	}
+
+	customerId, _ := strconv.Atoi(r.Header["Subject"][0])
+
+	_, err = getActiveCustomer(h.customerStore, customerId)
+	if err != nil {
+		handleStoreError(w, err)
+		return
+	}
+
+	household, _, ok := h.getMembership(w, householdId, customerId)
+	if !ok {
+		return
+	}
+
+	h.writeHousehold(w, household)
+}
+
+// inviteToHousehold lets owners invite others. The invitation is sent as a
+// code, which the owner shares with whoever they invite.
+func (h *HouseholdServer) inviteToHousehold(w http.ResponseWriter, r *http.Request) {
+	householdId, err := getHouseholdIDFromPath(r)
+	if err != nil {
+		writeJSONError(w, http.StatusBadRequest, ErrInvalidHouseholdID)
+		return
+	}
+
+	inviteToHouseholdRequest, err := validation.ValidateBody[InviteToHouseholdRequest](r.Body)
+	if err != nil {
+		writeJSONError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	customerId, _ := strconv.Atoi(r.Header["Subject"][0])
+
+	_, err = getActiveCustomer(h.customerStore, customerId)
+	if err != nil {
+		handleStoreError(w, err)
+		return
+	}
+
+	_, member, ok := h.getMembership(w, householdId, customerId)
+	if !ok {
+		return
+	}
+
+	if !member.IsOwner() {
+		writeJSONError(w, http.StatusUnauthorized, ErrUnathorizedAction)
+		return
+	}
+
+	role := inviteToHouseholdRequest.Role
+	if role == "" {
+		role = models.MEMBER_ROLE
+	}
+
+	invitation, err := models.NewHouseholdInvitation(householdId, customerId, role, time.Now())
+	if err != nil {
+		writeJSONError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	err = h.householdStore.CreateInvitation(&invitation)
+	if err != nil {
+		writeJSONError(w, http.StatusInternalServerError, ErrDatabaseError)
+		return
+	}
+
+	json.NewEncoder(w).Encode(HouseholdInvitationToInvitationResponse(invitation))
+}
+
+func (h *HouseholdServer) acceptInvitation(w http.ResponseWriter, r *http.Request) {
+	acceptInvitationRequest, err := validation.ValidateBody[AcceptInvitationRequest](r.Body)
+	if err != nil {
+		writeJSONError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	customerId, _ := strconv.Atoi(r.Header["Subject"][0])
+
+	_, err = getActiveCustomer(h.customerStore, customerId)
+	if err != nil {
+		handleStoreError(w, err)
+		return
+	}
+
+	code := strings.ToUpper(strings.TrimSpace(acceptInvitationRequest.Code))
+	member, err := h.householdStore.AcceptInvitation(code, customerId, time.Now())
+	if err != nil {
+		handleHouseholdStoreError(w, err, ErrInvitationNotFound)
+		return
+	}
+
+	household, err := h.householdStore.GetHouseholdByID(member.HouseholdId)
+	if err != nil {
+		handleHouseholdStoreError(w, err, ErrHouseholdNotFound)
+		return
+	}
+
+	h.writeHousehold(w, household)
+}
+
+// removeMember lets owners remove any member and members leave the
+// household themselves.
+func (h *HouseholdServer) removeMember(w http.ResponseWriter, r *http.Request) {
+	householdId, err := getHouseholdIDFromPath(r)
+	if err != nil {
+		writeJSONError(w, http.StatusBadRequest, ErrInvalidHouseholdID)
+		return
+	}
+
+	memberId, err := getMemberIDFromPath(r)
+	if err != nil {
+		writeJSONError(w, http.StatusBadRequest, ErrInvalidMemberID)
+		return
+	}
+
+	customerId, _ := strconv.Atoi(r.Header["Subject"][0])
+
+	_, err = getActiveCustomer(h.customerStore, customerId)
+	if err != nil {
+		handleStoreError(w, err)
+		return
+	}
+
+	_, member, ok := h.getMembership(w, householdId, customerId)
+	if !ok {
+		return
+	}
+
+	if memberId != customerId && !member.IsOwner() {
+		writeJSONError(w, http.StatusUnauthorized, ErrUnathorizedAction)
+		return
+	}
+
+	err = h.householdStore.RemoveHouseholdMember(householdId, memberId)
+	if err != nil {
+		handleHouseholdStoreError(w, err, ErrMemberNotFound)
+	}
+}
+
+// getMembership returns the household and the customer's membership in it,
+// rejecting customers who aren't members.
+func (h *HouseholdServer) getMembership(w http.ResponseWriter, householdId int, customerId int) (models.Household, models.HouseholdMember, bool) {
+	household, err := h.householdStore.GetHouseholdByID(householdId)
+	if err != nil {
+		handleHouseholdStoreError(w, err, ErrHouseholdNotFound)
+		return models.Household{}, models.HouseholdMember{}, false
+	}
+
+	member, err := h.householdStore.GetHouseholdMember(householdId, customerId)
+	if errors.Is(err, models.ErrNotFound) {
+		writeJSONError(w, http.StatusUnauthorized, ErrUnathorizedAction)
+		return models.Household{}, models.HouseholdMember{}, false
+	} else if err != nil {
+		writeJSONError(w, http.StatusInternalServerError, ErrDatabaseError)
+		return models.Household{}, models.HouseholdMember{}, false
+	}
+
+	return household, member, true
+}
+
+func (h *HouseholdServer) writeHousehold(w http.ResponseWriter, household models.Household) {
+	members, err := h.householdStore.GetHouseholdMembers(household.Id)
+	if err != nil {
+		writeJSONError(w, http.StatusInternalServerError, ErrDatabaseError)
+		return
+	}
+
+	json.NewEncoder(w).Encode(HouseholdToHouseholdResponse(household, members))
+}
+
+// getHouseholdIDFromPath reads the ID of paths of the form
+// /customer/households/{id}[/...].
+func getHouseholdIDFromPath(r *http.Request) (int, error) {
+	path := strings.Trim(strings.TrimPrefix(r.URL.Path, "/customer/households/"), "/")
+	idString, _, _ := strings.Cut(path, "/")
+	return strconv.Atoi(idString)
+}
+
+// getMemberIDFromPath reads the customer ID of paths of the form
+// /customer/households/{id}/members/{customerId}.
+func getMemberIDFromPath(r *http.Request) (int, error) {
+	_, idString, _ := strings.Cut(strings.Trim(r.URL.Path, "/"), "/members/")
+	return strconv.Atoi(idString)
+}
+
+func handleHouseholdStoreError(w http.ResponseWriter, err error, missingEntityError error) {
+	switch {
+	case errors.Is(err, models.ErrNotFound):
+		writeJSONError(w, http.StatusNotFound, missingEntityError)
+	case errors.Is(err, models.ErrInvitationExpired):
+		writeJSONError(w, http.StatusGone, ErrInvitationExpired)
+	case errors.Is(err, models.ErrInvitationUsed):
+		writeJSONError(w, http.StatusConflict, ErrInvitationUsed)
+	case errors.Is(err, models.ErrAlreadyMember):
+		writeJSONError(w, http.StatusConflict, ErrAlreadyMember)
+	case errors.Is(err, models.ErrLastOwner):
+		writeJSONError(w, http.StatusConflict, ErrLastOwner)
+	default:
+		writeJSONError(w, http.StatusInternalServerError, ErrDatabaseError)
+	}
+}
@@ -0,0 +1,60 @@
+package handlers
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"strconv"
+)
+
+func NewCreateHouseholdRequest(customerJWT string, name string) *http.Request {
+	body := bytes.NewBuffer([]byte{})
+	json.NewEncoder(body).Encode(CreateHouseholdRequest{Name: name})
+
+	request, _ := http.NewRequest(http.MethodPost, "/customer/households/", body)
+	request.Header.Add("Token", customerJWT)
+
+	return request
+}
+
+func NewGetHouseholdsRequest(customerJWT string) *http.Request {
+	request, _ := http.NewRequest(http.MethodGet, "/customer/households/", nil)
+	request.Header.Add("Token", customerJWT)
+
+	return request
+}
+
+func NewGetHouseholdRequest(customerJWT string, id int) *http.Request {
+	request, _ := http.NewRequest(http.MethodGet, "/customer/households/"+strconv.Itoa(id), nil)
+	request.Header.Add("Token", customerJWT)
+
+	return request
+}
+
+func NewInviteToHouseholdRequest(customerJWT string, id int, role string) *http.Request {
+	body := bytes.NewBuffer([]byte{})
+	json.NewEncoder(body).Encode(InviteToHouseholdRequest{Role: role})
+
+	request, _ := http.NewRequest(http.MethodPost, "/customer/households/"+strconv.Itoa(id)+"/invitations", body)
+	request.Header.Add("Token", customerJWT)
+
+	return request
+}
+
+func NewAcceptInvitationRequest(customerJWT string, code string) *http.Request {
+	body := bytes.NewBuffer([]byte{})
+	json.NewEncoder(body).Encode(AcceptInvitationRequest{Code: code})
+
+	request, _ := http.NewRequest(http.MethodPost, "/customer/households/invitations/accept/", body)
+	request.Header.Add("Token", customerJWT)
+
+	return request
+}
+
+func NewRemoveHouseholdMemberRequest(customerJWT string, id int, customerId int) *http.Request {
+	request, _ := http.NewRequest(http.MethodDelete,
+		"/customer/households/"+strconv.Itoa(id)+"/members/"+strconv.Itoa(customerId), nil)
+	request.Header.Add("Token", customerJWT)
+
+	return request
+}
@@ -0,0 +1,94 @@
+package handlers
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/VitoNaychev/auth"
+	"github.com/VitoNaychev/bt-customer-svc/models"
+)
+
+type HouseholdServer struct {
+	householdStore models.HouseholdStore
+	customerStore  models.CustomerStore
+	secretKey      []byte
+}
+
+func NewHouseholdServer(householdStore models.HouseholdStore, customerStore models.CustomerStore, secretKey []byte) *HouseholdServer {
+	householdServer := HouseholdServer{
+		householdStore: householdStore,
+		customerStore:  customerStore,
+		secretKey:      secretKey,
+	}
+
+	return &householdServer
+}
+
+func (h *HouseholdServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	path := strings.Trim(strings.TrimPrefix(r.URL.Path, "/customer/households"), "/")
+
+	switch {
+	case path == "":
+		h.HouseholdsHandler(w, r)
+	case path == "invitations/accept":
+		h.AcceptInvitationHandler(w, r)
+	case strings.HasSuffix(path, "/invitations"):
+		h.InvitationsHandler(w, r)
+	case strings.Contains(path, "/members/"):
+		h.MemberHandler(w, r)
+	default:
+		h.HouseholdHandler(w, r)
+	}
+}
+
+// HouseholdsHandler serves the households of the customer.
+func (h *HouseholdServer) HouseholdsHandler(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodPost:
+		auth.AuthenticationMiddleware(h.createHousehold, h.secretKey)(w, r)
+	case http.MethodGet:
+		auth.AuthenticationMiddleware(h.getHouseholds, h.secretKey)(w, r)
+	default:
+		writeMethodNotAllowed(w, http.MethodGet, http.MethodPost)
+	}
+}
+
+// HouseholdHandler serves a single household, identified by the ID in the
+// path.
+func (h *HouseholdServer) HouseholdHandler(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+		auth.AuthenticationMiddleware(h.getHousehold, h.secretKey)(w, r)
+	default:
+		writeMethodNotAllowed(w, http.MethodGet)
+	}
+}
+
+func (h *HouseholdServer) InvitationsHandler(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodPost:
+		auth.AuthenticationMiddleware(h.inviteToHousehold, h.secretKey)(w, r)
+	default:
+		writeMethodNotAllowed(w, http.MethodPost)
+	}
+}
+
+func (h *HouseholdServer) AcceptInvitationHandler(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodPost:
+		auth.AuthenticationMiddleware(h.acceptInvitation, h.secretKey)(w, r)
+	default:
+		writeMethodNotAllowed(w, http.MethodPost)
+	}
+}
+
+// MemberHandler serves a member of a household, identified by the
+// household ID and the customer ID of the member in the path.
+func (h *HouseholdServer) MemberHandler(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodDelete:
+		auth.AuthenticationMiddleware(h.removeMember, h.secretKey)(w, r)
+	default:
+		writeMethodNotAllowed(w, http.MethodDelete)
+	}
+}
@@ -0,0 +1,243 @@
+package handlers_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/VitoNaychev/auth"
+	"github.com/VitoNaychev/bt-customer-svc/handlers"
+	"github.com/VitoNaychev/bt-customer-svc/models"
+	td "github.com/VitoNaychev/bt-customer-svc/testdata"
+	"github.com/VitoNaychev/bt-customer-svc/testutil"
+)
+
+func TestHouseholdEndpointAuthentication(t *testing.T) {
+	customerStore := testutil.NewStubCustomerStore([]models.Customer{td.PeterCustomer})
+	server := handlers.NewHouseholdServer(testutil.NewStubHouseholdStore(nil, nil, nil), customerStore, testEnv.SecretKey)
+
+	cases := map[string]*http.Request{
+		"create household":  handlers.NewCreateHouseholdRequest("thisIsAnInvalidJWT", "Smith Home"),
+		"get households":    handlers.NewGetHouseholdsRequest("thisIsAnInvalidJWT"),
+		"get household":     handlers.NewGetHouseholdRequest("thisIsAnInvalidJWT", td.SmithHousehold.Id),
+		"invite":            handlers.NewInviteToHouseholdRequest("thisIsAnInvalidJWT", td.SmithHousehold.Id, ""),
+		"accept invitation": handlers.NewAcceptInvitationRequest("thisIsAnInvalidJWT", "ABCDEFGHJKMN"),
+		"remove member":     handlers.NewRemoveHouseholdMemberRequest("thisIsAnInvalidJWT", td.SmithHousehold.Id, td.AliceCustomer.Id),
+	}
+
+	for name, request := range cases {
+		t.Run(name, func(t *testing.T) {
+			response := httptest.NewRecorder()
+
+			server.ServeHTTP(response, request)
+
+			testutil.AssertStatus(t, response.Code, http.StatusUnauthorized)
+		})
+	}
+}
+
+func TestHouseholds(t *testing.T) {
+	newServer := func(members []models.HouseholdMember, invitations []models.HouseholdInvitation) *handlers.HouseholdServer {
+		customerStore := testutil.NewStubCustomerStore([]models.Customer{td.PeterCustomer, td.AliceCustomer})
+		householdStore := testutil.NewStubHouseholdStore([]models.Household{td.SmithHousehold}, members, invitations)
+		return handlers.NewHouseholdServer(householdStore, customerStore, testEnv.SecretKey)
+	}
+
+	peterJWT, _ := auth.GenerateJWT(testEnv.SecretKey, testEnv.ExpiresAt, td.PeterCustomer.Id)
+	aliceJWT, _ := auth.GenerateJWT(testEnv.SecretKey, testEnv.ExpiresAt, td.AliceCustomer.Id)
+
+	t.Run("creates household with the customer as owner", func(t *testing.T) {
+		server := newServer(nil, nil)
+
+		request := handlers.NewCreateHouseholdRequest(aliceJWT, "Johnson Flat")
+		response := httptest.NewRecorder()
+
+		server.ServeHTTP(response, request)
+
+		testutil.AssertStatus(t, response.Code, http.StatusOK)
+
+		var got handlers.HouseholdResponse
+		json.NewDecoder(response.Body).Decode(&got)
+		testutil.AssertEqual(t, got.Name, "Johnson Flat")
+		testutil.AssertEqual(t, len(got.Members), 1)
+		testutil.AssertEqual(t, got.Members[0].CustomerId, td.AliceCustomer.Id)
+		testutil.AssertEqual(t, got.Members[0].Role, models.OWNER_ROLE)
+	})
+
+	t.Run("lists the households of the customer", func(t *testing.T) {
+		server := newServer([]models.HouseholdMember{td.PeterHouseholdOwner, td.AliceHouseholdMember}, nil)
+
+		request := handlers.NewGetHouseholdsRequest(aliceJWT)
+		response := httptest.NewRecorder()
+
+		server.ServeHTTP(response, request)
+
+		testutil.AssertStatus(t, response.Code, http.StatusOK)
+
+		var got []handlers.HouseholdResponse
+		json.NewDecoder(response.Body).Decode(&got)
+		testutil.AssertEqual(t, len(got), 1)
+		testutil.AssertEqual(t, got[0].Id, td.SmithHousehold.Id)
+		testutil.AssertEqual(t, len(got[0].Members), 2)
+	})
+
+	t.Run("returns Unathorized on household the customer isn't a member of", func(t *testing.T) {
+		server := newServer([]models.HouseholdMember{td.PeterHouseholdOwner}, nil)
+
+		request := handlers.NewGetHouseholdRequest(aliceJWT, td.SmithHousehold.Id)
+		response := httptest.NewRecorder()
+
+		server.ServeHTTP(response, request)
+
+		testutil.AssertStatus(t, response.Code, http.StatusUnauthorized)
+		testutil.AssertErrorResponse(t, response.Body, handlers.ErrUnathorizedAction)
+	})
+
+	t.Run("returns Not Found on missing household", func(t *testing.T) {
+		server := newServer([]models.HouseholdMember{td.PeterHouseholdOwner}, nil)
+
+		request := handlers.NewGetHouseholdRequest(peterJWT, 10)
+		response := httptest.NewRecorder()
+
+		server.ServeHTTP(response, request)
+
+		testutil.AssertStatus(t, response.Code, http.StatusNotFound)
+		testutil.AssertErrorResponse(t, response.Body, handlers.ErrHouseholdNotFound)
+	})
+
+	t.Run("joins household with invitation from owner", func(t *testing.T) {
+		server := newServer([]models.HouseholdMember{td.PeterHouseholdOwner}, nil)
+
+		request := handlers.NewInviteToHouseholdRequest(peterJWT, td.SmithHousehold.Id, "")
+		response := httptest.NewRecorder()
+
+		server.ServeHTTP(response, request)
+
+		testutil.AssertStatus(t, response.Code, http.StatusOK)
+
+		var invitation handlers.InvitationResponse
+		json.NewDecoder(response.Body).Decode(&invitation)
+		testutil.AssertEqual(t, invitation.Role, models.MEMBER_ROLE)
+		testutil.AssertEqual(t, len(invitation.Code), models.INVITATION_CODE_LENGTH)
+
+		request = handlers.NewAcceptInvitationRequest(aliceJWT, invitation.Code)
+		response = httptest.NewRecorder()
+
+		server.ServeHTTP(response, request)
+
+		testutil.AssertStatus(t, response.Code, http.StatusOK)
+
+		var got handlers.HouseholdResponse
+		json.NewDecoder(response.Body).Decode(&got)
+		testutil.AssertEqual(t, len(got.Members), 2)
+		testutil.AssertEqual(t, got.Members[1].CustomerId, td.AliceCustomer.Id)
+		testutil.AssertEqual(t, got.Members[1].Role, models.MEMBER_ROLE)
+
+		request = handlers.NewAcceptInvitationRequest(aliceJWT, invitation.Code)
+		response = httptest.NewRecorder()
+
+		server.ServeHTTP(response, request)
+
+		testutil.AssertStatus(t, response.Code, http.StatusConflict)
+		testutil.AssertErrorResponse(t, response.Body, handlers.ErrInvitationUsed)
+	})
+
+	t.Run("returns Unathorized on invitation from member", func(t *testing.T) {
+		server := newServer([]models.HouseholdMember{td.PeterHouseholdOwner, td.AliceHouseholdMember}, nil)
+
+		request := handlers.NewInviteToHouseholdRequest(aliceJWT, td.SmithHousehold.Id, models.MEMBER_ROLE)
+		response := httptest.NewRecorder()
+
+		server.ServeHTTP(response, request)
+
+		testutil.AssertStatus(t, response.Code, http.StatusUnauthorized)
+		testutil.AssertErrorResponse(t, response.Body, handlers.ErrUnathorizedAction)
+	})
+
+	t.Run("returns Gone on expired invitation", func(t *testing.T) {
+		expired := models.HouseholdInvitation{
+			Code:        "ABCDEFGHJKMN",
+			HouseholdId: td.SmithHousehold.Id,
+			Role:        models.MEMBER_ROLE,
+			ExpiresAt:   time.Now().Add(-time.Hour),
+		}
+		server := newServer([]models.HouseholdMember{td.PeterHouseholdOwner}, []models.HouseholdInvitation{expired})
+
+		request := handlers.NewAcceptInvitationRequest(aliceJWT, "abcdefghjkmn")
+		response := httptest.NewRecorder()
+
+		server.ServeHTTP(response, request)
+
+		testutil.AssertStatus(t, response.Code, http.StatusGone)
+		testutil.AssertErrorResponse(t, response.Body, handlers.ErrInvitationExpired)
+	})
+
+	t.Run("returns Not Found on unknown invitation", func(t *testing.T) {
+		server := newServer([]models.HouseholdMember{td.PeterHouseholdOwner}, nil)
+
+		request := handlers.NewAcceptInvitationRequest(aliceJWT, "ABCDEFGHJKMN")
+		response := httptest.NewRecorder()
+
+		server.ServeHTTP(response, request)
+
+		testutil.AssertStatus(t, response.Code, http.StatusNotFound)
+		testutil.AssertErrorResponse(t, response.Body, handlers.ErrInvitationNotFound)
+	})
+
+	t.Run("lets member leave household", func(t *testing.T) {
+		server := newServer([]models.HouseholdMember{td.PeterHouseholdOwner, td.AliceHouseholdMember}, nil)
+
+		request := handlers.NewRemoveHouseholdMemberRequest(aliceJWT, td.SmithHousehold.Id, td.AliceCustomer.Id)
+		response := httptest.NewRecorder()
+
+		server.ServeHTTP(response, request)
+
+		testutil.AssertStatus(t, response.Code, http.StatusOK)
+
+		request = handlers.NewGetHouseholdRequest(aliceJWT, td.SmithHousehold.Id)
+		response = httptest.NewRecorder()
+
+		server.ServeHTTP(response, request)
+
+		testutil.AssertStatus(t, response.Code, http.StatusUnauthorized)
+	})
+
+	t.Run("returns Unathorized on member removing another member", func(t *testing.T) {
+		server := newServer([]models.HouseholdMember{td.PeterHouseholdOwner, td.AliceHouseholdMember}, nil)
+
+		request := handlers.NewRemoveHouseholdMemberRequest(aliceJWT, td.SmithHousehold.Id, td.PeterCustomer.Id)
+		response := httptest.NewRecorder()
+
+		server.ServeHTTP(response, request)
+
+		testutil.AssertStatus(t, response.Code, http.StatusUnauthorized)
+		testutil.AssertErrorResponse(t, response.Body, handlers.ErrUnathorizedAction)
+	})
+
+	t.Run("returns Conflict on removing the last owner", func(t *testing.T) {
+		server := newServer([]models.HouseholdMember{td.PeterHouseholdOwner, td.AliceHouseholdMember}, nil)
+
+		request := handlers.NewRemoveHouseholdMemberRequest(peterJWT, td.SmithHousehold.Id, td.PeterCustomer.Id)
+		response := httptest.NewRecorder()
+
+		server.ServeHTTP(response, request)
+
+		testutil.AssertStatus(t, response.Code, http.StatusConflict)
+		testutil.AssertErrorResponse(t, response.Body, handlers.ErrLastOwner)
+	})
+
+	t.Run("returns Method Not Allowed on unsupported method", func(t *testing.T) {
+		server := newServer([]models.HouseholdMember{td.PeterHouseholdOwner}, nil)
+
+		request := handlers.NewGetHouseholdRequest(peterJWT, td.SmithHousehold.Id)
+		request.Method = http.MethodDelete
+		response := httptest.NewRecorder()
+
+		server.ServeHTTP(response, request)
+
+		testutil.AssertStatus(t, response.Code, http.StatusMethodNotAllowed)
+		testutil.AssertEqual(t, response.Header().Get("Allow"), http.MethodGet)
+	})
+}
@@ -0,0 +1,70 @@
+package handlers
+
+import (
+	"time"
+
+	"github.com/VitoNaychev/bt-customer-svc/models"
+)
+
+type CreateHouseholdRequest struct {
+	Name string `validate:"required,max=40"`
+}
+
+// InviteToHouseholdRequest defaults to inviting a member.
+type InviteToHouseholdRequest struct {
+	Role string `validate:"omitempty,oneof=owner member"`
+}
+
+type AcceptInvitationRequest struct {
+	Code string `validate:"required,max=16"`
+}
+
+type HouseholdMemberResponse struct {
+	CustomerId int
+	Role       string
+	JoinedAt   time.Time
+}
+
+type HouseholdResponse struct {
+	Id        int
+	Name      string
+	CreatedAt time.Time
+	Members   []HouseholdMemberResponse
+}
+
+func HouseholdToHouseholdResponse(household models.Household, members []models.HouseholdMember) HouseholdResponse {
+	householdResponse := HouseholdResponse{
+		Id:        household.Id,
+		Name:      household.Name,
+		CreatedAt: household.CreatedAt,
+		Members:   []HouseholdMemberResponse{},
+	}
+
+	for _, member := range members {
+		householdResponse.Members = append(householdResponse.Members, HouseholdMemberResponse{
+			CustomerId: member.CustomerId,
+			Role:       member.Role,
+			JoinedAt:   member.JoinedAt,
+		})
+	}
+
+	return householdResponse
+}
+
+// InvitationResponse holds the code the invited customer accepts the
+// invitation with.
+type InvitationResponse struct {
+	Code        string
+	HouseholdId int
+	Role        string
+	ExpiresAt   time.Time
+}
+
+func HouseholdInvitationToInvitationResponse(invitation models.HouseholdInvitation) InvitationResponse {
+	return InvitationResponse{
+		Code:        invitation.Code,
+		HouseholdId: invitation.HouseholdId,
+		Role:        invitation.Role,
+		ExpiresAt:   invitation.ExpiresAt,
+	}
+}
@@ -0,0 +1,77 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+
+	"github.com/VitoNaychev/validation"
+)
+
+// freezeAddress keeps the revision an order delivers to, even after the
+// customer edits or deletes the address. Freezing is idempotent, so
+// retried checkouts get the same snapshot back.
+func (i *InternalServer) freezeAddress(w http.ResponseWriter, r *http.Request) {
+	freezeAddressRequest, err := validation.ValidateBody[FreezeAddressRequest](r.Body)
+	if err != nil {
+		writeJSONError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	revision := freezeAddressRequest.Revision
+	if revision == 0 {
+		address, err := i.addressStore.GetAddressByID(freezeAddressRequest.AddressId)
+		if err != nil {
+			handleAddressStoreError(w, err, ErrMissingAddress)
+			return
+		}
+		revision = address.Version
+	}
+
+	addressRevision, err := i.addressStore.GetAddressRevision(freezeAddressRequest.AddressId, revision)
+	if err != nil {
+		handleAddressStoreError(w, err, ErrMissingRevision)
+		return
+	}
+
+	if !checkAddressAccess(w, i.householdStore, freezeAddressRequest.CustomerId, addressRevision.Address(), useAddress) {
+		return
+	}
+
+	addressRevision, err = i.addressStore.FreezeAddressRevision(freezeAddressRequest.AddressId, revision)
+	if err != nil {
+		handleAddressStoreError(w, err, ErrMissingRevision)
+		return
+	}
+
+	json.NewEncoder(w).Encode(AddressRevisionToAddressSnapshotResponse(addressRevision))
+}
+
+func (i *InternalServer) getSegmentMembers(w http.ResponseWriter, r *http.Request) {
+	writeSegmentMembers(w, r, i.segmentStore)
+}
+
+// getCustomerSegments lets other services target a customer by segment.
+// Only active customers are members of segments, so erased and merged ones
+// aren't found.
+func (i *InternalServer) getCustomerSegments(w http.ResponseWriter, r *http.Request) {
+	customerId, err := strconv.Atoi(r.URL.Query().Get("customer_id"))
+	if err != nil {
+		writeJSONError(w, http.StatusBadRequest, ErrIncorrectRequestType)
+		return
+	}
+
+	_, err = getActiveCustomer(i.customerStore, customerId)
+	if err != nil {
+		handleStoreError(w, err)
+		return
+	}
+
+	segments, err := i.segmentStore.GetCustomerSegments(customerId)
+	if err != nil {
+		writeJSONError(w, http.StatusInternalServerError, ErrDatabaseError)
+		return
+	}
+
+	json.NewEncoder(w).Encode(CustomerSegmentsResponse{CustomerId: customerId, Segments: segments})
+}
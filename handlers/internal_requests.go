@@ -0,0 +1,35 @@
+package handlers
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/url"
+	"strconv"
+)
+
+func NewFreezeAddressRequest(internalJWT string, freezeAddressRequest FreezeAddressRequest) *http.Request {
+	body := bytes.NewBuffer([]byte{})
+	json.NewEncoder(body).Encode(freezeAddressRequest)
+
+	request, _ := http.NewRequest(http.MethodPost, "/internal/addresses/freeze/", body)
+	request.Header.Add("Token", internalJWT)
+
+	return request
+}
+
+func NewInternalGetSegmentMembersRequest(internalJWT string, query url.Values) *http.Request {
+	request, _ := http.NewRequest(http.MethodGet, "/internal/segments/members/?"+query.Encode(), nil)
+	request.Header.Add("Token", internalJWT)
+
+	return request
+}
+
+func NewInternalGetCustomerSegmentsRequest(internalJWT string, customerID int) *http.Request {
+	query := url.Values{"customer_id": {strconv.Itoa(customerID)}}
+
+	request, _ := http.NewRequest(http.MethodGet, "/internal/customer/segments/?"+query.Encode(), nil)
+	request.Header.Add("Token", internalJWT)
+
+	return request
+}
@@ -0,0 +1,72 @@
+package handlers
+
+import (
+	"net/http"
+
+	"github.com/VitoNaychev/auth"
+	"github.com/VitoNaychev/bt-customer-svc/models"
+)
+
+// InternalServer serves the API other services call, e.g. the order
+// service at checkout. It is authenticated with its own secret, so its
+// tokens are never handed out to customers.
+type InternalServer struct {
+	secretKey      []byte
+	customerStore  models.CustomerStore
+	addressStore   models.CustomerAddressStore
+	householdStore models.HouseholdStore
+	segmentStore   models.SegmentStore
+	http.Handler
+}
+
+func NewInternalServer(secretKey []byte, customerStore models.CustomerStore, addressStore models.CustomerAddressStore,
+	householdStore models.HouseholdStore, segmentStore models.SegmentStore) *InternalServer {
+	// Tokens signed with an empty key can be minted by anyone.
+	if len(secretKey) == 0 {
+		panic(ErrMissingSecretKey)
+	}
+
+	i := new(InternalServer)
+
+	i.secretKey = secretKey
+	i.customerStore = customerStore
+	i.addressStore = addressStore
+	i.householdStore = householdStore
+	i.segmentStore = segmentStore
+
+	router := http.NewServeMux()
+	router.HandleFunc("/internal/addresses/freeze/", i.FreezeAddressHandler)
+	router.HandleFunc("/internal/segments/members/", i.SegmentMembersHandler)
+	router.HandleFunc("/internal/customer/segments/", i.CustomerSegmentsHandler)
+
+	i.Handler = router
+
+	return i
+}
+
+func (i *InternalServer) FreezeAddressHandler(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodPost:
+		auth.AuthenticationMiddleware(i.freezeAddress, i.secretKey)(w, r)
+	default:
+		writeMethodNotAllowed(w, http.MethodPost)
+	}
+}
+
+func (i *InternalServer) SegmentMembersHandler(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+		auth.AuthenticationMiddleware(i.getSegmentMembers, i.secretKey)(w, r)
+	default:
+		writeMethodNotAllowed(w, http.MethodGet)
+	}
+}
+
+func (i *InternalServer) CustomerSegmentsHandler(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+		auth.AuthenticationMiddleware(i.getCustomerSegments, i.secretKey)(w, r)
+	default:
+		writeMethodNotAllowed(w, http.MethodGet)
+	}
+}
@@ -0,0 +1,198 @@
+package handlers_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+
+	"github.com/VitoNaychev/auth"
+	"github.com/VitoNaychev/bt-customer-svc/handlers"
+	"github.com/VitoNaychev/bt-customer-svc/models"
+	td "github.com/VitoNaychev/bt-customer-svc/testdata"
+	"github.com/VitoNaychev/bt-customer-svc/testutil"
+)
+
+func TestInternalEndpointAuthentication(t *testing.T) {
+	server := handlers.NewInternalServer(testEnv.InternalSecretKey, testutil.NewStubCustomerStore(nil), testutil.NewStubAddressStore(nil), testutil.NewStubHouseholdStore(nil, nil, nil), testutil.NewStubSegmentStore(nil, nil, nil))
+
+	customerJWT, _ := auth.GenerateJWT(testEnv.SecretKey, testEnv.ExpiresAt, td.PeterCustomer.Id)
+	freezeAddressRequest := handlers.FreezeAddressRequest{CustomerId: td.PeterCustomer.Id, AddressId: td.PeterAddress1.Id}
+	cases := map[string]*http.Request{
+		"freeze with invalid JWT":  handlers.NewFreezeAddressRequest("thisIsAnInvalidJWT", freezeAddressRequest),
+		"freeze with customer JWT": handlers.NewFreezeAddressRequest(customerJWT, freezeAddressRequest),
+		"segment members":          handlers.NewInternalGetSegmentMembersRequest(customerJWT, url.Values{"segment": {"vip"}}),
+		"customer segments":        handlers.NewInternalGetCustomerSegmentsRequest(customerJWT, td.PeterCustomer.Id),
+	}
+
+	for name, request := range cases {
+		t.Run(name, func(t *testing.T) {
+			response := httptest.NewRecorder()
+
+			server.ServeHTTP(response, request)
+
+			testutil.AssertStatus(t, response.Code, http.StatusUnauthorized)
+		})
+	}
+}
+
+func TestInternalServerRefusesEmptySecret(t *testing.T) {
+	defer func() {
+		testutil.AssertEqual(t, recover(), any(handlers.ErrMissingSecretKey))
+	}()
+
+	handlers.NewInternalServer(nil, testutil.NewStubCustomerStore(nil), testutil.NewStubAddressStore(nil), testutil.NewStubHouseholdStore(nil, nil, nil), testutil.NewStubSegmentStore(nil, nil, nil))
+}
+
+func TestFreezeAddress(t *testing.T) {
+	addressData := []models.Address{td.PeterAddress1, td.PeterAddress2, td.AliceAddress}
+	stubAddressStore := testutil.NewStubAddressStore(addressData)
+	server := handlers.NewInternalServer(testEnv.InternalSecretKey, testutil.NewStubCustomerStore(nil), stubAddressStore, testutil.NewStubHouseholdStore(nil, nil, nil), testutil.NewStubSegmentStore(nil, nil, nil))
+
+	internalJWT, _ := auth.GenerateJWT(testEnv.InternalSecretKey, testEnv.ExpiresAt, 1)
+
+	t.Run("freezes the current revision of an address", func(t *testing.T) {
+		request := handlers.NewFreezeAddressRequest(internalJWT, handlers.FreezeAddressRequest{
+			CustomerId: td.PeterCustomer.Id,
+			AddressId:  td.PeterAddress1.Id,
+		})
+		response := httptest.NewRecorder()
+
+		server.ServeHTTP(response, request)
+
+		testutil.AssertStatus(t, response.Code, http.StatusOK)
+
+		var got handlers.AddressSnapshotResponse
+		json.NewDecoder(response.Body).Decode(&got)
+		testutil.AssertEqual(t, got.AddressId, td.PeterAddress1.Id)
+		testutil.AssertEqual(t, got.Revision, td.PeterAddress1.Version)
+		testutil.AssertEqual(t, got.AddressLine1, td.PeterAddress1.AddressLine1)
+		if got.FrozenAt == nil {
+			t.Fatal("expected revision to be frozen")
+		}
+	})
+
+	t.Run("keeps frozen revision after the address is deleted", func(t *testing.T) {
+		request := handlers.NewFreezeAddressRequest(internalJWT, handlers.FreezeAddressRequest{
+			CustomerId: td.PeterCustomer.Id,
+			AddressId:  td.PeterAddress2.Id,
+			Revision:   td.PeterAddress2.Version,
+		})
+		response := httptest.NewRecorder()
+
+		server.ServeHTTP(response, request)
+
+		testutil.AssertStatus(t, response.Code, http.StatusOK)
+
+		err := stubAddressStore.DeleteAddress(td.PeterAddress2.Id, td.PeterAddress2.Version)
+		if err != nil {
+			t.Fatalf("couldn't delete address: %v", err)
+		}
+
+		_, err = stubAddressStore.GetAddressRevision(td.PeterAddress2.Id, td.PeterAddress2.Version)
+		if err != nil {
+			t.Fatalf("expected frozen revision to be kept: %v", err)
+		}
+	})
+
+	t.Run("returns Unathorized on another customer's address", func(t *testing.T) {
+		request := handlers.NewFreezeAddressRequest(internalJWT, handlers.FreezeAddressRequest{
+			CustomerId: td.PeterCustomer.Id,
+			AddressId:  td.AliceAddress.Id,
+		})
+		response := httptest.NewRecorder()
+
+		server.ServeHTTP(response, request)
+
+		testutil.AssertStatus(t, response.Code, http.StatusUnauthorized)
+		testutil.AssertErrorResponse(t, response.Body, handlers.ErrUnathorizedAction)
+	})
+
+	t.Run("returns Not Found on missing revision", func(t *testing.T) {
+		request := handlers.NewFreezeAddressRequest(internalJWT, handlers.FreezeAddressRequest{
+			CustomerId: td.PeterCustomer.Id,
+			AddressId:  td.PeterAddress1.Id,
+			Revision:   10,
+		})
+		response := httptest.NewRecorder()
+
+		server.ServeHTTP(response, request)
+
+		testutil.AssertStatus(t, response.Code, http.StatusNotFound)
+		testutil.AssertErrorResponse(t, response.Body, handlers.ErrMissingRevision)
+	})
+
+	t.Run("returns Not Found on missing address", func(t *testing.T) {
+		request := handlers.NewFreezeAddressRequest(internalJWT, handlers.FreezeAddressRequest{
+			CustomerId: td.PeterCustomer.Id,
+			AddressId:  10,
+		})
+		response := httptest.NewRecorder()
+
+		server.ServeHTTP(response, request)
+
+		testutil.AssertStatus(t, response.Code, http.StatusNotFound)
+		testutil.AssertErrorResponse(t, response.Body, handlers.ErrMissingAddress)
+	})
+}
+
+func TestInternalSegments(t *testing.T) {
+	anonymized := td.AliceCustomer
+	anonymized.Status = models.ANONYMIZED_STATUS
+	customerStore := testutil.NewStubCustomerStore([]models.Customer{td.PeterCustomer, anonymized})
+	segments := []models.Segment{{Id: 1, Name: "vip"}, {Id: 2, Name: "sofia"}}
+	members := map[string][]int{"vip": {td.PeterCustomer.Id}, "sofia": {td.PeterCustomer.Id}}
+	server := handlers.NewInternalServer(testEnv.InternalSecretKey, customerStore, testutil.NewStubAddressStore(nil),
+		testutil.NewStubHouseholdStore(nil, nil, nil), testutil.NewStubSegmentStore(nil, segments, members))
+
+	internalJWT, _ := auth.GenerateJWT(testEnv.InternalSecretKey, testEnv.ExpiresAt, 1)
+
+	t.Run("lists segment members", func(t *testing.T) {
+		request := handlers.NewInternalGetSegmentMembersRequest(internalJWT, url.Values{"segment": {"vip"}})
+		response := httptest.NewRecorder()
+
+		server.ServeHTTP(response, request)
+
+		testutil.AssertStatus(t, response.Code, http.StatusOK)
+
+		var got handlers.SegmentMembersResponse
+		json.NewDecoder(response.Body).Decode(&got)
+
+		testutil.AssertEqual(t, got, handlers.SegmentMembersResponse{Segment: "vip", CustomerIds: []int{td.PeterCustomer.Id}})
+	})
+
+	t.Run("lists the segments of a customer", func(t *testing.T) {
+		request := handlers.NewInternalGetCustomerSegmentsRequest(internalJWT, td.PeterCustomer.Id)
+		response := httptest.NewRecorder()
+
+		server.ServeHTTP(response, request)
+
+		testutil.AssertStatus(t, response.Code, http.StatusOK)
+
+		var got handlers.CustomerSegmentsResponse
+		json.NewDecoder(response.Body).Decode(&got)
+
+		testutil.AssertEqual(t, got, handlers.CustomerSegmentsResponse{CustomerId: td.PeterCustomer.Id, Segments: []string{"vip", "sofia"}})
+	})
+
+	t.Run("returns Not Found on anonymized customer", func(t *testing.T) {
+		request := handlers.NewInternalGetCustomerSegmentsRequest(internalJWT, anonymized.Id)
+		response := httptest.NewRecorder()
+
+		server.ServeHTTP(response, request)
+
+		testutil.AssertStatus(t, response.Code, http.StatusNotFound)
+		testutil.AssertErrorResponse(t, response.Body, handlers.ErrCustomerNotFound)
+	})
+
+	t.Run("returns Not Found on missing segment", func(t *testing.T) {
+		request := handlers.NewInternalGetSegmentMembersRequest(internalJWT, url.Values{"segment": {"varna"}})
+		response := httptest.NewRecorder()
+
+		server.ServeHTTP(response, request)
+
+		testutil.AssertStatus(t, response.Code, http.StatusNotFound)
+		testutil.AssertErrorResponse(t, response.Body, handlers.ErrSegmentNotFound)
+	})
+}
@@ -0,0 +1,9 @@
+package handlers
+
+// FreezeAddressRequest freezes the address an order delivers to. A zero
+// Revision freezes the current revision of the address.
+type FreezeAddressRequest struct {
+	CustomerId int `validate:"required"`
+	AddressId  int `validate:"required"`
+	Revision   int `validate:"min=0"`
+}
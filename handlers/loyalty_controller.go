@@ -0,0 +1,62 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/VitoNaychev/bt-customer-svc/models"
+)
+
+func (l *LoyaltyServer) getBalance(w http.ResponseWriter, r *http.Request) {
+	customerId, _ := strconv.Atoi(r.Header["Subject"][0])
+
+	_, err := getActiveCustomer(l.customerStore, customerId)
+	if err != nil {
+		handleStoreError(w, err)
+		return
+	}
+
+	balance, err := l.loyaltyStore.GetBalance(customerId)
+	if err != nil {
+		writeJSONError(w, http.StatusInternalServerError, ErrDatabaseError)
+		return
+	}
+
+	json.NewEncoder(w).Encode(BalanceResponse{Balance: balance})
+}
+
+func (l *LoyaltyServer) getTransactions(w http.ResponseWriter, r *http.Request) {
+	customerId, _ := strconv.Atoi(r.Header["Subject"][0])
+
+	_, err := getActiveCustomer(l.customerStore, customerId)
+	if err != nil {
+		handleStoreError(w, err)
+		return
+	}
+
+	transactions, err := l.loyaltyStore.GetTransactions(customerId)
+	if err != nil {
+		writeJSONError(w, http.StatusInternalServerError, ErrDatabaseError)
+		return
+	}
+
+	transactionsResponse := []LoyaltyTransactionResponse{}
+	for _, transaction := range transactions {
+		transactionsResponse = append(transactionsResponse, LoyaltyTransactionToLoyaltyTransactionResponse(transaction))
+	}
+
+	json.NewEncoder(w).Encode(transactionsResponse)
+}
+
+func handleLoyaltyStoreError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, models.ErrInsufficientPoints):
+		writeJSONError(w, http.StatusConflict, ErrInsufficientPoints)
+	case errors.Is(err, models.ErrTransactionConflict):
+		writeJSONError(w, http.StatusConflict, ErrTransactionConflict)
+	default:
+		writeJSONError(w, http.StatusInternalServerError, ErrDatabaseError)
+	}
+}
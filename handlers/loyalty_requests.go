@@ -0,0 +1,19 @@
+package handlers
+
+import (
+	"net/http"
+)
+
+func NewGetBalanceRequest(customerJWT string) *http.Request {
+	request, _ := http.NewRequest(http.MethodGet, "/customer/loyalty/balance/", nil)
+	request.Header.Add("Token", customerJWT)
+
+	return request
+}
+
+func NewGetTransactionsRequest(customerJWT string) *http.Request {
+	request, _ := http.NewRequest(http.MethodGet, "/customer/loyalty/transactions/", nil)
+	request.Header.Add("Token", customerJWT)
+
+	return request
+}
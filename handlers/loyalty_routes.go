@@ -0,0 +1,45 @@
+package handlers
+
+import (
+	"net/http"
+
+	"github.com/VitoNaychev/auth"
+	"github.com/VitoNaychev/bt-customer-svc/models"
+)
+
+type LoyaltyServer struct {
+	loyaltyStore  models.LoyaltyStore
+	customerStore models.CustomerStore
+	secretKey     []byte
+	http.Handler
+}
+
+func NewLoyaltyServer(loyaltyStore models.LoyaltyStore, customerStore models.CustomerStore, secretKey []byte) *LoyaltyServer {
+	l := new(LoyaltyServer)
+
+	l.loyaltyStore = loyaltyStore
+	l.customerStore = customerStore
+	l.secretKey = secretKey
+
+	router := http.NewServeMux()
+	router.HandleFunc("/customer/loyalty/balance/", l.BalanceHandler)
+	router.HandleFunc("/customer/loyalty/transactions/", l.TransactionsHandler)
+
+	l.Handler = router
+
+	return l
+}
+
+func (l *LoyaltyServer) BalanceHandler(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+		auth.AuthenticationMiddleware(l.getBalance, l.secretKey)(w, r)
+	}
+}
+
+func (l *LoyaltyServer) TransactionsHandler(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+		auth.AuthenticationMiddleware(l.getTransactions, l.secretKey)(w, r)
+	}
+}
@@ -0,0 +1,100 @@
+package handlers_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/VitoNaychev/auth"
+	"github.com/VitoNaychev/bt-customer-svc/handlers"
+	"github.com/VitoNaychev/bt-customer-svc/models"
+	td "github.com/VitoNaychev/bt-customer-svc/testdata"
+	"github.com/VitoNaychev/bt-customer-svc/testutil"
+)
+
+func TestLoyaltyEndpointAuthentication(t *testing.T) {
+	customerData := []models.Customer{td.PeterCustomer, td.AliceCustomer}
+	server := handlers.NewLoyaltyServer(testutil.NewStubLoyaltyStore(nil), testutil.NewStubCustomerStore(customerData), testEnv.SecretKey)
+
+	invalidJWT := "thisIsAnInvalidJWT"
+	cases := map[string]*http.Request{
+		"get balance authentication":      handlers.NewGetBalanceRequest(invalidJWT),
+		"get transactions authentication": handlers.NewGetTransactionsRequest(invalidJWT),
+	}
+
+	for name, request := range cases {
+		t.Run(name, func(t *testing.T) {
+			response := httptest.NewRecorder()
+
+			server.ServeHTTP(response, request)
+
+			testutil.AssertStatus(t, response.Code, http.StatusUnauthorized)
+		})
+	}
+}
+
+func TestGetLoyaltyBalance(t *testing.T) {
+	loyaltyData := []models.LoyaltyTransaction{td.PeterEarnedPoints, td.PeterRedeemedPoints}
+	customerData := []models.Customer{td.PeterCustomer, td.AliceCustomer}
+	server := handlers.NewLoyaltyServer(testutil.NewStubLoyaltyStore(loyaltyData), testutil.NewStubCustomerStore(customerData), testEnv.SecretKey)
+
+	cases := map[string]struct {
+		customer models.Customer
+		want     int
+	}{
+		"returns Peter's balance":              {td.PeterCustomer, 70},
+		"returns zero balance for new members": {td.AliceCustomer, 0},
+	}
+
+	for name, test := range cases {
+		t.Run(name, func(t *testing.T) {
+			customerJWT, _ := auth.GenerateJWT(testEnv.SecretKey, testEnv.ExpiresAt, test.customer.Id)
+			request := handlers.NewGetBalanceRequest(customerJWT)
+			response := httptest.NewRecorder()
+
+			server.ServeHTTP(response, request)
+
+			testutil.AssertStatus(t, response.Code, http.StatusOK)
+
+			var got handlers.BalanceResponse
+			json.NewDecoder(response.Body).Decode(&got)
+
+			testutil.AssertEqual(t, got, handlers.BalanceResponse{Balance: test.want})
+		})
+	}
+
+	t.Run("returns Not Found on missing customer", func(t *testing.T) {
+		missingJWT, _ := auth.GenerateJWT(testEnv.SecretKey, testEnv.ExpiresAt, 10)
+		request := handlers.NewGetBalanceRequest(missingJWT)
+		response := httptest.NewRecorder()
+
+		server.ServeHTTP(response, request)
+
+		testutil.AssertStatus(t, response.Code, http.StatusNotFound)
+		testutil.AssertErrorResponse(t, response.Body, handlers.ErrCustomerNotFound)
+	})
+}
+
+func TestGetLoyaltyTransactions(t *testing.T) {
+	loyaltyData := []models.LoyaltyTransaction{td.PeterEarnedPoints, td.PeterRedeemedPoints}
+	customerData := []models.Customer{td.PeterCustomer, td.AliceCustomer}
+	server := handlers.NewLoyaltyServer(testutil.NewStubLoyaltyStore(loyaltyData), testutil.NewStubCustomerStore(customerData), testEnv.SecretKey)
+
+	peterJWT, _ := auth.GenerateJWT(testEnv.SecretKey, testEnv.ExpiresAt, td.PeterCustomer.Id)
+	request := handlers.NewGetTransactionsRequest(peterJWT)
+	response := httptest.NewRecorder()
+
+	server.ServeHTTP(response, request)
+
+	testutil.AssertStatus(t, response.Code, http.StatusOK)
+
+	want := []handlers.LoyaltyTransactionResponse{
+		handlers.LoyaltyTransactionToLoyaltyTransactionResponse(td.PeterEarnedPoints),
+		handlers.LoyaltyTransactionToLoyaltyTransactionResponse(td.PeterRedeemedPoints),
+	}
+	var got []handlers.LoyaltyTransactionResponse
+	json.NewDecoder(response.Body).Decode(&got)
+
+	testutil.AssertEqual(t, got, want)
+}
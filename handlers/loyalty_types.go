@@ -0,0 +1,70 @@
+package handlers
+
+import (
+	"time"
+
+	"github.com/VitoNaychev/bt-customer-svc/models"
+)
+
+type BalanceResponse struct {
+	Balance int
+}
+
+type LoyaltyTransactionResponse struct {
+	Id          string
+	Kind        string
+	Points      int
+	Description string
+	ExpiresAt   *time.Time
+	CreatedAt   time.Time
+}
+
+func LoyaltyTransactionToLoyaltyTransactionResponse(transaction models.LoyaltyTransaction) LoyaltyTransactionResponse {
+	loyaltyTransactionResponse := LoyaltyTransactionResponse{
+		Id:          transaction.Id,
+		Kind:        transaction.Kind,
+		Points:      transaction.Points,
+		Description: transaction.Description,
+		ExpiresAt:   transaction.ExpiresAt,
+		CreatedAt:   transaction.CreatedAt,
+	}
+
+	return loyaltyTransactionResponse
+}
+
+// RecordTransactionRequest is sent by admins and other services. Points
+// are the amount earned or redeemed, or the signed change of the balance
+// for adjustments.
+type RecordTransactionRequest struct {
+	Id          string `validate:"required,max=64"`
+	CustomerId  int    `validate:"min=1"`
+	Kind        string `validate:"oneof=earn redeem adjust"`
+	Points      int    `validate:"required,min=-1000000,max=1000000"`
+	Description string `validate:"max=255"`
+}
+
+func RecordTransactionRequestToLoyaltyTransaction(recordTransactionRequest RecordTransactionRequest, now time.Time) (models.LoyaltyTransaction, error) {
+	transaction := models.LoyaltyTransaction{
+		Id:          recordTransactionRequest.Id,
+		CustomerId:  recordTransactionRequest.CustomerId,
+		Kind:        recordTransactionRequest.Kind,
+		Points:      recordTransactionRequest.Points,
+		Description: recordTransactionRequest.Description,
+	}
+
+	switch transaction.Kind {
+	case models.EARN_TRANSACTION:
+		if transaction.Points < 0 {
+			return models.LoyaltyTransaction{}, ErrNegativePoints
+		}
+		expiresAt := now.Add(models.POINTS_LIFETIME)
+		transaction.ExpiresAt = &expiresAt
+	case models.REDEEM_TRANSACTION:
+		if transaction.Points < 0 {
+			return models.LoyaltyTransaction{}, ErrNegativePoints
+		}
+		transaction.Points = -transaction.Points
+	}
+
+	return transaction, nil
+}
@@ -0,0 +1,73 @@
+package handlers
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"reflect"
+	"strings"
+
+	"github.com/VitoNaychev/validation"
+)
+
+const maxPatchSize = 10000
+
+// parseMergePatch decodes an RFC 7396 JSON merge patch into T, a struct of
+// pointer fields. Members missing from the document stay nil, so only the
+// members that are present get validated. A null member removes the field
+// from the target, which is only allowed for the fields listed in nullable;
+// those are set to their zero value.
+func parseMergePatch[T any](body io.Reader, nullable ...string) (T, error) {
+	var patch T
+
+	if body == nil {
+		return patch, ErrNoBody
+	}
+
+	content, _ := io.ReadAll(io.LimitReader(body, maxPatchSize))
+	if len(bytes.TrimSpace(content)) == 0 {
+		return patch, ErrEmptyBody
+	}
+
+	var members map[string]json.RawMessage
+	if err := json.Unmarshal(content, &members); err != nil || members == nil {
+		return patch, ErrIncorrectRequestType
+	}
+
+	decoder := json.NewDecoder(bytes.NewReader(content))
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(&patch); err != nil {
+		return patch, ErrIncorrectRequestType
+	}
+
+	patchValue := reflect.ValueOf(&patch).Elem()
+	for name, value := range members {
+		if string(bytes.TrimSpace(value)) != "null" {
+			continue
+		}
+
+		if !containsFold(nullable, name) {
+			return patch, ErrNullField
+		}
+
+		field := patchValue.FieldByNameFunc(func(fieldName string) bool {
+			return strings.EqualFold(fieldName, name)
+		})
+		field.Set(reflect.New(field.Type().Elem()))
+	}
+
+	if err := validation.ValidateStruct(patch); err != nil {
+		return patch, err
+	}
+
+	return patch, nil
+}
+
+func containsFold(names []string, name string) bool {
+	for _, candidate := range names {
+		if strings.EqualFold(candidate, name) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,105 @@
+package handlers
+
+import (
+	"fmt"
+	"hash/fnv"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/VitoNaychev/bt-customer-svc/models"
+)
+
+type PreconditionMode int
+
+const (
+	// LENIENT_PRECONDITIONS checks If-Match only when the client sends it.
+	LENIENT_PRECONDITIONS PreconditionMode = iota
+	// STRICT_PRECONDITIONS rejects updates and deletes without If-Match.
+	STRICT_PRECONDITIONS
+)
+
+func versionETag(version int) string {
+	return `"` + strconv.Itoa(version) + `"`
+}
+
+func addressesETag(addresses []models.Address) string {
+	hash := fnv.New64a()
+	for _, address := range addresses {
+		fmt.Fprintf(hash, "%d:%d;", address.Id, address.Version)
+	}
+	return fmt.Sprintf(`"%x"`, hash.Sum64())
+}
+
+// checkIfMatch verifies the If-Match precondition of an update or delete
+// against the current ETag of the resource. On failure it writes the error
+// response and returns false.
+func checkIfMatch(w http.ResponseWriter, r *http.Request, etag string, mode PreconditionMode) bool {
+	ifMatch := r.Header.Get("If-Match")
+	if ifMatch == "" {
+		if mode == STRICT_PRECONDITIONS {
+			writeJSONError(w, http.StatusPreconditionRequired, ErrPreconditionRequired)
+			return false
+		}
+		return true
+	}
+
+	if !etagListMatches(ifMatch, etag, false) {
+		writeJSONError(w, http.StatusPreconditionFailed, ErrPreconditionFailed)
+		return false
+	}
+
+	return true
+}
+
+// checkIfNoneMatch sets the ETag of a read response and answers with
+// 304 Not Modified when the client already has the current representation.
+// It returns true when the response has been written.
+func checkIfNoneMatch(w http.ResponseWriter, r *http.Request, etag string) bool {
+	w.Header().Set("ETag", etag)
+
+	ifNoneMatch := r.Header.Get("If-None-Match")
+	if ifNoneMatch != "" && etagListMatches(ifNoneMatch, etag, true) {
+		w.WriteHeader(http.StatusNotModified)
+		return true
+	}
+
+	return false
+}
+
+// etagListMatches compares an If-Match or If-None-Match header value against
+// an ETag. Weak validators only match when weak comparison is allowed.
+func etagListMatches(header string, etag string, weak bool) bool {
+	for _, candidate := range strings.Split(header, ",") {
+		candidate = strings.TrimSpace(candidate)
+		if candidate == "*" {
+			return true
+		}
+
+		if strings.HasPrefix(candidate, "W/") {
+			if !weak {
+				continue
+			}
+			candidate = strings.TrimPrefix(candidate, "W/")
+		}
+
+		if candidate == etag {
+			return true
+		}
+	}
+
+	return false
+}
+
+func handleVersionedStoreError(w http.ResponseWriter, err error) {
+	if handleConstraintError(w, err) {
+		return
+	}
+
+	if err == models.ErrVersionConflict {
+		writeJSONError(w, http.StatusPreconditionFailed, ErrPreconditionFailed)
+		return
+	}
+
+	writeJSONError(w, http.StatusInternalServerError, ErrDatabaseError)
+}
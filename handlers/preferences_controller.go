@@ -0,0 +1,57 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/VitoNaychev/bt-customer-svc/models"
+	"github.com/VitoNaychev/validation"
+)
+
+func (p *PreferencesServer) getPreferences(w http.ResponseWriter, r *http.Request) {
+	customerId, _ := strconv.Atoi(r.Header["Subject"][0])
+
+	_, err := getActiveCustomer(p.customerStore, customerId)
+	if err != nil {
+		handleStoreError(w, err)
+		return
+	}
+
+	preferences, err := p.preferencesStore.GetPreferences(customerId)
+	if errors.Is(err, models.ErrNotFound) {
+		preferences = models.DefaultPreferences(customerId)
+	} else if err != nil {
+		writeJSONError(w, http.StatusInternalServerError, ErrDatabaseError)
+		return
+	}
+
+	json.NewEncoder(w).Encode(PreferencesToPreferencesResponse(preferences))
+}
+
+func (p *PreferencesServer) updatePreferences(w http.ResponseWriter, r *http.Request) {
+	updatePreferencesRequest, err := validation.ValidateBody[UpdatePreferencesRequest](r.Body)
+	if err != nil {
+		writeJSONError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	customerId, _ := strconv.Atoi(r.Header["Subject"][0])
+
+	_, err = getActiveCustomer(p.customerStore, customerId)
+	if err != nil {
+		handleStoreError(w, err)
+		return
+	}
+
+	preferences := UpdatePreferencesRequestToPreferences(updatePreferencesRequest, customerId)
+
+	err = p.preferencesStore.UpsertPreferences(&preferences)
+	if err != nil {
+		writeJSONError(w, http.StatusInternalServerError, ErrDatabaseError)
+		return
+	}
+
+	json.NewEncoder(w).Encode(PreferencesToPreferencesResponse(preferences))
+}
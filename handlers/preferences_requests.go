@@ -0,0 +1,27 @@
+package handlers
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+
+	"github.com/VitoNaychev/bt-customer-svc/models"
+)
+
+func NewGetPreferencesRequest(customerJWT string) *http.Request {
+	request, _ := http.NewRequest(http.MethodGet, "/customer/preferences/", nil)
+	request.Header.Add("Token", customerJWT)
+
+	return request
+}
+
+func NewUpdatePreferencesRequest(customerJWT string, preferences models.Preferences) *http.Request {
+	updatePreferencesRequest := PreferencesToUpdatePreferencesRequest(preferences)
+	body := bytes.NewBuffer([]byte{})
+	json.NewEncoder(body).Encode(updatePreferencesRequest)
+
+	request, _ := http.NewRequest(http.MethodPut, "/customer/preferences/", body)
+	request.Header.Add("Token", customerJWT)
+
+	return request
+}
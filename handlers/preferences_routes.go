@@ -0,0 +1,33 @@
+package handlers
+
+import (
+	"net/http"
+
+	"github.com/VitoNaychev/auth"
+	"github.com/VitoNaychev/bt-customer-svc/models"
+)
+
+type PreferencesServer struct {
+	preferencesStore models.PreferencesStore
+	customerStore    models.CustomerStore
+	secretKey        []byte
+}
+
+func NewPreferencesServer(preferencesStore models.PreferencesStore, customerStore models.CustomerStore, secretKey []byte) *PreferencesServer {
+	preferencesServer := PreferencesServer{
+		preferencesStore: preferencesStore,
+		customerStore:    customerStore,
+		secretKey:        secretKey,
+	}
+
+	return &preferencesServer
+}
+
+func (p *PreferencesServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+		auth.AuthenticationMiddleware(p.getPreferences, p.secretKey)(w, r)
+	case http.MethodPut:
+		auth.AuthenticationMiddleware(p.updatePreferences, p.secretKey)(w, r)
+	}
+}
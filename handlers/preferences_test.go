@@ -0,0 +1,130 @@
+package handlers_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/VitoNaychev/auth"
+	"github.com/VitoNaychev/bt-customer-svc/handlers"
+	"github.com/VitoNaychev/bt-customer-svc/models"
+	td "github.com/VitoNaychev/bt-customer-svc/testdata"
+	"github.com/VitoNaychev/bt-customer-svc/testutil"
+)
+
+func TestPreferencesEndpointAuthentication(t *testing.T) {
+	customerData := []models.Customer{td.PeterCustomer, td.AliceCustomer}
+	stubPreferencesStore := testutil.NewStubPreferencesStore(nil)
+	stubCustomerStore := testutil.NewStubCustomerStore(customerData)
+	server := handlers.NewPreferencesServer(stubPreferencesStore, stubCustomerStore, testEnv.SecretKey)
+
+	invalidJWT := "thisIsAnInvalidJWT"
+	cases := map[string]*http.Request{
+		"get preferences authentication":    handlers.NewGetPreferencesRequest(invalidJWT),
+		"update preferences authentication": handlers.NewUpdatePreferencesRequest(invalidJWT, models.Preferences{}),
+	}
+
+	for name, request := range cases {
+		t.Run(name, func(t *testing.T) {
+			response := httptest.NewRecorder()
+
+			server.ServeHTTP(response, request)
+
+			testutil.AssertStatus(t, response.Code, http.StatusUnauthorized)
+		})
+	}
+}
+
+func TestGetPreferences(t *testing.T) {
+	preferencesData := []models.Preferences{td.AlicePreferences}
+	customerData := []models.Customer{td.PeterCustomer, td.AliceCustomer}
+	stubPreferencesStore := testutil.NewStubPreferencesStore(preferencesData)
+	stubCustomerStore := testutil.NewStubCustomerStore(customerData)
+	server := handlers.NewPreferencesServer(stubPreferencesStore, stubCustomerStore, testEnv.SecretKey)
+
+	t.Run("returns Alice's saved preferences", func(t *testing.T) {
+		aliceJWT, _ := auth.GenerateJWT(testEnv.SecretKey, testEnv.ExpiresAt, td.AliceCustomer.Id)
+		request := handlers.NewGetPreferencesRequest(aliceJWT)
+		response := httptest.NewRecorder()
+
+		server.ServeHTTP(response, request)
+
+		testutil.AssertStatus(t, response.Code, http.StatusOK)
+
+		want := handlers.PreferencesToPreferencesResponse(td.AlicePreferences)
+		var got handlers.PreferencesResponse
+		json.NewDecoder(response.Body).Decode(&got)
+
+		testutil.AssertEqual(t, got, want)
+	})
+
+	t.Run("returns default preferences for Peter", func(t *testing.T) {
+		peterJWT, _ := auth.GenerateJWT(testEnv.SecretKey, testEnv.ExpiresAt, td.PeterCustomer.Id)
+		request := handlers.NewGetPreferencesRequest(peterJWT)
+		response := httptest.NewRecorder()
+
+		server.ServeHTTP(response, request)
+
+		testutil.AssertStatus(t, response.Code, http.StatusOK)
+
+		want := handlers.PreferencesToPreferencesResponse(models.DefaultPreferences(td.PeterCustomer.Id))
+		var got handlers.PreferencesResponse
+		json.NewDecoder(response.Body).Decode(&got)
+
+		testutil.AssertEqual(t, got, want)
+	})
+
+	t.Run("returns Not Found on missing customer", func(t *testing.T) {
+		missingJWT, _ := auth.GenerateJWT(testEnv.SecretKey, testEnv.ExpiresAt, 10)
+		request := handlers.NewGetPreferencesRequest(missingJWT)
+		response := httptest.NewRecorder()
+
+		server.ServeHTTP(response, request)
+
+		testutil.AssertStatus(t, response.Code, http.StatusNotFound)
+		testutil.AssertErrorResponse(t, response.Body, handlers.ErrCustomerNotFound)
+	})
+}
+
+func TestUpdatePreferences(t *testing.T) {
+	customerData := []models.Customer{td.PeterCustomer, td.AliceCustomer}
+	stubPreferencesStore := testutil.NewStubPreferencesStore(nil)
+	stubCustomerStore := testutil.NewStubCustomerStore(customerData)
+	server := handlers.NewPreferencesServer(stubPreferencesStore, stubCustomerStore, testEnv.SecretKey)
+
+	aliceJWT, _ := auth.GenerateJWT(testEnv.SecretKey, testEnv.ExpiresAt, td.AliceCustomer.Id)
+
+	cases := map[string]func(*models.Preferences){
+		"unsupported language":      func(p *models.Preferences) { p.Language = "xx-XX" },
+		"unsupported currency":      func(p *models.Preferences) { p.Currency = "JPY" },
+		"unknown time zone":         func(p *models.Preferences) { p.TimeZone = "Europe/Atlantis" },
+		"unknown message type":      func(p *models.Preferences) { p.Notifications["newsletter"] = []string{models.EMAIL_CHANNEL} },
+		"unknown channel":           func(p *models.Preferences) { p.Notifications[models.ACCOUNT_MESSAGES] = []string{"pigeon"} },
+		"duplicate channel entries": func(p *models.Preferences) { p.Notifications[models.ACCOUNT_MESSAGES] = []string{"sms", "sms"} },
+	}
+
+	for name, modify := range cases {
+		t.Run("returns Bad Request on "+name, func(t *testing.T) {
+			preferences := models.DefaultPreferences(td.AliceCustomer.Id)
+			modify(&preferences)
+
+			request := handlers.NewUpdatePreferencesRequest(aliceJWT, preferences)
+			response := httptest.NewRecorder()
+
+			server.ServeHTTP(response, request)
+
+			testutil.AssertStatus(t, response.Code, http.StatusBadRequest)
+		})
+	}
+
+	t.Run("stores Alice's preferences", func(t *testing.T) {
+		request := handlers.NewUpdatePreferencesRequest(aliceJWT, td.AlicePreferences)
+		response := httptest.NewRecorder()
+
+		server.ServeHTTP(response, request)
+
+		testutil.AssertStatus(t, response.Code, http.StatusOK)
+		testutil.AssertUpsertedPreferences(t, stubPreferencesStore, td.AlicePreferences)
+	})
+}
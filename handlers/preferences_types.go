@@ -0,0 +1,51 @@
+package handlers
+
+import "github.com/VitoNaychev/bt-customer-svc/models"
+
+type PreferencesResponse struct {
+	Language      string
+	Currency      string
+	TimeZone      string
+	Notifications map[string][]string
+}
+
+func PreferencesToPreferencesResponse(preferences models.Preferences) PreferencesResponse {
+	preferencesResponse := PreferencesResponse{
+		Language:      preferences.Language,
+		Currency:      preferences.Currency,
+		TimeZone:      preferences.TimeZone,
+		Notifications: preferences.Notifications,
+	}
+
+	return preferencesResponse
+}
+
+type UpdatePreferencesRequest struct {
+	Language      string              `validate:"required,oneof=bg-BG en-GB en-US de-DE ro-RO el-GR"`
+	Currency      string              `validate:"required,oneof=BGN EUR USD GBP RON"`
+	TimeZone      string              `validate:"required,timezone"`
+	Notifications map[string][]string `validate:"required,dive,keys,oneof=order_updates promotions account,endkeys,unique,dive,oneof=email sms push"`
+}
+
+func PreferencesToUpdatePreferencesRequest(preferences models.Preferences) UpdatePreferencesRequest {
+	updatePreferencesRequest := UpdatePreferencesRequest{
+		Language:      preferences.Language,
+		Currency:      preferences.Currency,
+		TimeZone:      preferences.TimeZone,
+		Notifications: preferences.Notifications,
+	}
+
+	return updatePreferencesRequest
+}
+
+func UpdatePreferencesRequestToPreferences(updatePreferencesRequest UpdatePreferencesRequest, customerId int) models.Preferences {
+	preferences := models.Preferences{
+		CustomerId:    customerId,
+		Language:      updatePreferencesRequest.Language,
+		Currency:      updatePreferencesRequest.Currency,
+		TimeZone:      updatePreferencesRequest.TimeZone,
+		Notifications: updatePreferencesRequest.Notifications,
+	}
+
+	return preferences
+}
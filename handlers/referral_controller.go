@@ -0,0 +1,25 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+)
+
+func (r *ReferralServer) getReferralStats(w http.ResponseWriter, req *http.Request) {
+	customerId, _ := strconv.Atoi(req.Header["Subject"][0])
+
+	_, err := getActiveCustomer(r.customerStore, customerId)
+	if err != nil {
+		handleStoreError(w, err)
+		return
+	}
+
+	stats, err := r.referralStore.GetReferralStats(customerId)
+	if err != nil {
+		writeJSONError(w, http.StatusInternalServerError, ErrDatabaseError)
+		return
+	}
+
+	json.NewEncoder(w).Encode(ReferralStatsToReferralStatsResponse(stats))
+}
@@ -0,0 +1,10 @@
+package handlers
+
+import "net/http"
+
+func NewGetReferralStatsRequest(customerJWT string) *http.Request {
+	request, _ := http.NewRequest(http.MethodGet, "/customer/referrals/", nil)
+	request.Header.Add("Token", customerJWT)
+
+	return request
+}
@@ -0,0 +1,37 @@
+package handlers
+
+import (
+	"net/http"
+
+	"github.com/VitoNaychev/auth"
+	"github.com/VitoNaychev/bt-customer-svc/models"
+)
+
+type ReferralServer struct {
+	referralStore models.ReferralStore
+	customerStore models.CustomerStore
+	secretKey     []byte
+	http.Handler
+}
+
+func NewReferralServer(referralStore models.ReferralStore, customerStore models.CustomerStore, secretKey []byte) *ReferralServer {
+	r := new(ReferralServer)
+
+	r.referralStore = referralStore
+	r.customerStore = customerStore
+	r.secretKey = secretKey
+
+	router := http.NewServeMux()
+	router.HandleFunc("/customer/referrals/", r.ReferralsHandler)
+
+	r.Handler = router
+
+	return r
+}
+
+func (r *ReferralServer) ReferralsHandler(w http.ResponseWriter, req *http.Request) {
+	switch req.Method {
+	case http.MethodGet:
+		auth.AuthenticationMiddleware(r.getReferralStats, r.secretKey)(w, req)
+	}
+}
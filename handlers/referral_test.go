@@ -0,0 +1,142 @@
+package handlers_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/VitoNaychev/auth"
+	"github.com/VitoNaychev/bt-customer-svc/handlers"
+	"github.com/VitoNaychev/bt-customer-svc/models"
+	td "github.com/VitoNaychev/bt-customer-svc/testdata"
+	"github.com/VitoNaychev/bt-customer-svc/testutil"
+)
+
+const peterReferralCode = "PETE2345"
+
+var referralHashKey = []byte("testReferralHashKey")
+
+func TestSignupWithReferralCode(t *testing.T) {
+	newCustomer := models.Customer{
+		FirstName:   "Ivan",
+		LastName:    "Petrov",
+		PhoneNumber: "+359881112233",
+		Email:       "ivanpetrov@gmail.com",
+		Password:    "ivanivanivan",
+	}
+
+	newServer := func(customerData []models.Customer) (*handlers.CustomerServer, *testutil.StubCustomerStore) {
+		customerStore := testutil.NewStubCustomerStore(customerData)
+		referralStore := testutil.NewStubReferralStore(map[int]string{td.PeterCustomer.Id: peterReferralCode}, nil)
+		server := handlers.NewCustomerServer(testEnv.SecretKey, testEnv.ExpiresAt, customerStore, testutil.NewStubConsentStore(nil, nil), referralStore)
+		server.SetReferralHashKey(referralHashKey)
+
+		return server, customerStore
+	}
+
+	t.Run("credits the referrer", func(t *testing.T) {
+		server, customerStore := newServer([]models.Customer{td.PeterCustomer})
+
+		request := handlers.NewCreateCustomerRequestWithReferral(newCustomer, "pete-2345")
+		response := httptest.NewRecorder()
+
+		server.ServeHTTP(response, request)
+
+		testutil.AssertStatus(t, response.Code, http.StatusAccepted)
+		testutil.AssertSignupReferral(t, customerStore, td.PeterCustomer.Id, models.ACCEPTED_REFERRAL)
+	})
+
+	t.Run("doesn't credit signups with an alias of the referrer", func(t *testing.T) {
+		server, customerStore := newServer([]models.Customer{td.PeterCustomer})
+
+		alias := newCustomer
+		alias.Email = "pete.smith+new@gmail.com"
+
+		request := handlers.NewCreateCustomerRequestWithReferral(alias, peterReferralCode)
+		response := httptest.NewRecorder()
+
+		server.ServeHTTP(response, request)
+
+		testutil.AssertStatus(t, response.Code, http.StatusAccepted)
+		testutil.AssertSignupReferral(t, customerStore, td.PeterCustomer.Id, models.SELF_REFERRAL)
+	})
+
+	t.Run("returns Bad Request on unknown referral code", func(t *testing.T) {
+		server, _ := newServer([]models.Customer{td.PeterCustomer})
+
+		request := handlers.NewCreateCustomerRequestWithReferral(newCustomer, "ZZZZ2222")
+		response := httptest.NewRecorder()
+
+		server.ServeHTTP(response, request)
+
+		testutil.AssertStatus(t, response.Code, http.StatusBadRequest)
+		testutil.AssertFieldErrorResponse(t, response.Body, handlers.ErrInvalidReferralCode, "ReferralCode")
+	})
+
+	t.Run("returns Bad Request on code of an anonymized customer", func(t *testing.T) {
+		anonymized := td.PeterCustomer
+		anonymized.Status = models.ANONYMIZED_STATUS
+		server, _ := newServer([]models.Customer{anonymized})
+
+		request := handlers.NewCreateCustomerRequestWithReferral(newCustomer, peterReferralCode)
+		response := httptest.NewRecorder()
+
+		server.ServeHTTP(response, request)
+
+		testutil.AssertStatus(t, response.Code, http.StatusBadRequest)
+		testutil.AssertFieldErrorResponse(t, response.Body, handlers.ErrInvalidReferralCode, "ReferralCode")
+	})
+}
+
+func TestGetReferralStats(t *testing.T) {
+	customerData := []models.Customer{td.PeterCustomer, td.AliceCustomer}
+	referrals := []models.Referral{
+		models.NewReferral(td.PeterCustomer, td.AliceCustomer, peterReferralCode, referralHashKey),
+		{ReferrerId: td.PeterCustomer.Id, ReferredId: 3, Status: models.REUSED_IDENTITY_REFERRAL},
+	}
+	referralStore := testutil.NewStubReferralStore(map[int]string{td.PeterCustomer.Id: peterReferralCode}, referrals)
+	server := handlers.NewReferralServer(referralStore, testutil.NewStubCustomerStore(customerData), testEnv.SecretKey)
+
+	t.Run("returns Unauthorized on invalid JWT", func(t *testing.T) {
+		request := handlers.NewGetReferralStatsRequest("thisIsAnInvalidJWT")
+		response := httptest.NewRecorder()
+
+		server.ServeHTTP(response, request)
+
+		testutil.AssertStatus(t, response.Code, http.StatusUnauthorized)
+	})
+
+	t.Run("counts accepted referrals", func(t *testing.T) {
+		peterJWT, _ := auth.GenerateJWT(testEnv.SecretKey, testEnv.ExpiresAt, td.PeterCustomer.Id)
+		request := handlers.NewGetReferralStatsRequest(peterJWT)
+		response := httptest.NewRecorder()
+
+		server.ServeHTTP(response, request)
+
+		testutil.AssertStatus(t, response.Code, http.StatusOK)
+
+		var got handlers.ReferralStatsResponse
+		json.NewDecoder(response.Body).Decode(&got)
+
+		testutil.AssertEqual(t, got.Code, peterReferralCode)
+		testutil.AssertEqual(t, got.Referred, 1)
+	})
+
+	t.Run("generates a code on first use", func(t *testing.T) {
+		aliceJWT, _ := auth.GenerateJWT(testEnv.SecretKey, testEnv.ExpiresAt, td.AliceCustomer.Id)
+		request := handlers.NewGetReferralStatsRequest(aliceJWT)
+		response := httptest.NewRecorder()
+
+		server.ServeHTTP(response, request)
+
+		testutil.AssertStatus(t, response.Code, http.StatusOK)
+
+		var got handlers.ReferralStatsResponse
+		json.NewDecoder(response.Body).Decode(&got)
+
+		testutil.AssertEqual(t, len(got.Code), models.REFERRAL_CODE_LENGTH)
+		testutil.AssertEqual(t, got.Referred, 0)
+		testutil.AssertEqual(t, got.Code, models.NormalizeReferralCode(got.Code))
+	})
+}
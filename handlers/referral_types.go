@@ -0,0 +1,21 @@
+package handlers
+
+import (
+	"time"
+
+	"github.com/VitoNaychev/bt-customer-svc/models"
+)
+
+type ReferralStatsResponse struct {
+	Code           string
+	Referred       int
+	LastReferredAt *time.Time
+}
+
+func ReferralStatsToReferralStatsResponse(stats models.ReferralStats) ReferralStatsResponse {
+	return ReferralStatsResponse{
+		Code:           stats.Code,
+		Referred:       stats.Referred,
+		LastReferredAt: stats.LastReferredAt,
+	}
+}
@@ -5,6 +5,7 @@ import (
 )
 
 type RouterServer struct {
+	router *http.ServeMux
 	http.Handler
 }
 
@@ -15,7 +16,14 @@ func NewRouterServer(customerServer http.Handler, addressServer http.Handler) *R
 	router.Handle("/customer/", customerServer)
 	router.Handle("/customer/address/", addressServer)
 
+	routerServer.router = router
 	routerServer.Handler = router
 
 	return routerServer
 }
+
+// Handle mounts an additional server, e.g. the admin server, under the
+// given path prefix.
+func (r *RouterServer) Handle(pattern string, server http.Handler) {
+	r.router.Handle(pattern, server)
+}
@@ -13,6 +13,7 @@ import (
 
 var customerHandlerMessage = "Hello from customer handler"
 var addressHandlerMessage = "Hello from address handler"
+var adminHandlerMessage = "Hello from admin handler"
 
 func fakeCustomerHandler(w http.ResponseWriter, r *http.Request) {
 	w.WriteHeader(http.StatusAccepted)
@@ -25,11 +26,19 @@ func fakeAddressHandler(w http.ResponseWriter, r *http.Request) {
 
 }
 
+func fakeAdminHandler(w http.ResponseWriter, r *http.Request) {
+	w.WriteHeader(http.StatusAccepted)
+	w.Write([]byte(adminHandlerMessage))
+}
+
 func TestRouterServer(t *testing.T) {
 	fakeCustomerServer := http.HandlerFunc(fakeCustomerHandler)
 	fakeAddressServer := http.HandlerFunc(fakeAddressHandler)
 
+	fakeAdminServer := http.HandlerFunc(fakeAdminHandler)
+
 	routerServer := handlers.NewRouterServer(fakeCustomerServer, fakeAddressServer)
+	routerServer.Handle("/admin/", fakeAdminServer)
 
 	t.Run("routes requests to the customer server", func(t *testing.T) {
 		request, _ := http.NewRequest(http.MethodPost, "/customer/", nil)
@@ -59,6 +68,20 @@ func TestRouterServer(t *testing.T) {
 		assertHandlerMessage(t, got, want)
 	})
 
+	t.Run("routes requests to mounted servers", func(t *testing.T) {
+		request, _ := http.NewRequest(http.MethodPost, "/admin/customer/anonymize/", nil)
+		response := httptest.NewRecorder()
+
+		routerServer.ServeHTTP(response, request)
+
+		testutil.AssertStatus(t, response.Code, http.StatusAccepted)
+
+		want := adminHandlerMessage
+		got := getMessageFromBody(response.Body)
+
+		assertHandlerMessage(t, got, want)
+	})
+
 	t.Run("returns Not Found on unknown path", func(t *testing.T) {
 		request, _ := http.NewRequest(http.MethodPost, "/unknown/path", nil)
 		response := httptest.NewRecorder()
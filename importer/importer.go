@@ -0,0 +1,265 @@
+package importer
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"slices"
+	"strings"
+
+	"github.com/VitoNaychev/bt-customer-svc/handlers"
+	"github.com/VitoNaychev/bt-customer-svc/models"
+	"github.com/VitoNaychev/validation"
+)
+
+const DEFAULT_BATCH_SIZE = 1000
+
+var ErrSeveralDefaultAddresses = errors.New("more than one address is marked as default")
+
+// MAX_EMAIL_LENGTH is the length of the email columns, which the email rule
+// of CreateCustomerRequest doesn't bound.
+const MAX_EMAIL_LENGTH = 40
+
+type Report struct {
+	Source string
+	DryRun bool
+	// ResumedAfter is the checkpoint of an earlier run; records up to it
+	// were skipped.
+	ResumedAfter int
+	Skipped      int
+	Imported     int
+	Addresses    int
+	Rejected     int
+	Checkpoint   int
+}
+
+// Rejection is a line of the rejects file.
+type Rejection struct {
+	Line   int
+	Reason string
+	Record string
+}
+
+// Importer loads customers from a Reader in batches. Records are validated
+// with the rules of CreateCustomerRequest and CreateAddressRequest and
+// normalized like on signup; the ones that fail, or that repeat the phone
+// number or email of an earlier record or an existing customer, are written
+// to the rejects file instead. Consents and referral codes are not
+// imported, imported customers accept the documents on their first request.
+type Importer struct {
+	store     models.CustomerImportStore
+	rules     models.IdentityRules
+	rejects   *json.Encoder
+	batchSize int
+	dryRun    bool
+
+	phoneNumbers map[string]int
+	emails       map[string]int
+}
+
+func NewImporter(store models.CustomerImportStore, rules models.IdentityRules, rejects io.Writer) *Importer {
+	return &Importer{
+		store:        store,
+		rules:        rules,
+		rejects:      json.NewEncoder(rejects),
+		batchSize:    DEFAULT_BATCH_SIZE,
+		phoneNumbers: map[string]int{},
+		emails:       map[string]int{},
+	}
+}
+
+func (i *Importer) SetBatchSize(batchSize int) {
+	i.batchSize = max(batchSize, 1)
+}
+
+// SetDryRun makes the importer validate and insert every batch, but roll
+// it back, so the checkpoint doesn't move either.
+func (i *Importer) SetDryRun(dryRun bool) {
+	i.dryRun = dryRun
+}
+
+type pendingBatch struct {
+	customers  []models.ImportedCustomer
+	raw        map[int]string
+	rejections []Rejection
+	lastLine   int
+}
+
+// Run imports the records of reader under the name source. If an earlier
+// run of the same source was interrupted, the records it committed are
+// skipped. Every batch commits together with its checkpoint, and its
+// rejections are written once it committed, so a rerun neither imports
+// nor rejects a record twice.
+func (i *Importer) Run(source string, reader Reader) (Report, error) {
+	report := Report{Source: source, DryRun: i.dryRun}
+
+	checkpoint, err := i.store.GetImportCheckpoint(source)
+	if err != nil {
+		return report, err
+	}
+	report.ResumedAfter = checkpoint
+	report.Checkpoint = checkpoint
+
+	batch := pendingBatch{raw: map[int]string{}}
+	for {
+		record, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+
+		var recordErr *RecordError
+		if errors.As(err, &recordErr) {
+			if recordErr.Line <= checkpoint {
+				report.Skipped++
+				continue
+			}
+			batch.lastLine = recordErr.Line
+			batch.reject(recordErr.Line, recordErr.Raw, recordErr.Err.Error())
+			continue
+		}
+		if err != nil {
+			return report, err
+		}
+
+		if record.Line <= checkpoint {
+			report.Skipped++
+			continue
+		}
+		batch.lastLine = record.Line
+
+		imported, err := i.prepare(record)
+		if err != nil {
+			batch.reject(record.Line, record.Raw, err.Error())
+			continue
+		}
+		batch.customers = append(batch.customers, imported)
+		batch.raw[record.Line] = record.Raw
+
+		if len(batch.customers) >= i.batchSize {
+			if err = i.flush(source, &batch, &report); err != nil {
+				return report, err
+			}
+		}
+	}
+
+	if batch.lastLine > 0 {
+		if err = i.flush(source, &batch, &report); err != nil {
+			return report, err
+		}
+	}
+
+	return report, nil
+}
+
+// prepare validates a record and converts it to a normalized customer.
+func (i *Importer) prepare(record Record) (models.ImportedCustomer, error) {
+	if err := validation.ValidateStruct(record.Customer); err != nil {
+		return models.ImportedCustomer{}, errors.New(validationReason(err))
+	}
+
+	addresses := []models.Address{}
+	for n, createAddressRequest := range record.Addresses {
+		// files exported before countries were stored as codes have names
+		if code, ok := models.CountryCode(createAddressRequest.Country); ok {
+			createAddressRequest.Country = code
+		}
+		if err := validation.ValidateStruct(createAddressRequest); err != nil {
+			return models.ImportedCustomer{}, fmt.Errorf("address %d: %s", n+1, validationReason(err))
+		}
+		// imports aren't geocoded, so coordinates can't be left out
+		if createAddressRequest.Lat == 0 && createAddressRequest.Lon == 0 {
+			return models.ImportedCustomer{}, fmt.Errorf("address %d: %w", n+1, handlers.ErrMissingCoordinates)
+		}
+
+		address := handlers.CreateAddressRequestToAddress(createAddressRequest, 0)
+		models.NormalizePostalAddress(&address)
+		if err := models.CheckCountryRules(address); err != nil {
+			return models.ImportedCustomer{}, fmt.Errorf("address %d: %w", n+1, err)
+		}
+		addresses = append(addresses, address)
+	}
+
+	if err := markDefaultAddress(addresses); err != nil {
+		return models.ImportedCustomer{}, err
+	}
+
+	customer := handlers.CreateCustomerRequestToCustomer(record.Customer)
+	if err := i.rules.Normalize(&customer); err != nil {
+		return models.ImportedCustomer{}, err
+	}
+
+	if len(customer.Email) > MAX_EMAIL_LENGTH || len(customer.CanonicalEmail) > MAX_EMAIL_LENGTH {
+		return models.ImportedCustomer{}, fmt.Errorf("email is longer than %d characters", MAX_EMAIL_LENGTH)
+	}
+
+	if line, ok := i.phoneNumbers[customer.PhoneNumber]; ok {
+		return models.ImportedCustomer{}, fmt.Errorf("phone number is already used on line %d", line)
+	}
+	if line, ok := i.emails[customer.CanonicalEmail]; ok {
+		return models.ImportedCustomer{}, fmt.Errorf("email is already used on line %d", line)
+	}
+	i.phoneNumbers[customer.PhoneNumber] = record.Line
+	i.emails[customer.CanonicalEmail] = record.Line
+
+	return models.ImportedCustomer{Line: record.Line, Customer: customer, Addresses: addresses}, nil
+}
+
+// markDefaultAddress makes the first address the default unless the
+// record picked one, like the first address a customer creates.
+func markDefaultAddress(addresses []models.Address) error {
+	defaults := 0
+	for _, address := range addresses {
+		if address.IsDefault {
+			defaults++
+		}
+	}
+
+	if defaults > 1 {
+		return ErrSeveralDefaultAddresses
+	}
+	if defaults == 0 && len(addresses) > 0 {
+		addresses[0].IsDefault = true
+	}
+	return nil
+}
+
+func (i *Importer) flush(source string, batch *pendingBatch, report *Report) error {
+	batchReport, err := i.store.ImportCustomers(source, batch.lastLine, batch.customers, i.dryRun)
+	if err != nil {
+		return fmt.Errorf("couldn't import lines up to %d: %w", batch.lastLine, err)
+	}
+
+	for _, rejected := range batchReport.Rejected {
+		batch.reject(rejected.Line, batch.raw[rejected.Line], rejected.Reason)
+	}
+	slices.SortFunc(batch.rejections, func(a, b Rejection) int { return a.Line - b.Line })
+	for _, rejection := range batch.rejections {
+		if err = i.rejects.Encode(rejection); err != nil {
+			return fmt.Errorf("couldn't write rejects: %w", err)
+		}
+	}
+
+	report.Imported += batchReport.Imported
+	report.Addresses += batchReport.Addresses
+	report.Rejected += len(batch.rejections)
+	if !i.dryRun {
+		report.Checkpoint = batch.lastLine
+	}
+
+	*batch = pendingBatch{raw: map[int]string{}}
+	return nil
+}
+
+func (p *pendingBatch) reject(line int, raw string, reason string) {
+	p.rejections = append(p.rejections, Rejection{Line: line, Reason: reason, Record: raw})
+}
+
+// validationReason puts the field errors of a validation error on one line.
+func validationReason(err error) string {
+	lines := strings.Split(strings.TrimSpace(err.Error()), "\n")
+	if len(lines) > 1 {
+		lines = lines[1:]
+	}
+	return strings.Join(lines, "; ")
+}
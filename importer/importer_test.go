@@ -0,0 +1,229 @@
+package importer_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/VitoNaychev/bt-customer-svc/handlers"
+	"github.com/VitoNaychev/bt-customer-svc/importer"
+	"github.com/VitoNaychev/bt-customer-svc/models"
+	"github.com/VitoNaychev/bt-customer-svc/testdata"
+	"github.com/VitoNaychev/bt-customer-svc/testutil"
+)
+
+const testCSV = `first_name,last_name,phone_number,email,password,lat,lon,address_line1,address_line2,city,country
+Ivan,Petrov,0881234567,Ivan.Petrov@abv.bg,secret123,42.69,23.32,Vitosha Blvd 1,,Sofia,Bulgaria
+Maria,Ivanova,+359 88 765 4321,maria@abv.bg,secret456,,,,,,
+Georgi,Georgiev,0887777777,not-an-email,secret789,,,,,,
+Elena,Dimitrova,0881234567,elena@abv.bg,secret000,,,,,,
+Stoyan,Kolev,0889999999,stoyan@abv.bg,secret111,north,23.32,Vitosha Blvd 2,,Sofia,Bulgaria
+Peter,Smith,+359885765981,pete@abv.bg,secret222,,,,,,
+`
+
+func readAll(t testing.TB, reader importer.Reader) ([]importer.Record, []*importer.RecordError) {
+	t.Helper()
+
+	records := []importer.Record{}
+	recordErrors := []*importer.RecordError{}
+	for {
+		record, err := reader.Read()
+		if err == io.EOF {
+			return records, recordErrors
+		}
+
+		var recordErr *importer.RecordError
+		if errors.As(err, &recordErr) {
+			recordErrors = append(recordErrors, recordErr)
+			continue
+		}
+		if err != nil {
+			t.Fatalf("couldn't read record: %v", err)
+		}
+		records = append(records, record)
+	}
+}
+
+func readRejections(t testing.TB, rejects *bytes.Buffer) []importer.Rejection {
+	t.Helper()
+
+	rejections := []importer.Rejection{}
+	decoder := json.NewDecoder(rejects)
+	for decoder.More() {
+		var rejection importer.Rejection
+		if err := decoder.Decode(&rejection); err != nil {
+			t.Fatalf("couldn't decode rejection: %v", err)
+		}
+		rejections = append(rejections, rejection)
+	}
+	return rejections
+}
+
+func rejectedLines(rejections []importer.Rejection) []int {
+	lines := []int{}
+	for _, rejection := range rejections {
+		lines = append(lines, rejection.Line)
+	}
+	return lines
+}
+
+func TestCSVReader(t *testing.T) {
+	reader, err := importer.NewCSVReader(strings.NewReader(testCSV))
+	if err != nil {
+		t.Fatalf("couldn't create reader: %v", err)
+	}
+
+	records, recordErrors := readAll(t, reader)
+
+	t.Run("reads customers with their address", func(t *testing.T) {
+		want := importer.Record{
+			Line: 2,
+			Raw:  "Ivan,Petrov,0881234567,Ivan.Petrov@abv.bg,secret123,42.69,23.32,Vitosha Blvd 1,,Sofia,Bulgaria",
+			Customer: handlers.CreateCustomerRequest{
+				FirstName:   "Ivan",
+				LastName:    "Petrov",
+				PhoneNumber: "0881234567",
+				Email:       "Ivan.Petrov@abv.bg",
+				Password:    "secret123",
+			},
+			Addresses: []handlers.CreateAddressRequest{
+				{Lat: 42.69, Lon: 23.32, AddressLine1: "Vitosha Blvd 1", City: "Sofia", Country: "Bulgaria"},
+			},
+		}
+
+		testutil.AssertEqual(t, records[0], want)
+	})
+
+	t.Run("reads customers without address", func(t *testing.T) {
+		testutil.AssertEqual(t, records[1].Line, 3)
+		testutil.AssertEqual(t, len(records[1].Addresses), 0)
+	})
+
+	t.Run("returns record error on invalid coordinate", func(t *testing.T) {
+		testutil.AssertEqual(t, len(recordErrors), 1)
+		testutil.AssertEqual(t, recordErrors[0].Line, 6)
+	})
+
+	t.Run("returns error on missing columns", func(t *testing.T) {
+		_, err := importer.NewCSVReader(strings.NewReader("first_name,last_name,email\n"))
+
+		if !errors.Is(err, importer.ErrMissingColumns) {
+			t.Errorf("got error %v want %v", err, importer.ErrMissingColumns)
+		}
+	})
+}
+
+func TestJSONLReader(t *testing.T) {
+	jsonl := `{"FirstName":"Ivan","LastName":"Petrov","PhoneNumber":"0881234567","Email":"ivan@abv.bg","Password":"secret123","Addresses":[{"Lat":42.69,"Lon":23.32,"AddressLine1":"Vitosha Blvd 1","City":"Sofia","Country":"Bulgaria"}]}
+
+{"FirstName":"Maria","Nickname":"Mimi"}
+{"FirstName":
+`
+
+	records, recordErrors := readAll(t, importer.NewJSONLReader(strings.NewReader(jsonl)))
+
+	t.Run("reads customers with their addresses", func(t *testing.T) {
+		testutil.AssertEqual(t, len(records), 1)
+		testutil.AssertEqual(t, records[0].Customer.Email, "ivan@abv.bg")
+		testutil.AssertEqual(t, records[0].Addresses[0].City, "Sofia")
+	})
+
+	t.Run("returns record errors on unknown fields and malformed lines", func(t *testing.T) {
+		lines := []int{}
+		for _, recordErr := range recordErrors {
+			lines = append(lines, recordErr.Line)
+		}
+
+		testutil.AssertEqual(t, lines, []int{3, 4})
+	})
+}
+
+func TestImporter(t *testing.T) {
+	newReader := func(t testing.TB) importer.Reader {
+		reader, err := importer.NewCSVReader(strings.NewReader(testCSV))
+		if err != nil {
+			t.Fatalf("couldn't create reader: %v", err)
+		}
+		return reader
+	}
+
+	t.Run("imports valid records and rejects the rest", func(t *testing.T) {
+		store := testutil.NewStubCustomerImportStore([]models.Customer{testdata.PeterCustomer})
+		rejects := bytes.NewBuffer([]byte{})
+
+		report, err := importer.NewImporter(store, models.DefaultIdentityRules(), rejects).Run("legacy.csv", newReader(t))
+		if err != nil {
+			t.Fatalf("couldn't import: %v", err)
+		}
+
+		testutil.AssertEqual(t, report.Imported, 2)
+		testutil.AssertEqual(t, report.Addresses, 1)
+		testutil.AssertEqual(t, report.Rejected, 4)
+		testutil.AssertEqual(t, report.Checkpoint, 7)
+
+		ivan := store.Imported[0].Customer
+		testutil.AssertEqual(t, ivan.PhoneNumber, "+359881234567")
+		testutil.AssertEqual(t, ivan.CanonicalEmail, "ivan.petrov@abv.bg")
+
+		rejections := readRejections(t, rejects)
+		testutil.AssertEqual(t, rejectedLines(rejections), []int{4, 5, 6, 7})
+		testutil.AssertEqual(t, rejections[1].Reason, "phone number is already used on line 2")
+		testutil.AssertEqual(t, rejections[3].Reason, "phone number is already used by customer 1")
+	})
+
+	t.Run("dry run doesn't import or move the checkpoint", func(t *testing.T) {
+		store := testutil.NewStubCustomerImportStore(nil)
+
+		customerImporter := importer.NewImporter(store, models.DefaultIdentityRules(), io.Discard)
+		customerImporter.SetDryRun(true)
+
+		report, err := customerImporter.Run("legacy.csv", newReader(t))
+		if err != nil {
+			t.Fatalf("couldn't import: %v", err)
+		}
+
+		testutil.AssertEqual(t, report.Imported, 3)
+		testutil.AssertEqual(t, report.Checkpoint, 0)
+		testutil.AssertEqual(t, len(store.Imported), 0)
+	})
+
+	t.Run("resumes after the last committed batch", func(t *testing.T) {
+		store := testutil.NewStubCustomerImportStore(nil)
+		store.FailAfter = 1
+
+		rejects := bytes.NewBuffer([]byte{})
+		customerImporter := importer.NewImporter(store, models.DefaultIdentityRules(), rejects)
+		customerImporter.SetBatchSize(1)
+
+		report, err := customerImporter.Run("legacy.csv", newReader(t))
+		if err == nil {
+			t.Fatal("expected the import to be interrupted")
+		}
+		testutil.AssertEqual(t, report.Checkpoint, 2)
+
+		store.FailAfter = 0
+		customerImporter = importer.NewImporter(store, models.DefaultIdentityRules(), rejects)
+		customerImporter.SetBatchSize(1)
+
+		report, err = customerImporter.Run("legacy.csv", newReader(t))
+		if err != nil {
+			t.Fatalf("couldn't resume import: %v", err)
+		}
+
+		testutil.AssertEqual(t, report.ResumedAfter, 2)
+		testutil.AssertEqual(t, report.Skipped, 1)
+
+		lines := []int{}
+		for _, imported := range store.Imported {
+			lines = append(lines, imported.Line)
+		}
+		testutil.AssertEqual(t, lines, []int{2, 3, 7})
+
+		rejections := readRejections(t, rejects)
+		testutil.AssertEqual(t, rejectedLines(rejections), []int{4, 5, 6})
+		testutil.AssertEqual(t, rejections[1].Reason, "phone number is already used by customer 1")
+	})
+}
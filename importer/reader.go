@@ -0,0 +1,255 @@
+package importer
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+
+	"github.com/VitoNaychev/bt-customer-svc/handlers"
+)
+
+const (
+	CSV_FORMAT   = "csv"
+	JSONL_FORMAT = "jsonl"
+)
+
+// MAX_LINE_SIZE bounds a single JSONL record.
+const MAX_LINE_SIZE = 1 << 20
+
+var (
+	ErrUnknownFormat  = errors.New("import format must be csv or jsonl")
+	ErrMissingColumns = errors.New("CSV header is missing required columns")
+)
+
+// Record is a customer read from an import source. Line is the line of the
+// source it starts on, which identifies the record in rejections and
+// checkpoints, and Raw is its original text.
+type Record struct {
+	Line      int
+	Raw       string
+	Customer  handlers.CreateCustomerRequest
+	Addresses []handlers.CreateAddressRequest
+}
+
+// RecordError is returned for a record that couldn't be parsed. Reading can
+// continue with the next record.
+type RecordError struct {
+	Line int
+	Raw  string
+	Err  error
+}
+
+func (r *RecordError) Error() string {
+	return fmt.Sprintf("line %d: %v", r.Line, r.Err)
+}
+
+func (r *RecordError) Unwrap() error {
+	return r.Err
+}
+
+// Reader streams the records of an import source. Read returns io.EOF once
+// the source is exhausted.
+type Reader interface {
+	Read() (Record, error)
+}
+
+func NewReader(r io.Reader, format string) (Reader, error) {
+	switch format {
+	case CSV_FORMAT:
+		return NewCSVReader(r)
+	case JSONL_FORMAT:
+		return NewJSONLReader(r), nil
+	default:
+		return nil, ErrUnknownFormat
+	}
+}
+
+// jsonlRecord is a CreateCustomerRequest with the customer's addresses.
+type jsonlRecord struct {
+	handlers.CreateCustomerRequest
+	Addresses []handlers.CreateAddressRequest
+}
+
+// JSONLReader reads one customer per line. Like request bodies, records
+// with unknown fields are rejected. Blank lines are skipped.
+type JSONLReader struct {
+	scanner *bufio.Scanner
+	line    int
+}
+
+func NewJSONLReader(r io.Reader) *JSONLReader {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, 64*1024), MAX_LINE_SIZE)
+
+	return &JSONLReader{scanner: scanner}
+}
+
+func (j *JSONLReader) Read() (Record, error) {
+	for j.scanner.Scan() {
+		j.line++
+
+		raw := strings.TrimSpace(j.scanner.Text())
+		if raw == "" {
+			continue
+		}
+
+		decoder := json.NewDecoder(bytes.NewReader([]byte(raw)))
+		decoder.DisallowUnknownFields()
+
+		var record jsonlRecord
+		if err := decoder.Decode(&record); err != nil {
+			return Record{}, &RecordError{Line: j.line, Raw: raw, Err: err}
+		}
+
+		return Record{
+			Line:      j.line,
+			Raw:       raw,
+			Customer:  record.CreateCustomerRequest,
+			Addresses: record.Addresses,
+		}, nil
+	}
+
+	if err := j.scanner.Err(); err != nil {
+		return Record{}, fmt.Errorf("line %d: %w", j.line+1, err)
+	}
+	return Record{}, io.EOF
+}
+
+var customerColumns = []string{"firstname", "lastname", "phonenumber", "email", "password"}
+
+var addressColumns = []string{"lat", "lon", "addressline1", "addressline2", "city", "region", "postalcode", "country", "label",
+	"deliveryinstructions", "floor", "entrance", "apartment", "contactphone"}
+
+// CSVReader reads one customer per row, with at most one address in the
+// same row. Columns are matched to the fields of CreateCustomerRequest and
+// CreateAddressRequest by the header, ignoring case, spaces and
+// underscores, so both "AddressLine1" and "address_line1" work. A row
+// without any address column set has no address.
+type CSVReader struct {
+	reader  *csv.Reader
+	columns map[string]int
+}
+
+func NewCSVReader(r io.Reader) (*CSVReader, error) {
+	reader := csv.NewReader(r)
+
+	header, err := reader.Read()
+	if err != nil {
+		return nil, fmt.Errorf("couldn't read CSV header: %w", err)
+	}
+
+	columns := map[string]int{}
+	for i, name := range header {
+		name = strings.ToLower(strings.NewReplacer("_", "", " ", "").Replace(name))
+		columns[strings.TrimPrefix(name, "\ufeff")] = i
+	}
+
+	missing := []string{}
+	for _, column := range customerColumns {
+		if _, ok := columns[column]; !ok {
+			missing = append(missing, column)
+		}
+	}
+	if len(missing) > 0 {
+		return nil, fmt.Errorf("%w: %s", ErrMissingColumns, strings.Join(missing, ", "))
+	}
+
+	return &CSVReader{reader: reader, columns: columns}, nil
+}
+
+func (c *CSVReader) Read() (Record, error) {
+	fields, err := c.reader.Read()
+	if err == io.EOF {
+		return Record{}, io.EOF
+	}
+
+	line, _ := c.reader.FieldPos(0)
+	if errors.Is(err, csv.ErrFieldCount) {
+		return Record{}, &RecordError{Line: line, Raw: joinCSV(fields), Err: err}
+	}
+	if err != nil {
+		return Record{}, err
+	}
+
+	record := Record{
+		Line: line,
+		Raw:  joinCSV(fields),
+		Customer: handlers.CreateCustomerRequest{
+			FirstName:   c.field(fields, "firstname"),
+			LastName:    c.field(fields, "lastname"),
+			PhoneNumber: c.field(fields, "phonenumber"),
+			Email:       c.field(fields, "email"),
+			Password:    c.field(fields, "password"),
+		},
+	}
+
+	hasAddress := false
+	for _, column := range addressColumns {
+		hasAddress = hasAddress || c.field(fields, column) != ""
+	}
+	if !hasAddress {
+		return record, nil
+	}
+
+	address := handlers.CreateAddressRequest{
+		AddressLine1:         c.field(fields, "addressline1"),
+		AddressLine2:         c.field(fields, "addressline2"),
+		City:                 c.field(fields, "city"),
+		Region:               c.field(fields, "region"),
+		PostalCode:           c.field(fields, "postalcode"),
+		Country:              c.field(fields, "country"),
+		Label:                c.field(fields, "label"),
+		DeliveryInstructions: c.field(fields, "deliveryinstructions"),
+		Floor:                c.field(fields, "floor"),
+		Entrance:             c.field(fields, "entrance"),
+		Apartment:            c.field(fields, "apartment"),
+		ContactPhone:         c.field(fields, "contactphone"),
+	}
+	if address.Lat, err = c.coordinate(fields, "lat"); err != nil {
+		return Record{}, &RecordError{Line: line, Raw: record.Raw, Err: err}
+	}
+	if address.Lon, err = c.coordinate(fields, "lon"); err != nil {
+		return Record{}, &RecordError{Line: line, Raw: record.Raw, Err: err}
+	}
+
+	record.Addresses = []handlers.CreateAddressRequest{address}
+	return record, nil
+}
+
+func (c *CSVReader) field(fields []string, column string) string {
+	i, ok := c.columns[column]
+	if !ok || i >= len(fields) {
+		return ""
+	}
+	return strings.TrimSpace(fields[i])
+}
+
+// coordinate parses a Lat or Lon column. An empty value is left to the
+// required rule of CreateAddressRequest.
+func (c *CSVReader) coordinate(fields []string, column string) (float64, error) {
+	value := c.field(fields, column)
+	if value == "" {
+		return 0, nil
+	}
+
+	coordinate, err := strconv.ParseFloat(value, 64)
+	if err != nil {
+		return 0, fmt.Errorf("%s %q is not a number", column, value)
+	}
+	return coordinate, nil
+}
+
+func joinCSV(fields []string) string {
+	buf := bytes.NewBuffer([]byte{})
+	writer := csv.NewWriter(buf)
+	writer.Write(fields)
+	writer.Flush()
+
+	return strings.TrimRight(buf.String(), "\n")
+}
@@ -11,6 +11,7 @@ import (
 	"github.com/VitoNaychev/bt-customer-svc/models"
 	"github.com/VitoNaychev/bt-customer-svc/testdata"
 	"github.com/VitoNaychev/bt-customer-svc/testutil"
+	"github.com/jackc/pgx/v5"
 )
 
 func TestAddressServerOperations(t *testing.T) {
@@ -26,8 +27,23 @@ func TestAddressServerOperations(t *testing.T) {
 		t.Fatal(err)
 	}
 
-	customerServer := handlers.NewCustomerServer(testEnv.SecretKey, testEnv.ExpiresAt, &customerStore)
-	addressServer := handlers.NewCustomerAddressServer(&addressStore, &customerStore, testEnv.SecretKey)
+	consentStore, err := models.NewPgConsentStore(context.Background(), connStr)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	referralStore, err := models.NewPgReferralStore(context.Background(), connStr)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	householdStore, err := models.NewPgHouseholdStore(context.Background(), connStr)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	customerServer := handlers.NewCustomerServer(testEnv.SecretKey, testEnv.ExpiresAt, &customerStore, &consentStore, &referralStore)
+	addressServer := handlers.NewCustomerAddressServer(&addressStore, &customerStore, testEnv.SecretKey, &householdStore)
 
 	server := handlers.NewRouterServer(customerServer, addressServer)
 
@@ -75,16 +91,37 @@ func TestAddressServerOperations(t *testing.T) {
 		updateAddress.City = "Varna"
 
 		request := handlers.NewUpdateAddressRequest(peterJWT, updateAddress)
+		request.Header.Set("If-Match", `"1"`)
 		response := httptest.NewRecorder()
 
 		server.ServeHTTP(response, request)
 
 		testutil.AssertStatus(t, response.Code, http.StatusOK)
 
+		updateAddress.Version = 2
+
 		got := testutil.ParseAddressResponse(t, response.Body)
 		testutil.AssertEqual(t, got, updateAddress)
 	})
 
+	t.Run("patch address", func(t *testing.T) {
+		patchedAddress := testdata.PeterAddress2
+		patchedAddress.City = "Varna"
+		patchedAddress.AddressLine1 = "Slivnitsa Blvd 2"
+		patchedAddress.Version = 3
+
+		patch := map[string]any{"AddressLine1": patchedAddress.AddressLine1}
+		request := handlers.NewPatchAddressRequest(peterJWT, testdata.PeterAddress2.Id, patch)
+		response := httptest.NewRecorder()
+
+		server.ServeHTTP(response, request)
+
+		testutil.AssertStatus(t, response.Code, http.StatusOK)
+
+		got := testutil.ParseAddressResponse(t, response.Body)
+		testutil.AssertEqual(t, got, patchedAddress)
+	})
+
 	t.Run("delete address", func(t *testing.T) {
 		deleteAddressRequest := handlers.DeleteAddressRequest{Id: testdata.PeterAddress2.Id}
 
@@ -132,3 +169,356 @@ func createNewAddress(t testing.TB, server http.Handler, a models.Address, custo
 
 	return response
 }
+
+func TestDefaultAddress(t *testing.T) {
+	connStr := SetupDatabaseContainer(t)
+
+	addressStore, err := models.NewPgAddressStore(context.Background(), connStr)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	customerStore, err := models.NewPgCustomerStore(context.Background(), connStr)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	customer := testdata.PeterCustomer
+	if err = customerStore.CreateCustomer(&customer); err != nil {
+		t.Fatal(err)
+	}
+
+	first := testdata.PeterAddress1
+	first.IsDefault = false
+	second := testdata.PeterAddress2
+	for _, address := range []*models.Address{&first, &second} {
+		if err = addressStore.CreateAddress(address); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	assertDefaultAddress := func(t testing.TB, want int) {
+		t.Helper()
+
+		addresses, err := addressStore.GetAddressesByCustomerID(customer.Id)
+		if err != nil {
+			t.Fatalf("couldn't get addresses: %v", err)
+		}
+
+		defaults := []int{}
+		for _, address := range addresses {
+			if address.IsDefault {
+				defaults = append(defaults, address.Id)
+			}
+		}
+		testutil.AssertEqual(t, defaults, []int{want})
+	}
+
+	t.Run("first address becomes the default", func(t *testing.T) {
+		assertDefaultAddress(t, first.Id)
+	})
+
+	t.Run("setting a default clears the previous one", func(t *testing.T) {
+		patch := models.AddressPatch{IsDefault: &[]bool{true}[0]}
+		if _, err := addressStore.PatchAddress(second.Id, second.Version, patch); err != nil {
+			t.Fatalf("couldn't patch address: %v", err)
+		}
+
+		assertDefaultAddress(t, second.Id)
+	})
+
+	t.Run("keeps the version on an empty patch", func(t *testing.T) {
+		current, _ := addressStore.GetAddressByID(second.Id)
+
+		patched, err := addressStore.PatchAddress(second.Id, current.Version, models.AddressPatch{})
+		if err != nil {
+			t.Fatalf("couldn't patch address: %v", err)
+		}
+		testutil.AssertEqual(t, patched.Version, current.Version)
+	})
+
+	t.Run("refuses to delete a stale version", func(t *testing.T) {
+		err := addressStore.DeleteAddress(second.Id, second.Version)
+		testutil.AssertEqual(t, err, error(models.ErrVersionConflict))
+	})
+
+	t.Run("deleting the default promotes the remaining address", func(t *testing.T) {
+		current, _ := addressStore.GetAddressByID(second.Id)
+		if err := addressStore.DeleteAddress(second.Id, current.Version); err != nil {
+			t.Fatalf("couldn't delete address: %v", err)
+		}
+
+		assertDefaultAddress(t, first.Id)
+	})
+}
+
+func TestSearchAddressesNear(t *testing.T) {
+	connStr := SetupDatabaseContainer(t)
+
+	addressStore, err := models.NewPgAddressStore(context.Background(), connStr)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	customerStore, err := models.NewPgCustomerStore(context.Background(), connStr)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	peter, alice := testdata.PeterCustomer, testdata.AliceCustomer
+	for _, customer := range []*models.Customer{&peter, &alice} {
+		if err = customerStore.CreateCustomer(customer); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	peterAddress1, peterAddress2, aliceAddress := testdata.PeterAddress1, testdata.PeterAddress2, testdata.AliceAddress
+	for _, address := range []*models.Address{&peterAddress1, &peterAddress2, &aliceAddress} {
+		if err = addressStore.CreateAddress(address); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	addressIds := func(page models.AddressMatchPage) []int {
+		ids := []int{}
+		for _, match := range page.Matches {
+			ids = append(ids, match.Address.Id)
+		}
+		return ids
+	}
+
+	t.Run("stores geohash of the coordinates", func(t *testing.T) {
+		got, err := addressStore.GetAddressByID(aliceAddress.Id)
+		if err != nil {
+			t.Fatalf("couldn't get address: %v", err)
+		}
+
+		testutil.AssertEqual(t, got.Geohash, models.EncodeGeohash(aliceAddress.Lat, aliceAddress.Lon, models.GEOHASH_PRECISION))
+	})
+
+	t.Run("finds addresses within radius nearest first", func(t *testing.T) {
+		search := models.AddressProximitySearch{Area: models.CircleArea(aliceAddress.Lat, aliceAddress.Lon, 1000), Limit: 10}
+
+		got, err := addressStore.SearchAddressesNear(search)
+		if err != nil {
+			t.Fatalf("couldn't search addresses: %v", err)
+		}
+
+		testutil.AssertEqual(t, addressIds(got), []int{aliceAddress.Id, peterAddress1.Id})
+		if got.NextCursor != nil {
+			t.Errorf("got cursor %v want none", got.NextCursor)
+		}
+	})
+
+	t.Run("pages through matches", func(t *testing.T) {
+		search := models.AddressProximitySearch{Area: models.CircleArea(aliceAddress.Lat, aliceAddress.Lon, 2000), Limit: 2}
+
+		first, err := addressStore.SearchAddressesNear(search)
+		if err != nil {
+			t.Fatalf("couldn't search addresses: %v", err)
+		}
+		testutil.AssertEqual(t, addressIds(first), []int{aliceAddress.Id, peterAddress1.Id})
+
+		search.After = first.NextCursor
+		second, err := addressStore.SearchAddressesNear(search)
+		if err != nil {
+			t.Fatalf("couldn't search addresses: %v", err)
+		}
+		testutil.AssertEqual(t, addressIds(second), []int{peterAddress2.Id})
+	})
+
+	t.Run("finds addresses in bounding box", func(t *testing.T) {
+		box := models.BoundingBox{MinLat: 42.693, MinLon: 23.325, MaxLat: 42.696, MaxLon: 23.34}
+		search := models.AddressProximitySearch{Area: models.BoxArea(box), Limit: 10}
+
+		got, err := addressStore.SearchAddressesNear(search)
+		if err != nil {
+			t.Fatalf("couldn't search addresses: %v", err)
+		}
+
+		testutil.AssertEqual(t, addressIds(got), []int{peterAddress1.Id, peterAddress2.Id})
+	})
+}
+
+func TestAddressRevisions(t *testing.T) {
+	connStr := SetupDatabaseContainer(t)
+
+	addressStore, err := models.NewPgAddressStore(context.Background(), connStr)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	customerStore, err := models.NewPgCustomerStore(context.Background(), connStr)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	peter := testdata.PeterCustomer
+	if err = customerStore.CreateCustomer(&peter); err != nil {
+		t.Fatal(err)
+	}
+
+	peterAddress1, peterAddress2 := testdata.PeterAddress1, testdata.PeterAddress2
+	for _, address := range []*models.Address{&peterAddress1, &peterAddress2} {
+		if err = addressStore.CreateAddress(address); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	t.Run("records a revision on every update", func(t *testing.T) {
+		updatedAddress := peterAddress1
+		updatedAddress.City = "Plovdiv"
+		if err := addressStore.UpdateAddress(&updatedAddress); err != nil {
+			t.Fatalf("couldn't update address: %v", err)
+		}
+
+		original, err := addressStore.GetAddressRevision(peterAddress1.Id, peterAddress1.Version)
+		if err != nil {
+			t.Fatalf("couldn't get revision: %v", err)
+		}
+		testutil.AssertEqual(t, original.City, peterAddress1.City)
+		testutil.AssertEqual(t, original.CustomerId, peter.Id)
+
+		updated, err := addressStore.GetAddressRevision(peterAddress1.Id, updatedAddress.Version)
+		if err != nil {
+			t.Fatalf("couldn't get revision: %v", err)
+		}
+		testutil.AssertEqual(t, updated.City, "Plovdiv")
+	})
+
+	t.Run("keeps the time a revision was first frozen", func(t *testing.T) {
+		frozen, err := addressStore.FreezeAddressRevision(peterAddress1.Id, peterAddress1.Version)
+		if err != nil {
+			t.Fatalf("couldn't freeze revision: %v", err)
+		}
+		if frozen.FrozenAt == nil {
+			t.Fatal("expected revision to be frozen")
+		}
+
+		refrozen, err := addressStore.FreezeAddressRevision(peterAddress1.Id, peterAddress1.Version)
+		if err != nil {
+			t.Fatalf("couldn't freeze revision: %v", err)
+		}
+		testutil.AssertEqual(t, refrozen.FrozenAt.Equal(*frozen.FrozenAt), true)
+	})
+
+	t.Run("keeps only frozen revisions of deleted addresses", func(t *testing.T) {
+		_, err := addressStore.FreezeAddressRevision(peterAddress2.Id, peterAddress2.Version)
+		if err != nil {
+			t.Fatalf("couldn't freeze revision: %v", err)
+		}
+
+		label := "Office"
+		patched, err := addressStore.PatchAddress(peterAddress2.Id, peterAddress2.Version, models.AddressPatch{Label: &label})
+		if err != nil {
+			t.Fatalf("couldn't patch address: %v", err)
+		}
+
+		if err = addressStore.DeleteAddress(peterAddress2.Id, patched.Version); err != nil {
+			t.Fatalf("couldn't delete address: %v", err)
+		}
+
+		_, err = addressStore.GetAddressRevision(peterAddress2.Id, peterAddress2.Version)
+		if err != nil {
+			t.Fatalf("expected frozen revision to be kept: %v", err)
+		}
+
+		_, err = addressStore.GetAddressRevision(peterAddress2.Id, patched.Version)
+		testutil.AssertEqual(t, err, error(models.ErrNotFound))
+	})
+
+	t.Run("returns ErrNotFound on freezing missing revision", func(t *testing.T) {
+		_, err := addressStore.FreezeAddressRevision(peterAddress1.Id, 10)
+		testutil.AssertEqual(t, err, error(models.ErrNotFound))
+	})
+
+	t.Run("erases frozen revisions of deleted addresses on anonymization", func(t *testing.T) {
+		if _, err := customerStore.AnonymizeCustomer(peter.Id); err != nil {
+			t.Fatalf("couldn't anonymize customer: %v", err)
+		}
+
+		revision, err := addressStore.GetAddressRevision(peterAddress2.Id, peterAddress2.Version)
+		if err != nil {
+			t.Fatalf("couldn't get revision: %v", err)
+		}
+		testutil.AssertEqual(t, revision.AddressLine1, models.ErasedValue)
+		testutil.AssertEqual(t, revision.City, models.ErasedValue)
+		testutil.AssertEqual(t, revision.PostalCode, "")
+		testutil.AssertEqual(t, revision.ContactPhone, "")
+		testutil.AssertEqual(t, revision.Lat, 42.7)
+		testutil.AssertEqual(t, revision.Lon, 23.3)
+	})
+}
+
+func TestNormalizeCountries(t *testing.T) {
+	connStr := SetupDatabaseContainer(t)
+
+	customerStore, err := models.NewPgCustomerStore(context.Background(), connStr)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	customer := testdata.PeterCustomer
+	if err = customerStore.CreateCustomer(&customer); err != nil {
+		t.Fatal(err)
+	}
+
+	conn, err := pgx.Connect(context.Background(), connStr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close(context.Background())
+
+	// recreate the state before countries were stored as codes
+	_, err = conn.Exec(context.Background(), `
+		alter table addresses drop constraint addresses_country_check;
+		alter table addresses alter column country type varchar(40);
+		alter table addresses drop column region;
+		alter table addresses drop column postal_code;
+		insert into addresses(customer_id, lat, lon, address_line1, city, country) values
+			(1, 42.695111, 23.329184, 'Shipka Street 6', 'Sofia', 'Bulgaria'),
+			(1, 42.693857, 23.336245, 'Rakovski 96', 'Sofia', 'BG'),
+			(1, 42.693120, 23.322546, 'Angel Kanchev 1', 'Sofia', 'Narnia');`)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	addressStore, err := models.NewPgAddressStore(context.Background(), connStr)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	t.Run("maps names and reports unmappable countries", func(t *testing.T) {
+		report, err := addressStore.NormalizeCountries(false)
+		if err != nil {
+			t.Fatalf("couldn't normalize countries: %v", err)
+		}
+
+		want := models.CountryMigrationReport{
+			Updated:    1,
+			Unchanged:  1,
+			Unmappable: []models.UnmappableCountry{{AddressId: 3, Country: "Narnia"}},
+		}
+		testutil.AssertEqual(t, report, want)
+
+		address, err := addressStore.GetAddressByID(1)
+		if err != nil {
+			t.Fatalf("couldn't get address: %v", err)
+		}
+		testutil.AssertEqual(t, address.Country, "BG")
+	})
+
+	t.Run("constrains the column once every country is mapped", func(t *testing.T) {
+		if _, err := conn.Exec(context.Background(), `update addresses set country='Greece' where id=3`); err != nil {
+			t.Fatal(err)
+		}
+
+		report, err := addressStore.NormalizeCountries(false)
+		if err != nil {
+			t.Fatalf("couldn't normalize countries: %v", err)
+		}
+
+		want := models.CountryMigrationReport{Updated: 1, Unchanged: 2, Unmappable: []models.UnmappableCountry{}, Constrained: true}
+		testutil.AssertEqual(t, report, want)
+	})
+}
@@ -0,0 +1,100 @@
+package integrationtest
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/VitoNaychev/bt-customer-svc/models"
+	"github.com/VitoNaychev/bt-customer-svc/testdata"
+	"github.com/VitoNaychev/bt-customer-svc/testutil"
+)
+
+func TestCustomerExport(t *testing.T) {
+	connStr := SetupDatabaseContainer(t)
+
+	customerStore, err := models.NewPgCustomerStore(context.Background(), connStr)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	addressStore, err := models.NewPgAddressStore(context.Background(), connStr)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	peter, alice := testdata.PeterCustomer, testdata.AliceCustomer
+	customerStore.CreateCustomer(&peter)
+	customerStore.CreateCustomer(&alice)
+
+	preferencesStore, err := models.NewPgPreferencesStore(context.Background(), connStr)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	address := testdata.PeterAddress1
+	address.CustomerId = peter.Id
+	addressStore.CreateAddress(&address)
+
+	preferences := testdata.AlicePreferences
+	preferences.CustomerId = alice.Id
+	preferencesStore.UpsertPreferences(&preferences)
+
+	exportedIds := func(t testing.TB, since time.Time) []int {
+		t.Helper()
+
+		ids := []int{}
+		err := customerStore.ExportCustomers(since, func(customer models.Customer) error {
+			ids = append(ids, customer.Id)
+			return nil
+		})
+		if err != nil {
+			t.Fatalf("couldn't export customers: %v", err)
+		}
+		return ids
+	}
+
+	t.Run("exports every customer in ID order", func(t *testing.T) {
+		testutil.AssertEqual(t, exportedIds(t, time.Time{}), []int{peter.Id, alice.Id})
+	})
+
+	t.Run("exports customers updated since", func(t *testing.T) {
+		time.Sleep(10 * time.Millisecond)
+		since := time.Now()
+
+		alice.LastName = "Smith"
+		if err := customerStore.UpdateCustomer(&alice); err != nil {
+			t.Fatalf("couldn't update customer: %v", err)
+		}
+
+		testutil.AssertEqual(t, exportedIds(t, since), []int{alice.Id})
+	})
+
+	t.Run("exports addresses", func(t *testing.T) {
+		addresses := []models.Address{}
+		err := customerStore.ExportAddresses(time.Time{}, func(address models.Address) error {
+			addresses = append(addresses, address)
+			return nil
+		})
+		if err != nil {
+			t.Fatalf("couldn't export addresses: %v", err)
+		}
+
+		testutil.AssertEqual(t, len(addresses), 1)
+		testutil.AssertEqual(t, addresses[0].City, testdata.PeterAddress1.City)
+	})
+	t.Run("exports saved preferences", func(t *testing.T) {
+		exported := []models.Preferences{}
+		err := customerStore.ExportPreferences(time.Time{}, func(preferences models.Preferences) error {
+			exported = append(exported, preferences)
+			return nil
+		})
+		if err != nil {
+			t.Fatalf("couldn't export preferences: %v", err)
+		}
+
+		testutil.AssertEqual(t, len(exported), 1)
+		testutil.AssertEqual(t, exported[0].CustomerId, alice.Id)
+		testutil.AssertEqual(t, exported[0].Notifications, testdata.AlicePreferences.Notifications)
+	})
+}
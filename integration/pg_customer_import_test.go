@@ -0,0 +1,91 @@
+package integrationtest
+
+import (
+	"context"
+	"testing"
+
+	"github.com/VitoNaychev/bt-customer-svc/models"
+	"github.com/VitoNaychev/bt-customer-svc/testdata"
+	"github.com/VitoNaychev/bt-customer-svc/testutil"
+)
+
+func TestCustomerImport(t *testing.T) {
+	connStr := SetupDatabaseContainer(t)
+
+	customerStore, err := models.NewPgCustomerStore(context.Background(), connStr)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	addressStore, err := models.NewPgAddressStore(context.Background(), connStr)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	peter := testdata.PeterCustomer
+	customerStore.CreateCustomer(&peter)
+
+	newImported := func(line int, customer models.Customer, addresses ...models.Address) models.ImportedCustomer {
+		models.DefaultIdentityRules().Normalize(&customer)
+		return models.ImportedCustomer{Line: line, Customer: customer, Addresses: addresses}
+	}
+
+	alice := testdata.AliceCustomer
+	clashing := testdata.AliceCustomer
+	clashing.Email = "other@gmail.com"
+	clashing.PhoneNumber = peter.PhoneNumber
+
+	batch := []models.ImportedCustomer{
+		newImported(2, alice, testdata.AliceAddress),
+		newImported(3, clashing),
+	}
+
+	t.Run("dry run rolls back", func(t *testing.T) {
+		report, err := customerStore.ImportCustomers("legacy.csv", 3, batch, true)
+		if err != nil {
+			t.Fatalf("couldn't import customers: %v", err)
+		}
+		testutil.AssertEqual(t, report.Imported, 1)
+
+		_, err = customerStore.GetCustomerByEmail(alice.Email)
+		testutil.AssertEqual(t, err, error(models.ErrNotFound))
+
+		checkpoint, _ := customerStore.GetImportCheckpoint("legacy.csv")
+		testutil.AssertEqual(t, checkpoint, 0)
+	})
+
+	t.Run("copies customers and addresses and moves the checkpoint", func(t *testing.T) {
+		report, err := customerStore.ImportCustomers("legacy.csv", 3, batch, false)
+		if err != nil {
+			t.Fatalf("couldn't import customers: %v", err)
+		}
+
+		want := models.ImportBatchReport{
+			Imported:  1,
+			Addresses: 1,
+			Rejected:  []models.ImportRejection{{Line: 3, Reason: "phone number is already used by customer 1"}},
+		}
+		testutil.AssertEqual(t, report, want)
+
+		imported, err := customerStore.GetCustomerByEmail(alice.Email)
+		if err != nil {
+			t.Fatalf("couldn't get imported customer: %v", err)
+		}
+		testutil.AssertEqual(t, imported.Status, models.ACTIVE_STATUS)
+
+		addresses, _ := addressStore.GetAddressesByCustomerID(imported.Id)
+		testutil.AssertEqual(t, len(addresses), 1)
+
+		checkpoint, _ := customerStore.GetImportCheckpoint("legacy.csv")
+		testutil.AssertEqual(t, checkpoint, 3)
+	})
+
+	t.Run("created customers don't collide with imported IDs", func(t *testing.T) {
+		customer := models.Customer{FirstName: "Ivan", LastName: "Petrov", PhoneNumber: "+359881234567",
+			Email: "ivan@abv.bg", Password: "secret123"}
+
+		if err := customerStore.CreateCustomer(&customer); err != nil {
+			t.Fatalf("couldn't create customer after import: %v", err)
+		}
+	})
+}
@@ -2,6 +2,7 @@ package integrationtest
 
 import (
 	"context"
+	"errors"
 	"net/http"
 	"net/http/httptest"
 	"testing"
@@ -10,6 +11,7 @@ import (
 	"github.com/VitoNaychev/bt-customer-svc/models"
 	"github.com/VitoNaychev/bt-customer-svc/testdata"
 	"github.com/VitoNaychev/bt-customer-svc/testutil"
+	"github.com/jackc/pgx/v5"
 )
 
 func TestCustomerServerOperations(t *testing.T) {
@@ -20,7 +22,17 @@ func TestCustomerServerOperations(t *testing.T) {
 		t.Fatal(err)
 	}
 
-	server := handlers.NewCustomerServer(testEnv.SecretKey, testEnv.ExpiresAt, &store)
+	consentStore, err := models.NewPgConsentStore(context.Background(), connStr)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	referralStore, err := models.NewPgReferralStore(context.Background(), connStr)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	server := handlers.NewCustomerServer(testEnv.SecretKey, testEnv.ExpiresAt, &store, &consentStore, &referralStore)
 
 	var peterJWT string
 	var createdSuccessfully bool
@@ -74,6 +86,47 @@ func TestCustomerServerOperations(t *testing.T) {
 			testutil.AssertEqual(t, got, want)
 		})
 
+		t.Run("patch customer", func(t *testing.T) {
+			patchedCustomer := testdata.PeterCustomer
+			patchedCustomer.LastName = "Roper"
+			patchedCustomer.Email = "peteroper@gmail.com"
+			patchedCustomer.FirstName = "Pete"
+
+			request := handlers.NewPatchCustomerRequest(peterJWT, map[string]any{"FirstName": "Pete"})
+			response := httptest.NewRecorder()
+
+			server.ServeHTTP(response, request)
+
+			testutil.AssertStatus(t, response.Code, http.StatusOK)
+
+			want := handlers.CustomerToCustomerResponse(patchedCustomer)
+			got := testutil.ParseCustomerResponse(t, response.Body)
+
+			testutil.AssertEqual(t, got, want)
+		})
+
+		t.Run("keep the ETag on an empty patch", func(t *testing.T) {
+			response := httptest.NewRecorder()
+			server.ServeHTTP(response, handlers.NewGetCustomerRequest(peterJWT))
+			etag := response.Header().Get("ETag")
+
+			response = httptest.NewRecorder()
+			server.ServeHTTP(response, handlers.NewPatchCustomerRequest(peterJWT, map[string]any{}))
+
+			testutil.AssertStatus(t, response.Code, http.StatusOK)
+			testutil.AssertEqual(t, response.Header().Get("ETag"), etag)
+		})
+
+		t.Run("refuse to delete a stale version", func(t *testing.T) {
+			customer, err := store.GetCustomerByEmail("peteroper@gmail.com")
+			if err != nil {
+				t.Fatalf("couldn't get customer: %v", err)
+			}
+
+			err = store.DeleteCustomer(customer.Id, customer.Version-1)
+			testutil.AssertEqual(t, err, error(models.ErrVersionConflict))
+		})
+
 		t.Run("delete customer", func(t *testing.T) {
 			request := handlers.NewDeleteCustomerRequest(peterJWT)
 			response := httptest.NewRecorder()
@@ -95,3 +148,243 @@ func TestCustomerServerOperations(t *testing.T) {
 		})
 	}
 }
+
+func TestCustomerAnonymization(t *testing.T) {
+	connStr := SetupDatabaseContainer(t)
+
+	customerStore, err := models.NewPgCustomerStore(context.Background(), connStr)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	addressStore, err := models.NewPgAddressStore(context.Background(), connStr)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	customer := testdata.PeterCustomer
+	customerStore.CreateCustomer(&customer)
+
+	address := testdata.PeterAddress1
+	addressStore.CreateAddress(&address)
+
+	t.Run("scrubs customer and address PII", func(t *testing.T) {
+		report, err := customerStore.AnonymizeCustomer(customer.Id)
+		if err != nil {
+			t.Fatalf("couldn't anonymize customer: %v", err)
+		}
+
+		want := models.ErasureReport{
+			CustomerId:     customer.Id,
+			CustomerFields: []string{"FirstName", "LastName", "PhoneNumber", "Email", "Password"},
+			Addresses: []models.AddressErasure{
+				{AddressId: address.Id, Fields: []string{"Lat", "Lon", "AddressLine1", "City"}},
+			},
+		}
+		testutil.AssertEqual(t, report, want)
+
+		erasedAddress, _ := addressStore.GetAddressByID(address.Id)
+		testutil.AssertEqual(t, erasedAddress.CustomerId, customer.Id)
+		testutil.AssertEqual(t, erasedAddress.City, models.ErasedValue)
+	})
+
+	t.Run("reports no fields on repeated anonymization", func(t *testing.T) {
+		report, err := customerStore.AnonymizeCustomer(customer.Id)
+		if err != nil {
+			t.Fatalf("couldn't anonymize customer: %v", err)
+		}
+
+		testutil.AssertEqual(t, report.CustomerFields, []string{})
+	})
+}
+
+func TestCustomerSearch(t *testing.T) {
+	connStr := SetupDatabaseContainer(t)
+
+	customerStore, err := models.NewPgCustomerStore(context.Background(), connStr)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	peter := testdata.PeterCustomer
+	customerStore.CreateCustomer(&peter)
+
+	alice := testdata.AliceCustomer
+	customerStore.CreateCustomer(&alice)
+
+	t.Run("filters by name prefix and phone fragment", func(t *testing.T) {
+		search := models.CustomerSearch{NamePrefix: "ali", PhoneFragment: "2222", SortBy: models.SORT_BY_ID, Limit: 10}
+
+		page, err := customerStore.SearchCustomers(search)
+		if err != nil {
+			t.Fatalf("couldn't search customers: %v", err)
+		}
+
+		testutil.AssertEqual(t, len(page.Customers), 1)
+		testutil.AssertEqual(t, page.Customers[0].Id, alice.Id)
+	})
+
+	t.Run("filters by email fragment and status", func(t *testing.T) {
+		search := models.CustomerSearch{EmailFragment: "PETE", Status: models.ACTIVE_STATUS, SortBy: models.SORT_BY_ID, Limit: 10}
+
+		page, err := customerStore.SearchCustomers(search)
+		if err != nil {
+			t.Fatalf("couldn't search customers: %v", err)
+		}
+
+		testutil.AssertEqual(t, len(page.Customers), 1)
+		testutil.AssertEqual(t, page.Customers[0].Id, peter.Id)
+
+		search.Status = models.ANONYMIZED_STATUS
+		page, _ = customerStore.SearchCustomers(search)
+		testutil.AssertEqual(t, len(page.Customers), 0)
+	})
+
+	t.Run("pages with a cursor", func(t *testing.T) {
+		search := models.CustomerSearch{SortBy: models.SORT_BY_LAST_NAME, Limit: 1}
+
+		first, err := customerStore.SearchCustomers(search)
+		if err != nil {
+			t.Fatalf("couldn't search customers: %v", err)
+		}
+		testutil.AssertEqual(t, first.Customers[0].Id, alice.Id)
+
+		search.After = first.NextCursor
+		second, err := customerStore.SearchCustomers(search)
+		if err != nil {
+			t.Fatalf("couldn't search customers: %v", err)
+		}
+		testutil.AssertEqual(t, second.Customers[0].Id, peter.Id)
+		testutil.AssertEqual(t, second.NextCursor, nil)
+	})
+}
+
+func TestCustomerConstraintErrors(t *testing.T) {
+	connStr := SetupDatabaseContainer(t)
+
+	customerStore, err := models.NewPgCustomerStore(context.Background(), connStr)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	peter := testdata.PeterCustomer
+	customerStore.CreateCustomer(&peter)
+
+	cases := map[string]struct {
+		customer models.Customer
+		column   string
+	}{
+		"duplicate email":        {customer: models.Customer{FirstName: "Ivan", LastName: "Ivanov", PhoneNumber: "+359880000000", Email: peter.Email}, column: "canonical_email"},
+		"duplicate phone number": {customer: models.Customer{FirstName: "Ivan", LastName: "Ivanov", PhoneNumber: peter.PhoneNumber, Email: "ivan@gmail.com"}, column: "phone_number"},
+	}
+
+	for name, test := range cases {
+		t.Run(name, func(t *testing.T) {
+			customer := test.customer
+			err := customerStore.CreateCustomer(&customer)
+
+			var constraintError *models.ConstraintError
+			if !errors.As(err, &constraintError) || !errors.Is(err, models.ErrUniqueViolation) {
+				t.Fatalf("got error %v want unique violation", err)
+			}
+
+			testutil.AssertEqual(t, constraintError.Column, test.column)
+		})
+	}
+
+	t.Run("names no column for other constraints", func(t *testing.T) {
+		customer := models.Customer{FirstName: "Ivan", LastName: "Ivanov", PhoneNumber: "+359880000000", Email: "ivan@gmail.com"}
+		err := customerStore.SignUpCustomer(&customer, []models.Consent{{DocumentId: 100, Granted: true}}, nil)
+
+		var constraintError *models.ConstraintError
+		if !errors.As(err, &constraintError) || !errors.Is(err, models.ErrForeignKeyViolation) {
+			t.Fatalf("got error %v want foreign key violation", err)
+		}
+
+		testutil.AssertEqual(t, constraintError.Constraint, "consents_document_id_fkey")
+		testutil.AssertEqual(t, constraintError.Column, "")
+	})
+}
+
+func TestCustomerCanonicalIdentity(t *testing.T) {
+	connStr := SetupDatabaseContainer(t)
+
+	customerStore, err := models.NewPgCustomerStore(context.Background(), connStr)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ivan := models.Customer{FirstName: "Ivan", LastName: "Ivanov", PhoneNumber: "088 123 4567", Email: " Ivan@X.com"}
+	err = customerStore.CreateCustomer(&ivan)
+	if err != nil {
+		t.Fatalf("couldn't create customer: %v", err)
+	}
+
+	t.Run("stores canonical forms", func(t *testing.T) {
+		got, _ := customerStore.GetCustomerByID(ivan.Id)
+
+		testutil.AssertEqual(t, got.PhoneNumber, "+359881234567")
+		testutil.AssertEqual(t, got.Email, "ivan@x.com")
+	})
+
+	t.Run("looks up emails case-insensitively", func(t *testing.T) {
+		got, err := customerStore.GetCustomerByEmail("IVAN@x.com")
+		if err != nil {
+			t.Fatalf("couldn't find customer: %v", err)
+		}
+
+		testutil.AssertEqual(t, got.Id, ivan.Id)
+	})
+
+	t.Run("rejects the same phone in national format", func(t *testing.T) {
+		duplicate := models.Customer{FirstName: "Ivan", LastName: "Ivanov", PhoneNumber: "+359 88 123 4567", Email: "ivan@y.com"}
+		err := customerStore.CreateCustomer(&duplicate)
+
+		if !errors.Is(err, models.ErrUniqueViolation) {
+			t.Errorf("got error %v want %v", err, models.ErrUniqueViolation)
+		}
+	})
+}
+
+func TestNormalizeIdentities(t *testing.T) {
+	connStr := SetupDatabaseContainer(t)
+
+	conn, err := pgx.Connect(context.Background(), connStr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close(context.Background())
+
+	// recreate the state before canonicalization
+	_, err = conn.Exec(context.Background(), `
+		alter table customers drop constraint customers_phone_number_check;
+		alter table customers drop constraint customers_canonical_email_key;
+		alter table customers drop column canonical_email;
+		insert into customers(first_name, last_name, phone_number, email, password) values
+			('Ivan', 'Ivanov', '088 123 4567', 'Ivan@X.com', ''),
+			('Maria', 'Ivanova', '+359 88 765 4321', 'maria@x.com', ''),
+			('Maria', 'Ivanova', '0887654321', 'maria2@x.com', '');`)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	customerStore, err := models.NewPgCustomerStore(context.Background(), connStr)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	report, err := customerStore.NormalizeIdentities(false)
+	if err != nil {
+		t.Fatalf("couldn't normalize identities: %v", err)
+	}
+
+	want := models.IdentityMigrationReport{
+		Updated:    1,
+		Invalid:    []models.InvalidIdentity{},
+		Collisions: []models.IdentityCollision{{Column: "phone_number", Value: "+359887654321", CustomerIds: []int{2, 3}}},
+	}
+	testutil.AssertEqual(t, report, want)
+
+	ivan, _ := customerStore.GetCustomerByEmail("ivan@x.com")
+	testutil.AssertEqual(t, ivan.PhoneNumber, "+359881234567")
+}
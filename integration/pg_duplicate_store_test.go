@@ -0,0 +1,110 @@
+package integrationtest
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/VitoNaychev/bt-customer-svc/models"
+	"github.com/VitoNaychev/bt-customer-svc/testdata"
+	"github.com/VitoNaychev/bt-customer-svc/testutil"
+)
+
+func TestDuplicateMerge(t *testing.T) {
+	connStr := SetupDatabaseContainer(t)
+
+	customerStore, err := models.NewPgCustomerStore(context.Background(), connStr)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	addressStore, err := models.NewPgAddressStore(context.Background(), connStr)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	loyaltyStore, err := models.NewPgLoyaltyStore(context.Background(), connStr)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	duplicateStore, err := models.NewPgDuplicateStore(context.Background(), connStr)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	segmentStore, err := models.NewPgSegmentStore(context.Background(), connStr)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	peter := testdata.PeterCustomer
+	customerStore.CreateCustomer(&peter)
+
+	duplicate := models.Customer{FirstName: "Peter", LastName: "Smith", PhoneNumber: "+44 7785 765981",
+		Email: "pete.smith@abv.bg", Password: "firefirefire"}
+	customerStore.CreateCustomer(&duplicate)
+
+	address := testdata.PeterAddress1
+	address.CustomerId = duplicate.Id
+	addressStore.CreateAddress(&address)
+
+	earn := models.LoyaltyTransaction{Id: "order-1-earn", CustomerId: duplicate.Id, Kind: models.ADJUST_TRANSACTION, Points: 40}
+	loyaltyStore.RecordTransaction(&earn)
+
+	segmentStore.AddTags(peter.Id, []string{"vip"})
+	segmentStore.AddTags(duplicate.Id, []string{"vip", "wholesale"})
+
+	candidates, err := duplicateStore.DetectDuplicates()
+	if err != nil {
+		t.Fatalf("couldn't detect duplicates: %v", err)
+	}
+	if len(candidates) != 1 {
+		t.Fatalf("got %d candidates want 1", len(candidates))
+	}
+
+	report, err := duplicateStore.MergeCustomers(candidates[0].Id, peter.Id)
+	if err != nil {
+		t.Fatalf("couldn't merge customers: %v", err)
+	}
+
+	t.Run("moves owned data to the survivor", func(t *testing.T) {
+		testutil.AssertEqual(t, report.Addresses, 1)
+		testutil.AssertEqual(t, report.TransferredPoints, 40)
+
+		addresses, _ := addressStore.GetAddressesByCustomerID(peter.Id)
+		testutil.AssertEqual(t, len(addresses), 1)
+
+		balance, _ := loyaltyStore.GetBalance(peter.Id)
+		testutil.AssertEqual(t, balance, 40)
+
+		tags, _ := segmentStore.GetTags(peter.Id)
+		testutil.AssertEqual(t, tags, []string{"vip", "wholesale"})
+	})
+
+	t.Run("tombstones the merged customer", func(t *testing.T) {
+		tombstone, _ := customerStore.GetCustomerByID(duplicate.Id)
+
+		testutil.AssertEqual(t, tombstone.Status, models.MERGED_STATUS)
+		testutil.AssertEqual(t, *tombstone.MergedInto, peter.Id)
+		testutil.AssertEqual(t, tombstone.FirstName, models.ErasedValue)
+	})
+
+	t.Run("anonymizes the merged customer", func(t *testing.T) {
+		if _, err := customerStore.AnonymizeCustomer(duplicate.Id); err != nil {
+			t.Fatalf("couldn't anonymize merged customer: %v", err)
+		}
+
+		tombstone, _ := customerStore.GetCustomerByID(duplicate.Id)
+		testutil.AssertEqual(t, tombstone.Status, models.MERGED_STATUS)
+		testutil.AssertEqual(t, *tombstone.MergedInto, peter.Id)
+	})
+
+	t.Run("doesn't merge twice", func(t *testing.T) {
+		_, err := duplicateStore.MergeCustomers(candidates[0].Id, peter.Id)
+
+		if !errors.Is(err, models.ErrCandidateReviewed) {
+			t.Errorf("got error %v want %v", err, models.ErrCandidateReviewed)
+		}
+	})
+}
@@ -0,0 +1,137 @@
+package integrationtest
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/VitoNaychev/bt-customer-svc/models"
+	"github.com/VitoNaychev/bt-customer-svc/testdata"
+	"github.com/VitoNaychev/bt-customer-svc/testutil"
+)
+
+func TestHouseholds(t *testing.T) {
+	connStr := SetupDatabaseContainer(t)
+
+	customerStore, err := models.NewPgCustomerStore(context.Background(), connStr)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	addressStore, err := models.NewPgAddressStore(context.Background(), connStr)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	householdStore, err := models.NewPgHouseholdStore(context.Background(), connStr)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	peter, alice := testdata.PeterCustomer, testdata.AliceCustomer
+	customerStore.CreateCustomer(&peter)
+	customerStore.CreateCustomer(&alice)
+
+	household := models.Household{Name: testdata.SmithHousehold.Name}
+	if err := householdStore.CreateHousehold(&household, peter.Id); err != nil {
+		t.Fatalf("couldn't create household: %v", err)
+	}
+
+	t.Run("makes the creator the owner", func(t *testing.T) {
+		member, err := householdStore.GetHouseholdMember(household.Id, peter.Id)
+		if err != nil {
+			t.Fatalf("couldn't get member: %v", err)
+		}
+		testutil.AssertEqual(t, member.Role, models.OWNER_ROLE)
+	})
+
+	t.Run("accepts an invitation once", func(t *testing.T) {
+		invitation, _ := models.NewHouseholdInvitation(household.Id, peter.Id, models.MEMBER_ROLE, time.Now())
+		if err := householdStore.CreateInvitation(&invitation); err != nil {
+			t.Fatalf("couldn't create invitation: %v", err)
+		}
+
+		member, err := householdStore.AcceptInvitation(invitation.Code, alice.Id, time.Now())
+		if err != nil {
+			t.Fatalf("couldn't accept invitation: %v", err)
+		}
+		testutil.AssertEqual(t, member.Role, models.MEMBER_ROLE)
+
+		_, err = householdStore.AcceptInvitation(invitation.Code, alice.Id, time.Now())
+		testutil.AssertEqual(t, err, error(models.ErrInvitationUsed))
+	})
+
+	t.Run("keeps household addresses out of the personal ones", func(t *testing.T) {
+		address := testdata.SmithHouseholdAddress
+		address.CustomerId = alice.Id
+		address.HouseholdId = &household.Id
+		if err := addressStore.CreateAddress(&address); err != nil {
+			t.Fatalf("couldn't create address: %v", err)
+		}
+		testutil.AssertEqual(t, address.IsDefault, false)
+
+		personal, _ := addressStore.GetAddressesByCustomerID(alice.Id)
+		testutil.AssertEqual(t, len(personal), 0)
+
+		shared, err := addressStore.GetAddressesByHouseholdID(household.Id)
+		if err != nil {
+			t.Fatalf("couldn't get household addresses: %v", err)
+		}
+		testutil.AssertEqual(t, len(shared), 1)
+		testutil.AssertEqual(t, shared[0].Id, address.Id)
+	})
+
+	t.Run("refuses to remove the last owner", func(t *testing.T) {
+		err := householdStore.RemoveHouseholdMember(household.Id, peter.Id)
+		testutil.AssertEqual(t, err, error(models.ErrLastOwner))
+
+		_, err = householdStore.GetHouseholdMember(household.Id, peter.Id)
+		if err != nil {
+			t.Fatalf("couldn't get member: %v", err)
+		}
+	})
+
+	t.Run("promotes a member when the owner is anonymized", func(t *testing.T) {
+		if _, err := customerStore.AnonymizeCustomer(peter.Id); err != nil {
+			t.Fatalf("couldn't anonymize customer: %v", err)
+		}
+
+		members, err := householdStore.GetHouseholdMembers(household.Id)
+		if err != nil {
+			t.Fatalf("couldn't get members: %v", err)
+		}
+		testutil.AssertEqual(t, len(members), 1)
+		testutil.AssertEqual(t, members[0].CustomerId, alice.Id)
+		testutil.AssertEqual(t, members[0].Role, models.OWNER_ROLE)
+	})
+
+	t.Run("promotes a member when the owner is deleted", func(t *testing.T) {
+		ivan := models.Customer{FirstName: "Ivan", LastName: "Ivanov", PhoneNumber: "+359880000000", Email: "ivan@gmail.com", Password: "ivanpass"}
+		if err := customerStore.CreateCustomer(&ivan); err != nil {
+			t.Fatalf("couldn't create customer: %v", err)
+		}
+
+		ivanHousehold := models.Household{Name: "Ivanovi"}
+		if err := householdStore.CreateHousehold(&ivanHousehold, ivan.Id); err != nil {
+			t.Fatalf("couldn't create household: %v", err)
+		}
+
+		invitation, _ := models.NewHouseholdInvitation(ivanHousehold.Id, ivan.Id, models.MEMBER_ROLE, time.Now())
+		householdStore.CreateInvitation(&invitation)
+		if _, err := householdStore.AcceptInvitation(invitation.Code, alice.Id, time.Now()); err != nil {
+			t.Fatalf("couldn't accept invitation: %v", err)
+		}
+
+		if err := customerStore.DeleteCustomer(ivan.Id, ivan.Version); err != nil {
+			t.Fatalf("couldn't delete customer: %v", err)
+		}
+
+		members, err := householdStore.GetHouseholdMembers(ivanHousehold.Id)
+		if err != nil {
+			t.Fatalf("couldn't get members: %v", err)
+		}
+		testutil.AssertEqual(t, len(members), 1)
+		testutil.AssertEqual(t, members[0].CustomerId, alice.Id)
+		testutil.AssertEqual(t, members[0].Role, models.OWNER_ROLE)
+	})
+}
@@ -0,0 +1,92 @@
+package integrationtest
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/VitoNaychev/bt-customer-svc/models"
+	"github.com/VitoNaychev/bt-customer-svc/testutil"
+	"github.com/jackc/pgx/v5"
+)
+
+func TestLoyaltyLedger(t *testing.T) {
+	connStr := SetupDatabaseContainer(t)
+
+	loyaltyStore, err := models.NewPgLoyaltyStore(context.Background(), connStr)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expiresAt := time.Now().Add(time.Hour)
+	earn := models.LoyaltyTransaction{Id: "order-1-earn", CustomerId: 1, Kind: models.EARN_TRANSACTION, Points: 100, ExpiresAt: &expiresAt}
+	if err = loyaltyStore.RecordTransaction(&earn); err != nil {
+		t.Fatalf("couldn't record transaction: %v", err)
+	}
+
+	t.Run("replays a retried transaction", func(t *testing.T) {
+		retry := models.LoyaltyTransaction{Id: "order-1-earn", CustomerId: 1, Kind: models.EARN_TRANSACTION, Points: 100}
+		if err := loyaltyStore.RecordTransaction(&retry); err != nil {
+			t.Fatalf("couldn't replay transaction: %v", err)
+		}
+
+		testutil.AssertEqual(t, retry.CreatedAt, earn.CreatedAt)
+
+		balance, _ := loyaltyStore.GetBalance(1)
+		testutil.AssertEqual(t, balance, 100)
+	})
+
+	t.Run("rejects a reused transaction ID", func(t *testing.T) {
+		reused := models.LoyaltyTransaction{Id: "order-1-earn", CustomerId: 1, Kind: models.EARN_TRANSACTION, Points: 5}
+		err := loyaltyStore.RecordTransaction(&reused)
+
+		if !errors.Is(err, models.ErrTransactionConflict) {
+			t.Errorf("got error %v want %v", err, models.ErrTransactionConflict)
+		}
+	})
+
+	t.Run("rejects redemptions over the balance", func(t *testing.T) {
+		redeem := models.LoyaltyTransaction{Id: "order-2-redeem", CustomerId: 1, Kind: models.REDEEM_TRANSACTION, Points: -150}
+		err := loyaltyStore.RecordTransaction(&redeem)
+
+		if !errors.Is(err, models.ErrInsufficientPoints) {
+			t.Errorf("got error %v want %v", err, models.ErrInsufficientPoints)
+		}
+	})
+
+	t.Run("expires unspent points once", func(t *testing.T) {
+		redeem := models.LoyaltyTransaction{Id: "order-3-redeem", CustomerId: 1, Kind: models.REDEEM_TRANSACTION, Points: -30}
+		if err := loyaltyStore.RecordTransaction(&redeem); err != nil {
+			t.Fatalf("couldn't record transaction: %v", err)
+		}
+
+		later := expiresAt.Add(time.Minute)
+		expired, err := loyaltyStore.ExpirePoints(later)
+		if err != nil {
+			t.Fatalf("couldn't expire points: %v", err)
+		}
+
+		testutil.AssertEqual(t, len(expired), 1)
+		testutil.AssertEqual(t, expired[0].Points, -70)
+
+		expired, _ = loyaltyStore.ExpirePoints(later.Add(time.Minute))
+		testutil.AssertEqual(t, len(expired), 0)
+
+		balance, _ := loyaltyStore.GetBalance(1)
+		testutil.AssertEqual(t, balance, 0)
+	})
+
+	t.Run("keeps the ledger append-only", func(t *testing.T) {
+		conn, err := pgx.Connect(context.Background(), connStr)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer conn.Close(context.Background())
+
+		_, err = conn.Exec(context.Background(), `update loyalty_entries set amount=1000 where transaction_id='order-1-earn'`)
+		if err == nil {
+			t.Error("expected ledger entries to be immutable")
+		}
+	})
+}
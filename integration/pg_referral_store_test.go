@@ -0,0 +1,93 @@
+package integrationtest
+
+import (
+	"context"
+	"testing"
+
+	"github.com/VitoNaychev/bt-customer-svc/models"
+	"github.com/VitoNaychev/bt-customer-svc/testdata"
+	"github.com/VitoNaychev/bt-customer-svc/testutil"
+)
+
+func TestReferrals(t *testing.T) {
+	connStr := SetupDatabaseContainer(t)
+
+	customerStore, err := models.NewPgCustomerStore(context.Background(), connStr)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	referralStore, err := models.NewPgReferralStore(context.Background(), connStr)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	peter, alice := testdata.PeterCustomer, testdata.AliceCustomer
+	customerStore.CreateCustomer(&peter)
+	customerStore.CreateCustomer(&alice)
+
+	code, err := referralStore.GetReferralCode(peter.Id)
+	if err != nil {
+		t.Fatalf("couldn't generate referral code: %v", err)
+	}
+
+	t.Run("keeps the generated code", func(t *testing.T) {
+		again, _ := referralStore.GetReferralCode(peter.Id)
+		testutil.AssertEqual(t, again, code)
+
+		referrerId, err := referralStore.GetReferrerByCode(code)
+		if err != nil {
+			t.Fatalf("couldn't find referrer: %v", err)
+		}
+		testutil.AssertEqual(t, referrerId, peter.Id)
+	})
+
+	t.Run("rejects a reused identity", func(t *testing.T) {
+		signup := &models.ReferralSignup{Referrer: peter, Code: code, HashKey: []byte("key")}
+
+		ivan := models.Customer{FirstName: "Ivan", LastName: "Ivanov", PhoneNumber: "+359880000000", Email: "ivan@gmail.com", Password: "ivanpass"}
+		if err := customerStore.SignUpCustomer(&ivan, nil, signup); err != nil {
+			t.Fatalf("couldn't sign up customer: %v", err)
+		}
+
+		// ivan's phone number signing up again after anonymizing his account
+		if _, err := customerStore.AnonymizeCustomer(ivan.Id); err != nil {
+			t.Fatalf("couldn't anonymize customer: %v", err)
+		}
+		again := models.Customer{FirstName: "Ivan", LastName: "Ivanov", PhoneNumber: "+359880000000", Email: "ivan.ivanov@gmail.com", Password: "ivanpass"}
+		if err := customerStore.SignUpCustomer(&again, nil, signup); err != nil {
+			t.Fatalf("couldn't sign up customer: %v", err)
+		}
+
+		stats, err := referralStore.GetReferralStats(peter.Id)
+		if err != nil {
+			t.Fatalf("couldn't get referral stats: %v", err)
+		}
+		testutil.AssertEqual(t, stats.Referred, 1)
+	})
+
+	t.Run("leaves nothing behind on a failed signup", func(t *testing.T) {
+		signup := &models.ReferralSignup{Referrer: peter, Code: code, HashKey: []byte("key")}
+		maria := models.Customer{FirstName: "Maria", LastName: "Ivanova", PhoneNumber: "+359880000001", Email: "maria@gmail.com", Password: "mariapass"}
+
+		// a consent to a document that doesn't exist fails after the
+		// customer is inserted
+		err := customerStore.SignUpCustomer(&maria, []models.Consent{{DocumentId: 100, Granted: true}}, signup)
+		if err == nil {
+			t.Fatal("expected signup to fail")
+		}
+
+		_, err = customerStore.GetCustomerByEmail("maria@gmail.com")
+		testutil.AssertEqual(t, err, error(models.ErrNotFound))
+	})
+
+	t.Run("counts accepted referrals", func(t *testing.T) {
+		stats, err := referralStore.GetReferralStats(peter.Id)
+		if err != nil {
+			t.Fatalf("couldn't get referral stats: %v", err)
+		}
+
+		testutil.AssertEqual(t, stats.Code, code)
+		testutil.AssertEqual(t, stats.Referred, 1)
+	})
+}
@@ -0,0 +1,122 @@
+package integrationtest
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/VitoNaychev/bt-customer-svc/models"
+	"github.com/VitoNaychev/bt-customer-svc/testdata"
+	"github.com/VitoNaychev/bt-customer-svc/testutil"
+)
+
+func TestSegmentMembership(t *testing.T) {
+	connStr := SetupDatabaseContainer(t)
+
+	customerStore, err := models.NewPgCustomerStore(context.Background(), connStr)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	addressStore, err := models.NewPgAddressStore(context.Background(), connStr)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	segmentStore, err := models.NewPgSegmentStore(context.Background(), connStr)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	peter, alice := testdata.PeterCustomer, testdata.AliceCustomer
+	customerStore.CreateCustomer(&peter)
+	customerStore.CreateCustomer(&alice)
+
+	peterAddress, aliceAddress := testdata.PeterAddress1, testdata.AliceAddress
+	peterAddress.CustomerId, aliceAddress.CustomerId = peter.Id, alice.Id
+	addressStore.CreateAddress(&peterAddress)
+	addressStore.CreateAddress(&aliceAddress)
+
+	segmentStore.AddTags(peter.Id, []string{"vip"})
+	segmentStore.AddTags(alice.Id, []string{"test-account", "vip"})
+
+	segment := models.Segment{
+		Name:  "sofia-vip",
+		Rules: models.SegmentRules{Tags: []string{"vip"}, ExcludedTags: []string{"test-account"}, Cities: []string{"sofia"}},
+	}
+	if err = segmentStore.CreateSegment(&segment); err != nil {
+		t.Fatalf("couldn't create segment: %v", err)
+	}
+
+	t.Run("lists members", func(t *testing.T) {
+		members, err := segmentStore.GetSegmentMembers("sofia-vip", 0, 10)
+		if err != nil {
+			t.Fatalf("couldn't get members: %v", err)
+		}
+
+		testutil.AssertEqual(t, members, []int{peter.Id})
+	})
+
+	t.Run("lists the segments of a customer", func(t *testing.T) {
+		segments, _ := segmentStore.GetCustomerSegments(peter.Id)
+		testutil.AssertEqual(t, segments, []string{"sofia-vip"})
+
+		segments, _ = segmentStore.GetCustomerSegments(alice.Id)
+		testutil.AssertEqual(t, segments, []string{})
+	})
+
+	t.Run("reads back the rules", func(t *testing.T) {
+		segments, _ := segmentStore.GetSegments()
+
+		testutil.AssertEqual(t, len(segments), 1)
+		testutil.AssertEqual(t, segments[0].Rules.Cities, []string{"sofia"})
+	})
+
+	t.Run("matches countries by name and code", func(t *testing.T) {
+		segment := models.Segment{Name: "bulgaria", Rules: models.SegmentRules{Countries: []string{"Bulgaria"}}}
+		segment.Rules.Normalize()
+		if err := segmentStore.CreateSegment(&segment); err != nil {
+			t.Fatalf("couldn't create segment: %v", err)
+		}
+
+		members, err := segmentStore.GetSegmentMembers("bulgaria", 0, 10)
+		if err != nil {
+			t.Fatalf("couldn't get members: %v", err)
+		}
+
+		testutil.AssertEqual(t, members, []int{peter.Id, alice.Id})
+	})
+
+	t.Run("pages members after an ID", func(t *testing.T) {
+		members, _ := segmentStore.GetSegmentMembers("bulgaria", 0, 1)
+		testutil.AssertEqual(t, members, []int{peter.Id})
+
+		members, _ = segmentStore.GetSegmentMembers("bulgaria", peter.Id, 1)
+		testutil.AssertEqual(t, members, []int{alice.Id})
+	})
+
+	t.Run("matches the creation window", func(t *testing.T) {
+		future := time.Now().Add(time.Hour)
+		segment := models.Segment{Name: "new", Rules: models.SegmentRules{CreatedAfter: &future}}
+		if err := segmentStore.CreateSegment(&segment); err != nil {
+			t.Fatalf("couldn't create segment: %v", err)
+		}
+
+		members, _ := segmentStore.GetSegmentMembers("new", 0, 10)
+		testutil.AssertEqual(t, members, []int{})
+	})
+
+	t.Run("leaves out inactive customers", func(t *testing.T) {
+		if _, err := customerStore.AnonymizeCustomer(alice.Id); err != nil {
+			t.Fatalf("couldn't anonymize customer: %v", err)
+		}
+
+		members, _ := segmentStore.GetSegmentMembers("bulgaria", 0, 10)
+		testutil.AssertEqual(t, members, []int{peter.Id})
+	})
+
+	t.Run("returns ErrNotFound on missing segment", func(t *testing.T) {
+		_, err := segmentStore.GetSegmentMembers("varna", 0, 10)
+		testutil.AssertEqual(t, err, error(models.ErrNotFound))
+	})
+}
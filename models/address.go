@@ -1,12 +1,111 @@
 package models
 
+import "time"
+
+// Address is a delivery address of a customer, or of a household when
+// HouseholdId is set, in which case CustomerId is the member who added it.
+// Every customer with personal addresses has exactly one default address;
+// the default moves when another address is made the default, so IsDefault
+// can't be cleared by itself. Household addresses are never the default.
+// CoordinatesMismatch is set when the coordinates sent with the address
+// are far from where its address lines geocode to. UpdatedAt is only used
+// by exports and Geohash by proximity searches; neither is part of the
+// address responses.
 type Address struct {
-	Id           int
-	CustomerId   int `db:"customer_id"`
-	Lat          float64
-	Lon          float64
-	AddressLine1 string `db:"address_line1"`
-	AddressLine2 string `db:"address_line2"`
-	City         string
-	Country      string
+	Id                   int
+	CustomerId           int  `db:"customer_id"`
+	HouseholdId          *int `db:"household_id"`
+	Lat                  float64
+	Lon                  float64
+	AddressLine1         string `db:"address_line1"`
+	AddressLine2         string `db:"address_line2"`
+	City                 string
+	Region               string
+	PostalCode           string `db:"postal_code"`
+	Country              string
+	Label                string
+	IsDefault            bool   `db:"is_default"`
+	DeliveryInstructions string `db:"delivery_instructions"`
+	Floor                string
+	Entrance             string
+	Apartment            string
+	ContactPhone         string    `db:"contact_phone"`
+	CoordinatesMismatch  bool      `db:"coordinates_mismatch"`
+	Geohash              string    `db:"geohash" json:"-"`
+	UpdatedAt            time.Time `db:"updated_at" json:"-"`
+	Version              int
+}
+
+// AddressPatch holds the address fields present in a partial update. Nil
+// fields are left untouched.
+type AddressPatch struct {
+	Lat                  *float64
+	Lon                  *float64
+	AddressLine1         *string
+	AddressLine2         *string
+	City                 *string
+	Region               *string
+	PostalCode           *string
+	Country              *string
+	Label                *string
+	IsDefault            *bool
+	DeliveryInstructions *string
+	Floor                *string
+	Entrance             *string
+	Apartment            *string
+	ContactPhone         *string
+	CoordinatesMismatch  *bool
+}
+
+func (a AddressPatch) Apply(address Address) Address {
+	applyPatchField(&address.Lat, a.Lat)
+	applyPatchField(&address.Lon, a.Lon)
+	applyPatchField(&address.AddressLine1, a.AddressLine1)
+	applyPatchField(&address.AddressLine2, a.AddressLine2)
+	applyPatchField(&address.City, a.City)
+	applyPatchField(&address.Region, a.Region)
+	applyPatchField(&address.PostalCode, a.PostalCode)
+	applyPatchField(&address.Country, a.Country)
+	applyPatchField(&address.Label, a.Label)
+	applyPatchField(&address.DeliveryInstructions, a.DeliveryInstructions)
+	applyPatchField(&address.Floor, a.Floor)
+	applyPatchField(&address.Entrance, a.Entrance)
+	applyPatchField(&address.Apartment, a.Apartment)
+	applyPatchField(&address.ContactPhone, a.ContactPhone)
+	applyPatchField(&address.CoordinatesMismatch, a.CoordinatesMismatch)
+	if a.makesDefault() {
+		address.IsDefault = true
+	}
+
+	return address
+}
+
+func (a AddressPatch) columns() map[string]any {
+	columns := map[string]any{}
+	addPatchColumn(columns, "lat", a.Lat)
+	addPatchColumn(columns, "lon", a.Lon)
+	addPatchColumn(columns, "address_line1", a.AddressLine1)
+	addPatchColumn(columns, "address_line2", a.AddressLine2)
+	addPatchColumn(columns, "city", a.City)
+	addPatchColumn(columns, "region", a.Region)
+	addPatchColumn(columns, "postal_code", a.PostalCode)
+	addPatchColumn(columns, "country", a.Country)
+	addPatchColumn(columns, "label", a.Label)
+	addPatchColumn(columns, "delivery_instructions", a.DeliveryInstructions)
+	addPatchColumn(columns, "floor", a.Floor)
+	addPatchColumn(columns, "entrance", a.Entrance)
+	addPatchColumn(columns, "apartment", a.Apartment)
+	addPatchColumn(columns, "contact_phone", a.ContactPhone)
+	addPatchColumn(columns, "coordinates_mismatch", a.CoordinatesMismatch)
+	if a.makesDefault() {
+		columns["is_default"] = true
+	}
+
+	return columns
+}
+
+// makesDefault reports whether the patch makes the address the default.
+// Setting IsDefault to false is ignored, like in updates.
+func (a AddressPatch) makesDefault() bool {
+	return a.IsDefault != nil && *a.IsDefault
 }
@@ -0,0 +1,118 @@
+package models
+
+import "strings"
+
+const (
+	SINGLE_LINE_FORMAT = "single_line"
+	MULTI_LINE_FORMAT  = "multi_line"
+	LABEL_FORMAT       = "label"
+)
+
+// addressFormat lays out the lines of an address in a country. Lines hold
+// the fields %1 and %2 for the address lines, %D for the entrance, floor
+// and apartment, %C for the city, %S for the region and %Z for the postal
+// code. Upper lists the fields that are written in capitals on labels.
+type addressFormat struct {
+	lines []string
+	upper string
+}
+
+var defaultAddressFormat = addressFormat{lines: []string{"%1", "%2", "%D", "%C %S %Z"}, upper: "C"}
+
+// addressFormats holds the layouts that differ from the default one, by
+// country code.
+var addressFormats = map[string]addressFormat{
+	"AT": {lines: []string{"%1", "%2", "%D", "%Z %C"}, upper: "C"},
+	"BG": {lines: []string{"%1", "%2", "%D", "%Z %C", "%S"}, upper: "C"},
+	"DE": {lines: []string{"%1", "%2", "%D", "%Z %C"}, upper: "C"},
+	"ES": {lines: []string{"%1", "%2", "%D", "%Z %C", "%S"}, upper: "CS"},
+	"FR": {lines: []string{"%1", "%2", "%D", "%Z %C"}, upper: "C"},
+	"GB": {lines: []string{"%1", "%2", "%D", "%C", "%Z"}, upper: "C"},
+	"GR": {lines: []string{"%1", "%2", "%D", "%Z %C"}, upper: "C"},
+	"IT": {lines: []string{"%1", "%2", "%D", "%Z %C %S"}, upper: "CS"},
+	"MK": {lines: []string{"%1", "%2", "%D", "%Z %C"}, upper: "C"},
+	"NL": {lines: []string{"%1", "%2", "%D", "%Z %C"}, upper: "C"},
+	"RO": {lines: []string{"%1", "%2", "%D", "%Z %C", "%S"}, upper: "C"},
+	"RS": {lines: []string{"%1", "%2", "%D", "%Z %C"}, upper: "C"},
+	"TR": {lines: []string{"%1", "%2", "%D", "%Z %C/%S"}, upper: "CS"},
+	"US": {lines: []string{"%1", "%2", "%D", "%C, %S %Z"}, upper: "CS"},
+}
+
+// FormatAddress renders an address the way its country writes them.
+// SINGLE_LINE_FORMAT joins the lines with commas for lists,
+// MULTI_LINE_FORMAT puts them on separate lines for display and
+// LABEL_FORMAT also writes the city and the country in capitals, as
+// shipping labels need them. Unknown formats render as MULTI_LINE_FORMAT.
+func FormatAddress(address Address, format string) string {
+	layout, ok := addressFormats[address.Country]
+	if !ok {
+		layout = defaultAddressFormat
+	}
+
+	fields := map[byte]string{
+		'1': address.AddressLine1,
+		'2': address.AddressLine2,
+		'D': addressDetails(address),
+		'C': address.City,
+		'S': address.Region,
+		'Z': address.PostalCode,
+	}
+	if format == LABEL_FORMAT {
+		for _, field := range []byte(layout.upper) {
+			fields[field] = strings.ToUpper(fields[field])
+		}
+	}
+
+	lines := []string{}
+	for _, template := range layout.lines {
+		if line := renderAddressLine(template, fields); line != "" {
+			lines = append(lines, line)
+		}
+	}
+
+	country := address.Country
+	if rules, ok := countries[address.Country]; ok {
+		country = rules.Name
+	}
+	if format == LABEL_FORMAT {
+		country = strings.ToUpper(country)
+	}
+	lines = append(lines, country)
+
+	if format == SINGLE_LINE_FORMAT {
+		return strings.Join(lines, ", ")
+	}
+	return strings.Join(lines, "\n")
+}
+
+// renderAddressLine fills in the fields of a line and drops the spaces and
+// separators that are left over from empty fields.
+func renderAddressLine(template string, fields map[byte]string) string {
+	var line strings.Builder
+	for i := 0; i < len(template); i++ {
+		if template[i] == '%' && i+1 < len(template) {
+			i++
+			line.WriteString(strings.TrimSpace(fields[template[i]]))
+			continue
+		}
+		line.WriteByte(template[i])
+	}
+
+	rendered := strings.Join(strings.Fields(line.String()), " ")
+	rendered = strings.ReplaceAll(rendered, " ,", ",")
+	return strings.Trim(rendered, " ,/")
+}
+
+func addressDetails(address Address) string {
+	details := []string{}
+	if address.Entrance != "" {
+		details = append(details, "Entrance "+address.Entrance)
+	}
+	if address.Floor != "" {
+		details = append(details, "Floor "+address.Floor)
+	}
+	if address.Apartment != "" {
+		details = append(details, "Apartment "+address.Apartment)
+	}
+	return strings.Join(details, ", ")
+}
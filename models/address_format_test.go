@@ -0,0 +1,69 @@
+package models_test
+
+import (
+	"testing"
+
+	"github.com/VitoNaychev/bt-customer-svc/models"
+)
+
+func TestFormatAddress(t *testing.T) {
+	sofia := models.Address{
+		AddressLine1: "Shipka Street 6",
+		City:         "Sofia",
+		PostalCode:   "1504",
+		Country:      "BG",
+		Entrance:     "A",
+		Apartment:    "12",
+	}
+	sanFrancisco := models.Address{
+		AddressLine1: "1 Market St",
+		AddressLine2: "Suite 300",
+		City:         "San Francisco",
+		Region:       "CA",
+		PostalCode:   "94105",
+		Country:      "US",
+	}
+	london := models.Address{AddressLine1: "10 Downing Street", City: "London", PostalCode: "SW1A 2AA", Country: "GB"}
+	zurich := models.Address{AddressLine1: "Bahnhofstrasse 1", City: "Zürich", PostalCode: "8001", Country: "CH"}
+
+	cases := []struct {
+		name    string
+		address models.Address
+		format  string
+		want    string
+	}{
+		{"Bulgarian address on one line", sofia, models.SINGLE_LINE_FORMAT,
+			"Shipka Street 6, Entrance A, Apartment 12, 1504 Sofia, Bulgaria"},
+		{"Bulgarian address on several lines", sofia, models.MULTI_LINE_FORMAT,
+			"Shipka Street 6\nEntrance A, Apartment 12\n1504 Sofia\nBulgaria"},
+		{"Bulgarian label", sofia, models.LABEL_FORMAT,
+			"Shipka Street 6\nEntrance A, Apartment 12\n1504 SOFIA\nBULGARIA"},
+		{"American label", sanFrancisco, models.LABEL_FORMAT,
+			"1 Market St\nSuite 300\nSAN FRANCISCO, CA 94105\nUNITED STATES"},
+		{"British address", london, models.MULTI_LINE_FORMAT,
+			"10 Downing Street\nLondon\nSW1A 2AA\nUnited Kingdom"},
+		{"address of a country without a layout", zurich, models.SINGLE_LINE_FORMAT,
+			"Bahnhofstrasse 1, Zürich 8001, CH"},
+	}
+
+	for _, test := range cases {
+		t.Run(test.name, func(t *testing.T) {
+			got := models.FormatAddress(test.address, test.format)
+
+			if got != test.want {
+				t.Errorf("got %q want %q", got, test.want)
+			}
+		})
+	}
+
+	t.Run("drops separators of empty fields", func(t *testing.T) {
+		address := models.Address{AddressLine1: "1 Main St", City: "Springfield", Country: "US"}
+
+		got := models.FormatAddress(address, models.SINGLE_LINE_FORMAT)
+		want := "1 Main St, Springfield, United States"
+
+		if got != want {
+			t.Errorf("got %q want %q", got, want)
+		}
+	})
+}
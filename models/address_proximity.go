@@ -0,0 +1,183 @@
+package models
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"math"
+)
+
+// GEOHASH_PRECISION is the length of the geohashes stored with addresses,
+// which locate them to a few meters.
+const GEOHASH_PRECISION = 9
+
+// MAX_COVER_CELLS bounds the number of geohash cells a proximity search
+// looks up; larger areas are covered with coarser cells.
+const MAX_COVER_CELLS = 64
+
+const geohashAlphabet = "0123456789bcdefghjkmnpqrstuvwxyz"
+
+const metersPerDegree = math.Pi * earthRadius / 180
+
+type BoundingBox struct {
+	MinLat float64
+	MinLon float64
+	MaxLat float64
+	MaxLon float64
+}
+
+func (b BoundingBox) Contains(lat, lon float64) bool {
+	return lat >= b.MinLat && lat <= b.MaxLat && lon >= b.MinLon && lon <= b.MaxLon
+}
+
+// AddressArea is either a circle of Radius meters around Lat and Lon or,
+// with a zero Radius, the bounding box alone. Distances of matches are
+// measured from Lat and Lon, which is the center of the box for box
+// areas.
+type AddressArea struct {
+	Lat    float64
+	Lon    float64
+	Radius float64
+	Box    BoundingBox
+}
+
+// CircleArea returns the area within radius meters of a point. Its
+// bounding box spans every longitude when the circle reaches a pole or the
+// antimeridian.
+func CircleArea(lat, lon, radius float64) AddressArea {
+	dLat := radius / metersPerDegree
+	box := BoundingBox{MinLat: max(lat-dLat, -90), MinLon: -180, MaxLat: min(lat+dLat, 90), MaxLon: 180}
+
+	widest := math.Max(math.Abs(box.MinLat), math.Abs(box.MaxLat))
+	if widest < 90 {
+		dLon := dLat / math.Cos(widest*math.Pi/180)
+		if lon-dLon >= -180 && lon+dLon <= 180 {
+			box.MinLon, box.MaxLon = lon-dLon, lon+dLon
+		}
+	}
+
+	return AddressArea{Lat: lat, Lon: lon, Radius: radius, Box: box}
+}
+
+func BoxArea(box BoundingBox) AddressArea {
+	return AddressArea{Lat: (box.MinLat + box.MaxLat) / 2, Lon: (box.MinLon + box.MaxLon) / 2, Box: box}
+}
+
+func (a AddressArea) Contains(lat, lon float64) bool {
+	if !a.Box.Contains(lat, lon) {
+		return false
+	}
+	return a.Radius == 0 || Distance(a.Lat, a.Lon, lat, lon) <= a.Radius
+}
+
+// AddressProximitySearch is a page of the addresses in an area, nearest
+// first.
+type AddressProximitySearch struct {
+	Area  AddressArea
+	After *ProximityCursor
+	Limit int
+}
+
+type AddressMatch struct {
+	Address  Address
+	Distance float64
+}
+
+type AddressMatchPage struct {
+	Matches    []AddressMatch
+	NextCursor *ProximityCursor
+}
+
+// ProximityCursor points at the last match of a page, which continues
+// after the (Distance, Id) pair.
+type ProximityCursor struct {
+	Distance float64
+	Id       int
+}
+
+func (c ProximityCursor) Encode() string {
+	data, _ := json.Marshal(c)
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+func DecodeProximityCursor(encoded string) (ProximityCursor, error) {
+	data, err := base64.RawURLEncoding.DecodeString(encoded)
+	if err != nil {
+		return ProximityCursor{}, ErrInvalidCursor
+	}
+
+	var cursor ProximityCursor
+	if err = json.Unmarshal(data, &cursor); err != nil || cursor.Id < 1 || cursor.Distance < 0 {
+		return ProximityCursor{}, ErrInvalidCursor
+	}
+
+	return cursor, nil
+}
+
+// EncodeGeohash returns the geohash of a point with the given number of
+// characters. It matches geohash_encode in init.sql.
+func EncodeGeohash(lat, lon float64, chars int) string {
+	latRange, lonRange := [2]float64{-90, 90}, [2]float64{-180, 180}
+
+	hash := make([]byte, 0, chars)
+	even, bits, ch := true, 0, 0
+	for len(hash) < chars {
+		value, bounds := lat, &latRange
+		if even {
+			value, bounds = lon, &lonRange
+		}
+
+		mid := (bounds[0] + bounds[1]) / 2
+		if value >= mid {
+			ch, bounds[0] = ch*2+1, mid
+		} else {
+			ch, bounds[1] = ch*2, mid
+		}
+
+		even = !even
+		if bits++; bits == 5 {
+			hash = append(hash, geohashAlphabet[ch])
+			bits, ch = 0, 0
+		}
+	}
+
+	return string(hash)
+}
+
+// GeohashCover returns the geohash cells, of the longest length that needs
+// at most maxCells of them, that together cover the box. Every point in
+// the box has a geohash starting with one of the cells.
+func GeohashCover(box BoundingBox, maxCells int) []string {
+	for chars := GEOHASH_PRECISION; chars > 1; chars-- {
+		if cells := geohashCells(box, chars, maxCells); cells != nil {
+			return cells
+		}
+	}
+
+	return geohashCells(box, 1, len(geohashAlphabet))
+}
+
+// geohashCells returns nil when more than maxCells cells are needed.
+func geohashCells(box BoundingBox, chars int, maxCells int) []string {
+	lonBits, latBits := (5*chars+1)/2, 5*chars/2
+	cellLat, cellLon := 180/math.Exp2(float64(latBits)), 360/math.Exp2(float64(lonBits))
+
+	cellIndex := func(value, origin, size float64, bits int) int {
+		return min(int(math.Floor((value-origin)/size)), int(math.Exp2(float64(bits)))-1)
+	}
+	minLat, maxLat := cellIndex(box.MinLat, -90, cellLat, latBits), cellIndex(box.MaxLat, -90, cellLat, latBits)
+	minLon, maxLon := cellIndex(box.MinLon, -180, cellLon, lonBits), cellIndex(box.MaxLon, -180, cellLon, lonBits)
+
+	if (maxLat-minLat+1)*(maxLon-minLon+1) > maxCells {
+		return nil
+	}
+
+	cells := []string{}
+	for latIndex := minLat; latIndex <= maxLat; latIndex++ {
+		for lonIndex := minLon; lonIndex <= maxLon; lonIndex++ {
+			centerLat := -90 + (float64(latIndex)+0.5)*cellLat
+			centerLon := -180 + (float64(lonIndex)+0.5)*cellLon
+			cells = append(cells, EncodeGeohash(centerLat, centerLon, chars))
+		}
+	}
+	return cells
+}
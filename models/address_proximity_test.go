@@ -0,0 +1,78 @@
+package models_test
+
+import (
+	"math/rand"
+	"strings"
+	"testing"
+
+	"github.com/VitoNaychev/bt-customer-svc/models"
+)
+
+func TestEncodeGeohash(t *testing.T) {
+	cases := []struct {
+		lat, lon float64
+		chars    int
+		want     string
+	}{
+		{57.64911, 10.40744, 11, "u4pruydqqvj"},
+		{42.6977, 23.3219, 5, "sx8df"},
+		{-33.8688, 151.2093, 6, "r3gx2f"},
+		{0, 0, 1, "s"},
+	}
+
+	for _, test := range cases {
+		t.Run(test.want, func(t *testing.T) {
+			got := models.EncodeGeohash(test.lat, test.lon, test.chars)
+
+			if got != test.want {
+				t.Errorf("got %q want %q", got, test.want)
+			}
+		})
+	}
+}
+
+func TestGeohashCover(t *testing.T) {
+	boxes := map[string]models.BoundingBox{
+		"street in Sofia": {MinLat: 42.69, MinLon: 23.32, MaxLat: 42.70, MaxLon: 23.33},
+		"Sofia":           {MinLat: 42.6, MinLon: 23.2, MaxLat: 42.8, MaxLon: 23.5},
+		"around a pole":   models.CircleArea(89.9, 0, 50000).Box,
+	}
+
+	random := rand.New(rand.NewSource(1))
+	for name, box := range boxes {
+		t.Run(name, func(t *testing.T) {
+			cells := models.GeohashCover(box, models.MAX_COVER_CELLS)
+			if len(cells) == 0 || len(cells) > models.MAX_COVER_CELLS {
+				t.Fatalf("got %d cells", len(cells))
+			}
+
+			for i := 0; i < 1000; i++ {
+				lat := box.MinLat + random.Float64()*(box.MaxLat-box.MinLat)
+				lon := box.MinLon + random.Float64()*(box.MaxLon-box.MinLon)
+				geohash := models.EncodeGeohash(lat, lon, models.GEOHASH_PRECISION)
+
+				covered := false
+				for _, cell := range cells {
+					covered = covered || strings.HasPrefix(geohash, cell)
+				}
+				if !covered {
+					t.Fatalf("point (%v, %v) with geohash %q isn't covered by %v", lat, lon, geohash, cells)
+				}
+			}
+		})
+	}
+}
+
+func TestCircleArea(t *testing.T) {
+	area := models.CircleArea(42.6977, 23.3219, 1000)
+
+	if !area.Contains(42.7050, 23.3219) {
+		t.Errorf("point 810m north should be in the area")
+	}
+	if area.Contains(42.6977, 23.3400) {
+		t.Errorf("point 1.5km east shouldn't be in the area")
+	}
+	if area.Contains(42.7060, 23.3320) {
+		t.Errorf("corner of the bounding box shouldn't be in the area")
+	}
+}
@@ -0,0 +1,74 @@
+package models
+
+import "time"
+
+// AddressRevision is an immutable snapshot of what an address delivers to
+// at one of its versions; Revision is the version of the address it was
+// taken from. Orders freeze the revision they deliver to at checkout,
+// which keeps it after the address is deleted.
+type AddressRevision struct {
+	AddressId            int `db:"address_id"`
+	Revision             int
+	CustomerId           int  `db:"customer_id"`
+	HouseholdId          *int `db:"household_id"`
+	Lat                  float64
+	Lon                  float64
+	AddressLine1         string `db:"address_line1"`
+	AddressLine2         string `db:"address_line2"`
+	City                 string
+	Region               string
+	PostalCode           string `db:"postal_code"`
+	Country              string
+	DeliveryInstructions string `db:"delivery_instructions"`
+	Floor                string
+	Entrance             string
+	Apartment            string
+	ContactPhone         string     `db:"contact_phone"`
+	CreatedAt            time.Time  `db:"created_at"`
+	FrozenAt             *time.Time `db:"frozen_at"`
+}
+
+func NewAddressRevision(address Address) AddressRevision {
+	return AddressRevision{
+		AddressId:            address.Id,
+		Revision:             address.Version,
+		CustomerId:           address.CustomerId,
+		HouseholdId:          address.HouseholdId,
+		Lat:                  address.Lat,
+		Lon:                  address.Lon,
+		AddressLine1:         address.AddressLine1,
+		AddressLine2:         address.AddressLine2,
+		City:                 address.City,
+		Region:               address.Region,
+		PostalCode:           address.PostalCode,
+		Country:              address.Country,
+		DeliveryInstructions: address.DeliveryInstructions,
+		Floor:                address.Floor,
+		Entrance:             address.Entrance,
+		Apartment:            address.Apartment,
+		ContactPhone:         address.ContactPhone,
+	}
+}
+
+// Address returns the snapshot as an address, e.g. to format it.
+func (a AddressRevision) Address() Address {
+	return Address{
+		Id:                   a.AddressId,
+		CustomerId:           a.CustomerId,
+		HouseholdId:          a.HouseholdId,
+		Lat:                  a.Lat,
+		Lon:                  a.Lon,
+		AddressLine1:         a.AddressLine1,
+		AddressLine2:         a.AddressLine2,
+		City:                 a.City,
+		Region:               a.Region,
+		PostalCode:           a.PostalCode,
+		Country:              a.Country,
+		DeliveryInstructions: a.DeliveryInstructions,
+		Floor:                a.Floor,
+		Entrance:             a.Entrance,
+		Apartment:            a.Apartment,
+		ContactPhone:         a.ContactPhone,
+		Version:              a.Revision,
+	}
+}
@@ -4,6 +4,11 @@ type CustomerAddressStore interface {
 	GetAddressByID(id int) (Address, error)
 	GetAddressesByCustomerID(customerID int) ([]Address, error)
 	CreateAddress(address *Address) error
-	DeleteAddress(id int) error
+	DeleteAddress(id int, version int) error
 	UpdateAddress(address *Address) error
+	PatchAddress(id int, version int, patch AddressPatch) (Address, error)
+	SearchAddressesNear(search AddressProximitySearch) (AddressMatchPage, error)
+	GetAddressesByHouseholdID(householdID int) ([]Address, error)
+	GetAddressRevision(addressId int, revision int) (AddressRevision, error)
+	FreezeAddressRevision(addressId int, revision int) (AddressRevision, error)
 }
@@ -0,0 +1,45 @@
+package models
+
+import "time"
+
+const (
+	TERMS_DOCUMENT     = "terms"
+	PRIVACY_DOCUMENT   = "privacy"
+	MARKETING_DOCUMENT = "marketing"
+)
+
+type ConsentDocument struct {
+	Id          int
+	Kind        string
+	Version     string
+	Mandatory   bool
+	PublishedAt time.Time `db:"published_at"`
+}
+
+type Consent struct {
+	Id         int
+	CustomerId int `db:"customer_id"`
+	DocumentId int `db:"document_id"`
+	Granted    bool
+	RecordedAt time.Time `db:"recorded_at"`
+}
+
+// PendingDocuments returns the mandatory documents among the latest
+// published versions that the customer hasn't granted, or has withdrawn
+// consent for, according to their consent history.
+func PendingDocuments(latest []ConsentDocument, history []Consent) []ConsentDocument {
+	granted := map[int]bool{}
+	for _, consent := range history {
+		// history is ordered chronologically, so later records win
+		granted[consent.DocumentId] = consent.Granted
+	}
+
+	pending := []ConsentDocument{}
+	for _, document := range latest {
+		if document.Mandatory && !granted[document.Id] {
+			pending = append(pending, document)
+		}
+	}
+
+	return pending
+}
@@ -0,0 +1,8 @@
+package models
+
+type ConsentStore interface {
+	GetLatestDocuments() ([]ConsentDocument, error)
+	PublishDocument(document *ConsentDocument) error
+	GetConsentHistory(customerID int) ([]Consent, error)
+	RecordConsent(consent *Consent) error
+}
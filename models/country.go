@@ -0,0 +1,139 @@
+package models
+
+import (
+	"errors"
+	"regexp"
+	"slices"
+	"strings"
+)
+
+var (
+	ErrMissingPostalCode = errors.New("address must have a postal code in this country")
+	ErrInvalidPostalCode = errors.New("postal code doesn't match the format of the country")
+	ErrMissingRegion     = errors.New("address must have a region in this country")
+	ErrInvalidRegion     = errors.New("region isn't one of the regions of the country")
+)
+
+// CountryRules are the address rules of a country. A nil PostalCode means
+// postal codes are free-form. PostalCodeSpace is how many characters from
+// the end postal codes have a space, or 0 when they have none. Regions,
+// when set, lists the region codes the region of an address has to be one
+// of.
+type CountryRules struct {
+	Name               string
+	PostalCode         *regexp.Regexp
+	PostalCodeSpace    int
+	PostalCodeRequired bool
+	RegionRequired     bool
+	Regions            []string
+}
+
+var usStates = []string{
+	"AK", "AL", "AR", "AZ", "CA", "CO", "CT", "DC", "DE", "FL", "GA", "HI", "IA", "ID", "IL", "IN", "KS",
+	"KY", "LA", "MA", "MD", "ME", "MI", "MN", "MO", "MS", "MT", "NC", "ND", "NE", "NH", "NJ", "NM", "NV",
+	"NY", "OH", "OK", "OR", "PA", "PR", "RI", "SC", "SD", "TN", "TX", "UT", "VA", "VT", "WA", "WI", "WV", "WY",
+}
+
+// countries holds the rules of the countries addresses are validated
+// for, by ISO 3166-1 alpha-2 code. Addresses in other countries only have
+// to fit the column sizes.
+var countries = map[string]CountryRules{
+	"AT": {Name: "Austria", PostalCode: regexp.MustCompile(`^\d{4}$`)},
+	"BG": {Name: "Bulgaria", PostalCode: regexp.MustCompile(`^\d{4}$`)},
+	"DE": {Name: "Germany", PostalCode: regexp.MustCompile(`^\d{5}$`), PostalCodeRequired: true},
+	"ES": {Name: "Spain", PostalCode: regexp.MustCompile(`^\d{5}$`), PostalCodeRequired: true},
+	"FR": {Name: "France", PostalCode: regexp.MustCompile(`^\d{5}$`), PostalCodeRequired: true},
+	"GB": {Name: "United Kingdom", PostalCode: regexp.MustCompile(`^[A-Z]{1,2}\d[A-Z\d]? \d[A-Z]{2}$`), PostalCodeSpace: 3,
+		PostalCodeRequired: true},
+	"GR": {Name: "Greece", PostalCode: regexp.MustCompile(`^\d{3} \d{2}$`), PostalCodeSpace: 2},
+	"IT": {Name: "Italy", PostalCode: regexp.MustCompile(`^\d{5}$`), PostalCodeRequired: true, RegionRequired: true},
+	"MK": {Name: "North Macedonia", PostalCode: regexp.MustCompile(`^\d{4}$`)},
+	"NL": {Name: "Netherlands", PostalCode: regexp.MustCompile(`^\d{4} [A-Z]{2}$`), PostalCodeSpace: 2, PostalCodeRequired: true},
+	"RO": {Name: "Romania", PostalCode: regexp.MustCompile(`^\d{6}$`)},
+	"RS": {Name: "Serbia", PostalCode: regexp.MustCompile(`^\d{5}$`)},
+	"TR": {Name: "Turkey", PostalCode: regexp.MustCompile(`^\d{5}$`)},
+	"US": {Name: "United States", PostalCode: regexp.MustCompile(`^\d{5}(-\d{4})?$`), PostalCodeRequired: true,
+		RegionRequired: true, Regions: usStates},
+}
+
+// countryAliases maps the other names addresses were saved with before
+// countries were stored as codes, in their normalized form.
+var countryAliases = map[string]string{
+	"bulgaria": "BG", "българия": "BG", "republic of bulgaria": "BG",
+	"austria": "AT", "österreich": "AT",
+	"germany": "DE", "deutschland": "DE",
+	"spain": "ES", "españa": "ES",
+	"france": "FR", "république française": "FR",
+	"united kingdom": "GB", "uk": "GB", "great britain": "GB", "england": "GB", "scotland": "GB", "wales": "GB",
+	"greece": "GR", "ελλάδα": "GR", "hellas": "GR",
+	"italy": "IT", "italia": "IT",
+	"north macedonia": "MK", "macedonia": "MK", "северна македонија": "MK",
+	"netherlands": "NL", "the netherlands": "NL", "nederland": "NL", "holland": "NL",
+	"romania": "RO", "românia": "RO",
+	"serbia": "RS", "србија": "RS", "srbija": "RS",
+	"turkey": "TR", "türkiye": "TR", "turkiye": "TR",
+	"united states": "US", "united states of america": "US", "usa": "US",
+}
+
+// CountryCode returns the ISO 3166-1 alpha-2 code of a country given by
+// one of the supported codes, in any case, or by one of its names.
+func CountryCode(country string) (string, bool) {
+	country = strings.Join(strings.Fields(country), " ")
+
+	if _, ok := countries[strings.ToUpper(country)]; ok {
+		return strings.ToUpper(country), true
+	}
+
+	code, ok := countryAliases[strings.ToLower(strings.TrimSuffix(country, "."))]
+	return code, ok
+}
+
+// NormalizePostalAddress puts the country, postal code and region of an
+// address in the form they are stored in: the country code and postal code
+// upper-cased and postal codes spaced like the country writes them, e.g.
+// "sw1a1aa" becomes "SW1A 1AA".
+func NormalizePostalAddress(address *Address) {
+	address.Country = strings.ToUpper(strings.TrimSpace(address.Country))
+	address.Region = strings.Join(strings.Fields(address.Region), " ")
+	address.PostalCode = strings.ToUpper(strings.Join(strings.Fields(address.PostalCode), ""))
+
+	rules, ok := countries[address.Country]
+	if !ok {
+		return
+	}
+
+	if rules.Regions != nil {
+		address.Region = strings.ToUpper(address.Region)
+	}
+
+	if split := len(address.PostalCode) - rules.PostalCodeSpace; rules.PostalCodeSpace > 0 && split > 0 {
+		address.PostalCode = address.PostalCode[:split] + " " + address.PostalCode[split:]
+	}
+}
+
+// CheckCountryRules validates the postal code and region of a normalized
+// address against the rules of its country.
+func CheckCountryRules(address Address) error {
+	rules, ok := countries[address.Country]
+	if !ok {
+		return nil
+	}
+
+	if address.PostalCode == "" {
+		if rules.PostalCodeRequired {
+			return ErrMissingPostalCode
+		}
+	} else if rules.PostalCode != nil && !rules.PostalCode.MatchString(address.PostalCode) {
+		return ErrInvalidPostalCode
+	}
+
+	if address.Region == "" {
+		if rules.RegionRequired {
+			return ErrMissingRegion
+		}
+	} else if rules.Regions != nil && !slices.Contains(rules.Regions, address.Region) {
+		return ErrInvalidRegion
+	}
+
+	return nil
+}
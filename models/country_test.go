@@ -0,0 +1,94 @@
+package models_test
+
+import (
+	"testing"
+
+	"github.com/VitoNaychev/bt-customer-svc/models"
+)
+
+func TestCountryCode(t *testing.T) {
+	cases := map[string]string{
+		"BG":                       "BG",
+		"bg":                       "BG",
+		"Bulgaria":                 "BG",
+		" българия ":               "BG",
+		"United  Kingdom":          "GB",
+		"UK":                       "GB",
+		"United States of America": "US",
+	}
+
+	for country, want := range cases {
+		t.Run(country, func(t *testing.T) {
+			got, ok := models.CountryCode(country)
+			if !ok {
+				t.Fatalf("couldn't map %q", country)
+			}
+
+			if got != want {
+				t.Errorf("got %q want %q", got, want)
+			}
+		})
+	}
+
+	for _, country := range []string{"", "Narnia", "XX"} {
+		t.Run("rejects "+country, func(t *testing.T) {
+			if code, ok := models.CountryCode(country); ok {
+				t.Errorf("got %q want no code", code)
+			}
+		})
+	}
+}
+
+func TestCheckCountryRules(t *testing.T) {
+	valid := []models.Address{
+		{Country: "bg", PostalCode: " 1000 "},
+		{Country: "BG"},
+		{Country: "GB", PostalCode: "sw1a1aa"},
+		{Country: "NL", PostalCode: "1012ab"},
+		{Country: "GR", PostalCode: "105 57"},
+		{Country: "US", PostalCode: "94103-1234", Region: "ca"},
+		{Country: "CH", PostalCode: "8001", Region: "Zürich"},
+	}
+
+	for _, address := range valid {
+		t.Run(address.Country+" "+address.PostalCode, func(t *testing.T) {
+			models.NormalizePostalAddress(&address)
+
+			if err := models.CheckCountryRules(address); err != nil {
+				t.Errorf("got error %v", err)
+			}
+		})
+	}
+
+	t.Run("normalizes postal code and region", func(t *testing.T) {
+		address := models.Address{Country: "gb", PostalCode: "sw1a 1aa", Region: " Greater  London "}
+		models.NormalizePostalAddress(&address)
+
+		want := models.Address{Country: "GB", PostalCode: "SW1A 1AA", Region: "Greater London"}
+		if address != want {
+			t.Errorf("got %+v want %+v", address, want)
+		}
+	})
+
+	invalid := map[string]struct {
+		address models.Address
+		want    error
+	}{
+		"missing postal code":   {models.Address{Country: "DE"}, models.ErrMissingPostalCode},
+		"invalid postal code":   {models.Address{Country: "BG", PostalCode: "10000"}, models.ErrInvalidPostalCode},
+		"missing region":        {models.Address{Country: "US", PostalCode: "94103"}, models.ErrMissingRegion},
+		"unknown region":        {models.Address{Country: "US", PostalCode: "94103", Region: "California"}, models.ErrInvalidRegion},
+		"postal code of the UK": {models.Address{Country: "NL", PostalCode: "SW1A 1AA"}, models.ErrInvalidPostalCode},
+	}
+
+	for name, test := range invalid {
+		t.Run("rejects "+name, func(t *testing.T) {
+			models.NormalizePostalAddress(&test.address)
+
+			err := models.CheckCountryRules(test.address)
+			if err != test.want {
+				t.Errorf("got error %v want %v", err, test.want)
+			}
+		})
+	}
+}
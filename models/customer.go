@@ -1,10 +1,61 @@
 package models
 
+import "time"
+
+const (
+	ACTIVE_STATUS     = "active"
+	ANONYMIZED_STATUS = "anonymized"
+	MERGED_STATUS     = "merged"
+)
+
+// Customer is a customer account. MergedInto points at the surviving
+// account once this one was merged into it as a duplicate.
 type Customer struct {
-	Id          int
-	FirstName   string `db:"first_name"`
-	LastName    string `db:"last_name"`
-	PhoneNumber string `db:"phone_number"`
-	Email       string
-	Password    string
+	Id             int
+	FirstName      string `db:"first_name"`
+	LastName       string `db:"last_name"`
+	PhoneNumber    string `db:"phone_number"`
+	Email          string
+	CanonicalEmail string `db:"canonical_email"`
+	Password       string
+	AvatarURL      string `db:"avatar_url"`
+	Status         string
+	MergedInto     *int      `db:"merged_into"`
+	CreatedAt      time.Time `db:"created_at"`
+	UpdatedAt      time.Time `db:"updated_at"`
+	Version        int
+}
+
+// CustomerPatch holds the customer fields present in a partial update. Nil
+// fields are left untouched.
+type CustomerPatch struct {
+	FirstName   *string
+	LastName    *string
+	PhoneNumber *string
+	Email       *string
+	Password    *string
+	AvatarURL   *string
+}
+
+func (c CustomerPatch) Apply(customer Customer) Customer {
+	applyPatchField(&customer.FirstName, c.FirstName)
+	applyPatchField(&customer.LastName, c.LastName)
+	applyPatchField(&customer.PhoneNumber, c.PhoneNumber)
+	applyPatchField(&customer.Email, c.Email)
+	applyPatchField(&customer.Password, c.Password)
+	applyPatchField(&customer.AvatarURL, c.AvatarURL)
+
+	return customer
+}
+
+func (c CustomerPatch) columns() map[string]any {
+	columns := map[string]any{}
+	addPatchColumn(columns, "first_name", c.FirstName)
+	addPatchColumn(columns, "last_name", c.LastName)
+	addPatchColumn(columns, "phone_number", c.PhoneNumber)
+	addPatchColumn(columns, "email", c.Email)
+	addPatchColumn(columns, "password", c.Password)
+	addPatchColumn(columns, "avatar_url", c.AvatarURL)
+
+	return columns
 }
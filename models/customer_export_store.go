@@ -0,0 +1,12 @@
+package models
+
+import "time"
+
+// CustomerExportStore streams customers, addresses and saved preferences
+// changed at or after since, in ID order, to each. A zero since exports
+// every row.
+type CustomerExportStore interface {
+	ExportCustomers(since time.Time, each func(Customer) error) error
+	ExportAddresses(since time.Time, each func(Address) error) error
+	ExportPreferences(since time.Time, each func(Preferences) error) error
+}
@@ -0,0 +1,22 @@
+package models
+
+// ImportedCustomer is a validated and normalized record of a bulk import,
+// together with the line of the source it was read from. Exactly one of
+// its addresses, if it has any, is the default.
+type ImportedCustomer struct {
+	Line      int
+	Customer  Customer
+	Addresses []Address
+}
+
+// ImportRejection explains why the record on Line wasn't imported.
+type ImportRejection struct {
+	Line   int
+	Reason string
+}
+
+type ImportBatchReport struct {
+	Imported  int
+	Addresses int
+	Rejected  []ImportRejection
+}
@@ -0,0 +1,6 @@
+package models
+
+type CustomerImportStore interface {
+	GetImportCheckpoint(source string) (int, error)
+	ImportCustomers(source string, checkpoint int, batch []ImportedCustomer, dryRun bool) (ImportBatchReport, error)
+}
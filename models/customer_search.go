@@ -0,0 +1,98 @@
+package models
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	SORT_BY_ID         = "id"
+	SORT_BY_CREATED_AT = "created_at"
+	SORT_BY_FIRST_NAME = "first_name"
+	SORT_BY_LAST_NAME  = "last_name"
+	SORT_BY_EMAIL      = "email"
+)
+
+var ErrInvalidCursor = errors.New("search cursor is invalid")
+
+// CustomerSearch describes a page of an admin search over customers. Empty
+// filters match every customer.
+type CustomerSearch struct {
+	NamePrefix    string
+	EmailFragment string
+	PhoneFragment string
+	Status        string
+	CreatedAfter  time.Time
+	CreatedBefore time.Time
+	SortBy        string
+	Descending    bool
+	After         *SearchCursor
+	Limit         int
+}
+
+type CustomerPage struct {
+	Customers  []Customer
+	NextCursor *SearchCursor
+}
+
+// SearchCursor points at the last customer of a page. The following page
+// continues after the (Value, Id) pair in the order given by SortBy, so
+// customers created or deleted in between don't shift the results.
+type SearchCursor struct {
+	SortBy string
+	Value  string
+	Id     int
+}
+
+func NewSearchCursor(customer Customer, sortBy string) SearchCursor {
+	return SearchCursor{SortBy: sortBy, Value: SortValue(customer, sortBy), Id: customer.Id}
+}
+
+func (c SearchCursor) Encode() string {
+	data, _ := json.Marshal(c)
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+func DecodeSearchCursor(encoded string) (SearchCursor, error) {
+	data, err := base64.RawURLEncoding.DecodeString(encoded)
+	if err != nil {
+		return SearchCursor{}, ErrInvalidCursor
+	}
+
+	var cursor SearchCursor
+	if err = json.Unmarshal(data, &cursor); err != nil || cursor.Id < 1 {
+		return SearchCursor{}, ErrInvalidCursor
+	}
+
+	return cursor, nil
+}
+
+// SortValue returns the value of the sort column of a customer in the form
+// it is stored in a cursor.
+func SortValue(customer Customer, sortBy string) string {
+	switch sortBy {
+	case SORT_BY_CREATED_AT:
+		return customer.CreatedAt.UTC().Format(time.RFC3339Nano)
+	case SORT_BY_FIRST_NAME:
+		return customer.FirstName
+	case SORT_BY_LAST_NAME:
+		return customer.LastName
+	case SORT_BY_EMAIL:
+		return customer.Email
+	default:
+		return strconv.Itoa(customer.Id)
+	}
+}
+
+func PhoneDigits(phone string) string {
+	return strings.Map(func(r rune) rune {
+		if r >= '0' && r <= '9' {
+			return r
+		}
+		return -1
+	}, phone)
+}
@@ -4,6 +4,10 @@ type CustomerStore interface {
 	GetCustomerByID(id int) (Customer, error)
 	GetCustomerByEmail(email string) (Customer, error)
 	CreateCustomer(customer *Customer) error
-	DeleteCustomer(id int) error
+	SignUpCustomer(customer *Customer, consents []Consent, referral *ReferralSignup) error
+	DeleteCustomer(id int, version int) error
 	UpdateCustomer(customer *Customer) error
+	PatchCustomer(id int, version int, patch CustomerPatch) (Customer, error)
+	AnonymizeCustomer(id int) (ErasureReport, error)
+	SearchCustomers(search CustomerSearch) (CustomerPage, error)
 }
@@ -0,0 +1,8 @@
+package models
+
+type DuplicateStore interface {
+	DetectDuplicates() ([]DuplicateCandidate, error)
+	GetCandidates(status string) ([]DuplicateCandidate, error)
+	DismissCandidate(id int) error
+	MergeCustomers(candidateID int, survivorID int) (MergeReport, error)
+}
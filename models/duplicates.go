@@ -0,0 +1,301 @@
+package models
+
+import (
+	"math"
+	"sort"
+	"strings"
+	"time"
+	"unicode"
+	"unicode/utf8"
+)
+
+// Candidates scoring at least DUPLICATE_THRESHOLD are queued for review,
+// e.g. a shared phone number with the same name, or a near-identical name
+// at the same address.
+const DUPLICATE_THRESHOLD = 0.6
+
+// DUPLICATE_ADDRESS_DISTANCE is how close, in meters, two addresses have to
+// be to count as the same place.
+const DUPLICATE_ADDRESS_DISTANCE = 100.0
+
+const (
+	PENDING_CANDIDATE   = "pending"
+	MERGED_CANDIDATE    = "merged"
+	DISMISSED_CANDIDATE = "dismissed"
+)
+
+// Reasons a pair of customers was scored as duplicates.
+const (
+	SAME_PHONE_REASON   = "phone"
+	SAME_EMAIL_REASON   = "email"
+	SAME_NAME_REASON    = "name"
+	SIMILAR_NAME_REASON = "similar_name"
+	NEAR_ADDRESS_REASON = "address"
+)
+
+var duplicateWeights = map[string]float64{
+	SAME_PHONE_REASON:   0.5,
+	SAME_EMAIL_REASON:   0.3,
+	SAME_NAME_REASON:    0.35,
+	SIMILAR_NAME_REASON: 0.3,
+	NEAR_ADDRESS_REASON: 0.3,
+}
+
+var (
+	ErrInvalidMerge      = &StoreError{"only two active customers of a pending candidate can be merged"}
+	ErrCandidateReviewed = &StoreError{"duplicate candidate was already reviewed"}
+)
+
+// DuplicateCandidate is a pair of customers that likely belong to the same
+// person. CustomerId is always the lower of the two IDs.
+type DuplicateCandidate struct {
+	Id          int
+	CustomerId  int `db:"customer_id"`
+	DuplicateId int `db:"duplicate_id"`
+	Score       float64
+	Reasons     []string
+	Status      string
+	CreatedAt   time.Time  `db:"created_at"`
+	ReviewedAt  *time.Time `db:"reviewed_at"`
+}
+
+// MergeReport describes what was moved from the merged customer to the
+// survivor.
+type MergeReport struct {
+	SurvivorId        int
+	MergedId          int
+	Addresses         int
+	Preferences       bool
+	TransferredPoints int
+}
+
+// NormalizeName folds a full name for comparison: letters are lowercased,
+// everything else separates words, so "Ivan-Petrov " and "ivan petrov"
+// compare equal.
+func NormalizeName(firstName string, lastName string) string {
+	words := strings.FieldsFunc(strings.ToLower(firstName+" "+lastName), func(r rune) bool {
+		return !unicode.IsLetter(r)
+	})
+	return strings.Join(words, " ")
+}
+
+// phoneKey is the subscriber part of a phone number, which stays the same
+// when a number was entered with a different country code.
+func phoneKey(phoneNumber string) string {
+	digits := PhoneDigits(phoneNumber)
+	if len(digits) > 8 {
+		return digits[len(digits)-8:]
+	}
+	return digits
+}
+
+// emailKey is the whole canonical email. Local parts alone, like "info" or
+// "office", are shared by too many unrelated customers.
+func emailKey(email string) string {
+	return CanonicalEmail(email, true)
+}
+
+// nameInitials are the initials of the first and the last word of a
+// customer's name, which similar names almost always share.
+func nameInitials(customer Customer) string {
+	words := strings.Fields(NormalizeName(customer.FirstName, customer.LastName))
+	if len(words) == 0 {
+		return ""
+	}
+
+	first, _ := utf8.DecodeRuneInString(words[0])
+	last, _ := utf8.DecodeRuneInString(words[len(words)-1])
+	return string([]rune{first, last})
+}
+
+// ScoreDuplicate scores how likely two customers are the same person and
+// returns the reasons that contributed, capped at 1.
+func ScoreDuplicate(a Customer, aAddresses []Address, b Customer, bAddresses []Address) (float64, []string) {
+	reasons := []string{}
+
+	if phoneKey(a.PhoneNumber) == phoneKey(b.PhoneNumber) {
+		reasons = append(reasons, SAME_PHONE_REASON)
+	}
+
+	if emailKey(a.Email) == emailKey(b.Email) {
+		reasons = append(reasons, SAME_EMAIL_REASON)
+	}
+
+	aName, bName := NormalizeName(a.FirstName, a.LastName), NormalizeName(b.FirstName, b.LastName)
+	if aName == bName {
+		reasons = append(reasons, SAME_NAME_REASON)
+	} else if editDistance(aName, bName) <= 2 {
+		reasons = append(reasons, SIMILAR_NAME_REASON)
+	}
+
+	if nearAddresses(aAddresses, bAddresses) {
+		reasons = append(reasons, NEAR_ADDRESS_REASON)
+	}
+
+	score := 0.0
+	for _, reason := range reasons {
+		score += duplicateWeights[reason]
+	}
+
+	return math.Min(math.Round(score*100)/100, 1), reasons
+}
+
+func nearAddresses(aAddresses []Address, bAddresses []Address) bool {
+	for _, a := range aAddresses {
+		for _, b := range bAddresses {
+			if Distance(a.Lat, a.Lon, b.Lat, b.Lon) <= DUPLICATE_ADDRESS_DISTANCE {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// editDistance is the Levenshtein distance between two strings in runes.
+func editDistance(a string, b string) int {
+	ar, br := []rune(a), []rune(b)
+
+	previous := make([]int, len(br)+1)
+	for j := range previous {
+		previous[j] = j
+	}
+
+	for i := 1; i <= len(ar); i++ {
+		current := make([]int, len(br)+1)
+		current[0] = i
+		for j := 1; j <= len(br); j++ {
+			substitution := previous[j-1]
+			if ar[i-1] != br[j-1] {
+				substitution++
+			}
+			current[j] = min(previous[j]+1, current[j-1]+1, substitution)
+		}
+		previous = current
+	}
+
+	return previous[len(br)]
+}
+
+// DUPLICATE_CELL_PRECISION is the length of the geohash cells, roughly
+// 150 m wide, that addresses are blocked on.
+const DUPLICATE_CELL_PRECISION = 7
+
+// DUPLICATE_MAX_BLOCK bounds how many customers sharing a key are compared
+// with each other. A key shared by more, like a placeholder phone number,
+// doesn't identify anyone and would make the comparisons quadratic.
+const DUPLICATE_MAX_BLOCK = 100
+
+// duplicateKeys returns the sorted blocking keys of a customer: its phone
+// number, its email and, for each of its addresses, the geohash cells
+// within DUPLICATE_ADDRESS_DISTANCE of it together with its initials. As
+// names alone never reach DUPLICATE_THRESHOLD, and a nearby address only
+// does together with a similar name, customers sharing none of these keys
+// can't be duplicates. Addresses near the poles, which need too many cells,
+// aren't blocked on.
+func duplicateKeys(customer Customer, addresses []Address) []string {
+	keys := map[string]bool{
+		"phone:" + phoneKey(customer.PhoneNumber): true,
+		"email:" + emailKey(customer.Email):       true,
+	}
+
+	initials := nameInitials(customer)
+	for _, address := range addresses {
+		area := CircleArea(address.Lat, address.Lon, DUPLICATE_ADDRESS_DISTANCE)
+		for _, cell := range geohashCells(area.Box, DUPLICATE_CELL_PRECISION, MAX_COVER_CELLS) {
+			keys["address:"+cell+":"+initials] = true
+		}
+	}
+
+	sorted := make([]string, 0, len(keys))
+	for key := range keys {
+		sorted = append(sorted, key)
+	}
+	sort.Strings(sorted)
+	return sorted
+}
+
+// FindDuplicates returns the pairs of active customers scoring at least
+// DUPLICATE_THRESHOLD, ordered by descending score. Only customers sharing
+// one of their duplicateKeys are compared, and each pair only in the first
+// block it shares.
+func FindDuplicates(customers []Customer, addresses []Address) []DuplicateCandidate {
+	addressesByCustomer := map[int][]Address{}
+	for _, address := range addresses {
+		addressesByCustomer[address.CustomerId] = append(addressesByCustomer[address.CustomerId], address)
+	}
+
+	blocks := map[string][]int{}
+	keysByCustomer := map[int][]string{}
+	byId := map[int]Customer{}
+	for _, customer := range customers {
+		if customer.Status != "" && customer.Status != ACTIVE_STATUS {
+			continue
+		}
+		byId[customer.Id] = customer
+
+		keysByCustomer[customer.Id] = duplicateKeys(customer, addressesByCustomer[customer.Id])
+		for _, key := range keysByCustomer[customer.Id] {
+			blocks[key] = append(blocks[key], customer.Id)
+		}
+	}
+
+	candidates := []DuplicateCandidate{}
+	for key, block := range blocks {
+		if len(block) > DUPLICATE_MAX_BLOCK {
+			continue
+		}
+
+		for i := 0; i < len(block); i++ {
+			for j := i + 1; j < len(block); j++ {
+				pair := [2]int{min(block[i], block[j]), max(block[i], block[j])}
+				if firstSharedBlock(keysByCustomer[pair[0]], keysByCustomer[pair[1]], blocks) != key {
+					continue
+				}
+
+				a, b := byId[pair[0]], byId[pair[1]]
+				score, reasons := ScoreDuplicate(a, addressesByCustomer[a.Id], b, addressesByCustomer[b.Id])
+				if score >= DUPLICATE_THRESHOLD {
+					candidates = append(candidates, DuplicateCandidate{
+						CustomerId:  pair[0],
+						DuplicateId: pair[1],
+						Score:       score,
+						Reasons:     reasons,
+						Status:      PENDING_CANDIDATE,
+					})
+				}
+			}
+		}
+	}
+
+	sort.Slice(candidates, func(i, j int) bool {
+		if candidates[i].Score != candidates[j].Score {
+			return candidates[i].Score > candidates[j].Score
+		}
+		if candidates[i].CustomerId != candidates[j].CustomerId {
+			return candidates[i].CustomerId < candidates[j].CustomerId
+		}
+		return candidates[i].DuplicateId < candidates[j].DuplicateId
+	})
+
+	return candidates
+}
+
+// firstSharedBlock returns the first of the sorted keys two customers share
+// whose block is compared.
+func firstSharedBlock(aKeys []string, bKeys []string, blocks map[string][]int) string {
+	for i, j := 0, 0; i < len(aKeys) && j < len(bKeys); {
+		switch {
+		case aKeys[i] < bKeys[j]:
+			i++
+		case aKeys[i] > bKeys[j]:
+			j++
+		default:
+			if len(blocks[aKeys[i]]) <= DUPLICATE_MAX_BLOCK {
+				return aKeys[i]
+			}
+			i++
+			j++
+		}
+	}
+	return ""
+}
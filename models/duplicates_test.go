@@ -0,0 +1,74 @@
+package models_test
+
+import (
+	"fmt"
+	"reflect"
+	"testing"
+
+	"github.com/VitoNaychev/bt-customer-svc/models"
+)
+
+func TestFindDuplicates(t *testing.T) {
+	customers := []models.Customer{
+		{Id: 1, FirstName: "Ivan", LastName: "Petrov", PhoneNumber: "+359881234567", Email: "ivan@abv.bg", Status: models.ACTIVE_STATUS},
+		{Id: 2, FirstName: "ivan", LastName: "Petrov ", PhoneNumber: "+359881234567", Email: "ivan.petrov@gmail.com", Status: models.ACTIVE_STATUS},
+		{Id: 3, FirstName: "Maria", LastName: "Georgieva", PhoneNumber: "+359887000001", Email: "maria@abv.bg", Status: models.ACTIVE_STATUS},
+		{Id: 4, FirstName: "Mariya", LastName: "Georgieva", PhoneNumber: "+359887000002", Email: "mgeorgieva@gmail.com", Status: models.ACTIVE_STATUS},
+		{Id: 5, FirstName: "Georgi", LastName: "Ivanov", PhoneNumber: "+359887000003", Email: "georgi@abv.bg", Status: models.ACTIVE_STATUS},
+		{Id: 6, FirstName: "Ivan", LastName: "Petrov", PhoneNumber: "+40881234567", Email: "ivan@abv.bg", Status: models.ANONYMIZED_STATUS},
+	}
+	addresses := []models.Address{
+		{Id: 1, CustomerId: 3, Lat: 42.695111, Lon: 23.329184},
+		{Id: 2, CustomerId: 4, Lat: 42.695300, Lon: 23.329500},
+		{Id: 3, CustomerId: 5, Lat: 42.695111, Lon: 23.329184},
+	}
+
+	got := models.FindDuplicates(customers, addresses)
+
+	want := []models.DuplicateCandidate{
+		{CustomerId: 1, DuplicateId: 2, Score: 0.85, Reasons: []string{models.SAME_PHONE_REASON, models.SAME_NAME_REASON}, Status: models.PENDING_CANDIDATE},
+		{CustomerId: 3, DuplicateId: 4, Score: 0.6, Reasons: []string{models.SIMILAR_NAME_REASON, models.NEAR_ADDRESS_REASON}, Status: models.PENDING_CANDIDATE},
+	}
+
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got %v want %v", got, want)
+	}
+}
+
+func TestFindDuplicatesBlocking(t *testing.T) {
+	t.Run("doesn't match emails sharing only their local part", func(t *testing.T) {
+		customers := []models.Customer{
+			{Id: 1, FirstName: "Ivan", LastName: "Petrov", PhoneNumber: "+359881234567", Email: "info@abv.bg"},
+			{Id: 2, FirstName: "Ivan", LastName: "Petrov", PhoneNumber: "+359887000001", Email: "info@petrov.bg"},
+		}
+
+		got := models.FindDuplicates(customers, nil)
+
+		if len(got) != 0 {
+			t.Errorf("got %v want no candidates", got)
+		}
+	})
+
+	t.Run("skips keys shared by too many customers", func(t *testing.T) {
+		customers := []models.Customer{}
+		for id := 1; id <= models.DUPLICATE_MAX_BLOCK+1; id++ {
+			customers = append(customers, models.Customer{Id: id, FirstName: "Ivan", LastName: "Petrov",
+				PhoneNumber: "+359881234567", Email: fmt.Sprintf("ivan%d@abv.bg", id)})
+		}
+
+		got := models.FindDuplicates(customers, nil)
+
+		if len(got) != 0 {
+			t.Errorf("got %d candidates want none", len(got))
+		}
+	})
+}
+
+func TestNormalizeName(t *testing.T) {
+	got := models.NormalizeName(" Ivan-Marie", "PETROV ")
+	want := "ivan marie petrov"
+
+	if got != want {
+		t.Errorf("got %q want %q", got, want)
+	}
+}
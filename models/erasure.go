@@ -0,0 +1,95 @@
+package models
+
+import (
+	"fmt"
+	"math"
+)
+
+const ErasedValue = "erased"
+
+type ErasureReport struct {
+	CustomerId     int
+	CustomerFields []string
+	Addresses      []AddressErasure
+}
+
+type AddressErasure struct {
+	AddressId int
+	Fields    []string
+}
+
+// EraseCustomerPII replaces the personal data of a customer with tombstone
+// values and returns the scrubbed customer together with the names of the
+// fields that were changed. Email and phone tombstones are derived from the
+// customer ID so they keep satisfying the unique constraints. Merged
+// customers keep their status, so they still point at their survivor.
+func EraseCustomerPII(customer Customer) (Customer, []string) {
+	erased := customer
+	erased.FirstName = ErasedValue
+	erased.LastName = ErasedValue
+	erased.Email = fmt.Sprintf("erased-%d@erased.invalid", customer.Id)
+	erased.CanonicalEmail = erased.Email
+	erased.PhoneNumber = fmt.Sprintf("+999%010d", customer.Id)
+	erased.Password = ""
+	erased.AvatarURL = ""
+	if customer.Status != MERGED_STATUS {
+		erased.Status = ANONYMIZED_STATUS
+	}
+
+	fields := []string{}
+	fields = appendIfChanged(fields, "FirstName", customer.FirstName, erased.FirstName)
+	fields = appendIfChanged(fields, "LastName", customer.LastName, erased.LastName)
+	fields = appendIfChanged(fields, "PhoneNumber", customer.PhoneNumber, erased.PhoneNumber)
+	fields = appendIfChanged(fields, "Email", customer.Email, erased.Email)
+	fields = appendIfChanged(fields, "Password", customer.Password, erased.Password)
+	fields = appendIfChanged(fields, "AvatarURL", customer.AvatarURL, erased.AvatarURL)
+
+	return erased, fields
+}
+
+// EraseAddressPII scrubs the address lines, city, postal code and the free-text
+// delivery details and rounds the coordinates to one decimal place
+// (roughly 10 km), which is enough for aggregate reporting but no longer
+// identifies a household.
+func EraseAddressPII(address Address) (Address, []string) {
+	erased := address
+	erased.AddressLine1 = ErasedValue
+	erased.AddressLine2 = ""
+	erased.City = ErasedValue
+	erased.PostalCode = ""
+	erased.Lat = roundCoordinate(address.Lat)
+	erased.Lon = roundCoordinate(address.Lon)
+	erased.Label = ""
+	erased.DeliveryInstructions = ""
+	erased.Floor = ""
+	erased.Entrance = ""
+	erased.Apartment = ""
+	erased.ContactPhone = ""
+
+	fields := []string{}
+	fields = appendIfChanged(fields, "Lat", address.Lat, erased.Lat)
+	fields = appendIfChanged(fields, "Lon", address.Lon, erased.Lon)
+	fields = appendIfChanged(fields, "AddressLine1", address.AddressLine1, erased.AddressLine1)
+	fields = appendIfChanged(fields, "AddressLine2", address.AddressLine2, erased.AddressLine2)
+	fields = appendIfChanged(fields, "City", address.City, erased.City)
+	fields = appendIfChanged(fields, "PostalCode", address.PostalCode, erased.PostalCode)
+	fields = appendIfChanged(fields, "Label", address.Label, erased.Label)
+	fields = appendIfChanged(fields, "DeliveryInstructions", address.DeliveryInstructions, erased.DeliveryInstructions)
+	fields = appendIfChanged(fields, "Floor", address.Floor, erased.Floor)
+	fields = appendIfChanged(fields, "Entrance", address.Entrance, erased.Entrance)
+	fields = appendIfChanged(fields, "Apartment", address.Apartment, erased.Apartment)
+	fields = appendIfChanged(fields, "ContactPhone", address.ContactPhone, erased.ContactPhone)
+
+	return erased, fields
+}
+
+func roundCoordinate(coordinate float64) float64 {
+	return math.Round(coordinate*10) / 10
+}
+
+func appendIfChanged[T comparable](fields []string, name string, old, new T) []string {
+	if old != new {
+		return append(fields, name)
+	}
+	return fields
+}
@@ -4,6 +4,7 @@ import (
 	"errors"
 
 	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
 )
 
 type StoreError struct {
@@ -19,16 +20,97 @@ func NewStoreError(message string) *StoreError {
 }
 
 var (
-	ErrNotFound = &StoreError{"didn't find object in database"}
+	ErrNotFound            = &StoreError{"didn't find object in database"}
+	ErrVersionConflict     = &StoreError{"object was modified concurrently"}
+	ErrUniqueViolation     = &StoreError{"value is already taken"}
+	ErrForeignKeyViolation = &StoreError{"referenced object doesn't exist"}
+	ErrCheckViolation      = &StoreError{"value is not allowed"}
+	ErrNotNullViolation    = &StoreError{"value is required"}
 )
 
+// ConstraintError is returned when a write violates a constraint of the
+// schema. It wraps one of the violation errors above, so callers can match
+// it with errors.Is, and names the column the constraint is on. Column is
+// empty for constraints that aren't on a single known column.
+type ConstraintError struct {
+	Err        *StoreError
+	Constraint string
+	Column     string
+}
+
+func (c *ConstraintError) Error() string {
+	if c.Column == "" {
+		return c.Constraint + ": " + c.Err.Error()
+	}
+	return c.Column + ": " + c.Err.Error()
+}
+
+func (c *ConstraintError) Unwrap() error {
+	return c.Err
+}
+
+// SQLSTATE codes of the integrity constraint violations
+var constraintViolations = map[string]*StoreError{
+	"23505": ErrUniqueViolation,
+	"23503": ErrForeignKeyViolation,
+	"23514": ErrCheckViolation,
+	"23502": ErrNotNullViolation,
+}
+
 func pgxErrorToStoreError(err error) error {
 	if err != nil {
 		if errors.Is(err, pgx.ErrNoRows) {
 			return ErrNotFound
 		}
 
+		var pgError *pgconn.PgError
+		if errors.As(err, &pgError) {
+			if violation, ok := constraintViolations[pgError.Code]; ok {
+				return &ConstraintError{
+					Err:        violation,
+					Constraint: pgError.ConstraintName,
+					Column:     constraintColumn(pgError),
+				}
+			}
+		}
+
 		return NewStoreError(err.Error())
 	}
 	return nil
 }
+
+// constraintColumns names the column of the constraints that values sent
+// by clients can violate. Constraint names aren't parsed, since indexes,
+// primary keys and checks over several columns don't name a column.
+var constraintColumns = map[string]string{
+	"customers_phone_number_key":    "phone_number",
+	"customers_phone_number_check":  "phone_number",
+	"customers_canonical_email_key": "canonical_email",
+	"addresses_country_check":       "country",
+	"addresses_contact_phone_check": "contact_phone",
+	"addresses_is_default_check":    "is_default",
+	"addresses_one_default_idx":     "is_default",
+	"customer_tags_tag_check":       "tag",
+	"segments_name_key":             "name",
+	"segments_name_check":           "name",
+}
+
+// constraintColumn returns the column reported by Postgres, which is only
+// set for not-null violations, or the column of a known constraint.
+func constraintColumn(pgError *pgconn.PgError) string {
+	if pgError.ColumnName != "" {
+		return pgError.ColumnName
+	}
+
+	return constraintColumns[pgError.ConstraintName]
+}
+
+// pgxVersionedErrorToStoreError is used by conditional updates, where a
+// missing row means that the expected version didn't match.
+func pgxVersionedErrorToStoreError(err error) error {
+	if errors.Is(err, pgx.ErrNoRows) {
+		return ErrVersionConflict
+	}
+
+	return pgxErrorToStoreError(err)
+}
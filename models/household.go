@@ -0,0 +1,88 @@
+package models
+
+import "time"
+
+// Household roles. Owners invite and remove members and manage all the
+// addresses of the household; members use them and manage the ones they
+// added.
+const (
+	OWNER_ROLE  = "owner"
+	MEMBER_ROLE = "member"
+)
+
+const INVITATION_CODE_LENGTH = 12
+
+// INVITATION_LIFETIME is how long an invitation can be accepted for.
+const INVITATION_LIFETIME = 7 * 24 * time.Hour
+
+var (
+	ErrInvitationExpired = &StoreError{"invitation has expired"}
+	ErrInvitationUsed    = &StoreError{"invitation was already accepted"}
+	ErrAlreadyMember     = &StoreError{"customer is already a member of the household"}
+	ErrLastOwner         = &StoreError{"household must keep at least one owner"}
+)
+
+// Household shares its addresses between its members, e.g. a family or
+// flatmates ordering to the same home.
+type Household struct {
+	Id        int
+	Name      string
+	CreatedAt time.Time `db:"created_at"`
+}
+
+type HouseholdMember struct {
+	HouseholdId int `db:"household_id"`
+	CustomerId  int `db:"customer_id"`
+	Role        string
+	JoinedAt    time.Time `db:"joined_at"`
+}
+
+func (h HouseholdMember) IsOwner() bool {
+	return h.Role == OWNER_ROLE
+}
+
+// CanManage reports whether the member may edit and delete an address of
+// their household.
+func (h HouseholdMember) CanManage(address Address) bool {
+	return h.IsOwner() || address.CustomerId == h.CustomerId
+}
+
+// HouseholdInvitation lets whoever has the code join the household with
+// Role, once and until ExpiresAt.
+type HouseholdInvitation struct {
+	Code        string
+	HouseholdId int  `db:"household_id"`
+	InvitedBy   *int `db:"invited_by"`
+	Role        string
+	ExpiresAt   time.Time  `db:"expires_at"`
+	AcceptedBy  *int       `db:"accepted_by"`
+	AcceptedAt  *time.Time `db:"accepted_at"`
+	CreatedAt   time.Time  `db:"created_at"`
+}
+
+func NewHouseholdInvitation(householdId int, invitedBy int, role string, now time.Time) (HouseholdInvitation, error) {
+	code, err := newCode(INVITATION_CODE_LENGTH)
+	if err != nil {
+		return HouseholdInvitation{}, err
+	}
+
+	return HouseholdInvitation{
+		Code:        code,
+		HouseholdId: householdId,
+		InvitedBy:   &invitedBy,
+		Role:        role,
+		ExpiresAt:   now.Add(INVITATION_LIFETIME),
+	}, nil
+}
+
+// CheckAcceptable reports why the invitation can't be accepted at now, if
+// it can't.
+func (h HouseholdInvitation) CheckAcceptable(now time.Time) error {
+	if h.AcceptedAt != nil {
+		return ErrInvitationUsed
+	}
+	if !now.Before(h.ExpiresAt) {
+		return ErrInvitationExpired
+	}
+	return nil
+}
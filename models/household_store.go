@@ -0,0 +1,14 @@
+package models
+
+import "time"
+
+type HouseholdStore interface {
+	CreateHousehold(household *Household, ownerID int) error
+	GetHouseholdByID(id int) (Household, error)
+	GetHouseholdsByCustomerID(customerID int) ([]Household, error)
+	GetHouseholdMember(householdID int, customerID int) (HouseholdMember, error)
+	GetHouseholdMembers(householdID int) ([]HouseholdMember, error)
+	CreateInvitation(invitation *HouseholdInvitation) error
+	AcceptInvitation(code string, customerID int, now time.Time) (HouseholdMember, error)
+	RemoveHouseholdMember(householdID int, customerID int) error
+}
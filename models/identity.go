@@ -0,0 +1,133 @@
+package models
+
+import "strings"
+
+const DEFAULT_PHONE_REGION = "BG"
+
+var ErrInvalidPhoneNumber = &StoreError{"phone number can't be converted to E.164"}
+
+type phoneRegion struct {
+	callingCode string
+	trunkPrefix string
+}
+
+// phoneRegions holds the country calling code and the national trunk
+// prefix of the regions national phone numbers can be entered for.
+var phoneRegions = map[string]phoneRegion{
+	"AT": {"43", "0"},
+	"BG": {"359", "0"},
+	"DE": {"49", "0"},
+	"ES": {"34", ""},
+	"FR": {"33", "0"},
+	"GB": {"44", "0"},
+	"GR": {"30", ""},
+	"IT": {"39", ""},
+	"MK": {"389", "0"},
+	"NL": {"31", "0"},
+	"RO": {"40", "0"},
+	"RS": {"381", "0"},
+	"TR": {"90", "0"},
+	"US": {"1", "1"},
+}
+
+// NormalizePhoneNumber converts a phone number to E.164, e.g. "+359 88 123
+// 4567", "00359881234567" and, for region BG, "0881234567" all become
+// "+359881234567". Numbers without an international prefix are read as
+// national numbers of region.
+func NormalizePhoneNumber(phoneNumber string, region string) (string, error) {
+	number := strings.Map(func(r rune) rune {
+		if strings.ContainsRune(" -.()/", r) {
+			return -1
+		}
+		return r
+	}, strings.TrimSpace(phoneNumber))
+
+	var digits string
+	switch {
+	case strings.HasPrefix(number, "+"):
+		digits = number[1:]
+	case strings.HasPrefix(number, "00"):
+		digits = number[2:]
+	default:
+		phoneRegion, ok := phoneRegions[region]
+		if !ok {
+			return "", ErrInvalidPhoneNumber
+		}
+		national := strings.TrimPrefix(number, phoneRegion.trunkPrefix)
+		if len(national) < 6 {
+			return "", ErrInvalidPhoneNumber
+		}
+		digits = phoneRegion.callingCode + national
+	}
+
+	// E.164 numbers have at most 15 digits and calling codes never start
+	// with 0; anything shorter than 8 digits is not a subscriber number
+	if len(digits) < 8 || len(digits) > 15 || digits[0] == '0' || PhoneDigits(digits) != digits {
+		return "", ErrInvalidPhoneNumber
+	}
+
+	return "+" + digits, nil
+}
+
+// NormalizeEmail returns the form an email is stored and displayed in.
+func NormalizeEmail(email string) string {
+	return strings.ToLower(strings.TrimSpace(email))
+}
+
+// CanonicalEmail returns the identity of an email address, which is unique
+// across customers. With provider rules enabled it also folds the aliases
+// that well-known providers deliver to the same mailbox, so
+// "Ivan.Petrov+food@gmail.com" and "ivanpetrov@googlemail.com" collide.
+func CanonicalEmail(email string, providerRules bool) string {
+	email = NormalizeEmail(email)
+	if !providerRules {
+		return email
+	}
+
+	at := strings.LastIndex(email, "@")
+	if at < 0 {
+		return email
+	}
+	local, domain := email[:at], email[at+1:]
+
+	switch domain {
+	case "gmail.com", "googlemail.com":
+		local = strings.ReplaceAll(stripPlusTag(local), ".", "")
+		domain = "gmail.com"
+	case "outlook.com", "hotmail.com", "live.com", "icloud.com":
+		local = stripPlusTag(local)
+	}
+
+	return local + "@" + domain
+}
+
+func stripPlusTag(local string) string {
+	if plus := strings.Index(local, "+"); plus > 0 {
+		return local[:plus]
+	}
+	return local
+}
+
+// IdentityRules configure how phone numbers and emails are canonicalized.
+type IdentityRules struct {
+	PhoneRegion        string
+	EmailProviderRules bool
+}
+
+func DefaultIdentityRules() IdentityRules {
+	return IdentityRules{PhoneRegion: DEFAULT_PHONE_REGION}
+}
+
+// Normalize canonicalizes the phone number and emails of a customer.
+func (i IdentityRules) Normalize(customer *Customer) error {
+	phoneNumber, err := NormalizePhoneNumber(customer.PhoneNumber, i.PhoneRegion)
+	if err != nil {
+		return err
+	}
+
+	customer.PhoneNumber = phoneNumber
+	customer.Email = NormalizeEmail(customer.Email)
+	customer.CanonicalEmail = CanonicalEmail(customer.Email, i.EmailProviderRules)
+
+	return nil
+}
@@ -0,0 +1,70 @@
+package models_test
+
+import (
+	"testing"
+
+	"github.com/VitoNaychev/bt-customer-svc/models"
+)
+
+func TestNormalizePhoneNumber(t *testing.T) {
+	cases := []struct {
+		phoneNumber string
+		region      string
+		want        string
+	}{
+		{"+359 88 123 4567", "BG", "+359881234567"},
+		{"00359 88 123 4567", "BG", "+359881234567"},
+		{"088 123 4567", "BG", "+359881234567"},
+		{"(0)20-7946-0958", "GB", "+442079460958"},
+		{"210 123 4567", "GR", "+302101234567"},
+		{"+1 (415) 555-2671", "BG", "+14155552671"},
+	}
+
+	for _, test := range cases {
+		t.Run(test.phoneNumber, func(t *testing.T) {
+			got, err := models.NormalizePhoneNumber(test.phoneNumber, test.region)
+			if err != nil {
+				t.Fatalf("got error %v", err)
+			}
+
+			if got != test.want {
+				t.Errorf("got %q want %q", got, test.want)
+			}
+		})
+	}
+
+	invalid := []string{"", "12345", "+0 123 456 789", "088 CALL NOW", "+359 88 123 4567 8901 23"}
+	for _, phoneNumber := range invalid {
+		t.Run("rejects "+phoneNumber, func(t *testing.T) {
+			_, err := models.NormalizePhoneNumber(phoneNumber, "BG")
+			if err != models.ErrInvalidPhoneNumber {
+				t.Errorf("got error %v want %v", err, models.ErrInvalidPhoneNumber)
+			}
+		})
+	}
+}
+
+func TestCanonicalEmail(t *testing.T) {
+	cases := []struct {
+		email         string
+		providerRules bool
+		want          string
+	}{
+		{" Ivan@X.com ", false, "ivan@x.com"},
+		{"Ivan.Petrov+food@gmail.com", false, "ivan.petrov+food@gmail.com"},
+		{"Ivan.Petrov+food@gmail.com", true, "ivanpetrov@gmail.com"},
+		{"ivan.petrov@googlemail.com", true, "ivanpetrov@gmail.com"},
+		{"ivan.petrov+food@outlook.com", true, "ivan.petrov@outlook.com"},
+		{"ivan.petrov+food@abv.bg", true, "ivan.petrov+food@abv.bg"},
+	}
+
+	for _, test := range cases {
+		t.Run(test.email, func(t *testing.T) {
+			got := models.CanonicalEmail(test.email, test.providerRules)
+
+			if got != test.want {
+				t.Errorf("got %q want %q", got, test.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,116 @@
+package models
+
+import (
+	"fmt"
+	"time"
+)
+
+const (
+	EARN_TRANSACTION   = "earn"
+	REDEEM_TRANSACTION = "redeem"
+	EXPIRE_TRANSACTION = "expire"
+	ADJUST_TRANSACTION = "adjust"
+)
+
+// Ledger accounts. Every customer has their own CUSTOMER_ACCOUNT, the
+// others are system accounts that points come from or go to.
+const (
+	CUSTOMER_ACCOUNT    = "customer"
+	ISSUED_ACCOUNT      = "issued"
+	REDEEMED_ACCOUNT    = "redeemed"
+	EXPIRED_ACCOUNT     = "expired"
+	ADJUSTMENTS_ACCOUNT = "adjustments"
+)
+
+// POINTS_LIFETIME is how long earned points can be redeemed for.
+const POINTS_LIFETIME = 365 * 24 * time.Hour
+
+var (
+	ErrInsufficientPoints  = &StoreError{"customer doesn't have enough points"}
+	ErrTransactionConflict = &StoreError{"transaction ID was already used for a different transaction"}
+)
+
+// LoyaltyTransaction changes the points balance of a customer by Points,
+// which is positive for earn and negative for redeem and expire. The ID is
+// chosen by the caller, e.g. from the order that earned the points, so
+// retried requests don't record the same transaction twice.
+type LoyaltyTransaction struct {
+	Id          string
+	CustomerId  int `db:"customer_id"`
+	Kind        string
+	Points      int
+	Description string
+	ExpiresAt   *time.Time `db:"expires_at"`
+	CreatedAt   time.Time  `db:"created_at"`
+}
+
+type LedgerEntry struct {
+	TransactionId string
+	Account       string
+	CustomerId    *int
+	Amount        int
+}
+
+var counterAccounts = map[string]string{
+	EARN_TRANSACTION:   ISSUED_ACCOUNT,
+	REDEEM_TRANSACTION: REDEEMED_ACCOUNT,
+	EXPIRE_TRANSACTION: EXPIRED_ACCOUNT,
+	ADJUST_TRANSACTION: ADJUSTMENTS_ACCOUNT,
+}
+
+// Entries returns the two balanced ledger entries of a transaction: one on
+// the customer's account and the opposite one on the system account of its
+// kind.
+func (l LoyaltyTransaction) Entries() []LedgerEntry {
+	customerId := l.CustomerId
+
+	return []LedgerEntry{
+		{TransactionId: l.Id, Account: CUSTOMER_ACCOUNT, CustomerId: &customerId, Amount: l.Points},
+		{TransactionId: l.Id, Account: counterAccounts[l.Kind], Amount: -l.Points},
+	}
+}
+
+// SameAs reports whether a retried transaction matches the recorded one.
+func (l LoyaltyTransaction) SameAs(other LoyaltyTransaction) bool {
+	return l.Id == other.Id && l.CustomerId == other.CustomerId &&
+		l.Kind == other.Kind && l.Points == other.Points
+}
+
+func PointsBalance(transactions []LoyaltyTransaction) int {
+	balance := 0
+	for _, transaction := range transactions {
+		balance += transaction.Points
+	}
+	return balance
+}
+
+// ExpiredPoints returns how many points of a customer have expired at now
+// and are not yet written off. Spent points are taken from the earnings
+// that expire first, so only the part of the expired earnings that wasn't
+// covered by redemptions, expirations and negative adjustments is left.
+// Positive adjustments never expire.
+func ExpiredPoints(transactions []LoyaltyTransaction, now time.Time) int {
+	expiredEarnings, debited := 0, 0
+	for _, transaction := range transactions {
+		if transaction.Kind == EARN_TRANSACTION && transaction.ExpiresAt != nil && !transaction.ExpiresAt.After(now) {
+			expiredEarnings += transaction.Points
+		}
+		if transaction.Points < 0 {
+			debited -= transaction.Points
+		}
+	}
+
+	return max(expiredEarnings-debited, 0)
+}
+
+// NewExpireTransaction writes off expired points. Its ID is derived from the
+// customer and the time of the expiry run.
+func NewExpireTransaction(customerId int, points int, now time.Time) LoyaltyTransaction {
+	return LoyaltyTransaction{
+		Id:          fmt.Sprintf("expire-%d-%s", customerId, now.UTC().Format("20060102T150405")),
+		CustomerId:  customerId,
+		Kind:        EXPIRE_TRANSACTION,
+		Points:      -points,
+		Description: "points expired",
+	}
+}
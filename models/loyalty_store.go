@@ -0,0 +1,10 @@
+package models
+
+import "time"
+
+type LoyaltyStore interface {
+	RecordTransaction(transaction *LoyaltyTransaction) error
+	GetBalance(customerID int) (int, error)
+	GetTransactions(customerID int) ([]LoyaltyTransaction, error)
+	ExpirePoints(now time.Time) ([]LoyaltyTransaction, error)
+}
@@ -0,0 +1,80 @@
+package models_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/VitoNaychev/bt-customer-svc/models"
+)
+
+func TestExpiredPoints(t *testing.T) {
+	now := time.Date(2024, time.March, 1, 0, 0, 0, 0, time.UTC)
+	expired := now.Add(-time.Hour)
+	valid := now.Add(time.Hour)
+
+	earn := func(points int, expiresAt time.Time) models.LoyaltyTransaction {
+		return models.LoyaltyTransaction{Kind: models.EARN_TRANSACTION, Points: points, ExpiresAt: &expiresAt}
+	}
+	debit := func(kind string, points int) models.LoyaltyTransaction {
+		return models.LoyaltyTransaction{Kind: kind, Points: -points}
+	}
+
+	cases := map[string]struct {
+		transactions []models.LoyaltyTransaction
+		want         int
+	}{
+		"nothing expired": {
+			[]models.LoyaltyTransaction{earn(100, valid)},
+			0,
+		},
+		"unspent expired earnings": {
+			[]models.LoyaltyTransaction{earn(100, expired), earn(50, valid)},
+			100,
+		},
+		"redemptions spend the oldest earnings first": {
+			[]models.LoyaltyTransaction{earn(100, expired), earn(50, valid), debit(models.REDEEM_TRANSACTION, 30)},
+			70,
+		},
+		"redemptions over the expired earnings": {
+			[]models.LoyaltyTransaction{earn(100, expired), earn(50, valid), debit(models.REDEEM_TRANSACTION, 120)},
+			0,
+		},
+		"already written off": {
+			[]models.LoyaltyTransaction{earn(100, expired), debit(models.EXPIRE_TRANSACTION, 100)},
+			0,
+		},
+		"positive adjustments never expire": {
+			[]models.LoyaltyTransaction{{Kind: models.ADJUST_TRANSACTION, Points: 100}},
+			0,
+		},
+	}
+
+	for name, test := range cases {
+		t.Run(name, func(t *testing.T) {
+			got := models.ExpiredPoints(test.transactions, now)
+
+			if got != test.want {
+				t.Errorf("got %d want %d", got, test.want)
+			}
+		})
+	}
+}
+
+func TestLoyaltyTransactionEntries(t *testing.T) {
+	transaction := models.LoyaltyTransaction{Id: "order-1", CustomerId: 1, Kind: models.REDEEM_TRANSACTION, Points: -30}
+
+	entries := transaction.Entries()
+
+	sum := 0
+	for _, entry := range entries {
+		sum += entry.Amount
+	}
+
+	if sum != 0 {
+		t.Errorf("entries are not balanced, got sum %d", sum)
+	}
+
+	if entries[1].Account != models.REDEEMED_ACCOUNT {
+		t.Errorf("got counter account %q want %q", entries[1].Account, models.REDEEMED_ACCOUNT)
+	}
+}
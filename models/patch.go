@@ -0,0 +1,46 @@
+package models
+
+import (
+	"sort"
+	"strings"
+
+	"github.com/jackc/pgx/v5"
+)
+
+func applyPatchField[T any](field *T, value *T) {
+	if value != nil {
+		*field = *value
+	}
+}
+
+func addPatchColumn[T any](columns map[string]any, column string, value *T) {
+	if value != nil {
+		columns[column] = *value
+	}
+}
+
+// buildPatchQuery builds an update statement that only sets the given
+// columns of the row with the given ID and version, bumps the version and
+// returns the updated row. Without columns it only selects the row, so an
+// empty patch doesn't change the version, and with it the ETag.
+func buildPatchQuery(table string, id int, version int, columns map[string]any) (string, pgx.NamedArgs) {
+	if len(columns) == 0 {
+		return `select * from ` + table + ` where id=@id and version=@version`, pgx.NamedArgs{"id": id, "version": version}
+	}
+
+	names := make([]string, 0, len(columns))
+	for column := range columns {
+		names = append(names, column)
+	}
+	sort.Strings(names)
+
+	assignments := []string{"version=version+1"}
+	args := pgx.NamedArgs{"id": id, "version": version}
+	for _, column := range names {
+		assignments = append(assignments, column+"=@"+column)
+		args[column] = columns[column]
+	}
+
+	query := `update ` + table + ` set ` + strings.Join(assignments, ", ") + ` where id=@id and version=@version returning *`
+	return query, args
+}
@@ -5,14 +5,15 @@ import (
 	"fmt"
 
 	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
 )
 
 type PgAddressStore struct {
-	conn *pgx.Conn
+	conn *pgxpool.Pool
 }
 
 func NewPgAddressStore(ctx context.Context, connString string) (PgAddressStore, error) {
-	conn, err := pgx.Connect(ctx, connString)
+	conn, err := connect(ctx, connString)
 	if err != nil {
 		return PgAddressStore{}, fmt.Errorf("unable to connect to database: %w", err)
 	}
@@ -22,21 +23,41 @@ func NewPgAddressStore(ctx context.Context, connString string) (PgAddressStore,
 	return pgAddressStore, nil
 }
 
+// CreateAddress makes a personal address the default if it asks to be or if
+// it is the customer's first address.
 func (p *PgAddressStore) CreateAddress(address *Address) error {
-	query := `insert into addresses(customer_id, lat, lon, address_line1, address_line2, city, country) 
-	values (@customer_id, @lat, @lon, @address_line1, @address_line2, @city, @country) returning id`
-	args := pgx.NamedArgs{
-		"customer_id":   address.CustomerId,
-		"lat":           address.Lat,
-		"lon":           address.Lon,
-		"address_line1": address.AddressLine1,
-		"address_line2": address.AddressLine2,
-		"city":          address.City,
-		"country":       address.Country,
+	ctx := context.Background()
+
+	tx, err := p.conn.Begin(ctx)
+	if err != nil {
+		return pgxErrorToStoreError(err)
 	}
+	defer tx.Rollback(ctx)
+
+	if address.IsDefault && address.HouseholdId == nil {
+		if err = clearDefaultAddress(ctx, tx, address.CustomerId, 0); err != nil {
+			return err
+		}
+	}
+
+	query := `insert into addresses(customer_id, household_id, lat, lon, address_line1, address_line2, city, region,
+		postal_code, country, label, is_default, delivery_instructions, floor, entrance, apartment, contact_phone,
+		coordinates_mismatch)
+	values (@customer_id, @household_id, @lat, @lon, @address_line1, @address_line2, @city, @region, @postal_code,
+		@country, @label, @household_id::int is null
+			and (@is_default or not exists (select 1 from addresses where customer_id=@customer_id and is_default)),
+		@delivery_instructions, @floor, @entrance, @apartment, @contact_phone, @coordinates_mismatch)
+	returning id, is_default, version`
+	args := addressArgs(*address)
+	args["customer_id"] = address.CustomerId
+	args["household_id"] = address.HouseholdId
 
-	err := p.conn.QueryRow(context.Background(), query, args).Scan(&address.Id)
-	return err
+	err = tx.QueryRow(ctx, query, args).Scan(&address.Id, &address.IsDefault, &address.Version)
+	if err != nil {
+		return pgxErrorToStoreError(err)
+	}
+
+	return pgxErrorToStoreError(tx.Commit(ctx))
 }
 
 func (p *PgAddressStore) GetAddressByID(id int) (Address, error) {
@@ -55,8 +76,10 @@ func (p *PgAddressStore) GetAddressByID(id int) (Address, error) {
 	return address, nil
 }
 
+// GetAddressesByCustomerID returns the personal addresses of a customer,
+// without the ones they added to their households.
 func (p *PgAddressStore) GetAddressesByCustomerID(customerID int) ([]Address, error) {
-	query := `select * from addresses where customer_id=@customer_id`
+	query := `select * from addresses where customer_id=@customer_id and household_id is null`
 	args := pgx.NamedArgs{
 		"customer_id": customerID,
 	}
@@ -74,29 +97,253 @@ func (p *PgAddressStore) GetAddressesByCustomerID(customerID int) ([]Address, er
 	return address, nil
 }
 
-func (p *PgAddressStore) DeleteAddress(id int) error {
-	query := `delete from addresses where id=@id`
+func (p *PgAddressStore) GetAddressesByHouseholdID(householdID int) ([]Address, error) {
+	query := `select * from addresses where household_id=@household_id order by id`
 	args := pgx.NamedArgs{
-		"id": id,
+		"household_id": householdID,
 	}
 
-	_, err := p.conn.Exec(context.Background(), query, args)
-	return pgxErrorToStoreError(err)
+	row, _ := p.conn.Query(context.Background(), query, args)
+	addresses, err := pgx.CollectRows(row, pgx.RowToStructByName[Address])
+	if err != nil {
+		return nil, pgxErrorToStoreError(err)
+	}
+
+	return addresses, nil
 }
 
+// DeleteAddress deletes an address at the given version. It makes the
+// customer's oldest remaining personal address the default when the default
+// address is deleted.
+func (p *PgAddressStore) DeleteAddress(id int, version int) error {
+	ctx := context.Background()
+
+	tx, err := p.conn.Begin(ctx)
+	if err != nil {
+		return pgxErrorToStoreError(err)
+	}
+	defer tx.Rollback(ctx)
+
+	var customerId int
+	var isDefault bool
+	query := `delete from addresses where id=@id and version=@version returning customer_id, is_default`
+	err = tx.QueryRow(ctx, query, pgx.NamedArgs{"id": id, "version": version}).Scan(&customerId, &isDefault)
+	if err != nil {
+		return pgxVersionedErrorToStoreError(err)
+	}
+
+	if isDefault {
+		query = `update addresses set is_default=true, version=version+1
+			where id=(select min(id) from addresses where customer_id=@customer_id and household_id is null)`
+		if _, err = tx.Exec(ctx, query, pgx.NamedArgs{"customer_id": customerId}); err != nil {
+			return pgxErrorToStoreError(err)
+		}
+	}
+
+	query = `delete from address_revisions where address_id=@id and frozen_at is null`
+	if _, err = tx.Exec(ctx, query, pgx.NamedArgs{"id": id}); err != nil {
+		return pgxErrorToStoreError(err)
+	}
+
+	return pgxErrorToStoreError(tx.Commit(ctx))
+}
+
+// UpdateAddress can make the address the default, but not clear it.
 func (p *PgAddressStore) UpdateAddress(address *Address) error {
+	ctx := context.Background()
+
+	tx, err := p.conn.Begin(ctx)
+	if err != nil {
+		return pgxErrorToStoreError(err)
+	}
+	defer tx.Rollback(ctx)
+
+	if address.IsDefault {
+		if err = clearDefaultAddress(ctx, tx, address.CustomerId, address.Id); err != nil {
+			return err
+		}
+	}
+
 	query := `update addresses set lat=@lat, lon=@lon, address_line1=@address_line1,
-	address_line2=@address_line2, city=@city, country=@country where id=@id`
+	address_line2=@address_line2, city=@city, region=@region, postal_code=@postal_code, country=@country, label=@label,
+	is_default=is_default or @is_default, delivery_instructions=@delivery_instructions,
+	floor=@floor, entrance=@entrance, apartment=@apartment, contact_phone=@contact_phone,
+	coordinates_mismatch=@coordinates_mismatch, version=version+1
+	where id=@id and version=@version returning is_default, version`
+	args := addressArgs(*address)
+	args["id"] = address.Id
+	args["version"] = address.Version
+
+	err = tx.QueryRow(ctx, query, args).Scan(&address.IsDefault, &address.Version)
+	if err != nil {
+		return pgxVersionedErrorToStoreError(err)
+	}
+
+	return pgxErrorToStoreError(tx.Commit(ctx))
+}
+
+func (p *PgAddressStore) PatchAddress(id int, version int, patch AddressPatch) (Address, error) {
+	ctx := context.Background()
+
+	tx, err := p.conn.Begin(ctx)
+	if err != nil {
+		return Address{}, pgxErrorToStoreError(err)
+	}
+	defer tx.Rollback(ctx)
+
+	if patch.makesDefault() {
+		var customerId int
+		err = tx.QueryRow(ctx, `select customer_id from addresses where id=@id`, pgx.NamedArgs{"id": id}).Scan(&customerId)
+		if err != nil {
+			return Address{}, pgxErrorToStoreError(err)
+		}
+
+		if err = clearDefaultAddress(ctx, tx, customerId, id); err != nil {
+			return Address{}, err
+		}
+	}
+
+	query, args := buildPatchQuery("addresses", id, version, patch.columns())
+
+	row, _ := tx.Query(ctx, query, args)
+	address, err := pgx.CollectOneRow(row, pgx.RowToStructByName[Address])
+	if err != nil {
+		return Address{}, pgxVersionedErrorToStoreError(err)
+	}
+
+	if err = tx.Commit(ctx); err != nil {
+		return Address{}, pgxErrorToStoreError(err)
+	}
+
+	return address, nil
+}
+
+// GetAddressRevision also finds frozen revisions of deleted addresses.
+func (p *PgAddressStore) GetAddressRevision(addressId int, revision int) (AddressRevision, error) {
+	query := `select * from address_revisions where address_id=@address_id and revision=@revision`
+	args := pgx.NamedArgs{"address_id": addressId, "revision": revision}
+
+	row, _ := p.conn.Query(context.Background(), query, args)
+	addressRevision, err := pgx.CollectOneRow(row, pgx.RowToStructByName[AddressRevision])
+	if err != nil {
+		return AddressRevision{}, pgxErrorToStoreError(err)
+	}
+
+	return addressRevision, nil
+}
+
+// FreezeAddressRevision keeps the time a revision was first frozen, so
+// freezing it again for a retried checkout changes nothing.
+func (p *PgAddressStore) FreezeAddressRevision(addressId int, revision int) (AddressRevision, error) {
+	query := `update address_revisions set frozen_at=coalesce(frozen_at, now())
+		where address_id=@address_id and revision=@revision returning *`
+	args := pgx.NamedArgs{"address_id": addressId, "revision": revision}
+
+	row, _ := p.conn.Query(context.Background(), query, args)
+	addressRevision, err := pgx.CollectOneRow(row, pgx.RowToStructByName[AddressRevision])
+	if err != nil {
+		return AddressRevision{}, pgxErrorToStoreError(err)
+	}
+
+	return addressRevision, nil
+}
+
+type addressMatchRow struct {
+	Address
+	Distance float64
+}
+
+// SearchAddressesNear looks up the geohash cells covering the bounding box
+// of the area in addresses_geohash_idx, then filters the addresses in them
+// by the box and the radius. It fetches one match more than the page limit,
+// so it can tell whether there is a next page.
+func (p *PgAddressStore) SearchAddressesNear(search AddressProximitySearch) (AddressMatchPage, error) {
+	area := search.Area
+
+	query := `select a.*, d.distance
+	from unnest(@cells::text[]) as cell(prefix)
+	join addresses a on a.geohash >= cell.prefix collate "C" and a.geohash < cell.prefix || '{' collate "C"
+	cross join lateral (select 2 * @earth_radius::float8 * asin(sqrt(
+		power(sin(radians(a.lat::float8 - @lat::float8) / 2), 2) +
+		cos(radians(@lat::float8)) * cos(radians(a.lat::float8)) *
+		power(sin(radians(a.lon::float8 - @lon::float8) / 2), 2)
+	)) as distance) d
+	where a.lat between @min_lat and @max_lat and a.lon between @min_lon and @max_lon
+		and (@radius::float8 = 0 or d.distance <= @radius::float8)
+		and (d.distance, a.id) > (@cursor_distance::float8, @cursor_id::int)
+	order by d.distance, a.id
+	limit @limit`
+	args := pgx.NamedArgs{
+		"cells":           GeohashCover(area.Box, MAX_COVER_CELLS),
+		"earth_radius":    earthRadius,
+		"lat":             area.Lat,
+		"lon":             area.Lon,
+		"radius":          area.Radius,
+		"min_lat":         area.Box.MinLat,
+		"min_lon":         area.Box.MinLon,
+		"max_lat":         area.Box.MaxLat,
+		"max_lon":         area.Box.MaxLon,
+		"cursor_distance": -1.0,
+		"cursor_id":       0,
+		"limit":           search.Limit + 1,
+	}
+	if search.After != nil {
+		args["cursor_distance"] = search.After.Distance
+		args["cursor_id"] = search.After.Id
+	}
+
+	rows, _ := p.conn.Query(context.Background(), query, args)
+	matchRows, err := pgx.CollectRows(rows, pgx.RowToStructByName[addressMatchRow])
+	if err != nil {
+		return AddressMatchPage{}, pgxErrorToStoreError(err)
+	}
+
+	page := AddressMatchPage{Matches: []AddressMatch{}}
+	for _, row := range matchRows {
+		page.Matches = append(page.Matches, AddressMatch{Address: row.Address, Distance: row.Distance})
+	}
+
+	if len(page.Matches) > search.Limit {
+		page.Matches = page.Matches[:search.Limit]
+		last := page.Matches[search.Limit-1]
+		page.NextCursor = &ProximityCursor{Distance: last.Distance, Id: last.Address.Id}
+	}
+
+	return page, nil
+}
+
+// clearDefaultAddress unsets the default address of a customer, except
+// for the address with exceptID, so another one can become the default
+// without violating the one default per customer index.
+func clearDefaultAddress(ctx context.Context, tx pgx.Tx, customerID int, exceptID int) error {
+	query := `update addresses set is_default=false, version=version+1
+		where customer_id=@customer_id and is_default and id<>@id`
 	args := pgx.NamedArgs{
-		"id":            address.Id,
-		"lat":           address.Lat,
-		"lon":           address.Lon,
-		"address_line1": address.AddressLine1,
-		"address_line2": address.AddressLine2,
-		"city":          address.City,
-		"country":       address.Country,
+		"customer_id": customerID,
+		"id":          exceptID,
 	}
 
-	_, err := p.conn.Exec(context.Background(), query, args)
+	_, err := tx.Exec(ctx, query, args)
 	return pgxErrorToStoreError(err)
 }
+
+func addressArgs(address Address) pgx.NamedArgs {
+	return pgx.NamedArgs{
+		"lat":                   address.Lat,
+		"lon":                   address.Lon,
+		"address_line1":         address.AddressLine1,
+		"address_line2":         address.AddressLine2,
+		"city":                  address.City,
+		"region":                address.Region,
+		"postal_code":           address.PostalCode,
+		"country":               address.Country,
+		"label":                 address.Label,
+		"is_default":            address.IsDefault,
+		"delivery_instructions": address.DeliveryInstructions,
+		"floor":                 address.Floor,
+		"entrance":              address.Entrance,
+		"apartment":             address.Apartment,
+		"contact_phone":         address.ContactPhone,
+		"coordinates_mismatch":  address.CoordinatesMismatch,
+	}
+}
@@ -0,0 +1,82 @@
+package models
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+type PgConsentStore struct {
+	conn *pgxpool.Pool
+}
+
+func NewPgConsentStore(ctx context.Context, connString string) (PgConsentStore, error) {
+	conn, err := connect(ctx, connString)
+	if err != nil {
+		return PgConsentStore{}, fmt.Errorf("unable to connect to database: %w", err)
+	}
+
+	pgConsentStore := PgConsentStore{conn}
+	return pgConsentStore, nil
+}
+
+func (p *PgConsentStore) GetLatestDocuments() ([]ConsentDocument, error) {
+	query := `select distinct on (kind) * from consent_documents order by kind, published_at desc, id desc`
+
+	rows, _ := p.conn.Query(context.Background(), query)
+	documents, err := pgx.CollectRows(rows, pgx.RowToStructByName[ConsentDocument])
+
+	if err != nil {
+		return nil, pgxErrorToStoreError(err)
+	}
+
+	return documents, nil
+}
+
+func (p *PgConsentStore) PublishDocument(document *ConsentDocument) error {
+	query := `insert into consent_documents(kind, version, mandatory) 
+		values (@kind, @version, @mandatory) returning id, published_at`
+	args := pgx.NamedArgs{
+		"kind":      document.Kind,
+		"version":   document.Version,
+		"mandatory": document.Mandatory,
+	}
+
+	err := p.conn.QueryRow(context.Background(), query, args).Scan(&document.Id, &document.PublishedAt)
+	return pgxErrorToStoreError(err)
+}
+
+func (p *PgConsentStore) GetConsentHistory(customerID int) ([]Consent, error) {
+	query := `select * from consents where customer_id=@customer_id order by recorded_at, id`
+	args := pgx.NamedArgs{
+		"customer_id": customerID,
+	}
+
+	rows, _ := p.conn.Query(context.Background(), query, args)
+	consents, err := pgx.CollectRows(rows, pgx.RowToStructByName[Consent])
+
+	if err != nil {
+		return nil, pgxErrorToStoreError(err)
+	}
+
+	return consents, nil
+}
+
+func (p *PgConsentStore) RecordConsent(consent *Consent) error {
+	return insertConsent(context.Background(), p.conn, consent)
+}
+
+func insertConsent(ctx context.Context, q querier, consent *Consent) error {
+	query := `insert into consents(customer_id, document_id, granted) 
+		values (@customer_id, @document_id, @granted) returning id, recorded_at`
+	args := pgx.NamedArgs{
+		"customer_id": consent.CustomerId,
+		"document_id": consent.DocumentId,
+		"granted":     consent.Granted,
+	}
+
+	err := q.QueryRow(ctx, query, args).Scan(&consent.Id, &consent.RecordedAt)
+	return pgxErrorToStoreError(err)
+}
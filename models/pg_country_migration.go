@@ -0,0 +1,100 @@
+package models
+
+import (
+	"context"
+
+	"github.com/jackc/pgx/v5"
+)
+
+type CountryMigrationReport struct {
+	Updated    int
+	Unchanged  int
+	Unmappable []UnmappableCountry
+	// Constrained is set once every address has a country code and the
+	// schema only allows codes.
+	Constrained bool
+}
+
+// UnmappableCountry is an address whose country isn't a known name or
+// code. It has to be corrected by hand before the migration is re-run.
+type UnmappableCountry struct {
+	AddressId int
+	Country   string
+}
+
+type countryRow struct {
+	Id      int
+	Country string
+}
+
+// NormalizeCountries migrates an addresses table created before addresses
+// had a region and postal code and stored their country as a code. It adds
+// the new columns and rewrites country names to ISO 3166-1 alpha-2 codes;
+// names it can't map are reported and left untouched. Only when every row
+// has a code is the country column narrowed to codes, so the migration can
+// be re-run after fixing the reported rows. With dryRun the transaction is
+// rolled back.
+func (p *PgAddressStore) NormalizeCountries(dryRun bool) (CountryMigrationReport, error) {
+	ctx := context.Background()
+	report := CountryMigrationReport{Unmappable: []UnmappableCountry{}}
+
+	tx, err := p.conn.Begin(ctx)
+	if err != nil {
+		return report, pgxErrorToStoreError(err)
+	}
+	defer tx.Rollback(ctx)
+
+	_, err = tx.Exec(ctx, `
+		alter table addresses add column if not exists region varchar(40) not null default '';
+		alter table addresses add column if not exists postal_code varchar(10) not null default '';`)
+	if err != nil {
+		return report, pgxErrorToStoreError(err)
+	}
+
+	rows, _ := tx.Query(ctx, `select id, country from addresses order by id for update`)
+	countryRows, err := pgx.CollectRows(rows, pgx.RowToStructByName[countryRow])
+	if err != nil {
+		return report, pgxErrorToStoreError(err)
+	}
+
+	for _, row := range countryRows {
+		code, ok := CountryCode(row.Country)
+		if !ok {
+			report.Unmappable = append(report.Unmappable, UnmappableCountry{AddressId: row.Id, Country: row.Country})
+			continue
+		}
+
+		if code == row.Country {
+			report.Unchanged++
+			continue
+		}
+
+		_, err = tx.Exec(ctx, `update addresses set country=@country where id=@id`,
+			pgx.NamedArgs{"id": row.Id, "country": code})
+		if err != nil {
+			return report, pgxErrorToStoreError(err)
+		}
+		report.Updated++
+	}
+
+	if len(report.Unmappable) == 0 {
+		_, err = tx.Exec(ctx, `
+			alter table addresses alter column country type char(2);
+			alter table addresses drop constraint if exists addresses_country_check;
+			alter table addresses add constraint addresses_country_check check (country ~ '^[A-Z]{2}$');`)
+		if err != nil {
+			return report, pgxErrorToStoreError(err)
+		}
+		report.Constrained = true
+	}
+
+	if dryRun {
+		return report, nil
+	}
+
+	if err = tx.Commit(ctx); err != nil {
+		return report, pgxErrorToStoreError(err)
+	}
+
+	return report, nil
+}
@@ -0,0 +1,167 @@
+package models
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// GetImportCheckpoint returns the last line of source that was imported,
+// or 0 if nothing was imported from it yet.
+func (p *PgCustomerStore) GetImportCheckpoint(source string) (int, error) {
+	query := `select line from import_checkpoints where source=@source`
+	args := pgx.NamedArgs{
+		"source": source,
+	}
+
+	var line int
+	err := p.conn.QueryRow(context.Background(), query, args).Scan(&line)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, pgxErrorToStoreError(err)
+	}
+
+	return line, nil
+}
+
+// ImportCustomers copies a batch of normalized customers and their
+// addresses into the database and moves the checkpoint of source to
+// checkpoint in the same transaction, so a batch is either imported and
+// skipped on resume, or not imported at all. Customers whose phone number
+// or email is already taken are rejected instead of failing the batch.
+// With dryRun the transaction is rolled back.
+func (p *PgCustomerStore) ImportCustomers(source string, checkpoint int, batch []ImportedCustomer, dryRun bool) (ImportBatchReport, error) {
+	ctx := context.Background()
+	report := ImportBatchReport{Rejected: []ImportRejection{}}
+
+	tx, err := p.conn.Begin(ctx)
+	if err != nil {
+		return report, pgxErrorToStoreError(err)
+	}
+	defer tx.Rollback(ctx)
+
+	phoneNumbers := make([]string, len(batch))
+	canonicalEmails := make([]string, len(batch))
+	for i, imported := range batch {
+		phoneNumbers[i] = imported.Customer.PhoneNumber
+		canonicalEmails[i] = imported.Customer.CanonicalEmail
+	}
+
+	query := `select id, phone_number, email, canonical_email from customers
+		where phone_number = any(@phone_numbers) or canonical_email = any(@canonical_emails)`
+	args := pgx.NamedArgs{
+		"phone_numbers":    phoneNumbers,
+		"canonical_emails": canonicalEmails,
+	}
+
+	rows, _ := tx.Query(ctx, query, args)
+	existing, err := pgx.CollectRows(rows, pgx.RowToStructByName[identityRow])
+	if err != nil {
+		return report, pgxErrorToStoreError(err)
+	}
+
+	phoneOwners := map[string]int{}
+	emailOwners := map[string]int{}
+	for _, identity := range existing {
+		phoneOwners[identity.PhoneNumber] = identity.Id
+		if identity.CanonicalEmail != nil {
+			emailOwners[*identity.CanonicalEmail] = identity.Id
+		}
+	}
+
+	accepted := []ImportedCustomer{}
+	for _, imported := range batch {
+		if owner, ok := phoneOwners[imported.Customer.PhoneNumber]; ok {
+			reason := fmt.Sprintf("phone number is already used by customer %d", owner)
+			report.Rejected = append(report.Rejected, ImportRejection{Line: imported.Line, Reason: reason})
+			continue
+		}
+		if owner, ok := emailOwners[imported.Customer.CanonicalEmail]; ok {
+			reason := fmt.Sprintf("email is already used by customer %d", owner)
+			report.Rejected = append(report.Rejected, ImportRejection{Line: imported.Line, Reason: reason})
+			continue
+		}
+		accepted = append(accepted, imported)
+	}
+
+	if len(accepted) > 0 {
+		if err = copyImportedCustomers(ctx, tx, accepted, &report); err != nil {
+			return report, err
+		}
+	}
+
+	query = `insert into import_checkpoints(source, line) values (@source, @line)
+		on conflict (source) do update set line=excluded.line, updated_at=now()`
+	args = pgx.NamedArgs{
+		"source": source,
+		"line":   checkpoint,
+	}
+
+	if _, err = tx.Exec(ctx, query, args); err != nil {
+		return report, pgxErrorToStoreError(err)
+	}
+
+	if dryRun {
+		return report, nil
+	}
+
+	if err = tx.Commit(ctx); err != nil {
+		return report, pgxErrorToStoreError(err)
+	}
+
+	return report, nil
+}
+
+// copyImportedCustomers reserves IDs from the customers sequence up front,
+// since COPY can't return them, and uses them as the customer_id of the
+// copied addresses.
+func copyImportedCustomers(ctx context.Context, tx pgx.Tx, accepted []ImportedCustomer, report *ImportBatchReport) error {
+	query := `select nextval(pg_get_serial_sequence('customers', 'id')) from generate_series(1, @count)`
+	args := pgx.NamedArgs{
+		"count": len(accepted),
+	}
+
+	rows, _ := tx.Query(ctx, query, args)
+	ids, err := pgx.CollectRows(rows, pgx.RowTo[int])
+	if err != nil {
+		return pgxErrorToStoreError(err)
+	}
+
+	customerRows := make([][]any, len(accepted))
+	addressRows := [][]any{}
+	for i, imported := range accepted {
+		customer := imported.Customer
+		customerRows[i] = []any{ids[i], customer.FirstName, customer.LastName, customer.PhoneNumber,
+			customer.Email, customer.CanonicalEmail, customer.Password}
+
+		for _, address := range imported.Addresses {
+			addressRows = append(addressRows, []any{ids[i], address.Lat, address.Lon,
+				address.AddressLine1, address.AddressLine2, address.City, address.Region, address.PostalCode, address.Country, address.Label,
+				address.IsDefault, address.DeliveryInstructions, address.Floor, address.Entrance,
+				address.Apartment, address.ContactPhone})
+		}
+	}
+
+	_, err = tx.CopyFrom(ctx, pgx.Identifier{"customers"},
+		[]string{"id", "first_name", "last_name", "phone_number", "email", "canonical_email", "password"},
+		pgx.CopyFromRows(customerRows))
+	if err != nil {
+		return pgxErrorToStoreError(err)
+	}
+
+	_, err = tx.CopyFrom(ctx, pgx.Identifier{"addresses"},
+		[]string{"customer_id", "lat", "lon", "address_line1", "address_line2", "city", "region", "postal_code", "country", "label",
+			"is_default", "delivery_instructions", "floor", "entrance", "apartment", "contact_phone"},
+		pgx.CopyFromRows(addressRows))
+	if err != nil {
+		return pgxErrorToStoreError(err)
+	}
+
+	report.Imported = len(accepted)
+	report.Addresses = len(addressRows)
+	return nil
+}
@@ -3,28 +3,37 @@ package models
 import (
 	"context"
 	"fmt"
+	"strings"
 
 	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
 )
 
 type PgCustomerStore struct {
-	conn *pgx.Conn
+	conn  *pgxpool.Pool
+	rules IdentityRules
 }
 
 func NewPgCustomerStore(ctx context.Context, connString string) (PgCustomerStore, error) {
-	conn, err := pgx.Connect(ctx, connString)
+	conn, err := connect(ctx, connString)
 	if err != nil {
 		return PgCustomerStore{}, fmt.Errorf("unable to connect to database: %w", err)
 	}
 
-	pgCustomerStore := PgCustomerStore{conn}
+	pgCustomerStore := PgCustomerStore{conn: conn, rules: DefaultIdentityRules()}
 	return pgCustomerStore, nil
 }
 
+// SetIdentityRules changes how phone numbers and emails are canonicalized
+// on write and lookup.
+func (p *PgCustomerStore) SetIdentityRules(rules IdentityRules) {
+	p.rules = rules
+}
+
 func (p *PgCustomerStore) GetCustomerByEmail(email string) (Customer, error) {
-	query := `select * from customers where email=@email`
+	query := `select * from customers where canonical_email=@canonical_email`
 	args := pgx.NamedArgs{
-		"email": email,
+		"canonical_email": CanonicalEmail(email, p.rules.EmailProviderRules),
 	}
 
 	row, _ := p.conn.Query(context.Background(), query, args)
@@ -54,42 +63,350 @@ func (p *PgCustomerStore) GetCustomerByID(id int) (Customer, error) {
 }
 
 func (p *PgCustomerStore) CreateCustomer(customer *Customer) error {
-	query := `insert into customers(first_name, last_name, email, phone_number, password) 
-		values (@firstName, @lastName, @email, @phone_number, @password) returning id`
+	if err := p.rules.Normalize(customer); err != nil {
+		return err
+	}
+
+	return insertCustomer(context.Background(), p.conn, customer)
+}
+
+// SignUpCustomer creates a customer together with the consents they gave
+// and the referral they signed up with. Either all of them are written or
+// none is, so a failed signup can be retried without the customer already
+// existing.
+func (p *PgCustomerStore) SignUpCustomer(customer *Customer, consents []Consent, referral *ReferralSignup) error {
+	if err := p.rules.Normalize(customer); err != nil {
+		return err
+	}
+
+	ctx := context.Background()
+
+	tx, err := p.conn.Begin(ctx)
+	if err != nil {
+		return pgxErrorToStoreError(err)
+	}
+	defer tx.Rollback(ctx)
+
+	if err = insertCustomer(ctx, tx, customer); err != nil {
+		return err
+	}
+
+	for i := range consents {
+		consents[i].CustomerId = customer.Id
+		if err = insertConsent(ctx, tx, &consents[i]); err != nil {
+			return err
+		}
+	}
+
+	if referral != nil {
+		newReferral := referral.Referral(*customer)
+		if err = recordReferral(ctx, tx, &newReferral); err != nil {
+			return err
+		}
+	}
+
+	return pgxErrorToStoreError(tx.Commit(ctx))
+}
+
+func insertCustomer(ctx context.Context, q querier, customer *Customer) error {
+	query := `insert into customers(first_name, last_name, email, canonical_email, phone_number, password) 
+		values (@firstName, @lastName, @email, @canonical_email, @phone_number, @password) returning id, status, created_at, version`
 	args := pgx.NamedArgs{
-		"firstName":    customer.FirstName,
-		"lastName":     customer.LastName,
-		"email":        customer.Email,
-		"phone_number": customer.PhoneNumber,
-		"password":     customer.Password,
+		"firstName":       customer.FirstName,
+		"lastName":        customer.LastName,
+		"email":           customer.Email,
+		"canonical_email": customer.CanonicalEmail,
+		"phone_number":    customer.PhoneNumber,
+		"password":        customer.Password,
 	}
 
-	err := p.conn.QueryRow(context.Background(), query, args).Scan(&customer.Id)
+	err := q.QueryRow(ctx, query, args).Scan(&customer.Id, &customer.Status, &customer.CreatedAt, &customer.Version)
 	return pgxErrorToStoreError(err)
 }
 
-func (p *PgCustomerStore) DeleteCustomer(id int) error {
-	query := `delete from customers where id=@id`
+// DeleteCustomer deletes a customer at the given version and hands the
+// households they owned over to the remaining members, like
+// AnonymizeCustomer does.
+func (p *PgCustomerStore) DeleteCustomer(id int, version int) error {
+	ctx := context.Background()
+
+	tx, err := p.conn.Begin(ctx)
+	if err != nil {
+		return pgxErrorToStoreError(err)
+	}
+	defer tx.Rollback(ctx)
+
+	if err = leaveHouseholds(ctx, tx, id); err != nil {
+		return err
+	}
+
+	query := `delete from customers where id=@id and version=@version`
 	args := pgx.NamedArgs{
-		"id": id,
+		"id":      id,
+		"version": version,
 	}
 
-	_, err := p.conn.Exec(context.Background(), query, args)
-	return pgxErrorToStoreError(err)
+	result, err := tx.Exec(ctx, query, args)
+	if err != nil {
+		return pgxErrorToStoreError(err)
+	}
+
+	if result.RowsAffected() == 0 {
+		return ErrVersionConflict
+	}
+
+	return pgxErrorToStoreError(tx.Commit(ctx))
 }
 
 func (p *PgCustomerStore) UpdateCustomer(customer *Customer) error {
+	if err := p.rules.Normalize(customer); err != nil {
+		return err
+	}
+
 	query := `update customers set first_name=@first_name, last_name=@last_name, 
-		email=@email, phone_number=@phone_number, password=@password where id=@id`
+		email=@email, canonical_email=@canonical_email, phone_number=@phone_number, password=@password,
+		version=version+1 where id=@id and version=@version returning version`
 	args := pgx.NamedArgs{
-		"id":           customer.Id,
-		"first_name":   customer.FirstName,
-		"last_name":    customer.LastName,
-		"email":        customer.Email,
-		"phone_number": customer.PhoneNumber,
-		"password":     customer.Password,
+		"id":              customer.Id,
+		"first_name":      customer.FirstName,
+		"last_name":       customer.LastName,
+		"email":           customer.Email,
+		"canonical_email": customer.CanonicalEmail,
+		"phone_number":    customer.PhoneNumber,
+		"password":        customer.Password,
+		"version":         customer.Version,
+	}
+
+	err := p.conn.QueryRow(context.Background(), query, args).Scan(&customer.Version)
+	return pgxVersionedErrorToStoreError(err)
+}
+
+func (p *PgCustomerStore) PatchCustomer(id int, version int, patch CustomerPatch) (Customer, error) {
+	columns := patch.columns()
+
+	if patch.PhoneNumber != nil {
+		phoneNumber, err := NormalizePhoneNumber(*patch.PhoneNumber, p.rules.PhoneRegion)
+		if err != nil {
+			return Customer{}, err
+		}
+		columns["phone_number"] = phoneNumber
+	}
+
+	if patch.Email != nil {
+		columns["email"] = NormalizeEmail(*patch.Email)
+		columns["canonical_email"] = CanonicalEmail(*patch.Email, p.rules.EmailProviderRules)
+	}
+
+	query, args := buildPatchQuery("customers", id, version, columns)
+
+	row, _ := p.conn.Query(context.Background(), query, args)
+	customer, err := pgx.CollectOneRow(row, pgx.RowToStructByName[Customer])
+
+	if err != nil {
+		return Customer{}, pgxVersionedErrorToStoreError(err)
+	}
+
+	return customer, nil
+}
+
+func (p *PgCustomerStore) AnonymizeCustomer(id int) (ErasureReport, error) {
+	ctx := context.Background()
+
+	tx, err := p.conn.Begin(ctx)
+	if err != nil {
+		return ErasureReport{}, pgxErrorToStoreError(err)
+	}
+	defer tx.Rollback(ctx)
+
+	row, _ := tx.Query(ctx, `select * from customers where id=@id for update`, pgx.NamedArgs{"id": id})
+	customer, err := pgx.CollectOneRow(row, pgx.RowToStructByName[Customer])
+	if err != nil {
+		return ErasureReport{}, pgxErrorToStoreError(err)
 	}
 
-	_, err := p.conn.Exec(context.Background(), query, args)
+	erasedCustomer, fields := EraseCustomerPII(customer)
+	report := ErasureReport{CustomerId: id, CustomerFields: fields, Addresses: []AddressErasure{}}
+
+	query := `update customers set first_name=@first_name, last_name=@last_name,
+		email=@email, canonical_email=@canonical_email, phone_number=@phone_number, password=@password,
+		avatar_url=@avatar_url, status=@status, version=version+1 where id=@id`
+	args := pgx.NamedArgs{
+		"id":              erasedCustomer.Id,
+		"first_name":      erasedCustomer.FirstName,
+		"last_name":       erasedCustomer.LastName,
+		"email":           erasedCustomer.Email,
+		"canonical_email": erasedCustomer.CanonicalEmail,
+		"phone_number":    erasedCustomer.PhoneNumber,
+		"password":        erasedCustomer.Password,
+		"avatar_url":      erasedCustomer.AvatarURL,
+		"status":          erasedCustomer.Status,
+	}
+	if _, err = tx.Exec(ctx, query, args); err != nil {
+		return ErasureReport{}, pgxErrorToStoreError(err)
+	}
+
+	// the addresses the customer added to their households belong to the
+	// other members, so only the personal ones are erased
+	rows, _ := tx.Query(ctx, `select * from addresses where customer_id=@customer_id and household_id is null
+		order by id for update`, pgx.NamedArgs{"customer_id": id})
+	addresses, err := pgx.CollectRows(rows, pgx.RowToStructByName[Address])
+	if err != nil {
+		return ErasureReport{}, pgxErrorToStoreError(err)
+	}
+
+	for _, address := range addresses {
+		erasedAddress, fields := EraseAddressPII(address)
+
+		query := `update addresses set lat=@lat, lon=@lon, address_line1=@address_line1,
+			address_line2=@address_line2, city=@city, postal_code=@postal_code, label=@label, delivery_instructions=@delivery_instructions,
+			floor=@floor, entrance=@entrance, apartment=@apartment, contact_phone=@contact_phone,
+			version=version+1 where id=@id`
+		args := addressArgs(erasedAddress)
+		args["id"] = erasedAddress.Id
+		if _, err = tx.Exec(ctx, query, args); err != nil {
+			return ErasureReport{}, pgxErrorToStoreError(err)
+		}
+
+		report.Addresses = append(report.Addresses, AddressErasure{AddressId: address.Id, Fields: fields})
+	}
+
+	// revisions frozen by orders are erased as well, including those of
+	// addresses deleted since; the orders keep pointing at them, but only
+	// the erased form is left. Coordinates are rounded like EraseAddressPII
+	// does.
+	query = `update address_revisions set lat=round(lat, 1), lon=round(lon, 1), address_line1=@erased,
+		address_line2='', city=@erased, postal_code='', delivery_instructions='', floor='', entrance='',
+		apartment='', contact_phone='' where customer_id=@customer_id and household_id is null`
+	if _, err = tx.Exec(ctx, query, pgx.NamedArgs{"customer_id": id, "erased": ErasedValue}); err != nil {
+		return ErasureReport{}, pgxErrorToStoreError(err)
+	}
+
+	if err = leaveHouseholds(ctx, tx, id); err != nil {
+		return ErasureReport{}, err
+	}
+
+	if err = tx.Commit(ctx); err != nil {
+		return ErasureReport{}, pgxErrorToStoreError(err)
+	}
+
+	return report, nil
+}
+
+// leaveHouseholds removes a customer from their households. Households the
+// customer was the last owner of are taken over by their longest-standing
+// member.
+func leaveHouseholds(ctx context.Context, tx pgx.Tx, customerID int) error {
+	rows, _ := tx.Query(ctx, `delete from household_members where customer_id=@customer_id returning household_id`,
+		pgx.NamedArgs{"customer_id": customerID})
+	householdIDs, err := pgx.CollectRows(rows, pgx.RowTo[int])
+	if err != nil {
+		return pgxErrorToStoreError(err)
+	}
+
+	query := `update household_members set role=@owner where (household_id, customer_id) in (
+			select distinct on (household_id) household_id, customer_id from household_members
+			where household_id = any(@household_ids) order by household_id, joined_at, customer_id)
+		and household_id not in (select household_id from household_members where role=@owner)`
+	args := pgx.NamedArgs{
+		"household_ids": householdIDs,
+		"owner":         OWNER_ROLE,
+	}
+
+	_, err = tx.Exec(ctx, query, args)
 	return pgxErrorToStoreError(err)
 }
+
+func (p *PgCustomerStore) SearchCustomers(search CustomerSearch) (CustomerPage, error) {
+	query, args := buildCustomerSearchQuery(search)
+
+	rows, _ := p.conn.Query(context.Background(), query, args)
+	customers, err := pgx.CollectRows(rows, pgx.RowToStructByName[Customer])
+	if err != nil {
+		return CustomerPage{}, pgxErrorToStoreError(err)
+	}
+
+	page := CustomerPage{Customers: customers}
+	if len(customers) > search.Limit {
+		page.Customers = customers[:search.Limit]
+		cursor := NewSearchCursor(page.Customers[search.Limit-1], search.SortBy)
+		page.NextCursor = &cursor
+	}
+
+	return page, nil
+}
+
+// searchSortColumns maps the sort keys of a CustomerSearch to the column
+// they order by and the type a cursor value is cast to.
+var searchSortColumns = map[string][2]string{
+	SORT_BY_ID:         {"id", "int"},
+	SORT_BY_CREATED_AT: {"created_at", "timestamptz"},
+	SORT_BY_FIRST_NAME: {"first_name", "text"},
+	SORT_BY_LAST_NAME:  {"last_name", "text"},
+	SORT_BY_EMAIL:      {"email", "text"},
+}
+
+// buildCustomerSearchQuery builds a keyset paginated select that fetches
+// one customer more than the page limit, so the caller can tell whether
+// there is a next page. The filter expressions match the trigram indexes
+// in init.sql.
+func buildCustomerSearchQuery(search CustomerSearch) (string, pgx.NamedArgs) {
+	conditions := []string{"true"}
+	args := pgx.NamedArgs{"limit": search.Limit + 1}
+
+	if search.NamePrefix != "" {
+		conditions = append(conditions, `(lower(first_name) like @name_prefix
+			or lower(last_name) like @name_prefix
+			or lower(first_name) || ' ' || lower(last_name) like @name_prefix)`)
+		args["name_prefix"] = escapeLike(strings.ToLower(search.NamePrefix)) + "%"
+	}
+
+	if search.EmailFragment != "" {
+		conditions = append(conditions, `lower(email) like @email_fragment`)
+		args["email_fragment"] = "%" + escapeLike(strings.ToLower(search.EmailFragment)) + "%"
+	}
+
+	if search.PhoneFragment != "" {
+		conditions = append(conditions, `regexp_replace(phone_number, '[^0-9]', '', 'g') like @phone_fragment`)
+		args["phone_fragment"] = "%" + PhoneDigits(search.PhoneFragment) + "%"
+	}
+
+	if search.Status != "" {
+		conditions = append(conditions, `status = @status`)
+		args["status"] = search.Status
+	}
+
+	if !search.CreatedAfter.IsZero() {
+		conditions = append(conditions, `created_at >= @created_after`)
+		args["created_after"] = search.CreatedAfter
+	}
+
+	if !search.CreatedBefore.IsZero() {
+		conditions = append(conditions, `created_at < @created_before`)
+		args["created_before"] = search.CreatedBefore
+	}
+
+	sortColumn, ok := searchSortColumns[search.SortBy]
+	if !ok {
+		sortColumn = searchSortColumns[SORT_BY_ID]
+	}
+
+	comparison, direction := ">", "asc"
+	if search.Descending {
+		comparison, direction = "<", "desc"
+	}
+
+	if search.After != nil {
+		conditions = append(conditions, fmt.Sprintf(`(%s, id) %s (@cursor_value::%s, @cursor_id)`,
+			sortColumn[0], comparison, sortColumn[1]))
+		args["cursor_value"] = search.After.Value
+		args["cursor_id"] = search.After.Id
+	}
+
+	query := fmt.Sprintf(`select * from customers where %s order by %s %s, id %s limit @limit`,
+		strings.Join(conditions, " and "), sortColumn[0], direction, direction)
+	return query, args
+}
+
+func escapeLike(pattern string) string {
+	return strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`).Replace(pattern)
+}
@@ -0,0 +1,465 @@
+package models
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+type PgDuplicateStore struct {
+	conn *pgxpool.Pool
+}
+
+func NewPgDuplicateStore(ctx context.Context, connString string) (PgDuplicateStore, error) {
+	conn, err := connect(ctx, connString)
+	if err != nil {
+		return PgDuplicateStore{}, fmt.Errorf("unable to connect to database: %w", err)
+	}
+
+	pgDuplicateStore := PgDuplicateStore{conn}
+	return pgDuplicateStore, nil
+}
+
+// duplicateBatchSize is how many customers are keyed, and how many blocks
+// are scored, at a time.
+const duplicateBatchSize = 500
+
+// DetectDuplicates scores the active customers with FindDuplicates and
+// queues the candidates for review. Pending candidates are rescored and
+// reviewed ones are left alone. It returns the pending candidates found.
+//
+// The blocking keys of every customer are copied into a temporary table
+// and grouped by Postgres. The blocks are then read through a cursor and
+// scored a batch at a time, so neither the customers nor the blocks are
+// held in memory all at once.
+func (p *PgDuplicateStore) DetectDuplicates() ([]DuplicateCandidate, error) {
+	ctx := context.Background()
+
+	tx, err := p.conn.Begin(ctx)
+	if err != nil {
+		return nil, pgxErrorToStoreError(err)
+	}
+	defer tx.Rollback(ctx)
+
+	_, err = tx.Exec(ctx, `create temporary table duplicate_keys (key text not null, customer_id int not null) on commit drop`)
+	if err != nil {
+		return nil, pgxErrorToStoreError(err)
+	}
+
+	if err = copyDuplicateKeys(ctx, tx); err != nil {
+		return nil, err
+	}
+
+	// DECLARE can't be prepared, so the block size bound is formatted in
+	query := fmt.Sprintf(`declare duplicate_blocks no scroll cursor for
+		select array_agg(customer_id) from duplicate_keys group by key having count(*) between 2 and %d`,
+		DUPLICATE_MAX_BLOCK)
+	if _, err = tx.Exec(ctx, query); err != nil {
+		return nil, pgxErrorToStoreError(err)
+	}
+
+	queued := []DuplicateCandidate{}
+	// a pair sharing keys in two batches is scored in both
+	isQueued := map[int]bool{}
+
+	fetch := fmt.Sprintf(`fetch forward %d from duplicate_blocks`, duplicateBatchSize)
+	for {
+		rows, _ := tx.Query(ctx, fetch)
+		blocks, err := pgx.CollectRows(rows, pgx.RowTo[[]int])
+		if err != nil {
+			return nil, pgxErrorToStoreError(err)
+		}
+
+		ids := []int{}
+		for _, block := range blocks {
+			ids = append(ids, block...)
+		}
+
+		customers, addresses, err := getDuplicateBatch(ctx, tx, ids)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, candidate := range FindDuplicates(customers, addresses) {
+			query := `insert into duplicate_candidates(customer_id, duplicate_id, score, reasons)
+				values (@customer_id, @duplicate_id, @score, @reasons)
+				on conflict (customer_id, duplicate_id) do update set score=excluded.score, reasons=excluded.reasons
+				where duplicate_candidates.status=@pending
+				returning id, status, created_at`
+			args := pgx.NamedArgs{
+				"customer_id":  candidate.CustomerId,
+				"duplicate_id": candidate.DuplicateId,
+				"score":        candidate.Score,
+				"reasons":      candidate.Reasons,
+				"pending":      PENDING_CANDIDATE,
+			}
+
+			err := tx.QueryRow(ctx, query, args).Scan(&candidate.Id, &candidate.Status, &candidate.CreatedAt)
+			if errors.Is(err, pgx.ErrNoRows) {
+				continue
+			} else if err != nil {
+				return nil, pgxErrorToStoreError(err)
+			}
+
+			if !isQueued[candidate.Id] {
+				isQueued[candidate.Id] = true
+				queued = append(queued, candidate)
+			}
+		}
+
+		if len(blocks) < duplicateBatchSize {
+			break
+		}
+	}
+
+	if err = tx.Commit(ctx); err != nil {
+		return nil, pgxErrorToStoreError(err)
+	}
+
+	return queued, nil
+}
+
+// copyDuplicateKeys copies the duplicateKeys of the active customers into
+// duplicate_keys, paging through the customers by ID.
+func copyDuplicateKeys(ctx context.Context, tx pgx.Tx) error {
+	after := 0
+	for {
+		query := `select * from customers where status=@active and id > @after order by id limit @limit`
+		args := pgx.NamedArgs{
+			"active": ACTIVE_STATUS,
+			"after":  after,
+			"limit":  duplicateBatchSize,
+		}
+
+		rows, _ := tx.Query(ctx, query, args)
+		customers, err := pgx.CollectRows(rows, pgx.RowToStructByName[Customer])
+		if err != nil {
+			return pgxErrorToStoreError(err)
+		}
+		if len(customers) == 0 {
+			return nil
+		}
+
+		ids := make([]int, len(customers))
+		for i, customer := range customers {
+			ids[i] = customer.Id
+		}
+
+		rows, _ = tx.Query(ctx, `select * from addresses where customer_id = any(@ids)`, pgx.NamedArgs{"ids": ids})
+		addresses, err := pgx.CollectRows(rows, pgx.RowToStructByName[Address])
+		if err != nil {
+			return pgxErrorToStoreError(err)
+		}
+
+		addressesByCustomer := map[int][]Address{}
+		for _, address := range addresses {
+			addressesByCustomer[address.CustomerId] = append(addressesByCustomer[address.CustomerId], address)
+		}
+
+		keys := [][]any{}
+		for _, customer := range customers {
+			for _, key := range duplicateKeys(customer, addressesByCustomer[customer.Id]) {
+				keys = append(keys, []any{key, customer.Id})
+			}
+		}
+
+		_, err = tx.CopyFrom(ctx, pgx.Identifier{"duplicate_keys"}, []string{"key", "customer_id"}, pgx.CopyFromRows(keys))
+		if err != nil {
+			return pgxErrorToStoreError(err)
+		}
+
+		if len(customers) < duplicateBatchSize {
+			return nil
+		}
+		after = customers[len(customers)-1].Id
+	}
+}
+
+// getDuplicateBatch returns the active customers out of ids, together with
+// their addresses.
+func getDuplicateBatch(ctx context.Context, tx pgx.Tx, ids []int) ([]Customer, []Address, error) {
+	rows, _ := tx.Query(ctx, `select * from customers where id = any(@ids) and status=@active`,
+		pgx.NamedArgs{"ids": ids, "active": ACTIVE_STATUS})
+	customers, err := pgx.CollectRows(rows, pgx.RowToStructByName[Customer])
+	if err != nil {
+		return nil, nil, pgxErrorToStoreError(err)
+	}
+
+	rows, _ = tx.Query(ctx, `select * from addresses where customer_id = any(@ids)`, pgx.NamedArgs{"ids": ids})
+	addresses, err := pgx.CollectRows(rows, pgx.RowToStructByName[Address])
+	if err != nil {
+		return nil, nil, pgxErrorToStoreError(err)
+	}
+
+	return customers, addresses, nil
+}
+
+func (p *PgDuplicateStore) GetCandidates(status string) ([]DuplicateCandidate, error) {
+	query := `select * from duplicate_candidates where status=@status order by score desc, id`
+	args := pgx.NamedArgs{
+		"status": status,
+	}
+
+	rows, _ := p.conn.Query(context.Background(), query, args)
+	candidates, err := pgx.CollectRows(rows, pgx.RowToStructByName[DuplicateCandidate])
+	if err != nil {
+		return nil, pgxErrorToStoreError(err)
+	}
+
+	return candidates, nil
+}
+
+func (p *PgDuplicateStore) DismissCandidate(id int) error {
+	ctx := context.Background()
+
+	tx, err := p.conn.Begin(ctx)
+	if err != nil {
+		return pgxErrorToStoreError(err)
+	}
+	defer tx.Rollback(ctx)
+
+	if _, err = lockPendingCandidate(ctx, tx, id); err != nil {
+		return err
+	}
+
+	_, err = tx.Exec(ctx, `update duplicate_candidates set status=@dismissed, reviewed_at=now() where id=@id`,
+		pgx.NamedArgs{"id": id, "dismissed": DISMISSED_CANDIDATE})
+	if err != nil {
+		return pgxErrorToStoreError(err)
+	}
+
+	return pgxErrorToStoreError(tx.Commit(ctx))
+}
+
+func lockPendingCandidate(ctx context.Context, tx pgx.Tx, id int) (DuplicateCandidate, error) {
+	row, _ := tx.Query(ctx, `select * from duplicate_candidates where id=@id for update`, pgx.NamedArgs{"id": id})
+	candidate, err := pgx.CollectOneRow(row, pgx.RowToStructByName[DuplicateCandidate])
+	if err != nil {
+		return DuplicateCandidate{}, pgxErrorToStoreError(err)
+	}
+
+	if candidate.Status != PENDING_CANDIDATE {
+		return DuplicateCandidate{}, ErrCandidateReviewed
+	}
+
+	return candidate, nil
+}
+
+// MergeCustomers merges the other customer of a pending candidate into
+// survivorID in a single transaction. Addresses move to the survivor, the
+// preferences move too unless the survivor has its own, the tags are added
+// to the survivor's and the loyalty balance is transferred with a pair of
+// adjustments. Consents stay with the merged customer as evidence of what
+// it agreed to. The merged customer is scrubbed like an anonymized one and
+// left as a tombstone pointing at the survivor, which frees its phone
+// number and email.
+func (p *PgDuplicateStore) MergeCustomers(candidateID int, survivorID int) (MergeReport, error) {
+	ctx := context.Background()
+
+	tx, err := p.conn.Begin(ctx)
+	if err != nil {
+		return MergeReport{}, pgxErrorToStoreError(err)
+	}
+	defer tx.Rollback(ctx)
+
+	candidate, err := lockPendingCandidate(ctx, tx, candidateID)
+	if err != nil {
+		return MergeReport{}, err
+	}
+
+	var mergedID int
+	switch survivorID {
+	case candidate.CustomerId:
+		mergedID = candidate.DuplicateId
+	case candidate.DuplicateId:
+		mergedID = candidate.CustomerId
+	default:
+		return MergeReport{}, ErrInvalidMerge
+	}
+
+	rows, _ := tx.Query(ctx, `select * from customers where id in (@survivor_id, @merged_id) order by id for update`,
+		pgx.NamedArgs{"survivor_id": survivorID, "merged_id": mergedID})
+	customers, err := pgx.CollectRows(rows, pgx.RowToStructByName[Customer])
+	if err != nil {
+		return MergeReport{}, pgxErrorToStoreError(err)
+	}
+
+	if len(customers) != 2 {
+		return MergeReport{}, ErrInvalidMerge
+	}
+
+	var merged Customer
+	for _, customer := range customers {
+		if customer.Status != ACTIVE_STATUS {
+			return MergeReport{}, ErrInvalidMerge
+		}
+		if customer.Id == mergedID {
+			merged = customer
+		}
+	}
+
+	report := MergeReport{SurvivorId: survivorID, MergedId: mergedID}
+	args := pgx.NamedArgs{"survivor_id": survivorID, "merged_id": mergedID}
+
+	// the survivor's default address stays the default
+	tag, err := tx.Exec(ctx, `update addresses set customer_id=@survivor_id, version=version+1,
+		is_default = is_default and not exists (select 1 from addresses where customer_id=@survivor_id and is_default)
+		where customer_id=@merged_id`, args)
+	if err != nil {
+		return MergeReport{}, pgxErrorToStoreError(err)
+	}
+	report.Addresses = int(tag.RowsAffected())
+
+	_, err = tx.Exec(ctx, `update address_revisions set customer_id=@survivor_id where customer_id=@merged_id`, args)
+	if err != nil {
+		return MergeReport{}, pgxErrorToStoreError(err)
+	}
+
+	tag, err = tx.Exec(ctx, `update customer_preferences set customer_id=@survivor_id where customer_id=@merged_id
+		and not exists (select 1 from customer_preferences where customer_id=@survivor_id)`, args)
+	if err != nil {
+		return MergeReport{}, pgxErrorToStoreError(err)
+	}
+	report.Preferences = tag.RowsAffected() > 0
+
+	_, err = tx.Exec(ctx, `delete from customer_preferences where customer_id=@merged_id`, args)
+	if err != nil {
+		return MergeReport{}, pgxErrorToStoreError(err)
+	}
+
+	_, err = tx.Exec(ctx, `insert into customer_tags(customer_id, tag, created_at)
+		select @survivor_id, tag, created_at from customer_tags where customer_id=@merged_id
+		on conflict do nothing`, args)
+	if err != nil {
+		return MergeReport{}, pgxErrorToStoreError(err)
+	}
+
+	_, err = tx.Exec(ctx, `delete from customer_tags where customer_id=@merged_id`, args)
+	if err != nil {
+		return MergeReport{}, pgxErrorToStoreError(err)
+	}
+
+	_, err = tx.Exec(ctx, `delete from referral_codes where customer_id=@merged_id`, args)
+	if err != nil {
+		return MergeReport{}, pgxErrorToStoreError(err)
+	}
+
+	if err = transferHouseholds(ctx, tx, mergedID, survivorID); err != nil {
+		return MergeReport{}, err
+	}
+
+	if report.TransferredPoints, err = transferPoints(ctx, tx, mergedID, survivorID); err != nil {
+		return MergeReport{}, err
+	}
+
+	tombstone, _ := EraseCustomerPII(merged)
+	query := `update customers set first_name=@first_name, last_name=@last_name,
+		email=@email, canonical_email=@canonical_email, phone_number=@phone_number, password=@password,
+		avatar_url=@avatar_url, status=@status, merged_into=@survivor_id, version=version+1 where id=@merged_id`
+	tombstoneArgs := pgx.NamedArgs{
+		"merged_id":       mergedID,
+		"survivor_id":     survivorID,
+		"first_name":      tombstone.FirstName,
+		"last_name":       tombstone.LastName,
+		"email":           tombstone.Email,
+		"canonical_email": tombstone.CanonicalEmail,
+		"phone_number":    tombstone.PhoneNumber,
+		"password":        tombstone.Password,
+		"avatar_url":      tombstone.AvatarURL,
+		"status":          MERGED_STATUS,
+	}
+	if _, err = tx.Exec(ctx, query, tombstoneArgs); err != nil {
+		return MergeReport{}, pgxErrorToStoreError(err)
+	}
+
+	_, err = tx.Exec(ctx, `update duplicate_candidates set status=@merged, reviewed_at=now() where id=@id`,
+		pgx.NamedArgs{"id": candidateID, "merged": MERGED_CANDIDATE})
+	if err != nil {
+		return MergeReport{}, pgxErrorToStoreError(err)
+	}
+
+	// other pairs of the merged customer are stale, the next detection run
+	// scores the survivor instead
+	_, err = tx.Exec(ctx, `delete from duplicate_candidates where status=@pending
+		and (customer_id=@merged_id or duplicate_id=@merged_id)`,
+		pgx.NamedArgs{"merged_id": mergedID, "pending": PENDING_CANDIDATE})
+	if err != nil {
+		return MergeReport{}, pgxErrorToStoreError(err)
+	}
+
+	if err = tx.Commit(ctx); err != nil {
+		return MergeReport{}, pgxErrorToStoreError(err)
+	}
+
+	return report, nil
+}
+
+// transferPoints moves the loyalty balance of a merged customer to the
+// survivor. The points arrive as an adjustment, so they no longer expire.
+func transferPoints(ctx context.Context, tx pgx.Tx, mergedID int, survivorID int) (int, error) {
+	for _, customerID := range []int{min(mergedID, survivorID), max(mergedID, survivorID)} {
+		if err := lockLoyaltyAccount(ctx, tx, customerID); err != nil {
+			return 0, err
+		}
+	}
+
+	balance, err := getBalance(ctx, tx, mergedID)
+	if err != nil || balance <= 0 {
+		return 0, err
+	}
+
+	transfers := []LoyaltyTransaction{
+		{
+			Id:          fmt.Sprintf("merge-%d-into-%d-out", mergedID, survivorID),
+			CustomerId:  mergedID,
+			Kind:        ADJUST_TRANSACTION,
+			Points:      -balance,
+			Description: fmt.Sprintf("merged into customer %d", survivorID),
+		},
+		{
+			Id:          fmt.Sprintf("merge-%d-into-%d-in", mergedID, survivorID),
+			CustomerId:  survivorID,
+			Kind:        ADJUST_TRANSACTION,
+			Points:      balance,
+			Description: fmt.Sprintf("merged from customer %d", mergedID),
+		},
+	}
+
+	for _, transfer := range transfers {
+		if err := insertTransaction(ctx, tx, &transfer); err != nil {
+			return 0, err
+		}
+	}
+
+	return balance, nil
+}
+
+// transferHouseholds makes the survivor a member of the households of the
+// merged customer. Where both were members the survivor keeps the higher
+// of the two roles.
+func transferHouseholds(ctx context.Context, tx pgx.Tx, mergedID int, survivorID int) error {
+	args := pgx.NamedArgs{
+		"merged_id":   mergedID,
+		"survivor_id": survivorID,
+		"owner":       OWNER_ROLE,
+	}
+
+	_, err := tx.Exec(ctx, `update household_members set role=@owner where customer_id=@survivor_id
+		and household_id in (select household_id from household_members where customer_id=@merged_id and role=@owner)`, args)
+	if err != nil {
+		return pgxErrorToStoreError(err)
+	}
+
+	_, err = tx.Exec(ctx, `insert into household_members(household_id, customer_id, role, joined_at)
+		select household_id, @survivor_id, role, joined_at from household_members where customer_id=@merged_id
+		on conflict do nothing`, args)
+	if err != nil {
+		return pgxErrorToStoreError(err)
+	}
+
+	_, err = tx.Exec(ctx, `delete from household_members where customer_id=@merged_id`, args)
+	return pgxErrorToStoreError(err)
+}
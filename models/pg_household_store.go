@@ -0,0 +1,221 @@
+package models
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+type PgHouseholdStore struct {
+	conn *pgxpool.Pool
+}
+
+func NewPgHouseholdStore(ctx context.Context, connString string) (PgHouseholdStore, error) {
+	conn, err := connect(ctx, connString)
+	if err != nil {
+		return PgHouseholdStore{}, fmt.Errorf("unable to connect to database: %w", err)
+	}
+
+	pgHouseholdStore := PgHouseholdStore{conn}
+	return pgHouseholdStore, nil
+}
+
+// CreateHousehold makes the customer who creates the household its first
+// owner.
+func (p *PgHouseholdStore) CreateHousehold(household *Household, ownerID int) error {
+	ctx := context.Background()
+
+	tx, err := p.conn.Begin(ctx)
+	if err != nil {
+		return pgxErrorToStoreError(err)
+	}
+	defer tx.Rollback(ctx)
+
+	query := `insert into households(name) values (@name) returning id, created_at`
+	err = tx.QueryRow(ctx, query, pgx.NamedArgs{"name": household.Name}).Scan(&household.Id, &household.CreatedAt)
+	if err != nil {
+		return pgxErrorToStoreError(err)
+	}
+
+	query = `insert into household_members(household_id, customer_id, role) values (@household_id, @customer_id, @role)`
+	args := pgx.NamedArgs{
+		"household_id": household.Id,
+		"customer_id":  ownerID,
+		"role":         OWNER_ROLE,
+	}
+	if _, err = tx.Exec(ctx, query, args); err != nil {
+		return pgxErrorToStoreError(err)
+	}
+
+	return pgxErrorToStoreError(tx.Commit(ctx))
+}
+
+func (p *PgHouseholdStore) GetHouseholdByID(id int) (Household, error) {
+	row, _ := p.conn.Query(context.Background(), `select * from households where id=@id`, pgx.NamedArgs{"id": id})
+	household, err := pgx.CollectOneRow(row, pgx.RowToStructByName[Household])
+	if err != nil {
+		return Household{}, pgxErrorToStoreError(err)
+	}
+
+	return household, nil
+}
+
+func (p *PgHouseholdStore) GetHouseholdsByCustomerID(customerID int) ([]Household, error) {
+	query := `select households.* from households
+		join household_members on household_members.household_id = households.id
+		where household_members.customer_id=@customer_id order by households.id`
+
+	row, _ := p.conn.Query(context.Background(), query, pgx.NamedArgs{"customer_id": customerID})
+	households, err := pgx.CollectRows(row, pgx.RowToStructByName[Household])
+	if err != nil {
+		return nil, pgxErrorToStoreError(err)
+	}
+
+	return households, nil
+}
+
+// GetHouseholdMember returns ErrNotFound when the customer isn't a member of
+// the household.
+func (p *PgHouseholdStore) GetHouseholdMember(householdID int, customerID int) (HouseholdMember, error) {
+	query := `select * from household_members where household_id=@household_id and customer_id=@customer_id`
+	args := pgx.NamedArgs{
+		"household_id": householdID,
+		"customer_id":  customerID,
+	}
+
+	row, _ := p.conn.Query(context.Background(), query, args)
+	member, err := pgx.CollectOneRow(row, pgx.RowToStructByName[HouseholdMember])
+	if err != nil {
+		return HouseholdMember{}, pgxErrorToStoreError(err)
+	}
+
+	return member, nil
+}
+
+func (p *PgHouseholdStore) GetHouseholdMembers(householdID int) ([]HouseholdMember, error) {
+	query := `select * from household_members where household_id=@household_id order by joined_at, customer_id`
+
+	row, _ := p.conn.Query(context.Background(), query, pgx.NamedArgs{"household_id": householdID})
+	members, err := pgx.CollectRows(row, pgx.RowToStructByName[HouseholdMember])
+	if err != nil {
+		return nil, pgxErrorToStoreError(err)
+	}
+
+	return members, nil
+}
+
+func (p *PgHouseholdStore) CreateInvitation(invitation *HouseholdInvitation) error {
+	query := `insert into household_invitations(code, household_id, invited_by, role, expires_at)
+		values (@code, @household_id, @invited_by, @role, @expires_at) returning created_at`
+	args := pgx.NamedArgs{
+		"code":         invitation.Code,
+		"household_id": invitation.HouseholdId,
+		"invited_by":   invitation.InvitedBy,
+		"role":         invitation.Role,
+		"expires_at":   invitation.ExpiresAt,
+	}
+
+	err := p.conn.QueryRow(context.Background(), query, args).Scan(&invitation.CreatedAt)
+	return pgxErrorToStoreError(err)
+}
+
+// AcceptInvitation locks the invitation, so it can't be accepted twice by
+// concurrent requests.
+func (p *PgHouseholdStore) AcceptInvitation(code string, customerID int, now time.Time) (HouseholdMember, error) {
+	ctx := context.Background()
+
+	tx, err := p.conn.Begin(ctx)
+	if err != nil {
+		return HouseholdMember{}, pgxErrorToStoreError(err)
+	}
+	defer tx.Rollback(ctx)
+
+	row, _ := tx.Query(ctx, `select * from household_invitations where code=@code for update`,
+		pgx.NamedArgs{"code": code})
+	invitation, err := pgx.CollectOneRow(row, pgx.RowToStructByName[HouseholdInvitation])
+	if err != nil {
+		return HouseholdMember{}, pgxErrorToStoreError(err)
+	}
+
+	if err = invitation.CheckAcceptable(now); err != nil {
+		return HouseholdMember{}, err
+	}
+
+	query := `insert into household_members(household_id, customer_id, role)
+		values (@household_id, @customer_id, @role) on conflict do nothing returning *`
+	args := pgx.NamedArgs{
+		"household_id": invitation.HouseholdId,
+		"customer_id":  customerID,
+		"role":         invitation.Role,
+	}
+
+	row, _ = tx.Query(ctx, query, args)
+	member, err := pgx.CollectOneRow(row, pgx.RowToStructByName[HouseholdMember])
+	if errors.Is(err, pgx.ErrNoRows) {
+		return HouseholdMember{}, ErrAlreadyMember
+	} else if err != nil {
+		return HouseholdMember{}, pgxErrorToStoreError(err)
+	}
+
+	query = `update household_invitations set accepted_by=@customer_id, accepted_at=@now where code=@code`
+	args = pgx.NamedArgs{
+		"customer_id": customerID,
+		"now":         now,
+		"code":        code,
+	}
+	if _, err = tx.Exec(ctx, query, args); err != nil {
+		return HouseholdMember{}, pgxErrorToStoreError(err)
+	}
+
+	if err = tx.Commit(ctx); err != nil {
+		return HouseholdMember{}, pgxErrorToStoreError(err)
+	}
+
+	return member, nil
+}
+
+// RemoveHouseholdMember refuses to remove the last owner of a household.
+func (p *PgHouseholdStore) RemoveHouseholdMember(householdID int, customerID int) error {
+	ctx := context.Background()
+
+	tx, err := p.conn.Begin(ctx)
+	if err != nil {
+		return pgxErrorToStoreError(err)
+	}
+	defer tx.Rollback(ctx)
+
+	// locking the owners keeps two owners from removing each other at once
+	_, err = tx.Exec(ctx, `select 1 from household_members where household_id=@household_id and role=@owner for update`,
+		pgx.NamedArgs{"household_id": householdID, "owner": OWNER_ROLE})
+	if err != nil {
+		return pgxErrorToStoreError(err)
+	}
+
+	var role string
+	query := `delete from household_members where household_id=@household_id and customer_id=@customer_id returning role`
+	args := pgx.NamedArgs{
+		"household_id": householdID,
+		"customer_id":  customerID,
+	}
+	if err = tx.QueryRow(ctx, query, args).Scan(&role); err != nil {
+		return pgxErrorToStoreError(err)
+	}
+
+	if role == OWNER_ROLE {
+		var owners int
+		query = `select count(*) from household_members where household_id=@household_id and role=@owner`
+		err = tx.QueryRow(ctx, query, pgx.NamedArgs{"household_id": householdID, "owner": OWNER_ROLE}).Scan(&owners)
+		if err != nil {
+			return pgxErrorToStoreError(err)
+		}
+		if owners == 0 {
+			return ErrLastOwner
+		}
+	}
+
+	return pgxErrorToStoreError(tx.Commit(ctx))
+}
@@ -0,0 +1,158 @@
+package models
+
+import (
+	"context"
+	"sort"
+
+	"github.com/jackc/pgx/v5"
+)
+
+type IdentityMigrationReport struct {
+	Updated    int
+	Unchanged  int
+	Invalid    []InvalidIdentity
+	Collisions []IdentityCollision
+	// Constrained is set once every row is canonical and the schema
+	// enforces uniqueness of the canonical forms.
+	Constrained bool
+}
+
+type InvalidIdentity struct {
+	CustomerId  int
+	PhoneNumber string
+}
+
+// IdentityCollision lists customers whose phone numbers or emails only
+// differed in formatting and become equal once canonicalized. They have to
+// be resolved by hand, e.g. by merging the accounts.
+type IdentityCollision struct {
+	Column      string
+	Value       string
+	CustomerIds []int
+}
+
+type identityRow struct {
+	Id             int
+	PhoneNumber    string `db:"phone_number"`
+	Email          string
+	CanonicalEmail *string `db:"canonical_email"`
+}
+
+// NormalizeIdentities migrates a customers table created before phone
+// numbers and emails were canonicalized. Rows that can be normalized
+// without clashing with another row are rewritten; invalid phone numbers
+// and collisions are reported and left untouched. Only when nothing is left
+// to resolve are the canonical uniqueness and format constraints added, so
+// the migration can be re-run after fixing the reported rows. With dryRun
+// the transaction is rolled back.
+func (p *PgCustomerStore) NormalizeIdentities(dryRun bool) (IdentityMigrationReport, error) {
+	ctx := context.Background()
+	report := IdentityMigrationReport{Invalid: []InvalidIdentity{}, Collisions: []IdentityCollision{}}
+
+	tx, err := p.conn.Begin(ctx)
+	if err != nil {
+		return report, pgxErrorToStoreError(err)
+	}
+	defer tx.Rollback(ctx)
+
+	_, err = tx.Exec(ctx, `alter table customers add column if not exists canonical_email varchar(40)`)
+	if err != nil {
+		return report, pgxErrorToStoreError(err)
+	}
+
+	rows, _ := tx.Query(ctx, `select id, phone_number, email, canonical_email from customers order by id for update`)
+	identities, err := pgx.CollectRows(rows, pgx.RowToStructByName[identityRow])
+	if err != nil {
+		return report, pgxErrorToStoreError(err)
+	}
+
+	normalized := map[int]Customer{}
+	phoneOwners := map[string][]int{}
+	emailOwners := map[string][]int{}
+	for _, identity := range identities {
+		customer := Customer{Id: identity.Id, PhoneNumber: identity.PhoneNumber, Email: identity.Email}
+		if err := p.rules.Normalize(&customer); err != nil {
+			report.Invalid = append(report.Invalid, InvalidIdentity{CustomerId: identity.Id, PhoneNumber: identity.PhoneNumber})
+			continue
+		}
+
+		normalized[identity.Id] = customer
+		phoneOwners[customer.PhoneNumber] = append(phoneOwners[customer.PhoneNumber], identity.Id)
+		emailOwners[customer.CanonicalEmail] = append(emailOwners[customer.CanonicalEmail], identity.Id)
+	}
+
+	colliding := map[int]bool{}
+	report.Collisions = append(report.Collisions, collisions("phone_number", phoneOwners, colliding)...)
+	report.Collisions = append(report.Collisions, collisions("canonical_email", emailOwners, colliding)...)
+
+	for _, identity := range identities {
+		customer, ok := normalized[identity.Id]
+		if !ok || colliding[identity.Id] {
+			continue
+		}
+
+		if customer.PhoneNumber == identity.PhoneNumber && customer.Email == identity.Email &&
+			identity.CanonicalEmail != nil && customer.CanonicalEmail == *identity.CanonicalEmail {
+			report.Unchanged++
+			continue
+		}
+
+		query := `update customers set phone_number=@phone_number, email=@email,
+			canonical_email=@canonical_email where id=@id`
+		args := pgx.NamedArgs{
+			"id":              customer.Id,
+			"phone_number":    customer.PhoneNumber,
+			"email":           customer.Email,
+			"canonical_email": customer.CanonicalEmail,
+		}
+
+		if _, err = tx.Exec(ctx, query, args); err != nil {
+			return report, pgxErrorToStoreError(err)
+		}
+		report.Updated++
+	}
+
+	if len(report.Invalid) == 0 && len(report.Collisions) == 0 {
+		_, err = tx.Exec(ctx, `
+			alter table customers alter column canonical_email set not null;
+			alter table customers drop constraint if exists customers_email_key;
+			alter table customers drop constraint if exists customers_canonical_email_key;
+			alter table customers add constraint customers_canonical_email_key unique (canonical_email);
+			alter table customers drop constraint if exists customers_phone_number_check;
+			alter table customers add constraint customers_phone_number_check
+				check (phone_number ~ '^\+[1-9][0-9]{7,14}$');`)
+		if err != nil {
+			return report, pgxErrorToStoreError(err)
+		}
+		report.Constrained = true
+	}
+
+	if dryRun {
+		return report, nil
+	}
+
+	if err = tx.Commit(ctx); err != nil {
+		return report, pgxErrorToStoreError(err)
+	}
+
+	return report, nil
+}
+
+// collisions returns the values owned by more than one customer, in a
+// stable order, and marks their owners in colliding.
+func collisions(column string, owners map[string][]int, colliding map[int]bool) []IdentityCollision {
+	found := []IdentityCollision{}
+	for value, customerIds := range owners {
+		if len(customerIds) < 2 {
+			continue
+		}
+
+		for _, id := range customerIds {
+			colliding[id] = true
+		}
+		found = append(found, IdentityCollision{Column: column, Value: value, CustomerIds: customerIds})
+	}
+
+	sort.Slice(found, func(i, j int) bool { return found[i].Value < found[j].Value })
+	return found
+}
@@ -0,0 +1,220 @@
+package models
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+type PgLoyaltyStore struct {
+	conn *pgxpool.Pool
+}
+
+func NewPgLoyaltyStore(ctx context.Context, connString string) (PgLoyaltyStore, error) {
+	conn, err := connect(ctx, connString)
+	if err != nil {
+		return PgLoyaltyStore{}, fmt.Errorf("unable to connect to database: %w", err)
+	}
+
+	pgLoyaltyStore := PgLoyaltyStore{conn}
+	return pgLoyaltyStore, nil
+}
+
+// RecordTransaction records a transaction and its ledger entries. If a
+// transaction with the same ID was already recorded, the stored one is
+// returned through transaction instead, or ErrTransactionConflict if it
+// differs. Debits that would make the balance negative fail with
+// ErrInsufficientPoints.
+func (p *PgLoyaltyStore) RecordTransaction(transaction *LoyaltyTransaction) error {
+	ctx := context.Background()
+
+	tx, err := p.conn.Begin(ctx)
+	if err != nil {
+		return pgxErrorToStoreError(err)
+	}
+	defer tx.Rollback(ctx)
+
+	if err = lockLoyaltyAccount(ctx, tx, transaction.CustomerId); err != nil {
+		return err
+	}
+
+	row, _ := tx.Query(ctx, `select * from loyalty_transactions where id=@id`, pgx.NamedArgs{"id": transaction.Id})
+	recorded, err := pgx.CollectOneRow(row, pgx.RowToStructByName[LoyaltyTransaction])
+	if err == nil {
+		if !recorded.SameAs(*transaction) {
+			return ErrTransactionConflict
+		}
+		*transaction = recorded
+		return nil
+	} else if !errors.Is(err, pgx.ErrNoRows) {
+		return pgxErrorToStoreError(err)
+	}
+
+	if transaction.Points < 0 {
+		balance, err := getBalance(ctx, tx, transaction.CustomerId)
+		if err != nil {
+			return err
+		}
+
+		if balance+transaction.Points < 0 {
+			return ErrInsufficientPoints
+		}
+	}
+
+	if err = insertTransaction(ctx, tx, transaction); err != nil {
+		return err
+	}
+
+	return pgxErrorToStoreError(tx.Commit(ctx))
+}
+
+// lockLoyaltyAccount serializes the transactions of a customer until the
+// end of tx, so concurrent redemptions can't both pass the balance check.
+func lockLoyaltyAccount(ctx context.Context, tx pgx.Tx, customerID int) error {
+	_, err := tx.Exec(ctx, `select pg_advisory_xact_lock(hashtext('loyalty'), @customer_id)`,
+		pgx.NamedArgs{"customer_id": customerID})
+	return pgxErrorToStoreError(err)
+}
+
+func insertTransaction(ctx context.Context, tx pgx.Tx, transaction *LoyaltyTransaction) error {
+	query := `insert into loyalty_transactions(id, customer_id, kind, points, description, expires_at)
+		values (@id, @customer_id, @kind, @points, @description, @expires_at) returning created_at`
+	args := pgx.NamedArgs{
+		"id":          transaction.Id,
+		"customer_id": transaction.CustomerId,
+		"kind":        transaction.Kind,
+		"points":      transaction.Points,
+		"description": transaction.Description,
+		"expires_at":  transaction.ExpiresAt,
+	}
+	if err := tx.QueryRow(ctx, query, args).Scan(&transaction.CreatedAt); err != nil {
+		return pgxErrorToStoreError(err)
+	}
+
+	for _, entry := range transaction.Entries() {
+		query := `insert into loyalty_entries(transaction_id, account, customer_id, amount)
+			values (@transaction_id, @account, @customer_id, @amount)`
+		args := pgx.NamedArgs{
+			"transaction_id": entry.TransactionId,
+			"account":        entry.Account,
+			"customer_id":    entry.CustomerId,
+			"amount":         entry.Amount,
+		}
+		if _, err := tx.Exec(ctx, query, args); err != nil {
+			return pgxErrorToStoreError(err)
+		}
+	}
+
+	return nil
+}
+
+func (p *PgLoyaltyStore) GetBalance(customerID int) (int, error) {
+	return getBalance(context.Background(), p.conn, customerID)
+}
+
+type queryRower interface {
+	QueryRow(ctx context.Context, sql string, args ...any) pgx.Row
+}
+
+func getBalance(ctx context.Context, conn queryRower, customerID int) (int, error) {
+	query := `select coalesce(sum(amount), 0) from loyalty_entries
+		where account=@account and customer_id=@customer_id`
+	args := pgx.NamedArgs{
+		"account":     CUSTOMER_ACCOUNT,
+		"customer_id": customerID,
+	}
+
+	var balance int
+	err := conn.QueryRow(ctx, query, args).Scan(&balance)
+	return balance, pgxErrorToStoreError(err)
+}
+
+func (p *PgLoyaltyStore) GetTransactions(customerID int) ([]LoyaltyTransaction, error) {
+	query := `select * from loyalty_transactions where customer_id=@customer_id order by created_at, id`
+	args := pgx.NamedArgs{
+		"customer_id": customerID,
+	}
+
+	rows, _ := p.conn.Query(context.Background(), query, args)
+	transactions, err := pgx.CollectRows(rows, pgx.RowToStructByName[LoyaltyTransaction])
+
+	if err != nil {
+		return nil, pgxErrorToStoreError(err)
+	}
+
+	return transactions, nil
+}
+
+// ExpirePoints writes off the points that have expired at now for every
+// customer, as computed by ExpiredPoints, and returns the expire
+// transactions it recorded. Running it again is harmless, as written off
+// points count as spent.
+func (p *PgLoyaltyStore) ExpirePoints(now time.Time) ([]LoyaltyTransaction, error) {
+	query := `select distinct customer_id from loyalty_transactions
+		where kind=@earn and expires_at <= @now order by customer_id`
+	args := pgx.NamedArgs{
+		"earn": EARN_TRANSACTION,
+		"now":  now,
+	}
+
+	rows, _ := p.conn.Query(context.Background(), query, args)
+	customerIDs, err := pgx.CollectRows(rows, pgx.RowTo[int])
+	if err != nil {
+		return nil, pgxErrorToStoreError(err)
+	}
+
+	expired := []LoyaltyTransaction{}
+	for _, customerID := range customerIDs {
+		transaction, err := p.expireCustomerPoints(customerID, now)
+		if err != nil {
+			return expired, err
+		}
+
+		if transaction != nil {
+			expired = append(expired, *transaction)
+		}
+	}
+
+	return expired, nil
+}
+
+func (p *PgLoyaltyStore) expireCustomerPoints(customerID int, now time.Time) (*LoyaltyTransaction, error) {
+	ctx := context.Background()
+
+	tx, err := p.conn.Begin(ctx)
+	if err != nil {
+		return nil, pgxErrorToStoreError(err)
+	}
+	defer tx.Rollback(ctx)
+
+	if err = lockLoyaltyAccount(ctx, tx, customerID); err != nil {
+		return nil, err
+	}
+
+	rows, _ := tx.Query(ctx, `select * from loyalty_transactions where customer_id=@customer_id`,
+		pgx.NamedArgs{"customer_id": customerID})
+	transactions, err := pgx.CollectRows(rows, pgx.RowToStructByName[LoyaltyTransaction])
+	if err != nil {
+		return nil, pgxErrorToStoreError(err)
+	}
+
+	points := ExpiredPoints(transactions, now)
+	if points == 0 {
+		return nil, nil
+	}
+
+	transaction := NewExpireTransaction(customerID, points, now)
+	if err = insertTransaction(ctx, tx, &transaction); err != nil {
+		return nil, err
+	}
+
+	if err = tx.Commit(ctx); err != nil {
+		return nil, pgxErrorToStoreError(err)
+	}
+
+	return &transaction, nil
+}
@@ -0,0 +1,32 @@
+package models
+
+import (
+	"context"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// querier is implemented by both the pool and transactions, so a statement
+// can run on its own or as part of a larger transaction.
+type querier interface {
+	QueryRow(ctx context.Context, sql string, args ...any) pgx.Row
+}
+
+// connect opens a connection pool, so that concurrent requests, and the
+// transactions they run, each get a connection of their own. The pool
+// connects lazily, so it is pinged to fail early on a bad connection
+// string or an unreachable database.
+func connect(ctx context.Context, connString string) (*pgxpool.Pool, error) {
+	pool, err := pgxpool.New(ctx, connString)
+	if err != nil {
+		return nil, err
+	}
+
+	if err = pool.Ping(ctx); err != nil {
+		pool.Close()
+		return nil, err
+	}
+
+	return pool, nil
+}
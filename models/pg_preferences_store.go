@@ -0,0 +1,56 @@
+package models
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+type PgPreferencesStore struct {
+	conn *pgxpool.Pool
+}
+
+func NewPgPreferencesStore(ctx context.Context, connString string) (PgPreferencesStore, error) {
+	conn, err := connect(ctx, connString)
+	if err != nil {
+		return PgPreferencesStore{}, fmt.Errorf("unable to connect to database: %w", err)
+	}
+
+	pgPreferencesStore := PgPreferencesStore{conn}
+	return pgPreferencesStore, nil
+}
+
+func (p *PgPreferencesStore) GetPreferences(customerID int) (Preferences, error) {
+	query := `select * from customer_preferences where customer_id=@customer_id`
+	args := pgx.NamedArgs{
+		"customer_id": customerID,
+	}
+
+	row, _ := p.conn.Query(context.Background(), query, args)
+	preferences, err := pgx.CollectOneRow(row, pgx.RowToStructByName[Preferences])
+
+	if err != nil {
+		return Preferences{}, pgxErrorToStoreError(err)
+	}
+
+	return preferences, nil
+}
+
+func (p *PgPreferencesStore) UpsertPreferences(preferences *Preferences) error {
+	query := `insert into customer_preferences(customer_id, language, currency, time_zone, notifications)
+		values (@customer_id, @language, @currency, @time_zone, @notifications)
+		on conflict (customer_id) do update set language=excluded.language, currency=excluded.currency,
+		time_zone=excluded.time_zone, notifications=excluded.notifications`
+	args := pgx.NamedArgs{
+		"customer_id":   preferences.CustomerId,
+		"language":      preferences.Language,
+		"currency":      preferences.Currency,
+		"time_zone":     preferences.TimeZone,
+		"notifications": preferences.Notifications,
+	}
+
+	_, err := p.conn.Exec(context.Background(), query, args)
+	return pgxErrorToStoreError(err)
+}
@@ -0,0 +1,150 @@
+package models
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// referralCodeAttempts bounds how often a new code is drawn when it clashes
+// with an existing one, which is rare with 31^8 possible codes.
+const referralCodeAttempts = 5
+
+type PgReferralStore struct {
+	conn *pgxpool.Pool
+}
+
+func NewPgReferralStore(ctx context.Context, connString string) (PgReferralStore, error) {
+	conn, err := connect(ctx, connString)
+	if err != nil {
+		return PgReferralStore{}, fmt.Errorf("unable to connect to database: %w", err)
+	}
+
+	pgReferralStore := PgReferralStore{conn}
+	return pgReferralStore, nil
+}
+
+// GetReferralCode returns the referral code of a customer, generating one
+// the first time it is asked for.
+func (p *PgReferralStore) GetReferralCode(customerID int) (string, error) {
+	ctx := context.Background()
+
+	for attempt := 0; attempt < referralCodeAttempts; attempt++ {
+		code, err := NewReferralCode()
+		if err != nil {
+			return "", err
+		}
+
+		query := `insert into referral_codes(customer_id, code) values (@customer_id, @code)
+			on conflict (customer_id) do nothing`
+		args := pgx.NamedArgs{
+			"customer_id": customerID,
+			"code":        code,
+		}
+
+		_, err = p.conn.Exec(ctx, query, args)
+		if err = pgxErrorToStoreError(err); errors.Is(err, ErrUniqueViolation) {
+			continue
+		} else if err != nil {
+			return "", err
+		}
+
+		var stored string
+		err = p.conn.QueryRow(ctx, `select code from referral_codes where customer_id=@customer_id`,
+			pgx.NamedArgs{"customer_id": customerID}).Scan(&stored)
+		return stored, pgxErrorToStoreError(err)
+	}
+
+	return "", fmt.Errorf("couldn't generate a unique referral code in %d attempts", referralCodeAttempts)
+}
+
+func (p *PgReferralStore) GetReferrerByCode(code string) (int, error) {
+	var customerID int
+	err := p.conn.QueryRow(context.Background(), `select customer_id from referral_codes where code=@code`,
+		pgx.NamedArgs{"code": code}).Scan(&customerID)
+
+	return customerID, pgxErrorToStoreError(err)
+}
+
+// recordReferral records an accepted referral as REUSED_IDENTITY_REFERRAL
+// if the phone number or email of the referred customer was already
+// referred. A concurrent signup that wins the race is caught by the unique
+// indexes on the hashes of accepted referrals; the insert runs in a
+// savepoint so the signup can go on after it.
+func recordReferral(ctx context.Context, tx pgx.Tx, referral *Referral) error {
+	if referral.Status == ACCEPTED_REFERRAL {
+		var reused bool
+		query := `select exists(select 1 from referrals where status=@accepted
+			and (phone_hash=@phone_hash or email_hash=@email_hash))`
+		args := pgx.NamedArgs{
+			"accepted":   ACCEPTED_REFERRAL,
+			"phone_hash": referral.PhoneHash,
+			"email_hash": referral.EmailHash,
+		}
+		if err := tx.QueryRow(ctx, query, args).Scan(&reused); err != nil {
+			return pgxErrorToStoreError(err)
+		}
+
+		if reused {
+			referral.Status = REUSED_IDENTITY_REFERRAL
+		}
+	}
+
+	savepoint, err := tx.Begin(ctx)
+	if err != nil {
+		return pgxErrorToStoreError(err)
+	}
+	defer savepoint.Rollback(ctx)
+
+	err = insertReferral(ctx, savepoint, referral)
+	if errors.Is(err, ErrUniqueViolation) && referral.Status == ACCEPTED_REFERRAL {
+		var constraintError *ConstraintError
+		if errors.As(err, &constraintError) && constraintError.Constraint != "referrals_pkey" {
+			savepoint.Rollback(ctx)
+			referral.Status = REUSED_IDENTITY_REFERRAL
+			return insertReferral(ctx, tx, referral)
+		}
+	}
+	if err != nil {
+		return err
+	}
+
+	return pgxErrorToStoreError(savepoint.Commit(ctx))
+}
+
+func insertReferral(ctx context.Context, tx pgx.Tx, referral *Referral) error {
+	query := `insert into referrals(referred_id, referrer_id, code, phone_hash, email_hash, status)
+		values (@referred_id, @referrer_id, @code, @phone_hash, @email_hash, @status) returning created_at`
+	args := pgx.NamedArgs{
+		"referred_id": referral.ReferredId,
+		"referrer_id": referral.ReferrerId,
+		"code":        referral.Code,
+		"phone_hash":  referral.PhoneHash,
+		"email_hash":  referral.EmailHash,
+		"status":      referral.Status,
+	}
+
+	err := tx.QueryRow(ctx, query, args).Scan(&referral.CreatedAt)
+	return pgxErrorToStoreError(err)
+}
+
+func (p *PgReferralStore) GetReferralStats(customerID int) (ReferralStats, error) {
+	code, err := p.GetReferralCode(customerID)
+	if err != nil {
+		return ReferralStats{}, err
+	}
+
+	stats := ReferralStats{Code: code}
+	query := `select count(*), max(created_at) from referrals
+		where referrer_id=@referrer_id and status=@accepted`
+	args := pgx.NamedArgs{
+		"referrer_id": customerID,
+		"accepted":    ACCEPTED_REFERRAL,
+	}
+
+	err = p.conn.QueryRow(context.Background(), query, args).Scan(&stats.Referred, &stats.LastReferredAt)
+	return stats, pgxErrorToStoreError(err)
+}
@@ -0,0 +1,221 @@
+package models
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+type PgSegmentStore struct {
+	conn *pgxpool.Pool
+}
+
+func NewPgSegmentStore(ctx context.Context, connString string) (PgSegmentStore, error) {
+	conn, err := connect(ctx, connString)
+	if err != nil {
+		return PgSegmentStore{}, fmt.Errorf("unable to connect to database: %w", err)
+	}
+
+	pgSegmentStore := PgSegmentStore{conn}
+	return pgSegmentStore, nil
+}
+
+func (p *PgSegmentStore) GetTags(customerID int) ([]string, error) {
+	query := `select tag from customer_tags where customer_id=@customer_id order by tag`
+	args := pgx.NamedArgs{
+		"customer_id": customerID,
+	}
+
+	rows, _ := p.conn.Query(context.Background(), query, args)
+	tags, err := pgx.CollectRows(rows, pgx.RowTo[string])
+	if err != nil {
+		return nil, pgxErrorToStoreError(err)
+	}
+
+	return tags, nil
+}
+
+// AddTags tags a customer. Tags the customer already has are left as is.
+func (p *PgSegmentStore) AddTags(customerID int, tags []string) error {
+	query := `insert into customer_tags(customer_id, tag) select @customer_id, unnest(@tags::text[])
+		on conflict do nothing`
+	args := pgx.NamedArgs{
+		"customer_id": customerID,
+		"tags":        tags,
+	}
+
+	_, err := p.conn.Exec(context.Background(), query, args)
+	return pgxErrorToStoreError(err)
+}
+
+func (p *PgSegmentStore) RemoveTag(customerID int, tag string) error {
+	query := `delete from customer_tags where customer_id=@customer_id and tag=@tag`
+	args := pgx.NamedArgs{
+		"customer_id": customerID,
+		"tag":         tag,
+	}
+
+	result, err := p.conn.Exec(context.Background(), query, args)
+	if err != nil {
+		return pgxErrorToStoreError(err)
+	}
+
+	if result.RowsAffected() == 0 {
+		return ErrNotFound
+	}
+
+	return nil
+}
+
+func (p *PgSegmentStore) CreateSegment(segment *Segment) error {
+	query := `insert into segments(name, description, rules) values (@name, @description, @rules)
+		returning id, created_at`
+	args := pgx.NamedArgs{
+		"name":        segment.Name,
+		"description": segment.Description,
+		"rules":       segment.Rules,
+	}
+
+	err := p.conn.QueryRow(context.Background(), query, args).Scan(&segment.Id, &segment.CreatedAt)
+	return pgxErrorToStoreError(err)
+}
+
+func (p *PgSegmentStore) GetSegments() ([]Segment, error) {
+	rows, _ := p.conn.Query(context.Background(), `select * from segments order by name`)
+	segments, err := pgx.CollectRows(rows, pgx.RowToStructByName[Segment])
+	if err != nil {
+		return nil, pgxErrorToStoreError(err)
+	}
+
+	return segments, nil
+}
+
+func (p *PgSegmentStore) DeleteSegment(name string) error {
+	result, err := p.conn.Exec(context.Background(), `delete from segments where name=@name`, pgx.NamedArgs{"name": name})
+	if err != nil {
+		return pgxErrorToStoreError(err)
+	}
+
+	if result.RowsAffected() == 0 {
+		return ErrNotFound
+	}
+
+	return nil
+}
+
+func (p *PgSegmentStore) getSegment(ctx context.Context, name string) (Segment, error) {
+	row, _ := p.conn.Query(ctx, `select * from segments where name=@name`, pgx.NamedArgs{"name": name})
+	segment, err := pgx.CollectOneRow(row, pgx.RowToStructByName[Segment])
+	if err != nil {
+		return Segment{}, pgxErrorToStoreError(err)
+	}
+
+	return segment, nil
+}
+
+// GetSegmentMembers returns the IDs of up to limit members of a segment
+// following afterID, in ascending order.
+func (p *PgSegmentStore) GetSegmentMembers(name string, afterID int, limit int) ([]int, error) {
+	ctx := context.Background()
+
+	segment, err := p.getSegment(ctx, name)
+	if err != nil {
+		return nil, err
+	}
+
+	conditions, args := buildSegmentConditions(segment.Rules)
+	conditions = append(conditions, "c.id > @after_id")
+	args["after_id"] = afterID
+	args["limit"] = limit
+
+	query := fmt.Sprintf(`select c.id from customers c where %s order by c.id limit @limit`,
+		strings.Join(conditions, " and "))
+
+	rows, _ := p.conn.Query(ctx, query, args)
+	members, err := pgx.CollectRows(rows, pgx.RowTo[int])
+	if err != nil {
+		return nil, pgxErrorToStoreError(err)
+	}
+
+	return members, nil
+}
+
+// GetCustomerSegments returns the names of the segments a customer is a
+// member of, evaluating every saved segment against that one customer.
+func (p *PgSegmentStore) GetCustomerSegments(customerID int) ([]string, error) {
+	ctx := context.Background()
+
+	segments, err := p.GetSegments()
+	if err != nil {
+		return nil, err
+	}
+
+	names := []string{}
+	for _, segment := range segments {
+		conditions, args := buildSegmentConditions(segment.Rules)
+		conditions = append(conditions, "c.id = @customer_id")
+		args["customer_id"] = customerID
+
+		query := fmt.Sprintf(`select exists(select 1 from customers c where %s)`, strings.Join(conditions, " and "))
+
+		var member bool
+		if err := p.conn.QueryRow(ctx, query, args).Scan(&member); err != nil {
+			return nil, pgxErrorToStoreError(err)
+		}
+
+		if member {
+			names = append(names, segment.Name)
+		}
+	}
+
+	return names, nil
+}
+
+// buildSegmentConditions translates segment rules to the conditions of a
+// query over customers aliased as c.
+func buildSegmentConditions(rules SegmentRules) ([]string, pgx.NamedArgs) {
+	conditions := []string{"c.status = @active"}
+	args := pgx.NamedArgs{"active": ACTIVE_STATUS}
+
+	if len(rules.Tags) > 0 {
+		conditions = append(conditions, `(select count(*) from customer_tags t
+			where t.customer_id = c.id and t.tag = any(@tags)) = @tag_count`)
+		args["tags"] = rules.Tags
+		args["tag_count"] = len(rules.Tags)
+	}
+
+	if len(rules.ExcludedTags) > 0 {
+		conditions = append(conditions, `not exists (select 1 from customer_tags t
+			where t.customer_id = c.id and t.tag = any(@excluded_tags))`)
+		args["excluded_tags"] = rules.ExcludedTags
+	}
+
+	if len(rules.Cities) > 0 || len(rules.Countries) > 0 {
+		addressConditions := []string{"a.customer_id = c.id"}
+		if len(rules.Cities) > 0 {
+			addressConditions = append(addressConditions, "lower(a.city) = any(@cities)")
+			args["cities"] = rules.Cities
+		}
+		if len(rules.Countries) > 0 {
+			addressConditions = append(addressConditions, "lower(a.country) = any(@countries)")
+			args["countries"] = countryKeys(rules.Countries)
+		}
+		conditions = append(conditions, fmt.Sprintf("exists (select 1 from addresses a where %s)",
+			strings.Join(addressConditions, " and ")))
+	}
+
+	if rules.CreatedAfter != nil {
+		conditions = append(conditions, "c.created_at >= @created_after")
+		args["created_after"] = *rules.CreatedAfter
+	}
+
+	if rules.CreatedBefore != nil {
+		conditions = append(conditions, "c.created_at < @created_before")
+		args["created_before"] = *rules.CreatedBefore
+	}
+
+	return conditions, args
+}
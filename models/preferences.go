@@ -0,0 +1,41 @@
+package models
+
+import "time"
+
+const (
+	EMAIL_CHANNEL = "email"
+	SMS_CHANNEL   = "sms"
+	PUSH_CHANNEL  = "push"
+)
+
+const (
+	ORDER_UPDATES_MESSAGES = "order_updates"
+	PROMOTION_MESSAGES     = "promotions"
+	ACCOUNT_MESSAGES       = "account"
+)
+
+type Preferences struct {
+	CustomerId    int `db:"customer_id"`
+	Language      string
+	Currency      string
+	TimeZone      string `db:"time_zone"`
+	Notifications map[string][]string
+	UpdatedAt     time.Time `db:"updated_at"`
+}
+
+// DefaultPreferences returns the preferences of a customer that hasn't
+// saved any yet. Promotional messages are opt-in, so no channels are
+// enabled for them by default.
+func DefaultPreferences(customerId int) Preferences {
+	return Preferences{
+		CustomerId: customerId,
+		Language:   "bg-BG",
+		Currency:   "BGN",
+		TimeZone:   "Europe/Sofia",
+		Notifications: map[string][]string{
+			ORDER_UPDATES_MESSAGES: {EMAIL_CHANNEL, PUSH_CHANNEL},
+			PROMOTION_MESSAGES:     {},
+			ACCOUNT_MESSAGES:       {EMAIL_CHANNEL},
+		},
+	}
+}
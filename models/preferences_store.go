@@ -0,0 +1,6 @@
+package models
+
+type PreferencesStore interface {
+	GetPreferences(customerID int) (Preferences, error)
+	UpsertPreferences(preferences *Preferences) error
+}
@@ -0,0 +1,122 @@
+package models
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"math/big"
+	"strings"
+	"time"
+)
+
+const REFERRAL_CODE_LENGTH = 8
+
+// codeAlphabet leaves out 0, 1, I, L and O, which are easily confused when
+// a code is read out or typed from a flyer.
+const codeAlphabet = "23456789ABCDEFGHJKMNPQRSTUVWXYZ"
+
+// Referral statuses. Only accepted referrals count towards the stats of the
+// referrer; rejected ones are kept so abuse can be investigated.
+const (
+	ACCEPTED_REFERRAL        = "accepted"
+	SELF_REFERRAL            = "self_referral"
+	REUSED_IDENTITY_REFERRAL = "reused_identity"
+)
+
+// Referral records that the customer ReferredId signed up with the code of
+// ReferrerId. The phone number and email of the referred customer are kept
+// as keyed hashes, so a phone or email that was already referred once is
+// detected even after the account using it was anonymized, without the
+// hashes identifying the anonymized customer to anyone lacking the key.
+type Referral struct {
+	ReferredId int `db:"referred_id"`
+	ReferrerId int `db:"referrer_id"`
+	Code       string
+	PhoneHash  string `db:"phone_hash"`
+	EmailHash  string `db:"email_hash"`
+	Status     string
+	CreatedAt  time.Time `db:"created_at"`
+}
+
+type ReferralStats struct {
+	Code           string
+	Referred       int
+	LastReferredAt *time.Time
+}
+
+func NewReferralCode() (string, error) {
+	return newCode(REFERRAL_CODE_LENGTH)
+}
+
+func newCode(length int) (string, error) {
+	alphabetSize := big.NewInt(int64(len(codeAlphabet)))
+
+	code := make([]byte, length)
+	for i := range code {
+		n, err := rand.Int(rand.Reader, alphabetSize)
+		if err != nil {
+			return "", err
+		}
+		code[i] = codeAlphabet[n.Int64()]
+	}
+
+	return string(code), nil
+}
+
+// NormalizeReferralCode returns the stored form of a code as typed by a
+// customer, e.g. "ab3d-ef7h" becomes "AB3DEF7H".
+func NormalizeReferralCode(code string) string {
+	return strings.Map(func(r rune) rune {
+		if strings.ContainsRune(" -", r) {
+			return -1
+		}
+		return r
+	}, strings.ToUpper(strings.TrimSpace(code)))
+}
+
+// IdentityHash is an HMAC of a phone number or email. A plain hash could be
+// reversed by hashing every phone number, which are few enough to try.
+func IdentityHash(key []byte, value string) string {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(value))
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// NewReferral attributes the signup of referred to referrer. Emails are
+// compared with the provider rules applied regardless of configuration, so
+// signing up with a Gmail alias of the referrer counts as self-referral and
+// the alias can't be referred again. hashKey keys the IdentityHash of the
+// referred customer's phone number and email.
+func NewReferral(referrer Customer, referred Customer, code string, hashKey []byte) Referral {
+	referredEmail := CanonicalEmail(referred.Email, true)
+
+	referral := Referral{
+		ReferredId: referred.Id,
+		ReferrerId: referrer.Id,
+		Code:       code,
+		PhoneHash:  IdentityHash(hashKey, referred.PhoneNumber),
+		EmailHash:  IdentityHash(hashKey, referredEmail),
+		Status:     ACCEPTED_REFERRAL,
+	}
+
+	if referrer.Id == referred.Id || referrer.PhoneNumber == referred.PhoneNumber ||
+		CanonicalEmail(referrer.Email, true) == referredEmail {
+		referral.Status = SELF_REFERRAL
+	}
+
+	return referral
+}
+
+// ReferralSignup is a signup with the referral code of Referrer. The
+// referral itself is built once the referred customer is stored, so its
+// hashes are taken over the normalized phone number and email.
+type ReferralSignup struct {
+	Referrer Customer
+	Code     string
+	HashKey  []byte
+}
+
+func (s ReferralSignup) Referral(referred Customer) Referral {
+	return NewReferral(s.Referrer, referred, s.Code, s.HashKey)
+}
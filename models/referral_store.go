@@ -0,0 +1,7 @@
+package models
+
+type ReferralStore interface {
+	GetReferralCode(customerID int) (string, error)
+	GetReferrerByCode(code string) (int, error)
+	GetReferralStats(customerID int) (ReferralStats, error)
+}
@@ -0,0 +1,66 @@
+package models_test
+
+import (
+	"testing"
+
+	"github.com/VitoNaychev/bt-customer-svc/models"
+)
+
+func TestNewReferralCode(t *testing.T) {
+	code, err := models.NewReferralCode()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(code) != models.REFERRAL_CODE_LENGTH {
+		t.Errorf("got code %q of length %d want %d", code, len(code), models.REFERRAL_CODE_LENGTH)
+	}
+
+	if models.NormalizeReferralCode(code) != code {
+		t.Errorf("generated code %q is not normalized", code)
+	}
+}
+
+func TestNewReferral(t *testing.T) {
+	referrer := models.Customer{Id: 1, PhoneNumber: "+359881112233", Email: "ivanpetrov@gmail.com"}
+
+	cases := map[string]struct {
+		referred models.Customer
+		want     string
+	}{
+		"different person": {
+			models.Customer{Id: 2, PhoneNumber: "+359884445566", Email: "maria@abv.bg"},
+			models.ACCEPTED_REFERRAL,
+		},
+		"Gmail alias of the referrer": {
+			models.Customer{Id: 2, PhoneNumber: "+359884445566", Email: "ivan.petrov+1@googlemail.com"},
+			models.SELF_REFERRAL,
+		},
+		"phone number of the referrer": {
+			models.Customer{Id: 2, PhoneNumber: "+359881112233", Email: "maria@abv.bg"},
+			models.SELF_REFERRAL,
+		},
+	}
+
+	for name, test := range cases {
+		t.Run(name, func(t *testing.T) {
+			got := models.NewReferral(referrer, test.referred, "PETE2345", []byte("key"))
+
+			if got.Status != test.want {
+				t.Errorf("got status %q want %q", got.Status, test.want)
+			}
+		})
+	}
+}
+
+func TestIdentityHash(t *testing.T) {
+	hash := models.IdentityHash([]byte("key"), "+359881112233")
+
+	if hash != models.IdentityHash([]byte("key"), "+359881112233") {
+		t.Errorf("hash of the same value with the same key differs")
+	}
+
+	if hash == models.IdentityHash([]byte("other key"), "+359881112233") {
+		t.Errorf("hash doesn't depend on the key")
+	}
+}
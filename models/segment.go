@@ -0,0 +1,113 @@
+package models
+
+import (
+	"errors"
+	"regexp"
+	"slices"
+	"strings"
+	"time"
+)
+
+var (
+	ErrInvalidTag         = errors.New("tag must be 1 to 32 lowercase letters, digits, dashes or underscores")
+	ErrInvalidSegmentName = errors.New("segment name must be 1 to 32 lowercase letters, digits, dashes or underscores")
+)
+
+var tagPattern = regexp.MustCompile(`^[a-z0-9][a-z0-9_-]{0,31}$`)
+
+// NormalizeTag returns the stored form of a tag, so "VIP " and "vip" are
+// the same tag.
+func NormalizeTag(tag string) (string, error) {
+	tag = strings.ToLower(strings.TrimSpace(tag))
+	if !tagPattern.MatchString(tag) {
+		return "", ErrInvalidTag
+	}
+	return tag, nil
+}
+
+// NormalizeSegmentName returns the stored form of a segment name, which
+// follows the same rules as tags so it can be used in URLs as is.
+func NormalizeSegmentName(name string) (string, error) {
+	name = strings.ToLower(strings.TrimSpace(name))
+	if !tagPattern.MatchString(name) {
+		return "", ErrInvalidSegmentName
+	}
+	return name, nil
+}
+
+func NormalizeTags(tags []string) ([]string, error) {
+	normalized := []string{}
+	for _, tag := range tags {
+		tag, err := NormalizeTag(tag)
+		if err != nil {
+			return nil, err
+		}
+		if !slices.Contains(normalized, tag) {
+			normalized = append(normalized, tag)
+		}
+	}
+
+	slices.Sort(normalized)
+	return normalized, nil
+}
+
+// SegmentRules select the active customers of a segment. Empty rules are
+// ignored, so a segment without rules contains every active customer.
+// Cities and countries are compared case-insensitively and are both
+// matched against the same address.
+type SegmentRules struct {
+	Tags          []string
+	ExcludedTags  []string
+	Cities        []string
+	Countries     []string
+	CreatedAfter  *time.Time
+	CreatedBefore *time.Time
+}
+
+// Segment is a saved, named definition of a group of customers. Its
+// members are evaluated when queried, so they follow the data.
+type Segment struct {
+	Id          int
+	Name        string
+	Description string
+	Rules       SegmentRules
+	CreatedAt   time.Time `db:"created_at"`
+}
+
+// Normalize validates the tags of the rules and folds cities and countries
+// to lowercase.
+func (s *SegmentRules) Normalize() error {
+	var err error
+	if s.Tags, err = NormalizeTags(s.Tags); err != nil {
+		return err
+	}
+	if s.ExcludedTags, err = NormalizeTags(s.ExcludedTags); err != nil {
+		return err
+	}
+
+	s.Cities = lowerAll(s.Cities)
+	s.Countries = countryKeys(s.Countries)
+
+	return nil
+}
+
+// countryKeys maps the countries of segment rules to lowercase country
+// codes. Rules saved before addresses stored codes name the countries.
+func countryKeys(countries []string) []string {
+	keys := []string{}
+	for _, country := range countries {
+		if code, ok := CountryCode(country); ok {
+			country = code
+		}
+		keys = append(keys, strings.ToLower(strings.TrimSpace(country)))
+	}
+	return keys
+}
+
+func lowerAll(values []string) []string {
+	lowered := []string{}
+	for _, value := range values {
+		lowered = append(lowered, strings.ToLower(strings.TrimSpace(value)))
+	}
+	return lowered
+}
@@ -0,0 +1,12 @@
+package models
+
+type SegmentStore interface {
+	GetTags(customerID int) ([]string, error)
+	AddTags(customerID int, tags []string) error
+	RemoveTag(customerID int, tag string) error
+	CreateSegment(segment *Segment) error
+	GetSegments() ([]Segment, error)
+	DeleteSegment(name string) error
+	GetSegmentMembers(name string, afterID int, limit int) ([]int, error)
+	GetCustomerSegments(customerID int) ([]string, error)
+}
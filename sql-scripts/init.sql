@@ -1,22 +1,440 @@
+DROP TABLE IF EXISTS import_checkpoints;
+DROP TABLE IF EXISTS segments;
+DROP TABLE IF EXISTS customer_tags;
+DROP TABLE IF EXISTS duplicate_candidates;
+DROP TABLE IF EXISTS referrals;
+DROP TABLE IF EXISTS referral_codes;
+DROP TABLE IF EXISTS loyalty_entries;
+DROP TABLE IF EXISTS loyalty_transactions;
+DROP FUNCTION IF EXISTS check_loyalty_transaction_balanced;
+DROP FUNCTION IF EXISTS reject_ledger_changes;
+DROP TABLE IF EXISTS consents;
+DROP TABLE IF EXISTS consent_documents;
+DROP FUNCTION IF EXISTS reject_consent_changes;
+DROP TABLE IF EXISTS customer_preferences;
+DROP TABLE IF EXISTS address_revisions;
 DROP TABLE IF EXISTS addresses;
+DROP TABLE IF EXISTS household_invitations;
+DROP TABLE IF EXISTS household_members;
+DROP TABLE IF EXISTS households;
+DROP FUNCTION IF EXISTS record_address_revision;
+DROP FUNCTION IF EXISTS check_default_address;
+DROP FUNCTION IF EXISTS geohash_encode;
 DROP TABLE IF EXISTS customers;
+DROP FUNCTION IF EXISTS touch_updated_at;
 
 CREATE TABLE customers (
   id                  serial               PRIMARY KEY,
 	first_name          varchar(20)          NOT NULL,
   last_name           varchar(20)          NOT NULL,
-  phone_number        varchar(20)          UNIQUE NOT NULL,
-  email               varchar(40)          UNIQUE NOT NULL,
-  password            varchar(72)          NOT NULL
+  phone_number        varchar(20)          UNIQUE NOT NULL
+                                           CHECK (phone_number ~ '^\+[1-9][0-9]{7,14}$'),
+  email               varchar(40)          NOT NULL,
+  canonical_email     varchar(40)          UNIQUE NOT NULL,
+  password            varchar(72)          NOT NULL,
+  avatar_url          varchar(255)         NOT NULL DEFAULT '',
+  status              varchar(16)          NOT NULL DEFAULT 'active'
+                                           CHECK (status IN ('active', 'anonymized', 'merged')),
+  merged_into         int                  REFERENCES customers(id),
+  created_at          timestamptz          NOT NULL DEFAULT now(),
+  updated_at          timestamptz          NOT NULL DEFAULT now(),
+  version             int                  NOT NULL DEFAULT 1,
+  CHECK ((status = 'merged') = (merged_into IS NOT NULL))
   );
 
+-- Households share their addresses between their members. Owners invite
+-- and remove members; a household always keeps at least one owner.
+CREATE TABLE households (
+  id                  serial               PRIMARY KEY,
+  name                varchar(40)          NOT NULL,
+  created_at          timestamptz          NOT NULL DEFAULT now()
+  );
+
+CREATE TABLE household_members (
+  household_id        int                  NOT NULL REFERENCES households(id) ON DELETE CASCADE,
+  customer_id         int                  NOT NULL REFERENCES customers(id) ON DELETE CASCADE,
+  role                varchar(10)          NOT NULL CHECK (role IN ('owner', 'member')),
+  joined_at           timestamptz          NOT NULL DEFAULT now(),
+  PRIMARY KEY (household_id, customer_id)
+  );
+
+CREATE INDEX household_members_customer_id_idx ON household_members (customer_id);
+
+CREATE TABLE household_invitations (
+  code                varchar(16)          PRIMARY KEY,
+  household_id        int                  NOT NULL REFERENCES households(id) ON DELETE CASCADE,
+  invited_by          int                  REFERENCES customers(id) ON DELETE SET NULL,
+  role                varchar(10)          NOT NULL CHECK (role IN ('owner', 'member')),
+  expires_at          timestamptz          NOT NULL,
+  accepted_by         int                  REFERENCES customers(id) ON DELETE SET NULL,
+  accepted_at         timestamptz                  ,
+  created_at          timestamptz          NOT NULL DEFAULT now()
+  );
+
+CREATE EXTENSION IF NOT EXISTS pg_trgm;
+
+-- Trigram indexes back the prefix and fragment filters of the admin
+-- customer search.
+CREATE INDEX customers_first_name_trgm_idx ON customers USING gin (lower(first_name) gin_trgm_ops);
+CREATE INDEX customers_last_name_trgm_idx ON customers USING gin (lower(last_name) gin_trgm_ops);
+CREATE INDEX customers_full_name_trgm_idx ON customers
+  USING gin ((lower(first_name) || ' ' || lower(last_name)) gin_trgm_ops);
+CREATE INDEX customers_email_trgm_idx ON customers USING gin (lower(email) gin_trgm_ops);
+CREATE INDEX customers_phone_digits_trgm_idx ON customers
+  USING gin (regexp_replace(phone_number, '[^0-9]', '', 'g') gin_trgm_ops);
+CREATE INDEX customers_created_at_idx ON customers (created_at, id);
+
+-- geohash_encode matches models.EncodeGeohash. Addresses close to each
+-- other share a geohash prefix, so proximity searches look up a few
+-- prefixes in a plain btree index.
+CREATE FUNCTION geohash_encode(lat double precision, lon double precision, chars int) RETURNS text AS $$
+DECLARE
+  alphabet constant text := '0123456789bcdefghjkmnpqrstuvwxyz';
+  lat_min double precision := -90;
+  lat_max double precision := 90;
+  lon_min double precision := -180;
+  lon_max double precision := 180;
+  mid double precision;
+  hash text := '';
+  bits int := 0;
+  ch int := 0;
+  even boolean := true;
+BEGIN
+  WHILE length(hash) < chars LOOP
+    IF even THEN
+      mid := (lon_min + lon_max) / 2;
+      IF lon >= mid THEN
+        ch := ch * 2 + 1;
+        lon_min := mid;
+      ELSE
+        ch := ch * 2;
+        lon_max := mid;
+      END IF;
+    ELSE
+      mid := (lat_min + lat_max) / 2;
+      IF lat >= mid THEN
+        ch := ch * 2 + 1;
+        lat_min := mid;
+      ELSE
+        ch := ch * 2;
+        lat_max := mid;
+      END IF;
+    END IF;
+
+    even := NOT even;
+    bits := bits + 1;
+    IF bits = 5 THEN
+      hash := hash || substr(alphabet, ch + 1, 1);
+      bits := 0;
+      ch := 0;
+    END IF;
+  END LOOP;
+  RETURN hash;
+END;
+$$ LANGUAGE plpgsql IMMUTABLE STRICT;
+
 CREATE TABLE addresses (
   id                  serial               PRIMARY KEY,
   customer_id         int                  REFERENCES customers(id),
+  household_id        int                  REFERENCES households(id),
   lat                 numeric(10, 7)       NOT NULL,
   lon                 numeric(10, 7)       NOT NULL,
   address_line1       varchar(100)         NOT NULL,
   address_line2       varchar(100)                 ,
   city                varchar(40)          NOT NULL,
-  country             varchar(40)          NOT NULL
-  );
\ No newline at end of file
+  region              varchar(40)          NOT NULL DEFAULT '',
+  postal_code         varchar(10)          NOT NULL DEFAULT '',
+  country             char(2)              NOT NULL CHECK (country ~ '^[A-Z]{2}$'),
+  label               varchar(20)          NOT NULL DEFAULT '',
+  is_default          boolean              NOT NULL DEFAULT false,
+  delivery_instructions varchar(255)       NOT NULL DEFAULT '',
+  floor               varchar(10)          NOT NULL DEFAULT '',
+  entrance            varchar(10)          NOT NULL DEFAULT '',
+  apartment           varchar(10)          NOT NULL DEFAULT '',
+  contact_phone       varchar(20)          NOT NULL DEFAULT ''
+                                           CHECK (contact_phone = '' OR contact_phone ~ '^\+[1-9][0-9]{7,14}$'),
+  coordinates_mismatch boolean             NOT NULL DEFAULT false,
+  geohash             varchar(12)          COLLATE "C"
+                                           GENERATED ALWAYS AS (geohash_encode(lat::float8, lon::float8, 9)) STORED,
+  updated_at          timestamptz          NOT NULL DEFAULT now(),
+  version             int                  NOT NULL DEFAULT 1,
+  CHECK (household_id IS NULL OR NOT is_default)
+  );
+
+CREATE INDEX addresses_household_id_idx ON addresses (household_id);
+
+-- Addresses of a household belong to all of its members; customer_id only
+-- records who added them, so they are never anyone's default address.
+-- A customer with addresses has exactly one default address. The unique
+-- index rejects a second default right away, even from concurrent
+-- transactions; the deferred trigger rejects a customer left without one,
+-- but only at commit, so the default can be moved within a transaction.
+CREATE UNIQUE INDEX addresses_one_default_idx ON addresses (customer_id) WHERE is_default;
+
+CREATE FUNCTION check_default_address() RETURNS trigger AS $$
+DECLARE
+  customer int;
+BEGIN
+  FOREACH customer IN ARRAY ARRAY[OLD.customer_id, NEW.customer_id] LOOP
+    IF customer IS NOT NULL
+      AND EXISTS (SELECT 1 FROM addresses WHERE customer_id = customer AND household_id IS NULL)
+      AND NOT EXISTS (SELECT 1 FROM addresses WHERE customer_id = customer AND is_default) THEN
+      RAISE EXCEPTION 'customer % has no default address', customer
+        USING ERRCODE = 'check_violation', TABLE = 'addresses', CONSTRAINT = 'addresses_is_default_check';
+    END IF;
+  END LOOP;
+  RETURN NULL;
+END;
+$$ LANGUAGE plpgsql;
+
+CREATE CONSTRAINT TRIGGER addresses_default_required
+  AFTER INSERT OR UPDATE OR DELETE ON addresses
+  DEFERRABLE INITIALLY DEFERRED
+  FOR EACH ROW EXECUTE FUNCTION check_default_address();
+
+-- updated_at backs the incremental exports, so it is kept by a trigger
+-- rather than by every statement that changes a row.
+CREATE FUNCTION touch_updated_at() RETURNS trigger AS $$
+BEGIN
+  NEW.updated_at = now();
+  RETURN NEW;
+END;
+$$ LANGUAGE plpgsql;
+
+CREATE TRIGGER customers_touch_updated_at
+  BEFORE UPDATE ON customers
+  FOR EACH ROW EXECUTE FUNCTION touch_updated_at();
+
+CREATE TRIGGER addresses_touch_updated_at
+  BEFORE UPDATE ON addresses
+  FOR EACH ROW EXECUTE FUNCTION touch_updated_at();
+
+CREATE INDEX customers_updated_at_idx ON customers (updated_at, id);
+CREATE INDEX addresses_updated_at_idx ON addresses (updated_at, id);
+CREATE INDEX addresses_geohash_idx ON addresses (geohash);
+
+-- Every version of an address is kept as a revision, so orders can refer to
+-- the address they deliver to after it is edited. Revisions outlive their
+-- address, so address_id deliberately has no foreign key; revisions frozen
+-- by an order are kept when the address is deleted, the others go with it.
+CREATE TABLE address_revisions (
+  address_id          int                  NOT NULL,
+  revision            int                  NOT NULL,
+  customer_id         int                          ,
+  household_id        int                          ,
+  lat                 numeric(10, 7)       NOT NULL,
+  lon                 numeric(10, 7)       NOT NULL,
+  address_line1       varchar(100)         NOT NULL,
+  address_line2       varchar(100)                 ,
+  city                varchar(40)          NOT NULL,
+  region              varchar(40)          NOT NULL,
+  postal_code         varchar(10)          NOT NULL,
+  country             char(2)              NOT NULL,
+  delivery_instructions varchar(255)       NOT NULL,
+  floor               varchar(10)          NOT NULL,
+  entrance            varchar(10)          NOT NULL,
+  apartment           varchar(10)          NOT NULL,
+  contact_phone       varchar(20)          NOT NULL,
+  created_at          timestamptz          NOT NULL DEFAULT now(),
+  frozen_at           timestamptz                  ,
+  PRIMARY KEY (address_id, revision)
+  );
+
+CREATE INDEX address_revisions_customer_id_idx ON address_revisions (customer_id);
+
+-- Updates that don't bump the version, like the country migration writing
+-- a country name as its code, don't change what the address delivers to,
+-- so they conflict and are skipped.
+CREATE FUNCTION record_address_revision() RETURNS trigger AS $$
+BEGIN
+  INSERT INTO address_revisions(address_id, revision, customer_id, household_id, lat, lon, address_line1,
+    address_line2, city, region, postal_code, country, delivery_instructions, floor, entrance, apartment,
+    contact_phone)
+  VALUES (NEW.id, NEW.version, NEW.customer_id, NEW.household_id, NEW.lat, NEW.lon, NEW.address_line1,
+    NEW.address_line2, NEW.city, NEW.region, NEW.postal_code, NEW.country, NEW.delivery_instructions, NEW.floor,
+    NEW.entrance, NEW.apartment, NEW.contact_phone)
+  ON CONFLICT (address_id, revision) DO NOTHING;
+  RETURN NULL;
+END;
+$$ LANGUAGE plpgsql;
+
+CREATE TRIGGER addresses_record_revision
+  AFTER INSERT OR UPDATE ON addresses
+  FOR EACH ROW EXECUTE FUNCTION record_address_revision();
+
+CREATE TABLE customer_preferences (
+  customer_id         int                  PRIMARY KEY REFERENCES customers(id) ON DELETE CASCADE,
+  language            varchar(10)          NOT NULL,
+  currency            char(3)              NOT NULL,
+  time_zone           varchar(64)          NOT NULL,
+  notifications       jsonb                NOT NULL DEFAULT '{}',
+  updated_at          timestamptz          NOT NULL DEFAULT now()
+  );
+
+CREATE TRIGGER customer_preferences_touch_updated_at
+  BEFORE UPDATE ON customer_preferences
+  FOR EACH ROW EXECUTE FUNCTION touch_updated_at();
+
+CREATE INDEX customer_preferences_updated_at_idx ON customer_preferences (updated_at, customer_id);
+
+CREATE TABLE consent_documents (
+  id                  serial               PRIMARY KEY,
+  kind                varchar(20)          NOT NULL,
+  version             varchar(20)          NOT NULL,
+  mandatory           boolean              NOT NULL,
+  published_at        timestamptz          NOT NULL DEFAULT now(),
+  UNIQUE (kind, version)
+  );
+
+-- Consents are kept as legal evidence even after the customer account is
+-- gone, so customer_id deliberately has no foreign key.
+CREATE TABLE consents (
+  id                  serial               PRIMARY KEY,
+  customer_id         int                  NOT NULL,
+  document_id         int                  NOT NULL REFERENCES consent_documents(id),
+  granted             boolean              NOT NULL,
+  recorded_at         timestamptz          NOT NULL DEFAULT now()
+  );
+
+CREATE INDEX consents_customer_id_idx ON consents (customer_id, recorded_at);
+
+CREATE FUNCTION reject_consent_changes() RETURNS trigger AS $$
+BEGIN
+  RAISE EXCEPTION 'consent history is append-only';
+END;
+$$ LANGUAGE plpgsql;
+
+CREATE TRIGGER consents_append_only
+  BEFORE UPDATE OR DELETE ON consents
+  FOR EACH ROW EXECUTE FUNCTION reject_consent_changes();
+
+-- The loyalty ledger is kept for accounting after the customer account is
+-- gone, so customer_id deliberately has no foreign key. Transaction IDs are
+-- chosen by the caller and make retries idempotent.
+CREATE TABLE loyalty_transactions (
+  id                  varchar(64)          PRIMARY KEY,
+  customer_id         int                  NOT NULL,
+  kind                varchar(10)          NOT NULL
+                                           CHECK (kind IN ('earn', 'redeem', 'expire', 'adjust')),
+  points              int                  NOT NULL CHECK (points <> 0),
+  description         varchar(255)         NOT NULL DEFAULT '',
+  expires_at          timestamptz                  ,
+  created_at          timestamptz          NOT NULL DEFAULT now()
+  );
+
+CREATE INDEX loyalty_transactions_customer_id_idx ON loyalty_transactions (customer_id, created_at);
+
+-- Every transaction moves points between the customer's account and a
+-- system account, so the amounts of its entries always sum to zero.
+CREATE TABLE loyalty_entries (
+  id                  serial               PRIMARY KEY,
+  transaction_id      varchar(64)          NOT NULL REFERENCES loyalty_transactions(id),
+  account             varchar(20)          NOT NULL,
+  customer_id         int                          ,
+  amount              int                  NOT NULL,
+  CHECK ((account = 'customer') = (customer_id IS NOT NULL))
+  );
+
+CREATE INDEX loyalty_entries_customer_id_idx ON loyalty_entries (customer_id) WHERE account = 'customer';
+
+CREATE FUNCTION check_loyalty_transaction_balanced() RETURNS trigger AS $$
+BEGIN
+  IF (SELECT sum(amount) FROM loyalty_entries WHERE transaction_id = NEW.transaction_id) <> 0 THEN
+    RAISE EXCEPTION 'loyalty transaction % is not balanced', NEW.transaction_id;
+  END IF;
+  RETURN NULL;
+END;
+$$ LANGUAGE plpgsql;
+
+CREATE CONSTRAINT TRIGGER loyalty_entries_balanced
+  AFTER INSERT ON loyalty_entries
+  DEFERRABLE INITIALLY DEFERRED
+  FOR EACH ROW EXECUTE FUNCTION check_loyalty_transaction_balanced();
+
+CREATE FUNCTION reject_ledger_changes() RETURNS trigger AS $$
+BEGIN
+  RAISE EXCEPTION 'loyalty ledger is append-only';
+END;
+$$ LANGUAGE plpgsql;
+
+CREATE TRIGGER loyalty_transactions_append_only
+  BEFORE UPDATE OR DELETE ON loyalty_transactions
+  FOR EACH ROW EXECUTE FUNCTION reject_ledger_changes();
+
+CREATE TRIGGER loyalty_entries_append_only
+  BEFORE UPDATE OR DELETE ON loyalty_entries
+  FOR EACH ROW EXECUTE FUNCTION reject_ledger_changes();
+
+CREATE TABLE referral_codes (
+  customer_id         int                  PRIMARY KEY REFERENCES customers(id) ON DELETE CASCADE,
+  code                char(8)              UNIQUE NOT NULL
+                                           CHECK (code ~ '^[2-9A-HJKMNP-Z]{8}$'),
+  created_at          timestamptz          NOT NULL DEFAULT now()
+  );
+
+-- Referrals are kept after either account is gone, so abuse by reusing a
+-- phone number or email is still detected; only hashes of the referred
+-- identity are stored and the customer IDs deliberately have no foreign key.
+CREATE TABLE referrals (
+  referred_id         int                  PRIMARY KEY,
+  referrer_id         int                  NOT NULL,
+  code                char(8)              NOT NULL,
+  phone_hash          char(64)             NOT NULL,
+  email_hash          char(64)             NOT NULL,
+  status              varchar(16)          NOT NULL
+                                           CHECK (status IN ('accepted', 'self_referral', 'reused_identity')),
+  created_at          timestamptz          NOT NULL DEFAULT now()
+  );
+
+CREATE INDEX referrals_referrer_id_idx ON referrals (referrer_id, created_at);
+CREATE UNIQUE INDEX referrals_phone_hash_key ON referrals (phone_hash) WHERE status = 'accepted';
+CREATE UNIQUE INDEX referrals_email_hash_key ON referrals (email_hash) WHERE status = 'accepted';
+
+-- Pairs of customers the duplicate detection job scored as likely the same
+-- person, queued for an admin to merge or dismiss. Dismissed pairs are kept
+-- so later runs don't queue them again.
+CREATE TABLE duplicate_candidates (
+  id                  serial               PRIMARY KEY,
+  customer_id         int                  NOT NULL REFERENCES customers(id) ON DELETE CASCADE,
+  duplicate_id        int                  NOT NULL REFERENCES customers(id) ON DELETE CASCADE,
+  score               numeric(3, 2)        NOT NULL,
+  reasons             text[]               NOT NULL,
+  status              varchar(10)          NOT NULL DEFAULT 'pending'
+                                           CHECK (status IN ('pending', 'merged', 'dismissed')),
+  created_at          timestamptz          NOT NULL DEFAULT now(),
+  reviewed_at         timestamptz                  ,
+  UNIQUE (customer_id, duplicate_id),
+  CHECK (customer_id < duplicate_id)
+  );
+
+CREATE INDEX duplicate_candidates_status_idx ON duplicate_candidates (status, score DESC);
+
+CREATE TABLE customer_tags (
+  customer_id         int                  NOT NULL REFERENCES customers(id) ON DELETE CASCADE,
+  tag                 varchar(32)          NOT NULL CHECK (tag ~ '^[a-z0-9][a-z0-9_-]*$'),
+  created_at          timestamptz          NOT NULL DEFAULT now(),
+  PRIMARY KEY (customer_id, tag)
+  );
+
+CREATE INDEX customer_tags_tag_idx ON customer_tags (tag, customer_id);
+
+-- Segments store the rules of models.SegmentRules as JSON; their members
+-- are evaluated on every query rather than materialized.
+CREATE TABLE segments (
+  id                  serial               PRIMARY KEY,
+  name                varchar(32)          UNIQUE NOT NULL CHECK (name ~ '^[a-z0-9][a-z0-9_-]*$'),
+  description         varchar(255)         NOT NULL DEFAULT '',
+  rules               jsonb                NOT NULL DEFAULT '{}',
+  created_at          timestamptz          NOT NULL DEFAULT now()
+  );
+
+CREATE INDEX addresses_city_idx ON addresses (lower(city), customer_id);
+
+-- Import checkpoints hold the last source line a bulk import committed, so
+-- an interrupted import resumes after it. They are written in the same
+-- transaction as the imported rows.
+CREATE TABLE import_checkpoints (
+  source              varchar(255)         PRIMARY KEY,
+  line                int                  NOT NULL,
+  updated_at          timestamptz          NOT NULL DEFAULT now()
+  );
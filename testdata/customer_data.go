@@ -1,6 +1,8 @@
 package testdata
 
 import (
+	"time"
+
 	"github.com/VitoNaychev/bt-customer-svc/models"
 )
 
@@ -8,18 +10,22 @@ var PeterCustomer = models.Customer{
 	Id:          1,
 	FirstName:   "Peter",
 	LastName:    "Smith",
-	PhoneNumber: "+359 88 576 5981",
+	PhoneNumber: "+359885765981",
 	Email:       "petesmith@gmail.com",
 	Password:    "firefirefire",
+	Status:      models.ACTIVE_STATUS,
+	Version:     1,
 }
 
 var AliceCustomer = models.Customer{
 	Id:          2,
 	FirstName:   "Alice",
 	LastName:    "Johnson",
-	PhoneNumber: "+359 88 444 2222",
+	PhoneNumber: "+359884442222",
 	Email:       "alicejohn@gmail.com",
 	Password:    "helloJohn123",
+	Status:      models.ACTIVE_STATUS,
+	Version:     1,
 }
 
 var PeterAddress1 = models.Address{
@@ -30,7 +36,9 @@ var PeterAddress1 = models.Address{
 	AddressLine1: "Shipka Street 6",
 	AddressLine2: "",
 	City:         "Sofia",
-	Country:      "Bulgaria",
+	Country:      "BG",
+	IsDefault:    true,
+	Version:      1,
 }
 
 var PeterAddress2 = models.Address{
@@ -41,7 +49,8 @@ var PeterAddress2 = models.Address{
 	AddressLine1: "ulitsa Gerogi S. Rakovski 96",
 	AddressLine2: "",
 	City:         "Sofia",
-	Country:      "Bulgaria",
+	Country:      "BG",
+	Version:      1,
 }
 
 var AliceAddress = models.Address{
@@ -52,5 +61,92 @@ var AliceAddress = models.Address{
 	AddressLine1: "ut. Angel Kanchev 1",
 	AddressLine2: "",
 	City:         "Sofia",
-	Country:      "Bulgaria",
+	Country:      "BG",
+	IsDefault:    true,
+	Version:      1,
+}
+
+var SmithHousehold = models.Household{
+	Id:   1,
+	Name: "Smith Home",
+}
+
+var PeterHouseholdOwner = models.HouseholdMember{
+	HouseholdId: 1,
+	CustomerId:  1,
+	Role:        models.OWNER_ROLE,
+}
+
+var AliceHouseholdMember = models.HouseholdMember{
+	HouseholdId: 1,
+	CustomerId:  2,
+	Role:        models.MEMBER_ROLE,
+}
+
+// SmithHouseholdAddress was added to the household by Alice.
+var SmithHouseholdAddress = models.Address{
+	Id:           4,
+	CustomerId:   2,
+	HouseholdId:  &SmithHousehold.Id,
+	Lat:          42.6977082,
+	Lon:          23.3218675,
+	AddressLine1: "Vitosha Boulevard 18",
+	AddressLine2: "",
+	City:         "Sofia",
+	Country:      "BG",
+	Version:      1,
+}
+
+var AlicePreferences = models.Preferences{
+	CustomerId: 2,
+	Language:   "en-GB",
+	Currency:   "EUR",
+	TimeZone:   "Europe/London",
+	Notifications: map[string][]string{
+		models.ORDER_UPDATES_MESSAGES: {models.SMS_CHANNEL},
+		models.PROMOTION_MESSAGES:     {models.EMAIL_CHANNEL},
+		models.ACCOUNT_MESSAGES:       {models.EMAIL_CHANNEL},
+	},
+}
+
+var TermsV1 = models.ConsentDocument{
+	Id:        1,
+	Kind:      models.TERMS_DOCUMENT,
+	Version:   "2023-01",
+	Mandatory: true,
+}
+
+var MarketingV1 = models.ConsentDocument{
+	Id:        2,
+	Kind:      models.MARKETING_DOCUMENT,
+	Version:   "2023-01",
+	Mandatory: false,
+}
+
+var TermsV2 = models.ConsentDocument{
+	Id:        3,
+	Kind:      models.TERMS_DOCUMENT,
+	Version:   "2023-11",
+	Mandatory: true,
+}
+
+var pointsExpiry = time.Date(2024, time.November, 1, 0, 0, 0, 0, time.UTC)
+
+var PeterEarnedPoints = models.LoyaltyTransaction{
+	Id:          "order-1001-earn",
+	CustomerId:  1,
+	Kind:        models.EARN_TRANSACTION,
+	Points:      120,
+	Description: "order 1001",
+	ExpiresAt:   &pointsExpiry,
+	CreatedAt:   time.Date(2023, time.November, 1, 0, 0, 0, 0, time.UTC),
+}
+
+var PeterRedeemedPoints = models.LoyaltyTransaction{
+	Id:          "order-1002-redeem",
+	CustomerId:  1,
+	Kind:        models.REDEEM_TRANSACTION,
+	Points:      -50,
+	Description: "order 1002",
+	CreatedAt:   time.Date(2023, time.November, 5, 0, 0, 0, 0, time.UTC),
 }
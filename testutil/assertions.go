@@ -31,6 +31,21 @@ func AssertErrorResponse(t testing.TB, body io.Reader, expetedError error) {
 	}
 }
 
+func AssertFieldErrorResponse(t testing.TB, body io.Reader, expetedError error, expectedField string) {
+	t.Helper()
+
+	var fieldErrorResponse handlers.FieldErrorResponse
+	json.NewDecoder(body).Decode(&fieldErrorResponse)
+
+	if fieldErrorResponse.Message != expetedError.Error() {
+		t.Errorf("got error %q want %q", fieldErrorResponse.Message, expetedError.Error())
+	}
+
+	if fieldErrorResponse.Field != expectedField {
+		t.Errorf("got field %q want %q", fieldErrorResponse.Field, expectedField)
+	}
+}
+
 func AssertStatus(t testing.TB, got, want int) {
 	t.Helper()
 
@@ -154,3 +169,118 @@ func AssertStoredAddress(t testing.TB, store *StubAddressStore, address models.A
 		t.Errorf("did not store correct customer got %d want %d", store.storeCalls[0].Id, address.Id)
 	}
 }
+
+func AssertAnonymizedCustomer(t testing.TB, store *StubCustomerStore, customer models.Customer) {
+	t.Helper()
+
+	if len(store.anonymizeCalls) != 1 {
+		t.Fatalf("got %d calls to AnonymizeCustomer expected %d", len(store.anonymizeCalls), 1)
+	}
+
+	if store.anonymizeCalls[0] != customer.Id {
+		t.Errorf("did not anonymize correct customer got %d want %d", store.anonymizeCalls[0], customer.Id)
+	}
+}
+
+func AssertPatchedCustomer(t testing.TB, store *StubCustomerStore, patch models.CustomerPatch) {
+	t.Helper()
+
+	if len(store.patchCalls) != 1 {
+		t.Fatalf("got %d calls to PatchCustomer expected %d", len(store.patchCalls), 1)
+	}
+
+	if !reflect.DeepEqual(store.patchCalls[0], patch) {
+		t.Errorf("did not apply correct patch got %v want %v", store.patchCalls[0], patch)
+	}
+}
+
+func AssertPatchedAddress(t testing.TB, store *StubAddressStore, patch models.AddressPatch) {
+	t.Helper()
+
+	if len(store.patchCalls) != 1 {
+		t.Fatalf("got %d calls to PatchAddress expected %d", len(store.patchCalls), 1)
+	}
+
+	if !reflect.DeepEqual(store.patchCalls[0], patch) {
+		t.Errorf("did not apply correct patch got %v want %v", store.patchCalls[0], patch)
+	}
+}
+
+func AssertUpsertedPreferences(t testing.TB, store *StubPreferencesStore, preferences models.Preferences) {
+	t.Helper()
+
+	if len(store.upsertCalls) != 1 {
+		t.Fatalf("got %d calls to UpsertPreferences expected %d", len(store.upsertCalls), 1)
+	}
+
+	if !reflect.DeepEqual(store.upsertCalls[0], preferences) {
+		t.Errorf("did not store correct preferences got %v want %v", store.upsertCalls[0], preferences)
+	}
+}
+
+func AssertRecordedConsents(t testing.TB, store *StubConsentStore, want []models.Consent) {
+	t.Helper()
+
+	if len(store.recordCalls) != len(want) {
+		t.Fatalf("got %d calls to RecordConsent expected %d", len(store.recordCalls), len(want))
+	}
+
+	for i, consent := range store.recordCalls {
+		got := models.Consent{CustomerId: consent.CustomerId, DocumentId: consent.DocumentId, Granted: consent.Granted}
+		if !reflect.DeepEqual(got, want[i]) {
+			t.Errorf("did not record correct consent got %v want %v", got, want[i])
+		}
+	}
+}
+
+func AssertRecordedTransaction(t testing.TB, store *StubLoyaltyStore, want models.LoyaltyTransaction) {
+	t.Helper()
+
+	if len(store.recordCalls) != 1 {
+		t.Fatalf("got %d calls to RecordTransaction expected %d", len(store.recordCalls), 1)
+	}
+
+	if !store.recordCalls[0].SameAs(want) {
+		t.Errorf("did not record correct transaction got %v want %v", store.recordCalls[0], want)
+	}
+}
+
+func AssertSignupConsents(t testing.TB, store *StubCustomerStore, want []models.Consent) {
+	t.Helper()
+
+	if len(store.signupConsents) != len(want) {
+		t.Fatalf("got %d consents on signup expected %d", len(store.signupConsents), len(want))
+	}
+
+	for i, got := range store.signupConsents {
+		if !reflect.DeepEqual(got, want[i]) {
+			t.Errorf("did not sign up with correct consent got %v want %v", got, want[i])
+		}
+	}
+}
+
+func AssertSignupReferral(t testing.TB, store *StubCustomerStore, referrerID int, status string) {
+	t.Helper()
+
+	if len(store.signupReferrals) != 1 {
+		t.Fatalf("got %d referrals on signup expected %d", len(store.signupReferrals), 1)
+	}
+
+	got := store.signupReferrals[0]
+	if got.ReferrerId != referrerID || got.Status != status {
+		t.Errorf("did not sign up with correct referral got referrer %d status %q want referrer %d status %q",
+			got.ReferrerId, got.Status, referrerID, status)
+	}
+}
+
+func AssertMergedCustomers(t testing.TB, store *StubDuplicateStore, survivorID int, mergedID int) {
+	t.Helper()
+
+	if len(store.mergeCalls) != 1 {
+		t.Fatalf("got %d calls to MergeCustomers expected %d", len(store.mergeCalls), 1)
+	}
+
+	if store.mergeCalls[0] != [2]int{survivorID, mergedID} {
+		t.Errorf("did not merge correct customers got %v want %v", store.mergeCalls[0], [2]int{survivorID, mergedID})
+	}
+}
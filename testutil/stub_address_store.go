@@ -1,6 +1,10 @@
 package testutil
 
 import (
+	"slices"
+	"sort"
+	"time"
+
 	"github.com/VitoNaychev/bt-customer-svc/models"
 	td "github.com/VitoNaychev/bt-customer-svc/testdata"
 )
@@ -10,14 +14,23 @@ type StubAddressStore struct {
 	storeCalls  []models.Address
 	deleteCalls []int
 	updateCalls []models.Address
+	patchCalls  []models.AddressPatch
+	revisions   []models.AddressRevision
 }
 
 func NewStubAddressStore(data []models.Address) *StubAddressStore {
+	revisions := []models.AddressRevision{}
+	for _, address := range data {
+		revisions = append(revisions, models.NewAddressRevision(address))
+	}
+
 	return &StubAddressStore{
 		addresses:   data,
 		storeCalls:  []models.Address{},
 		deleteCalls: []int{},
 		updateCalls: []models.Address{},
+		patchCalls:  []models.AddressPatch{},
+		revisions:   revisions,
 	}
 }
 
@@ -42,30 +55,168 @@ func (s *StubAddressStore) GetAddressesByCustomerID(customerId int) ([]models.Ad
 	return []models.Address{}, nil
 }
 
+func (s *StubAddressStore) GetAddressesByHouseholdID(householdID int) ([]models.Address, error) {
+	addresses := []models.Address{}
+	for _, address := range s.addresses {
+		if address.HouseholdId != nil && *address.HouseholdId == householdID {
+			addresses = append(addresses, address)
+		}
+	}
+	return addresses, nil
+}
+
 func (s *StubAddressStore) CreateAddress(address *models.Address) error {
 	address.Id = len(s.addresses) + 1
+	address.Version = 1
+	address.IsDefault = address.HouseholdId == nil && (address.IsDefault || !s.hasDefault(address.CustomerId))
+	if address.IsDefault {
+		s.clearDefault(address.CustomerId, address.Id)
+	}
 	s.addresses = append(s.addresses, *address)
 	s.storeCalls = append(s.storeCalls, *address)
+	s.revisions = append(s.revisions, models.NewAddressRevision(*address))
 
 	return nil
 }
 
 func (s *StubAddressStore) UpdateAddress(address *models.Address) error {
 	s.updateCalls = append(s.updateCalls, *address)
+
+	for _, stored := range s.addresses {
+		if stored.Id == address.Id && stored.Version != address.Version {
+			return models.ErrVersionConflict
+		}
+		if stored.Id == address.Id {
+			address.IsDefault = address.IsDefault || stored.IsDefault
+		}
+	}
+	if address.IsDefault {
+		s.clearDefault(address.CustomerId, address.Id)
+	}
+	address.Version++
+	s.revisions = append(s.revisions, models.NewAddressRevision(*address))
+
 	return nil
 }
 
-func (s *StubAddressStore) DeleteAddress(id int) error {
-	_, err := s.GetAddressByID(id)
+func (s *StubAddressStore) PatchAddress(id int, version int, patch models.AddressPatch) (models.Address, error) {
+	for i, address := range s.addresses {
+		if address.Id == id {
+			if address.Version != version {
+				return models.Address{}, models.ErrVersionConflict
+			}
+
+			if patch == (models.AddressPatch{}) {
+				s.patchCalls = append(s.patchCalls, patch)
+				return address, nil
+			}
+
+			if patch.IsDefault != nil && *patch.IsDefault {
+				s.clearDefault(address.CustomerId, id)
+			}
+			s.addresses[i] = patch.Apply(address)
+			s.addresses[i].Version++
+			s.patchCalls = append(s.patchCalls, patch)
+			s.revisions = append(s.revisions, models.NewAddressRevision(s.addresses[i]))
+			return s.addresses[i], nil
+		}
+	}
+
+	return models.Address{}, models.ErrNotFound
+}
+
+func (s *StubAddressStore) DeleteAddress(id int, version int) error {
+	address, err := s.GetAddressByID(id)
 	if err != nil {
 		return err
+	} else if address.Version != version {
+		return models.ErrVersionConflict
 	} else {
 		s.deleteCalls = append(s.deleteCalls, id)
+		s.revisions = slices.DeleteFunc(s.revisions, func(revision models.AddressRevision) bool {
+			return revision.AddressId == id && revision.FrozenAt == nil
+		})
 		return nil
 	}
 }
 
+func (s *StubAddressStore) GetAddressRevision(addressId int, revision int) (models.AddressRevision, error) {
+	for _, addressRevision := range s.revisions {
+		if addressRevision.AddressId == addressId && addressRevision.Revision == revision {
+			return addressRevision, nil
+		}
+	}
+	return models.AddressRevision{}, models.ErrNotFound
+}
+
+func (s *StubAddressStore) FreezeAddressRevision(addressId int, revision int) (models.AddressRevision, error) {
+	for i, addressRevision := range s.revisions {
+		if addressRevision.AddressId == addressId && addressRevision.Revision == revision {
+			if addressRevision.FrozenAt == nil {
+				frozenAt := time.Now()
+				s.revisions[i].FrozenAt = &frozenAt
+			}
+			return s.revisions[i], nil
+		}
+	}
+	return models.AddressRevision{}, models.ErrNotFound
+}
+
+func (s *StubAddressStore) SearchAddressesNear(search models.AddressProximitySearch) (models.AddressMatchPage, error) {
+	matches := []models.AddressMatch{}
+	for _, address := range s.addresses {
+		if search.Area.Contains(address.Lat, address.Lon) {
+			distance := models.Distance(search.Area.Lat, search.Area.Lon, address.Lat, address.Lon)
+			matches = append(matches, models.AddressMatch{Address: address, Distance: distance})
+		}
+	}
+
+	sort.Slice(matches, func(i, j int) bool {
+		if matches[i].Distance != matches[j].Distance {
+			return matches[i].Distance < matches[j].Distance
+		}
+		return matches[i].Address.Id < matches[j].Address.Id
+	})
+
+	if search.After != nil {
+		after := *search.After
+		matches = slices.DeleteFunc(matches, func(match models.AddressMatch) bool {
+			return match.Distance < after.Distance || (match.Distance == after.Distance && match.Address.Id <= after.Id)
+		})
+	}
+
+	page := models.AddressMatchPage{Matches: matches}
+	if len(matches) > search.Limit {
+		page.Matches = matches[:search.Limit]
+		last := page.Matches[search.Limit-1]
+		page.NextCursor = &models.ProximityCursor{Distance: last.Distance, Id: last.Address.Id}
+	}
+
+	return page, nil
+}
+
+func (s *StubAddressStore) hasDefault(customerId int) bool {
+	for _, address := range s.addresses {
+		if address.CustomerId == customerId && address.IsDefault {
+			return true
+		}
+	}
+	return false
+}
+
+// clearDefault unsets the default address of a customer like
+// PgAddressStore does before another address becomes the default.
+func (s *StubAddressStore) clearDefault(customerId int, exceptId int) {
+	for i, address := range s.addresses {
+		if address.CustomerId == customerId && address.Id != exceptId && address.IsDefault {
+			s.addresses[i].IsDefault = false
+			s.addresses[i].Version++
+		}
+	}
+}
+
 func (s *StubAddressStore) Empty() {
 	s.addresses = []models.Address{}
 	s.storeCalls = []models.Address{}
+	s.revisions = []models.AddressRevision{}
 }
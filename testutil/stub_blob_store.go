@@ -0,0 +1,44 @@
+package testutil
+
+import (
+	"io"
+
+	"github.com/VitoNaychev/bt-customer-svc/blobstore"
+)
+
+type StubBlobStore struct {
+	Blobs        map[string][]byte
+	ContentTypes map[string]string
+}
+
+func NewStubBlobStore() *StubBlobStore {
+	return &StubBlobStore{
+		Blobs:        map[string][]byte{},
+		ContentTypes: map[string]string{},
+	}
+}
+
+func (s *StubBlobStore) Put(key string, contentType string, data io.Reader) error {
+	blob, err := io.ReadAll(data)
+	if err != nil {
+		return err
+	}
+
+	s.Blobs[key] = blob
+	s.ContentTypes[key] = contentType
+	return nil
+}
+
+func (s *StubBlobStore) Delete(key string) error {
+	if _, ok := s.Blobs[key]; !ok {
+		return blobstore.ErrNotFound
+	}
+
+	delete(s.Blobs, key)
+	delete(s.ContentTypes, key)
+	return nil
+}
+
+func (s *StubBlobStore) URL(key string) string {
+	return "/blobs/" + key
+}
@@ -0,0 +1,70 @@
+package testutil
+
+import (
+	"time"
+
+	"github.com/VitoNaychev/bt-customer-svc/models"
+)
+
+type StubConsentStore struct {
+	documents    []models.ConsentDocument
+	consents     []models.Consent
+	publishCalls []models.ConsentDocument
+	recordCalls  []models.Consent
+}
+
+func NewStubConsentStore(documents []models.ConsentDocument, consents []models.Consent) *StubConsentStore {
+	return &StubConsentStore{
+		documents:    documents,
+		consents:     consents,
+		publishCalls: []models.ConsentDocument{},
+		recordCalls:  []models.Consent{},
+	}
+}
+
+func (s *StubConsentStore) GetLatestDocuments() ([]models.ConsentDocument, error) {
+	latestByKind := map[string]models.ConsentDocument{}
+	kinds := []string{}
+	for _, document := range s.documents {
+		if _, ok := latestByKind[document.Kind]; !ok {
+			kinds = append(kinds, document.Kind)
+		}
+		latestByKind[document.Kind] = document
+	}
+
+	latest := []models.ConsentDocument{}
+	for _, kind := range kinds {
+		latest = append(latest, latestByKind[kind])
+	}
+
+	return latest, nil
+}
+
+func (s *StubConsentStore) PublishDocument(document *models.ConsentDocument) error {
+	document.Id = len(s.documents) + 1
+	document.PublishedAt = time.Now()
+	s.documents = append(s.documents, *document)
+	s.publishCalls = append(s.publishCalls, *document)
+
+	return nil
+}
+
+func (s *StubConsentStore) GetConsentHistory(customerID int) ([]models.Consent, error) {
+	history := []models.Consent{}
+	for _, consent := range s.consents {
+		if consent.CustomerId == customerID {
+			history = append(history, consent)
+		}
+	}
+
+	return history, nil
+}
+
+func (s *StubConsentStore) RecordConsent(consent *models.Consent) error {
+	consent.Id = len(s.consents) + 1
+	consent.RecordedAt = time.Now()
+	s.consents = append(s.consents, *consent)
+	s.recordCalls = append(s.recordCalls, *consent)
+
+	return nil
+}
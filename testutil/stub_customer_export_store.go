@@ -0,0 +1,68 @@
+package testutil
+
+import (
+	"slices"
+	"time"
+
+	"github.com/VitoNaychev/bt-customer-svc/models"
+)
+
+type StubCustomerExportStore struct {
+	customers   []models.Customer
+	addresses   []models.Address
+	preferences []models.Preferences
+}
+
+func NewStubCustomerExportStore(customers []models.Customer, addresses []models.Address,
+	preferences []models.Preferences) *StubCustomerExportStore {
+	return &StubCustomerExportStore{
+		customers:   customers,
+		addresses:   addresses,
+		preferences: preferences,
+	}
+}
+
+func (s *StubCustomerExportStore) ExportCustomers(since time.Time, each func(models.Customer) error) error {
+	customers := slices.Clone(s.customers)
+	slices.SortFunc(customers, func(a, b models.Customer) int { return a.Id - b.Id })
+
+	for _, customer := range customers {
+		if customer.UpdatedAt.Before(since) {
+			continue
+		}
+		if err := each(customer); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (s *StubCustomerExportStore) ExportAddresses(since time.Time, each func(models.Address) error) error {
+	addresses := slices.Clone(s.addresses)
+	slices.SortFunc(addresses, func(a, b models.Address) int { return a.Id - b.Id })
+
+	for _, address := range addresses {
+		if address.UpdatedAt.Before(since) {
+			continue
+		}
+		if err := each(address); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (s *StubCustomerExportStore) ExportPreferences(since time.Time, each func(models.Preferences) error) error {
+	preferences := slices.Clone(s.preferences)
+	slices.SortFunc(preferences, func(a, b models.Preferences) int { return a.CustomerId - b.CustomerId })
+
+	for _, customerPreferences := range preferences {
+		if customerPreferences.UpdatedAt.Before(since) {
+			continue
+		}
+		if err := each(customerPreferences); err != nil {
+			return err
+		}
+	}
+	return nil
+}
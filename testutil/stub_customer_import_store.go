@@ -0,0 +1,74 @@
+package testutil
+
+import (
+	"fmt"
+
+	"github.com/VitoNaychev/bt-customer-svc/models"
+)
+
+// StubCustomerImportStore rejects imported customers that clash with the
+// customers it was created with or imported before, like PgCustomerStore.ImportCustomers.
+// FailAfter makes every batch after the first FailAfter ones fail, which
+// simulates an interrupted import.
+type StubCustomerImportStore struct {
+	customers   []models.Customer
+	checkpoints map[string]int
+	FailAfter   int
+	batches     int
+	Imported    []models.ImportedCustomer
+}
+
+func NewStubCustomerImportStore(customers []models.Customer) *StubCustomerImportStore {
+	return &StubCustomerImportStore{
+		customers:   customers,
+		checkpoints: map[string]int{},
+		Imported:    []models.ImportedCustomer{},
+	}
+}
+
+func (s *StubCustomerImportStore) GetImportCheckpoint(source string) (int, error) {
+	return s.checkpoints[source], nil
+}
+
+func (s *StubCustomerImportStore) ImportCustomers(source string, checkpoint int, batch []models.ImportedCustomer, dryRun bool) (models.ImportBatchReport, error) {
+	if s.FailAfter > 0 && s.batches >= s.FailAfter {
+		return models.ImportBatchReport{}, models.NewStoreError("connection reset")
+	}
+	s.batches++
+
+	report := models.ImportBatchReport{Rejected: []models.ImportRejection{}}
+	accepted := []models.ImportedCustomer{}
+	for _, imported := range batch {
+		if reason := s.clash(imported.Customer); reason != "" {
+			report.Rejected = append(report.Rejected, models.ImportRejection{Line: imported.Line, Reason: reason})
+			continue
+		}
+
+		accepted = append(accepted, imported)
+		report.Imported++
+		report.Addresses += len(imported.Addresses)
+	}
+
+	if !dryRun {
+		for i := range accepted {
+			accepted[i].Customer.Id = len(s.customers) + 1
+			s.customers = append(s.customers, accepted[i].Customer)
+		}
+		s.Imported = append(s.Imported, accepted...)
+		s.checkpoints[source] = checkpoint
+	}
+
+	return report, nil
+}
+
+func (s *StubCustomerImportStore) clash(customer models.Customer) string {
+	for _, existing := range s.customers {
+		if existing.PhoneNumber == customer.PhoneNumber {
+			return fmt.Sprintf("phone number is already used by customer %d", existing.Id)
+		}
+		if existing.Email == customer.Email {
+			return fmt.Sprintf("email is already used by customer %d", existing.Id)
+		}
+	}
+	return ""
+}
@@ -1,6 +1,9 @@
 package testutil
 
 import (
+	"sort"
+	"strings"
+
 	"github.com/VitoNaychev/bt-customer-svc/models"
 )
 
@@ -9,6 +12,11 @@ type StubCustomerStore struct {
 	storeCalls  []models.Customer
 	deleteCalls []int
 	updateCalls []models.Customer
+	patchCalls  []models.CustomerPatch
+
+	anonymizeCalls  []int
+	signupConsents  []models.Consent
+	signupReferrals []models.Referral
 }
 
 func NewStubCustomerStore(data []models.Customer) *StubCustomerStore {
@@ -17,6 +25,11 @@ func NewStubCustomerStore(data []models.Customer) *StubCustomerStore {
 		storeCalls:  []models.Customer{},
 		deleteCalls: []int{},
 		updateCalls: []models.Customer{},
+		patchCalls:  []models.CustomerPatch{},
+
+		anonymizeCalls:  []int{},
+		signupConsents:  []models.Consent{},
+		signupReferrals: []models.Referral{},
 	}
 }
 
@@ -41,22 +54,90 @@ func (s *StubCustomerStore) GetCustomerByEmail(email string) (models.Customer, e
 }
 
 func (s *StubCustomerStore) CreateCustomer(customer *models.Customer) error {
+	if err := s.checkUnique(*customer); err != nil {
+		return err
+	}
+
 	customer.Id = len(s.customers) + 1
+	customer.Status = models.ACTIVE_STATUS
+	customer.Version = 1
 	s.customers = append(s.customers, *customer)
 	s.storeCalls = append(s.storeCalls, *customer)
 
 	return nil
 }
 
+// SignUpCustomer keeps the consents and the referral of the signup for
+// assertions. Referrals aren't checked for identity reuse, that is decided
+// by the query of PgCustomerStore.
+func (s *StubCustomerStore) SignUpCustomer(customer *models.Customer, consents []models.Consent, referral *models.ReferralSignup) error {
+	if err := s.CreateCustomer(customer); err != nil {
+		return err
+	}
+
+	for _, consent := range consents {
+		consent.CustomerId = customer.Id
+		s.signupConsents = append(s.signupConsents, consent)
+	}
+
+	if referral != nil {
+		s.signupReferrals = append(s.signupReferrals, referral.Referral(*customer))
+	}
+
+	return nil
+}
+
 func (s *StubCustomerStore) UpdateCustomer(customer *models.Customer) error {
 	s.updateCalls = append(s.updateCalls, *customer)
 
+	if err := s.checkUnique(*customer); err != nil {
+		return err
+	}
+
+	for _, stored := range s.customers {
+		if stored.Id == customer.Id && stored.Version != customer.Version {
+			return models.ErrVersionConflict
+		}
+	}
+	customer.Version++
+
 	return nil
 }
 
-func (s *StubCustomerStore) DeleteCustomer(id int) error {
+func (s *StubCustomerStore) PatchCustomer(id int, version int, patch models.CustomerPatch) (models.Customer, error) {
+	for i, customer := range s.customers {
+		if customer.Id == id {
+			if customer.Version != version {
+				return models.Customer{}, models.ErrVersionConflict
+			}
+
+			if patch == (models.CustomerPatch{}) {
+				s.patchCalls = append(s.patchCalls, patch)
+				return customer, nil
+			}
+
+			patched := patch.Apply(customer)
+			if err := s.checkUnique(patched); err != nil {
+				return models.Customer{}, err
+			}
+
+			s.customers[i] = patched
+			s.customers[i].Version++
+			s.patchCalls = append(s.patchCalls, patch)
+			return s.customers[i], nil
+		}
+	}
+
+	return models.Customer{}, models.ErrNotFound
+}
+
+func (s *StubCustomerStore) DeleteCustomer(id int, version int) error {
 	for _, customer := range s.customers {
 		if customer.Id == id {
+			if customer.Version != version {
+				return models.ErrVersionConflict
+			}
+
 			// s.customers = append(s.customers[:id], s.customers[id+1:]...)
 			s.deleteCalls = append(s.deleteCalls, id)
 			return nil
@@ -66,6 +147,135 @@ func (s *StubCustomerStore) DeleteCustomer(id int) error {
 	return models.ErrNotFound
 }
 
+func (s *StubCustomerStore) AnonymizeCustomer(id int) (models.ErasureReport, error) {
+	for i, customer := range s.customers {
+		if customer.Id == id {
+			erased, fields := models.EraseCustomerPII(customer)
+			s.customers[i] = erased
+			s.anonymizeCalls = append(s.anonymizeCalls, id)
+
+			report := models.ErasureReport{
+				CustomerId:     id,
+				CustomerFields: fields,
+				Addresses:      []models.AddressErasure{},
+			}
+			return report, nil
+		}
+	}
+
+	return models.ErasureReport{}, models.ErrNotFound
+}
+
+func (s *StubCustomerStore) SearchCustomers(search models.CustomerSearch) (models.CustomerPage, error) {
+	matches := []models.Customer{}
+	for _, customer := range s.customers {
+		if matchesSearch(search, customer) {
+			matches = append(matches, customer)
+		}
+	}
+
+	less := func(a, b models.Customer) bool {
+		if c := compareSortKeys(a, b, search.SortBy); c != 0 {
+			return c < 0
+		}
+		return a.Id < b.Id
+	}
+	if search.Descending {
+		less = func(a, b models.Customer) bool {
+			if c := compareSortKeys(a, b, search.SortBy); c != 0 {
+				return c > 0
+			}
+			return a.Id > b.Id
+		}
+	}
+	sort.Slice(matches, func(i, j int) bool { return less(matches[i], matches[j]) })
+
+	if search.After != nil {
+		for i, customer := range matches {
+			if customer.Id == search.After.Id {
+				matches = matches[i+1:]
+				break
+			}
+		}
+	}
+
+	page := models.CustomerPage{Customers: matches}
+	if len(matches) > search.Limit {
+		page.Customers = matches[:search.Limit]
+		cursor := models.NewSearchCursor(page.Customers[search.Limit-1], search.SortBy)
+		page.NextCursor = &cursor
+	}
+
+	return page, nil
+}
+
+// matchesSearch reports whether a customer satisfies the filters of the
+// search, approximating the query of PgCustomerStore.SearchCustomers.
+func matchesSearch(s models.CustomerSearch, customer models.Customer) bool {
+	if s.NamePrefix != "" {
+		prefix := strings.ToLower(s.NamePrefix)
+		fullName := strings.ToLower(customer.FirstName + " " + customer.LastName)
+
+		if !strings.HasPrefix(strings.ToLower(customer.FirstName), prefix) &&
+			!strings.HasPrefix(strings.ToLower(customer.LastName), prefix) &&
+			!strings.HasPrefix(fullName, prefix) {
+			return false
+		}
+	}
+
+	if s.EmailFragment != "" && !strings.Contains(strings.ToLower(customer.Email), strings.ToLower(s.EmailFragment)) {
+		return false
+	}
+
+	if s.PhoneFragment != "" && !strings.Contains(models.PhoneDigits(customer.PhoneNumber), models.PhoneDigits(s.PhoneFragment)) {
+		return false
+	}
+
+	if s.Status != "" && customer.Status != s.Status {
+		return false
+	}
+
+	if !s.CreatedAfter.IsZero() && customer.CreatedAt.Before(s.CreatedAfter) {
+		return false
+	}
+
+	if !s.CreatedBefore.IsZero() && !customer.CreatedAt.Before(s.CreatedBefore) {
+		return false
+	}
+
+	return true
+}
+
+func compareSortKeys(a, b models.Customer, sortBy string) int {
+	switch sortBy {
+	case models.SORT_BY_CREATED_AT:
+		return a.CreatedAt.Compare(b.CreatedAt)
+	case models.SORT_BY_ID:
+		return a.Id - b.Id
+	default:
+		return strings.Compare(models.SortValue(a, sortBy), models.SortValue(b, sortBy))
+	}
+}
+
+// checkUnique mimics the unique constraints on email and phone number.
+func (s *StubCustomerStore) checkUnique(customer models.Customer) error {
+	for _, stored := range s.customers {
+		if stored.Id == customer.Id {
+			continue
+		}
+
+		if stored.Email == customer.Email {
+			return &models.ConstraintError{Err: models.ErrUniqueViolation, Constraint: "customers_canonical_email_key", Column: "canonical_email"}
+		}
+
+		if stored.PhoneNumber == customer.PhoneNumber {
+			return &models.ConstraintError{Err: models.ErrUniqueViolation, Constraint: "customers_phone_number_key", Column: "phone_number"}
+		}
+	}
+
+	return nil
+}
+
 func (s *StubCustomerStore) Empty() {
 	s.customers = []models.Customer{}
 	s.storeCalls = []models.Customer{}
@@ -0,0 +1,87 @@
+package testutil
+
+import (
+	"time"
+
+	"github.com/VitoNaychev/bt-customer-svc/models"
+)
+
+type StubDuplicateStore struct {
+	candidates   []models.DuplicateCandidate
+	mergeCalls   [][2]int
+	dismissCalls []int
+}
+
+func NewStubDuplicateStore(candidates []models.DuplicateCandidate) *StubDuplicateStore {
+	return &StubDuplicateStore{
+		candidates:   candidates,
+		mergeCalls:   [][2]int{},
+		dismissCalls: []int{},
+	}
+}
+
+func (s *StubDuplicateStore) DetectDuplicates() ([]models.DuplicateCandidate, error) {
+	return s.GetCandidates(models.PENDING_CANDIDATE)
+}
+
+func (s *StubDuplicateStore) GetCandidates(status string) ([]models.DuplicateCandidate, error) {
+	candidates := []models.DuplicateCandidate{}
+	for _, candidate := range s.candidates {
+		if candidate.Status == status {
+			candidates = append(candidates, candidate)
+		}
+	}
+
+	return candidates, nil
+}
+
+func (s *StubDuplicateStore) DismissCandidate(id int) error {
+	candidate, err := s.reviewCandidate(id, models.DISMISSED_CANDIDATE)
+	if err != nil {
+		return err
+	}
+
+	s.dismissCalls = append(s.dismissCalls, candidate.Id)
+	return nil
+}
+
+func (s *StubDuplicateStore) MergeCustomers(candidateID int, survivorID int) (models.MergeReport, error) {
+	for _, candidate := range s.candidates {
+		if candidate.Id == candidateID && candidate.Status == models.PENDING_CANDIDATE &&
+			survivorID != candidate.CustomerId && survivorID != candidate.DuplicateId {
+			return models.MergeReport{}, models.ErrInvalidMerge
+		}
+	}
+
+	candidate, err := s.reviewCandidate(candidateID, models.MERGED_CANDIDATE)
+	if err != nil {
+		return models.MergeReport{}, err
+	}
+
+	mergedID := candidate.CustomerId
+	if mergedID == survivorID {
+		mergedID = candidate.DuplicateId
+	}
+
+	s.mergeCalls = append(s.mergeCalls, [2]int{survivorID, mergedID})
+	return models.MergeReport{SurvivorId: survivorID, MergedId: mergedID}, nil
+}
+
+func (s *StubDuplicateStore) reviewCandidate(id int, status string) (models.DuplicateCandidate, error) {
+	for i, candidate := range s.candidates {
+		if candidate.Id != id {
+			continue
+		}
+
+		if candidate.Status != models.PENDING_CANDIDATE {
+			return models.DuplicateCandidate{}, models.ErrCandidateReviewed
+		}
+
+		reviewedAt := time.Now()
+		s.candidates[i].Status = status
+		s.candidates[i].ReviewedAt = &reviewedAt
+		return s.candidates[i], nil
+	}
+
+	return models.DuplicateCandidate{}, models.ErrNotFound
+}
@@ -0,0 +1,47 @@
+package testutil
+
+import (
+	"github.com/VitoNaychev/bt-customer-svc/geocoding"
+	"github.com/VitoNaychev/bt-customer-svc/models"
+)
+
+// StubGeocoder knows the locations of address lines regardless of their
+// city and country. Reverse lookups return the nearest of Places within
+// MAX_STREET_DISTANCE.
+type StubGeocoder struct {
+	Locations map[string]geocoding.Location
+	Places    []geocoding.Place
+	Queries   []geocoding.Query
+}
+
+func NewStubGeocoder(locations map[string]geocoding.Location) *StubGeocoder {
+	return &StubGeocoder{Locations: locations, Places: []geocoding.Place{}, Queries: []geocoding.Query{}}
+}
+
+func (s *StubGeocoder) Geocode(query geocoding.Query) (geocoding.Location, error) {
+	s.Queries = append(s.Queries, query)
+
+	location, ok := s.Locations[query.AddressLine1]
+	if !ok {
+		return geocoding.Location{}, geocoding.ErrNotFound
+	}
+	return location, nil
+}
+
+func (s *StubGeocoder) Reverse(lat, lon float64) (geocoding.Place, error) {
+	found := false
+	nearest := geocoding.Place{Distance: geocoding.MAX_STREET_DISTANCE}
+	for _, place := range s.Places {
+		distance := models.Distance(lat, lon, place.Location.Lat, place.Location.Lon)
+		if distance <= nearest.Distance {
+			nearest = place
+			nearest.Distance = distance
+			found = true
+		}
+	}
+
+	if !found {
+		return geocoding.Place{}, geocoding.ErrNotFound
+	}
+	return nearest, nil
+}
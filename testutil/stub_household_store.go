@@ -0,0 +1,136 @@
+package testutil
+
+import (
+	"slices"
+	"time"
+
+	"github.com/VitoNaychev/bt-customer-svc/models"
+)
+
+type StubHouseholdStore struct {
+	households  []models.Household
+	members     []models.HouseholdMember
+	invitations []models.HouseholdInvitation
+}
+
+func NewStubHouseholdStore(households []models.Household, members []models.HouseholdMember,
+	invitations []models.HouseholdInvitation) *StubHouseholdStore {
+	return &StubHouseholdStore{
+		households:  households,
+		members:     members,
+		invitations: invitations,
+	}
+}
+
+func (s *StubHouseholdStore) CreateHousehold(household *models.Household, ownerID int) error {
+	household.Id = len(s.households) + 1
+	household.CreatedAt = time.Now()
+	s.households = append(s.households, *household)
+	s.members = append(s.members, models.HouseholdMember{
+		HouseholdId: household.Id,
+		CustomerId:  ownerID,
+		Role:        models.OWNER_ROLE,
+		JoinedAt:    household.CreatedAt,
+	})
+
+	return nil
+}
+
+func (s *StubHouseholdStore) GetHouseholdByID(id int) (models.Household, error) {
+	for _, household := range s.households {
+		if household.Id == id {
+			return household, nil
+		}
+	}
+	return models.Household{}, models.ErrNotFound
+}
+
+func (s *StubHouseholdStore) GetHouseholdsByCustomerID(customerID int) ([]models.Household, error) {
+	households := []models.Household{}
+	for _, member := range s.members {
+		if member.CustomerId == customerID {
+			household, _ := s.GetHouseholdByID(member.HouseholdId)
+			households = append(households, household)
+		}
+	}
+	return households, nil
+}
+
+func (s *StubHouseholdStore) GetHouseholdMember(householdID int, customerID int) (models.HouseholdMember, error) {
+	for _, member := range s.members {
+		if member.HouseholdId == householdID && member.CustomerId == customerID {
+			return member, nil
+		}
+	}
+	return models.HouseholdMember{}, models.ErrNotFound
+}
+
+func (s *StubHouseholdStore) GetHouseholdMembers(householdID int) ([]models.HouseholdMember, error) {
+	members := []models.HouseholdMember{}
+	for _, member := range s.members {
+		if member.HouseholdId == householdID {
+			members = append(members, member)
+		}
+	}
+	return members, nil
+}
+
+func (s *StubHouseholdStore) CreateInvitation(invitation *models.HouseholdInvitation) error {
+	invitation.CreatedAt = time.Now()
+	s.invitations = append(s.invitations, *invitation)
+	return nil
+}
+
+func (s *StubHouseholdStore) AcceptInvitation(code string, customerID int, now time.Time) (models.HouseholdMember, error) {
+	for i, invitation := range s.invitations {
+		if invitation.Code != code {
+			continue
+		}
+
+		if err := invitation.CheckAcceptable(now); err != nil {
+			return models.HouseholdMember{}, err
+		}
+		if _, err := s.GetHouseholdMember(invitation.HouseholdId, customerID); err == nil {
+			return models.HouseholdMember{}, models.ErrAlreadyMember
+		}
+
+		member := models.HouseholdMember{
+			HouseholdId: invitation.HouseholdId,
+			CustomerId:  customerID,
+			Role:        invitation.Role,
+			JoinedAt:    now,
+		}
+		s.members = append(s.members, member)
+		s.invitations[i].AcceptedBy = &customerID
+		s.invitations[i].AcceptedAt = &now
+
+		return member, nil
+	}
+
+	return models.HouseholdMember{}, models.ErrNotFound
+}
+
+func (s *StubHouseholdStore) RemoveHouseholdMember(householdID int, customerID int) error {
+	member, err := s.GetHouseholdMember(householdID, customerID)
+	if err != nil {
+		return err
+	}
+
+	if member.IsOwner() {
+		owners := 0
+		for _, other := range s.members {
+			if other.HouseholdId == householdID && other.IsOwner() {
+				owners++
+			}
+		}
+		if owners == 1 {
+			return models.ErrLastOwner
+		}
+	}
+
+	s.members = slices.DeleteFunc(s.members, func(other models.HouseholdMember) bool {
+		return other.HouseholdId == householdID && other.CustomerId == customerID
+	})
+
+	return nil
+}
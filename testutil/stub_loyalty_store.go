@@ -0,0 +1,83 @@
+package testutil
+
+import (
+	"time"
+
+	"github.com/VitoNaychev/bt-customer-svc/models"
+)
+
+type StubLoyaltyStore struct {
+	transactions []models.LoyaltyTransaction
+	recordCalls  []models.LoyaltyTransaction
+}
+
+func NewStubLoyaltyStore(data []models.LoyaltyTransaction) *StubLoyaltyStore {
+	return &StubLoyaltyStore{
+		transactions: data,
+		recordCalls:  []models.LoyaltyTransaction{},
+	}
+}
+
+func (s *StubLoyaltyStore) RecordTransaction(transaction *models.LoyaltyTransaction) error {
+	for _, recorded := range s.transactions {
+		if recorded.Id == transaction.Id {
+			if !recorded.SameAs(*transaction) {
+				return models.ErrTransactionConflict
+			}
+			*transaction = recorded
+			return nil
+		}
+	}
+
+	if transaction.Points < 0 {
+		balance, _ := s.GetBalance(transaction.CustomerId)
+		if balance+transaction.Points < 0 {
+			return models.ErrInsufficientPoints
+		}
+	}
+
+	transaction.CreatedAt = time.Now()
+	s.transactions = append(s.transactions, *transaction)
+	s.recordCalls = append(s.recordCalls, *transaction)
+
+	return nil
+}
+
+func (s *StubLoyaltyStore) GetBalance(customerID int) (int, error) {
+	transactions, _ := s.GetTransactions(customerID)
+	return models.PointsBalance(transactions), nil
+}
+
+func (s *StubLoyaltyStore) GetTransactions(customerID int) ([]models.LoyaltyTransaction, error) {
+	transactions := []models.LoyaltyTransaction{}
+	for _, transaction := range s.transactions {
+		if transaction.CustomerId == customerID {
+			transactions = append(transactions, transaction)
+		}
+	}
+
+	return transactions, nil
+}
+
+func (s *StubLoyaltyStore) ExpirePoints(now time.Time) ([]models.LoyaltyTransaction, error) {
+	customerIDs := []int{}
+	seen := map[int]bool{}
+	for _, transaction := range s.transactions {
+		if !seen[transaction.CustomerId] {
+			seen[transaction.CustomerId] = true
+			customerIDs = append(customerIDs, transaction.CustomerId)
+		}
+	}
+
+	expired := []models.LoyaltyTransaction{}
+	for _, customerID := range customerIDs {
+		transactions, _ := s.GetTransactions(customerID)
+		if points := models.ExpiredPoints(transactions, now); points > 0 {
+			transaction := models.NewExpireTransaction(customerID, points, now)
+			s.RecordTransaction(&transaction)
+			expired = append(expired, transaction)
+		}
+	}
+
+	return expired, nil
+}
@@ -0,0 +1,41 @@
+package testutil
+
+import (
+	"github.com/VitoNaychev/bt-customer-svc/models"
+)
+
+type StubPreferencesStore struct {
+	preferences []models.Preferences
+	upsertCalls []models.Preferences
+}
+
+func NewStubPreferencesStore(data []models.Preferences) *StubPreferencesStore {
+	return &StubPreferencesStore{
+		preferences: data,
+		upsertCalls: []models.Preferences{},
+	}
+}
+
+func (s *StubPreferencesStore) GetPreferences(customerID int) (models.Preferences, error) {
+	for _, preferences := range s.preferences {
+		if preferences.CustomerId == customerID {
+			return preferences, nil
+		}
+	}
+
+	return models.Preferences{}, models.ErrNotFound
+}
+
+func (s *StubPreferencesStore) UpsertPreferences(preferences *models.Preferences) error {
+	s.upsertCalls = append(s.upsertCalls, *preferences)
+
+	for i, stored := range s.preferences {
+		if stored.CustomerId == preferences.CustomerId {
+			s.preferences[i] = *preferences
+			return nil
+		}
+	}
+
+	s.preferences = append(s.preferences, *preferences)
+	return nil
+}
@@ -0,0 +1,64 @@
+package testutil
+
+import (
+	"github.com/VitoNaychev/bt-customer-svc/models"
+)
+
+type StubReferralStore struct {
+	codes     map[int]string
+	referrals []models.Referral
+}
+
+func NewStubReferralStore(codes map[int]string, referrals []models.Referral) *StubReferralStore {
+	if codes == nil {
+		codes = map[int]string{}
+	}
+
+	return &StubReferralStore{
+		codes:     codes,
+		referrals: referrals,
+	}
+}
+
+func (s *StubReferralStore) GetReferralCode(customerID int) (string, error) {
+	if code, ok := s.codes[customerID]; ok {
+		return code, nil
+	}
+
+	code, err := models.NewReferralCode()
+	if err != nil {
+		return "", err
+	}
+	s.codes[customerID] = code
+
+	return code, nil
+}
+
+func (s *StubReferralStore) GetReferrerByCode(code string) (int, error) {
+	for customerID, customerCode := range s.codes {
+		if customerCode == code {
+			return customerID, nil
+		}
+	}
+
+	return 0, models.ErrNotFound
+}
+
+func (s *StubReferralStore) GetReferralStats(customerID int) (models.ReferralStats, error) {
+	code, _ := s.GetReferralCode(customerID)
+
+	stats := models.ReferralStats{Code: code}
+	for _, referral := range s.referrals {
+		if referral.ReferrerId != customerID || referral.Status != models.ACCEPTED_REFERRAL {
+			continue
+		}
+
+		stats.Referred++
+		if stats.LastReferredAt == nil || referral.CreatedAt.After(*stats.LastReferredAt) {
+			createdAt := referral.CreatedAt
+			stats.LastReferredAt = &createdAt
+		}
+	}
+
+	return stats, nil
+}
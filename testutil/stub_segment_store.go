@@ -0,0 +1,122 @@
+package testutil
+
+import (
+	"slices"
+	"time"
+
+	"github.com/VitoNaychev/bt-customer-svc/models"
+)
+
+// StubSegmentStore doesn't evaluate segment rules, it returns the members
+// it was created with for each segment name. The rules themselves are
+// tested against Postgres.
+type StubSegmentStore struct {
+	tags     map[int][]string
+	segments []models.Segment
+	members  map[string][]int
+}
+
+func NewStubSegmentStore(tags map[int][]string, segments []models.Segment, members map[string][]int) *StubSegmentStore {
+	if tags == nil {
+		tags = map[int][]string{}
+	}
+
+	return &StubSegmentStore{
+		tags:     tags,
+		segments: segments,
+		members:  members,
+	}
+}
+
+func (s *StubSegmentStore) GetTags(customerID int) ([]string, error) {
+	tags := slices.Clone(s.tags[customerID])
+	slices.Sort(tags)
+	if tags == nil {
+		tags = []string{}
+	}
+
+	return tags, nil
+}
+
+func (s *StubSegmentStore) AddTags(customerID int, tags []string) error {
+	for _, tag := range tags {
+		if !slices.Contains(s.tags[customerID], tag) {
+			s.tags[customerID] = append(s.tags[customerID], tag)
+		}
+	}
+
+	return nil
+}
+
+func (s *StubSegmentStore) RemoveTag(customerID int, tag string) error {
+	i := slices.Index(s.tags[customerID], tag)
+	if i < 0 {
+		return models.ErrNotFound
+	}
+
+	s.tags[customerID] = slices.Delete(s.tags[customerID], i, i+1)
+	return nil
+}
+
+func (s *StubSegmentStore) CreateSegment(segment *models.Segment) error {
+	for _, existing := range s.segments {
+		if existing.Name == segment.Name {
+			return &models.ConstraintError{Err: models.ErrUniqueViolation, Constraint: "segments_name_key", Column: "name"}
+		}
+	}
+
+	segment.Id = len(s.segments) + 1
+	segment.CreatedAt = time.Now()
+	s.segments = append(s.segments, *segment)
+
+	return nil
+}
+
+func (s *StubSegmentStore) GetSegments() ([]models.Segment, error) {
+	return s.segments, nil
+}
+
+func (s *StubSegmentStore) DeleteSegment(name string) error {
+	i := slices.IndexFunc(s.segments, func(segment models.Segment) bool { return segment.Name == name })
+	if i < 0 {
+		return models.ErrNotFound
+	}
+
+	s.segments = slices.Delete(s.segments, i, i+1)
+	return nil
+}
+
+func (s *StubSegmentStore) GetSegmentMembers(name string, afterID int, limit int) ([]int, error) {
+	if !s.hasSegment(name) {
+		return nil, models.ErrNotFound
+	}
+
+	members := []int{}
+	for _, id := range s.members[name] {
+		if id > afterID {
+			members = append(members, id)
+		}
+	}
+
+	slices.Sort(members)
+	if len(members) > limit {
+		members = members[:limit]
+	}
+
+	return members, nil
+}
+
+func (s *StubSegmentStore) GetCustomerSegments(customerID int) ([]string, error) {
+	names := []string{}
+	for _, segment := range s.segments {
+		if slices.Contains(s.members[segment.Name], customerID) {
+			names = append(names, segment.Name)
+		}
+	}
+
+	return names, nil
+}
+
+func (s *StubSegmentStore) hasSegment(name string) bool {
+	return slices.ContainsFunc(s.segments, func(segment models.Segment) bool { return segment.Name == name })
+}
@@ -0,0 +1,105 @@
+package zones
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+)
+
+var ErrInvalidGeoJSON = errors.New("GeoJSON is invalid")
+
+type geoJSONObject struct {
+	Type        string           `json:"type"`
+	Features    []geoJSONObject  `json:"features"`
+	Geometry    *geoJSONObject   `json:"geometry"`
+	Properties  map[string]any   `json:"properties"`
+	Coordinates json.RawMessage  `json:"coordinates"`
+	Geometries  []*geoJSONObject `json:"geometries"`
+}
+
+// ParseGeoJSON reads the zones of a FeatureCollection, a single Feature or
+// a bare Polygon or MultiPolygon. A feature is named by its "name"
+// property and belongs to the city in its "city" property; unnamed zones
+// are named after source and their position in it.
+func ParseGeoJSON(r io.Reader, source string) ([]Zone, error) {
+	var object geoJSONObject
+	if err := json.NewDecoder(r).Decode(&object); err != nil {
+		return nil, fmt.Errorf("%w: %s: %v", ErrInvalidGeoJSON, source, err)
+	}
+
+	features := []geoJSONObject{object}
+	switch object.Type {
+	case "FeatureCollection":
+		features = object.Features
+	case "Feature", "Polygon", "MultiPolygon":
+	default:
+		return nil, fmt.Errorf("%w: %s: unsupported type %q", ErrInvalidGeoJSON, source, object.Type)
+	}
+
+	zones := []Zone{}
+	for i, feature := range features {
+		geometry := &feature
+		if feature.Type == "Feature" {
+			geometry = feature.Geometry
+		}
+		if geometry == nil {
+			return nil, fmt.Errorf("%w: %s: feature %d has no geometry", ErrInvalidGeoJSON, source, i)
+		}
+
+		polygons, err := parsePolygons(*geometry)
+		if err != nil {
+			return nil, fmt.Errorf("%w: %s: feature %d: %v", ErrInvalidGeoJSON, source, i, err)
+		}
+
+		zone := Zone{
+			Name:     stringProperty(feature.Properties, "name"),
+			City:     stringProperty(feature.Properties, "city"),
+			polygons: polygons,
+		}
+		if zone.Name == "" {
+			zone.Name = fmt.Sprintf("%s#%d", source, i)
+		}
+		zones = append(zones, zone.withBounds())
+	}
+
+	return zones, nil
+}
+
+func parsePolygons(geometry geoJSONObject) ([]polygon, error) {
+	switch geometry.Type {
+	case "Polygon":
+		var coordinates [][][]float64
+		if err := json.Unmarshal(geometry.Coordinates, &coordinates); err != nil {
+			return nil, err
+		}
+
+		p, err := newPolygon(coordinates)
+		if err != nil {
+			return nil, err
+		}
+		return []polygon{p}, nil
+	case "MultiPolygon":
+		var coordinates [][][][]float64
+		if err := json.Unmarshal(geometry.Coordinates, &coordinates); err != nil {
+			return nil, err
+		}
+
+		polygons := []polygon{}
+		for _, rings := range coordinates {
+			p, err := newPolygon(rings)
+			if err != nil {
+				return nil, err
+			}
+			polygons = append(polygons, p)
+		}
+		return polygons, nil
+	default:
+		return nil, fmt.Errorf("unsupported geometry %q", geometry.Type)
+	}
+}
+
+func stringProperty(properties map[string]any, key string) string {
+	value, _ := properties[key].(string)
+	return value
+}
@@ -0,0 +1,120 @@
+package zones
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync/atomic"
+)
+
+// LoadDir loads the zones of every .geojson file in dir, in the order of
+// the file names.
+func LoadDir(dir string) (*Zones, error) {
+	paths, err := geoJSONFiles(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	loaded := []Zone{}
+	for _, path := range paths {
+		file, err := os.Open(path)
+		if err != nil {
+			return nil, err
+		}
+
+		fileZones, err := ParseGeoJSON(file, filepath.Base(path))
+		file.Close()
+		if err != nil {
+			return nil, err
+		}
+		loaded = append(loaded, fileZones...)
+	}
+
+	return NewZones(loaded), nil
+}
+
+func geoJSONFiles(dir string) ([]string, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	paths := []string{}
+	for _, entry := range entries {
+		if !entry.IsDir() && strings.HasSuffix(strings.ToLower(entry.Name()), ".geojson") {
+			paths = append(paths, filepath.Join(dir, entry.Name()))
+		}
+	}
+
+	sort.Strings(paths)
+	return paths, nil
+}
+
+// Registry serves the zones of a directory and swaps them for new ones
+// when the files change, so zones can be edited without a restart.
+// Lookups never see a partially loaded set.
+type Registry struct {
+	dir         string
+	zones       atomic.Pointer[Zones]
+	fingerprint string
+}
+
+func NewRegistry(dir string) (*Registry, error) {
+	r := &Registry{dir: dir}
+	r.zones.Store(NewZones(nil))
+	if _, err := r.ReloadIfChanged(); err != nil {
+		return nil, err
+	}
+
+	return r, nil
+}
+
+func (r *Registry) Locate(lat, lon float64) (Zone, bool) {
+	return r.zones.Load().Locate(lat, lon)
+}
+
+func (r *Registry) Len() int {
+	return r.zones.Load().Len()
+}
+
+// ReloadIfChanged reloads the zones if a file was added, removed or
+// modified since the last load and reports whether it did. When the new
+// files are invalid the current zones are kept. It is meant to be called
+// from a single goroutine.
+func (r *Registry) ReloadIfChanged() (bool, error) {
+	fingerprint, err := r.dirFingerprint()
+	if err != nil {
+		return false, err
+	}
+	if fingerprint == r.fingerprint {
+		return false, nil
+	}
+
+	zones, err := LoadDir(r.dir)
+	if err != nil {
+		return false, err
+	}
+
+	r.zones.Store(zones)
+	r.fingerprint = fingerprint
+	return true, nil
+}
+
+func (r *Registry) dirFingerprint() (string, error) {
+	paths, err := geoJSONFiles(r.dir)
+	if err != nil {
+		return "", err
+	}
+
+	fingerprint := strings.Builder{}
+	for _, path := range paths {
+		info, err := os.Stat(path)
+		if err != nil {
+			return "", err
+		}
+		fmt.Fprintf(&fingerprint, "%s:%d:%d;", path, info.Size(), info.ModTime().UnixNano())
+	}
+	return fingerprint.String(), nil
+}
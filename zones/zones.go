@@ -0,0 +1,143 @@
+package zones
+
+import (
+	"errors"
+	"math"
+)
+
+// point is a GeoJSON position, longitude first.
+type point struct {
+	lon float64
+	lat float64
+}
+
+// polygon is an outer ring with optional holes. Rings don't have to repeat
+// their first point at the end.
+type polygon struct {
+	outer []point
+	holes [][]point
+}
+
+type bounds struct {
+	minLat, minLon, maxLat, maxLon float64
+}
+
+func (b bounds) contains(lat, lon float64) bool {
+	return lat >= b.minLat && lat <= b.maxLat && lon >= b.minLon && lon <= b.maxLon
+}
+
+// Zone is a delivery area of a city, made of one or more polygons.
+type Zone struct {
+	Name     string
+	City     string
+	polygons []polygon
+	bounds   bounds
+}
+
+func newPolygon(coordinates [][][]float64) (polygon, error) {
+	if len(coordinates) == 0 {
+		return polygon{}, errors.New("polygon has no rings")
+	}
+
+	rings := [][]point{}
+	for _, ring := range coordinates {
+		points := []point{}
+		for _, position := range ring {
+			if len(position) < 2 || position[0] < -180 || position[0] > 180 || position[1] < -90 || position[1] > 90 {
+				return polygon{}, errors.New("position is not a valid longitude and latitude")
+			}
+			points = append(points, point{lon: position[0], lat: position[1]})
+		}
+
+		if len(points) > 1 && points[0] == points[len(points)-1] {
+			points = points[:len(points)-1]
+		}
+		if len(points) < 3 {
+			return polygon{}, errors.New("ring has less than 3 distinct positions")
+		}
+		rings = append(rings, points)
+	}
+
+	return polygon{outer: rings[0], holes: rings[1:]}, nil
+}
+
+func (z Zone) withBounds() Zone {
+	z.bounds = bounds{math.Inf(1), math.Inf(1), math.Inf(-1), math.Inf(-1)}
+	for _, p := range z.polygons {
+		for _, point := range p.outer {
+			z.bounds.minLat = min(z.bounds.minLat, point.lat)
+			z.bounds.minLon = min(z.bounds.minLon, point.lon)
+			z.bounds.maxLat = max(z.bounds.maxLat, point.lat)
+			z.bounds.maxLon = max(z.bounds.maxLon, point.lon)
+		}
+	}
+	return z
+}
+
+func (z Zone) Contains(lat, lon float64) bool {
+	if !z.bounds.contains(lat, lon) {
+		return false
+	}
+
+	for _, p := range z.polygons {
+		if p.contains(lat, lon) {
+			return true
+		}
+	}
+	return false
+}
+
+func (p polygon) contains(lat, lon float64) bool {
+	if !inRing(p.outer, lat, lon) {
+		return false
+	}
+
+	for _, hole := range p.holes {
+		if inRing(hole, lat, lon) {
+			return false
+		}
+	}
+	return true
+}
+
+// inRing casts a ray from the point towards increasing longitude and
+// counts the edges of the ring it crosses.
+func inRing(ring []point, lat, lon float64) bool {
+	inside := false
+	for i, j := 0, len(ring)-1; i < len(ring); j, i = i, i+1 {
+		a, b := ring[i], ring[j]
+		if (a.lat > lat) != (b.lat > lat) &&
+			lon < (b.lon-a.lon)*(lat-a.lat)/(b.lat-a.lat)+a.lon {
+			inside = !inside
+		}
+	}
+	return inside
+}
+
+// DeliveryArea tells in which zone, if any, a point is delivered to.
+type DeliveryArea interface {
+	Locate(lat, lon float64) (Zone, bool)
+}
+
+// Zones is a fixed set of zones. Overlapping zones are matched in the
+// order they were loaded in.
+type Zones struct {
+	zones []Zone
+}
+
+func NewZones(zones []Zone) *Zones {
+	return &Zones{zones: zones}
+}
+
+func (z *Zones) Locate(lat, lon float64) (Zone, bool) {
+	for _, zone := range z.zones {
+		if zone.Contains(lat, lon) {
+			return zone, true
+		}
+	}
+	return Zone{}, false
+}
+
+func (z *Zones) Len() int {
+	return len(z.zones)
+}
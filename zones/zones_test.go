@@ -0,0 +1,156 @@
+package zones_test
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/VitoNaychev/bt-customer-svc/testutil"
+	"github.com/VitoNaychev/bt-customer-svc/zones"
+)
+
+// center has a hole around (42.70, 23.33); lozenets is a multipolygon of
+// two squares.
+const testZones = `{
+  "type": "FeatureCollection",
+  "features": [
+    {
+      "type": "Feature",
+      "properties": {"name": "center", "city": "Sofia"},
+      "geometry": {"type": "Polygon", "coordinates": [
+        [[23.30, 42.68], [23.36, 42.68], [23.36, 42.72], [23.30, 42.72], [23.30, 42.68]],
+        [[23.32, 42.69], [23.34, 42.69], [23.34, 42.71], [23.32, 42.71]]
+      ]}
+    },
+    {
+      "type": "Feature",
+      "properties": {"name": "lozenets", "city": "Sofia"},
+      "geometry": {"type": "MultiPolygon", "coordinates": [
+        [[[23.30, 42.66], [23.32, 42.66], [23.32, 42.67], [23.30, 42.67]]],
+        [[[23.34, 42.66], [23.36, 42.66], [23.36, 42.67], [23.34, 42.67]]]
+      ]}
+    }
+  ]
+}`
+
+func TestParseGeoJSON(t *testing.T) {
+	parsed, err := zones.ParseGeoJSON(strings.NewReader(testZones), "sofia.geojson")
+	if err != nil {
+		t.Fatalf("couldn't parse zones: %v", err)
+	}
+	area := zones.NewZones(parsed)
+
+	cases := map[string]struct {
+		lat, lon float64
+		want     string
+	}{
+		"point inside a polygon":            {42.685, 23.31, "center"},
+		"point inside a hole":               {42.70, 23.33, ""},
+		"point inside a second polygon":     {42.665, 23.35, "lozenets"},
+		"point between polygons":            {42.665, 23.33, ""},
+		"point outside of the bounding box": {42.60, 23.33, ""},
+	}
+
+	for name, test := range cases {
+		t.Run(name, func(t *testing.T) {
+			zone, ok := area.Locate(test.lat, test.lon)
+
+			testutil.AssertEqual(t, ok, test.want != "")
+			testutil.AssertEqual(t, zone.Name, test.want)
+		})
+	}
+
+	t.Run("reads the city of a zone", func(t *testing.T) {
+		zone, _ := area.Locate(42.685, 23.31)
+		testutil.AssertEqual(t, zone.City, "Sofia")
+	})
+
+	t.Run("names unnamed zones after their source", func(t *testing.T) {
+		polygon := `{"type": "Polygon", "coordinates": [[[23.30, 42.66], [23.32, 42.66], [23.32, 42.67]]]}`
+		parsed, err := zones.ParseGeoJSON(strings.NewReader(polygon), "extra.geojson")
+		if err != nil {
+			t.Fatalf("couldn't parse zones: %v", err)
+		}
+		testutil.AssertEqual(t, parsed[0].Name, "extra.geojson#0")
+	})
+
+	invalid := map[string]string{
+		"unsupported type":     `{"type": "Point", "coordinates": [23.3, 42.6]}`,
+		"degenerate ring":      `{"type": "Polygon", "coordinates": [[[23.30, 42.66], [23.32, 42.66], [23.30, 42.66]]]}`,
+		"invalid position":     `{"type": "Polygon", "coordinates": [[[23.30, 92.66], [23.32, 42.66], [23.32, 42.67]]]}`,
+		"feature without area": `{"type": "Feature", "properties": {}}`,
+		"malformed JSON":       `{"type": "Polygon",`,
+	}
+	for name, geoJSON := range invalid {
+		t.Run("rejects "+name, func(t *testing.T) {
+			_, err := zones.ParseGeoJSON(strings.NewReader(geoJSON), "invalid.geojson")
+			if !errors.Is(err, zones.ErrInvalidGeoJSON) {
+				t.Errorf("got error %v want %v", err, zones.ErrInvalidGeoJSON)
+			}
+		})
+	}
+}
+
+func TestRegistry(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "sofia.geojson")
+	writeZones := func(t testing.TB, data string, modTime time.Time) {
+		t.Helper()
+
+		if err := os.WriteFile(path, []byte(data), 0o644); err != nil {
+			t.Fatalf("couldn't write zones: %v", err)
+		}
+		// file systems with coarse timestamps wouldn't see the change
+		if err := os.Chtimes(path, modTime, modTime); err != nil {
+			t.Fatalf("couldn't touch zones: %v", err)
+		}
+	}
+
+	writeZones(t, testZones, time.Now().Add(-time.Hour))
+
+	registry, err := zones.NewRegistry(dir)
+	if err != nil {
+		t.Fatalf("couldn't load zones: %v", err)
+	}
+
+	t.Run("serves loaded zones", func(t *testing.T) {
+		_, ok := registry.Locate(42.685, 23.31)
+		testutil.AssertEqual(t, ok, true)
+		testutil.AssertEqual(t, registry.Len(), 2)
+	})
+
+	t.Run("doesn't reload unchanged files", func(t *testing.T) {
+		reloaded, err := registry.ReloadIfChanged()
+
+		testutil.AssertEqual(t, err, nil)
+		testutil.AssertEqual(t, reloaded, false)
+	})
+
+	t.Run("keeps the zones on invalid files", func(t *testing.T) {
+		writeZones(t, `{"type": "Polygon",`, time.Now().Add(-time.Minute))
+
+		_, err := registry.ReloadIfChanged()
+		if !errors.Is(err, zones.ErrInvalidGeoJSON) {
+			t.Errorf("got error %v want %v", err, zones.ErrInvalidGeoJSON)
+		}
+
+		_, ok := registry.Locate(42.685, 23.31)
+		testutil.AssertEqual(t, ok, true)
+	})
+
+	t.Run("reloads changed files", func(t *testing.T) {
+		polygon := `{"type": "Polygon", "coordinates": [[[27.90, 43.20], [27.93, 43.20], [27.93, 43.23]]]}`
+		writeZones(t, polygon, time.Now())
+
+		reloaded, err := registry.ReloadIfChanged()
+		testutil.AssertEqual(t, err, nil)
+		testutil.AssertEqual(t, reloaded, true)
+
+		_, ok := registry.Locate(42.685, 23.31)
+		testutil.AssertEqual(t, ok, false)
+		testutil.AssertEqual(t, registry.Len(), 1)
+	})
+}